@@ -0,0 +1,67 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// A ReceivedAck is passed to Config.ReceivedAckHook.
+// It describes an ACK frame received from the peer, after it was processed by the loss detection.
+type ReceivedAck struct {
+	EncryptionLevel qlog.EncryptionLevel
+	// Ranges are the ACK ranges of the ACK frame, starting with the range containing the largest
+	// acknowledged packet.
+	Ranges   []qlog.AckRange
+	AckDelay time.Duration
+	// ECT0, ECT1 and ECNCE are the ECN counts, if the ACK frame contained them.
+	ECT0, ECT1, ECNCE uint64
+	// LargestNewlyAcked is the largest packet number acknowledged for the first time by this ACK frame.
+	// It is -1 if the ACK frame didn't acknowledge any new packets.
+	LargestNewlyAcked qlog.PacketNumber
+	// RTTSample is the RTT sample generated by this ACK frame, before the ACK delay is subtracted.
+	// It is 0 if the ACK frame didn't generate an RTT sample.
+	RTTSample  time.Duration
+	ReceivedAt time.Time
+}
+
+// The receivedAckEmitter calls the Config.ReceivedAckHook.
+// It is only created if the hook is set.
+type receivedAckEmitter struct {
+	hook func(*ReceivedAck)
+	copy bool
+
+	ack ReceivedAck // reused for every ACK frame, unless copy is set
+}
+
+func newReceivedAckEmitter(hook func(*ReceivedAck), copy bool) *receivedAckEmitter {
+	return &receivedAckEmitter{hook: hook, copy: copy}
+}
+
+func (e *receivedAckEmitter) ReceivedAck(
+	f *wire.AckFrame,
+	encLevel protocol.EncryptionLevel,
+	largestNewlyAcked protocol.PacketNumber,
+	rttSample time.Duration,
+	rcvTime monotime.Time,
+) {
+	ack := &e.ack
+	if e.copy {
+		ack = &ReceivedAck{}
+	}
+	*ack = ReceivedAck{
+		EncryptionLevel:   encLevel,
+		Ranges:            append(ack.Ranges[:0], f.AckRanges...),
+		AckDelay:          f.DelayTime,
+		ECT0:              f.ECT0,
+		ECT1:              f.ECT1,
+		ECNCE:             f.ECNCE,
+		LargestNewlyAcked: largestNewlyAcked,
+		RTTSample:         rttSample,
+		ReceivedAt:        rcvTime.ToTime(),
+	}
+	e.hook(ack)
+}
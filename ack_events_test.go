@@ -0,0 +1,77 @@
+package quic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/qlog"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceivedAckEmitter(t *testing.T) {
+	t.Run("reused", func(t *testing.T) {
+		testReceivedAckEmitter(t, false)
+	})
+	t.Run("copied", func(t *testing.T) {
+		testReceivedAckEmitter(t, true)
+	})
+}
+
+func testReceivedAckEmitter(t *testing.T, copy bool) {
+	var acks []*ReceivedAck
+	e := newReceivedAckEmitter(func(ack *ReceivedAck) { acks = append(acks, ack) }, copy)
+
+	now := monotime.Now()
+	e.ReceivedAck(
+		&wire.AckFrame{
+			AckRanges: []wire.AckRange{{Smallest: 8, Largest: 10}, {Smallest: 1, Largest: 5}},
+			DelayTime: 3 * time.Millisecond,
+			ECT0:      1, ECT1: 2, ECNCE: 3,
+		},
+		protocol.Encryption1RTT,
+		10,
+		25*time.Millisecond,
+		now,
+	)
+	require.Len(t, acks, 1)
+	first := *acks[0]
+	require.Equal(t, ReceivedAck{
+		EncryptionLevel:   protocol.Encryption1RTT,
+		Ranges:            []qlog.AckRange{{Smallest: 8, Largest: 10}, {Smallest: 1, Largest: 5}},
+		AckDelay:          3 * time.Millisecond,
+		ECT0:              1,
+		ECT1:              2,
+		ECNCE:             3,
+		LargestNewlyAcked: 10,
+		RTTSample:         25 * time.Millisecond,
+		ReceivedAt:        now.ToTime(),
+	}, first)
+
+	// a duplicate ACK doesn't acknowledge any new packets
+	e.ReceivedAck(
+		&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}},
+		protocol.Encryption1RTT,
+		protocol.InvalidPacketNumber,
+		0,
+		now.Add(time.Millisecond),
+	)
+	require.Len(t, acks, 2)
+	second := acks[1]
+	require.Equal(t, []qlog.AckRange{{Smallest: 1, Largest: 10}}, second.Ranges)
+	require.Equal(t, protocol.InvalidPacketNumber, second.LargestNewlyAcked)
+	require.Zero(t, second.RTTSample)
+	require.Zero(t, second.ECNCE)
+
+	if copy {
+		require.NotSame(t, acks[0], acks[1])
+		require.Equal(t, first, *acks[0])
+	} else {
+		require.Same(t, acks[0], acks[1])
+		// the ranges slice is reused as well
+		require.Equal(t, &first.Ranges[0], &second.Ranges[0])
+	}
+}
@@ -4,27 +4,34 @@ import (
 	"math/bits"
 	"net"
 	"sync/atomic"
+	"time"
 
+	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
 // A closedLocalConn is a connection that we closed locally.
 // When receiving packets for such a connection, we need to retransmit the packet containing the CONNECTION_CLOSE frame,
 // with an exponential backoff.
+// If a retransmission interval is set, the packet is retransmitted at most once per interval.
 type closedLocalConn struct {
 	counter atomic.Uint32
 	logger  utils.Logger
 
+	retransmitInterval time.Duration
+	lastRetransmission atomic.Int64 // monotime.Time
+
 	sendPacket func(net.Addr, packetInfo)
 }
 
 var _ packetHandler = &closedLocalConn{}
 
 // newClosedLocalConn creates a new closedLocalConn and runs it.
-func newClosedLocalConn(sendPacket func(net.Addr, packetInfo), logger utils.Logger) packetHandler {
+func newClosedLocalConn(sendPacket func(net.Addr, packetInfo), retransmitInterval time.Duration, logger utils.Logger) packetHandler {
 	return &closedLocalConn{
-		sendPacket: sendPacket,
-		logger:     logger,
+		sendPacket:         sendPacket,
+		retransmitInterval: retransmitInterval,
+		logger:             logger,
 	}
 }
 
@@ -35,6 +42,16 @@ func (c *closedLocalConn) handlePacket(p receivedPacket) {
 	if bits.OnesCount32(n) != 1 {
 		return
 	}
+	if c.retransmitInterval > 0 {
+		last := c.lastRetransmission.Load()
+		if last != 0 && p.rcvTime.Sub(monotime.Time(last)) < c.retransmitInterval {
+			return
+		}
+		// another packet might be handled concurrently
+		if !c.lastRetransmission.CompareAndSwap(last, int64(p.rcvTime)) {
+			return
+		}
+	}
 	c.logger.Debugf("Received %d packets after sending CONNECTION_CLOSE. Retransmitting.", n)
 	c.sendPacket(p.remoteAddr, p.info)
 }
@@ -3,7 +3,9 @@ package quic
 import (
 	"net"
 	"testing"
+	"time"
 
+	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/utils"
 
 	"github.com/stretchr/testify/require"
@@ -11,7 +13,7 @@ import (
 
 func TestClosedLocalConnection(t *testing.T) {
 	written := make(chan net.Addr, 1)
-	conn := newClosedLocalConn(func(addr net.Addr, _ packetInfo) { written <- addr }, utils.DefaultLogger)
+	conn := newClosedLocalConn(func(addr net.Addr, _ packetInfo) { written <- addr }, 0, utils.DefaultLogger)
 	addr := &net.UDPAddr{IP: net.IPv4(127, 1, 2, 3), Port: 1337}
 	for i := 1; i <= 20; i++ {
 		conn.handlePacket(receivedPacket{remoteAddr: addr})
@@ -32,3 +34,24 @@ func TestClosedLocalConnection(t *testing.T) {
 		}
 	}
 }
+
+func TestClosedLocalConnectionRetransmitInterval(t *testing.T) {
+	var written int
+	conn := newClosedLocalConn(func(net.Addr, packetInfo) { written++ }, 100*time.Millisecond, utils.DefaultLogger)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 1, 2, 3), Port: 1337}
+
+	// a burst of packets only triggers a single retransmission
+	now := monotime.Now()
+	for range 100 {
+		conn.handlePacket(receivedPacket{remoteAddr: addr, rcvTime: now})
+	}
+	require.Equal(t, 1, written)
+
+	// a flood of packets, arriving every millisecond for one second
+	for i := range 1000 {
+		conn.handlePacket(receivedPacket{remoteAddr: addr, rcvTime: now.Add(time.Duration(i+1) * time.Millisecond)})
+	}
+	// at most one retransmission per interval
+	require.LessOrEqual(t, written, 1+10)
+	require.Greater(t, written, 1)
+}
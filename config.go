@@ -42,9 +42,18 @@ func validateConfig(config *Config) error {
 	if config.InitialPacketSize > 0 && config.InitialPacketSize < protocol.MinInitialPacketSize {
 		config.InitialPacketSize = protocol.MinInitialPacketSize
 	}
+	if config.InitialPacketIPTolerance < 0 {
+		config.InitialPacketIPTolerance = 0
+	}
 	if config.InitialPacketSize > protocol.MaxPacketBufferSize {
 		config.InitialPacketSize = protocol.MaxPacketBufferSize
 	}
+	if addr := config.PreferredAddress; addr != nil && (addr.Port == 0 || addr.IP == nil || addr.IP.IsUnspecified()) {
+		return fmt.Errorf("invalid preferred address: %s", addr)
+	}
+	if !config.CongestionControl.valid() {
+		return fmt.Errorf("invalid congestion control algorithm: %d", config.CongestionControl)
+	}
 	// check that all QUIC versions are actually supported
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
@@ -76,6 +85,18 @@ func populateConfig(config *Config) *Config {
 	if initialStreamReceiveWindow == 0 {
 		initialStreamReceiveWindow = protocol.DefaultInitialMaxStreamData
 	}
+	initialStreamReceiveWindowBidiLocal := config.InitialStreamReceiveWindowBidiLocal
+	if initialStreamReceiveWindowBidiLocal == 0 {
+		initialStreamReceiveWindowBidiLocal = initialStreamReceiveWindow
+	}
+	initialStreamReceiveWindowBidiRemote := config.InitialStreamReceiveWindowBidiRemote
+	if initialStreamReceiveWindowBidiRemote == 0 {
+		initialStreamReceiveWindowBidiRemote = initialStreamReceiveWindow
+	}
+	initialStreamReceiveWindowUni := config.InitialStreamReceiveWindowUni
+	if initialStreamReceiveWindowUni == 0 {
+		initialStreamReceiveWindowUni = initialStreamReceiveWindow
+	}
 	maxStreamReceiveWindow := config.MaxStreamReceiveWindow
 	if maxStreamReceiveWindow == 0 {
 		maxStreamReceiveWindow = protocol.DefaultMaxReceiveStreamFlowControlWindow
@@ -100,31 +121,96 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	handshakeQueueDepth := config.HandshakeQueueDepth
+	if handshakeQueueDepth <= 0 {
+		handshakeQueueDepth = protocol.DefaultHandshakeQueueDepth
+	}
+	receivePacketBudget := config.ReceivePacketBudget
+	if receivePacketBudget <= 0 {
+		receivePacketBudget = protocol.DefaultReceivePacketBudget
+	}
+	sendPacketBudget := config.SendPacketBudget
+	if sendPacketBudget <= 0 {
+		sendPacketBudget = protocol.DefaultSendPacketBudget
+	}
+	maxQueuedPackets := config.MaxQueuedPackets
+	if maxQueuedPackets <= 0 {
+		maxQueuedPackets = protocol.DefaultMaxQueuedPackets
+	}
+	connectivityDegradedPTOCount := config.ConnectivityDegradedPTOCount
+	if connectivityDegradedPTOCount <= 0 {
+		connectivityDegradedPTOCount = protocol.DefaultConnectivityDegradedPTOCount
+	}
 	initialPacketSize := config.InitialPacketSize
 	if initialPacketSize == 0 {
 		initialPacketSize = protocol.InitialPacketSize
 	}
 
 	return &Config{
-		GetConfigForClient:               config.GetConfigForClient,
-		Versions:                         versions,
-		HandshakeIdleTimeout:             handshakeIdleTimeout,
-		MaxIdleTimeout:                   idleTimeout,
-		KeepAlivePeriod:                  config.KeepAlivePeriod,
-		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
-		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
-		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
-		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
-		AllowConnectionWindowIncrease:    config.AllowConnectionWindowIncrease,
-		MaxIncomingStreams:               maxIncomingStreams,
-		MaxIncomingUniStreams:            maxIncomingUniStreams,
-		TokenStore:                       config.TokenStore,
-		EnableDatagrams:                  config.EnableDatagrams,
-		InitialPacketSize:                initialPacketSize,
-		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
-		EnableStreamResetPartialDelivery: config.EnableStreamResetPartialDelivery,
-		Allow0RTT:                        config.Allow0RTT,
-		CongestionControl:                config.CongestionControl,
-		Tracer:                           config.Tracer,
+		GetConfigForClient:                   config.GetConfigForClient,
+		Versions:                             versions,
+		HandshakeIdleTimeout:                 handshakeIdleTimeout,
+		MaxIdleTimeout:                       idleTimeout,
+		OnConnectivityDegraded:               config.OnConnectivityDegraded,
+		ConnectivityDegradedPTOCount:         connectivityDegradedPTOCount,
+		KeepAlivePeriod:                      config.KeepAlivePeriod,
+		InitialStreamReceiveWindow:           initialStreamReceiveWindow,
+		InitialStreamReceiveWindowBidiLocal:  initialStreamReceiveWindowBidiLocal,
+		InitialStreamReceiveWindowBidiRemote: initialStreamReceiveWindowBidiRemote,
+		InitialStreamReceiveWindowUni:        initialStreamReceiveWindowUni,
+		MaxStreamReceiveWindow:               maxStreamReceiveWindow,
+		InitialConnectionReceiveWindow:       initialConnectionReceiveWindow,
+		MaxConnectionReceiveWindow:           maxConnectionReceiveWindow,
+		AllowConnectionWindowIncrease:        config.AllowConnectionWindowIncrease,
+		EnableReceiveWindowDonation:          config.EnableReceiveWindowDonation,
+		MaxIncomingStreams:                   maxIncomingStreams,
+		MaxIncomingUniStreams:                maxIncomingUniStreams,
+		PredictiveStreamLimits:               config.PredictiveStreamLimits,
+		TokenStore:                           config.TokenStore,
+		DisableNewTokens:                     config.DisableNewTokens,
+		EnableDatagrams:                      config.EnableDatagrams,
+		InitialPacketSize:                    initialPacketSize,
+		CoalescedInitialPadding:              config.CoalescedInitialPadding,
+		DisablePathMTUDiscovery:              config.DisablePathMTUDiscovery,
+		EnableStreamResetPartialDelivery:     config.EnableStreamResetPartialDelivery,
+		Allow0RTT:                            config.Allow0RTT,
+		StrictPathValidation:                 config.StrictPathValidation,
+		InitialPacketIPTolerance:             config.InitialPacketIPTolerance,
+		HandshakeQueueDepth:                  handshakeQueueDepth,
+		HandshakeQueueStrategy:               config.HandshakeQueueStrategy,
+		DisablePeerMigration:                 config.DisablePeerMigration,
+		PreferredAddress:                     config.PreferredAddress,
+		VerifyPeerMigration:                  config.VerifyPeerMigration,
+		MaxPathChallengeRetransmissions:      config.MaxPathChallengeRetransmissions,
+		MaxIssuedConnectionIDs:               config.MaxIssuedConnectionIDs,
+		ConnectionCloseRetransmitInterval:    config.ConnectionCloseRetransmitInterval,
+		StrictMode:                           config.StrictMode,
+		ErrorSpaces:                          config.ErrorSpaces,
+		EnableParallelDecryption:             config.EnableParallelDecryption,
+		ReceivePacketBudget:                  receivePacketBudget,
+		SendPacketBudget:                     sendPacketBudget,
+		MaxQueuedPackets:                     maxQueuedPackets,
+		CoalesceAcks:                         config.CoalesceAcks,
+		MinimizeAckDelay:                     config.MinimizeAckDelay,
+		KeepReceiveBuffersOnClose:            config.KeepReceiveBuffersOnClose,
+		IgnoreLateStreamResets:               config.IgnoreLateStreamResets,
+		MaxSendBufferPerStream:               config.MaxSendBufferPerStream,
+		SendBufferMemoryPressureHook:         config.SendBufferMemoryPressureHook,
+		MaxStreamsPerPacket:                  config.MaxStreamsPerPacket,
+		PrioritizeRetransmissions:            config.PrioritizeRetransmissions,
+		MaxStreamOutOfOrderBuffer:            config.MaxStreamOutOfOrderBuffer,
+		StreamOutOfOrderBufferOverflow:       config.StreamOutOfOrderBufferOverflow,
+		StreamOutOfOrderBufferErrorCode:      config.StreamOutOfOrderBufferErrorCode,
+		MaxUnacceptedStreams:                 config.MaxUnacceptedStreams,
+		UnacceptedStreamsOverflow:            config.UnacceptedStreamsOverflow,
+		UnacceptedStreamsErrorCode:           config.UnacceptedStreamsErrorCode,
+		CongestionControl:                    config.CongestionControl,
+		CongestionControlSwitch:              config.CongestionControlSwitch,
+		EnableRuntimeTrace:                   config.EnableRuntimeTrace,
+		StreamEventHook:                      config.StreamEventHook,
+		StreamEventHookEnabled:               config.StreamEventHookEnabled,
+		ReceivedAckHook:                      config.ReceivedAckHook,
+		ReceivedAckHookCopy:                  config.ReceivedAckHookCopy,
+		Tracer:                               config.Tracer,
 	}
 }
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -65,6 +66,26 @@ func TestConfigValidation(t *testing.T) {
 		require.NoError(t, validateConfig(conf))
 		require.Equal(t, uint16(protocol.MaxPacketBufferSize), conf.InitialPacketSize)
 	})
+
+	t.Run("preferred address", func(t *testing.T) {
+		require.NoError(t, validateConfig(&Config{PreferredAddress: &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 443}}))
+		require.EqualError(t,
+			validateConfig(&Config{PreferredAddress: &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4)}}),
+			"invalid preferred address: 1.2.3.4:0",
+		)
+		require.EqualError(t,
+			validateConfig(&Config{PreferredAddress: &net.UDPAddr{IP: net.IPv6zero, Port: 443}}),
+			"invalid preferred address: [::]:443",
+		)
+	})
+
+	t.Run("congestion control", func(t *testing.T) {
+		require.NoError(t, validateConfig(&Config{CongestionControl: CUBIC}))
+		require.EqualError(t,
+			validateConfig(&Config{CongestionControl: 42}),
+			"invalid congestion control algorithm: 42",
+		)
+	})
 }
 
 func TestConfigHandshakeIdleTimeout(t *testing.T) {
@@ -86,7 +107,7 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 		}
 
 		switch fn := typ.Field(i).Name; fn {
-		case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer":
+		case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "VerifyPeerMigration", "OnConnectivityDegraded", "CongestionControlSwitch", "SendBufferMemoryPressureHook", "StreamEventHook", "ReceivedAckHook", "Tracer":
 			// Can't compare functions.
 		case "Versions":
 			f.Set(reflect.ValueOf([]Version{1, 2, 3}))
@@ -102,6 +123,12 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 			f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
 		case "InitialStreamReceiveWindow":
 			f.Set(reflect.ValueOf(uint64(1234)))
+		case "InitialStreamReceiveWindowBidiLocal":
+			f.Set(reflect.ValueOf(uint64(1235)))
+		case "InitialStreamReceiveWindowBidiRemote":
+			f.Set(reflect.ValueOf(uint64(1236)))
+		case "InitialStreamReceiveWindowUni":
+			f.Set(reflect.ValueOf(uint64(1237)))
 		case "MaxStreamReceiveWindow":
 			f.Set(reflect.ValueOf(uint64(9)))
 		case "InitialConnectionReceiveWindow":
@@ -112,6 +139,8 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 			f.Set(reflect.ValueOf(int64(11)))
 		case "MaxIncomingUniStreams":
 			f.Set(reflect.ValueOf(int64(12)))
+		case "PredictiveStreamLimits":
+			f.Set(reflect.ValueOf(true))
 		case "StatelessResetKey":
 			f.Set(reflect.ValueOf(&StatelessResetKey{1, 2, 3, 4}))
 		case "KeepAlivePeriod":
@@ -122,12 +151,84 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 			f.Set(reflect.ValueOf(true))
 		case "InitialPacketSize":
 			f.Set(reflect.ValueOf(uint16(1350)))
+		case "CoalescedInitialPadding":
+			f.Set(reflect.ValueOf(true))
 		case "DisablePathMTUDiscovery":
 			f.Set(reflect.ValueOf(true))
 		case "Allow0RTT":
 			f.Set(reflect.ValueOf(true))
 		case "EnableStreamResetPartialDelivery":
 			f.Set(reflect.ValueOf(true))
+		case "EnableReceiveWindowDonation":
+			f.Set(reflect.ValueOf(true))
+		case "CongestionControl":
+			f.Set(reflect.ValueOf(CUBIC))
+		case "StrictPathValidation":
+			f.Set(reflect.ValueOf(true))
+		case "InitialPacketIPTolerance":
+			f.Set(reflect.ValueOf(1))
+		case "HandshakeQueueDepth":
+			f.Set(reflect.ValueOf(1000))
+		case "ReceivePacketBudget":
+			f.Set(reflect.ValueOf(64))
+		case "SendPacketBudget":
+			f.Set(reflect.ValueOf(16))
+		case "ConnectivityDegradedPTOCount":
+			f.Set(reflect.ValueOf(5))
+		case "MaxQueuedPackets":
+			f.Set(reflect.ValueOf(32))
+		case "HandshakeQueueStrategy":
+			f.Set(reflect.ValueOf(HandshakeQueueSourceIPDiverse))
+		case "DisablePeerMigration":
+			f.Set(reflect.ValueOf(true))
+		case "PreferredAddress":
+			f.Set(reflect.ValueOf(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 443}))
+		case "MaxPathChallengeRetransmissions":
+			f.Set(reflect.ValueOf(3))
+		case "MaxIssuedConnectionIDs":
+			f.Set(reflect.ValueOf(uint64(100)))
+		case "ConnectionCloseRetransmitInterval":
+			f.Set(reflect.ValueOf(time.Second))
+		case "StrictMode":
+			f.Set(reflect.ValueOf(true))
+		case "ErrorSpaces":
+			f.Set(reflect.ValueOf(&ErrorSpaces{}))
+		case "CoalesceAcks":
+			f.Set(reflect.ValueOf(true))
+		case "MinimizeAckDelay":
+			f.Set(reflect.ValueOf(true))
+		case "EnableParallelDecryption":
+			f.Set(reflect.ValueOf(true))
+		case "KeepReceiveBuffersOnClose":
+			f.Set(reflect.ValueOf(true))
+		case "IgnoreLateStreamResets":
+			f.Set(reflect.ValueOf(true))
+		case "EnableRuntimeTrace":
+			f.Set(reflect.ValueOf(true))
+		case "DisableNewTokens":
+			f.Set(reflect.ValueOf(true))
+		case "MaxSendBufferPerStream":
+			f.Set(reflect.ValueOf(uint64(1 << 20)))
+		case "MaxStreamsPerPacket":
+			f.Set(reflect.ValueOf(4))
+		case "PrioritizeRetransmissions":
+			f.Set(reflect.ValueOf(true))
+		case "MaxStreamOutOfOrderBuffer":
+			f.Set(reflect.ValueOf(uint64(1 << 18)))
+		case "StreamOutOfOrderBufferOverflow":
+			f.Set(reflect.ValueOf(OutOfOrderBufferOverflowReset))
+		case "StreamOutOfOrderBufferErrorCode":
+			f.Set(reflect.ValueOf(StreamErrorCode(42)))
+		case "MaxUnacceptedStreams":
+			f.Set(reflect.ValueOf(int64(16)))
+		case "UnacceptedStreamsOverflow":
+			f.Set(reflect.ValueOf(UnacceptedStreamsOverflowReset))
+		case "UnacceptedStreamsErrorCode":
+			f.Set(reflect.ValueOf(StreamErrorCode(43)))
+		case "StreamEventHookEnabled":
+			f.Set(reflect.ValueOf(true))
+		case "ReceivedAckHookCopy":
+			f.Set(reflect.ValueOf(true))
 		default:
 			t.Fatalf("all fields must be accounted for, but saw unknown field %q", fn)
 		}
@@ -137,10 +238,22 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 
 func TestConfigClone(t *testing.T) {
 	t.Run("function fields", func(t *testing.T) {
-		var calledAllowConnectionWindowIncrease, calledTracer bool
+		var calledAllowConnectionWindowIncrease, calledOnConnectivityDegraded, calledStreamEventHook, calledTracer bool
+		var calledCongestionControlSwitch, calledReceivedAckHook bool
 		c1 := &Config{
 			GetConfigForClient:            func(info *ClientInfo) (*Config, error) { return nil, assert.AnError },
 			AllowConnectionWindowIncrease: func(*Conn, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
+			VerifyPeerMigration: func(*Conn, net.Addr, net.Addr) (PeerMigrationAction, error) {
+				return PeerMigrationRevalidate, assert.AnError
+			},
+			OnConnectivityDegraded: func(*Conn) { calledOnConnectivityDegraded = true },
+			CongestionControlSwitch: func(time.Duration, ConnectionStats) (CongestionControlAlgorithm, bool) {
+				calledCongestionControlSwitch = true
+				return CUBIC, true
+			},
+			SendBufferMemoryPressureHook: func() float64 { return 0.42 },
+			StreamEventHook:              func(StreamEvent) { calledStreamEventHook = true },
+			ReceivedAckHook:              func(*ReceivedAck) { calledReceivedAckHook = true },
 			Tracer: func(context.Context, bool, ConnectionID) qlogwriter.Trace {
 				calledTracer = true
 				return nil
@@ -153,6 +266,20 @@ func TestConfigClone(t *testing.T) {
 		require.ErrorIs(t, err, assert.AnError)
 		c2.Tracer(context.Background(), true, protocol.ConnectionID{})
 		require.True(t, calledTracer)
+		action, err := c2.VerifyPeerMigration(nil, nil, nil)
+		require.Equal(t, PeerMigrationRevalidate, action)
+		require.ErrorIs(t, err, assert.AnError)
+		c2.OnConnectivityDegraded(nil)
+		require.True(t, calledOnConnectivityDegraded)
+		alg, ok := c2.CongestionControlSwitch(time.Minute, ConnectionStats{})
+		require.True(t, ok)
+		require.Equal(t, CUBIC, alg)
+		require.True(t, calledCongestionControlSwitch)
+		require.Equal(t, 0.42, c2.SendBufferMemoryPressureHook())
+		c2.StreamEventHook(StreamEvent{})
+		require.True(t, calledStreamEventHook)
+		c2.ReceivedAckHook(&ReceivedAck{})
+		require.True(t, calledReceivedAckHook)
 	})
 
 	t.Run("non-function fields", func(t *testing.T) {
@@ -179,13 +306,28 @@ func TestConfigDefaultValues(t *testing.T) {
 	require.Equal(t, protocol.DefaultHandshakeIdleTimeout, c.HandshakeIdleTimeout)
 	require.Equal(t, protocol.DefaultIdleTimeout, c.MaxIdleTimeout)
 	require.EqualValues(t, protocol.DefaultInitialMaxStreamData, c.InitialStreamReceiveWindow)
+	require.EqualValues(t, protocol.DefaultInitialMaxStreamData, c.InitialStreamReceiveWindowBidiLocal)
+	require.EqualValues(t, protocol.DefaultInitialMaxStreamData, c.InitialStreamReceiveWindowBidiRemote)
+	require.EqualValues(t, protocol.DefaultInitialMaxStreamData, c.InitialStreamReceiveWindowUni)
 	require.EqualValues(t, protocol.DefaultMaxReceiveStreamFlowControlWindow, c.MaxStreamReceiveWindow)
 	require.EqualValues(t, protocol.DefaultInitialMaxData, c.InitialConnectionReceiveWindow)
 	require.EqualValues(t, protocol.DefaultMaxReceiveConnectionFlowControlWindow, c.MaxConnectionReceiveWindow)
 	require.EqualValues(t, protocol.DefaultMaxIncomingStreams, c.MaxIncomingStreams)
 	require.EqualValues(t, protocol.DefaultMaxIncomingUniStreams, c.MaxIncomingUniStreams)
+	require.Equal(t, protocol.DefaultHandshakeQueueDepth, c.HandshakeQueueDepth)
+	require.Equal(t, protocol.DefaultReceivePacketBudget, c.ReceivePacketBudget)
+	require.Equal(t, protocol.DefaultSendPacketBudget, c.SendPacketBudget)
+	require.Equal(t, protocol.DefaultMaxQueuedPackets, c.MaxQueuedPackets)
+	require.Equal(t, protocol.DefaultConnectivityDegradedPTOCount, c.ConnectivityDegradedPTOCount)
+	require.Equal(t, HandshakeQueueFIFO, c.HandshakeQueueStrategy)
 	require.False(t, c.DisablePathMTUDiscovery)
 	require.Nil(t, c.GetConfigForClient)
+
+	// the per-stream-type windows default to the InitialStreamReceiveWindow
+	c = populateConfig(&Config{InitialStreamReceiveWindow: 1234, InitialStreamReceiveWindowUni: 5678})
+	require.EqualValues(t, 1234, c.InitialStreamReceiveWindowBidiLocal)
+	require.EqualValues(t, 1234, c.InitialStreamReceiveWindowBidiRemote)
+	require.EqualValues(t, 5678, c.InitialStreamReceiveWindowUni)
 }
 
 func TestConfigZeroLimits(t *testing.T) {
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"time"
@@ -14,7 +15,7 @@ import (
 type connRunnerCallbacks struct {
 	AddConnectionID    func(protocol.ConnectionID)
 	RemoveConnectionID func(protocol.ConnectionID)
-	ReplaceWithClosed  func([]protocol.ConnectionID, []byte, time.Duration)
+	ReplaceWithClosed  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration)
 }
 
 // The memory address of the Transport is used as the key.
@@ -32,9 +33,9 @@ func (cr connRunners) RemoveConnectionID(id protocol.ConnectionID) {
 	}
 }
 
-func (cr connRunners) ReplaceWithClosed(ids []protocol.ConnectionID, b []byte, expiry time.Duration) {
+func (cr connRunners) ReplaceWithClosed(ids []protocol.ConnectionID, b []byte, retransmitInterval, expiry time.Duration) {
 	for _, c := range cr {
-		c.ReplaceWithClosed(ids, b, expiry)
+		c.ReplaceWithClosed(ids, b, retransmitInterval, expiry)
 	}
 }
 
@@ -48,6 +49,11 @@ type connIDGenerator struct {
 	highestSeq  uint64
 	connRunners connRunners
 
+	// maxIssued is the maximum number of connection IDs issued in NEW_CONNECTION_ID frames.
+	// 0 means that there's no limit.
+	maxIssued uint64
+	numIssued uint64
+
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	connIDsToRetire         []connIDToRetire       // sorted by t
 	initialClientDestConnID *protocol.ConnectionID // nil for the client
@@ -65,9 +71,11 @@ func newConnIDGenerator(
 	callbacks connRunnerCallbacks,
 	queueControlFrame func(wire.Frame),
 	generator ConnectionIDGenerator,
+	maxIssued uint64,
 ) *connIDGenerator {
 	m := &connIDGenerator{
 		generator:         generator,
+		maxIssued:         maxIssued,
 		activeSrcConnIDs:  make(map[uint64]protocol.ConnectionID),
 		statelessResetter: statelessResetter,
 		connRunners:       map[connRunner]connRunnerCallbacks{runner: callbacks},
@@ -86,8 +94,6 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
 	for i := uint64(len(m.activeSrcConnIDs)); i < min(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
@@ -134,7 +140,29 @@ func (m *connIDGenerator) queueConnIDForRetiring(connID protocol.ConnectionID, e
 	m.connIDsToRetire = slices.Insert(m.connIDsToRetire, idx, connIDToRetire{t: expiry, connID: connID})
 }
 
+// IssuePreferredAddressConnID issues the connection ID for the preferred_address transport parameter.
+// It must be called before any other connection IDs are issued,
+// since this connection ID has the sequence number 1.
+func (m *connIDGenerator) IssuePreferredAddressConnID() (protocol.ConnectionID, protocol.StatelessResetToken, error) {
+	if m.highestSeq != 0 {
+		return protocol.ConnectionID{}, protocol.StatelessResetToken{}, errors.New("connection IDs already issued")
+	}
+	connID, err := m.generator.GenerateConnectionID()
+	if err != nil {
+		return protocol.ConnectionID{}, protocol.StatelessResetToken{}, err
+	}
+	m.highestSeq++
+	m.activeSrcConnIDs[m.highestSeq] = connID
+	m.connRunners.AddConnectionID(connID)
+	return connID, m.statelessResetter.GetStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) issueNewConnID() error {
+	// Once the limit is reached, we stop rotating connection IDs.
+	// The peer keeps using the connection IDs that are still active.
+	if m.maxIssued > 0 && m.numIssued >= m.maxIssued {
+		return nil
+	}
 	connID, err := m.generator.GenerateConnectionID()
 	if err != nil {
 		return err
@@ -147,6 +175,7 @@ func (m *connIDGenerator) issueNewConnID() error {
 		StatelessResetToken: m.statelessResetter.GetStatelessResetToken(connID),
 	})
 	m.highestSeq++
+	m.numIssued++
 	return nil
 }
 
@@ -182,7 +211,7 @@ func (m *connIDGenerator) RemoveAll() {
 	}
 }
 
-func (m *connIDGenerator) ReplaceWithClosed(connClose []byte, expiry time.Duration) {
+func (m *connIDGenerator) ReplaceWithClosed(connClose []byte, retransmitInterval, expiry time.Duration) {
 	connIDs := make([]protocol.ConnectionID, 0, len(m.activeSrcConnIDs)+len(m.connIDsToRetire)+1)
 	if m.initialClientDestConnID != nil {
 		connIDs = append(connIDs, *m.initialClientDestConnID)
@@ -193,7 +222,7 @@ func (m *connIDGenerator) ReplaceWithClosed(connClose []byte, expiry time.Durati
 	for _, c := range m.connIDsToRetire {
 		connIDs = append(connIDs, c.connID)
 	}
-	m.connRunners.ReplaceWithClosed(connIDs, connClose, expiry)
+	m.connRunners.ReplaceWithClosed(connIDs, connClose, retransmitInterval, expiry)
 }
 
 func (m *connIDGenerator) AddConnRunner(runner connRunner, r connRunnerCallbacks) {
@@ -210,3 +239,52 @@ func (m *connIDGenerator) AddConnRunner(runner connRunner, r connRunnerCallbacks
 		r.AddConnectionID(connID)
 	}
 }
+
+// maxConnIDGenerationAttempts is the number of times a connection ID of the wrong length
+// is generated anew before giving up.
+const maxConnIDGenerationAttempts = 3
+
+// A fixedLengthConnIDGenerator wraps a ConnectionIDGenerator,
+// and makes sure that all generated connection IDs have the same length.
+// It is used when Transport.EnforceConnectionIDLength is set.
+type fixedLengthConnIDGenerator struct {
+	ConnectionIDGenerator
+	length int
+}
+
+// newFixedLengthConnIDGenerator checks that the generator is configured for, and actually generates,
+// connection IDs of the configured length. If configuredLen is 0, the generator's ConnectionIDLen is used.
+func newFixedLengthConnIDGenerator(gen ConnectionIDGenerator, configuredLen int) (*fixedLengthConnIDGenerator, error) {
+	l := gen.ConnectionIDLen()
+	if l < 0 || l > protocol.MaxConnIDLen {
+		return nil, fmt.Errorf("quic: invalid connection ID length: %d", l)
+	}
+	if configuredLen != 0 && configuredLen != l {
+		return nil, fmt.Errorf("quic: ConnectionIDGenerator uses a connection ID length of %d, expected %d", l, configuredLen)
+	}
+	connID, err := gen.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	if connID.Len() != l {
+		return nil, fmt.Errorf("quic: ConnectionIDGenerator generated a connection ID of length %d, expected %d", connID.Len(), l)
+	}
+	return &fixedLengthConnIDGenerator{ConnectionIDGenerator: gen, length: l}, nil
+}
+
+func (g *fixedLengthConnIDGenerator) GenerateConnectionID() (protocol.ConnectionID, error) {
+	var l int
+	for range maxConnIDGenerationAttempts {
+		connID, err := g.ConnectionIDGenerator.GenerateConnectionID()
+		if err != nil {
+			return protocol.ConnectionID{}, err
+		}
+		if connID.Len() == g.length {
+			return connID, nil
+		}
+		l = connID.Len()
+	}
+	return protocol.ConnectionID{}, fmt.Errorf("quic: ConnectionIDGenerator generated a connection ID of length %d, expected %d", l, g.length)
+}
+
+func (g *fixedLengthConnIDGenerator) ConnectionIDLen() int { return g.length }
@@ -42,10 +42,11 @@ func testConnIDGeneratorIssueAndRetire(t *testing.T, hasInitialClientDestConnID
 		connRunnerCallbacks{
 			AddConnectionID:    func(c protocol.ConnectionID) { added = append(added, c) },
 			RemoveConnectionID: func(c protocol.ConnectionID) { removed = append(removed, c) },
-			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration) {},
+			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration) {},
 		},
 		func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
 		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
 	)
 
 	require.Empty(t, added)
@@ -104,6 +105,43 @@ func testConnIDGeneratorIssueAndRetire(t *testing.T, hasInitialClientDestConnID
 	require.Empty(t, removed)
 }
 
+func TestConnIDGeneratorPreferredAddress(t *testing.T) {
+	var added []protocol.ConnectionID
+	var queuedFrames []wire.Frame
+	sr := newStatelessResetter(&StatelessResetKey{1, 2, 3, 4})
+	g := newConnIDGenerator(
+		&packetHandlerMap{},
+		protocol.ParseConnectionID([]byte{1, 1, 1, 1}),
+		nil,
+		sr,
+		connRunnerCallbacks{
+			AddConnectionID:    func(c protocol.ConnectionID) { added = append(added, c) },
+			RemoveConnectionID: func(protocol.ConnectionID) {},
+			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration) {},
+		},
+		func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
+	)
+
+	connID, token, err := g.IssuePreferredAddressConnID()
+	require.NoError(t, err)
+	require.Equal(t, []protocol.ConnectionID{connID}, added)
+	require.Equal(t, sr.GetStatelessResetToken(connID), token)
+	// the connection ID is sent in the transport parameters, not in a NEW_CONNECTION_ID frame
+	require.Empty(t, queuedFrames)
+
+	// the connection ID counts towards the active_connection_id_limit
+	require.NoError(t, g.SetMaxActiveConnIDs(4))
+	require.Len(t, queuedFrames, 2)
+	for i, f := range queuedFrames {
+		require.EqualValues(t, i+2, f.(*wire.NewConnectionIDFrame).SequenceNumber)
+	}
+
+	_, _, err = g.IssuePreferredAddressConnID()
+	require.Error(t, err)
+}
+
 func TestConnIDGeneratorRetiring(t *testing.T) {
 	initialConnID := protocol.ParseConnectionID([]byte{2, 2, 2, 2})
 	var added, removed []protocol.ConnectionID
@@ -115,10 +153,11 @@ func TestConnIDGeneratorRetiring(t *testing.T) {
 		connRunnerCallbacks{
 			AddConnectionID:    func(c protocol.ConnectionID) { added = append(added, c) },
 			RemoveConnectionID: func(c protocol.ConnectionID) { removed = append(removed, c) },
-			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration) {},
+			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration) {},
 		},
 		func(f wire.Frame) {},
 		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
 	)
 	require.NoError(t, g.SetMaxActiveConnIDs(6))
 	require.Empty(t, removed)
@@ -151,6 +190,43 @@ func TestConnIDGeneratorRetiring(t *testing.T) {
 	}
 }
 
+func TestConnIDGeneratorMaxIssued(t *testing.T) {
+	var queuedFrames []wire.Frame
+	g := newConnIDGenerator(
+		&packetHandlerMap{},
+		protocol.ParseConnectionID([]byte{1, 1, 1, 1}),
+		nil,
+		newStatelessResetter(&StatelessResetKey{1, 2, 3, 4}),
+		connRunnerCallbacks{
+			AddConnectionID:    func(protocol.ConnectionID) {},
+			RemoveConnectionID: func(protocol.ConnectionID) {},
+			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration) {},
+		},
+		func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		5,
+	)
+	require.NoError(t, g.SetMaxActiveConnIDs(4))
+	require.Len(t, queuedFrames, 3)
+
+	// the first two retired connection IDs are replaced
+	now := monotime.Now()
+	require.NoError(t, g.Retire(1, protocol.ParseConnectionID([]byte{9, 9, 9, 9}), now))
+	require.NoError(t, g.Retire(2, protocol.ParseConnectionID([]byte{9, 9, 9, 9}), now))
+	require.Len(t, queuedFrames, 5)
+	require.EqualValues(t, 5, queuedFrames[4].(*wire.NewConnectionIDFrame).SequenceNumber)
+
+	// the limit is reached, no more connection IDs are issued
+	queuedFrames = queuedFrames[:0]
+	for _, seq := range []uint64{3, 4, 5} {
+		require.NoError(t, g.Retire(seq, protocol.ParseConnectionID([]byte{9, 9, 9, 9}), now))
+	}
+	require.Empty(t, queuedFrames)
+	// a higher active_connection_id_limit doesn't lead to new connection IDs either
+	require.NoError(t, g.SetMaxActiveConnIDs(8))
+	require.Empty(t, queuedFrames)
+}
+
 func TestConnIDGeneratorRemoveAll(t *testing.T) {
 	t.Run("with initial client destination connection ID", func(t *testing.T) {
 		testConnIDGeneratorRemoveAll(t, true)
@@ -178,10 +254,11 @@ func testConnIDGeneratorRemoveAll(t *testing.T, hasInitialClientDestConnID bool)
 		connRunnerCallbacks{
 			AddConnectionID:    func(c protocol.ConnectionID) { added = append(added, c) },
 			RemoveConnectionID: func(c protocol.ConnectionID) { removed = append(removed, c) },
-			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration) {},
+			ReplaceWithClosed:  func([]protocol.ConnectionID, []byte, time.Duration, time.Duration) {},
 		},
 		func(f wire.Frame) {},
 		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
 	)
 
 	require.NoError(t, g.SetMaxActiveConnIDs(1000))
@@ -228,13 +305,14 @@ func testConnIDGeneratorReplaceWithClosed(t *testing.T, hasInitialClientDestConn
 		connRunnerCallbacks{
 			AddConnectionID:    func(c protocol.ConnectionID) { added = append(added, c) },
 			RemoveConnectionID: func(c protocol.ConnectionID) { t.Fatal("didn't expect conn ID removals") },
-			ReplaceWithClosed: func(connIDs []protocol.ConnectionID, b []byte, _ time.Duration) {
+			ReplaceWithClosed: func(connIDs []protocol.ConnectionID, b []byte, _, _ time.Duration) {
 				replaced = connIDs
 				replacedWith = b
 			},
 		},
 		func(f wire.Frame) {},
 		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
 	)
 
 	require.NoError(t, g.SetMaxActiveConnIDs(1000))
@@ -245,7 +323,7 @@ func testConnIDGeneratorReplaceWithClosed(t *testing.T, hasInitialClientDestConn
 	require.NoError(t, g.Retire(4, protocol.ParseConnectionID([]byte{1, 1, 1, 1}), monotime.Now()))
 	require.Len(t, added, protocol.MaxIssuedConnectionIDs+1)
 
-	g.ReplaceWithClosed([]byte("foobar"), time.Second)
+	g.ReplaceWithClosed([]byte("foobar"), 0, time.Second)
 	if hasInitialClientDestConnID {
 		require.Len(t, replaced, protocol.MaxIssuedConnectionIDs+3)
 		require.Contains(t, replaced, *initialClientDestConnID)
@@ -271,21 +349,21 @@ func TestConnIDGeneratorAddConnRunner(t *testing.T) {
 	runner1 := connRunnerCallbacks{
 		AddConnectionID:    func(c protocol.ConnectionID) { tracker1.added = append(tracker1.added, c) },
 		RemoveConnectionID: func(c protocol.ConnectionID) { tracker1.removed = append(tracker1.removed, c) },
-		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _ time.Duration) {
+		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _, _ time.Duration) {
 			tracker1.replaced = append(tracker1.replaced, connIDs...)
 		},
 	}
 	runner2 := connRunnerCallbacks{
 		AddConnectionID:    func(c protocol.ConnectionID) { tracker2.added = append(tracker2.added, c) },
 		RemoveConnectionID: func(c protocol.ConnectionID) { tracker2.removed = append(tracker2.removed, c) },
-		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _ time.Duration) {
+		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _, _ time.Duration) {
 			tracker2.replaced = append(tracker2.replaced, connIDs...)
 		},
 	}
 	runner3 := connRunnerCallbacks{
 		AddConnectionID:    func(c protocol.ConnectionID) { tracker3.added = append(tracker3.added, c) },
 		RemoveConnectionID: func(c protocol.ConnectionID) { tracker3.removed = append(tracker3.removed, c) },
-		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _ time.Duration) {
+		ReplaceWithClosed: func(connIDs []protocol.ConnectionID, _ []byte, _, _ time.Duration) {
 			tracker3.replaced = append(tracker3.replaced, connIDs...)
 		},
 	}
@@ -302,6 +380,7 @@ func TestConnIDGeneratorAddConnRunner(t *testing.T) {
 		runner1,
 		func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
 		&protocol.DefaultConnectionIDGenerator{ConnLen: 5},
+		0,
 	)
 	require.NoError(t, g.SetMaxActiveConnIDs(3))
 	require.Len(t, tracker1.added, 2)
@@ -338,7 +417,7 @@ func TestConnIDGeneratorAddConnRunner(t *testing.T) {
 	require.Equal(t, []protocol.ConnectionID{clientDestConnID}, tracker1.removed)
 	require.Equal(t, []protocol.ConnectionID{clientDestConnID}, tracker2.removed)
 
-	g.ReplaceWithClosed([]byte("connection closed"), time.Second)
+	g.ReplaceWithClosed([]byte("connection closed"), 0, time.Second)
 	require.True(t, len(tracker1.replaced) > 0)
 	require.Equal(t, tracker1.replaced, tracker2.replaced)
 
@@ -348,3 +427,58 @@ func TestConnIDGeneratorAddConnRunner(t *testing.T) {
 	require.NotEmpty(t, tracker1.removed)
 	require.Equal(t, tracker1.removed, tracker2.removed)
 }
+
+type connIDLengthsGenerator struct {
+	lengths []int // lengths of the connection IDs to generate, the last one is repeated
+	connLen int   // the length returned by ConnectionIDLen
+}
+
+func (g *connIDLengthsGenerator) GenerateConnectionID() (protocol.ConnectionID, error) {
+	l := g.lengths[0]
+	if len(g.lengths) > 1 {
+		g.lengths = g.lengths[1:]
+	}
+	return protocol.GenerateConnectionID(l)
+}
+
+func (g *connIDLengthsGenerator) ConnectionIDLen() int { return g.connLen }
+
+func TestFixedLengthConnIDGenerator(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		gen, err := newFixedLengthConnIDGenerator(&connIDLengthsGenerator{lengths: []int{8}, connLen: 8}, 0)
+		require.NoError(t, err)
+		require.Equal(t, 8, gen.ConnectionIDLen())
+		connID, err := gen.GenerateConnectionID()
+		require.NoError(t, err)
+		require.Equal(t, 8, connID.Len())
+	})
+
+	t.Run("mismatching configured length", func(t *testing.T) {
+		_, err := newFixedLengthConnIDGenerator(&connIDLengthsGenerator{lengths: []int{8}, connLen: 8}, 6)
+		require.EqualError(t, err, "quic: ConnectionIDGenerator uses a connection ID length of 8, expected 6")
+	})
+
+	t.Run("invalid length", func(t *testing.T) {
+		_, err := newFixedLengthConnIDGenerator(&connIDLengthsGenerator{lengths: []int{8}, connLen: 21}, 0)
+		require.EqualError(t, err, "quic: invalid connection ID length: 21")
+	})
+
+	t.Run("mismatching generated length", func(t *testing.T) {
+		_, err := newFixedLengthConnIDGenerator(&connIDLengthsGenerator{lengths: []int{6}, connLen: 8}, 8)
+		require.EqualError(t, err, "quic: ConnectionIDGenerator generated a connection ID of length 6, expected 8")
+	})
+
+	t.Run("regenerating", func(t *testing.T) {
+		g := &connIDLengthsGenerator{lengths: []int{8, 5, 6, 8, 5}, connLen: 8}
+		gen, err := newFixedLengthConnIDGenerator(g, 8)
+		require.NoError(t, err)
+		// the connection IDs of length 5 and 6 are discarded
+		connID, err := gen.GenerateConnectionID()
+		require.NoError(t, err)
+		require.Equal(t, 8, connID.Len())
+		require.Equal(t, []int{5}, g.lengths)
+		// give up after too many attempts
+		_, err = gen.GenerateConnectionID()
+		require.EqualError(t, err, "quic: ConnectionIDGenerator generated a connection ID of length 5, expected 8")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"slices"
 
+	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
@@ -35,9 +36,12 @@ type connIDManager struct {
 	packetsSinceLastChange uint32
 	packetsPerConnectionID uint32
 
+	// the number of RETIRE_CONNECTION_ID frames that haven't been acknowledged yet
+	numUnacknowledgedRetired int
+
 	addStatelessResetToken    func(protocol.StatelessResetToken)
 	removeStatelessResetToken func(protocol.StatelessResetToken)
-	queueControlFrame         func(wire.Frame)
+	queueControlFrame         func(ackhandler.Frame)
 
 	closed bool
 }
@@ -46,7 +50,7 @@ func newConnIDManager(
 	initialDestConnID protocol.ConnectionID,
 	addStatelessResetToken func(protocol.StatelessResetToken),
 	removeStatelessResetToken func(protocol.StatelessResetToken),
-	queueControlFrame func(wire.Frame),
+	queueControlFrame func(ackhandler.Frame),
 ) *connIDManager {
 	return &connIDManager{
 		activeConnectionID:        initialDestConnID,
@@ -65,9 +69,19 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
 	}
-	if len(h.queue) >= protocol.MaxActiveConnectionIDs {
+	// Connection IDs used for probing paths are still active, and count towards the limit.
+	if len(h.queue)+len(h.pathProbing) >= protocol.MaxActiveConnectionIDs {
 		return &qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}
 	}
+	// RFC 9000, Section 5.1.2:
+	// An endpoint SHOULD limit the number of connection IDs it has retired locally
+	// for which RETIRE_CONNECTION_ID frames have not yet been acknowledged.
+	if h.numUnacknowledgedRetired > protocol.MaxUnacknowledgedRetiredConnectionIDs {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ConnectionIDLimitError,
+			ErrorMessage: "too many unacknowledged RETIRE_CONNECTION_ID frames",
+		}
+	}
 	return nil
 }
 
@@ -81,18 +95,14 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequence number is smaller than the currently active
 	// connection ID or if it was already retired, send the RETIRE_CONNECTION_ID frame immediately.
 	if f.SequenceNumber < max(h.activeSequenceNumber, h.highestProbingID) || f.SequenceNumber < h.highestRetired {
-		h.queueControlFrame(&wire.RetireConnectionIDFrame{
-			SequenceNumber: f.SequenceNumber,
-		})
+		h.retire(f.SequenceNumber)
 		return nil
 	}
 
 	if f.RetirePriorTo != 0 && h.pathProbing != nil {
 		for id, entry := range h.pathProbing {
 			if entry.SequenceNumber < f.RetirePriorTo {
-				h.queueControlFrame(&wire.RetireConnectionIDFrame{
-					SequenceNumber: entry.SequenceNumber,
-				})
+				h.retire(entry.SequenceNumber)
 				h.removeStatelessResetToken(entry.StatelessResetToken)
 				delete(h.pathProbing, id)
 			}
@@ -106,7 +116,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 			if entry.SequenceNumber >= f.RetirePriorTo {
 				newQueue = append(newQueue, entry)
 			} else {
-				h.queueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: entry.SequenceNumber})
+				h.retire(entry.SequenceNumber)
 			}
 		}
 		h.queue = newQueue
@@ -165,11 +175,17 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 	return nil // unreachable
 }
 
+func (h *connIDManager) retire(seq uint64) {
+	h.numUnacknowledgedRetired++
+	h.queueControlFrame(ackhandler.Frame{
+		Frame:   &wire.RetireConnectionIDFrame{SequenceNumber: seq},
+		Handler: (*connIDManagerAckHandler)(h),
+	})
+}
+
 func (h *connIDManager) updateConnectionID() {
 	h.assertNotClosed()
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: h.activeSequenceNumber,
-	})
+	h.retire(h.activeSequenceNumber)
 	h.highestRetired = max(h.highestRetired, h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -287,9 +303,7 @@ func (h *connIDManager) RetireConnIDForPath(pathID pathID) {
 	if !ok {
 		return
 	}
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: entry.SequenceNumber,
-	})
+	h.retire(entry.SequenceNumber)
 	h.removeStatelessResetToken(entry.StatelessResetToken)
 	delete(h.pathProbing, pathID)
 }
@@ -310,6 +324,18 @@ func (h *connIDManager) IsActiveStatelessResetToken(token protocol.StatelessRese
 	return false
 }
 
+type connIDManagerAckHandler connIDManager
+
+var _ ackhandler.FrameHandler = &connIDManagerAckHandler{}
+
+func (h *connIDManagerAckHandler) OnAcked(wire.Frame) {
+	h.numUnacknowledgedRetired--
+}
+
+func (h *connIDManagerAckHandler) OnLost(f wire.Frame) {
+	h.queueControlFrame(ackhandler.Frame{Frame: f, Handler: h})
+}
+
 // Using the connIDManager after it has been closed can have disastrous effects:
 // If the connection ID is rotated, a new entry would be inserted into the packet handler map,
 // leading to a memory leak of the connection struct.
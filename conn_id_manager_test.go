@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"testing"
 
+	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/wire"
@@ -24,7 +25,7 @@ func TestConnIDManagerAddConnIDs(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(protocol.StatelessResetToken) {},
 		func(protocol.StatelessResetToken) {},
-		func(wire.Frame) {},
+		func(ackhandler.Frame) {},
 	)
 	f1 := &wire.NewConnectionIDFrame{
 		SequenceNumber:      1,
@@ -73,7 +74,7 @@ func TestConnIDManagerLimit(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(protocol.StatelessResetToken) {},
 		func(protocol.StatelessResetToken) {},
-		func(f wire.Frame) {},
+		func(ackhandler.Frame) {},
 	)
 	for i := uint8(1); i < protocol.MaxActiveConnectionIDs; i++ {
 		require.NoError(t, m.Add(&wire.NewConnectionIDFrame{
@@ -89,13 +90,72 @@ func TestConnIDManagerLimit(t *testing.T) {
 	}))
 }
 
+func TestConnIDManagerLimitPathProbing(t *testing.T) {
+	m := newConnIDManager(
+		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		func(protocol.StatelessResetToken) {},
+		func(protocol.StatelessResetToken) {},
+		func(ackhandler.Frame) {},
+	)
+	for i := uint8(1); i < protocol.MaxActiveConnectionIDs; i++ {
+		require.NoError(t, m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      uint64(i),
+			ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+			StatelessResetToken: protocol.StatelessResetToken{i, i, i, i, i, i, i, i, i, i, i, i, i, i, i, i},
+		}))
+	}
+	// connection IDs used for path probing are still active
+	_, ok := m.GetConnIDForPath(1)
+	require.True(t, ok)
+	require.Equal(t, &qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}, m.Add(&wire.NewConnectionIDFrame{
+		SequenceNumber:      uint64(9999),
+		ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		StatelessResetToken: protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}))
+}
+
+func TestConnIDManagerUnacknowledgedRetireConnectionIDLimit(t *testing.T) {
+	var frameQueue []ackhandler.Frame
+	m := newConnIDManager(
+		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		func(protocol.StatelessResetToken) {},
+		func(protocol.StatelessResetToken) {},
+		func(f ackhandler.Frame) { frameQueue = append(frameQueue, f) },
+	)
+	// Every NEW_CONNECTION_ID frame retires all previous connection IDs.
+	// If the peer doesn't acknowledge the RETIRE_CONNECTION_ID frames, it exceeds the limit.
+	addConnID := func(seq uint64) error {
+		return m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: seq,
+			RetirePriorTo:  seq,
+			ConnectionID:   protocol.ParseConnectionID([]byte{byte(seq), byte(seq), byte(seq), byte(seq)}),
+		})
+	}
+	for i := uint64(1); i <= protocol.MaxUnacknowledgedRetiredConnectionIDs; i++ {
+		require.NoError(t, addConnID(i))
+	}
+	require.Len(t, frameQueue, protocol.MaxUnacknowledgedRetiredConnectionIDs)
+	// losing a RETIRE_CONNECTION_ID frame requeues it
+	frameQueue[0].Handler.OnLost(frameQueue[0].Frame)
+	require.Len(t, frameQueue, protocol.MaxUnacknowledgedRetiredConnectionIDs+1)
+	require.Equal(t, frameQueue[0].Frame, frameQueue[len(frameQueue)-1].Frame)
+	// once a RETIRE_CONNECTION_ID frame is acknowledged, the peer can issue a new connection ID
+	frameQueue[len(frameQueue)-1].Handler.OnAcked(frameQueue[len(frameQueue)-1].Frame)
+	require.NoError(t, addConnID(protocol.MaxUnacknowledgedRetiredConnectionIDs+1))
+
+	require.Equal(t, &qerr.TransportError{
+		ErrorCode:    qerr.ConnectionIDLimitError,
+		ErrorMessage: "too many unacknowledged RETIRE_CONNECTION_ID frames",
+	}, addConnID(protocol.MaxUnacknowledgedRetiredConnectionIDs+2))
+}
+
 func TestConnIDManagerRetiringConnectionIDs(t *testing.T) {
 	var frameQueue []wire.Frame
 	m := newConnIDManager(
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(protocol.StatelessResetToken) {},
 		func(protocol.StatelessResetToken) {},
-		func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		func(f ackhandler.Frame) { frameQueue = append(frameQueue, f.Frame) },
 	)
 	require.NoError(t, m.Add(&wire.NewConnectionIDFrame{
 		SequenceNumber: 10,
@@ -135,7 +195,7 @@ func TestConnIDManagerHandshakeCompletion(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(token protocol.StatelessResetToken) { addedTokens = append(addedTokens, token) },
 		func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
-		func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		func(f ackhandler.Frame) { frameQueue = append(frameQueue, f.Frame) },
 	)
 	m.SetStatelessResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
 	require.Equal(t, []protocol.StatelessResetToken{{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}}, addedTokens)
@@ -167,7 +227,7 @@ func TestConnIDManagerConnIDRotation(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(token protocol.StatelessResetToken) { addedTokens = append(addedTokens, token) },
 		func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
-		func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		func(f ackhandler.Frame) { frameQueue = append(frameQueue, f.Frame) },
 	)
 	// the first connection ID is used as soon as the handshake is complete
 	m.SetHandshakeComplete()
@@ -237,7 +297,7 @@ func TestConnIDManagerPathMigration(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(token protocol.StatelessResetToken) { addedTokens = append(addedTokens, token) },
 		func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
-		func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		func(f ackhandler.Frame) { frameQueue = append(frameQueue, f.Frame) },
 	)
 
 	// no connection ID available yet
@@ -338,7 +398,7 @@ func TestConnIDManagerZeroLengthConnectionID(t *testing.T) {
 		protocol.ConnectionID{},
 		func(protocol.StatelessResetToken) {},
 		func(protocol.StatelessResetToken) {},
-		func(f wire.Frame) {},
+		func(ackhandler.Frame) {},
 	)
 	require.Equal(t, protocol.ConnectionID{}, m.Get())
 	for range 5 * protocol.PacketsPerConnectionID {
@@ -370,7 +430,7 @@ func TestConnIDManagerClose(t *testing.T) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(token protocol.StatelessResetToken) { addedTokens = append(addedTokens, token) },
 		func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
-		func(f wire.Frame) {},
+		func(ackhandler.Frame) {},
 	)
 	m.SetStatelessResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
 	require.Equal(t, []protocol.StatelessResetToken{{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}}, addedTokens)
@@ -395,7 +455,7 @@ func benchmarkConnIDManager(b *testing.B, reordered bool) {
 		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 		func(protocol.StatelessResetToken) {},
 		func(protocol.StatelessResetToken) {},
-		func(f wire.Frame) {},
+		func(ackhandler.Frame) {},
 	)
 	connIDs := make([]protocol.ConnectionID, 0, protocol.MaxActiveConnectionIDs)
 	statelessResetTokens := make([]protocol.StatelessResetToken, 0, protocol.MaxActiveConnectionIDs)
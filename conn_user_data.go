@@ -0,0 +1,53 @@
+package quic
+
+import (
+	"context"
+	"sync"
+)
+
+type connUserDataKey struct{}
+
+// connUserData holds the application data attached to a connection.
+// It is stored on the connection's context before the Tracer is called,
+// such that data set later on is visible to everyone holding a derived context.
+type connUserData struct {
+	mutex sync.Mutex
+	data  any
+}
+
+func (d *connUserData) Set(v any) {
+	d.mutex.Lock()
+	d.data = v
+	d.mutex.Unlock()
+}
+
+func (d *connUserData) Get() any {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.data
+}
+
+func contextWithConnUserData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connUserDataKey{}, &connUserData{})
+}
+
+// getConnUserData returns the connUserData stored on ctx.
+// If ctx doesn't carry one, a new one is returned.
+func getConnUserData(ctx context.Context) *connUserData {
+	if d, ok := ctx.Value(connUserDataKey{}).(*connUserData); ok {
+		return d
+	}
+	return &connUserData{}
+}
+
+// UserDataFromContext returns the data attached to the connection using Conn.SetUserData.
+// The context can be any context derived from the connection's context,
+// for example the context passed to Config.Tracer, or the context returned by
+// Conn.Context or Stream.Context.
+// It returns nil if no data was attached, or if ctx doesn't belong to a connection.
+func UserDataFromContext(ctx context.Context) any {
+	if d, ok := ctx.Value(connUserDataKey{}).(*connUserData); ok {
+		return d.Get()
+	}
+	return nil
+}
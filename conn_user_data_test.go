@@ -0,0 +1,25 @@
+package quic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnUserDataContext(t *testing.T) {
+	require.Nil(t, UserDataFromContext(context.Background()))
+
+	ctx := contextWithConnUserData(context.Background())
+	require.Nil(t, UserDataFromContext(ctx))
+	d := getConnUserData(ctx)
+	d.Set("foobar")
+	// the data is visible on derived contexts
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+	require.Equal(t, "foobar", UserDataFromContext(derived))
+	require.Same(t, d, getConnUserData(derived))
+
+	// a context without user data gets a fresh container
+	require.Nil(t, getConnUserData(context.Background()).Get())
+}
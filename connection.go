@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"reflect"
 	"slices"
 	"sync"
@@ -31,6 +32,7 @@ import (
 type unpacker interface {
 	UnpackLongHeader(hdr *wire.Header, data []byte) (*unpackedPacket, error)
 	UnpackShortHeader(rcvTime monotime.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error)
+	UnpackShortHeaderBatch([]receivedPacket)
 }
 
 type cryptoStreamHandler interface {
@@ -56,6 +58,9 @@ type receivedPacket struct {
 	ecn protocol.ECN
 
 	info packetInfo // only valid if the contained IP address is valid
+
+	// set if this is a 1-RTT packet that was already unpacked as part of a batch
+	unpacked *unpackedShortHeaderPacket
 }
 
 type receivedPacketWithDatagramID struct {
@@ -79,7 +84,7 @@ func (p *receivedPacket) Clone() *receivedPacket {
 type connRunner interface {
 	Add(protocol.ConnectionID, packetHandler) bool
 	Remove(protocol.ConnectionID)
-	ReplaceWithClosed([]protocol.ConnectionID, []byte, time.Duration)
+	ReplaceWithClosed([]protocol.ConnectionID, []byte, time.Duration, time.Duration)
 	AddResetToken(protocol.StatelessResetToken, packetHandler)
 	RemoveResetToken(protocol.StatelessResetToken)
 }
@@ -123,6 +128,9 @@ const (
 //   - [HandshakeTimeoutError]: when the cryptographic handshake takes too long (this is a [net.Error] timeout error)
 //   - [StatelessResetError]: when we receive a stateless reset
 //   - [VersionNegotiationError]: returned by the client, when there's no version overlap between the peers
+//
+// A Conn remains valid after it has been closed: methods like Context, ConnectionState and UnreadStreamData
+// can still be called. For this reason, Conn objects are never reused for new connections.
 type Conn struct {
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
@@ -168,6 +176,10 @@ type Conn struct {
 
 	currentMTUEstimate atomic.Uint32
 
+	requiresRevalidation atomic.Bool // set when Config.VerifyPeerMigration requests a revalidation
+
+	userData *connUserData
+
 	initialStream       *initialCryptoStream
 	handshakeStream     *cryptoStream
 	oneRTTStream        *cryptoStream // only set for the server
@@ -175,19 +187,23 @@ type Conn struct {
 
 	notifyReceivedPacket chan struct{}
 	sendingScheduled     chan struct{}
+	writeBatch           writeBatch
 	receivedPacketMx     sync.Mutex
 	receivedPackets      ringbuffer.RingBuffer[receivedPacket]
+	packetBatch          []receivedPacket // only used if parallel decryption is enabled
 
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan struct{}
 	closeErr  atomic.Pointer[closeError]
 
-	ctx                   context.Context
-	ctxCancel             context.CancelCauseFunc
-	handshakeCompleteChan chan struct{}
+	ctx                    context.Context
+	ctxCancel              context.CancelCauseFunc
+	handshakeCompleteChan  chan struct{}
+	handshakeConfirmedChan chan struct{}
 
 	undecryptablePackets          []receivedPacketWithDatagramID // undecryptable packets, waiting for a change in encryption level
 	undecryptablePacketsToProcess []receivedPacketWithDatagramID
+	undecryptable1RTTBytes        protocol.ByteCount // size of the 1-RTT packets in undecryptablePackets
 
 	earlyConnReadyChan chan struct{}
 	sentFirstPacket    bool
@@ -201,33 +217,68 @@ type Conn struct {
 
 	blocked blockMode
 
-	// the minimum of the max_idle_timeout values advertised by both endpoints
-	idleTimeout  time.Duration
-	creationTime monotime.Time
+	// the minimum of the max_idle_timeout values advertised by both endpoints,
+	// and the idle timeout requested by SetIdleTimeout
+	idleTimeout time.Duration
+	// idleTimeoutRequest is the idle timeout requested by SetIdleTimeout, not yet applied by the run loop
+	idleTimeoutRequest atomic.Pointer[time.Duration]
+	// requestedIdleTimeout is the idle timeout last requested by SetIdleTimeout, 0 if none was requested
+	requestedIdleTimeout time.Duration
+	creationTime         monotime.Time
 	// The idle timeout is set based on the max of the time we received the last packet...
 	lastPacketReceivedTime monotime.Time
 	// ... and the time we sent a new ack-eliciting packet after receiving a packet.
 	firstAckElicitingPacketAfterIdleSentTime monotime.Time
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline monotime.Time
+	// nextCongestionControlSwitchTime is the time when Config.CongestionControlSwitch is called next
+	nextCongestionControlSwitchTime monotime.Time
+	// congestionControlRequest is the congestion control algorithm requested by SetCongestionControl
+	congestionControlRequest atomic.Pointer[CongestionControlAlgorithm]
+	// packetTooLargeSize is the size of the smallest packet the kernel rejected as too large,
+	// not yet handled by the run loop
+	packetTooLargeSize atomic.Uint32
 
 	peerParams *wire.TransportParameters
+	// peerMaxDatagramFrameSize is the peer's max_datagram_frame_size transport parameter.
+	// On the client side, it is initially set from the transport parameters restored for 0-RTT.
+	peerMaxDatagramFrameSize atomic.Int64
+	// num0RTTDatagrams is the number of DATAGRAM frames sent in 0-RTT packets
+	num0RTTDatagrams uint64
+	// datagrams0RTTRejected is set when 0-RTT is rejected,
+	// and reset once the application calls NextConnection.
+	datagrams0RTTRejected atomic.Bool
 
 	timer *time.Timer
+	// Only set if the Transport consolidates timers.
+	// Used instead of the timer for deadlines that are not in the near future.
+	timerWheel *timerWheel
+	wheelTimer *wheelTimer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
 
+	pingTracker *pingTracker
+
 	datagramQueue *datagramQueue
 
-	connStateMutex sync.Mutex
-	connState      ConnectionState
+	connStateMutex   sync.Mutex
+	connState        ConnectionState
+	unreadStreamData []UnreadStreamData
+
+	logID         string
+	qlogTrace     qlogwriter.Trace
+	qlogger       qlogwriter.Recorder
+	runtimeTracer *runtimeTracer      // only set if runtime tracing is enabled
+	streamEvents  *streamEventEmitter // only set if the stream event hook is enabled
+	receivedAcks  *receivedAckEmitter // only set if the received ACK hook is set
+	logger        utils.Logger
+
+	faultInjector func(FaultInfo) FaultAction // only set when testing
 
-	logID     string
-	qlogTrace qlogwriter.Trace
-	qlogger   qlogwriter.Recorder
-	logger    utils.Logger
+	lastMemoryPressureCheck monotime.Time
+	memoryPressure          atomic.Uint32 // memoryPressureLevel
 }
 
 var _ streamSender = &Conn{}
@@ -263,7 +314,10 @@ var newConnection = func(
 	tlsConf *tls.Config,
 	tokenGenerator *handshake.TokenGenerator,
 	clientAddressValidated bool,
+	addrValidation AddressValidationInfo,
 	rtt time.Duration,
+	faultInjector func(FaultInfo) FaultAction,
+	timerWheel *timerWheel,
 	qlogTrace qlogwriter.Trace,
 	logger utils.Logger,
 	v protocol.Version,
@@ -279,12 +333,19 @@ var newConnection = func(
 		oneRTTStream:        newCryptoStream(),
 		perspective:         protocol.PerspectiveServer,
 		qlogTrace:           qlogTrace,
+		faultInjector:       faultInjector,
+		timerWheel:          timerWheel,
+		pingTracker:         newPingTracker(),
 		logger:              logger,
 		version:             v,
+		userData:            getConnUserData(ctx),
 	}
 	if qlogTrace != nil {
 		s.qlogger = qlogTrace.AddProducer()
 	}
+	if conf.EnableRuntimeTrace {
+		s.runtimeTracer = &runtimeTracer{}
+	}
 	if origDestConnID.Len() > 0 {
 		s.logID = origDestConnID.String()
 	} else {
@@ -294,7 +355,7 @@ var newConnection = func(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
-		s.queueControlFrame,
+		s.queueControlFrameWithHandler,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		runner,
@@ -308,8 +369,11 @@ var newConnection = func(
 		},
 		s.queueControlFrame,
 		connIDGenerator,
+		conf.MaxIssuedConnectionIDs,
 	)
 	s.preSetup()
+	s.connState.AddressValidation = addrValidation
+	s.connState.OriginalDestinationConnectionID = origDestConnID
 	s.rttStats.SetInitialRTT(rtt)
 	s.sentPacketHandler = ackhandler.NewSentPacketHandler(
 		0,
@@ -321,15 +385,16 @@ var newConnection = func(
 		s.receivedPacketHandler.IgnorePacketsBelow,
 		s.perspective,
 		s.qlogger,
+		s.runtimeTracer.Hooks(),
 		s.logger,
 		congestion.CongestionControlAlgorithm(s.config.CongestionControl),
 	)
 	s.currentMTUEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	statelessResetToken := statelessResetter.GetStatelessResetToken(srcConnID)
 	params := &wire.TransportParameters{
-		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataUni:         protocol.ByteCount(s.config.InitialStreamReceiveWindow),
+		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindowBidiLocal),
+		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindowBidiRemote),
+		InitialMaxStreamDataUni:         protocol.ByteCount(s.config.InitialStreamReceiveWindowUni),
 		InitialMaxData:                  protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
@@ -348,12 +413,27 @@ var newConnection = func(
 		InitialSourceConnectionID: srcConnID,
 		RetrySourceConnectionID:   retrySrcConnID,
 		EnableResetStreamAt:       conf.EnableStreamResetPartialDelivery,
+		DisableActiveMigration:    conf.DisablePeerMigration,
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = wire.MaxDatagramSize
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	if s.config.PreferredAddress != nil {
+		addr := s.config.PreferredAddress.AddrPort()
+		preferredAddr := netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+		if connID, token, err := s.connIDGenerator.IssuePreferredAddressConnID(); err != nil || connID.Len() == 0 {
+			s.logger.Debugf("Not advertising preferred_address: failed to issue connection ID: %v", err)
+		} else {
+			params.PreferredAddress = &wire.PreferredAddress{ConnectionID: connID, StatelessResetToken: token}
+			if preferredAddr.Addr().Is4() {
+				params.PreferredAddress.IPv4 = preferredAddr
+			} else {
+				params.PreferredAddress.IPv6 = preferredAddr
+			}
+		}
+	}
 	if s.qlogger != nil {
 		s.qlogTransportParameters(params, protocol.PerspectiveServer, false)
 	}
@@ -370,7 +450,7 @@ var newConnection = func(
 		s.version,
 	)
 	s.cryptoStreamHandler = cs
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, &s.receivedPacketHandler, s.datagramQueue, s.perspective)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, &s.receivedPacketHandler, s.datagramQueue, s.perspective, s.config.CoalescedInitialPadding, faultInjector)
 	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
 	s.cryptoStreamManager = newCryptoStreamManager(s.initialStream, s.handshakeStream, s.oneRTTStream)
 	return &wrappedConn{Conn: s}
@@ -390,6 +470,8 @@ var newClientConnection = func(
 	initialPacketNumber protocol.PacketNumber,
 	enable0RTT bool,
 	hasNegotiatedVersion bool,
+	faultInjector func(FaultInfo) FaultAction,
+	timerWheel *timerWheel,
 	qlogTrace qlogwriter.Trace,
 	logger utils.Logger,
 	v protocol.Version,
@@ -404,12 +486,18 @@ var newClientConnection = func(
 		logID:               destConnID.String(),
 		logger:              logger,
 		qlogTrace:           qlogTrace,
+		faultInjector:       faultInjector,
+		timerWheel:          timerWheel,
+		pingTracker:         newPingTracker(),
 		versionNegotiated:   hasNegotiatedVersion,
 		version:             v,
 	}
 	if qlogTrace != nil {
 		s.qlogger = qlogTrace.AddProducer()
 	}
+	if conf.EnableRuntimeTrace {
+		s.runtimeTracer = &runtimeTracer{}
+	}
 	if s.qlogger != nil {
 		var srcAddr, destAddr *net.UDPAddr
 		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
@@ -424,7 +512,7 @@ var newClientConnection = func(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
-		s.queueControlFrame,
+		s.queueControlFrameWithHandler,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		runner,
@@ -438,9 +526,12 @@ var newClientConnection = func(
 		},
 		s.queueControlFrame,
 		connIDGenerator,
+		conf.MaxIssuedConnectionIDs,
 	)
 	s.ctx, s.ctxCancel = context.WithCancelCause(ctx)
+	s.userData = getConnUserData(ctx)
 	s.preSetup()
+	s.connState.OriginalDestinationConnectionID = destConnID
 	s.sentPacketHandler = ackhandler.NewSentPacketHandler(
 		initialPacketNumber,
 		protocol.ByteCount(s.config.InitialPacketSize),
@@ -451,15 +542,16 @@ var newClientConnection = func(
 		s.receivedPacketHandler.IgnorePacketsBelow,
 		s.perspective,
 		s.qlogger,
+		s.runtimeTracer.Hooks(),
 		s.logger,
 		congestion.CongestionControlAlgorithm(s.config.CongestionControl),
 	)
 	s.currentMTUEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	oneRTTStream := newCryptoStream()
 	params := &wire.TransportParameters{
-		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataBidiLocal:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataUni:        protocol.ByteCount(s.config.InitialStreamReceiveWindow),
+		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindowBidiRemote),
+		InitialMaxStreamDataBidiLocal:  protocol.ByteCount(s.config.InitialStreamReceiveWindowBidiLocal),
+		InitialMaxStreamDataUni:        protocol.ByteCount(s.config.InitialStreamReceiveWindowUni),
 		InitialMaxData:                 protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
@@ -497,7 +589,7 @@ var newClientConnection = func(
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(s.initialStream, s.handshakeStream, oneRTTStream)
 	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, &s.receivedPacketHandler, s.datagramQueue, s.perspective)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, &s.receivedPacketHandler, s.datagramQueue, s.perspective, s.config.CoalescedInitialPadding, faultInjector)
 	if len(tlsConf.ServerName) > 0 {
 		s.tokenStoreKey = tlsConf.ServerName
 	} else {
@@ -516,13 +608,14 @@ func (c *Conn) preSetup() {
 	c.largestRcvdAppData = protocol.InvalidPacketNumber
 	c.initialStream = newInitialCryptoStream(c.perspective == protocol.PerspectiveClient)
 	c.handshakeStream = newCryptoStream()
-	c.sendQueue = newSendQueue(c.conn)
+	c.sendQueue = newSendQueue(c.conn, c.config.MaxQueuedPackets, c.onPacketTooLarge)
 	c.retransmissionQueue = newRetransmissionQueue()
 	c.frameParser = *wire.NewFrameParser(
 		c.config.EnableDatagrams,
 		c.config.EnableStreamResetPartialDelivery,
 		false, // ACK_FREQUENCY is not supported yet
 	)
+	c.frameParser.SetStrict(c.config.StrictMode)
 	c.rttStats = utils.NewRTTStats()
 	c.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(c.config.InitialConnectionReceiveWindow),
@@ -536,7 +629,14 @@ func (c *Conn) preSetup() {
 		c.rttStats,
 		c.logger,
 	)
+	if c.config.EnableReceiveWindowDonation {
+		c.connFlowController.EnableReceiveWindowDonation()
+	}
 	c.earlyConnReadyChan = make(chan struct{})
+	var streamLimitRTTStats *utils.RTTStats
+	if c.config.PredictiveStreamLimits {
+		streamLimitRTTStats = c.rttStats
+	}
 	c.streamsMap = newStreamsMap(
 		c.ctx,
 		c,
@@ -545,19 +645,48 @@ func (c *Conn) preSetup() {
 		uint64(c.config.MaxIncomingStreams),
 		uint64(c.config.MaxIncomingUniStreams),
 		c.perspective,
+		c.config.KeepReceiveBuffersOnClose,
+		c.config.IgnoreLateStreamResets,
+		protocol.ByteCount(c.config.MaxSendBufferPerStream),
+		outOfOrderBufferLimit{
+			maxBytes:  protocol.ByteCount(c.config.MaxStreamOutOfOrderBuffer),
+			action:    c.config.StreamOutOfOrderBufferOverflow,
+			errorCode: c.config.StreamOutOfOrderBufferErrorCode,
+		},
+		unacceptedStreamsLimit{
+			maxStreams: uint64(max(0, c.config.MaxUnacceptedStreams)),
+			action:     c.config.UnacceptedStreamsOverflow,
+			errorCode:  c.config.UnacceptedStreamsErrorCode,
+		},
+		streamLimitRTTStats,
 	)
-	c.framer = newFramer(c.connFlowController)
+	if c.config.StreamEventHookEnabled && c.config.StreamEventHook != nil {
+		c.streamEvents = newStreamEventEmitter(c.config.StreamEventHook)
+		c.streamsMap.onStreamOpened = c.streamEvents.Opened
+	}
+	if c.config.ReceivedAckHook != nil {
+		c.receivedAcks = newReceivedAckEmitter(c.config.ReceivedAckHook, c.config.ReceivedAckHookCopy)
+	}
+	c.framer = newFramer(c.connFlowController, c.config.MaxStreamsPerPacket, c.config.PrioritizeRetransmissions)
 	c.receivedPackets.Init(8)
 	c.notifyReceivedPacket = make(chan struct{}, 1)
 	c.closeChan = make(chan struct{}, 1)
 	c.sendingScheduled = make(chan struct{}, 1)
+	c.writeBatch.maxDelay = protocol.MaxWriteBatchDelay
 	c.handshakeCompleteChan = make(chan struct{})
+	c.handshakeConfirmedChan = make(chan struct{})
 
 	now := monotime.Now()
 	c.lastPacketReceivedTime = now
 	c.creationTime = now
 
-	c.receivedPacketHandler = *ackhandler.NewReceivedPacketHandler(c.logger)
+	c.receivedPacketHandler = *ackhandler.NewReceivedPacketHandler(c.rttStats, c.qlogger, c.logger)
+	if c.config.CoalesceAcks {
+		c.receivedPacketHandler.EnableAckCoalescing()
+	}
+	if c.config.MinimizeAckDelay {
+		c.receivedPacketHandler.EnableImmediateAcks()
+	}
 
 	c.datagramQueue = newDatagramQueue(c.scheduleSending, c.logger)
 	c.connState.Version = c.version
@@ -567,6 +696,11 @@ func (c *Conn) preSetup() {
 func (c *Conn) run() (err error) {
 	defer func() { c.ctxCancel(err) }()
 
+	if c.runtimeTracer != nil {
+		c.runtimeTracer.Start(c.ctx)
+		defer c.runtimeTracer.End()
+	}
+
 	defer func() {
 		// drain queued packets that will never be processed
 		c.receivedPacketMx.Lock()
@@ -580,6 +714,11 @@ func (c *Conn) run() (err error) {
 	}()
 
 	c.timer = time.NewTimer(monotime.Until(c.idleTimeoutStartTime().Add(c.config.HandshakeIdleTimeout)))
+	var wheelTimerChan <-chan struct{}
+	if c.timerWheel != nil {
+		c.wheelTimer = c.timerWheel.NewTimer()
+		wheelTimerChan = c.wheelTimer.C
+	}
 
 	if err := c.cryptoStreamHandler.StartHandshake(c.ctx); err != nil {
 		return err
@@ -624,6 +763,7 @@ runLoop:
 			queue := c.undecryptablePacketsToProcess
 			c.undecryptablePacketsToProcess = nil
 			for _, p := range queue {
+				is1RTT := !wire.IsLongHeaderPacket(p.data[0])
 				processed, err := c.handleOnePacket(p.receivedPacket, p.datagramID)
 				if err != nil {
 					c.setCloseError(&closeError{err: err})
@@ -631,6 +771,9 @@ runLoop:
 				}
 				if processed {
 					processedUndecryptablePacket = true
+					if is1RTT {
+						c.connStats.Early1RTTPacketsSalvaged.Add(1)
+					}
 				}
 			}
 			if processedUndecryptablePacket {
@@ -661,6 +804,7 @@ runLoop:
 			case <-c.closeChan:
 				break runLoop
 			case <-c.timer.C:
+			case <-wheelTimerChan:
 			case <-c.sendingScheduled:
 			case <-sendQueueAvailable:
 			case <-c.notifyReceivedPacket:
@@ -679,11 +823,27 @@ runLoop:
 		// Check for loss detection timeout.
 		// This could cause packets to be declared lost, and retransmissions to be enqueued.
 		now := monotime.Now()
+		if d := c.idleTimeoutRequest.Swap(nil); d != nil {
+			c.requestedIdleTimeout = *d
+			// Otherwise, the idle timeout is set when the peer's transport parameters are applied.
+			if c.idleTimeout > 0 {
+				c.setIdleTimeout()
+			}
+		}
+		if size := c.packetTooLargeSize.Swap(0); size != 0 {
+			c.handlePacketTooLarge(protocol.ByteCount(size), now)
+		}
 		if timeout := c.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && !timeout.After(now) {
+			ptoCount := c.sentPacketHandler.PTOCount()
 			if err := c.sentPacketHandler.OnLossDetectionTimeout(now); err != nil {
 				c.setCloseError(&closeError{err: err})
 				break runLoop
 			}
+			degradedPTOCount := uint32(c.config.ConnectivityDegradedPTOCount)
+			if c.config.OnConnectivityDegraded != nil &&
+				ptoCount < degradedPTOCount && c.sentPacketHandler.PTOCount() >= degradedPTOCount {
+				go c.config.OnConnectivityDegraded(c)
+			}
 		}
 
 		if keepAliveTime := c.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
@@ -704,13 +864,14 @@ runLoop:
 		}
 
 		c.connIDGenerator.RemoveRetiredConnIDs(now)
+		c.maybeSwitchCongestionControl(now)
 
 		if c.perspective == protocol.PerspectiveClient {
 			pm := c.pathManagerOutgoing.Load()
 			if pm != nil {
-				tr, ok := pm.ShouldSwitchPath()
+				tr, remoteAddr, ok := pm.ShouldSwitchPath()
 				if ok {
-					c.switchToNewPath(tr, now)
+					c.switchToNewPath(tr, remoteAddr, now)
 				}
 			}
 		}
@@ -755,6 +916,9 @@ runLoop:
 	}
 	c.logger.Infof("Connection %s closed.", c.logID)
 	c.timer.Stop()
+	if c.wheelTimer != nil {
+		c.wheelTimer.Stop()
+	}
 	return closeErr.err
 }
 
@@ -769,8 +933,38 @@ func (c *Conn) Context() context.Context {
 	return c.ctx
 }
 
+// SetUserData attaches arbitrary application data to the connection.
+// The data can be retrieved using UserData, or using UserDataFromContext from any context
+// derived from the connection's context, including the context passed to Config.Tracer.
+// If the data implements qlogwriter.Tagger, and the connection's qlog trace implements
+// qlogwriter.TaggedTrace, the tags are attached to all qlog events recorded after the call.
+func (c *Conn) SetUserData(v any) {
+	c.userData.Set(v)
+	if t, ok := c.qlogTrace.(qlogwriter.TaggedTrace); ok {
+		var tags []qlogwriter.Tag
+		if tagger, ok := v.(qlogwriter.Tagger); ok {
+			tags = tagger.Tags()
+		}
+		t.SetTags(tags)
+	}
+}
+
+// UserData returns the data attached to the connection using SetUserData.
+func (c *Conn) UserData() any {
+	return c.userData.Get()
+}
+
 func (c *Conn) supportsDatagrams() bool {
-	return c.peerParams.MaxDatagramFrameSize > 0
+	return c.peerMaxDatagramFrameSize.Load() > 0
+}
+
+// UnreadStreamData returns the data that was received on open streams, but not read by the application,
+// at the time the connection was closed.
+// It returns nil if the connection hasn't been closed yet, or if no receive streams were open.
+func (c *Conn) UnreadStreamData() []UnreadStreamData {
+	c.connStateMutex.Lock()
+	defer c.connStateMutex.Unlock()
+	return c.unreadStreamData
 }
 
 // ConnectionState returns basic details about the QUIC connection.
@@ -788,9 +982,31 @@ func (c *Conn) ConnectionState() ConnectionState {
 	c.connState.SupportsDatagrams.Local = c.config.EnableDatagrams
 	c.connState.SupportsStreamResetPartialDelivery.Local = c.config.EnableStreamResetPartialDelivery
 	c.connState.GSO = c.conn.capabilities().GSO
+	c.connState.SmoothedRTT = c.rttStats.SmoothedRTT()
+	c.connState.RTTVariance = c.rttStats.MeanDeviation()
+	c.connState.LatestRTT = c.rttStats.LatestRTT()
+	c.connState.MinRTT = c.rttStats.MinRTT()
+	c.connState.CongestionLimited = c.connStats.CongestionLimited.Load()
+	c.connState.KeyExchange = ""
+	if curveID := cs.ConnectionState.CurveID; curveID != 0 {
+		c.connState.KeyExchange = curveID.String()
+	}
+	c.connState.PostQuantumKeyExchange = isPostQuantumKeyExchange(cs.ConnectionState.CurveID)
+	c.connState.Streams = c.streamsMap.StreamCounts()
 	return c.connState
 }
 
+func isPostQuantumKeyExchange(id tls.CurveID) bool {
+	switch id {
+	case tls.X25519MLKEM768,
+		0x11eb, // SecP256r1MLKEM768
+		0x11ed: // SecP384r1MLKEM1024
+		return true
+	default:
+		return false
+	}
+}
+
 // ConnectionStats contains statistics about the QUIC connection
 type ConnectionStats struct {
 	// MinRTT is the estimate of the minimum RTT observed on the active network
@@ -828,9 +1044,74 @@ type ConnectionStats struct {
 	// (does not monotonically increase, because packets that are declared lost
 	// can subsequently be received).
 	PacketsLost uint64
+
+	// OutOfOrderStreamBytes is the number of bytes buffered beyond a gap in the
+	// received data, summed over all open streams.
+	OutOfOrderStreamBytes uint64
+
+	// UnacceptedStreams is the number of streams opened by the peer that
+	// haven't been accepted by the application yet.
+	UnacceptedStreams uint64
+	// OldestUnacceptedStreamAge is the time since the oldest of these streams
+	// was opened. It is zero if there are no unaccepted streams.
+	OldestUnacceptedStreamAge time.Duration
+
+	// PathValidationsDropped is the number of packets received from a previously
+	// unseen path for which no path validation was started, either because the
+	// maximum number of concurrent path validations was reached, or because too
+	// many path validations were started for the same IP address.
+	PathValidationsDropped uint64
+	// PathProbesAmplificationLimited is the number of times a path probe packet
+	// was not sent on an unvalidated path because of the anti-amplification limit.
+	PathProbesAmplificationLimited uint64
+	// PathSwitchesDropped is the number of times the connection didn't switch to
+	// a validated path because the previous path switch happened too recently.
+	PathSwitchesDropped uint64
+
+	// MaxCryptoFrameGaps is the highest number of gaps observed in the data
+	// received in CRYPTO frames, on any encryption level. If the peer creates
+	// too many gaps, the connection is closed with a CRYPTO_BUFFER_EXCEEDED error.
+	MaxCryptoFrameGaps uint64
+
+	// Datagrams0RTTDropped is the number of DATAGRAM frames that were dropped
+	// because the server rejected 0-RTT. This includes DATAGRAM frames sent in
+	// 0-RTT packets, as well as DATAGRAM frames that were queued for sending.
+	Datagrams0RTTDropped uint64
+
+	// Early1RTTPacketsSalvaged is the number of 1-RTT packets that arrived before the
+	// 1-RTT keys were available, and that were successfully processed once the keys
+	// became available. Without buffering, these packets would have been retransmitted.
+	Early1RTTPacketsSalvaged uint64
+
+	// PacketSizeReductions is the number of times the maximum packet size was
+	// reduced because the operating system rejected a packet as too large
+	// (EMSGSIZE). The packets affected are retransmitted using the reduced size.
+	PacketSizeReductions uint64
+
+	// LastAckElicitingPacketSentAt is the time when the last ack-eliciting packet was sent.
+	// Packets sent to probe a new path are not taken into account.
+	// It is zero if no ack-eliciting packet was sent yet.
+	LastAckElicitingPacketSentAt time.Time
+	// LastPacketReceivedAt is the time when the last packet was received and successfully processed.
+	// It is zero if no packet was received yet.
+	LastPacketReceivedAt time.Time
+
+	// CongestionControl is the congestion control algorithm currently in use.
+	// It changes when the congestion controller is switched, either using Conn.SetCongestionControl
+	// or Config.CongestionControlSwitch, and when the connection migrates to a new path.
+	CongestionControl CongestionControlAlgorithm
+	// CongestionWindow is the congestion window of the active path, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes sent on the active path that are neither acknowledged nor declared lost.
+	BytesInFlight uint64
 }
 
 func (c *Conn) ConnectionStats() ConnectionStats {
+	var oldestUnacceptedStreamAge time.Duration
+	unacceptedStreams, oldestUnacceptedStream := c.streamsMap.UnacceptedStreams()
+	if unacceptedStreams > 0 {
+		oldestUnacceptedStreamAge = monotime.Since(oldestUnacceptedStream)
+	}
 	return ConnectionStats{
 		MinRTT:        c.rttStats.MinRTT(),
 		LatestRTT:     c.rttStats.LatestRTT(),
@@ -843,9 +1124,73 @@ func (c *Conn) ConnectionStats() ConnectionStats {
 		PacketsReceived: c.connStats.PacketsReceived.Load(),
 		BytesLost:       c.connStats.BytesLost.Load(),
 		PacketsLost:     c.connStats.PacketsLost.Load(),
+
+		OutOfOrderStreamBytes: c.streamsMap.OutOfOrderBytes(),
+
+		UnacceptedStreams:         unacceptedStreams,
+		OldestUnacceptedStreamAge: oldestUnacceptedStreamAge,
+
+		PathValidationsDropped:         c.connStats.PathValidationsDropped.Load(),
+		PathProbesAmplificationLimited: c.connStats.PathProbesAmplificationLimited.Load(),
+		PathSwitchesDropped:            c.connStats.PathSwitchesDropped.Load(),
+
+		MaxCryptoFrameGaps: c.connStats.MaxCryptoFrameGaps.Load(),
+
+		Datagrams0RTTDropped:     c.connStats.Datagrams0RTTDropped.Load(),
+		Early1RTTPacketsSalvaged: c.connStats.Early1RTTPacketsSalvaged.Load(),
+
+		PacketSizeReductions: c.connStats.PacketSizeReductions.Load(),
+
+		LastAckElicitingPacketSentAt: monotime.Time(c.connStats.LastAckElicitingPacketSent.Load()).ToTime(),
+		LastPacketReceivedAt:         monotime.Time(c.connStats.LastPacketReceived.Load()).ToTime(),
+
+		CongestionControl: CongestionControlAlgorithm(c.connStats.CongestionControl.Load()),
+		CongestionWindow:  c.connStats.CongestionWindow.Load(),
+		BytesInFlight:     c.connStats.BytesInFlight.Load(),
 	}
 }
 
+// PathStats contains statistics about a network path of a QUIC connection, see Conn.Paths.
+type PathStats struct {
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Active is true for the path that is currently used to send packets.
+	Active bool
+	// Validated is true once path validation has succeeded.
+	// The active path is always validated.
+	Validated bool
+
+	// For the active path, the RTT statistics are the same as in ConnectionStats.
+	// For other paths, they are derived from PATH_CHALLENGE / PATH_RESPONSE exchanges,
+	// and are zero if no PATH_RESPONSE has been received yet.
+	MinRTT      time.Duration
+	LatestRTT   time.Duration
+	SmoothedRTT time.Duration
+}
+
+// Paths returns statistics about the network paths of the connection.
+// The first element is the active path. It is followed by the paths added using Conn.AddPath
+// and the path to the server's preferred address (if any), in the order they were created.
+// On the server side, only the active path is returned.
+//
+// Packets are only ever sent on the active path, so there's only a single congestion controller.
+// Its state is reported in ConnectionStats.
+func (c *Conn) Paths() []PathStats {
+	stats := []PathStats{{
+		LocalAddr:   c.LocalAddr(),
+		RemoteAddr:  c.RemoteAddr(),
+		Active:      true,
+		Validated:   true,
+		MinRTT:      c.rttStats.MinRTT(),
+		LatestRTT:   c.rttStats.LatestRTT(),
+		SmoothedRTT: c.rttStats.SmoothedRTT(),
+	}}
+	if pm := c.pathManagerOutgoing.Load(); pm != nil {
+		stats = pm.AppendPathStats(stats, c.LocalAddr(), c.RemoteAddr())
+	}
+	return stats
+}
+
 // Time when the connection should time out
 func (c *Conn) nextIdleTimeoutTime() monotime.Time {
 	idleTimeout := max(c.idleTimeout, c.rttStats.PTO(true)*3)
@@ -862,6 +1207,78 @@ func (c *Conn) nextKeepAliveTime() monotime.Time {
 	return c.lastPacketReceivedTime.Add(keepAliveInterval)
 }
 
+func (c *Conn) maybeSwitchCongestionControl(now monotime.Time) {
+	if alg := c.congestionControlRequest.Swap(nil); alg != nil {
+		c.switchCongestionControl(*alg, now)
+	}
+	if c.nextCongestionControlSwitchTime.IsZero() || now.Before(c.nextCongestionControlSwitchTime) {
+		return
+	}
+	c.nextCongestionControlSwitchTime = now.Add(protocol.CongestionControlSwitchInterval)
+	alg, ok := c.config.CongestionControlSwitch(now.Sub(c.creationTime), c.ConnectionStats())
+	if !ok || !alg.valid() {
+		return
+	}
+	c.switchCongestionControl(alg, now)
+}
+
+func (c *Conn) switchCongestionControl(alg CongestionControlAlgorithm, now monotime.Time) {
+	c.sentPacketHandler.SetCongestionControl(congestion.CongestionControlAlgorithm(alg), c.maxPacketSize(), now)
+}
+
+// SetCongestionControl switches the congestion controller of the connection,
+// for example to compare the performance of different algorithms on live connections.
+// The switch is applied asynchronously, by the connection's run loop.
+// ConnectionStats.CongestionControl reports the algorithm in use.
+//
+// All acknowledgments received before the switch are delivered to the old congestion controller.
+// The new congestion controller then takes over its state: the congestion window, the slow start threshold,
+// an ongoing recovery period and the pacing budget. It doesn't start over in slow start.
+// If the state can't be transferred, the new congestion controller starts in congestion avoidance,
+// using the current congestion window.
+// Bytes in flight are tracked independently of the congestion controller, and are not affected.
+// Algorithm-specific state, such as the CUBIC growth function, is not transferred,
+// so switching frequently still distorts the behavior of both algorithms.
+func (c *Conn) SetCongestionControl(alg CongestionControlAlgorithm) error {
+	if !alg.valid() {
+		return fmt.Errorf("invalid congestion control algorithm: %d", alg)
+	}
+	c.congestionControlRequest.Store(&alg)
+	c.scheduleSending()
+	return nil
+}
+
+// SetIdleTimeout sets the idle timeout of the connection, replacing Config.MaxIdleTimeout.
+// The new value applies from now on, and is measured from the last network activity,
+// so the connection is closed right away if it has already been idle for longer than d.
+// The idle timeout can only be reduced below the value negotiated during the handshake,
+// i.e. the minimum of Config.MaxIdleTimeout and the peer's max_idle_timeout transport parameter:
+// values larger than that are capped, since the peer would close the connection anyway.
+// This makes it possible to restore the negotiated idle timeout after lowering it.
+// The keep-alive interval is adjusted accordingly.
+// If called before the handshake completes, the value is applied once the handshake completes.
+// The handshake itself is governed by Config.HandshakeIdleTimeout.
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.idleTimeoutRequest.Store(&d)
+	c.scheduleSending()
+}
+
+func (c *Conn) setIdleTimeout() {
+	// Our local idle timeout will always be > 0.
+	c.idleTimeout = c.config.MaxIdleTimeout
+	// If the peer advertised an idle timeout, take the minimum of the values.
+	if c.peerParams.MaxIdleTimeout > 0 {
+		c.idleTimeout = min(c.idleTimeout, c.peerParams.MaxIdleTimeout)
+	}
+	if c.requestedIdleTimeout > 0 {
+		c.idleTimeout = min(c.idleTimeout, c.requestedIdleTimeout)
+	}
+	c.keepAliveInterval = min(c.config.KeepAlivePeriod, c.idleTimeout/2)
+}
+
 func (c *Conn) maybeResetTimer() {
 	var deadline monotime.Time
 	if !c.handshakeComplete {
@@ -881,11 +1298,14 @@ func (c *Conn) maybeResetTimer() {
 				deadline = c.nextIdleTimeoutTime()
 			}
 		}
+		if t := c.nextCongestionControlSwitchTime; !t.IsZero() && t.Before(deadline) {
+			deadline = t
+		}
 	}
 	// If the connection is hard-blocked, we can't even send acknowledgments,
 	// nor can we send PTO probe packets.
 	if c.blocked == blockModeHardBlocked {
-		c.timer.Reset(monotime.Until(deadline))
+		c.resetTimer(deadline)
 		return
 	}
 
@@ -896,14 +1316,34 @@ func (c *Conn) maybeResetTimer() {
 		deadline = t
 	}
 	if c.blocked == blockModeCongestionLimited {
-		c.timer.Reset(monotime.Until(deadline))
+		c.resetTimer(deadline)
 		return
 	}
 
 	if !c.pacingDeadline.IsZero() && c.pacingDeadline.Before(deadline) {
 		deadline = c.pacingDeadline
 	}
-	c.timer.Reset(monotime.Until(deadline))
+	if t := c.writeBatch.Deadline(); !t.IsZero() && t.Before(deadline) {
+		deadline = t
+	}
+	c.resetTimer(deadline)
+}
+
+func (c *Conn) resetTimer(deadline monotime.Time) {
+	if c.wheelTimer == nil {
+		c.timer.Reset(monotime.Until(deadline))
+		return
+	}
+	// The timer wheel is shared by all connections of the Transport.
+	// To avoid locking it on every iteration of the run loop, it is only re-armed if the deadline
+	// moves closer, and not stopped when switching to the connection's own timer.
+	// It might therefore fire early, which just causes another iteration of the run loop.
+	if d := monotime.Until(deadline); d < timerWheelMinDelay {
+		c.timer.Reset(d)
+		return
+	}
+	c.timer.Stop()
+	c.wheelTimer.Arm(deadline)
 }
 
 func (c *Conn) idleTimeoutStartTime() monotime.Time {
@@ -914,7 +1354,7 @@ func (c *Conn) idleTimeoutStartTime() monotime.Time {
 	return startTime
 }
 
-func (c *Conn) switchToNewPath(tr *Transport, now monotime.Time) {
+func (c *Conn) switchToNewPath(tr *Transport, remoteAddr net.Addr, now monotime.Time) {
 	initialPacketSize := protocol.ByteCount(c.config.InitialPacketSize)
 	c.sentPacketHandler.MigratedPath(now, initialPacketSize)
 	maxPacketSize := protocol.ByteCount(protocol.MaxPacketBufferSize)
@@ -922,9 +1362,17 @@ func (c *Conn) switchToNewPath(tr *Transport, now monotime.Time) {
 		maxPacketSize = c.peerParams.MaxUDPPayloadSize
 	}
 	c.mtuDiscoverer.Reset(now, initialPacketSize, maxPacketSize)
-	c.conn = newSendConn(tr.conn, c.conn.RemoteAddr(), packetInfo{}, utils.DefaultLogger) // TODO: find a better way
+	if remoteAddr == nil {
+		remoteAddr = c.conn.RemoteAddr()
+	}
+	// When migrating to the server's preferred address, the socket stays the same.
+	if tr == nil {
+		c.conn.ChangeRemoteAddr(remoteAddr, packetInfo{})
+		return
+	}
+	c.conn = newSendConn(tr.conn, remoteAddr, packetInfo{}, utils.DefaultLogger) // TODO: find a better way
 	c.sendQueue.Close()
-	c.sendQueue = newSendQueue(c.conn)
+	c.sendQueue = newSendQueue(c.conn, c.config.MaxQueuedPackets, c.onPacketTooLarge)
 	go func() {
 		if err := c.sendQueue.Run(); err != nil {
 			c.destroyImpl(err)
@@ -932,6 +1380,62 @@ func (c *Conn) switchToNewPath(tr *Transport, now monotime.Time) {
 	}()
 }
 
+// onPacketTooLarge is called by the send queue when the kernel rejected a packet as too large.
+func (c *Conn) onPacketTooLarge(size protocol.ByteCount) {
+	for {
+		old := c.packetTooLargeSize.Load()
+		if old != 0 && old <= uint32(size) {
+			break
+		}
+		if c.packetTooLargeSize.CompareAndSwap(old, uint32(size)) {
+			break
+		}
+	}
+	c.scheduleSending()
+}
+
+// handlePacketTooLarge reduces the maximum packet size after the kernel rejected a packet of the given size.
+// The packets that couldn't be sent are declared lost, such that their frames are sent in smaller packets.
+func (c *Conn) handlePacketTooLarge(size protocol.ByteCount, now monotime.Time) {
+	// Before the handshake completes, the configured initial packet size is used.
+	if c.mtuDiscoverer == nil {
+		return
+	}
+	// It is expected that Path MTU probe packets are sometimes too large.
+	// DPLPMTUD deals with these once they are declared lost.
+	currentSize := c.mtuDiscoverer.CurrentSize()
+	if size > currentSize {
+		return
+	}
+	maxSize := size - 1
+	var sizeKnown bool
+	if conn, ok := c.conn.(interface {
+		maxPayloadSize() (protocol.ByteCount, bool)
+	}); ok {
+		if s, ok := conn.maxPayloadSize(); ok && s <= maxSize {
+			maxSize = s
+			sizeKnown = true
+		}
+	}
+	maxSize = max(maxSize, protocol.MinInitialPacketSize)
+	if maxSize >= currentSize {
+		return
+	}
+	// If the kernel told us the path MTU, we can use it right away.
+	// Otherwise, DPLPMTUD searches for the largest packet size that can be sent, starting from the initial packet size.
+	startSize := maxSize
+	if !sizeKnown {
+		startSize = min(protocol.ByteCount(c.config.InitialPacketSize), maxSize)
+	}
+	if c.logger.Debug() {
+		c.logger.Debugf("Packet of size %d too large. Reducing the maximum packet size to %d (search up to %d).", size, startSize, maxSize)
+	}
+	c.connStats.PacketSizeReductions.Add(1)
+	c.sentPacketHandler.ReduceMaxDatagramSize(startSize, now)
+	c.mtuDiscoverer.Reset(now, startSize, maxSize)
+	c.currentMTUEstimate.Store(uint32(estimateMaxPayloadSize(startSize)))
+}
+
 func (c *Conn) handleHandshakeComplete(now monotime.Time) error {
 	defer close(c.handshakeCompleteChan)
 	// Once the handshake completes, we have derived 1-RTT keys.
@@ -940,17 +1444,36 @@ func (c *Conn) handleHandshakeComplete(now monotime.Time) error {
 
 	c.connIDManager.SetHandshakeComplete()
 	c.connIDGenerator.SetHandshakeComplete(now.Add(3 * c.rttStats.PTO(false)))
+	if c.config.CongestionControlSwitch != nil {
+		c.nextCongestionControlSwitchTime = c.creationTime.Add(protocol.CongestionControlSwitchInterval)
+	}
+	c.connStateMutex.Lock()
+	c.connState.HandshakeComplete = true
+	c.connStateMutex.Unlock()
 
 	if c.qlogger != nil {
 		c.qlogger.RecordEvent(qlog.ALPNInformation{
 			ChosenALPN: c.cryptoStreamHandler.ConnectionState().NegotiatedProtocol,
 		})
+		c.connStateMutex.Lock()
+		av := c.connState.AddressValidation
+		origDestConnID := c.connState.OriginalDestinationConnectionID
+		c.connStateMutex.Unlock()
+		c.qlogger.RecordEvent(qlog.HandshakeSummary{
+			UsedRetry:                av.UsedRetry,
+			UsedNewToken:             av.UsedNewToken,
+			RetryLatency:             av.RetryLatency,
+			RetriesReceived:          av.RetriesReceived,
+			OriginalDestConnectionID: origDestConnID,
+			RetrySrcConnectionID:     av.RetrySrcConnectionID,
+		})
 	}
 
 	// The server applies transport parameters right away, but the client side has to wait for handshake completion.
 	// During a 0-RTT connection, the client is only allowed to use the new transport parameters for 1-RTT packets.
 	if c.perspective == protocol.PerspectiveClient {
 		c.applyTransportParameters()
+		c.streamsMap.HandleHandshakeComplete()
 		return nil
 	}
 
@@ -971,11 +1494,13 @@ func (c *Conn) handleHandshakeComplete(now monotime.Time) error {
 			}
 		}
 	}
-	token, err := c.tokenGenerator.NewToken(c.conn.RemoteAddr(), c.rttStats.SmoothedRTT())
-	if err != nil {
-		return err
+	if !c.config.DisableNewTokens {
+		token, err := c.tokenGenerator.NewToken(c.conn.RemoteAddr(), c.rttStats.SmoothedRTT())
+		if err != nil {
+			return err
+		}
+		c.queueControlFrame(&wire.NewTokenFrame{Token: token})
 	}
-	c.queueControlFrame(&wire.NewTokenFrame{Token: token})
 	c.queueControlFrame(&wire.HandshakeDoneFrame{})
 	return nil
 }
@@ -993,19 +1518,56 @@ func (c *Conn) handleHandshakeConfirmed(now monotime.Time) error {
 	}
 
 	c.handshakeConfirmed = true
+	c.connStateMutex.Lock()
+	c.connState.HandshakeConfirmed = true
+	c.connStateMutex.Unlock()
+	close(c.handshakeConfirmedChan)
 	c.cryptoStreamHandler.SetHandshakeConfirmed()
 
 	if !c.config.DisablePathMTUDiscovery && c.conn.capabilities().DF {
 		c.mtuDiscoverer.Start(now)
 	}
+	if c.perspective == protocol.PerspectiveClient {
+		if addr, ok := c.preferredAddress(); ok {
+			c.migrateToPreferredAddress(net.UDPAddrFromAddrPort(addr))
+		}
+	}
 	return nil
 }
 
-const maxPacketsToProcess = 32
+// migrateToPreferredAddress validates the path to the server's preferred address,
+// and migrates the connection once the path is validated (see section 9.6 of RFC 9000).
+// If path validation fails, the connection continues using the current path.
+func (c *Conn) migrateToPreferredAddress(addr net.Addr) {
+	if addrsEqual(addr, c.RemoteAddr()) {
+		return
+	}
+	path := c.getPathManager().NewPreferredAddressPath(addr, c.rttStats.SmoothedRTT())
+	// RFC 9000, section 8.2.4: use three times the PTO as the path validation timeout
+	timeout := 3 * c.rttStats.PTO(false)
+	go func() {
+		ctx, cancel := context.WithTimeout(c.ctx, timeout)
+		defer cancel()
+		if err := path.Probe(ctx); err != nil {
+			c.logger.Debugf("Validating the path to the preferred address %s failed: %s", addr, err)
+			path.Close()
+			return
+		}
+		if err := path.Switch(); err != nil {
+			c.logger.Debugf("Switching to the preferred address %s failed: %s", addr, err)
+			return
+		}
+		c.logger.Debugf("Migrated to the preferred address %s", addr)
+	}()
+}
 
 func (c *Conn) handlePackets() (wasProcessed bool, _ error) {
+	if c.config.EnableParallelDecryption && c.handshakeConfirmed {
+		return c.handlePacketBatch()
+	}
+
 	// Process packets from the receivedPackets queue.
-	// Limit the number of packets to process to maxPacketsToProcess,
+	// Limit the number of packets to process to the receive budget,
 	// so we eventually get a chance to send out an ACK when receiving a lot of packets.
 	c.receivedPacketMx.Lock()
 
@@ -1015,7 +1577,7 @@ func (c *Conn) handlePackets() (wasProcessed bool, _ error) {
 	}
 
 	var hasMorePackets bool
-	for range maxPacketsToProcess {
+	for range c.config.ReceivePacketBudget {
 		p := c.receivedPackets.PopFront()
 		c.receivedPacketMx.Unlock()
 
@@ -1052,8 +1614,55 @@ func (c *Conn) handlePackets() (wasProcessed bool, _ error) {
 	return wasProcessed, nil
 }
 
+// handlePacketBatch processes up to ReceivePacketBudget packets from the receivedPackets queue.
+// The 1-RTT packets are decrypted concurrently, all frames are then handled serially,
+// in the order the packets were received.
+func (c *Conn) handlePacketBatch() (wasProcessed bool, _ error) {
+	c.receivedPacketMx.Lock()
+	for len(c.packetBatch) < c.config.ReceivePacketBudget && !c.receivedPackets.Empty() {
+		c.packetBatch = append(c.packetBatch, c.receivedPackets.PopFront())
+	}
+	hasMorePackets := !c.receivedPackets.Empty()
+	c.receivedPacketMx.Unlock()
+
+	batch := c.packetBatch
+	defer func() {
+		clear(batch)
+		c.packetBatch = batch[:0]
+	}()
+
+	c.unpacker.UnpackShortHeaderBatch(batch)
+	for i, p := range batch {
+		var datagramID qlog.DatagramID
+		if c.qlogger != nil && wire.IsLongHeaderPacket(p.data[0]) {
+			datagramID = qlog.CalculateDatagramID(p.data)
+		}
+		processed, err := c.handleOnePacket(p, datagramID)
+		if err != nil {
+			// release the packets that will never be processed
+			for _, p := range batch[i+1:] {
+				p.buffer.Decrement()
+				p.buffer.MaybeRelease()
+			}
+			return false, err
+		}
+		if processed {
+			wasProcessed = true
+		}
+	}
+
+	if hasMorePackets {
+		select {
+		case c.notifyReceivedPacket <- struct{}{}:
+		default:
+		}
+	}
+	return wasProcessed, nil
+}
+
 func (c *Conn) handleOnePacket(rp receivedPacket, datagramID qlog.DatagramID) (wasProcessed bool, _ error) {
 	c.sentPacketHandler.ReceivedBytes(rp.Size(), rp.rcvTime)
+	c.receivedPacketHandler.ReceivedBytes(rp.Size(), rp.rcvTime)
 
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		return false, c.handleVersionNegotiationPacket(rp)
@@ -1197,7 +1806,17 @@ func (c *Conn) handleShortHeaderPacket(
 		})
 		return false, nil
 	}
-	pn, pnLen, keyPhase, data, err := c.unpacker.UnpackShortHeader(p.rcvTime, p.data)
+	var (
+		pn       protocol.PacketNumber
+		pnLen    protocol.PacketNumberLen
+		keyPhase protocol.KeyPhaseBit
+		data     []byte
+	)
+	if p.unpacked != nil {
+		pn, pnLen, keyPhase, data, err = p.unpacked.pn, p.unpacked.pnLen, p.unpacked.kp, p.unpacked.data, p.unpacked.err
+	} else {
+		pn, pnLen, keyPhase, data, err = c.unpacker.UnpackShortHeader(p.rcvTime, p.data)
+	}
 	if err != nil {
 		// Stateless reset packets (see RFC 9000, section 10.3):
 		// * fill the entire UDP datagram (i.e. they cannot be part of a coalesced packet)
@@ -1213,6 +1832,24 @@ func (c *Conn) handleShortHeaderPacket(
 		wasQueued, err = c.handleUnpackError(err, p, qlog.PacketType1RTT, datagramID)
 		return false, err
 	}
+	// If peer migration is disabled, packets received from a different address are dropped,
+	// and the connection stays on the current path (see section 9 of RFC 9000).
+	// Closing the connection instead would allow an off-path attacker to close it using spoofed packets.
+	if c.perspective == protocol.PerspectiveServer && c.config.DisablePeerMigration && !addrsEqual(p.remoteAddr, c.RemoteAddr()) {
+		c.logger.Debugf("Dropping packet %d received from %s, since peer migration is disabled.", pn, p.remoteAddr)
+		if c.qlogger != nil {
+			c.qlogger.RecordEvent(qlog.PacketDropped{
+				Header: qlog.PacketHeader{
+					PacketType:   qlog.PacketType1RTT,
+					PacketNumber: pn,
+				},
+				Raw:        qlog.RawInfo{Length: int(p.Size())},
+				DatagramID: datagramID,
+				Trigger:    qlog.PacketDropUnexpectedPacket,
+			})
+		}
+		return false, nil
+	}
 	c.largestRcvdAppData = max(c.largestRcvdAppData, pn)
 
 	if c.logger.Debug() {
@@ -1256,8 +1893,12 @@ func (c *Conn) handleShortHeaderPacket(
 			})
 		}
 	}
-	isNonProbing, pathChallenge, err := c.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log)
+	isNonProbing, pathChallenge, err := c.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, p.remoteAddr, log)
 	if err != nil {
+		if err == errStreamDataDropped {
+			c.logger.Debugf("Not acknowledging packet %d, since it contained STREAM data exceeding the out-of-order buffer limit.", pn)
+			return false, nil
+		}
 		return false, err
 	}
 
@@ -1274,12 +1915,14 @@ func (c *Conn) handleShortHeaderPacket(
 		c.pathManager = newPathManager(
 			c.connIDManager.GetConnIDForPath,
 			c.connIDManager.RetireConnIDForPath,
+			c.config.StrictPathValidation,
+			&c.connStats,
 			c.logger,
 		)
 	}
-	destConnID, frames, shouldSwitchPath := c.pathManager.HandlePacket(p.remoteAddr, p.rcvTime, pathChallenge, isNonProbing)
+	destConnID, frames, probeSize, shouldSwitchPath := c.pathManager.HandlePacket(p.remoteAddr, p.rcvTime, p.Size(), pathChallenge, isNonProbing)
 	if len(frames) > 0 {
-		probe, buf, err := c.packer.PackPathProbePacket(destConnID, frames, c.version)
+		probe, buf, err := c.packer.PackPathProbePacket(destConnID, frames, probeSize, c.version)
 		if err != nil {
 			return true, err
 		}
@@ -1293,7 +1936,8 @@ func (c *Conn) handleShortHeaderPacket(
 	if !shouldSwitchPath || pn != c.largestRcvdAppData {
 		return true, nil
 	}
-	c.pathManager.SwitchToPath(p.remoteAddr)
+	oldAddr := c.RemoteAddr()
+	c.pathManager.SwitchToPath(p.remoteAddr, p.rcvTime)
 	c.sentPacketHandler.MigratedPath(p.rcvTime, protocol.ByteCount(c.config.InitialPacketSize))
 	maxPacketSize := protocol.ByteCount(protocol.MaxPacketBufferSize)
 	if c.peerParams.MaxUDPPayloadSize > 0 && c.peerParams.MaxUDPPayloadSize < maxPacketSize {
@@ -1305,7 +1949,36 @@ func (c *Conn) handleShortHeaderPacket(
 		maxPacketSize,
 	)
 	c.conn.ChangeRemoteAddr(p.remoteAddr, p.info)
-	return true, nil
+	// The new path was validated, so the CONNECTION_CLOSE (if any) is sent on the new path.
+	return true, c.verifyPeerMigration(oldAddr, p.remoteAddr)
+}
+
+// verifyPeerMigration is called after the client migrated to a new path.
+// If it returns an error, the connection is closed.
+func (c *Conn) verifyPeerMigration(oldAddr, newAddr net.Addr) error {
+	if c.config.VerifyPeerMigration == nil {
+		return nil
+	}
+	action, err := c.config.VerifyPeerMigration(c, oldAddr, newAddr)
+	if err != nil {
+		return err
+	}
+	if action == PeerMigrationRevalidate {
+		c.requiresRevalidation.Store(true)
+	}
+	return nil
+}
+
+// RequiresRevalidation says if the application state associated with the connection needs to be
+// revalidated, because Config.VerifyPeerMigration returned PeerMigrationRevalidate.
+// The flag is cleared by calling MarkRevalidated.
+func (c *Conn) RequiresRevalidation() bool {
+	return c.requiresRevalidation.Load()
+}
+
+// MarkRevalidated marks the application state associated with the connection as revalidated.
+func (c *Conn) MarkRevalidated() {
+	c.requiresRevalidation.Store(false)
 }
 
 func (c *Conn) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header, datagramID qlog.DatagramID) (wasProcessed bool, _ error) {
@@ -1339,6 +2012,23 @@ func (c *Conn) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header, datagr
 		c.logger.Debugf("Dropping Initial packet (%d bytes) with unexpected source connection ID: %s (expected %s)", p.Size(), hdr.SrcConnectionID, c.handshakeDestConnID)
 		return false, nil
 	}
+	// Initial packets received from a different IP address might have been replayed by an attacker.
+	if c.perspective == protocol.PerspectiveServer && hdr.Type == protocol.PacketTypeInitial &&
+		!ipsWithinTolerance(p.remoteAddr, c.RemoteAddr(), c.config.InitialPacketIPTolerance) {
+		if c.qlogger != nil {
+			c.qlogger.RecordEvent(qlog.PacketDropped{
+				Header: qlog.PacketHeader{
+					PacketType:   qlog.PacketTypeInitial,
+					PacketNumber: protocol.InvalidPacketNumber,
+				},
+				Raw:        qlog.RawInfo{Length: int(p.Size())},
+				DatagramID: datagramID,
+				Trigger:    qlog.PacketDropDOSPrevention,
+			})
+		}
+		c.logger.Debugf("Dropping Initial packet (%d bytes) from unexpected address %s (expected %s)", p.Size(), p.remoteAddr, c.RemoteAddr())
+		return false, nil
+	}
 	// drop 0-RTT packets, if we are a client
 	if c.perspective == protocol.PerspectiveClient && hdr.Type == protocol.PacketType0RTT {
 		if c.qlogger != nil {
@@ -1386,6 +2076,10 @@ func (c *Conn) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header, datagr
 	}
 
 	if err := c.handleUnpackedLongHeaderPacket(packet, p.ecn, p.rcvTime, datagramID, p.Size()); err != nil {
+		if err == errStreamDataDropped {
+			c.logger.Debugf("Not acknowledging packet %d, since it contained STREAM data exceeding the out-of-order buffer limit.", packet.hdr.PacketNumber)
+			return false, nil
+		}
 		return false, err
 	}
 	return true, nil
@@ -1479,6 +2173,9 @@ func (c *Conn) handleRetryPacket(hdr *wire.Header, data []byte, rcvTime monotime
 		c.logger.Debugf("Ignoring Retry.")
 		return false
 	}
+	c.connStateMutex.Lock()
+	c.connState.AddressValidation.RetriesReceived++
+	c.connStateMutex.Unlock()
 	if c.receivedFirstPacket {
 		if c.qlogger != nil {
 			c.qlogger.RecordEvent(qlog.PacketDropped{
@@ -1545,6 +2242,10 @@ func (c *Conn) handleRetryPacket(hdr *wire.Header, data []byte, rcvTime monotime
 	c.cryptoStreamHandler.ChangeConnectionID(newDestConnID)
 	c.packer.SetToken(hdr.Token)
 	c.connIDManager.ChangeInitialConnID(newDestConnID)
+	c.connStateMutex.Lock()
+	c.connState.AddressValidation.UsedRetry = true
+	c.connState.AddressValidation.RetrySrcConnectionID = newDestConnID
+	c.connStateMutex.Unlock()
 
 	if c.logger.Debug() {
 		c.logger.Debugf("<- Received Retry:")
@@ -1738,7 +2439,7 @@ func (c *Conn) handleUnpackedLongHeaderPacket(
 			})
 		}
 	}
-	isAckEliciting, _, _, err := c.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log, rcvTime)
+	isAckEliciting, _, _, _, err := c.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, nil, log, rcvTime)
 	if err != nil {
 		return err
 	}
@@ -1752,18 +2453,24 @@ func (c *Conn) handleUnpackedShortHeaderPacket(
 	data []byte,
 	ecn protocol.ECN,
 	rcvTime monotime.Time,
+	remoteAddr net.Addr,
 	log func([]qlog.Frame),
 ) (isNonProbing bool, pathChallenge *wire.PathChallengeFrame, _ error) {
 	c.lastPacketReceivedTime = rcvTime
 	c.firstAckElicitingPacketAfterIdleSentTime = 0
 	c.keepAlivePingSent = false
 
-	isAckEliciting, isNonProbing, pathChallenge, err := c.handleFrames(data, destConnID, protocol.Encryption1RTT, log, rcvTime)
+	isAckEliciting, isLatencyTolerant, isNonProbing, pathChallenge, err := c.handleFrames(data, destConnID, protocol.Encryption1RTT, remoteAddr, log, rcvTime)
 	if err != nil {
 		return false, nil, err
 	}
 	c.sentPacketHandler.ReceivedPacket(protocol.Encryption1RTT, rcvTime)
-	if err := c.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting); err != nil {
+	if isLatencyTolerant {
+		err = c.receivedPacketHandler.ReceivedLatencyTolerantPacket(pn, ecn, rcvTime)
+	} else {
+		err = c.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
+	}
+	if err != nil {
 		return false, nil, err
 	}
 	return isNonProbing, pathChallenge, nil
@@ -1771,13 +2478,27 @@ func (c *Conn) handleUnpackedShortHeaderPacket(
 
 // handleFrames parses the frames, one after the other, and handles them.
 // It returns the last PATH_CHALLENGE frame contained in the packet, if any.
+// A packet is latency-tolerant if all its ack-eliciting frames are STREAM frames for streams
+// that the application marked using SetAckLatencyHint.
+// The remote address is only needed for 1-RTT packets, it may be nil for long header packets.
 func (c *Conn) handleFrames(
 	data []byte,
 	destConnID protocol.ConnectionID,
 	encLevel protocol.EncryptionLevel,
+	remoteAddr net.Addr,
 	log func([]qlog.Frame),
 	rcvTime monotime.Time,
-) (isAckEliciting, isNonProbing bool, pathChallenge *wire.PathChallengeFrame, _ error) {
+) (isAckEliciting, isLatencyTolerant, isNonProbing bool, pathChallenge *wire.PathChallengeFrame, err error) {
+	if c.config.StrictMode {
+		defer func() {
+			if err != nil {
+				c.recordProtocolViolation(err)
+			}
+		}()
+	}
+	if c.faultInjector != nil {
+		applyPlaintextFault(c.faultInjector, FaultDirectionIncoming, data)
+	}
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []qlog.Frame
@@ -1786,7 +2507,16 @@ func (c *Conn) handleFrames(
 	}
 	handshakeWasComplete := c.handshakeComplete
 	var handleErr error
-	var skipHandling bool
+	// Set if a STREAM frame exceeds the out-of-order buffer limit.
+	// The packet is not acknowledged, and the peer will retransmit all of its frames.
+	// The limit is checked before handling the first frame, so none of the frames are handled.
+	droppedStreamData := c.exceedsOutOfOrderLimit(data, encLevel)
+	if droppedStreamData && log == nil {
+		return false, false, false, nil, errStreamDataDropped
+	}
+	// if we're logging, we need to keep parsing (but not handling) all frames
+	skipHandling := droppedStreamData
+	isLatencyTolerant = true
 
 	for len(data) > 0 {
 		frameType, l, err := c.frameParser.ParseType(data, encLevel)
@@ -1796,12 +2526,15 @@ func (c *Conn) handleFrames(
 			if err == io.EOF {
 				break
 			}
-			return false, false, nil, err
+			return false, false, false, nil, err
 		}
 		data = data[l:]
 
 		if ackhandler.IsFrameTypeAckEliciting(frameType) {
 			isAckEliciting = true
+			if !frameType.IsStreamFrameType() {
+				isLatencyTolerant = false
+			}
 		}
 		if !wire.IsProbingFrameType(frameType) {
 			isNonProbing = true
@@ -1812,27 +2545,43 @@ func (c *Conn) handleFrames(
 		if frameType.IsStreamFrameType() {
 			streamFrame, l, err := c.frameParser.ParseStreamFrame(frameType, data, c.version)
 			if err != nil {
-				return false, false, nil, err
+				return false, false, false, nil, err
 			}
 			data = data[l:]
 
 			if log != nil {
-				frames = append(frames, toQlogFrame(streamFrame))
+				frames = append(frames, toQlogFrame(streamFrame, c.config.ErrorSpaces))
 			}
 			// an error occurred handling a previous frame, don't handle the current frame
 			if skipHandling {
 				continue
 			}
 			wire.LogFrame(c.logger, streamFrame, false)
+			if c.streamEvents != nil {
+				c.streamEvents.ReceivedStreamFrame(streamFrame)
+			}
 			handleErr = c.streamsMap.HandleStreamFrame(streamFrame, rcvTime)
+			// This was already checked by exceedsOutOfOrderLimit, before handling the first frame.
+			// It can only happen here if the packet couldn't be parsed, and the connection is closed anyway.
+			if handleErr == errStreamDataDropped {
+				droppedStreamData = true
+				handleErr = nil
+				skipHandling = true
+				if log == nil {
+					break
+				}
+			}
+			if isLatencyTolerant && !c.streamsMap.IsAckLatencyTolerant(streamFrame.StreamID) {
+				isLatencyTolerant = false
+			}
 		} else if frameType.IsAckFrameType() {
 			ackFrame, l, err := c.frameParser.ParseAckFrame(frameType, data, encLevel, c.version)
 			if err != nil {
-				return false, false, nil, err
+				return false, false, false, nil, err
 			}
 			data = data[l:]
 			if log != nil {
-				frames = append(frames, toQlogFrame(ackFrame))
+				frames = append(frames, toQlogFrame(ackFrame, c.config.ErrorSpaces))
 			}
 			// an error occurred handling a previous frame, don't handle the current frame
 			if skipHandling {
@@ -1843,12 +2592,12 @@ func (c *Conn) handleFrames(
 		} else if frameType.IsDatagramFrameType() {
 			datagramFrame, l, err := c.frameParser.ParseDatagramFrame(frameType, data, c.version)
 			if err != nil {
-				return false, false, nil, err
+				return false, false, false, nil, err
 			}
 			data = data[l:]
 
 			if log != nil {
-				frames = append(frames, toQlogFrame(datagramFrame))
+				frames = append(frames, toQlogFrame(datagramFrame, c.config.ErrorSpaces))
 			}
 			// an error occurred handling a previous frame, don't handle the current frame
 			if skipHandling {
@@ -1859,18 +2608,18 @@ func (c *Conn) handleFrames(
 		} else {
 			frame, l, err := c.frameParser.ParseLessCommonFrame(frameType, data, c.version)
 			if err != nil {
-				return false, false, nil, err
+				return false, false, false, nil, err
 			}
 			data = data[l:]
 
 			if log != nil {
-				frames = append(frames, toQlogFrame(frame))
+				frames = append(frames, toQlogFrame(frame, c.config.ErrorSpaces))
 			}
 			// an error occurred handling a previous frame, don't handle the current frame
 			if skipHandling {
 				continue
 			}
-			pc, err := c.handleFrame(frame, encLevel, destConnID, rcvTime)
+			pc, err := c.handleFrame(frame, encLevel, destConnID, remoteAddr, rcvTime)
 			if pc != nil {
 				pathChallenge = pc
 			}
@@ -1878,10 +2627,13 @@ func (c *Conn) handleFrames(
 		}
 
 		if handleErr != nil {
+			if c.config.StrictMode {
+				handleErr = withFrameType(handleErr, frameType)
+			}
 			// if we're logging, we need to keep parsing (but not handling) all frames
 			skipHandling = true
 			if log == nil {
-				return false, false, nil, handleErr
+				return false, false, false, nil, handleErr
 			}
 		}
 	}
@@ -1889,7 +2641,7 @@ func (c *Conn) handleFrames(
 	if log != nil {
 		log(frames)
 		if handleErr != nil {
-			return false, false, nil, handleErr
+			return false, false, false, nil, handleErr
 		}
 	}
 
@@ -1899,16 +2651,108 @@ func (c *Conn) handleFrames(
 	// and an ACK serialized after that CRYPTO frame. In this case, we still want to process the ACK frame.
 	if !handshakeWasComplete && c.handshakeComplete {
 		if err := c.handleHandshakeComplete(rcvTime); err != nil {
-			return false, false, nil, err
+			return false, false, false, nil, err
 		}
 	}
+	if droppedStreamData {
+		return false, false, false, nil, errStreamDataDropped
+	}
+	isLatencyTolerant = isLatencyTolerant && isAckEliciting
 	return
 }
 
+// exceedsOutOfOrderLimit says if any STREAM frame in the packet exceeds the out-of-order buffer limit,
+// see Config.MaxStreamOutOfOrderBuffer.
+// Since such a packet is not acknowledged, this needs to be checked before handling any of its frames.
+// Parsing errors are ignored, they are returned when the frames are handled.
+func (c *Conn) exceedsOutOfOrderLimit(data []byte, encLevel protocol.EncryptionLevel) bool {
+	if !c.streamsMap.DropsOutOfOrderData() || (encLevel != protocol.Encryption1RTT && encLevel != protocol.Encryption0RTT) {
+		return false
+	}
+	// the out-of-order data of preceding STREAM frames in this packet
+	var pending map[protocol.StreamID]protocol.ByteCount
+	for len(data) > 0 {
+		frameType, l, err := c.frameParser.ParseType(data, encLevel)
+		if err != nil {
+			return false
+		}
+		data = data[l:]
+		switch {
+		case frameType.IsStreamFrameType():
+			f, l, err := c.frameParser.ParseStreamFrame(frameType, data, c.version)
+			if err != nil {
+				return false
+			}
+			data = data[l:]
+			outOfOrder, drop := c.streamsMap.CheckOutOfOrderLimit(f, pending[f.StreamID])
+			if outOfOrder > 0 {
+				if pending == nil {
+					pending = make(map[protocol.StreamID]protocol.ByteCount, 1)
+				}
+				pending[f.StreamID] += outOfOrder
+			}
+			streamID := f.StreamID
+			f.PutBack()
+			if drop {
+				c.logger.Debugf("STREAM frame for stream %d exceeds the out-of-order buffer limit.", streamID)
+				return true
+			}
+		case frameType.IsAckFrameType():
+			_, l, err = c.frameParser.ParseAckFrame(frameType, data, encLevel, c.version)
+			if err != nil {
+				return false
+			}
+			data = data[l:]
+		case frameType.IsDatagramFrameType():
+			_, l, err = c.frameParser.ParseDatagramFrame(frameType, data, c.version)
+			if err != nil {
+				return false
+			}
+			data = data[l:]
+		default:
+			_, l, err = c.frameParser.ParseLessCommonFrame(frameType, data, c.version)
+			if err != nil {
+				return false
+			}
+			data = data[l:]
+		}
+	}
+	return false
+}
+
+// withFrameType sets the frame type of a transport error, unless it is already set.
+func withFrameType(err error, frameType wire.FrameType) error {
+	var transportErr *qerr.TransportError
+	if !errors.As(err, &transportErr) || transportErr.Remote || transportErr.FrameType != 0 {
+		return err
+	}
+	e := *transportErr
+	e.FrameType = uint64(frameType)
+	return &e
+}
+
+// recordProtocolViolation records a transport error caused by the peer's frames.
+// It is only used in strict mode.
+func (c *Conn) recordProtocolViolation(err error) {
+	if c.qlogger == nil {
+		return
+	}
+	var transportErr *qerr.TransportError
+	if !errors.As(err, &transportErr) || transportErr.Remote {
+		return
+	}
+	c.qlogger.RecordEvent(qlog.ProtocolViolation{
+		ErrorCode: transportErr.ErrorCode,
+		FrameType: transportErr.FrameType,
+		Reason:    transportErr.ErrorMessage,
+	})
+}
+
 func (c *Conn) handleFrame(
 	f wire.Frame,
 	encLevel protocol.EncryptionLevel,
 	destConnID protocol.ConnectionID,
+	remoteAddr net.Addr,
 	rcvTime monotime.Time,
 ) (pathChallenge *wire.PathChallengeFrame, _ error) {
 	var err error
@@ -1923,6 +2767,9 @@ func (c *Conn) handleFrame(
 	case *wire.MaxDataFrame:
 		c.connFlowController.UpdateSendWindow(frame.MaximumData)
 	case *wire.MaxStreamDataFrame:
+		if c.streamEvents != nil {
+			c.streamEvents.ReceivedMaxStreamDataFrame(frame)
+		}
 		err = c.streamsMap.HandleMaxStreamDataFrame(frame)
 	case *wire.MaxStreamsFrame:
 		c.streamsMap.HandleMaxStreamsFrame(frame)
@@ -1937,7 +2784,7 @@ func (c *Conn) handleFrame(
 		c.handlePathChallengeFrame(frame)
 		pathChallenge = frame
 	case *wire.PathResponseFrame:
-		err = c.handlePathResponseFrame(frame)
+		err = c.handlePathResponseFrame(frame, remoteAddr)
 	case *wire.NewTokenFrame:
 		err = c.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1998,7 +2845,12 @@ func (c *Conn) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) erro
 }
 
 func (c *Conn) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel, rcvTime monotime.Time) error {
-	if err := c.cryptoStreamManager.HandleCryptoFrame(frame, encLevel); err != nil {
+	err := c.cryptoStreamManager.HandleCryptoFrame(frame, encLevel)
+	// only the run loop updates this value, no need for a compare-and-swap loop
+	if gaps := uint64(c.cryptoStreamManager.NumGaps(encLevel)); gaps > c.connStats.MaxCryptoFrameGaps.Load() {
+		c.connStats.MaxCryptoFrameGaps.Store(gaps)
+	}
+	if err != nil {
 		return err
 	}
 	for {
@@ -2030,9 +2882,7 @@ func (c *Conn) handleHandshakeEvents(now monotime.Time) error {
 			c.restoreTransportParameters(ev.TransportParameters)
 			close(c.earlyConnReadyChan)
 		case handshake.EventReceivedReadKeys:
-			// queue all previously undecryptable packets
-			c.undecryptablePacketsToProcess = append(c.undecryptablePacketsToProcess, c.undecryptablePackets...)
-			c.undecryptablePackets = nil
+			c.processUndecryptablePackets(now)
 		case handshake.EventDiscard0RTTKeys:
 			err = c.dropEncryptionLevel(protocol.Encryption0RTT, now)
 		case handshake.EventWriteInitialData:
@@ -2052,12 +2902,12 @@ func (c *Conn) handlePathChallengeFrame(f *wire.PathChallengeFrame) {
 	}
 }
 
-func (c *Conn) handlePathResponseFrame(f *wire.PathResponseFrame) error {
+func (c *Conn) handlePathResponseFrame(f *wire.PathResponseFrame, remoteAddr net.Addr) error {
 	switch c.perspective {
 	case protocol.PerspectiveClient:
 		return c.handlePathResponseFrameClient(f)
 	case protocol.PerspectiveServer:
-		return c.handlePathResponseFrameServer(f)
+		return c.handlePathResponseFrameServer(f, remoteAddr)
 	default:
 		panic("unreachable")
 	}
@@ -2075,7 +2925,7 @@ func (c *Conn) handlePathResponseFrameClient(f *wire.PathResponseFrame) error {
 	return nil
 }
 
-func (c *Conn) handlePathResponseFrameServer(f *wire.PathResponseFrame) error {
+func (c *Conn) handlePathResponseFrameServer(f *wire.PathResponseFrame, remoteAddr net.Addr) error {
 	if c.pathManager == nil {
 		// since we didn't send PATH_CHALLENGEs yet, we don't expect PATH_RESPONSEs
 		return &qerr.TransportError{
@@ -2083,7 +2933,17 @@ func (c *Conn) handlePathResponseFrameServer(f *wire.PathResponseFrame) error {
 			ErrorMessage: "unexpected PATH_RESPONSE frame",
 		}
 	}
-	c.pathManager.HandlePathResponseFrame(f)
+	challengedPath := c.pathManager.HandlePathResponseFrame(f, remoteAddr)
+	if challengedPath != nil && c.qlogger != nil {
+		ev := qlog.PathResponseMismatch{Validated: !c.config.StrictPathValidation}
+		if addr, ok := challengedPath.(*net.UDPAddr); ok {
+			ev.ChallengedPath = toPathEndpointInfo(addr)
+		}
+		if addr, ok := remoteAddr.(*net.UDPAddr); ok {
+			ev.ReceivedPath = toPathEndpointInfo(addr)
+		}
+		c.qlogger.RecordEvent(ev)
+	}
 	return nil
 }
 
@@ -2118,6 +2978,10 @@ func (c *Conn) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encryption
 	if err != nil {
 		return err
 	}
+	if c.receivedAcks != nil {
+		largestNewlyAcked, rttSample := c.sentPacketHandler.LastAck()
+		c.receivedAcks.ReceivedAck(frame, encLevel, largestNewlyAcked, rttSample, c.lastPacketReceivedTime)
+	}
 	if !acked1RTTPacket {
 		return nil
 	}
@@ -2254,6 +3118,10 @@ func (c *Conn) handleCloseError(closeErr *closeError) {
 	}
 
 	c.streamsMap.CloseWithError(e)
+	unread := c.streamsMap.UnreadData()
+	c.connStateMutex.Lock()
+	c.unreadStreamData = unread
+	c.connStateMutex.Unlock()
 	if c.datagramQueue != nil {
 		c.datagramQueue.CloseWithError(e)
 	}
@@ -2269,18 +3137,25 @@ func (c *Conn) handleCloseError(closeErr *closeError) {
 		if isRemoteClose {
 			initiator = qlog.InitiatorRemote
 		}
+		var applicationErrorSpace string
+		if applicationErrorCode != nil {
+			if space, ok := c.config.ErrorSpaces.LookupApplicationErrorSpace(*applicationErrorCode); ok {
+				applicationErrorSpace = space.Name
+			}
+		}
 		c.qlogger.RecordEvent(qlog.ConnectionClosed{
-			Initiator:        initiator,
-			ConnectionError:  transportErrorCode,
-			ApplicationError: applicationErrorCode,
-			Trigger:          trigger,
-			Reason:           reason,
+			Initiator:             initiator,
+			ConnectionError:       transportErrorCode,
+			ApplicationError:      applicationErrorCode,
+			ApplicationErrorSpace: applicationErrorSpace,
+			Trigger:               trigger,
+			Reason:                reason,
 		})
 	}
 
 	// If this is a remote close we're done here
 	if isRemoteClose {
-		c.connIDGenerator.ReplaceWithClosed(nil, 3*c.rttStats.PTO(false))
+		c.connIDGenerator.ReplaceWithClosed(nil, 0, 3*c.rttStats.PTO(false))
 		return
 	}
 	if closeErr.immediate {
@@ -2297,7 +3172,7 @@ func (c *Conn) handleCloseError(closeErr *closeError) {
 	if err != nil {
 		c.logger.Debugf("Error sending CONNECTION_CLOSE: %s", err)
 	}
-	c.connIDGenerator.ReplaceWithClosed(connClosePacket, 3*c.rttStats.PTO(false))
+	c.connIDGenerator.ReplaceWithClosed(connClosePacket, c.config.ConnectionCloseRetransmitInterval, 3*c.rttStats.PTO(false))
 }
 
 func (c *Conn) dropEncryptionLevel(encLevel protocol.EncryptionLevel, now monotime.Time) error {
@@ -2311,11 +3186,25 @@ func (c *Conn) dropEncryptionLevel(encLevel protocol.EncryptionLevel, now monoti
 	case protocol.Encryption0RTT:
 		c.streamsMap.ResetFor0RTT()
 		c.framer.Handle0RTTRejection()
+		c.drop0RTTDatagrams()
 		return c.connFlowController.Reset()
 	}
 	return c.cryptoStreamManager.Drop(encLevel)
 }
 
+// drop0RTTDatagrams is called when the server rejects 0-RTT.
+// DATAGRAM frames sent in 0-RTT packets are lost, and queued DATAGRAM frames are
+// discarded, since the server might not support datagrams on this connection.
+func (c *Conn) drop0RTTDatagrams() {
+	c.datagrams0RTTRejected.Store(true)
+	dropped := c.num0RTTDatagrams + uint64(c.datagramQueue.DropQueued())
+	c.num0RTTDatagrams = 0
+	c.connStats.Datagrams0RTTDropped.Add(dropped)
+	if dropped > 0 && c.logger.Debug() {
+		c.logger.Debugf("0-RTT rejected. Dropped %d DATAGRAM frames.", dropped)
+	}
+}
+
 // is called for the client, when restoring transport parameters saved for 0-RTT
 func (c *Conn) restoreTransportParameters(params *wire.TransportParameters) {
 	if c.logger.Debug() {
@@ -2348,11 +3237,33 @@ func (c *Conn) restoreTransportParameters(params *wire.TransportParameters) {
 	}
 
 	c.peerParams = params
+	c.peerMaxDatagramFrameSize.Store(int64(params.MaxDatagramFrameSize))
 	c.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	c.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	c.streamsMap.HandleTransportParameters(params)
 }
 
+// preferredAddress returns the address advertised in the server's preferred_address transport parameter.
+// If the server advertised both an IPv4 and an IPv6 address, the one matching the current address family is used.
+func (c *Conn) preferredAddress() (netip.AddrPort, bool) {
+	if c.peerParams == nil || c.peerParams.PreferredAddress == nil {
+		return netip.AddrPort{}, false
+	}
+	pa := c.peerParams.PreferredAddress
+	// prefer the address family that is currently in use
+	if udpAddr, ok := c.conn.RemoteAddr().(*net.UDPAddr); ok && udpAddr.AddrPort().Addr().Unmap().Is4() {
+		if pa.IPv4.IsValid() {
+			return pa.IPv4, true
+		}
+	} else if pa.IPv6.IsValid() {
+		return pa.IPv6, true
+	}
+	if pa.IPv4.IsValid() {
+		return pa.IPv4, true
+	}
+	return pa.IPv6, pa.IPv6.IsValid()
+}
+
 func (c *Conn) handleTransportParameters(params *wire.TransportParameters) error {
 	if c.qlogger != nil {
 		c.qlogTransportParameters(params, c.perspective.Opposite(), false)
@@ -2415,13 +3326,13 @@ func (c *Conn) checkTransportParameters(params *wire.TransportParameters) error
 
 func (c *Conn) applyTransportParameters() {
 	params := c.peerParams
-	// Our local idle timeout will always be > 0.
-	c.idleTimeout = c.config.MaxIdleTimeout
-	// If the peer advertised an idle timeout, take the minimum of the values.
-	if params.MaxIdleTimeout > 0 {
-		c.idleTimeout = min(c.idleTimeout, params.MaxIdleTimeout)
+	c.setIdleTimeout()
+	c.peerMaxDatagramFrameSize.Store(int64(params.MaxDatagramFrameSize))
+	if params.MaxDatagramFrameSize <= 0 {
+		// The peer doesn't support datagrams (anymore).
+		// Don't send any DATAGRAM frames that were queued before the handshake completed.
+		c.datagramQueue.DropQueued()
 	}
-	c.keepAliveInterval = min(c.config.KeepAlivePeriod, c.idleTimeout/2)
 	c.streamsMap.HandleTransportParameters(params)
 	c.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	c.connFlowController.UpdateSendWindow(params.InitialMaxData)
@@ -2430,9 +3341,8 @@ func (c *Conn) applyTransportParameters() {
 	if params.StatelessResetToken != nil {
 		c.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// The connection ID is used for probing the path to the preferred address.
 	if params.PreferredAddress != nil {
-		// Retire the connection ID.
 		c.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
 	maxPacketSize := protocol.ByteCount(protocol.MaxPacketBufferSize)
@@ -2453,6 +3363,11 @@ func (c *Conn) triggerSending(now monotime.Time) error {
 	sendMode := c.sentPacketHandler.SendMode(now)
 	switch sendMode {
 	case ackhandler.SendAny:
+		// While a write batch is open, only acknowledgments are sent.
+		// Everything else is sent once the batch ends (or expires).
+		if c.handshakeConfirmed && c.writeBatch.Deferring(now) {
+			return c.maybeSendAckOnlyPacket(now)
+		}
 		return c.sendPackets(now)
 	case ackhandler.SendNone:
 		c.blocked = blockModeHardBlocked
@@ -2488,18 +3403,29 @@ func (c *Conn) triggerSending(now monotime.Time) error {
 }
 
 func (c *Conn) sendPackets(now monotime.Time) error {
+	c.maybeUpdateMemoryPressure(now)
 	if c.perspective == protocol.PerspectiveClient && c.handshakeConfirmed {
 		if pm := c.pathManagerOutgoing.Load(); pm != nil {
-			connID, frame, tr, ok := pm.NextPathToProbe()
+			connID, frame, tr, remoteAddr, ok := pm.NextPathToProbe()
 			if ok {
-				probe, buf, err := c.packer.PackPathProbePacket(connID, []ackhandler.Frame{frame}, c.version)
+				// The probe packet must only contain probing frames (RFC 9000, Section 9.1),
+				// otherwise the peer would migrate to the probed path.
+				// PATH_CHALLENGE frames are ack-eliciting, so no PING frame is needed.
+				probe, buf, err := c.packer.PackPathProbePacket(connID, []ackhandler.Frame{frame}, protocol.MinInitialPacketSize, c.version)
 				if err != nil {
 					return err
 				}
 				c.logger.Debugf("sending path probe packet from %s", c.LocalAddr())
 				c.logShortHeaderPacket(probe, protocol.ECNNon, buf.Len())
 				c.registerPackedShortHeaderPacket(probe, protocol.ECNNon, now)
-				tr.WriteTo(buf.Data, c.conn.RemoteAddr())
+				if remoteAddr == nil {
+					remoteAddr = c.conn.RemoteAddr()
+				}
+				if tr != nil {
+					tr.WriteTo(buf.Data, remoteAddr)
+				} else {
+					c.sendQueue.SendProbe(buf, remoteAddr, packetInfo{})
+				}
 				// There's (likely) more data to send. Loop around again.
 				c.scheduleSending()
 				return nil
@@ -2559,7 +3485,7 @@ func (c *Conn) sendPackets(now monotime.Time) error {
 }
 
 func (c *Conn) sendPacketsWithoutGSO(now monotime.Time) error {
-	for {
+	for sent := 1; ; sent++ {
 		buf := getPacketBuffer()
 		ecn := c.sentPacketHandler.ECNMode(true)
 		if _, err := c.appendOneShortHeaderPacket(buf, c.maxPacketSize(), ecn, now); err != nil {
@@ -2583,11 +3509,9 @@ func (c *Conn) sendPacketsWithoutGSO(now monotime.Time) error {
 		if sendMode != ackhandler.SendAny {
 			return nil
 		}
-		// Prioritize receiving of packets over sending out more packets.
-		c.receivedPacketMx.Lock()
-		hasPackets := !c.receivedPackets.Empty()
-		c.receivedPacketMx.Unlock()
-		if hasPackets {
+		// Once the send budget is used up, process received packets (and check the timers),
+		// before continuing to send.
+		if sent >= c.config.SendPacketBudget && c.hasQueuedReceivedPackets() {
 			c.pacingDeadline = deadlineSendImmediately
 			return nil
 		}
@@ -2599,6 +3523,7 @@ func (c *Conn) sendPacketsWithGSO(now monotime.Time) error {
 	maxSize := c.maxPacketSize()
 
 	ecn := c.sentPacketHandler.ECNMode(true)
+	var sent int
 	for {
 		var dontSendMore bool
 		size, err := c.appendOneShortHeaderPacket(buf, maxSize, ecn, now)
@@ -2636,6 +3561,7 @@ func (c *Conn) sendPacketsWithGSO(now monotime.Time) error {
 		}
 
 		c.sendQueue.Send(buf, uint16(maxSize), ecn)
+		sent++
 
 		if dontSendMore {
 			return nil
@@ -2644,11 +3570,9 @@ func (c *Conn) sendPacketsWithGSO(now monotime.Time) error {
 			return nil
 		}
 
-		// Prioritize receiving of packets over sending out more packets.
-		c.receivedPacketMx.Lock()
-		hasPackets := !c.receivedPackets.Empty()
-		c.receivedPacketMx.Unlock()
-		if hasPackets {
+		// Once the send budget is used up, process received packets (and check the timers),
+		// before continuing to send.
+		if sent >= c.config.SendPacketBudget && c.hasQueuedReceivedPackets() {
 			c.pacingDeadline = deadlineSendImmediately
 			return nil
 		}
@@ -2658,6 +3582,12 @@ func (c *Conn) sendPacketsWithGSO(now monotime.Time) error {
 	}
 }
 
+func (c *Conn) hasQueuedReceivedPackets() bool {
+	c.receivedPacketMx.Lock()
+	defer c.receivedPacketMx.Unlock()
+	return !c.receivedPackets.Empty()
+}
+
 func (c *Conn) resetPacingDeadline() {
 	deadline := c.sentPacketHandler.TimeUntilSend()
 	if deadline.IsZero() {
@@ -2782,6 +3712,9 @@ func (c *Conn) registerPackedShortHeaderPacket(p shortHeaderPacket, ecn protocol
 		p.IsPathMTUProbePacket,
 		false,
 	)
+	if c.streamEvents != nil {
+		c.streamEvents.SentPacket(p.StreamFrames, p.Frames)
+	}
 	c.connIDManager.SentPacket()
 }
 
@@ -2807,6 +3740,16 @@ func (c *Conn) sendPackedCoalescedPacket(packet *coalescedPacket, ecn protocol.E
 			false,
 			false,
 		)
+		if c.streamEvents != nil {
+			c.streamEvents.SentPacket(p.streamFrames, p.frames)
+		}
+		if p.EncryptionLevel() == protocol.Encryption0RTT {
+			for _, f := range p.frames {
+				if _, ok := f.Frame.(*wire.DatagramFrame); ok {
+					c.num0RTTDatagrams++
+				}
+			}
+		}
 		if c.perspective == protocol.PerspectiveClient && p.EncryptionLevel() == protocol.EncryptionHandshake &&
 			!c.droppedInitialKeys {
 			// On the client side, Initial keys are dropped as soon as the first Handshake packet is sent.
@@ -2836,6 +3779,9 @@ func (c *Conn) sendPackedCoalescedPacket(packet *coalescedPacket, ecn protocol.E
 			p.IsPathMTUProbePacket,
 			false,
 		)
+		if c.streamEvents != nil {
+			c.streamEvents.SentPacket(p.StreamFrames, p.Frames)
+		}
 	}
 	c.connIDManager.SentPacket()
 	c.sendQueue.Send(packet.buffer, 0, ecn)
@@ -2899,6 +3845,7 @@ func (c *Conn) AcceptUniStream(ctx context.Context) (*ReceiveStream, error) {
 // or the stream has been reset or closed.
 // When reaching the peer's stream limit, it is not possible to open a new stream until the
 // peer raises the stream limit. In that case, a [StreamLimitReachedError] is returned.
+// Once all stream IDs have been used, [ErrStreamIDExhausted] is returned.
 func (c *Conn) OpenStream() (*Stream, error) {
 	return c.streamsMap.OpenStream()
 }
@@ -2908,6 +3855,7 @@ func (c *Conn) OpenStream() (*Stream, error) {
 // There is no signaling to the peer about new streams:
 // The peer can only accept the stream after data has been sent on the stream,
 // or the stream has been reset or closed.
+// Once all stream IDs have been used, [ErrStreamIDExhausted] is returned.
 func (c *Conn) OpenStreamSync(ctx context.Context) (*Stream, error) {
 	return c.streamsMap.OpenStreamSync(ctx)
 }
@@ -2918,6 +3866,7 @@ func (c *Conn) OpenStreamSync(ctx context.Context) (*Stream, error) {
 // or the stream has been reset or closed.
 // When reaching the peer's stream limit, it is not possible to open a new stream until the
 // peer raises the stream limit. In that case, a [StreamLimitReachedError] is returned.
+// Once all stream IDs have been used, [ErrStreamIDExhausted] is returned.
 func (c *Conn) OpenUniStream() (*SendStream, error) {
 	return c.streamsMap.OpenUniStream()
 }
@@ -2927,30 +3876,91 @@ func (c *Conn) OpenUniStream() (*SendStream, error) {
 // There is no signaling to the peer about new streams:
 // The peer can only accept the stream after data has been sent on the stream,
 // or the stream has been reset or closed.
+// Once all stream IDs have been used, [ErrStreamIDExhausted] is returned.
 func (c *Conn) OpenUniStreamSync(ctx context.Context) (*SendStream, error) {
 	return c.streamsMap.OpenUniStreamSync(ctx)
 }
 
 func (c *Conn) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
 	initialSendWindow := c.peerParams.InitialMaxStreamDataUni
+	initialReceiveWindow := c.config.InitialStreamReceiveWindowUni
 	if id.Type() == protocol.StreamTypeBidi {
 		if id.InitiatedBy() == c.perspective {
 			initialSendWindow = c.peerParams.InitialMaxStreamDataBidiRemote
+			initialReceiveWindow = c.config.InitialStreamReceiveWindowBidiLocal
 		} else {
 			initialSendWindow = c.peerParams.InitialMaxStreamDataBidiLocal
+			initialReceiveWindow = c.config.InitialStreamReceiveWindowBidiRemote
 		}
 	}
+	maxReceiveWindow := c.config.MaxStreamReceiveWindow
+	if memoryPressureLevel(c.memoryPressure.Load()) == memoryPressureCritical {
+		maxReceiveWindow = min(maxReceiveWindow, initialReceiveWindow)
+	}
 	return flowcontrol.NewStreamFlowController(
 		id,
 		c.connFlowController,
-		protocol.ByteCount(c.config.InitialStreamReceiveWindow),
-		protocol.ByteCount(c.config.MaxStreamReceiveWindow),
+		protocol.ByteCount(initialReceiveWindow),
+		protocol.ByteCount(maxReceiveWindow),
 		initialSendWindow,
 		c.rttStats,
 		c.logger,
 	)
 }
 
+func (c *Conn) maybeUpdateMemoryPressure(now monotime.Time) {
+	if !c.lastMemoryPressureCheck.IsZero() && now.Sub(c.lastMemoryPressureCheck) < memoryPressureCheckInterval {
+		return
+	}
+	hook := c.config.SendBufferMemoryPressureHook
+	if hook == nil {
+		// Reading the heap statistics stops the world.
+		// Only do this if the application limited the send buffer.
+		if c.config.MaxSendBufferPerStream == 0 {
+			return
+		}
+		hook = heapMemoryPressure
+	}
+	c.lastMemoryPressureCheck = now
+	level := memoryPressureLevelFromFactor(hook())
+	if level == memoryPressureLevel(c.memoryPressure.Swap(uint32(level))) {
+		return
+	}
+	c.logger.Debugf("Memory pressure level changed to %d", level)
+	if c.config.MaxSendBufferPerStream > 0 {
+		c.streamsMap.SetMaxSendBuffer(level.sendBufferLimit(protocol.ByteCount(c.config.MaxSendBufferPerStream)))
+	}
+}
+
+// Batch calls fn, deferring the sending of data written to streams and of datagrams until fn returns.
+// This allows data written to multiple streams to be packed into as few packets as possible.
+// See BeginBatch for details.
+func (c *Conn) Batch(fn func()) {
+	c.BeginBatch()
+	defer c.EndBatch()
+	fn()
+}
+
+// BeginBatch opens a write batch. Until the batch is ended by EndBatch,
+// the connection defers sending packets, with the exception of acknowledgments.
+// Batches can be nested, sending resumes when the outermost batch ends.
+// Batches apply to the whole connection: while a batch is open, data written
+// by all goroutines is deferred.
+// To prevent an open batch from stalling the connection, sending resumes at most 10ms after
+// the outermost batch was opened, even if the batch wasn't ended yet.
+// Batching only takes effect once the handshake has been confirmed.
+func (c *Conn) BeginBatch() {
+	c.writeBatch.Begin(monotime.Now())
+}
+
+// EndBatch ends a write batch opened by BeginBatch.
+// Calling EndBatch without a corresponding call to BeginBatch is a no-op.
+func (c *Conn) EndBatch() {
+	if c.writeBatch.End() {
+		c.scheduleSending()
+	}
+}
+
 // scheduleSending signals that we have data for sending
 func (c *Conn) scheduleSending() {
 	select {
@@ -2965,7 +3975,8 @@ func (c *Conn) tryQueueingUndecryptablePacket(p receivedPacket, pt qlog.PacketTy
 	if c.handshakeComplete {
 		panic("shouldn't queue undecryptable packets after handshake completion")
 	}
-	if len(c.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
+	if len(c.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets ||
+		(pt == qlog.PacketType1RTT && c.undecryptable1RTTBytes+p.Size() > protocol.MaxUndecryptable1RTTBytes) {
 		if c.qlogger != nil {
 			c.qlogger.RecordEvent(qlog.PacketDropped{
 				Header: qlog.PacketHeader{
@@ -2991,14 +4002,51 @@ func (c *Conn) tryQueueingUndecryptablePacket(p receivedPacket, pt qlog.PacketTy
 			DatagramID: datagramID,
 		})
 	}
+	if pt == qlog.PacketType1RTT {
+		c.undecryptable1RTTBytes += p.Size()
+	}
 	c.undecryptablePackets = append(c.undecryptablePackets, receivedPacketWithDatagramID{receivedPacket: p, datagramID: datagramID})
 }
 
+// processUndecryptablePackets queues all previously undecryptable packets for processing.
+// 1-RTT packets that were queued for too long are dropped: by now, the peer has likely
+// already declared them lost and retransmitted their contents.
+func (c *Conn) processUndecryptablePackets(now monotime.Time) {
+	maxAge := 3 * c.rttStats.PTO(false)
+	for _, p := range c.undecryptablePackets {
+		if !wire.IsLongHeaderPacket(p.data[0]) && now.Sub(p.rcvTime) > maxAge {
+			if c.qlogger != nil {
+				c.qlogger.RecordEvent(qlog.PacketDropped{
+					Header: qlog.PacketHeader{
+						PacketType:   qlog.PacketType1RTT,
+						PacketNumber: protocol.InvalidPacketNumber,
+					},
+					Raw:        qlog.RawInfo{Length: int(p.Size())},
+					DatagramID: p.datagramID,
+					Trigger:    qlog.PacketDropKeyUnavailable,
+				})
+			}
+			c.logger.Debugf("Dropping 1-RTT packet (%d bytes) that was queued for too long.", p.Size())
+			p.buffer.Decrement()
+			p.buffer.MaybeRelease()
+			continue
+		}
+		c.undecryptablePacketsToProcess = append(c.undecryptablePacketsToProcess, p)
+	}
+	c.undecryptablePackets = nil
+	c.undecryptable1RTTBytes = 0
+}
+
 func (c *Conn) queueControlFrame(f wire.Frame) {
 	c.framer.QueueControlFrame(f)
 	c.scheduleSending()
 }
 
+func (c *Conn) queueControlFrameWithHandler(f ackhandler.Frame) {
+	c.framer.QueueControlFrameWithHandler(f)
+	c.scheduleSending()
+}
+
 func (c *Conn) onHasConnectionData() { c.scheduleSending() }
 
 func (c *Conn) onHasStreamData(id protocol.StreamID, str *SendStream) {
@@ -3015,17 +4063,51 @@ func (c *Conn) onStreamCompleted(id protocol.StreamID) {
 	if err := c.streamsMap.DeleteStream(id); err != nil {
 		c.closeLocal(err)
 	}
+	if c.streamEvents != nil {
+		c.streamEvents.Closed(id)
+	}
 	c.framer.RemoveActiveStream(id)
 }
 
+// SendPing sends a PING frame to the peer.
+// The round-trip time of the PING, measured from the call to SendPing until the packet carrying
+// the PING frame is acknowledged, is recorded, see PingRTTHistory and PingRTTStats.
+// PING frames are not retransmitted when lost, and lost PINGs don't produce a measurement.
+func (c *Conn) SendPing() {
+	c.queueControlFrameWithHandler(c.pingTracker.NewPing(monotime.Now()))
+}
+
+// PingRTTHistory returns the round-trip times of the last 10 PINGs sent using SendPing,
+// oldest first.
+// Unlike the RTT estimate in ConnectionStats, these measurements are not corrected for the
+// peer's ACK delay, and are therefore usually slightly larger than the network round-trip time.
+func (c *Conn) PingRTTHistory() []time.Duration {
+	return c.pingTracker.History()
+}
+
+// PingRTTStats returns the minimum, maximum and average of the round-trip times returned by PingRTTHistory.
+// If no round-trip time was measured yet, all values are zero.
+func (c *Conn) PingRTTStats() (minRTT, maxRTT, avgRTT time.Duration) {
+	return c.pingTracker.Stats()
+}
+
 // SendDatagram sends a message using a QUIC datagram, as specified in RFC 9221,
 // if the peer enabled datagram support.
 // There is no delivery guarantee for DATAGRAM frames, they are not retransmitted if lost.
 // The payload of the datagram needs to fit into a single QUIC packet.
 // In addition, a datagram may be dropped before being sent out if the available packet size suddenly decreases.
 // If the payload is too large to be sent at the current time, a DatagramTooLargeError is returned.
+//
+// When using 0-RTT, datagrams can be sent before the handshake completes, if the transport parameters
+// remembered from the previous connection allowed datagrams. If the server rejects 0-RTT, these datagrams
+// are dropped (see [ConnectionStats.Datagrams0RTTDropped]), and SendDatagram returns [Err0RTTRejected]
+// until [Conn.NextConnection] is called.
 func (c *Conn) SendDatagram(p []byte) error {
-	if !c.supportsDatagrams() {
+	if c.datagrams0RTTRejected.Load() {
+		return Err0RTTRejected
+	}
+	maxDatagramFrameSize := protocol.ByteCount(c.peerMaxDatagramFrameSize.Load())
+	if maxDatagramFrameSize <= 0 {
 		return errors.New("datagram support disabled")
 	}
 
@@ -3033,7 +4115,7 @@ func (c *Conn) SendDatagram(p []byte) error {
 	// The payload size estimate is conservative.
 	// Under many circumstances we could send a few more bytes.
 	maxDataLen := min(
-		f.MaxDataLen(c.peerParams.MaxDatagramFrameSize, c.version),
+		f.MaxDataLen(maxDatagramFrameSize, c.version),
 		protocol.ByteCount(c.currentMTUEstimate.Load()),
 	)
 	if protocol.ByteCount(len(p)) > maxDataLen {
@@ -3044,6 +4126,24 @@ func (c *Conn) SendDatagram(p []byte) error {
 	return c.datagramQueue.Add(f)
 }
 
+// SendDatagramIfIdle sends a datagram, unless an ack-eliciting packet was sent within the last idleFor.
+// It reports whether the datagram was sent. If it was suppressed, the return values are false and nil.
+//
+// This allows applications to implement heartbeats that are only sent if the connection has been idle,
+// avoiding additional transmissions (and radio wake-ups) while QUIC is sending anyway.
+// The payload may be empty. Apart from the idleness check, it behaves like SendDatagram.
+// Use ConnectionStats.LastAckElicitingPacketSentAt and ConnectionStats.LastPacketReceivedAt
+// to implement more sophisticated logic.
+func (c *Conn) SendDatagramIfIdle(p []byte, idleFor time.Duration) (bool, error) {
+	if last := monotime.Time(c.connStats.LastAckElicitingPacketSent.Load()); !last.IsZero() && monotime.Since(last) < idleFor {
+		return false, nil
+	}
+	if err := c.SendDatagram(p); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // ReceiveDatagram gets a message received in a QUIC datagram, as specified in RFC 9221.
 func (c *Conn) ReceiveDatagram(ctx context.Context) ([]byte, error) {
 	if !c.config.EnableDatagrams {
@@ -3072,6 +4172,8 @@ func (c *Conn) getPathManager() *pathManagerOutgoing {
 		c.connIDManager.GetConnIDForPath,
 		c.connIDManager.RetireConnIDForPath,
 		c.scheduleSending,
+		c.config.MaxPathChallengeRetransmissions,
+		c.onPathChallengeRetransmitted,
 	)
 	if c.pathManagerOutgoing.CompareAndSwap(old, new) {
 		return new
@@ -3081,6 +4183,28 @@ func (c *Conn) getPathManager() *pathManagerOutgoing {
 	return c.pathManagerOutgoing.Load()
 }
 
+// onPathChallengeRetransmitted is called by the pathManagerOutgoing from the run loop.
+func (c *Conn) onPathChallengeRetransmitted(tr *Transport, remoteAddr net.Addr, count int) {
+	if c.qlogger == nil {
+		return
+	}
+	localAddr := c.LocalAddr()
+	if tr != nil {
+		localAddr = tr.Conn.LocalAddr()
+	}
+	if remoteAddr == nil {
+		remoteAddr = c.RemoteAddr()
+	}
+	ev := qlog.PathChallengeRetransmitted{Count: count}
+	if addr, ok := localAddr.(*net.UDPAddr); ok {
+		ev.Local = toPathEndpointInfo(addr)
+	}
+	if addr, ok := remoteAddr.(*net.UDPAddr); ok {
+		ev.Remote = toPathEndpointInfo(addr)
+	}
+	c.qlogger.RecordEvent(ev)
+}
+
 func (c *Conn) AddPath(t *Transport) (*Path, error) {
 	if c.perspective == protocol.PerspectiveServer {
 		return nil, errors.New("server cannot initiate connection migration")
@@ -3116,6 +4240,16 @@ func (c *Conn) HandshakeComplete() <-chan struct{} {
 	return c.handshakeCompleteChan
 }
 
+// HandshakeConfirmed blocks until the handshake is confirmed (or fails).
+// For the server, the handshake is confirmed as soon as it completes.
+// For the client, the handshake is confirmed when a HANDSHAKE_DONE frame is received from the server,
+// or when the server acknowledges a 1-RTT packet, which can take (at least) one more round trip.
+// Some actions, like initiating a key update or connection migration, are only performed once
+// the handshake is confirmed.
+func (c *Conn) HandshakeConfirmed() <-chan struct{} {
+	return c.handshakeConfirmedChan
+}
+
 // QlogTrace returns the qlog trace of the QUIC connection.
 // It is nil if qlog is not enabled.
 func (c *Conn) QlogTrace() qlogwriter.Trace {
@@ -3129,6 +4263,8 @@ func (c *Conn) QlogTrace() qlogwriter.Trace {
 //
 // Note that 0-RTT rejection invalidates all data sent in 0-RTT packets. It is the
 // application's responsibility to handle this (for example by resending the data).
+// Data sent on streams for which [SendStream.Enable0RTTRetry] was called is resent automatically,
+// and these streams remain usable.
 func (c *Conn) NextConnection(ctx context.Context) (*Conn, error) {
 	// The handshake might fail after the server rejected 0-RTT.
 	// This could happen if the Finished message is malformed or never received.
@@ -3138,6 +4274,7 @@ func (c *Conn) NextConnection(ctx context.Context) (*Conn, error) {
 	case <-c.Context().Done():
 	case <-c.HandshakeComplete():
 		c.streamsMap.UseResetMaps()
+		c.datagrams0RTTRejected.Store(false)
 	}
 	return c, nil
 }
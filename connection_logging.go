@@ -13,7 +13,9 @@ import (
 // ConvertFrame converts a wire.Frame into a logging.Frame.
 // This makes it possible for external packages to access the frames.
 // Furthermore, it removes the data slices from CRYPTO and STREAM frames.
-func toQlogFrame(frame wire.Frame) qlog.Frame {
+// For RESET_STREAM, STOP_SENDING and CONNECTION_CLOSE frames, it adds the name of the error space
+// that the error code belongs to.
+func toQlogFrame(frame wire.Frame, errorSpaces *ErrorSpaces) qlog.Frame {
 	switch f := frame.(type) {
 	case *wire.AckFrame:
 		// We use a pool for ACK frames.
@@ -41,9 +43,23 @@ func toQlogFrame(frame wire.Frame) qlog.Frame {
 				Length: int64(len(f.Data)),
 			},
 		}
-	default:
-		return qlog.Frame{Frame: frame}
+	case *wire.ResetStreamFrame:
+		if space, ok := errorSpaces.LookupStreamErrorSpace(f.ErrorCode); ok {
+			return qlog.Frame{Frame: frame, ErrorCodeSpace: space.Name}
+		}
+	case *wire.StopSendingFrame:
+		if space, ok := errorSpaces.LookupStreamErrorSpace(f.ErrorCode); ok {
+			return qlog.Frame{Frame: frame, ErrorCodeSpace: space.Name}
+		}
+	case *wire.ConnectionCloseFrame:
+		if !f.IsApplicationError {
+			break
+		}
+		if space, ok := errorSpaces.LookupApplicationErrorSpace(ApplicationErrorCode(f.ErrorCode)); ok {
+			return qlog.Frame{Frame: frame, ErrorCodeSpace: space.Name}
+		}
 	}
+	return qlog.Frame{Frame: frame}
 }
 
 func toQlogAckFrame(f *wire.AckFrame) *qlog.AckFrame {
@@ -80,13 +96,13 @@ func (c *Conn) logLongHeaderPacket(p *longHeaderPacket, ecn protocol.ECN, datagr
 		}
 		frames := make([]qlog.Frame, 0, numFrames)
 		if p.ack != nil {
-			frames = append(frames, toQlogFrame(p.ack))
+			frames = append(frames, toQlogFrame(p.ack, c.config.ErrorSpaces))
 		}
 		for _, f := range p.frames {
-			frames = append(frames, toQlogFrame(f.Frame))
+			frames = append(frames, toQlogFrame(f.Frame, c.config.ErrorSpaces))
 		}
 		for _, f := range p.streamFrames {
-			frames = append(frames, toQlogFrame(f.Frame))
+			frames = append(frames, toQlogFrame(f.Frame, c.config.ErrorSpaces))
 		}
 		c.qlogger.RecordEvent(qlog.PacketSent{
 			Header: qlog.PacketHeader{
@@ -138,13 +154,13 @@ func (c *Conn) logShortHeaderPacketWithDatagramID(p shortHeaderPacket, ecn proto
 		}
 		fs := make([]qlog.Frame, 0, numFrames)
 		if p.Ack != nil {
-			fs = append(fs, toQlogFrame(p.Ack))
+			fs = append(fs, toQlogFrame(p.Ack, c.config.ErrorSpaces))
 		}
 		for _, f := range p.Frames {
-			fs = append(fs, toQlogFrame(f.Frame))
+			fs = append(fs, toQlogFrame(f.Frame, c.config.ErrorSpaces))
 		}
 		for _, f := range p.StreamFrames {
-			fs = append(fs, toQlogFrame(f.Frame))
+			fs = append(fs, toQlogFrame(f.Frame, c.config.ErrorSpaces))
 		}
 		c.qlogger.RecordEvent(qlog.PacketSent{
 			Header: qlog.PacketHeader{
@@ -15,7 +15,7 @@ func TestConnectionLoggingCryptoFrame(t *testing.T) {
 	f := toQlogFrame(&wire.CryptoFrame{
 		Offset: 1234,
 		Data:   []byte("foobar"),
-	})
+	}, nil)
 	require.Equal(t, &qlog.CryptoFrame{
 		Offset: 1234,
 		Length: 6,
@@ -28,7 +28,7 @@ func TestConnectionLoggingStreamFrame(t *testing.T) {
 		Offset:   1234,
 		Data:     []byte("foo"),
 		Fin:      true,
-	})
+	}, nil)
 	require.Equal(t, &qlog.StreamFrame{
 		StreamID: 42,
 		Offset:   1234,
@@ -48,7 +48,7 @@ func TestConnectionLoggingAckFrame(t *testing.T) {
 		ECT0:      456,
 		ECT1:      789,
 	}
-	f := toQlogFrame(ack)
+	f := toQlogFrame(ack, nil)
 	// now modify the ACK range in the original frame
 	ack.AckRanges[0].Smallest = 2
 	require.Equal(t, &qlog.AckFrame{
@@ -63,13 +63,29 @@ func TestConnectionLoggingAckFrame(t *testing.T) {
 	}, f.Frame)
 }
 
+func TestConnectionLoggingErrorCodeSpaces(t *testing.T) {
+	var spaces ErrorSpaces
+	_, err := spaces.RegisterStreamErrorSpace(0x100, 0x10, "storage")
+	require.NoError(t, err)
+	_, err = spaces.RegisterApplicationErrorSpace(0x200, 0x10, "auth")
+	require.NoError(t, err)
+
+	require.Equal(t, "storage", toQlogFrame(&wire.ResetStreamFrame{ErrorCode: 0x101}, &spaces).ErrorCodeSpace)
+	require.Equal(t, "storage", toQlogFrame(&wire.StopSendingFrame{ErrorCode: 0x10f}, &spaces).ErrorCodeSpace)
+	require.Empty(t, toQlogFrame(&wire.StopSendingFrame{ErrorCode: 0x110}, &spaces).ErrorCodeSpace)
+	require.Equal(t, "auth", toQlogFrame(&wire.ConnectionCloseFrame{IsApplicationError: true, ErrorCode: 0x201}, &spaces).ErrorCodeSpace)
+	// transport error codes don't belong to any error space
+	require.Empty(t, toQlogFrame(&wire.ConnectionCloseFrame{ErrorCode: 0x201}, &spaces).ErrorCodeSpace)
+	require.Empty(t, toQlogFrame(&wire.ResetStreamFrame{ErrorCode: 0x101}, nil).ErrorCodeSpace)
+}
+
 func TestConnectionLoggingDatagramFrame(t *testing.T) {
-	f := toQlogFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+	f := toQlogFrame(&wire.DatagramFrame{Data: []byte("foobar")}, nil)
 	require.Equal(t, &qlog.DatagramFrame{Length: 6}, f.Frame)
 }
 
 func TestConnectionLoggingOtherFrames(t *testing.T) {
-	f := toQlogFrame(&wire.MaxDataFrame{MaximumData: 1234})
+	f := toQlogFrame(&wire.MaxDataFrame{MaximumData: 1234}, nil)
 	require.Equal(t, &qlog.MaxDataFrame{MaximumData: 1234}, f.Frame)
 }
 
@@ -0,0 +1,111 @@
+package quic
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// A ConnectionRateLimiter limits the number of concurrent handshakes.
+type ConnectionRateLimiter interface {
+	// Allow is called for every new connection attempt, before the connection is created.
+	// If it returns false, the connection attempt is refused with a CONNECTION_REFUSED error.
+	// Note that the address is unvalidated, unless address validation was performed using
+	// QUIC's Retry mechanism, and might be spoofed in case of an attack.
+	Allow(addr net.Addr) bool
+	// Done is called for every connection attempt that was allowed,
+	// once the handshake has completed or failed.
+	Done(addr net.Addr)
+}
+
+const (
+	defaultIPv4SubnetPrefixLen = 24
+	defaultIPv6SubnetPrefixLen = 48
+)
+
+// SubnetConnectionRateLimiter is a ConnectionRateLimiter that limits the number of
+// concurrent handshakes originating from the same subnet.
+// This makes it harder for an attacker controlling a range of addresses to exhaust the server's resources.
+// Setting the prefix lengths to 32 (for IPv4) and 128 (for IPv6) limits the number of handshakes per IP address.
+type SubnetConnectionRateLimiter struct {
+	// MaxHandshakes is the maximum number of concurrent handshakes per subnet.
+	// If zero, the number of handshakes is not limited.
+	MaxHandshakes int
+	// IPv4PrefixLen is the prefix length of IPv4 subnets.
+	// If zero, it defaults to 24.
+	IPv4PrefixLen int
+	// IPv6PrefixLen is the prefix length of IPv6 subnets.
+	// If zero, it defaults to 48.
+	IPv6PrefixLen int
+
+	mutex      sync.Mutex
+	handshakes map[netip.Prefix]int
+}
+
+var _ ConnectionRateLimiter = &SubnetConnectionRateLimiter{}
+
+// Allow allows the connection attempt, unless the maximum number of handshakes
+// from the subnet of addr is reached.
+func (l *SubnetConnectionRateLimiter) Allow(addr net.Addr) bool {
+	subnet, ok := l.subnet(addr)
+	if !ok {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.MaxHandshakes > 0 && l.handshakes[subnet] >= l.MaxHandshakes {
+		return false
+	}
+	if l.handshakes == nil {
+		l.handshakes = make(map[netip.Prefix]int)
+	}
+	l.handshakes[subnet]++
+	return true
+}
+
+// Done releases the handshake slot of the subnet of addr.
+func (l *SubnetConnectionRateLimiter) Done(addr net.Addr) {
+	subnet, ok := l.subnet(addr)
+	if !ok {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.handshakes[subnet] <= 1 {
+		delete(l.handshakes, subnet)
+		return
+	}
+	l.handshakes[subnet]--
+}
+
+func (l *SubnetConnectionRateLimiter) subnet(addr net.Addr) (netip.Prefix, bool) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	ip, ok := netip.AddrFromSlice(udpAddr.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	ip = ip.Unmap()
+
+	prefixLen := l.IPv6PrefixLen
+	if prefixLen == 0 {
+		prefixLen = defaultIPv6SubnetPrefixLen
+	}
+	if ip.Is4() {
+		prefixLen = l.IPv4PrefixLen
+		if prefixLen == 0 {
+			prefixLen = defaultIPv4SubnetPrefixLen
+		}
+	}
+	subnet, err := ip.Prefix(prefixLen)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return subnet, true
+}
@@ -0,0 +1,61 @@
+package quic
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetConnectionRateLimiter(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		testSubnetConnectionRateLimiter(t,
+			&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(10, 0, 0, 254), Port: 4321},
+			&net.UDPAddr{IP: net.IPv4(10, 0, 1, 1), Port: 1234},
+		)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		testSubnetConnectionRateLimiter(t,
+			&net.UDPAddr{IP: net.ParseIP("2001:db8:1:1::1"), Port: 1234},
+			&net.UDPAddr{IP: net.ParseIP("2001:db8:1:ffff::1"), Port: 4321},
+			&net.UDPAddr{IP: net.ParseIP("2001:db8:2::1"), Port: 1234},
+		)
+	})
+}
+
+func testSubnetConnectionRateLimiter(t *testing.T, addr1, addr2SameSubnet, addrOtherSubnet net.Addr) {
+	l := &SubnetConnectionRateLimiter{MaxHandshakes: 2}
+	require.True(t, l.Allow(addr1))
+	require.True(t, l.Allow(addr2SameSubnet))
+	require.False(t, l.Allow(addr1))
+	require.False(t, l.Allow(addr2SameSubnet))
+	require.True(t, l.Allow(addrOtherSubnet))
+
+	l.Done(addr1)
+	require.True(t, l.Allow(addr2SameSubnet))
+	require.False(t, l.Allow(addr1))
+
+	l.Done(addr1)
+	l.Done(addr1)
+	l.Done(addrOtherSubnet)
+	require.Empty(t, l.handshakes)
+}
+
+func TestSubnetConnectionRateLimiterPrefixLength(t *testing.T) {
+	l := &SubnetConnectionRateLimiter{MaxHandshakes: 1, IPv4PrefixLen: 32}
+	require.True(t, l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1)}))
+	require.False(t, l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 42}))
+	require.True(t, l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2)}))
+
+	// IPv4-mapped IPv6 addresses are treated as IPv4 addresses
+	require.False(t, l.Allow(&net.UDPAddr{IP: net.ParseIP("::ffff:10.0.0.2")}))
+}
+
+func TestSubnetConnectionRateLimiterUnlimited(t *testing.T) {
+	l := &SubnetConnectionRateLimiter{}
+	for range 100 {
+		require.True(t, l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1)}))
+	}
+}
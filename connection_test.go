@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/mocks"
@@ -26,6 +27,7 @@ import (
 	"github.com/quic-go/quic-go/internal/wire"
 	"github.com/quic-go/quic-go/qlog"
 	"github.com/quic-go/quic-go/qlogwriter"
+	"github.com/quic-go/quic-go/quicvarint"
 	"github.com/quic-go/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/assert"
@@ -132,8 +134,11 @@ func newServerTestConnection(
 		&tls.Config{},
 		handshake.NewTokenGenerator(handshake.TokenProtectorKey{}),
 		false,
+		AddressValidationInfo{},
 		1337*time.Millisecond,
 		nil,
+		nil,
+		nil,
 		utils.DefaultLogger,
 		protocol.Version1,
 	)
@@ -193,6 +198,8 @@ func newClientTestConnection(
 		enable0RTT,
 		false,
 		nil,
+		nil,
+		nil,
 		utils.DefaultLogger,
 		protocol.Version1,
 	)
@@ -231,7 +238,7 @@ func TestConnectionHandleStreamRelatedFrames(t *testing.T) {
 			tc := newServerTestConnection(t, gomock.NewController(t), nil, false)
 			data, err := test.frame.Append(nil, protocol.Version1)
 			require.NoError(t, err)
-			_, _, _, err = tc.conn.handleFrames(data, connID, protocol.Encryption1RTT, nil, monotime.Now())
+			_, _, _, _, err = tc.conn.handleFrames(data, connID, protocol.Encryption1RTT, nil, nil, monotime.Now())
 			require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.StreamStateError})
 		})
 	}
@@ -245,11 +252,11 @@ func TestConnectionHandleConnectionFlowControlFrames(t *testing.T) {
 	now := monotime.Now()
 	connID := protocol.ConnectionID{}
 	// MAX_DATA frame
-	_, err := tc.conn.handleFrame(&wire.MaxDataFrame{MaximumData: 1337}, protocol.Encryption1RTT, connID, now)
+	_, err := tc.conn.handleFrame(&wire.MaxDataFrame{MaximumData: 1337}, protocol.Encryption1RTT, connID, nil, now)
 	require.NoError(t, err)
 	require.Equal(t, protocol.ByteCount(1337), connFC.SendWindowSize())
 	// DATA_BLOCKED frame
-	_, err = tc.conn.handleFrame(&wire.DataBlockedFrame{MaximumData: 1337}, protocol.Encryption1RTT, connID, now)
+	_, err = tc.conn.handleFrame(&wire.DataBlockedFrame{MaximumData: 1337}, protocol.Encryption1RTT, connID, nil, now)
 	require.NoError(t, err)
 }
 
@@ -266,7 +273,7 @@ func TestConnectionServerInvalidFrames(t *testing.T) {
 		{Name: "PATH_RESPONSE", Frame: &wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}},
 	} {
 		t.Run(test.Name, func(t *testing.T) {
-			_, err := tc.conn.handleFrame(test.Frame, protocol.Encryption1RTT, protocol.ConnectionID{}, monotime.Now())
+			_, err := tc.conn.handleFrame(test.Frame, protocol.Encryption1RTT, protocol.ConnectionID{}, nil, monotime.Now())
 			require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation})
 		})
 	}
@@ -306,7 +313,7 @@ func testConnectionClose(t *testing.T, useApplicationClose bool, expectedErr err
 			tc.packer.EXPECT().PackConnectionClose(expectedErr, gomock.Any(), protocol.Version1).Return(&coalescedPacket{buffer: b}, nil)
 		}
 		tc.sendConn.EXPECT().Write([]byte("connection close"), gomock.Any(), gomock.Any())
-		tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 
 		go func() { errChan <- tc.conn.run() }()
 		tc.conn.closeLocal(expectedErr)
@@ -348,6 +355,24 @@ func testConnectionClose(t *testing.T, useApplicationClose bool, expectedErr err
 	})
 }
 
+func TestConnectionStateRTT(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	cs := mocks.NewMockCryptoSetup(mockCtrl)
+	cs.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).AnyTimes()
+	tc := newServerTestConnection(t, mockCtrl, nil, false, connectionOptCryptoSetup(cs))
+
+	for _, rtt := range []time.Duration{50 * time.Millisecond, 30 * time.Millisecond, 80 * time.Millisecond, 40 * time.Millisecond} {
+		tc.conn.rttStats.UpdateRTT(rtt, 0)
+	}
+	state := tc.conn.ConnectionState()
+	require.Equal(t, tc.conn.rttStats.SmoothedRTT(), state.SmoothedRTT)
+	require.Equal(t, tc.conn.rttStats.MeanDeviation(), state.RTTVariance)
+	require.Equal(t, 40*time.Millisecond, state.LatestRTT)
+	require.Equal(t, 30*time.Millisecond, state.MinRTT)
+	require.NotZero(t, state.SmoothedRTT)
+	require.NotZero(t, state.RTTVariance)
+}
+
 func TestConnectionStatelessReset(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
@@ -368,6 +393,104 @@ func TestConnectionStatelessReset(t *testing.T) {
 	})
 }
 
+func TestConnectionStatelessResetTokenFromTransportParameters(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		cs := mocks.NewMockCryptoSetup(mockCtrl)
+		unpacker := NewMockUnpacker(mockCtrl)
+		var eventRecorder events.Recorder
+		tc := newClientTestConnection(t,
+			mockCtrl,
+			nil,
+			false,
+			connectionOptCryptoSetup(cs),
+			connectionOptUnpacker(unpacker),
+			connectionOptTracer(&eventRecorder),
+		)
+		cs.EXPECT().StartHandshake(gomock.Any())
+		cs.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent}).AnyTimes()
+		cs.EXPECT().Close().AnyTimes()
+		tc.packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), gomock.Any(), protocol.Version1).Return(nil, nil).AnyTimes()
+
+		token := protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+		// the token is registered with the Transport...
+		tc.connRunner.EXPECT().AddResetToken(token, gomock.Any())
+		tc.connRunner.EXPECT().Add(gomock.Any(), gomock.Any()).AnyTimes()
+		require.NoError(t, tc.conn.handleTransportParameters(&wire.TransportParameters{
+			InitialSourceConnectionID:       tc.destConnID,
+			OriginalDestinationConnectionID: tc.destConnID,
+			StatelessResetToken:             &token,
+		}))
+		// the client applies the server's transport parameters when the handshake completes
+		tc.conn.applyTransportParameters()
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		// ... and used to detect stateless resets for packets that can't be decrypted
+		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(
+			protocol.PacketNumber(0), protocol.PacketNumberLen(0), protocol.KeyPhaseBit(0), nil, handshake.ErrDecryptionFailed,
+		).Times(2)
+		otherToken := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0x42, append(make([]byte, 20), otherToken[:]...)))
+		synctest.Wait()
+		select {
+		case <-errChan:
+			t.Fatal("connection should not have been closed")
+		default:
+		}
+
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		tc.connRunner.EXPECT().RemoveResetToken(token).AnyTimes()
+		tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0x43, append(make([]byte, 20), token[:]...)))
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.ErrorIs(t, err, &StatelessResetError{})
+		default:
+			t.Fatal("connection should have been closed")
+		}
+		require.Equal(t,
+			[]qlogwriter.Event{qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, Trigger: qlog.ConnectionCloseTriggerStatelessReset}},
+			eventRecorder.Events(qlog.ConnectionClosed{}),
+		)
+	})
+}
+
+func TestConnectionRuntimeTrace(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			&Config{DisablePathMTUDiscovery: true, EnableRuntimeTrace: true},
+			false,
+		)
+		require.NotNil(t, tc.conn.runtimeTracer)
+		// runtime tracing doesn't enable qlog
+		require.Nil(t, tc.conn.qlogger)
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+		synctest.Wait()
+		require.NotNil(t, tc.conn.runtimeTracer.task)
+		// the congestion controller starts in slow start
+		require.NotNil(t, tc.conn.runtimeTracer.region)
+		require.Equal(t, qlog.CongestionStateSlowStart, tc.conn.runtimeTracer.state)
+
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case <-errChan:
+		default:
+			t.Fatal("connection should have been closed")
+		}
+		// the task is ended when the run loop returns
+		require.Nil(t, tc.conn.runtimeTracer.task)
+		require.Nil(t, tc.conn.runtimeTracer.region)
+	})
+}
+
 func getLongHeaderPacket(t *testing.T, remoteAddr net.Addr, extHdr *wire.ExtendedHeader, data []byte) receivedPacket {
 	t.Helper()
 	b, err := extHdr.Append(nil, protocol.Version1)
@@ -659,6 +782,80 @@ func TestConnectionUnpacking(t *testing.T) {
 	require.True(t, wasProcessed)
 }
 
+func TestConnectionOutOfOrderBufferLimit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	unpacker := NewMockUnpacker(mockCtrl)
+	tc := newServerTestConnection(t,
+		mockCtrl,
+		&Config{MaxStreamOutOfOrderBuffer: 10, DisablePathMTUDiscovery: true},
+		false,
+		connectionOptUnpacker(unpacker),
+	)
+	require.NoError(t, tc.conn.handleTransportParameters(&wire.TransportParameters{}))
+
+	receivePacket := func(pn protocol.PacketNumber, frames ...*wire.StreamFrame) (wasProcessed bool) {
+		t.Helper()
+		var data []byte
+		for _, f := range frames {
+			var err error
+			data, err = f.Append(data, protocol.Version1)
+			require.NoError(t, err)
+		}
+		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(
+			pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, data, nil,
+		)
+		wasProcessed, err := tc.conn.handleOnePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, pn, nil), 0)
+		require.NoError(t, err)
+		return wasProcessed
+	}
+
+	// The packet is dropped, and not acknowledged.
+	// None of its frames are handled, not even the ones preceding the dropped STREAM frame,
+	// since the peer will retransmit all of them.
+	require.False(t, receivePacket(10,
+		&wire.StreamFrame{StreamID: 4, Offset: 100, Data: make([]byte, 5), DataLenPresent: true},
+		&wire.StreamFrame{StreamID: 0, Offset: 100, Data: make([]byte, 11)},
+	))
+	require.False(t, tc.conn.receivedPacketHandler.IsPotentiallyDuplicate(10, protocol.Encryption1RTT))
+	require.Zero(t, tc.conn.ConnectionStats().OutOfOrderStreamBytes)
+
+	// The limit applies to the sum of the out-of-order data of all STREAM frames for the stream in the packet.
+	require.False(t, receivePacket(11,
+		&wire.StreamFrame{StreamID: 0, Offset: 100, Data: make([]byte, 6), DataLenPresent: true},
+		&wire.StreamFrame{StreamID: 0, Offset: 200, Data: make([]byte, 6)},
+	))
+	require.Zero(t, tc.conn.ConnectionStats().OutOfOrderStreamBytes)
+
+	require.True(t, receivePacket(12,
+		&wire.StreamFrame{StreamID: 0, Offset: 100, Data: make([]byte, 10), DataLenPresent: true},
+		&wire.StreamFrame{StreamID: 4, Offset: 100, Data: make([]byte, 5)},
+	))
+	require.True(t, tc.conn.receivedPacketHandler.IsPotentiallyDuplicate(12, protocol.Encryption1RTT))
+	require.Equal(t, uint64(15), tc.conn.ConnectionStats().OutOfOrderStreamBytes)
+}
+
+func TestConnectionCryptoFrameGaps(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	tc := newServerTestConnection(t, mockCtrl, nil, false)
+
+	for i := range protocol.MaxCryptoStreamFrameSorterGaps - 1 {
+		require.NoError(t, tc.conn.handleCryptoFrame(
+			&wire.CryptoFrame{Offset: protocol.ByteCount(2*i + 1), Data: []byte("a")},
+			protocol.EncryptionHandshake,
+			monotime.Now(),
+		))
+	}
+	require.Equal(t, uint64(protocol.MaxCryptoStreamFrameSorterGaps-1), tc.conn.ConnectionStats().MaxCryptoFrameGaps)
+
+	err := tc.conn.handleCryptoFrame(
+		&wire.CryptoFrame{Offset: 1000, Data: []byte("a")},
+		protocol.EncryptionHandshake,
+		monotime.Now(),
+	)
+	require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.CryptoBufferExceeded})
+	require.Equal(t, uint64(protocol.MaxCryptoStreamFrameSorterGaps), tc.conn.ConnectionStats().MaxCryptoFrameGaps)
+}
+
 func TestConnectionUnpackCoalescedPacket(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	unpacker := NewMockUnpacker(mockCtrl)
@@ -768,6 +965,75 @@ func TestConnectionUnpackCoalescedPacket(t *testing.T) {
 	)
 }
 
+func TestConnectionInitialPacketIPTolerance(t *testing.T) {
+	t.Run("strict", func(t *testing.T) {
+		testConnectionInitialPacketIPTolerance(t, 0, net.IPv4(1, 2, 3, 5), false)
+	})
+	t.Run("strict, different port", func(t *testing.T) {
+		testConnectionInitialPacketIPTolerance(t, 0, net.IPv4(1, 2, 3, 4), true)
+	})
+	t.Run("tolerant, different last octet", func(t *testing.T) {
+		testConnectionInitialPacketIPTolerance(t, 1, net.IPv4(1, 2, 3, 5), true)
+	})
+	t.Run("tolerant, different subnet", func(t *testing.T) {
+		testConnectionInitialPacketIPTolerance(t, 1, net.IPv4(1, 2, 4, 4), false)
+	})
+}
+
+func testConnectionInitialPacketIPTolerance(t *testing.T, tolerance int, ip net.IP, expectAccepted bool) {
+	mockCtrl := gomock.NewController(t)
+	unpacker := NewMockUnpacker(mockCtrl)
+	var eventRecorder events.Recorder
+	tc := newServerTestConnection(t,
+		mockCtrl,
+		&Config{InitialPacketIPTolerance: tolerance},
+		false,
+		connectionOptUnpacker(unpacker),
+		connectionOptTracer(&eventRecorder),
+	)
+	hdr := &wire.ExtendedHeader{
+		Header: wire.Header{
+			Type:             protocol.PacketTypeInitial,
+			DestConnectionID: tc.srcConnID,
+			Version:          protocol.Version1,
+			Length:           1,
+		},
+		PacketNumber:    1,
+		PacketNumberLen: protocol.PacketNumberLen1,
+	}
+	// the port is different from the address the connection was started from
+	packet := getLongHeaderPacket(t, &net.UDPAddr{IP: ip, Port: 5678}, hdr, nil)
+	if expectAccepted {
+		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+			encryptionLevel: protocol.EncryptionInitial,
+			hdr:             hdr,
+			data:            []byte{0}, // one PADDING frame
+		}, nil)
+	}
+	wasProcessed, err := tc.conn.handleOnePacket(packet, 0)
+	require.NoError(t, err)
+	require.Equal(t, expectAccepted, wasProcessed)
+
+	if expectAccepted {
+		require.Empty(t, eventRecorder.Events(qlog.PacketDropped{}))
+		require.Len(t, eventRecorder.Events(qlog.PacketReceived{}), 1)
+		return
+	}
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.PacketDropped{
+				Header: qlog.PacketHeader{
+					PacketType:   qlog.PacketTypeInitial,
+					PacketNumber: protocol.InvalidPacketNumber,
+				},
+				Raw:     qlog.RawInfo{Length: int(packet.Size())},
+				Trigger: qlog.PacketDropDOSPrevention,
+			},
+		},
+		eventRecorder.Events(qlog.PacketDropped{}),
+	)
+}
+
 func TestConnectionUnpackFailuresFatal(t *testing.T) {
 	t.Run("other errors", func(t *testing.T) {
 		require.ErrorIs(t,
@@ -794,7 +1060,7 @@ func testConnectionUnpackFailureFatal(t *testing.T, unpackErr error) error {
 		connectionOptUnpacker(unpacker),
 	)
 
-	tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any())
+	tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 	unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(0), protocol.PacketNumberLen(0), protocol.KeyPhaseBit(0), nil, unpackErr)
 	tc.packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), protocol.Version1).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 	errChan := make(chan error, 1)
@@ -922,7 +1188,7 @@ func TestConnectionRemoteClose(t *testing.T) {
 		require.NoError(t, err)
 		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(1), protocol.PacketNumberLen2, protocol.KeyPhaseBit(0), ccf, nil)
 
-		tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any())
+		tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 
 		errChan := make(chan error, 1)
 		go func() { errChan <- tc.conn.run() }()
@@ -1083,6 +1349,110 @@ func TestConnectionTransportParameters(t *testing.T) {
 	)
 }
 
+func TestConnectionStreamFlowControlWindows(t *testing.T) {
+	tc := newServerTestConnection(t, nil, &Config{
+		InitialStreamReceiveWindowBidiLocal:  1000,
+		InitialStreamReceiveWindowBidiRemote: 2000,
+		InitialStreamReceiveWindowUni:        3000,
+	}, false)
+	tc.conn.peerParams = &wire.TransportParameters{
+		InitialMaxStreamDataBidiLocal:  4000,
+		InitialMaxStreamDataBidiRemote: 5000,
+		InitialMaxStreamDataUni:        6000,
+	}
+
+	for _, tt := range []struct {
+		name                      string
+		id                        protocol.StreamID
+		sendWindow, receiveWindow protocol.ByteCount
+	}{
+		// streams opened by the server
+		{name: "bidi local", id: 1, sendWindow: 5000, receiveWindow: 1000},
+		// streams opened by the client
+		{name: "bidi remote", id: 0, sendWindow: 4000, receiveWindow: 2000},
+		{name: "uni remote", id: 2, receiveWindow: 3000},
+		{name: "uni local", id: 3, sendWindow: 6000},
+	} {
+		t.Run(fmt.Sprintf("%s, stream %d", tt.name, tt.id), func(t *testing.T) {
+			sendWindow, _, receiveWindow, _ := tc.conn.newFlowController(tt.id).Offsets()
+			if tt.sendWindow > 0 {
+				require.Equal(t, tt.sendWindow, sendWindow)
+			}
+			if tt.receiveWindow > 0 {
+				require.Equal(t, tt.receiveWindow, receiveWindow)
+			}
+		})
+	}
+}
+
+func TestConnectionMemoryPressure(t *testing.T) {
+	var pressure float64
+	tc := newServerTestConnection(t, nil, &Config{
+		MaxSendBufferPerStream:       1000,
+		SendBufferMemoryPressureHook: func() float64 { return pressure },
+	}, false)
+	params := &wire.TransportParameters{MaxBidiStreamNum: 10, MaxUniStreamNum: 10}
+	tc.conn.peerParams = params
+	tc.conn.streamsMap.HandleTransportParameters(params)
+
+	str, err := tc.conn.OpenStream()
+	require.NoError(t, err)
+	uniStr, err := tc.conn.OpenUniStream()
+	require.NoError(t, err)
+	require.Equal(t, protocol.ByteCount(1000), str.sendStr.maxSendBuffer)
+	require.Equal(t, protocol.ByteCount(1000), uniStr.maxSendBuffer)
+
+	now := monotime.Now()
+	tc.conn.maybeUpdateMemoryPressure(now)
+	require.Equal(t, protocol.ByteCount(1000), str.sendStr.maxSendBuffer)
+
+	// high memory pressure halves the limit for existing and new streams
+	pressure = 0.85
+	now = now.Add(memoryPressureCheckInterval)
+	tc.conn.maybeUpdateMemoryPressure(now)
+	require.Equal(t, protocol.ByteCount(500), str.sendStr.maxSendBuffer)
+	require.Equal(t, protocol.ByteCount(500), uniStr.maxSendBuffer)
+	str2, err := tc.conn.OpenStream()
+	require.NoError(t, err)
+	require.Equal(t, protocol.ByteCount(500), str2.sendStr.maxSendBuffer)
+
+	// the hook is only called periodically
+	pressure = 0.99
+	tc.conn.maybeUpdateMemoryPressure(now.Add(memoryPressureCheckInterval / 2))
+	require.Equal(t, protocol.ByteCount(500), str.sendStr.maxSendBuffer)
+	now = now.Add(memoryPressureCheckInterval)
+	tc.conn.maybeUpdateMemoryPressure(now)
+	require.Equal(t, protocol.ByteCount(250), str.sendStr.maxSendBuffer)
+	require.Equal(t, protocol.ByteCount(250), str2.sendStr.maxSendBuffer)
+	require.Equal(t, protocol.ByteCount(250), uniStr.maxSendBuffer)
+
+	// the limit is restored once the memory pressure drops
+	pressure = 0.5
+	now = now.Add(memoryPressureCheckInterval)
+	tc.conn.maybeUpdateMemoryPressure(now)
+	require.Equal(t, protocol.ByteCount(1000), str.sendStr.maxSendBuffer)
+	require.Equal(t, protocol.ByteCount(1000), uniStr.maxSendBuffer)
+}
+
+func TestConnectionMemoryPressureDisabled(t *testing.T) {
+	var called bool
+	orig := heapMemoryPressure
+	heapMemoryPressure = func() float64 { called = true; return 1 }
+	t.Cleanup(func() { heapMemoryPressure = orig })
+
+	// memory pressure is not monitored if neither the hook nor a send buffer limit is set
+	tc := newServerTestConnection(t, nil, &Config{}, false)
+	tc.conn.maybeUpdateMemoryPressure(monotime.Now())
+	require.False(t, called)
+	require.Equal(t, memoryPressureNone, memoryPressureLevel(tc.conn.memoryPressure.Load()))
+
+	// the default hook is used if a send buffer limit is set
+	tc = newServerTestConnection(t, nil, &Config{MaxSendBufferPerStream: 1000}, false)
+	tc.conn.maybeUpdateMemoryPressure(monotime.Now())
+	require.True(t, called)
+	require.Equal(t, memoryPressureCritical, memoryPressureLevel(tc.conn.memoryPressure.Load()))
+}
+
 func TestConnectionHandleMaxStreamsFrame(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
@@ -1117,6 +1487,7 @@ func TestConnectionHandleMaxStreamsFrame(t *testing.T) {
 			&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: 10},
 			protocol.Encryption1RTT,
 			protocol.ConnectionID{},
+			nil,
 			monotime.Now(),
 		)
 		require.NoError(t, err)
@@ -1140,6 +1511,7 @@ func TestConnectionHandleMaxStreamsFrame(t *testing.T) {
 			&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: 10},
 			protocol.Encryption1RTT,
 			protocol.ConnectionID{},
+			nil,
 			monotime.Now(),
 		)
 		require.NoError(t, err)
@@ -1232,13 +1604,22 @@ func TestConnectionTransportParameterValidationFailureClient(t *testing.T) {
 }
 
 func TestConnectionHandshakeServer(t *testing.T) {
+	t.Run("with NEW_TOKEN", func(t *testing.T) {
+		testConnectionHandshakeServer(t, false)
+	})
+	t.Run("without NEW_TOKEN", func(t *testing.T) {
+		testConnectionHandshakeServer(t, true)
+	})
+}
+
+func testConnectionHandshakeServer(t *testing.T, disableNewTokens bool) {
 	mockCtrl := gomock.NewController(t)
 	cs := mocks.NewMockCryptoSetup(mockCtrl)
 	unpacker := NewMockUnpacker(mockCtrl)
 	tc := newServerTestConnection(
 		t,
 		mockCtrl,
-		nil,
+		&Config{DisablePathMTUDiscovery: true, DisableNewTokens: disableNewTokens},
 		false,
 		connectionOptCryptoSetup(cs),
 		connectionOptUnpacker(unpacker),
@@ -1279,6 +1660,12 @@ func TestConnectionHandshakeServer(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Fatal("timeout")
 	}
+	// on the server side, the handshake is confirmed when it completes
+	select {
+	case <-tc.conn.HandshakeConfirmed():
+	default:
+		t.Fatal("handshake should be confirmed")
+	}
 
 	var foundSessionTicket, foundHandshakeDone, foundNewToken bool
 	frames, _, _ := tc.conn.framer.Append(nil, nil, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
@@ -1296,7 +1683,7 @@ func TestConnectionHandshakeServer(t *testing.T) {
 	}
 	assert.True(t, foundSessionTicket)
 	assert.True(t, foundHandshakeDone)
-	assert.True(t, foundNewToken)
+	assert.Equal(t, !disableNewTokens, foundNewToken)
 
 	// test teardown
 	cs.EXPECT().Close()
@@ -1394,6 +1781,14 @@ func testConnectionHandshakeClient(t *testing.T, usePreferredAddress bool) {
 
 	require.True(t, mockCtrl.Satisfied())
 	// the handshake isn't confirmed until we receive a HANDSHAKE_DONE frame from the server
+	cs.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).AnyTimes()
+	require.True(t, tc.conn.ConnectionState().HandshakeComplete)
+	require.False(t, tc.conn.ConnectionState().HandshakeConfirmed)
+	select {
+	case <-tc.conn.HandshakeConfirmed():
+		t.Fatal("handshake shouldn't be confirmed yet")
+	default:
+	}
 
 	data, err = (&wire.HandshakeDoneFrame{}).Append(nil, protocol.Version1)
 	require.NoError(t, err)
@@ -1421,6 +1816,12 @@ func testConnectionHandshakeClient(t *testing.T, usePreferredAddress bool) {
 	case <-time.After(time.Second):
 		t.Fatal("timeout")
 	}
+	select {
+	case <-tc.conn.HandshakeConfirmed():
+	default:
+		t.Fatal("handshake should be confirmed")
+	}
+	require.True(t, tc.conn.ConnectionState().HandshakeConfirmed)
 
 	if usePreferredAddress {
 		tc.connRunner.EXPECT().AddResetToken(preferredAddressResetToken, gomock.Any())
@@ -1498,7 +1899,7 @@ func TestConnection0RTTTransportParameters(t *testing.T) {
 	)
 	tc.packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), gomock.Any(), protocol.Version1).Return(nil, nil).AnyTimes()
 	tc.packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), protocol.Version1).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-	tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any())
+	tc.connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 
 	errChan := make(chan error, 1)
 	go func() { errChan <- tc.conn.run() }()
@@ -1783,6 +2184,119 @@ func TestConnectionPacketBuffering(t *testing.T) {
 	})
 }
 
+func TestConnectionEarly1RTTPacketBuffering(t *testing.T) {
+	t.Run("salvaging packets", func(t *testing.T) {
+		testConnectionEarly1RTTPacketBuffering(t, false)
+	})
+	t.Run("dropping packets queued for too long", func(t *testing.T) {
+		testConnectionEarly1RTTPacketBuffering(t, true)
+	})
+}
+
+func testConnectionEarly1RTTPacketBuffering(t *testing.T, expire bool) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		unpacker := NewMockUnpacker(mockCtrl)
+		cs := mocks.NewMockCryptoSetup(mockCtrl)
+		var eventRecorder events.Recorder
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			nil,
+			false,
+			connectionOptUnpacker(unpacker),
+			connectionOptCryptoSetup(cs),
+			connectionOptTracer(&eventRecorder),
+		)
+
+		cs.EXPECT().StartHandshake(gomock.Any())
+		cs.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+		tc.packer.EXPECT().PackCoalescedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+		payload := make([]byte, 1000)
+		p := getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0, payload)
+		numBuffered := int(protocol.MaxUndecryptable1RTTBytes / p.Size())
+		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(
+			protocol.PacketNumber(0), protocol.PacketNumberLen(0), protocol.KeyPhaseBit(0), nil, handshake.ErrKeysNotYetAvailable,
+		).Times(numBuffered + 2)
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		// the number of queued 1-RTT packets is limited by their total size
+		for i := range numBuffered + 2 {
+			tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, protocol.PacketNumber(i), payload))
+		}
+		synctest.Wait()
+		require.Len(t, eventRecorder.Events(qlog.PacketBuffered{}), numBuffered)
+		require.Len(t, eventRecorder.Events(qlog.PacketDropped{}), 2)
+		for _, ev := range eventRecorder.Events(qlog.PacketDropped{}) {
+			require.Equal(t, qlog.PacketDropDOSPrevention, ev.(qlog.PacketDropped).Trigger)
+		}
+		eventRecorder.Clear()
+
+		if expire {
+			time.Sleep(3*tc.conn.rttStats.PTO(false) + time.Millisecond)
+		}
+
+		// Now receive a Handshake packet.
+		// In reality, this packet would contain the CRYPTO frame that makes the 1-RTT keys available.
+		hdr := wire.ExtendedHeader{
+			Header: wire.Header{
+				Type:             protocol.PacketTypeHandshake,
+				DestConnectionID: tc.srcConnID,
+				SrcConnectionID:  tc.destConnID,
+				Length:           8,
+				Version:          protocol.Version1,
+			},
+			PacketNumberLen: protocol.PacketNumberLen1,
+			PacketNumber:    1,
+		}
+		cf := &wire.CryptoFrame{Data: []byte("foobar")}
+		b, err := cf.Append(nil, protocol.Version1)
+		require.NoError(t, err)
+		cs.EXPECT().DiscardInitialKeys()
+		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any()).Return(
+			&unpackedPacket{hdr: &hdr, encryptionLevel: protocol.EncryptionHandshake, data: b}, nil,
+		)
+		cs.EXPECT().HandleMessage(gomock.Any(), gomock.Any())
+		cs.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventReceivedReadKeys})
+		cs.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent}).AnyTimes()
+		var numUnpacked int
+		if !expire {
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ monotime.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+					numUnpacked++
+					return protocol.PacketNumber(numUnpacked), protocol.PacketNumberLen2, protocol.KeyPhaseOne, []byte{0} /* PADDING */, nil
+				},
+			).Times(numBuffered)
+		}
+		tc.conn.handlePacket(getLongHeaderPacket(t, tc.remoteAddr, &hdr, []byte("foobar!")))
+		synctest.Wait()
+
+		if expire {
+			require.Zero(t, tc.conn.ConnectionStats().Early1RTTPacketsSalvaged)
+			require.Len(t, eventRecorder.Events(qlog.PacketDropped{}), numBuffered)
+			for _, ev := range eventRecorder.Events(qlog.PacketDropped{}) {
+				require.Equal(t, qlog.PacketDropKeyUnavailable, ev.(qlog.PacketDropped).Trigger)
+			}
+		} else {
+			require.Equal(t, numBuffered, numUnpacked)
+			require.Equal(t, uint64(numBuffered), tc.conn.ConnectionStats().Early1RTTPacketsSalvaged)
+		}
+
+		// test teardown
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		cs.EXPECT().Close()
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("run should have returned")
+		}
+	})
+}
+
 func TestConnectionPacketPacing(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
@@ -1900,14 +2414,20 @@ func TestConnectionPacketPacing(t *testing.T) {
 	})
 }
 
-// When the send queue blocks, we need to reset the pacing timer, otherwise the run loop might busy-loop.
-// See https://github.com/quic-go/quic-go/pull/4943 for more details.
-func TestConnectionPacingAndSendQueue(t *testing.T) {
+func TestConnectionWriteBatch(t *testing.T) {
+	t.Run("ending the batch", func(t *testing.T) {
+		testConnectionWriteBatch(t, false)
+	})
+	t.Run("batch expiring", func(t *testing.T) {
+		testConnectionWriteBatch(t, true)
+	})
+}
+
+func testConnectionWriteBatch(t *testing.T, expire bool) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 		sender := NewMockSender(mockCtrl)
-
 		tc := newServerTestConnection(t,
 			mockCtrl,
 			nil,
@@ -1917,16 +2437,315 @@ func TestConnectionPacingAndSendQueue(t *testing.T) {
 			connectionOptHandshakeConfirmed(),
 		)
 		sender.EXPECT().Run()
-
-		sendQueueAvailable := make(chan struct{})
-		pacingDeadline := monotime.Now().Add(-time.Millisecond)
-		var counter int
-		// allow exactly one packet to be sent, then become blocked
-		sender.EXPECT().WouldBlock().Return(false)
-		sender.EXPECT().WouldBlock().DoAndReturn(func() bool { counter++; return true }).AnyTimes()
-		sender.EXPECT().Available().Return(sendQueueAvailable).AnyTimes()
+		sender.EXPECT().WouldBlock().AnyTimes()
 		sph.EXPECT().GetLossDetectionTimeout().Return(monotime.Now().Add(time.Hour)).AnyTimes()
-		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited).AnyTimes()
+		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+		sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		// while the batch is open, only ACKs are sent
+		var numAckOnly int
+		tc.packer.EXPECT().PackAckOnlyPacket(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(protocol.ByteCount, monotime.Time, protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
+				numAckOnly++
+				return shortHeaderPacket{}, nil, errNothingToPack
+			},
+		).AnyTimes()
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		start := monotime.Now()
+		tc.conn.BeginBatch()
+		tc.conn.BeginBatch() // nested batch
+		tc.conn.scheduleSending()
+		synctest.Wait()
+		tc.conn.EndBatch()
+		tc.conn.scheduleSending()
+		synctest.Wait()
+		require.NotZero(t, numAckOnly)
+		require.True(t, mockCtrl.Satisfied())
+
+		sendChan := make(chan monotime.Time, 1)
+		tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), Version1).DoAndReturn(
+			func(buf *packetBuffer, _ protocol.ByteCount, _ monotime.Time, _ protocol.Version) (shortHeaderPacket, error) {
+				buf.Data = append(buf.Data, []byte("packet")...)
+				return shortHeaderPacket{PacketNumber: 1}, nil
+			},
+		)
+		tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), Version1).Return(shortHeaderPacket{}, errNothingToPack)
+		sender.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*packetBuffer, uint16, protocol.ECN) {
+			sendChan <- monotime.Now()
+		})
+		if expire {
+			// the application forgot to end the batch
+			time.Sleep(protocol.MaxWriteBatchDelay)
+		} else {
+			tc.conn.EndBatch()
+		}
+		synctest.Wait()
+		select {
+		case sent := <-sendChan:
+			if expire {
+				require.Equal(t, start.Add(protocol.MaxWriteBatchDelay), sent)
+			} else {
+				require.Equal(t, start, sent)
+			}
+		default:
+			t.Fatal("should have sent a packet")
+		}
+		tc.conn.EndBatch() // no-op
+
+		// test teardown
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		sender.EXPECT().Close()
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("should have timed out")
+		}
+	})
+}
+
+// When the send queue blocks, we need to reset the pacing timer, otherwise the run loop might busy-loop.
+// See https://github.com/quic-go/quic-go/pull/4943 for more details.
+func TestConnectionInboundFloodDoesntDelaySending(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// simulate the cost of processing a packet by advancing the (virtual) clock
+		const processingTime = 100 * time.Microsecond
+
+		mockCtrl := gomock.NewController(t)
+		unpacker := NewMockUnpacker(mockCtrl)
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			&Config{EnableDatagrams: true, DisablePathMTUDiscovery: true},
+			false,
+			connectionOptUnpacker(unpacker),
+			connectionOptHandshakeConfirmed(),
+		)
+
+		var pn protocol.PacketNumber
+		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(monotime.Time, []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				time.Sleep(processingTime)
+				pn++
+				return pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{1} /* PING */, nil
+			},
+		).AnyTimes()
+		datagramSent := make(chan monotime.Time, 1)
+		tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(*packetBuffer, protocol.ByteCount, monotime.Time, protocol.Version) (shortHeaderPacket, error) {
+				if tc.conn.datagramQueue.Peek() != nil {
+					tc.conn.datagramQueue.Pop()
+					datagramSent <- monotime.Now()
+				}
+				return shortHeaderPacket{}, errNothingToPack
+			},
+		).AnyTimes()
+		tc.packer.EXPECT().PackAckOnlyPacket(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			shortHeaderPacket{}, nil, errNothingToPack,
+		).AnyTimes()
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		// packets arrive twice as fast as they can be processed
+		stopFlood := make(chan struct{})
+		floodDone := make(chan struct{})
+		go func() {
+			defer close(floodDone)
+			for i := protocol.PacketNumber(0); ; i++ {
+				select {
+				case <-stopFlood:
+					return
+				default:
+				}
+				tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, i, []byte("foobar")))
+				time.Sleep(processingTime / 2)
+			}
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		start := monotime.Now()
+		require.NoError(t, tc.conn.datagramQueue.Add(&wire.DatagramFrame{Data: []byte("foobar")}))
+
+		select {
+		case sent := <-datagramSent:
+			// at most one receive budget worth of packets is processed before sending
+			require.LessOrEqual(t, sent.Sub(start), (protocol.DefaultReceivePacketBudget+1)*processingTime)
+		case <-time.After(time.Second):
+			t.Fatal("datagram not sent")
+		}
+		close(stopFlood)
+		<-floodDone
+
+		// test teardown
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestConnectionSendPacketBudgetInterruption(t *testing.T) {
+	t.Run("no received packets", func(t *testing.T) {
+		testConnectionSendPacketBudgetInterruption(t, false)
+	})
+	t.Run("received packets", func(t *testing.T) {
+		testConnectionSendPacketBudgetInterruption(t, true)
+	})
+}
+
+func testConnectionSendPacketBudgetInterruption(t *testing.T, receivedPackets bool) {
+	mockCtrl := gomock.NewController(t)
+	sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+	tc := newServerTestConnection(t,
+		mockCtrl,
+		&Config{SendPacketBudget: 3, DisablePathMTUDiscovery: true},
+		false,
+		connectionOptSentPacketHandler(sph),
+		connectionOptHandshakeConfirmed(),
+	)
+	sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+	sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+	sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	var packed int
+	tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(buf *packetBuffer, _ protocol.ByteCount, _ monotime.Time, _ protocol.Version) (shortHeaderPacket, error) {
+			if packed == 5 {
+				return shortHeaderPacket{}, errNothingToPack
+			}
+			packed++
+			buf.Data = append(buf.Data, []byte("foobar")...)
+			return shortHeaderPacket{PacketNumber: protocol.PacketNumber(packed)}, nil
+		},
+	).AnyTimes()
+
+	if receivedPackets {
+		tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0, []byte("foobar")))
+	}
+	require.NoError(t, tc.conn.sendPackets(monotime.Now()))
+	if receivedPackets {
+		// sending is interrupted once the send budget is used up
+		require.Equal(t, 3, packed)
+		require.Equal(t, deadlineSendImmediately, tc.conn.pacingDeadline)
+	} else {
+		require.Equal(t, 5, packed)
+		require.NotEqual(t, deadlineSendImmediately, tc.conn.pacingDeadline)
+	}
+}
+
+func TestConnectionSendPacketBudget(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		unpacker := NewMockUnpacker(mockCtrl)
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			&Config{SendPacketBudget: 3, DisablePathMTUDiscovery: true},
+			false,
+			connectionOptUnpacker(unpacker),
+			connectionOptSentPacketHandler(sph),
+			connectionOptHandshakeConfirmed(),
+		)
+
+		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+		sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		sph.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+		sph.EXPECT().ReceivedBytes(gomock.Any(), gomock.Any()).AnyTimes()
+
+		var events []string
+		var pn protocol.PacketNumber
+		unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(monotime.Time, []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				events = append(events, "unpack")
+				pn++
+				return pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{1} /* PING */, nil
+			},
+		).AnyTimes()
+		done := make(chan struct{})
+		tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(buf *packetBuffer, _ protocol.ByteCount, _ monotime.Time, _ protocol.Version) (shortHeaderPacket, error) {
+				if len(events) >= 12 {
+					select {
+					case <-done:
+					default:
+						close(done)
+					}
+					return shortHeaderPacket{}, errNothingToPack
+				}
+				events = append(events, "pack")
+				// every packet sent triggers the receipt of a new packet,
+				// so there are always received packets waiting to be processed
+				tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0, []byte("foobar")))
+				buf.Data = append(buf.Data, []byte("foobar")...)
+				return shortHeaderPacket{PacketNumber: protocol.PacketNumber(len(events))}, nil
+			},
+		).AnyTimes()
+		tc.sendConn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+		tc.conn.handlePacket(getShortHeaderPacket(t, tc.remoteAddr, tc.srcConnID, 0, []byte("foobar")))
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		require.Equal(t,
+			[]string{"unpack", "pack", "pack", "pack", "unpack", "unpack", "unpack", "pack", "pack", "pack", "unpack", "unpack"},
+			events[:12],
+		)
+
+		// test teardown
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestConnectionPacingAndSendQueue(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sender := NewMockSender(mockCtrl)
+
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			nil,
+			false,
+			connectionOptSentPacketHandler(sph),
+			connectionOptSender(sender),
+			connectionOptHandshakeConfirmed(),
+		)
+		sender.EXPECT().Run()
+
+		sendQueueAvailable := make(chan struct{})
+		pacingDeadline := monotime.Now().Add(-time.Millisecond)
+		var counter int
+		// allow exactly one packet to be sent, then become blocked
+		sender.EXPECT().WouldBlock().Return(false)
+		sender.EXPECT().WouldBlock().DoAndReturn(func() bool { counter++; return true }).AnyTimes()
+		sender.EXPECT().Available().Return(sendQueueAvailable).AnyTimes()
+		sph.EXPECT().GetLossDetectionTimeout().Return(monotime.Now().Add(time.Hour)).AnyTimes()
+		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited).AnyTimes()
 		sph.EXPECT().TimeUntilSend().Return(pacingDeadline).AnyTimes()
 		sph.EXPECT().ECNMode(gomock.Any()).Return(protocol.ECNNon).AnyTimes()
 		tc.packer.EXPECT().PackAckOnlyPacket(gomock.Any(), gomock.Any(), gomock.Any()).Return(
@@ -2008,6 +2827,77 @@ func TestConnectionIdleTimeout(t *testing.T) {
 	})
 }
 
+func TestConnectionCongestionControlSwitch(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sender := NewMockSender(mockCtrl)
+		var ages []time.Duration
+		tc := newServerTestConnection(t,
+			mockCtrl,
+			&Config{
+				MaxIdleTimeout: time.Hour,
+				CongestionControlSwitch: func(age time.Duration, _ ConnectionStats) (CongestionControlAlgorithm, bool) {
+					ages = append(ages, age)
+					if len(ages) < 2 {
+						return 0, false
+					}
+					return CUBIC, true
+				},
+			},
+			false,
+			connectionOptSentPacketHandler(sph),
+			connectionOptSender(sender),
+			connectionOptHandshakeConfirmed(),
+		)
+		sender.EXPECT().Run()
+		sender.EXPECT().WouldBlock().AnyTimes()
+		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+		tc.packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(shortHeaderPacket{}, errNothingToPack).AnyTimes()
+		// the idle timeout is set when the transport parameters are received
+		require.NoError(t, tc.conn.handleTransportParameters(&wire.TransportParameters{MaxIdleTimeout: time.Hour}))
+		// the switch time is set when the handshake completes
+		tc.conn.nextCongestionControlSwitchTime = monotime.Now().Add(protocol.CongestionControlSwitchInterval)
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- tc.conn.run() }()
+
+		time.Sleep(protocol.CongestionControlSwitchInterval)
+		synctest.Wait()
+		require.Equal(t, []time.Duration{protocol.CongestionControlSwitchInterval}, ages)
+
+		sph.EXPECT().SetCongestionControl(congestion.CUBIC, gomock.Any(), gomock.Any())
+		time.Sleep(protocol.CongestionControlSwitchInterval)
+		synctest.Wait()
+		require.Equal(t, []time.Duration{protocol.CongestionControlSwitchInterval, 2 * protocol.CongestionControlSwitchInterval}, ages)
+
+		// test teardown
+		tc.connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+		sender.EXPECT().Close()
+		tc.conn.destroy(nil)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("run should have returned")
+		}
+	})
+}
+
+func TestConnectionSetCongestionControl(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	tc := newServerTestConnection(t, mockCtrl, nil, false)
+
+	require.EqualError(t, tc.conn.SetCongestionControl(42), "invalid congestion control algorithm: 42")
+	require.Nil(t, tc.conn.congestionControlRequest.Load())
+
+	require.NoError(t, tc.conn.SetCongestionControl(CUBIC))
+	require.Equal(t, CUBIC, *tc.conn.congestionControlRequest.Load())
+}
+
 func TestConnectionKeepAlive(t *testing.T) {
 	t.Run("enabled", func(t *testing.T) {
 		testConnectionKeepAlive(t, true, true)
@@ -2930,6 +3820,8 @@ func TestConnectionRetryDrops(t *testing.T) {
 		},
 		eventRecorder.Events(qlog.PacketDropped{}),
 	)
+	// dropped Retry packets are counted, but no Retry was performed
+	require.Equal(t, AddressValidationInfo{RetriesReceived: 2}, tc.conn.connState.AddressValidation)
 }
 
 func TestConnectionRetryAfterReceivedPacket(t *testing.T) {
@@ -3065,6 +3957,16 @@ func testConnectionConnectionIDChanges(t *testing.T, sendRetry bool) {
 				},
 				eventRecorder.Events(qlog.PacketReceived{}, qlog.PacketDropped{}),
 			)
+			tc.conn.connStateMutex.Lock()
+			require.Equal(t,
+				AddressValidationInfo{
+					UsedRetry:            true,
+					RetriesReceived:      1,
+					RetrySrcConnectionID: retryConnID,
+				},
+				tc.conn.connState.AddressValidation,
+			)
+			tc.conn.connStateMutex.Unlock()
 		}
 		eventRecorder.Clear()
 
@@ -3242,8 +4144,8 @@ func testConnectionPathValidation(t *testing.T, isNATRebinding bool) {
 			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(
 				protocol.PacketNumber(10), protocol.PacketNumberLen2, protocol.KeyPhaseZero, payload, nil,
 			),
-			tc.packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
-				func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
+			tc.packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount, _ protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
 					pathChallenge = frames[0].Frame.(*wire.PathChallengeFrame)
 					return shortHeaderPacket{IsPathProbePacket: true}, getPacketBuffer(), nil
 				},
@@ -3257,7 +4159,7 @@ func testConnectionPathValidation(t *testing.T, isNATRebinding bool) {
 		)
 
 		tc.conn.handlePacket(receivedPacket{
-			data:       make([]byte, 10),
+			data:       make([]byte, protocol.MinInitialPacketSize),
 			buffer:     getPacketBuffer(),
 			remoteAddr: newRemoteAddr,
 			rcvTime:    monotime.Now(),
@@ -3364,6 +4266,46 @@ func testConnectionPathValidation(t *testing.T, isNATRebinding bool) {
 	})
 }
 
+func TestConnectionPeerMigrationDisabled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	unpacker := NewMockUnpacker(mockCtrl)
+	var eventRecorder events.Recorder
+	tc := newServerTestConnection(t,
+		mockCtrl,
+		&Config{DisablePeerMigration: true},
+		false,
+		connectionOptUnpacker(unpacker),
+		connectionOptHandshakeConfirmed(),
+		connectionOptTracer(&eventRecorder),
+	)
+
+	newRemoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 1234}
+	require.NotEqual(t, tc.remoteAddr, newRemoteAddr)
+	packet := getShortHeaderPacket(t, newRemoteAddr, tc.srcConnID, 10, nil)
+	unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(
+		protocol.PacketNumber(10), protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{1} /* PING */, nil,
+	)
+	// no path probe is sent, and the connection is not closed
+	wasProcessed, err := tc.conn.handleOnePacket(packet, 42)
+	require.NoError(t, err)
+	require.False(t, wasProcessed)
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.PacketDropped{
+				Header: qlog.PacketHeader{
+					PacketType:   qlog.PacketType1RTT,
+					PacketNumber: 10,
+				},
+				Raw:        qlog.RawInfo{Length: int(packet.Size())},
+				DatagramID: 42,
+				Trigger:    qlog.PacketDropUnexpectedPacket,
+			},
+		},
+		eventRecorder.Events(qlog.PacketReceived{}, qlog.PacketDropped{}),
+	)
+	require.Equal(t, tc.remoteAddr, tc.conn.RemoteAddr())
+}
+
 func TestConnectionMigrationServer(t *testing.T) {
 	tc := newServerTestConnection(t, nil, nil, false)
 	_, err := tc.conn.AddPath(&Transport{})
@@ -3407,9 +4349,12 @@ func testConnectionMigration(t *testing.T, enabled bool) {
 		shortHeaderPacket{}, errNothingToPack,
 	).AnyTimes()
 	packedProbe := make(chan struct{})
-	tc.packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
-		func(protocol.ConnectionID, []ackhandler.Frame, protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
+	tc.packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount, _ protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
 			defer close(packedProbe)
+			// only probing frames must be sent on the probed path
+			require.Len(t, frames, 1)
+			require.IsType(t, &wire.PathChallengeFrame{}, frames[0].Frame)
 			return shortHeaderPacket{IsPathProbePacket: true}, getPacketBuffer(), nil
 		},
 	).AnyTimes()
@@ -3418,7 +4363,7 @@ func testConnectionMigration(t *testing.T, enabled bool) {
 	_, err = tc.conn.handleFrame(&wire.NewConnectionIDFrame{
 		SequenceNumber: 1,
 		ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
-	}, protocol.EncryptionInitial, tc.destConnID, monotime.Now())
+	}, protocol.EncryptionInitial, tc.destConnID, nil, monotime.Now())
 	require.NoError(t, err)
 	errChan := make(chan error, 1)
 	go func() { errChan <- tc.conn.run() }()
@@ -3450,6 +4395,124 @@ func testConnectionMigration(t *testing.T, enabled bool) {
 	}
 }
 
+func TestConnectionFrameAtWrongEncryptionLevel(t *testing.T) {
+	tc := newServerTestConnection(t, nil, nil, false)
+
+	data, err := (&wire.StreamFrame{StreamID: 4, Data: []byte("foobar")}).Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	_, _, _, _, err = tc.conn.handleFrames(data, protocol.ConnectionID{}, protocol.EncryptionHandshake, nil, nil, monotime.Now())
+	var transportErr *qerr.TransportError
+	require.ErrorAs(t, err, &transportErr)
+	require.Equal(t, qerr.ProtocolViolation, transportErr.ErrorCode)
+	require.Equal(t, uint64(wire.FrameType(data[0])), transportErr.FrameType)
+}
+
+func TestConnectionStrictMode(t *testing.T) {
+	appendFrame := func(t *testing.T, f wire.Frame) []byte {
+		b, err := f.Append(nil, protocol.Version1)
+		require.NoError(t, err)
+		return b
+	}
+	streamFrame := appendFrame(t, &wire.StreamFrame{StreamID: 3, Data: []byte("foobar")})
+
+	for _, test := range []struct {
+		name      string
+		data      []byte
+		errorCode qerr.TransportErrorCode
+		frameType uint64
+	}{
+		{
+			name:      "STREAM frame for a locally-initiated unidirectional stream",
+			data:      streamFrame,
+			errorCode: qerr.StreamStateError,
+			frameType: uint64(streamFrame[0]),
+		},
+		{
+			name:      "MAX_STREAM_DATA frame for a stream that was never opened",
+			data:      appendFrame(t, &wire.MaxStreamDataFrame{StreamID: 1, MaximumStreamData: 1337}),
+			errorCode: qerr.StreamStateError,
+			frameType: uint64(wire.FrameTypeMaxStreamData),
+		},
+		{
+			name: "NEW_CONNECTION_ID frame retiring connection IDs beyond its sequence number",
+			data: appendFrame(t, &wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				RetirePriorTo:  2,
+				ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			}),
+			errorCode: qerr.FrameEncodingError,
+			frameType: uint64(wire.FrameTypeNewConnectionID),
+		},
+		{
+			name:      "frame type not minimally encoded",
+			data:      quicvarint.AppendWithLen(nil, uint64(wire.FrameTypePing), 2),
+			errorCode: qerr.ProtocolViolation,
+			frameType: uint64(wire.FrameTypePing),
+		},
+		{
+			name:      "unknown frame type",
+			data:      append(quicvarint.Append(nil, 0x1f*42+0x21), []byte("foobar")...),
+			errorCode: qerr.FrameEncodingError,
+			frameType: 0x1f*42 + 0x21,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var eventRecorder events.Recorder
+			tc := newServerTestConnection(t,
+				nil,
+				&Config{StrictMode: true},
+				false,
+				connectionOptTracer(&eventRecorder),
+			)
+			_, _, _, _, err := tc.conn.handleFrames(test.data, protocol.ConnectionID{}, protocol.Encryption1RTT, nil, nil, monotime.Now())
+			require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: test.errorCode, FrameType: test.frameType})
+			var transportErr *qerr.TransportError
+			require.ErrorAs(t, err, &transportErr)
+			require.Equal(t,
+				[]qlogwriter.Event{
+					qlog.ProtocolViolation{
+						ErrorCode: test.errorCode,
+						FrameType: test.frameType,
+						Reason:    transportErr.ErrorMessage,
+					},
+				},
+				eventRecorder.Events(qlog.ProtocolViolation{}),
+			)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var eventRecorder events.Recorder
+		tc := newServerTestConnection(t, nil, nil, false, connectionOptTracer(&eventRecorder))
+		// non-minimal encodings of the frame type are accepted
+		isAckEliciting, _, _, _, err := tc.conn.handleFrames(
+			quicvarint.AppendWithLen(nil, uint64(wire.FrameTypePing), 2),
+			protocol.ConnectionID{},
+			protocol.Encryption1RTT,
+			nil,
+			nil,
+			monotime.Now(),
+		)
+		require.NoError(t, err)
+		require.True(t, isAckEliciting)
+		// errors returned when handling frames don't carry the frame type
+		_, _, _, _, err = tc.conn.handleFrames(streamFrame, protocol.ConnectionID{}, protocol.Encryption1RTT, nil, nil, monotime.Now())
+		require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.StreamStateError})
+		require.Empty(t, eventRecorder.Events(qlog.ProtocolViolation{}))
+	})
+}
+
+func TestConnectionUnknownFrames(t *testing.T) {
+	tc := newServerTestConnection(t, nil, nil, false)
+
+	data, err := (&wire.PingFrame{}).Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	data = quicvarint.Append(data, 0x1f*42+0x21) // an unknown (GREASE) frame type
+	data = append(data, []byte("foobar")...)
+	_, _, _, _, err = tc.conn.handleFrames(data, protocol.ConnectionID{}, protocol.Encryption1RTT, nil, nil, monotime.Now())
+	require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.FrameEncodingError, FrameType: 0x1f*42 + 0x21})
+}
+
 func TestConnectionDatagrams(t *testing.T) {
 	t.Run("disabled", func(t *testing.T) {
 		testConnectionDatagrams(t, false)
@@ -3466,7 +4529,7 @@ func testConnectionDatagrams(t *testing.T, enabled bool) {
 	require.NoError(t, err)
 	data, err = (&wire.DatagramFrame{Data: []byte("bar")}).Append(data, protocol.Version1)
 	require.NoError(t, err)
-	_, _, _, err = tc.conn.handleFrames(data, protocol.ConnectionID{}, protocol.Encryption1RTT, nil, monotime.Now())
+	_, _, _, _, err = tc.conn.handleFrames(data, protocol.ConnectionID{}, protocol.Encryption1RTT, nil, nil, monotime.Now())
 
 	if !enabled {
 		require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.FrameEncodingError, FrameType: uint64(wire.FrameTypeDatagramWithLength)})
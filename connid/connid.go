@@ -0,0 +1,56 @@
+// Package connid exposes the rules quic-go uses to extract the destination connection ID from QUIC packets.
+//
+// It is intended for code that needs to steer packets to a quic-go Transport outside of quic-go,
+// for example for generating or testing XDP / eBPF programs that redirect packets based on their connection ID.
+// The functions in this package use the same code that the Transport uses to route incoming packets,
+// so they are guaranteed to agree with quic-go.
+//
+// Long header packets carry the length of the destination connection ID on the wire.
+// Short header packets don't, so the length of the connection IDs issued by the Transport needs to be known.
+// This is the Transport's ConnectionIDLength, or ConnectionIDGenerator.ConnectionIDLen if a
+// ConnectionIDGenerator is used. If neither is set, DefaultLength is used.
+// Transport.EnforceConnectionIDLength can be used to make sure that all issued connection IDs have this length.
+package connid
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+const (
+	// MaxLength is the maximum length of a connection ID, in bytes.
+	MaxLength = protocol.MaxConnIDLen
+	// DefaultLength is the length of the connection IDs issued by a Transport,
+	// if neither a ConnectionIDLength nor a ConnectionIDGenerator is configured.
+	DefaultLength = protocol.DefaultConnectionIDLength
+)
+
+const (
+	// ShortHeaderOffset is the offset of the destination connection ID in a short header packet.
+	ShortHeaderOffset = 1
+	// LongHeaderLengthOffset is the offset of the destination connection ID length field in a long header packet.
+	LongHeaderLengthOffset = 5
+	// LongHeaderOffset is the offset of the destination connection ID in a long header packet.
+	LongHeaderOffset = LongHeaderLengthOffset + 1
+)
+
+// ErrInvalidLength is returned when the destination connection ID length of a long header packet exceeds MaxLength.
+var ErrInvalidLength = protocol.ErrInvalidConnectionIDLen
+
+// IsLongHeader says if a packet with this first byte is a long header packet.
+func IsLongHeader(firstByte byte) bool {
+	return wire.IsLongHeaderPacket(firstByte)
+}
+
+// Destination returns the destination connection ID of a packet.
+// For short header packets, shortHeaderLen is the length of the connection IDs issued by the Transport.
+// For long header packets, the length is read from the packet, and shortHeaderLen is ignored.
+// If the packet is too short to contain the connection ID, io.EOF is returned.
+// The returned slice is a copy, it doesn't alias packet.
+func Destination(packet []byte, shortHeaderLen int) ([]byte, error) {
+	connID, err := wire.ParseConnectionID(packet, shortHeaderLen)
+	if err != nil {
+		return nil, err
+	}
+	return connID.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package connid
+
+import (
+	"io"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationShortHeader(t *testing.T) {
+	connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6})
+	b, err := wire.AppendShortHeader(nil, connID, 1337, protocol.PacketNumberLen2, protocol.KeyPhaseOne)
+	require.NoError(t, err)
+	b = append(b, []byte("payload")...)
+
+	require.False(t, IsLongHeader(b[0]))
+	dest, err := Destination(b, connID.Len())
+	require.NoError(t, err)
+	require.Equal(t, connID.Bytes(), dest)
+	require.Equal(t, dest, b[ShortHeaderOffset:ShortHeaderOffset+connID.Len()])
+
+	// the returned connection ID doesn't alias the packet
+	b[ShortHeaderOffset] = 0xff
+	require.Equal(t, connID.Bytes(), dest)
+
+	_, err = Destination(b[:connID.Len()], connID.Len())
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestDestinationLongHeader(t *testing.T) {
+	for _, v := range []protocol.Version{protocol.Version1, protocol.Version2} {
+		t.Run(v.String(), func(t *testing.T) {
+			connID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37})
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: connID,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+					Length:           100,
+					Version:          v,
+				},
+				PacketNumber:    42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			b, err := hdr.Append(nil, v)
+			require.NoError(t, err)
+
+			require.True(t, IsLongHeader(b[0]))
+			require.Equal(t, connID.Len(), int(b[LongHeaderLengthOffset]))
+			// the short header length is ignored for long header packets
+			dest, err := Destination(b, 4)
+			require.NoError(t, err)
+			require.Equal(t, connID.Bytes(), dest)
+			require.Equal(t, dest, b[LongHeaderOffset:LongHeaderOffset+connID.Len()])
+
+			_, err = Destination(b[:LongHeaderOffset+connID.Len()-1], 4)
+			require.ErrorIs(t, err, io.EOF)
+
+			b[LongHeaderLengthOffset] = MaxLength + 1
+			_, err = Destination(b, 4)
+			require.ErrorIs(t, err, ErrInvalidLength)
+		})
+	}
+}
@@ -28,7 +28,7 @@ type baseCryptoStream struct {
 }
 
 func newCryptoStream() *cryptoStream {
-	return &cryptoStream{baseCryptoStream{queue: *newFrameSorter()}}
+	return &cryptoStream{baseCryptoStream{queue: *newFrameSorterWithMaxGaps(protocol.MaxCryptoStreamFrameSorterGaps)}}
 }
 
 func (s *baseCryptoStream) HandleCryptoFrame(f *wire.CryptoFrame) error {
@@ -52,7 +52,21 @@ func (s *baseCryptoStream) HandleCryptoFrame(f *wire.CryptoFrame) error {
 		return nil
 	}
 	s.highestOffset = max(s.highestOffset, highestOffset)
-	return s.queue.Push(f.Data, f.Offset, nil)
+	if err := s.queue.Push(f.Data, f.Offset, nil); err != nil {
+		if errors.Is(err, errTooManyGaps) {
+			return &qerr.TransportError{
+				ErrorCode:    qerr.CryptoBufferExceeded,
+				ErrorMessage: "too many gaps in received crypto data",
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// NumGaps returns the number of gaps in the received crypto data.
+func (s *baseCryptoStream) NumGaps() int {
+	return s.queue.NumGaps()
 }
 
 // GetCryptoData retrieves data that was received in CRYPTO frames
@@ -118,7 +132,7 @@ func newInitialCryptoStream(isClient bool) *initialCryptoStream {
 		scramble = err != nil || !disabled
 	}
 	s := &initialCryptoStream{
-		baseCryptoStream: baseCryptoStream{queue: *newFrameSorter()},
+		baseCryptoStream: baseCryptoStream{queue: *newFrameSorterWithMaxGaps(protocol.MaxCryptoStreamFrameSorterGaps)},
 		scramble:         scramble,
 	}
 	for i := range len(s.cuts) {
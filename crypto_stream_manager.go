@@ -53,6 +53,20 @@ func (m *cryptoStreamManager) GetCryptoData(encLevel protocol.EncryptionLevel) [
 	}
 }
 
+func (m *cryptoStreamManager) NumGaps(encLevel protocol.EncryptionLevel) int {
+	//nolint:exhaustive // CRYPTO frames cannot be sent in 0-RTT packets.
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		return m.initialStream.NumGaps()
+	case protocol.EncryptionHandshake:
+		return m.handshakeStream.NumGaps()
+	case protocol.Encryption1RTT:
+		return m.oneRTTStream.NumGaps()
+	default:
+		return 0
+	}
+}
+
 func (m *cryptoStreamManager) GetPostHandshakeData(maxSize protocol.ByteCount) *wire.CryptoFrame {
 	if !m.oneRTTStream.HasData() {
 		return nil
@@ -49,6 +49,26 @@ func TestCryptoStreamMaxOffset(t *testing.T) {
 	)
 }
 
+func TestCryptoStreamTooManyGaps(t *testing.T) {
+	str := newCryptoStream()
+	for i := range protocol.MaxCryptoStreamFrameSorterGaps - 1 {
+		require.NoError(t, str.HandleCryptoFrame(&wire.CryptoFrame{
+			Offset: protocol.ByteCount(2*i + 1),
+			Data:   []byte("a"),
+		}))
+		require.Equal(t, i+1, str.NumGaps())
+	}
+	// filling a gap is always possible
+	require.NoError(t, str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 2, Data: []byte("b")}))
+	require.Equal(t, protocol.MaxCryptoStreamFrameSorterGaps-2, str.NumGaps())
+	require.NoError(t, str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 1000, Data: []byte("c")}))
+	require.Equal(t, protocol.MaxCryptoStreamFrameSorterGaps-1, str.NumGaps())
+	require.ErrorIs(t,
+		str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 2000, Data: []byte("d")}),
+		&qerr.TransportError{ErrorCode: qerr.CryptoBufferExceeded},
+	)
+}
+
 func TestCryptoStreamFinishWithQueuedData(t *testing.T) {
 	t.Run("with data at current offset", func(t *testing.T) {
 		str := newCryptoStream()
@@ -89,6 +89,20 @@ func (h *datagramQueue) Pop() {
 	}
 }
 
+// DropQueued discards all DATAGRAM frames queued for sending.
+// It returns the number of frames that were discarded.
+func (h *datagramQueue) DropQueued() int {
+	h.sendMx.Lock()
+	defer h.sendMx.Unlock()
+	n := h.sendQueue.Len()
+	h.sendQueue.Clear()
+	select {
+	case h.sent <- struct{}{}:
+	default:
+	}
+	return n
+}
+
 // HandleDatagramFrame handles a received DATAGRAM frame.
 func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	data := make([]byte, len(f.Data))
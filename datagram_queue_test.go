@@ -72,6 +72,33 @@ func TestDatagramQueueSendQueueLength(t *testing.T) {
 	})
 }
 
+func TestDatagramQueueDropQueued(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		queue := newDatagramQueue(func() {}, utils.DefaultLogger)
+		require.Zero(t, queue.DropQueued())
+
+		for range maxDatagramSendQueueLen {
+			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte{0}}))
+		}
+		errChan := make(chan error, 1)
+		go func() { errChan <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}) }()
+		synctest.Wait()
+
+		// dropping the queued datagrams unblocks Add
+		require.Equal(t, maxDatagramSendQueueLen, queue.DropQueued())
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("Add should have returned")
+		}
+		require.Equal(t, &wire.DatagramFrame{Data: []byte("foobar")}, queue.Peek())
+		require.Equal(t, 1, queue.DropQueued())
+		require.Nil(t, queue.Peek())
+	})
+}
+
 func TestDatagramQueueReceive(t *testing.T) {
 	queue := newDatagramQueue(func() {}, utils.DefaultLogger)
 
@@ -0,0 +1,182 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+// FailoverReason says why DialFailover returned the connection it returned.
+type FailoverReason uint8
+
+const (
+	// FailoverPrimaryHandshakeComplete means that the handshake with the primary address completed first.
+	FailoverPrimaryHandshakeComplete FailoverReason = iota
+	// FailoverPrimary0RTTRejected means that the handshake with the primary address completed first,
+	// but the server rejected 0-RTT.
+	FailoverPrimary0RTTRejected
+	// FailoverBackupHandshakeComplete means that the handshake with the backup address completed
+	// before the handshake with the primary address.
+	FailoverBackupHandshakeComplete
+	// FailoverPrimaryFailed means that the connection to the primary address failed.
+	FailoverPrimaryFailed
+)
+
+func (r FailoverReason) String() string {
+	switch r {
+	case FailoverPrimaryHandshakeComplete:
+		return "primary handshake complete"
+	case FailoverPrimary0RTTRejected:
+		return "primary handshake complete, 0-RTT rejected"
+	case FailoverBackupHandshakeComplete:
+		return "backup handshake complete"
+	case FailoverPrimaryFailed:
+		return "primary failed"
+	default:
+		return "unknown failover reason"
+	}
+}
+
+// FailoverInfo describes the outcome of DialFailover.
+type FailoverInfo struct {
+	// UsedBackup says if the returned connection was established to the backup address.
+	UsedBackup bool
+	Reason     FailoverReason
+	// PrimaryErr is the error that the connection to the primary address failed with.
+	// It is only set if the reason is FailoverPrimaryFailed.
+	PrimaryErr error
+}
+
+type failoverDialResult struct {
+	conn *Conn
+	err  error
+}
+
+// DialFailover dials the primary address, resuming a previous session if possible.
+// If the handshake with the primary address doesn't complete within headStart, or if the
+// connection to the primary address fails, it additionally dials the backup address.
+// headStart is typically an estimate of the RTT to the primary address.
+//
+// It returns the connection whose handshake completed first, and closes the other one.
+// If the handshake with the primary address completes first, this connection is used even if
+// the server rejected 0-RTT.
+// Since the connection is only returned once the handshake completed, data written by the application
+// is only ever sent on the returned connection. This means that no application data is sent in 0-RTT packets:
+// 0-RTT data would have to be sent before the race is decided, and replaying it on the backup connection
+// is not safe for arbitrary application data.
+// Applications that want to send 0-RTT data should use DialEarly instead.
+func (t *Transport) DialFailover(
+	ctx context.Context,
+	primary, backup net.Addr,
+	headStart time.Duration,
+	tlsConf *tls.Config,
+	conf *Config,
+) (*Conn, FailoverInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	primaryChan := make(chan failoverDialResult, 1)
+	go func() {
+		conn, err := t.DialEarly(ctx, primary, tlsConf, conf)
+		primaryChan <- failoverDialResult{conn: conn, err: err}
+	}()
+
+	var backupChan chan failoverDialResult
+	startBackup := func() {
+		if backupChan != nil {
+			return
+		}
+		backupChan = make(chan failoverDialResult, 1)
+		go func() {
+			conn, err := t.Dial(ctx, backup, tlsConf, conf)
+			backupChan <- failoverDialResult{conn: conn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(headStart)
+	defer timer.Stop()
+
+	var primaryConn *Conn
+	// Receiving from a nil channel blocks forever.
+	var primaryHandshakeComplete, primaryClosed <-chan struct{}
+	var primaryErr, backupErr error
+	for {
+		select {
+		case <-ctx.Done():
+			closeFailoverLoser(primaryConn, primaryChan)
+			closeFailoverLoser(nil, backupChan)
+			return nil, FailoverInfo{}, context.Cause(ctx)
+		case <-timer.C:
+			startBackup()
+		case r := <-primaryChan:
+			primaryChan = nil
+			if r.err != nil {
+				primaryErr = r.err
+				if backupErr != nil {
+					return nil, FailoverInfo{}, errors.Join(primaryErr, backupErr)
+				}
+				startBackup()
+				continue
+			}
+			primaryConn = r.conn
+			primaryHandshakeComplete = primaryConn.HandshakeComplete()
+			primaryClosed = primaryConn.Context().Done()
+		case <-primaryClosed:
+			primaryErr = context.Cause(primaryConn.Context())
+			primaryConn = nil
+			primaryHandshakeComplete = nil
+			primaryClosed = nil
+			if backupErr != nil {
+				return nil, FailoverInfo{}, errors.Join(primaryErr, backupErr)
+			}
+			startBackup()
+		case <-primaryHandshakeComplete:
+			closeFailoverLoser(nil, backupChan)
+			info := FailoverInfo{Reason: FailoverPrimaryHandshakeComplete}
+			select {
+			case <-primaryConn.earlyConnReady():
+				if !primaryConn.ConnectionState().Used0RTT {
+					info.Reason = FailoverPrimary0RTTRejected
+				}
+			default: // 0-RTT wasn't attempted
+			}
+			return primaryConn, info, nil
+		case r := <-backupChan:
+			backupChan = nil
+			if r.err != nil {
+				backupErr = r.err
+				if primaryErr != nil {
+					return nil, FailoverInfo{}, errors.Join(primaryErr, backupErr)
+				}
+				continue
+			}
+			closeFailoverLoser(primaryConn, primaryChan)
+			info := FailoverInfo{UsedBackup: true, Reason: FailoverBackupHandshakeComplete}
+			if primaryErr != nil {
+				info.Reason = FailoverPrimaryFailed
+				info.PrimaryErr = primaryErr
+			}
+			return r.conn, info, nil
+		}
+	}
+}
+
+// closeFailoverLoser closes the connection that lost the race.
+// If the dial is still in progress, the connection is closed once the dial returns.
+// The caller is expected to cancel the dial's context.
+func closeFailoverLoser(conn *Conn, dialChan <-chan failoverDialResult) {
+	if conn != nil {
+		conn.CloseWithError(0, "")
+		return
+	}
+	if dialChan == nil {
+		return
+	}
+	go func() {
+		if r := <-dialChan; r.conn != nil {
+			r.conn.CloseWithError(0, "")
+		}
+	}()
+}
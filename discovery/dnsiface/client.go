@@ -0,0 +1,27 @@
+// Package dnsiface defines the interface used to update DNS records.
+package dnsiface
+
+import (
+	"context"
+	"time"
+)
+
+// An SRVRecord is a DNS SRV record, as defined in RFC 2782.
+type SRVRecord struct {
+	// Name is the owner name of the record, e.g. "_quic._udp.example.com.".
+	Name     string
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+	TTL      time.Duration
+}
+
+// A Client updates the records of a DNS zone.
+// It is typically implemented using the API of a DNS provider, or using DNS UPDATE (RFC 2136).
+type Client interface {
+	// SetSRV creates the SRV record, or updates it if a record with the same name and target already exists.
+	SetSRV(ctx context.Context, zone string, record SRVRecord) error
+	// DeleteSRV deletes the SRV record.
+	DeleteSRV(ctx context.Context, zone string, record SRVRecord) error
+}
@@ -0,0 +1,124 @@
+// Package discovery implements advertising QUIC servers using DNS.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/discovery/dnsiface"
+)
+
+const (
+	defaultTTL = 5 * time.Minute
+	// dnsUpdateTimeout is the timeout of every request to the DNS client.
+	dnsUpdateTimeout = 10 * time.Second
+)
+
+// An Advertiser advertises the address of a server.
+type Advertiser interface {
+	// Advertise registers the server, and keeps the registration fresh until Withdraw is called.
+	Advertise() error
+	// Withdraw removes the registration.
+	Withdraw() error
+}
+
+type srvAdvertiser struct {
+	client dnsiface.Client
+	zone   string
+	record dnsiface.SRVRecord
+	err    error // error detected when creating the advertiser
+
+	mutex   sync.Mutex
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var _ Advertiser = &srvAdvertiser{}
+
+// DNSSRVAdvertiser creates an Advertiser that registers a DNS SRV record for a QUIC server,
+// i.e. _quic._udp.<zone> SRV 0 0 <port> <zone>.
+// The record is refreshed every half TTL. If the TTL is zero, a TTL of 5 minutes is used.
+func DNSSRVAdvertiser(zone string, port int, ttl time.Duration, dnsClient dnsiface.Client) Advertiser {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	zone = strings.TrimSuffix(zone, ".") + "."
+	a := &srvAdvertiser{
+		client: dnsClient,
+		zone:   zone,
+		record: dnsiface.SRVRecord{
+			Name:   "_quic._udp." + zone,
+			Target: zone,
+			Port:   uint16(port),
+			TTL:    ttl,
+		},
+	}
+	if port <= 0 || port > 0xffff {
+		a.err = fmt.Errorf("discovery: invalid port: %d", port)
+	}
+	return a
+}
+
+func (a *srvAdvertiser) Advertise() error {
+	if a.err != nil {
+		return a.err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.running {
+		return errors.New("discovery: already advertising")
+	}
+	if err := a.set(); err != nil {
+		return err
+	}
+	a.running = true
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+	go a.refresh(a.stop, a.done)
+	return nil
+}
+
+func (a *srvAdvertiser) refresh(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(a.record.TTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// If refreshing fails, the record is still valid until the TTL expires.
+			// It will be refreshed again on the next tick.
+			_ = a.set()
+		}
+	}
+}
+
+func (a *srvAdvertiser) set() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsUpdateTimeout)
+	defer cancel()
+	return a.client.SetSRV(ctx, a.zone, a.record)
+}
+
+func (a *srvAdvertiser) Withdraw() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.running {
+		return nil
+	}
+	a.running = false
+	close(a.stop)
+	<-a.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsUpdateTimeout)
+	defer cancel()
+	return a.client.DeleteSRV(ctx, a.zone, a.record)
+}
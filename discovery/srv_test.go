@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go/discovery/dnsiface"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockDNSClient struct {
+	mutex   sync.Mutex
+	sets    []dnsiface.SRVRecord
+	deletes []dnsiface.SRVRecord
+	zones   []string
+	setErr  error
+}
+
+var _ dnsiface.Client = &mockDNSClient{}
+
+func (c *mockDNSClient) SetSRV(_ context.Context, zone string, record dnsiface.SRVRecord) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.zones = append(c.zones, zone)
+	if c.setErr != nil {
+		return c.setErr
+	}
+	c.sets = append(c.sets, record)
+	return nil
+}
+
+func (c *mockDNSClient) DeleteSRV(_ context.Context, zone string, record dnsiface.SRVRecord) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.zones = append(c.zones, zone)
+	c.deletes = append(c.deletes, record)
+	return nil
+}
+
+func (c *mockDNSClient) numSets() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.sets)
+}
+
+func TestDNSSRVAdvertiser(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		client := &mockDNSClient{}
+		a := DNSSRVAdvertiser("example.com", 443, time.Minute, client)
+		require.NoError(t, a.Advertise())
+		require.ErrorContains(t, a.Advertise(), "already advertising")
+
+		expected := dnsiface.SRVRecord{
+			Name:   "_quic._udp.example.com.",
+			Target: "example.com.",
+			Port:   443,
+			TTL:    time.Minute,
+		}
+		require.Equal(t, []dnsiface.SRVRecord{expected}, client.sets)
+
+		// the record is refreshed every half TTL
+		time.Sleep(time.Minute + time.Second)
+		synctest.Wait()
+		require.Equal(t, 3, client.numSets())
+
+		require.NoError(t, a.Withdraw())
+		require.Equal(t, []dnsiface.SRVRecord{expected}, client.deletes)
+		require.NoError(t, a.Withdraw()) // duplicate calls are ok
+		require.Len(t, client.deletes, 1)
+		for _, zone := range client.zones {
+			require.Equal(t, "example.com.", zone)
+		}
+
+		// no more refreshes after withdrawing
+		time.Sleep(time.Hour)
+		require.Equal(t, 3, client.numSets())
+	})
+}
+
+func TestDNSSRVAdvertiserErrors(t *testing.T) {
+	t.Run("invalid port", func(t *testing.T) {
+		a := DNSSRVAdvertiser("example.com", 1<<16, time.Minute, &mockDNSClient{})
+		require.EqualError(t, a.Advertise(), "discovery: invalid port: 65536")
+	})
+
+	t.Run("registration fails", func(t *testing.T) {
+		testErr := errors.New("test error")
+		client := &mockDNSClient{setErr: testErr}
+		a := DNSSRVAdvertiser("example.com.", 443, 0, client)
+		require.ErrorIs(t, a.Advertise(), testErr)
+		// nothing to withdraw
+		require.NoError(t, a.Withdraw())
+		require.Empty(t, client.deletes)
+	})
+}
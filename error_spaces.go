@@ -0,0 +1,207 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// An ErrorSpace is a named range of application-defined error codes, starting at Base and containing Size codes.
+// It allows multiple modules of an application to share a connection, each using its own error codes.
+type ErrorSpace[T StreamErrorCode | ApplicationErrorCode] struct {
+	Name string
+	Base T
+	Size uint64
+}
+
+// Code returns the n-th error code of the error space.
+// It panics if n is not smaller than the size of the error space.
+func (s *ErrorSpace[T]) Code(n uint64) T {
+	if n >= s.Size {
+		panic(fmt.Sprintf("error code %d out of range for error space %s (size %d)", n, s.Name, s.Size))
+	}
+	return s.Base + T(n)
+}
+
+// Contains says if the error code belongs to the error space.
+func (s *ErrorSpace[T]) Contains(code T) bool {
+	return code >= s.Base && uint64(code-s.Base) < s.Size
+}
+
+// Offset returns the position of the error code within the error space.
+// The second return value is false if the error code doesn't belong to the error space.
+func (s *ErrorSpace[T]) Offset(code T) (uint64, bool) {
+	if !s.Contains(code) {
+		return 0, false
+	}
+	return uint64(code - s.Base), true
+}
+
+type errorSpaceRegistry[T StreamErrorCode | ApplicationErrorCode] struct {
+	mutex  sync.RWMutex
+	spaces []*ErrorSpace[T]
+}
+
+func (r *errorSpaceRegistry[T]) register(base T, size uint64, name string) (*ErrorSpace[T], error) {
+	if size == 0 {
+		return nil, errors.New("error space must not be empty")
+	}
+	if uint64(base) > quicvarint.Max || size-1 > quicvarint.Max-uint64(base) {
+		return nil, fmt.Errorf("error space %s exceeds the maximum error code (%d)", name, uint64(quicvarint.Max))
+	}
+	s := &ErrorSpace[T]{Name: name, Base: base, Size: size}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, other := range r.spaces {
+		if s.Contains(other.Base) || other.Contains(s.Base) {
+			return nil, fmt.Errorf("error space %s overlaps with error space %s", name, other.Name)
+		}
+	}
+	r.spaces = append(r.spaces, s)
+	return s, nil
+}
+
+func (r *errorSpaceRegistry[T]) unregister(s *ErrorSpace[T]) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.spaces = slices.DeleteFunc(r.spaces, func(other *ErrorSpace[T]) bool { return other == s })
+}
+
+func (r *errorSpaceRegistry[T]) lookup(code T) (*ErrorSpace[T], bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, s := range r.spaces {
+		if s.Contains(code) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// ErrorSpaces is a set of error spaces, owned by the application.
+// Applications that multiplex multiple modules over a single connection can register
+// one error space per module, and set Config.ErrorSpaces.
+// The names of the error spaces are then included in qlog.
+// Stream and application error spaces are registered independently,
+// since the same error code can have a different meaning when used to close a connection.
+// The zero value is an empty set. It is safe for concurrent use,
+// and can be shared between multiple Configs.
+type ErrorSpaces struct {
+	stream      errorSpaceRegistry[StreamErrorCode]
+	application errorSpaceRegistry[ApplicationErrorCode]
+}
+
+// RegisterStreamErrorSpace registers an error space for the stream error codes
+// in the range [base, base+size), as used for RESET_STREAM and STOP_SENDING frames.
+// It returns an error if the range overlaps with a previously registered stream error space.
+func (s *ErrorSpaces) RegisterStreamErrorSpace(base StreamErrorCode, size uint64, name string) (*ErrorSpace[StreamErrorCode], error) {
+	return s.stream.register(base, size, name)
+}
+
+// UnregisterStreamErrorSpace removes a stream error space registered using RegisterStreamErrorSpace.
+// Its error codes can then be registered again.
+func (s *ErrorSpaces) UnregisterStreamErrorSpace(space *ErrorSpace[StreamErrorCode]) {
+	s.stream.unregister(space)
+}
+
+// LookupStreamErrorSpace returns the stream error space that the error code belongs to.
+// It is valid to call LookupStreamErrorSpace on a nil ErrorSpaces.
+func (s *ErrorSpaces) LookupStreamErrorSpace(code StreamErrorCode) (*ErrorSpace[StreamErrorCode], bool) {
+	if s == nil {
+		return nil, false
+	}
+	return s.stream.lookup(code)
+}
+
+// RegisterApplicationErrorSpace registers an error space for the application error codes
+// in the range [base, base+size), as used for CONNECTION_CLOSE frames.
+// It returns an error if the range overlaps with a previously registered application error space.
+func (s *ErrorSpaces) RegisterApplicationErrorSpace(base ApplicationErrorCode, size uint64, name string) (*ErrorSpace[ApplicationErrorCode], error) {
+	return s.application.register(base, size, name)
+}
+
+// UnregisterApplicationErrorSpace removes an application error space registered using RegisterApplicationErrorSpace.
+// Its error codes can then be registered again.
+func (s *ErrorSpaces) UnregisterApplicationErrorSpace(space *ErrorSpace[ApplicationErrorCode]) {
+	s.application.unregister(space)
+}
+
+// LookupApplicationErrorSpace returns the application error space that the error code belongs to.
+// It is valid to call LookupApplicationErrorSpace on a nil ErrorSpaces.
+func (s *ErrorSpaces) LookupApplicationErrorSpace(code ApplicationErrorCode) (*ErrorSpace[ApplicationErrorCode], bool) {
+	if s == nil {
+		return nil, false
+	}
+	return s.application.lookup(code)
+}
+
+// StreamError maps err to the stream error space that its error code belongs to.
+// It returns false if err is not a StreamError, or if the error code doesn't belong to a registered error space.
+func (s *ErrorSpaces) StreamError(err error) (*ErrorSpaceStreamError, bool) {
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		return nil, false
+	}
+	space, ok := s.LookupStreamErrorSpace(streamErr.ErrorCode)
+	if !ok {
+		return nil, false
+	}
+	return &ErrorSpaceStreamError{StreamError: streamErr, Space: space}, true
+}
+
+// ApplicationError maps err to the application error space that its error code belongs to.
+// It returns false if err is not an ApplicationError, or if the error code doesn't belong to a registered error space.
+func (s *ErrorSpaces) ApplicationError(err error) (*ErrorSpaceApplicationError, bool) {
+	var appErr *ApplicationError
+	if !errors.As(err, &appErr) {
+		return nil, false
+	}
+	space, ok := s.LookupApplicationErrorSpace(appErr.ErrorCode)
+	if !ok {
+		return nil, false
+	}
+	return &ErrorSpaceApplicationError{ApplicationError: appErr, Space: space}, true
+}
+
+// An ErrorSpaceStreamError is a StreamError with an error code belonging to a registered error space.
+type ErrorSpaceStreamError struct {
+	*StreamError
+	Space *ErrorSpace[StreamErrorCode]
+}
+
+// Code returns the position of the error code within the error space.
+func (e *ErrorSpaceStreamError) Code() uint64 {
+	offset, _ := e.Space.Offset(e.ErrorCode)
+	return offset
+}
+
+func (e *ErrorSpaceStreamError) Error() string {
+	return fmt.Sprintf("%s [%s]", e.StreamError.Error(), e.Space.Name)
+}
+
+func (e *ErrorSpaceStreamError) Unwrap() error { return e.StreamError }
+
+// An ErrorSpaceApplicationError is an ApplicationError with an error code belonging to a registered error space.
+type ErrorSpaceApplicationError struct {
+	*ApplicationError
+	Space *ErrorSpace[ApplicationErrorCode]
+}
+
+// Code returns the position of the error code within the error space.
+func (e *ErrorSpaceApplicationError) Code() uint64 {
+	offset, _ := e.Space.Offset(e.ErrorCode)
+	return offset
+}
+
+func (e *ErrorSpaceApplicationError) Error() string {
+	return fmt.Sprintf("%s [%s]", e.ApplicationError.Error(), e.Space.Name)
+}
+
+func (e *ErrorSpaceApplicationError) Unwrap() error { return e.ApplicationError }
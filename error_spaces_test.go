@@ -0,0 +1,157 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/quic-go/quic-go/quicvarint"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorSpace(t *testing.T) {
+	s := &ErrorSpace[StreamErrorCode]{Name: "foo", Base: 0x100, Size: 0x10}
+	require.Equal(t, StreamErrorCode(0x100), s.Code(0))
+	require.Equal(t, StreamErrorCode(0x10f), s.Code(0xf))
+	require.Panics(t, func() { s.Code(0x10) })
+
+	require.False(t, s.Contains(0xff))
+	require.True(t, s.Contains(0x100))
+	require.True(t, s.Contains(0x10f))
+	require.False(t, s.Contains(0x110))
+
+	offset, ok := s.Offset(0x105)
+	require.True(t, ok)
+	require.Equal(t, uint64(5), offset)
+	_, ok = s.Offset(0x110)
+	require.False(t, ok)
+}
+
+func TestRegisterErrorSpaces(t *testing.T) {
+	var r errorSpaceRegistry[ApplicationErrorCode]
+	foo, err := r.register(0x100, 0x10, "foo")
+	require.NoError(t, err)
+	bar, err := r.register(0x110, 0x10, "bar")
+	require.NoError(t, err)
+
+	s, ok := r.lookup(0x10f)
+	require.True(t, ok)
+	require.Same(t, foo, s)
+	s, ok = r.lookup(0x110)
+	require.True(t, ok)
+	require.Same(t, bar, s)
+	_, ok = r.lookup(0x120)
+	require.False(t, ok)
+
+	// overlapping error spaces
+	_, err = r.register(0x10f, 2, "baz")
+	require.EqualError(t, err, "error space baz overlaps with error space foo")
+	_, err = r.register(0x80, 0x100, "baz")
+	require.EqualError(t, err, "error space baz overlaps with error space foo")
+	// invalid error spaces
+	_, err = r.register(0x200, 0, "baz")
+	require.EqualError(t, err, "error space must not be empty")
+	_, err = r.register(quicvarint.Max, 2, "baz")
+	require.ErrorContains(t, err, "exceeds the maximum error code")
+	// the error space may include the maximum error code
+	_, err = r.register(quicvarint.Max, 1, "max")
+	require.NoError(t, err)
+
+	// after unregistering an error space, its error codes can be registered again
+	r.unregister(foo)
+	_, ok = r.lookup(0x100)
+	require.False(t, ok)
+	baz, err := r.register(0x80, 0x90, "baz")
+	require.NoError(t, err)
+	s, ok = r.lookup(0x100)
+	require.True(t, ok)
+	require.Same(t, baz, s)
+}
+
+func TestErrorSpaces(t *testing.T) {
+	var spaces ErrorSpaces
+	streamSpace, err := spaces.RegisterStreamErrorSpace(0x2000, 0x100, "rpc")
+	require.NoError(t, err)
+	_, err = spaces.RegisterStreamErrorSpace(0x2080, 0x100, "other")
+	require.Error(t, err)
+	appSpace, err := spaces.RegisterApplicationErrorSpace(0x2000, 0x10, "auth")
+	require.NoError(t, err)
+
+	s, ok := spaces.LookupStreamErrorSpace(0x2042)
+	require.True(t, ok)
+	require.Same(t, streamSpace, s)
+	// stream error spaces are independent from application error spaces
+	_, ok = spaces.LookupApplicationErrorSpace(0x2042)
+	require.False(t, ok)
+	a, ok := spaces.LookupApplicationErrorSpace(0x2001)
+	require.True(t, ok)
+	require.Same(t, appSpace, a)
+
+	// error spaces are scoped to the ErrorSpaces they were registered with
+	var otherSpaces ErrorSpaces
+	_, ok = otherSpaces.LookupStreamErrorSpace(0x2042)
+	require.False(t, ok)
+	_, err = otherSpaces.RegisterStreamErrorSpace(0x2080, 0x100, "other")
+	require.NoError(t, err)
+
+	spaces.UnregisterStreamErrorSpace(streamSpace)
+	_, ok = spaces.LookupStreamErrorSpace(0x2042)
+	require.False(t, ok)
+	spaces.UnregisterApplicationErrorSpace(appSpace)
+	_, ok = spaces.LookupApplicationErrorSpace(0x2001)
+	require.False(t, ok)
+
+	// a nil ErrorSpaces doesn't contain any error spaces
+	var nilSpaces *ErrorSpaces
+	_, ok = nilSpaces.LookupStreamErrorSpace(0x2042)
+	require.False(t, ok)
+	_, ok = nilSpaces.LookupApplicationErrorSpace(0x2001)
+	require.False(t, ok)
+}
+
+func TestErrorSpacesStreamError(t *testing.T) {
+	var spaces ErrorSpaces
+	space, err := spaces.RegisterStreamErrorSpace(0x2000, 0x100, "rpc")
+	require.NoError(t, err)
+
+	streamErr := &StreamError{StreamID: 4, ErrorCode: space.Code(0x42), Remote: true}
+	e, ok := spaces.StreamError(fmt.Errorf("read failed: %w", streamErr))
+	require.True(t, ok)
+	require.Same(t, space, e.Space)
+	require.Equal(t, uint64(0x42), e.Code())
+	require.Equal(t, "stream 4 canceled by remote with error code 8258 [rpc]", e.Error())
+	require.ErrorIs(t, e, &StreamError{StreamID: 4, ErrorCode: 0x2042, Remote: true})
+	var unwrapped *StreamError
+	require.ErrorAs(t, e, &unwrapped)
+	require.Same(t, streamErr, unwrapped)
+
+	_, ok = spaces.StreamError(&StreamError{StreamID: 4, ErrorCode: 0x1000})
+	require.False(t, ok)
+	_, ok = spaces.StreamError(errors.New("foobar"))
+	require.False(t, ok)
+}
+
+func TestErrorSpacesApplicationError(t *testing.T) {
+	var spaces ErrorSpaces
+	space, err := spaces.RegisterApplicationErrorSpace(0x1000, 0x100, "auth")
+	require.NoError(t, err)
+
+	appErr := &ApplicationError{ErrorCode: space.Code(1), Remote: true, ErrorMessage: "foobar"}
+	e, ok := spaces.ApplicationError(appErr)
+	require.True(t, ok)
+	require.Same(t, space, e.Space)
+	require.Equal(t, uint64(1), e.Code())
+	require.Equal(t, "Application error 0x1001 (remote): foobar [auth]", e.Error())
+	require.ErrorIs(t, e, net.ErrClosed)
+	var unwrapped *ApplicationError
+	require.ErrorAs(t, e, &unwrapped)
+	require.Same(t, appErr, unwrapped)
+
+	_, ok = spaces.ApplicationError(&ApplicationError{ErrorCode: 0x1100})
+	require.False(t, ok)
+	// stream errors don't belong to application error spaces
+	_, ok = spaces.ApplicationError(&StreamError{ErrorCode: 0x1001})
+	require.False(t, ok)
+}
@@ -0,0 +1,174 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// FaultDirection is the direction of a packet passed to the [Transport.FaultInjector].
+type FaultDirection uint8
+
+const (
+	// FaultDirectionIncoming is used for packets received from the peer.
+	FaultDirectionIncoming FaultDirection = iota + 1
+	// FaultDirectionOutgoing is used for packets sent to the peer.
+	FaultDirectionOutgoing
+)
+
+// FaultLayer is the layer at which the [Transport.FaultInjector] is called.
+type FaultLayer uint8
+
+const (
+	// FaultLayerNetwork is used for UDP datagrams as they are sent on and received from the wire.
+	// Outgoing datagrams have already been encrypted, incoming datagrams haven't been decrypted yet.
+	// Corrupting a datagram at this layer simulates bit flips on the network.
+	FaultLayerNetwork FaultLayer = iota + 1
+	// FaultLayerPlaintext is used for the payload of single QUIC packets.
+	// Outgoing payloads haven't been encrypted yet, incoming payloads have already been decrypted.
+	// Corrupting a payload at this layer simulates a malicious peer.
+	FaultLayerPlaintext
+)
+
+// FaultInfo describes a packet passed to the [Transport.FaultInjector].
+type FaultInfo struct {
+	Direction FaultDirection
+	Layer     FaultLayer
+	// RemoteAddr is the address of the peer.
+	// It is only set for the network layer.
+	RemoteAddr net.Addr
+	// Data is the UDP datagram (network layer), or the packet payload (plaintext layer).
+	// It must not be modified or retained.
+	Data []byte
+}
+
+// A FaultAction is returned by the [Transport.FaultInjector].
+// The zero value processes the packet normally.
+type FaultAction struct {
+	// Drop drops the packet.
+	// Only used for the network layer.
+	Drop bool
+	// Duplicate processes the packet twice.
+	// Only used for the network layer.
+	Duplicate bool
+	// Delay delays the packet by the given duration.
+	// Only used for the network layer.
+	Delay time.Duration
+	// CorruptStart and CorruptEnd define the range of bytes that are corrupted by flipping all their bits.
+	// The range is clamped to the length of the data.
+	// If the range is empty, the packet is not corrupted.
+	CorruptStart, CorruptEnd int
+}
+
+func (a FaultAction) corrupt(b []byte) {
+	end := min(a.CorruptEnd, len(b))
+	for i := max(a.CorruptStart, 0); i < end; i++ {
+		b[i] ^= 0xff
+	}
+}
+
+func (a FaultAction) isCorrupting() bool {
+	return a.CorruptEnd > max(a.CorruptStart, 0)
+}
+
+// applyPlaintextFault calls the fault injector for the payload of a QUIC packet,
+// and corrupts the payload in place if requested.
+func applyPlaintextFault(injector func(FaultInfo) FaultAction, dir FaultDirection, payload []byte) {
+	action := injector(FaultInfo{Direction: dir, Layer: FaultLayerPlaintext, Data: payload})
+	action.corrupt(payload)
+}
+
+// handlePacketWithFault calls the fault injector for an incoming datagram,
+// and passes the resulting packets to handle.
+func handlePacketWithFault(injector func(FaultInfo) FaultAction, p receivedPacket, handle func(receivedPacket)) {
+	action := injector(FaultInfo{
+		Direction:  FaultDirectionIncoming,
+		Layer:      FaultLayerNetwork,
+		RemoteAddr: p.remoteAddr,
+		Data:       p.data,
+	})
+	if action.Drop {
+		p.buffer.Release()
+		return
+	}
+	action.corrupt(p.data)
+	packets := []receivedPacket{p}
+	if action.Duplicate {
+		dup := p
+		dup.buffer = getPacketBuffer()
+		dup.buffer.Data = append(dup.buffer.Data, p.data...)
+		dup.data = dup.buffer.Data
+		packets = append(packets, dup)
+	}
+	for _, p := range packets {
+		if action.Delay > 0 {
+			time.AfterFunc(action.Delay, func() {
+				p.rcvTime = monotime.Now()
+				handle(p)
+			})
+			continue
+		}
+		handle(p)
+	}
+}
+
+// faultInjectingConn is a rawConn that calls the fault injector for every outgoing datagram.
+type faultInjectingConn struct {
+	rawConn
+
+	injector func(FaultInfo) FaultAction
+}
+
+var _ rawConn = &faultInjectingConn{}
+
+func (c *faultInjectingConn) WritePacket(b []byte, addr net.Addr, packetInfoOOB []byte, gsoSize uint16, ecn protocol.ECN) (int, error) {
+	if gsoSize == 0 {
+		return len(b), c.writeDatagram(b, addr, packetInfoOOB, ecn)
+	}
+	// Split GSO batches, so that the fault injector is called for every single datagram.
+	var n int
+	for n < len(b) {
+		size := min(int(gsoSize), len(b)-n)
+		if err := c.writeDatagram(b[n:n+size], addr, packetInfoOOB, ecn); err != nil {
+			return n, err
+		}
+		n += size
+	}
+	return n, nil
+}
+
+func (c *faultInjectingConn) writeDatagram(b []byte, addr net.Addr, packetInfoOOB []byte, ecn protocol.ECN) error {
+	action := c.injector(FaultInfo{
+		Direction:  FaultDirectionOutgoing,
+		Layer:      FaultLayerNetwork,
+		RemoteAddr: addr,
+		Data:       b,
+	})
+	if action.Drop {
+		return nil
+	}
+	if action.isCorrupting() || action.Delay > 0 {
+		// the buffer is reused once WritePacket returns
+		b = append([]byte(nil), b...)
+		packetInfoOOB = append([]byte(nil), packetInfoOOB...)
+		action.corrupt(b)
+	}
+	n := 1
+	if action.Duplicate {
+		n = 2
+	}
+	for range n {
+		if action.Delay > 0 {
+			time.AfterFunc(action.Delay, func() {
+				c.rawConn.WritePacket(b, addr, packetInfoOOB, 0, ecn)
+			})
+			continue
+		}
+		if _, err := c.rawConn.WritePacket(b, addr, packetInfoOOB, 0, ecn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package quic
+
+import (
+	"net"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFaultActionCorrupt(t *testing.T) {
+	b := []byte{0, 1, 2, 3}
+	FaultAction{}.corrupt(b)
+	require.Equal(t, []byte{0, 1, 2, 3}, b)
+
+	FaultAction{CorruptStart: 1, CorruptEnd: 3}.corrupt(b)
+	require.Equal(t, []byte{0, 0xfe, 0xfd, 3}, b)
+
+	// the range is clamped to the length of the data
+	b = []byte{0, 1, 2, 3}
+	FaultAction{CorruptStart: -1, CorruptEnd: 100}.corrupt(b)
+	require.Equal(t, []byte{0xff, 0xfe, 0xfd, 0xfc}, b)
+}
+
+func TestFaultInjectionIncoming(t *testing.T) {
+	newPacket := func(data []byte) receivedPacket {
+		buf := getPacketBuffer()
+		buf.Data = append(buf.Data, data...)
+		return receivedPacket{
+			buffer:     buf,
+			data:       buf.Data,
+			remoteAddr: &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+		}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		var handled []receivedPacket
+		handlePacketWithFault(
+			func(FaultInfo) FaultAction { return FaultAction{Drop: true} },
+			newPacket([]byte("foobar")),
+			func(p receivedPacket) { handled = append(handled, p) },
+		)
+		require.Empty(t, handled)
+	})
+
+	t.Run("duplicate and corrupt", func(t *testing.T) {
+		var info FaultInfo
+		var handled []receivedPacket
+		handlePacketWithFault(
+			func(i FaultInfo) FaultAction {
+				info = i
+				return FaultAction{Duplicate: true, CorruptStart: 0, CorruptEnd: 1}
+			},
+			newPacket([]byte("foobar")),
+			func(p receivedPacket) { handled = append(handled, p) },
+		)
+		require.Equal(t, FaultDirectionIncoming, info.Direction)
+		require.Equal(t, FaultLayerNetwork, info.Layer)
+		require.Equal(t, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, info.RemoteAddr)
+		require.Len(t, handled, 2)
+		require.Equal(t, append([]byte{'f' ^ 0xff}, "oobar"...), handled[0].data)
+		require.Equal(t, handled[0].data, handled[1].data)
+		require.NotSame(t, handled[0].buffer, handled[1].buffer)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			handled := make(chan time.Time, 1)
+			start := time.Now()
+			handlePacketWithFault(
+				func(FaultInfo) FaultAction { return FaultAction{Delay: 42 * time.Millisecond} },
+				newPacket([]byte("foobar")),
+				func(receivedPacket) { handled <- time.Now() },
+			)
+			synctest.Wait()
+			require.Empty(t, handled)
+			require.Equal(t, start.Add(42*time.Millisecond), <-handled)
+		})
+	})
+}
+
+func TestFaultInjectionOutgoing(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+
+	t.Run("GSO batches are split", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		rawConn := NewMockRawConn(mockCtrl)
+		var sizes []int
+		c := &faultInjectingConn{
+			rawConn: rawConn,
+			injector: func(i FaultInfo) FaultAction {
+				require.Equal(t, FaultDirectionOutgoing, i.Direction)
+				require.Equal(t, FaultLayerNetwork, i.Layer)
+				require.Equal(t, addr, i.RemoteAddr)
+				sizes = append(sizes, len(i.Data))
+				// drop the second datagram
+				return FaultAction{Drop: len(sizes) == 2}
+			},
+		}
+		gomock.InOrder(
+			rawConn.EXPECT().WritePacket(make([]byte, 10), addr, []byte("oob"), uint16(0), protocol.ECT1),
+			rawConn.EXPECT().WritePacket(make([]byte, 5), addr, []byte("oob"), uint16(0), protocol.ECT1),
+		)
+		n, err := c.WritePacket(make([]byte, 25), addr, []byte("oob"), 10, protocol.ECT1)
+		require.NoError(t, err)
+		require.Equal(t, 25, n)
+		require.Equal(t, []int{10, 10, 5}, sizes)
+	})
+
+	t.Run("duplicate and corrupt", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		rawConn := NewMockRawConn(mockCtrl)
+		c := &faultInjectingConn{
+			rawConn: rawConn,
+			injector: func(FaultInfo) FaultAction {
+				return FaultAction{Duplicate: true, CorruptStart: 3, CorruptEnd: 6}
+			},
+		}
+		b := []byte("foobar")
+		rawConn.EXPECT().WritePacket(append([]byte("foo"), 'b'^0xff, 'a'^0xff, 'r'^0xff), addr, nil, uint16(0), protocol.ECNNon).Times(2)
+		_, err := c.WritePacket(b, addr, nil, 0, protocol.ECNNon)
+		require.NoError(t, err)
+		// the original buffer is not modified
+		require.Equal(t, []byte("foobar"), b)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			rawConn := NewMockRawConn(mockCtrl)
+			c := &faultInjectingConn{
+				rawConn: rawConn,
+				injector: func(FaultInfo) FaultAction {
+					return FaultAction{Delay: 42 * time.Millisecond}
+				},
+			}
+			written := make(chan time.Time, 1)
+			rawConn.EXPECT().WritePacket([]byte("foobar"), addr, nil, uint16(0), protocol.ECNNon).DoAndReturn(
+				func([]byte, net.Addr, []byte, uint16, protocol.ECN) (int, error) {
+					written <- time.Now()
+					return 6, nil
+				},
+			)
+			b := []byte("foobar")
+			start := time.Now()
+			_, err := c.WritePacket(b, addr, nil, 0, protocol.ECNNon)
+			require.NoError(t, err)
+			// the buffer can be reused as soon as WritePacket returns
+			copy(b, "raboof")
+			require.Equal(t, start.Add(42*time.Millisecond), <-written)
+		})
+	})
+}
@@ -26,17 +26,27 @@ type frameSorterEntry struct {
 }
 
 type frameSorter struct {
-	queue   map[protocol.ByteCount]frameSorterEntry
-	readPos protocol.ByteCount
-	gaps    *list.List[byteInterval]
+	queue         map[protocol.ByteCount]frameSorterEntry
+	bufferedBytes protocol.ByteCount
+	readPos       protocol.ByteCount
+	gaps          *list.List[byteInterval]
+	maxGaps       int
 }
 
-var errDuplicateStreamData = errors.New("duplicate stream data")
+var (
+	errDuplicateStreamData = errors.New("duplicate stream data")
+	errTooManyGaps         = errors.New("too many gaps in received data")
+)
 
 func newFrameSorter() *frameSorter {
+	return newFrameSorterWithMaxGaps(protocol.MaxStreamFrameSorterGaps)
+}
+
+func newFrameSorterWithMaxGaps(maxGaps int) *frameSorter {
 	s := frameSorter{
-		gaps:  list.NewWithPool[byteInterval](&byteIntervalElementPool),
-		queue: make(map[protocol.ByteCount]frameSorterEntry),
+		gaps:    list.NewWithPool[byteInterval](&byteIntervalElementPool),
+		queue:   make(map[protocol.ByteCount]frameSorterEntry),
+		maxGaps: maxGaps,
 	}
 	s.gaps.PushFront(byteInterval{Start: 0, End: protocol.MaxByteCount})
 	return &s
@@ -93,6 +103,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
 			delete(s.queue, pos)
+			s.bufferedBytes -= oldEntryLen
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -169,11 +180,12 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		}
 	}
 
-	if s.gaps.Len() > protocol.MaxStreamFrameSorterGaps {
-		return errors.New("too many gaps in received data")
+	if s.gaps.Len() > s.maxGaps {
+		return errTooManyGaps
 	}
 
 	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.bufferedBytes += protocol.ByteCount(len(data))
 	return nil
 }
 
@@ -210,6 +222,7 @@ func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 		}
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		delete(s.queue, pos)
+		s.bufferedBytes -= oldEntryLen
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
@@ -223,6 +236,7 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 		return s.readPos, nil, nil
 	}
 	delete(s.queue, s.readPos)
+	s.bufferedBytes -= protocol.ByteCount(len(entry.Data))
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
 	if s.gaps.Front().Value.End <= s.readPos {
@@ -231,6 +245,27 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	return offset, entry.Data, entry.DoneCb
 }
 
+// BufferedBytes returns the number of bytes queued at *any* offset.
+func (s *frameSorter) BufferedBytes() protocol.ByteCount {
+	return s.bufferedBytes
+}
+
+// OutOfOrderBytes returns the number of bytes queued beyond the contiguous offset,
+// i.e. the data that can't be read until a gap is filled.
+func (s *frameSorter) OutOfOrderBytes() protocol.ByteCount {
+	return s.bufferedBytes - (s.ContiguousOffset() - s.readPos)
+}
+
+// NumGaps returns the number of gaps in front of data that was received out of order.
+func (s *frameSorter) NumGaps() int {
+	return s.gaps.Len() - 1
+}
+
+// ContiguousOffset returns the offset up to which data was received without any gaps.
+func (s *frameSorter) ContiguousOffset() protocol.ByteCount {
+	return s.gaps.Front().Value.Start
+}
+
 // HasMoreData says if there is any more data queued at *any* offset.
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
@@ -85,6 +85,34 @@ func TestFrameSorterSimpleCases(t *testing.T) {
 	require.False(t, s.HasMoreData())
 }
 
+func TestFrameSorterBufferedBytes(t *testing.T) {
+	s := newFrameSorter()
+	require.NoError(t, s.Push([]byte("foobar"), 10, nil))
+	require.Equal(t, protocol.ByteCount(6), s.BufferedBytes())
+	require.Equal(t, protocol.ByteCount(6), s.OutOfOrderBytes())
+	// overlapping data
+	require.NoError(t, s.Push([]byte("raboof"), 14, nil))
+	require.Equal(t, protocol.ByteCount(10), s.BufferedBytes())
+	require.Equal(t, protocol.ByteCount(10), s.OutOfOrderBytes())
+	// duplicate data
+	require.NoError(t, s.Push([]byte("foo"), 10, nil))
+	require.Equal(t, protocol.ByteCount(10), s.BufferedBytes())
+
+	// fill the first part of the gap
+	require.NoError(t, s.Push([]byte("lorem"), 0, nil))
+	require.Equal(t, protocol.ByteCount(15), s.BufferedBytes())
+	require.Equal(t, protocol.ByteCount(10), s.OutOfOrderBytes())
+	_, data, _ := s.Pop()
+	require.Equal(t, []byte("lorem"), data)
+	require.Equal(t, protocol.ByteCount(10), s.BufferedBytes())
+	require.Equal(t, protocol.ByteCount(10), s.OutOfOrderBytes())
+
+	// close the gap
+	require.NoError(t, s.Push([]byte("ipsum"), 5, nil))
+	require.Equal(t, protocol.ByteCount(15), s.BufferedBytes())
+	require.Zero(t, s.OutOfOrderBytes())
+}
+
 // Usually, it's not a good idea to test the implementation details.
 // However, we need to make sure that the frame sorter handles gaps correctly,
 // in particular when overlapping stream data is received.
@@ -1451,6 +1479,8 @@ func testFrameSorterRandomized(t *testing.T, dataLen protocol.ByteCount, injectD
 	}
 	require.Equal(t, 1, s.gaps.Len())
 	require.Equal(t, byteInterval{Start: num * dataLen, End: protocol.MaxByteCount}, s.gaps.Front().Value)
+	require.Equal(t, num*dataLen, s.BufferedBytes())
+	require.Zero(t, s.OutOfOrderBytes())
 
 	// read all data
 	var read []byte
@@ -1468,6 +1498,7 @@ func testFrameSorterRandomized(t *testing.T, dataLen protocol.ByteCount, injectD
 
 	require.Equal(t, data, read)
 	require.False(t, s.HasMoreData())
+	require.Zero(t, s.BufferedBytes())
 	for _, cb := range callbacks {
 		require.True(t, cb.WasCalled())
 	}
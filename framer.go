@@ -24,6 +24,8 @@ const maxStreamControlFrameSize = 25
 
 type streamFrameGetter interface {
 	popStreamFrame(protocol.ByteCount, protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool)
+	// hasRetransmission says if the next STREAM frame popped from the stream is a retransmission.
+	hasRetransmission() bool
 }
 
 type streamControlFrameGetter interface {
@@ -36,19 +38,29 @@ type framer struct {
 	activeStreams            map[protocol.StreamID]streamFrameGetter
 	streamQueue              ringbuffer.RingBuffer[protocol.StreamID]
 	streamsWithControlFrames map[protocol.StreamID]streamControlFrameGetter
+	// maxStreamsPerPacket limits the number of streams that STREAM frames are packed for in a single packet.
+	// If 0, the number of streams is not limited.
+	maxStreamsPerPacket int
+	// prioritizeRetransmissions makes the framer pack all retransmissions before packing new STREAM data.
+	prioritizeRetransmissions bool
 
-	controlFrameMutex          sync.Mutex
-	controlFrames              []wire.Frame
+	controlFrameMutex sync.Mutex
+	controlFrames     []ackhandler.Frame
+	// queuedMaxData is the MAX_DATA frame currently waiting in controlFrames, if any.
+	// Newer flow control updates are coalesced into this frame.
+	queuedMaxData              *wire.MaxDataFrame
 	pathResponses              []*wire.PathResponseFrame
 	connFlowController         flowcontrol.ConnectionFlowController
 	queuedTooManyControlFrames bool
 }
 
-func newFramer(connFlowController flowcontrol.ConnectionFlowController) *framer {
+func newFramer(connFlowController flowcontrol.ConnectionFlowController, maxStreamsPerPacket int, prioritizeRetransmissions bool) *framer {
 	return &framer{
-		activeStreams:            make(map[protocol.StreamID]streamFrameGetter),
-		streamsWithControlFrames: make(map[protocol.StreamID]streamControlFrameGetter),
-		connFlowController:       connFlowController,
+		activeStreams:             make(map[protocol.StreamID]streamFrameGetter),
+		streamsWithControlFrames:  make(map[protocol.StreamID]streamControlFrameGetter),
+		connFlowController:        connFlowController,
+		maxStreamsPerPacket:       max(maxStreamsPerPacket, 0),
+		prioritizeRetransmissions: prioritizeRetransmissions,
 	}
 }
 
@@ -78,6 +90,27 @@ func (f *framer) QueueControlFrame(frame wire.Frame) {
 		f.pathResponses = append(f.pathResponses, pr)
 		return
 	}
+	f.queueControlFrame(ackhandler.Frame{Frame: frame})
+}
+
+// QueueControlFrameWithHandler queues a control frame.
+// The handler is notified when the packet containing the frame is acknowledged or declared lost.
+func (f *framer) QueueControlFrameWithHandler(frame ackhandler.Frame) {
+	f.controlFrameMutex.Lock()
+	defer f.controlFrameMutex.Unlock()
+
+	f.queueControlFrame(frame)
+}
+
+func (f *framer) queueControlFrame(frame ackhandler.Frame) {
+	// A MAX_DATA frame supersedes any MAX_DATA frame that hasn't been sent yet.
+	if mdf, ok := frame.Frame.(*wire.MaxDataFrame); ok && frame.Handler == nil {
+		if f.queuedMaxData != nil {
+			f.queuedMaxData.MaximumData = max(f.queuedMaxData.MaximumData, mdf.MaximumData)
+			return
+		}
+		f.queuedMaxData = mdf
+	}
 	// This is a hack.
 	if len(f.controlFrames) >= maxControlFrames {
 		f.queuedTooManyControlFrames = true
@@ -100,31 +133,84 @@ func (f *framer) Append(
 	var lastFrame ackhandler.StreamFrame
 	var streamFrameLen protocol.ByteCount
 	f.mutex.Lock()
+	if f.prioritizeRetransmissions {
+		var retransmissionsLeft bool
+		streamFrames, lastFrame, streamFrameLen, retransmissionsLeft = f.appendRetransmissions(streamFrames, maxLen, v)
+		maxLen -= streamFrameLen
+		if retransmissionsLeft {
+			// Don't send any new data until all retransmissions have been sent.
+			maxLen = 0
+		}
+	}
 	// pop STREAM frames, until less than 128 bytes are left in the packet
 	numActiveStreams := f.streamQueue.Len()
+	fairShare := f.fairConnectionWindowShare(maxLen)
+	var numStreams int
+streamLoop:
 	for i := 0; i < numActiveStreams; i++ {
 		if protocol.MinStreamFrameSize > maxLen {
 			break
 		}
-		sf, blocked := f.getNextStreamFrame(maxLen, v)
-		if sf.Frame != nil {
-			streamFrames = append(streamFrames, sf)
-			maxLen -= sf.Frame.Length(v)
-			lastFrame = sf
-			streamFrameLen += sf.Frame.Length(v)
+		if f.maxStreamsPerPacket > 0 && numStreams >= f.maxStreamsPerPacket {
+			// The remaining streams stay at the front of the queue,
+			// and will be the first ones to be packed into the next packet.
+			break
+		}
+		id := f.streamQueue.PopFront()
+		// The stream might have been removed after being enqueued.
+		str, ok := f.activeStreams[id]
+		if !ok {
+			continue
 		}
-		// If the stream just became blocked on stream flow control, attempt to pack the
-		// STREAM_DATA_BLOCKED into the same packet.
-		if blocked != nil {
-			l := blocked.Length(v)
-			// In case it doesn't fit, queue it for the next packet.
-			if maxLen < l {
-				f.controlFrames = append(f.controlFrames, blocked)
+		var packedFrame bool
+		budget := fairShare
+		for {
+			frameMaxLen := maxLen
+			if fairShare > 0 {
+				frameMaxLen = min(maxLen, budget+minStreamFrameHeaderLen(id, budget))
+			}
+			sf, blocked, hasMoreData := f.getNextStreamFrame(str, frameMaxLen, v)
+			if !hasMoreData { // no more data to send. Stream is not active
+				delete(f.activeStreams, id)
+			}
+			if sf.Frame != nil {
+				if !packedFrame {
+					numStreams++
+					packedFrame = true
+				}
+				streamFrames = append(streamFrames, sf)
+				maxLen -= sf.Frame.Length(v)
+				lastFrame = sf
+				streamFrameLen += sf.Frame.Length(v)
+				budget -= min(budget, sf.Frame.DataLen())
+			}
+			// Without a limit on the number of streams per packet, every stream gets (at most) one frame per packet.
+			// Otherwise, we prefer filling the packet from fewer streams:
+			// as long as the stream has more data and the frame would have a reasonable size, we keep packing it.
+			keepPacking := f.maxStreamsPerPacket > 0 && hasMoreData && sf.Frame != nil && maxLen >= protocol.MinStreamFrameSize &&
+				(fairShare == 0 || budget > 0)
+			if hasMoreData && !keepPacking { // put the stream back in the queue (at the end)
+				f.streamQueue.PushBack(id)
+			}
+			// If the stream just became blocked on stream flow control, attempt to pack the
+			// STREAM_DATA_BLOCKED into the same packet.
+			if blocked != nil {
+				l := blocked.Length(v)
+				// In case it doesn't fit, queue it for the next packet.
+				if maxLen < l {
+					f.controlFrames = append(f.controlFrames, ackhandler.Frame{Frame: blocked})
+					if keepPacking {
+						f.streamQueue.PushBack(id)
+					}
+					break streamLoop
+				}
+				frames = append(frames, ackhandler.Frame{Frame: blocked})
+				maxLen -= l
+				controlFrameLen += l
+			}
+			if !keepPacking {
 				break
 			}
-			frames = append(frames, ackhandler.Frame{Frame: blocked})
-			maxLen -= l
-			controlFrameLen += l
 		}
 	}
 
@@ -137,7 +223,7 @@ func (f *framer) Append(
 			frames = append(frames, ackhandler.Frame{Frame: blocked})
 			controlFrameLen += l
 		} else {
-			f.controlFrames = append(f.controlFrames, blocked)
+			f.controlFrames = append(f.controlFrames, ackhandler.Frame{Frame: blocked})
 		}
 	}
 
@@ -154,6 +240,49 @@ func (f *framer) Append(
 	return frames, streamFrames, controlFrameLen + streamFrameLen
 }
 
+// appendRetransmissions packs the STREAM frames queued for retransmission, for all streams.
+// The order of the stream queue is preserved.
+// It reports whether there are retransmissions left that didn't fit into the packet.
+func (f *framer) appendRetransmissions(
+	streamFrames []ackhandler.StreamFrame,
+	maxLen protocol.ByteCount,
+	v protocol.Version,
+) (_ []ackhandler.StreamFrame, lastFrame ackhandler.StreamFrame, length protocol.ByteCount, retransmissionsLeft bool) {
+	numActiveStreams := f.streamQueue.Len()
+	for i := 0; i < numActiveStreams; i++ {
+		id := f.streamQueue.PopFront()
+		// The stream might have been removed after being enqueued.
+		str, ok := f.activeStreams[id]
+		if !ok {
+			continue
+		}
+		hasMoreData := true
+		for hasMoreData && str.hasRetransmission() {
+			if protocol.MinStreamFrameSize > maxLen {
+				retransmissionsLeft = true
+				break
+			}
+			var sf ackhandler.StreamFrame
+			sf, _, hasMoreData = f.getNextStreamFrame(str, maxLen, v)
+			if sf.Frame == nil {
+				// The retransmission didn't fit into the remaining space.
+				retransmissionsLeft = retransmissionsLeft || hasMoreData
+				break
+			}
+			streamFrames = append(streamFrames, sf)
+			maxLen -= sf.Frame.Length(v)
+			length += sf.Frame.Length(v)
+			lastFrame = sf
+		}
+		if hasMoreData {
+			f.streamQueue.PushBack(id)
+		} else {
+			delete(f.activeStreams, id)
+		}
+	}
+	return streamFrames, lastFrame, length, retransmissionsLeft
+}
+
 func (f *framer) appendControlFrames(
 	frames []ackhandler.Frame,
 	maxLen protocol.ByteCount,
@@ -197,13 +326,16 @@ func (f *framer) appendControlFrames(
 
 	for len(f.controlFrames) > 0 {
 		frame := f.controlFrames[len(f.controlFrames)-1]
-		frameLen := frame.Length(v)
+		frameLen := frame.Frame.Length(v)
 		if length+frameLen > maxLen {
 			break
 		}
-		frames = append(frames, ackhandler.Frame{Frame: frame})
+		frames = append(frames, frame)
 		length += frameLen
 		f.controlFrames = f.controlFrames[:len(f.controlFrames)-1]
+		if frame.Frame == f.queuedMaxData {
+			f.queuedMaxData = nil
+		}
 	}
 
 	return frames, length
@@ -245,29 +377,40 @@ func (f *framer) RemoveActiveStream(id protocol.StreamID) {
 	f.mutex.Unlock()
 }
 
-func (f *framer) getNextStreamFrame(maxLen protocol.ByteCount, v protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame) {
-	id := f.streamQueue.PopFront()
-	// This should never return an error. Better check it anyway.
-	// The stream will only be in the streamQueue, if it enqueued itself there.
-	str, ok := f.activeStreams[id]
-	// The stream might have been removed after being enqueued.
-	if !ok {
-		return ackhandler.StreamFrame{}, nil
+// fairConnectionWindowShare returns the number of bytes of the connection-level send window
+// that each active stream may use in a packet that has maxLen bytes left for STREAM frames.
+// If the connection-level flow control window is small, letting the streams consume it on a first-come,
+// first-served basis would allow a single stream to starve all other streams.
+// It returns 0 if there's no need to limit the streams, i.e. if the packet isn't limited by
+// connection-level flow control.
+func (f *framer) fairConnectionWindowShare(maxLen protocol.ByteCount) protocol.ByteCount {
+	n := protocol.ByteCount(len(f.activeStreams))
+	if n <= 1 {
+		return 0
+	}
+	window := f.connFlowController.SendWindowSize()
+	if window == 0 || window >= maxLen {
+		return 0
 	}
+	return (window + n - 1) / n
+}
+
+// minStreamFrameHeaderLen returns the minimum length of the header of a STREAM frame carrying dataLen bytes.
+// Since the offset is not taken into account, a frame of this size never carries more than dataLen bytes.
+func minStreamFrameHeaderLen(id protocol.StreamID, dataLen protocol.ByteCount) protocol.ByteCount {
+	// type byte, stream ID, data length
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(id))+quicvarint.Len(uint64(dataLen)))
+}
+
+func (f *framer) getNextStreamFrame(str streamFrameGetter, maxLen protocol.ByteCount, v protocol.Version) (_ ackhandler.StreamFrame, _ *wire.StreamDataBlockedFrame, hasMoreData bool) {
 	// For the last STREAM frame, we'll remove the DataLen field later.
 	// Therefore, we can pretend to have more bytes available when popping
 	// the STREAM frame (which will always have the DataLen set).
 	maxLen += protocol.ByteCount(quicvarint.Len(uint64(maxLen)))
-	frame, blocked, hasMoreData := str.popStreamFrame(maxLen, v)
-	if hasMoreData { // put the stream back in the queue (at the end)
-		f.streamQueue.PushBack(id)
-	} else { // no more data to send. Stream is not active
-		delete(f.activeStreams, id)
-	}
 	// Note that the frame.Frame can be nil:
 	// * if the stream was canceled after it said it had data
 	// * the remaining size doesn't allow us to add another STREAM frame
-	return frame, blocked
+	return str.popStreamFrame(maxLen, v)
 }
 
 func (f *framer) Handle0RTTRejection() {
@@ -282,7 +425,7 @@ func (f *framer) Handle0RTTRejection() {
 	}
 	var j int
 	for i, frame := range f.controlFrames {
-		switch frame.(type) {
+		switch frame.Frame.(type) {
 		case *wire.MaxDataFrame, *wire.MaxStreamDataFrame, *wire.MaxStreamsFrame,
 			*wire.DataBlockedFrame, *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
 			continue
@@ -292,4 +435,5 @@ func (f *framer) Handle0RTTRejection() {
 		}
 	}
 	f.controlFrames = slices.Delete(f.controlFrames, j, len(f.controlFrames))
+	f.queuedMaxData = nil
 }
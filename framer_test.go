@@ -3,7 +3,9 @@ package quic
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"math/rand/v2"
+	"slices"
 	"testing"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
@@ -20,7 +22,7 @@ func TestFramerControlFrames(t *testing.T) {
 	pc := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 6, 7, 8}}
 	msf := &wire.MaxStreamsFrame{MaxStreamNum: 0x1337}
 
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	require.False(t, framer.HasData())
 	framer.QueueControlFrame(pc)
 	require.True(t, framer.HasData())
@@ -41,12 +43,25 @@ func TestFramerControlFrames(t *testing.T) {
 	require.False(t, framer.HasData())
 }
 
+func TestFramerControlFramesWithHandler(t *testing.T) {
+	rcid := &wire.RetireConnectionIDFrame{SequenceNumber: 42}
+	handler := emptyHandler{}
+
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
+	framer.QueueControlFrameWithHandler(ackhandler.Frame{Frame: rcid, Handler: handler})
+	require.True(t, framer.HasData())
+	frames, _, length := framer.Append(nil, nil, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
+	require.Equal(t, []ackhandler.Frame{{Frame: rcid, Handler: handler}}, frames)
+	require.Equal(t, rcid.Length(protocol.Version1), length)
+	require.False(t, framer.HasData())
+}
+
 func TestFramerControlFrameSizing(t *testing.T) {
 	const maxSize = protocol.ByteCount(1000)
 	bf := &wire.DataBlockedFrame{MaximumData: 0x1337}
 	bfLen := bf.Length(protocol.Version1)
 
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	numFrames := int(maxSize / bfLen) // max number of frames that fit into maxSize
 	for i := 0; i < numFrames+1; i++ {
 		framer.QueueControlFrame(bf)
@@ -66,7 +81,7 @@ func TestFramerStreamControlFrames(t *testing.T) {
 	mdf1 := &wire.MaxStreamDataFrame{StreamID: streamID, MaximumStreamData: 1337}
 	mdf2 := &wire.MaxStreamDataFrame{StreamID: streamID, MaximumStreamData: 1338}
 
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	framer.QueueControlFrame(ping)
 	str := NewMockStreamControlFrameGetter(gomock.NewController(t))
 	framer.AddStreamWithControlFrames(streamID, str)
@@ -88,7 +103,7 @@ func TestFramerStreamControlFramesSizing(t *testing.T) {
 	mdf1 := &wire.MaxStreamDataFrame{MaximumStreamData: 1337}
 
 	str := NewMockStreamControlFrameGetter(gomock.NewController(t))
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	framer.AddStreamWithControlFrames(10, str)
 	str.EXPECT().getControlFrame(gomock.Any()).Return(ackhandler.Frame{Frame: mdf1}, true, true).AnyTimes()
 	frames, _, l := framer.Append(nil, nil, 100, monotime.Now(), protocol.Version1)
@@ -97,6 +112,62 @@ func TestFramerStreamControlFramesSizing(t *testing.T) {
 	require.LessOrEqual(t, l, protocol.ByteCount(100))
 }
 
+func TestFramerCoalesceMaxDataFrames(t *testing.T) {
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 1000})
+	framer.QueueControlFrame(&wire.PingFrame{})
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 3000})
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 2000})
+	frames, _, _ := framer.Append(nil, nil, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
+	require.Len(t, frames, 2)
+	require.Contains(t, frames, ackhandler.Frame{Frame: &wire.PingFrame{}})
+	require.Contains(t, frames, ackhandler.Frame{Frame: &wire.MaxDataFrame{MaximumData: 3000}})
+	require.False(t, framer.HasData())
+
+	// once the frame was sent, a new MAX_DATA frame is queued
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 4000})
+	frames, _, _ = framer.Append(nil, nil, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
+	require.Equal(t, []ackhandler.Frame{{Frame: &wire.MaxDataFrame{MaximumData: 4000}}}, frames)
+}
+
+func TestFramerFlowControlUpdatesForManyStreams(t *testing.T) {
+	const (
+		numStreams = 100
+		packetSize = 1200
+	)
+	mockCtrl := gomock.NewController(t)
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
+	for i := range numStreams {
+		id := protocol.StreamID(4 * i)
+		str := NewMockStreamControlFrameGetter(mockCtrl)
+		mdf := &wire.MaxStreamDataFrame{StreamID: id, MaximumStreamData: 1 << 20}
+		require.LessOrEqual(t, mdf.Length(protocol.Version1), protocol.ByteCount(8))
+		str.EXPECT().getControlFrame(gomock.Any()).Return(ackhandler.Frame{Frame: mdf}, true, false)
+		framer.AddStreamWithControlFrames(id, str)
+	}
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 1 << 24})
+	framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 1 << 25})
+
+	var numPackets, numMaxStreamData, numMaxData int
+	for framer.HasData() {
+		frames, _, length := framer.Append(nil, nil, packetSize, monotime.Now(), protocol.Version1)
+		require.NotEmpty(t, frames)
+		require.LessOrEqual(t, length, protocol.ByteCount(packetSize))
+		numPackets++
+		for _, f := range frames {
+			switch f.Frame.(type) {
+			case *wire.MaxStreamDataFrame:
+				numMaxStreamData++
+			case *wire.MaxDataFrame:
+				numMaxData++
+			}
+		}
+	}
+	require.Equal(t, numStreams, numMaxStreamData)
+	require.Equal(t, 1, numMaxData)
+	require.LessOrEqual(t, numPackets, 3)
+}
+
 func TestFramerStreamDataBlocked(t *testing.T) {
 	t.Run("small STREAM frame", func(t *testing.T) {
 		testFramerStreamDataBlocked(t, true)
@@ -115,7 +186,7 @@ func TestFramerStreamDataBlocked(t *testing.T) {
 func testFramerStreamDataBlocked(t *testing.T, fits bool) {
 	const streamID = 5
 	str := NewMockStreamFrameGetter(gomock.NewController(t))
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	framer.AddActiveStream(streamID, str)
 	str.EXPECT().popStreamFrame(gomock.Any(), gomock.Any()).DoAndReturn(
 		func(size protocol.ByteCount, v protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool) {
@@ -176,7 +247,7 @@ func testFramerDataBlocked(t *testing.T, fits bool) {
 	fc.AddBytesSent(offset)
 
 	str := NewMockStreamFrameGetter(gomock.NewController(t))
-	framer := newFramer(fc)
+	framer := newFramer(fc, 0, false)
 	framer.AddActiveStream(streamID, str)
 
 	str.EXPECT().popStreamFrame(gomock.Any(), gomock.Any()).DoAndReturn(
@@ -210,7 +281,7 @@ func testFramerDataBlocked(t *testing.T, fits bool) {
 }
 
 func TestFramerDetectsFrameDoS(t *testing.T) {
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	for i := 0; i < maxControlFrames-1; i++ {
 		framer.QueueControlFrame(&wire.PingFrame{})
 		framer.QueueControlFrame(&wire.PingFrame{})
@@ -228,7 +299,7 @@ func TestFramerDetectsFrameDoS(t *testing.T) {
 }
 
 func TestFramerDetectsFramePathResponseDoS(t *testing.T) {
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	var pathResponses []*wire.PathResponseFrame
 	for i := 0; i < 2*maxPathResponses; i++ {
 		var f wire.PathResponseFrame
@@ -250,7 +321,7 @@ func TestFramerDetectsFramePathResponseDoS(t *testing.T) {
 }
 
 func TestFramerPacksSinglePathResponsePerPacket(t *testing.T) {
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	f1 := &wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
 	f2 := &wire.PathResponseFrame{Data: [8]byte{2, 3, 4, 5, 6, 7, 8, 9}}
 	cf1 := &wire.DataBlockedFrame{MaximumData: 1337}
@@ -282,7 +353,7 @@ func TestFramerAppendStreamFrames(t *testing.T) {
 	f2 := &wire.StreamFrame{StreamID: str2ID, Data: []byte("bar"), DataLenPresent: true}
 	totalLen := f1.Length(protocol.Version1) + f2.Length(protocol.Version1)
 
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	require.False(t, framer.HasData())
 	// no frames added yet
 	controlFrames, fs, length := framer.Append(nil, nil, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
@@ -330,7 +401,7 @@ func TestFramerAppendStreamFrames(t *testing.T) {
 
 func TestFramerRemoveActiveStream(t *testing.T) {
 	const id = protocol.StreamID(42)
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	require.False(t, framer.HasData())
 	framer.AddActiveStream(id, NewMockStreamFrameGetter(gomock.NewController(t)))
 	require.True(t, framer.HasData())
@@ -343,7 +414,7 @@ func TestFramerRemoveActiveStream(t *testing.T) {
 
 func TestFramerMinStreamFrameSize(t *testing.T) {
 	const id = protocol.StreamID(42)
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	str := NewMockStreamFrameGetter(gomock.NewController(t))
 	framer.AddActiveStream(id, str)
 
@@ -368,7 +439,7 @@ func TestFramerMinStreamFrameSize(t *testing.T) {
 
 func TestFramerMinStreamFrameSizeMultipleStreamFrames(t *testing.T) {
 	const id = protocol.StreamID(42)
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	str := NewMockStreamFrameGetter(gomock.NewController(t))
 	framer.AddActiveStream(id, str)
 
@@ -389,7 +460,7 @@ func TestFramerMinStreamFrameSizeMultipleStreamFrames(t *testing.T) {
 func TestFramerFillPacketOneStream(t *testing.T) {
 	const id = protocol.StreamID(42)
 	str := NewMockStreamFrameGetter(gomock.NewController(t))
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 
 	for i := protocol.MinStreamFrameSize; i < 2000; i++ {
 		str.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).DoAndReturn(
@@ -420,7 +491,7 @@ func TestFramerFillPacketMultipleStreams(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	stream1 := NewMockStreamFrameGetter(mockCtrl)
 	stream2 := NewMockStreamFrameGetter(mockCtrl)
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 
 	for i := 2 * protocol.MinStreamFrameSize; i < 2000; i++ {
 		stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).DoAndReturn(
@@ -448,6 +519,197 @@ func TestFramerFillPacketMultipleStreams(t *testing.T) {
 	}
 }
 
+// chunkedStreamFrameGetter is a stream that has a number of small chunks of data to send,
+// and returns (at most) one chunk per call to popStreamFrame.
+type chunkedStreamFrameGetter struct {
+	id        protocol.StreamID
+	chunks    int // -1 means infinite
+	chunkSize int
+	offset    protocol.ByteCount
+}
+
+func (s *chunkedStreamFrameGetter) popStreamFrame(maxLen protocol.ByteCount, v protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool) {
+	if s.chunks == 0 {
+		return ackhandler.StreamFrame{}, nil, false
+	}
+	f := &wire.StreamFrame{StreamID: s.id, Offset: s.offset, DataLenPresent: true}
+	size := min(protocol.ByteCount(s.chunkSize), f.MaxDataLen(maxLen, v))
+	if size == 0 {
+		return ackhandler.StreamFrame{}, nil, true
+	}
+	f.Data = make([]byte, size)
+	s.offset += size
+	if s.chunks > 0 {
+		s.chunks--
+	}
+	return ackhandler.StreamFrame{Frame: f}, nil, s.chunks != 0
+}
+
+func (s *chunkedStreamFrameGetter) hasRetransmission() bool { return false }
+
+func packetStreamIDs(frames []ackhandler.StreamFrame) []protocol.StreamID {
+	var ids []protocol.StreamID
+	for _, f := range frames {
+		if !slices.Contains(ids, f.Frame.StreamID) {
+			ids = append(ids, f.Frame.StreamID)
+		}
+	}
+	return ids
+}
+
+func TestFramerMaxStreamsPerPacket(t *testing.T) {
+	const (
+		numStreams       = 40
+		chunksPerStream  = 5
+		maxPacketSize    = 1200
+		maxStreamsPerPkt = 4
+	)
+
+	addStreams := func(framer *framer) {
+		for i := range numStreams {
+			id := protocol.StreamID(4 * i)
+			framer.AddActiveStream(id, &chunkedStreamFrameGetter{id: id, chunks: chunksPerStream, chunkSize: 10})
+		}
+	}
+
+	t.Run("without a limit", func(t *testing.T) {
+		framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
+		addStreams(framer)
+		_, frames, _ := framer.Append(nil, nil, maxPacketSize, monotime.Now(), protocol.Version1)
+		// every stream gets one frame
+		require.Len(t, frames, numStreams)
+		require.Len(t, packetStreamIDs(frames), numStreams)
+	})
+
+	t.Run("with a limit", func(t *testing.T) {
+		framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), maxStreamsPerPkt, false)
+		addStreams(framer)
+		var servedStreams []protocol.StreamID
+		var numPackets int
+		for framer.HasData() {
+			_, frames, length := framer.Append(nil, nil, maxPacketSize, monotime.Now(), protocol.Version1)
+			require.LessOrEqual(t, length, protocol.ByteCount(maxPacketSize))
+			ids := packetStreamIDs(frames)
+			// all data of a stream is packed into the same packet
+			require.Len(t, ids, maxStreamsPerPkt)
+			require.Len(t, frames, maxStreamsPerPkt*chunksPerStream)
+			require.False(t, frames[len(frames)-1].Frame.DataLenPresent)
+			servedStreams = append(servedStreams, ids...)
+			numPackets++
+		}
+		require.Equal(t, numStreams/maxStreamsPerPkt, numPackets)
+		// streams are served in the order they became active
+		for i, id := range servedStreams {
+			require.Equal(t, protocol.StreamID(4*i), id)
+		}
+	})
+
+	t.Run("round-robin across packets", func(t *testing.T) {
+		framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 2, false)
+		for _, id := range []protocol.StreamID{0, 4, 8} {
+			framer.AddActiveStream(id, &chunkedStreamFrameGetter{id: id, chunks: -1, chunkSize: 100})
+		}
+		var packets [][]protocol.StreamID
+		for range 4 {
+			_, frames, length := framer.Append(nil, nil, maxPacketSize, monotime.Now(), protocol.Version1)
+			// the packet is filled up from a single stream
+			require.Greater(t, length, protocol.ByteCount(maxPacketSize-protocol.MinStreamFrameSize))
+			packets = append(packets, packetStreamIDs(frames))
+		}
+		require.Equal(t, [][]protocol.StreamID{{0}, {4}, {8}, {0}}, packets)
+	})
+}
+
+// connFlowControlledStream is a stream that always has data to send,
+// and that is only limited by connection-level flow control.
+type connFlowControlledStream struct {
+	id     protocol.StreamID
+	fc     flowcontrol.ConnectionFlowController
+	offset protocol.ByteCount
+}
+
+func (s *connFlowControlledStream) hasRetransmission() bool { return false }
+
+func (s *connFlowControlledStream) popStreamFrame(maxLen protocol.ByteCount, v protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool) {
+	f := &wire.StreamFrame{StreamID: s.id, Offset: s.offset, DataLenPresent: true}
+	size := min(f.MaxDataLen(maxLen, v), s.fc.SendWindowSize())
+	if size == 0 {
+		return ackhandler.StreamFrame{}, nil, true
+	}
+	f.Data = make([]byte, size)
+	s.offset += size
+	s.fc.AddBytesSent(size)
+	return ackhandler.StreamFrame{Frame: f}, nil, true
+}
+
+func TestFramerConnectionWindowFairness(t *testing.T) {
+	const (
+		maxPacketSize = 1200
+		windowUpdate  = 1000 // less than fits into a single packet
+	)
+
+	fc := flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil)
+	var window protocol.ByteCount
+	framer := newFramer(fc, 0, false)
+	str1 := &connFlowControlledStream{id: 0, fc: fc}
+	str2 := &connFlowControlledStream{id: 4, fc: fc}
+	framer.AddActiveStream(str1.id, str1)
+	framer.AddActiveStream(str2.id, str2)
+
+	sendPacket := func() []protocol.StreamID {
+		window += windowUpdate
+		fc.UpdateSendWindow(window)
+		_, frames, length := framer.Append(nil, nil, maxPacketSize, monotime.Now(), protocol.Version1)
+		require.LessOrEqual(t, length, protocol.ByteCount(maxPacketSize))
+		return packetStreamIDs(frames)
+	}
+
+	for range 100 {
+		sendPacket()
+	}
+	// the connection-level flow control window is (almost) fully used
+	require.InDelta(t, 100*windowUpdate, float64(str1.offset+str2.offset), 10)
+	// both streams get (roughly) half of the connection-level flow control window
+	require.InDelta(t, 50*windowUpdate, float64(str1.offset), windowUpdate)
+	require.InDelta(t, 50*windowUpdate, float64(str2.offset), windowUpdate)
+
+	// a newly added stream gets data in the next packet
+	str3 := &connFlowControlledStream{id: 8, fc: fc}
+	framer.AddActiveStream(str3.id, str3)
+	require.Contains(t, sendPacket(), str3.id)
+	offset1, offset2, offset3 := str1.offset, str2.offset, str3.offset
+	for range 99 {
+		sendPacket()
+	}
+	for _, d := range []protocol.ByteCount{str1.offset - offset1, str2.offset - offset2, str3.offset - offset3} {
+		require.InDelta(t, 100*windowUpdate/3, float64(d), windowUpdate)
+	}
+}
+
+func TestFramerConnectionWindowFairnessNotFlowControlLimited(t *testing.T) {
+	const maxPacketSize = 1200
+
+	fc := flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil)
+	framer := newFramer(fc, 0, false)
+	str1 := &connFlowControlledStream{id: 0, fc: fc}
+	str2 := &connFlowControlledStream{id: 4, fc: fc}
+	framer.AddActiveStream(str1.id, str1)
+	framer.AddActiveStream(str2.id, str2)
+
+	// If the packet is not limited by connection-level flow control,
+	// the streams are not limited to a share of the window,
+	// even if the window is smaller than what all active streams could send.
+	// Every packet is filled by a single stream, alternating between the streams.
+	for i := range 10 {
+		fc.UpdateSendWindow(str1.offset + str2.offset + maxPacketSize*3/2)
+		_, frames, length := framer.Append(nil, nil, maxPacketSize, monotime.Now(), protocol.Version1)
+		require.Equal(t, protocol.ByteCount(maxPacketSize), length)
+		require.Len(t, frames, 1)
+		require.Equal(t, []protocol.StreamID{protocol.StreamID(4 * (i % 2))}, packetStreamIDs(frames))
+	}
+	require.Equal(t, str1.offset, str2.offset)
+}
+
 func TestFramer0RTTRejection(t *testing.T) {
 	ncid := &wire.NewConnectionIDFrame{
 		SequenceNumber: 10,
@@ -456,7 +718,7 @@ func TestFramer0RTTRejection(t *testing.T) {
 	ping := &wire.PingFrame{}
 	pc := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 6, 7, 8}}
 
-	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil))
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, false)
 	framer.QueueControlFrame(ncid)
 	framer.QueueControlFrame(&wire.DataBlockedFrame{MaximumData: 1337})
 	framer.QueueControlFrame(&wire.StreamDataBlockedFrame{StreamID: 42, MaximumStreamData: 1337})
@@ -474,3 +736,70 @@ func TestFramer0RTTRejection(t *testing.T) {
 	require.Contains(t, controlFrames, ackhandler.Frame{Frame: ping})
 	require.Contains(t, controlFrames, ackhandler.Frame{Frame: ncid})
 }
+
+// lossyStreamFrameGetter is a chunkedStreamFrameGetter that has STREAM frames queued for retransmission.
+type lossyStreamFrameGetter struct {
+	chunkedStreamFrameGetter
+	retransmissions []*wire.StreamFrame
+}
+
+func (s *lossyStreamFrameGetter) hasRetransmission() bool { return len(s.retransmissions) > 0 }
+
+func (s *lossyStreamFrameGetter) popStreamFrame(maxLen protocol.ByteCount, v protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool) {
+	if len(s.retransmissions) > 0 {
+		f := s.retransmissions[0]
+		if f.Length(v) > maxLen {
+			return ackhandler.StreamFrame{}, nil, true
+		}
+		s.retransmissions = s.retransmissions[1:]
+		return ackhandler.StreamFrame{Frame: f}, nil, true
+	}
+	return s.chunkedStreamFrameGetter.popStreamFrame(maxLen, v)
+}
+
+func TestFramerPrioritizeRetransmissions(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		frames := testFramerPrioritizeRetransmissions(t, false)
+		// retransmissions are sent alongside new data, in a round-robin fashion
+		require.Equal(t, []string{"new 4", "retransmission 8"}, frames[0])
+		require.Equal(t, []string{"new 4", "retransmission 8"}, frames[1])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		frames := testFramerPrioritizeRetransmissions(t, true)
+		// no new data is sent until all retransmissions have been sent
+		require.Equal(t, []string{"retransmission 8", "retransmission 8"}, frames[0])
+		require.Equal(t, []string{"retransmission 8", "new 4", "new 8"}, frames[1])
+	})
+}
+
+func testFramerPrioritizeRetransmissions(t *testing.T, prioritize bool) [][]string {
+	framer := newFramer(flowcontrol.NewConnectionFlowController(0, 0, nil, nil, nil), 0, prioritize)
+	framer.AddActiveStream(4, &chunkedStreamFrameGetter{id: 4, chunks: -1, chunkSize: 400})
+	lossyStr := &lossyStreamFrameGetter{chunkedStreamFrameGetter: chunkedStreamFrameGetter{id: 8, chunks: -1, chunkSize: 400}}
+	for i := range 3 {
+		lossyStr.retransmissions = append(lossyStr.retransmissions, &wire.StreamFrame{
+			StreamID:       8,
+			Offset:         protocol.ByteCount(i * 400),
+			Data:           make([]byte, 400),
+			DataLenPresent: true,
+		})
+	}
+	lossyStr.offset = 3 * 400
+	framer.AddActiveStream(8, lossyStr)
+
+	var packets [][]string
+	for range 2 {
+		_, streamFrames, _ := framer.Append(nil, nil, 1000, monotime.Now(), protocol.Version1)
+		var frames []string
+		for _, f := range streamFrames {
+			desc := "new"
+			if f.Frame.StreamID == 8 && f.Frame.Offset < 3*400 {
+				desc = "retransmission"
+			}
+			frames = append(frames, fmt.Sprintf("%s %d", desc, f.Frame.StreamID))
+		}
+		packets = append(packets, frames)
+	}
+	return packets
+}
@@ -0,0 +1,115 @@
+// Package framing implements length-prefixed messages on top of QUIC streams.
+//
+// Every message is prefixed by its length, encoded as a QUIC variable-length integer (RFC 9000, section 16).
+package framing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// DefaultMaxMessageSize is the maximum size of a message read by ReadMessage,
+// if no other limit is configured.
+const DefaultMaxMessageSize = 1 << 20 // 1 MB
+
+// ErrMessageTooLarge is returned when the length prefix of a message exceeds the maximum message size.
+var ErrMessageTooLarge = errors.New("framing: message too large")
+
+// WriteMessage writes a length-prefixed message to w.
+// The length prefix and the message are written using a single call to Write.
+func WriteMessage(w io.Writer, msg []byte) error {
+	b := make([]byte, 0, quicvarint.Len(uint64(len(msg)))+len(msg))
+	_, err := w.Write(append(quicvarint.Append(b, uint64(len(msg))), msg...))
+	return err
+}
+
+// ReadMessage reads a length-prefixed message from r.
+// If r returns io.EOF before the first byte of the length prefix, ReadMessage returns io.EOF.
+// If r returns io.EOF in the middle of a message, ReadMessage returns io.ErrUnexpectedEOF.
+// Any other error returned by r (e.g. a quic.StreamError if the stream was reset) is returned unchanged,
+// and the partially read message is discarded.
+// If the message is larger than maxSize, ErrMessageTooLarge is returned.
+func ReadMessage(r io.Reader, maxSize uint64) ([]byte, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return nil, err
+	}
+	l := 1 << (b[0] >> 6) // 1, 2, 4, or 8 bytes
+	if _, err := io.ReadFull(r, b[1:l]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	size, _, err := quicvarint.Parse(b[:l])
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return msg, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// A Stream sends and receives length-prefixed messages on a bidirectional QUIC stream.
+type Stream struct {
+	str *quic.Stream
+
+	// MaxMessageSize is the maximum size of a message read by ReadMessage.
+	// If zero, DefaultMaxMessageSize is used.
+	MaxMessageSize uint64
+}
+
+// NewStream wraps a QUIC stream.
+func NewStream(str *quic.Stream) *Stream {
+	return &Stream{str: str}
+}
+
+// OpenStream opens a new bidirectional stream, and writes msg as the first message.
+// It blocks until the stream can be opened, see quic.Conn.OpenStreamSync.
+func OpenStream(ctx context.Context, conn *quic.Conn, msg []byte) (*Stream, error) {
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := NewStream(str)
+	if err := s.WriteMessage(msg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Stream returns the underlying QUIC stream.
+func (s *Stream) Stream() *quic.Stream { return s.str }
+
+// WriteMessage writes a length-prefixed message.
+func (s *Stream) WriteMessage(msg []byte) error {
+	return WriteMessage(s.str, msg)
+}
+
+// ReadMessage reads the next length-prefixed message.
+// It returns io.EOF if the peer closed the stream after the last message.
+func (s *Stream) ReadMessage() ([]byte, error) {
+	maxSize := s.MaxMessageSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	return ReadMessage(s.str, maxSize)
+}
+
+// Close closes the send direction of the stream.
+func (s *Stream) Close() error {
+	return s.str.Close()
+}
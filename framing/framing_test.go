@@ -0,0 +1,135 @@
+package framing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWriteMessages(t *testing.T) {
+	large := make([]byte, 100_000)
+	rand.Read(large)
+	messages := [][]byte{[]byte("foo"), {}, large, []byte("bar")}
+
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		require.NoError(t, WriteMessage(&buf, msg))
+	}
+	// read one byte at a time, to make sure partial reads are handled
+	r := iotest.OneByteReader(&buf)
+	for _, msg := range messages {
+		m, err := ReadMessage(r, DefaultMaxMessageSize)
+		require.NoError(t, err)
+		require.Equal(t, msg, m)
+	}
+	_, err := ReadMessage(r, DefaultMaxMessageSize)
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadTruncatedMessage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, make([]byte, 1000)))
+	data := buf.Bytes()
+
+	// truncated length prefix
+	_, err := ReadMessage(bytes.NewReader(data[:1]), DefaultMaxMessageSize)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	// truncated message
+	_, err = ReadMessage(bytes.NewReader(data[:len(data)-1]), DefaultMaxMessageSize)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	_, err := ReadMessage(bytes.NewReader(quicvarint.Append(nil, 1001)), 1000)
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+}
+
+func newConnPair(t *testing.T) (client, server *quic.Conn) {
+	t.Helper()
+
+	udpConn := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{"framing"}
+	ln, err := quic.Listen(udpConn(), tlsConf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	client, err = quic.Dial(
+		ctx,
+		udpConn(),
+		ln.Addr(),
+		&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"framing"}},
+		nil,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.CloseWithError(0, "") })
+
+	server, err = ln.Accept(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.CloseWithError(0, "") })
+	return client, server
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	client, server := newConnPair(t)
+
+	large := make([]byte, 100_000)
+	rand.Read(large)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	str, err := OpenStream(ctx, client, []byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, str.WriteMessage(large))
+
+	serverStr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	s := NewStream(serverStr)
+	msg, err := s.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), msg)
+	msg, err = s.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, large, msg)
+
+	// echo a message back
+	require.NoError(t, s.WriteMessage([]byte("world")))
+	require.NoError(t, s.Close())
+	msg, err = str.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("world"), msg)
+	_, err = str.ReadMessage()
+	require.ErrorIs(t, err, io.EOF)
+
+	// Reset the stream in the middle of a message.
+	// Write the length prefix and the first half of the message, then reset the stream.
+	_, err = str.Stream().Write(append(quicvarint.Append(nil, 1000), make([]byte, 500)...))
+	require.NoError(t, err)
+	str.Stream().CancelWrite(42)
+
+	_, err = s.ReadMessage()
+	var streamErr *quic.StreamError
+	require.ErrorAs(t, err, &streamErr)
+	require.Equal(t, quic.StreamErrorCode(42), streamErr.ErrorCode)
+	require.True(t, streamErr.Remote)
+}
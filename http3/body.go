@@ -19,6 +19,17 @@ type Settingser interface {
 	Settings() *Settings
 }
 
+// WaitForSettings waits until the peer's SETTINGS frame was received, and returns the settings.
+// It returns an error if the context is canceled before that.
+func WaitForSettings(ctx context.Context, s Settingser) (*Settings, error) {
+	select {
+	case <-s.ReceivedSettings():
+		return s.Settings(), nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
 var errTooMuchData = errors.New("peer sent too much data")
 
 // The body is used in the requestBody (for a http.Request) and the responseBody (for a http.Response).
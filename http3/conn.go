@@ -222,6 +222,9 @@ func (c *rawConn) handleControlStream(str *quic.ReceiveStream) {
 	c.settings = &Settings{
 		EnableDatagrams:       sf.Datagram,
 		EnableExtendedConnect: sf.ExtendedConnect,
+		MaxFieldSectionSize:   sf.MaxFieldSectionSize,
+		QPACKMaxTableCapacity: sf.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   sf.QPACKBlockedStreams,
 		Other:                 sf.Other,
 	}
 	close(c.receivedSettings)
@@ -52,6 +52,7 @@ func TestConnReceiveSettings(t *testing.T) {
 	settings := conn.Settings()
 	require.True(t, settings.EnableDatagrams)
 	require.True(t, settings.EnableExtendedConnect)
+	require.EqualValues(t, 1234, settings.MaxFieldSectionSize)
 	require.Equal(t, map[uint64]uint64{1337: 42}, settings.Other)
 
 	expectedLen, expectedPayloadLen := expectedFrameLength(t, sf)
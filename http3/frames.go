@@ -158,8 +158,12 @@ func (f *headersFrame) Append(b []byte) []byte {
 }
 
 const (
+	// SETTINGS_QPACK_MAX_TABLE_CAPACITY, RFC 9204
+	settingQPACKMaxTableCapacity = 0x1
 	// SETTINGS_MAX_FIELD_SECTION_SIZE
 	settingMaxFieldSectionSize = 0x6
+	// SETTINGS_QPACK_BLOCKED_STREAMS, RFC 9204
+	settingQPACKBlockedStreams = 0x7
 	// Extended CONNECT, RFC 9220
 	settingExtendedConnect = 0x8
 	// HTTP Datagrams, RFC 9297
@@ -169,6 +173,9 @@ const (
 type settingsFrame struct {
 	MaxFieldSectionSize int64 // SETTINGS_MAX_FIELD_SECTION_SIZE, -1 if not set
 
+	QPACKMaxTableCapacity uint64 // SETTINGS_QPACK_MAX_TABLE_CAPACITY, RFC 9204
+	QPACKBlockedStreams   uint64 // SETTINGS_QPACK_BLOCKED_STREAMS, RFC 9204
+
 	Datagram        bool              // HTTP Datagrams, RFC 9297
 	ExtendedConnect bool              // Extended CONNECT, RFC 9220
 	Other           map[uint64]uint64 // all settings that we don't explicitly recognize
@@ -193,6 +200,7 @@ func parseSettingsFrame(r *countingByteReader, l uint64, streamID quic.StreamID,
 	b := bytes.NewReader(buf)
 	settingsFrame := qlog.SettingsFrame{MaxFieldSectionSize: -1}
 	var readMaxFieldSectionSize, readDatagram, readExtendedConnect bool
+	var readQPACKMaxTableCapacity, readQPACKBlockedStreams bool
 	for b.Len() > 0 {
 		id, err := quicvarint.Read(b)
 		if err != nil { // should not happen. We allocated the whole frame already.
@@ -211,6 +219,18 @@ func parseSettingsFrame(r *countingByteReader, l uint64, streamID quic.StreamID,
 			readMaxFieldSectionSize = true
 			frame.MaxFieldSectionSize = int64(val)
 			settingsFrame.MaxFieldSectionSize = int64(val)
+		case settingQPACKMaxTableCapacity:
+			if readQPACKMaxTableCapacity {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readQPACKMaxTableCapacity = true
+			frame.QPACKMaxTableCapacity = val
+		case settingQPACKBlockedStreams:
+			if readQPACKBlockedStreams {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readQPACKBlockedStreams = true
+			frame.QPACKBlockedStreams = val
 		case settingExtendedConnect:
 			if readExtendedConnect {
 				return nil, fmt.Errorf("duplicate setting: %d", id)
@@ -247,6 +267,19 @@ func parseSettingsFrame(r *countingByteReader, l uint64, streamID quic.StreamID,
 	}
 	if qlogger != nil {
 		settingsFrame.Other = maps.Clone(frame.Other)
+		// qlog doesn't define dedicated fields for the QPACK settings
+		if readQPACKMaxTableCapacity {
+			if settingsFrame.Other == nil {
+				settingsFrame.Other = make(map[uint64]uint64)
+			}
+			settingsFrame.Other[settingQPACKMaxTableCapacity] = frame.QPACKMaxTableCapacity
+		}
+		if readQPACKBlockedStreams {
+			if settingsFrame.Other == nil {
+				settingsFrame.Other = make(map[uint64]uint64)
+			}
+			settingsFrame.Other[settingQPACKBlockedStreams] = frame.QPACKBlockedStreams
+		}
 
 		qlogger.RecordEvent(qlog.FrameParsed{
 			StreamID: streamID,
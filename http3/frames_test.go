@@ -253,6 +253,16 @@ func TestParserSettingsFrameDuplicateSettings(t *testing.T) {
 			num:  settingDatagram,
 			val:  1,
 		},
+		{
+			name: "QPACK max table capacity",
+			num:  settingQPACKMaxTableCapacity,
+			val:  4096,
+		},
+		{
+			name: "QPACK blocked streams",
+			num:  settingQPACKBlockedStreams,
+			val:  100,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			settings := appendSetting(nil, tc.num, tc.val)
@@ -304,6 +314,39 @@ func testParserSettingsFrameMaxFieldSectionSize(t *testing.T, present bool) {
 	require.Equal(t, sf, f2)
 }
 
+func TestParserSettingsFrameQPACK(t *testing.T) {
+	settings := appendSetting(nil, settingQPACKMaxTableCapacity, 4096)
+	settings = appendSetting(settings, settingQPACKBlockedStreams, 100)
+	settings = appendSetting(settings, 13, 37)
+	data := quicvarint.Append(nil, 4) // type byte
+	data = quicvarint.Append(data, uint64(len(settings)))
+	data = append(data, settings...)
+
+	var eventRecorder events.Recorder
+	fp := frameParser{r: bytes.NewReader(data)}
+	f, err := fp.ParseNext(&eventRecorder)
+	require.NoError(t, err)
+	require.IsType(t, &settingsFrame{}, f)
+	sf := f.(*settingsFrame)
+	require.EqualValues(t, 4096, sf.QPACKMaxTableCapacity)
+	require.EqualValues(t, 100, sf.QPACKBlockedStreams)
+	require.Equal(t, map[uint64]uint64{13: 37}, sf.Other)
+
+	// qlog doesn't define fields for the QPACK settings
+	require.Len(t, eventRecorder.Events(qlog.FrameParsed{}), 1)
+	require.Equal(t,
+		qlog.Frame{Frame: qlog.SettingsFrame{
+			MaxFieldSectionSize: -1,
+			Other: map[uint64]uint64{
+				settingQPACKMaxTableCapacity: 4096,
+				settingQPACKBlockedStreams:   100,
+				13:                           37,
+			},
+		}},
+		eventRecorder.Events(qlog.FrameParsed{})[0].(qlog.FrameParsed).Frame,
+	)
+}
+
 func TestParserSettingsFrameDatagram(t *testing.T) {
 	t.Run("enabled", func(t *testing.T) {
 		testParserSettingsFrameDatagram(t, true)
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/discovery"
 	"github.com/quic-go/quic-go/http3/qlog"
 	"github.com/quic-go/quic-go/qlogwriter"
 )
@@ -86,6 +87,12 @@ var ServerContextKey = &contextKey{"http3-server"}
 // than its string representation.
 var RemoteAddrContextKey = &contextKey{"remote-addr"}
 
+// SettingsContextKey is a context key. It can be used in HTTP
+// handlers with Context.Value to access the HTTP/3 settings
+// sent by the client. The associated value will be of type
+// Settingser.
+var SettingsContextKey = &contextKey{"settings"}
+
 // listener contains info about specific listener added with addListener
 type listener struct {
 	ln   *QUICListener
@@ -142,7 +149,8 @@ type Server struct {
 	MaxHeaderBytes int
 
 	// AdditionalSettings specifies additional HTTP/3 settings.
-	// It is invalid to specify any settings defined by RFC 9114 (HTTP/3) and RFC 9297 (HTTP Datagrams).
+	// It is invalid to specify any settings defined by RFC 9114 (HTTP/3), RFC 9204 (QPACK),
+	// RFC 9220 (Extended CONNECT) and RFC 9297 (HTTP Datagrams).
 	AdditionalSettings map[uint64]uint64
 
 	// IdleTimeout specifies how long until idle clients connection should be
@@ -157,7 +165,19 @@ type Server struct {
 
 	Logger *slog.Logger
 
-	mutex     sync.RWMutex
+	// Advertiser optionally advertises the server, for example using a DNS SRV record
+	// (see discovery.DNSSRVAdvertiser).
+	// The server is advertised when it starts serving on a listener,
+	// and the advertisement is withdrawn when the server is closed.
+	Advertiser discovery.Advertiser
+
+	mutex sync.RWMutex
+
+	// advertiseMutex serializes calls to the Advertiser.
+	// It is not held together with the mutex, since advertising might block on network I/O.
+	advertiseMutex sync.Mutex
+	advertising    bool // protected by advertiseMutex
+
 	listeners []listener
 
 	closed           bool
@@ -269,6 +289,7 @@ func (s *Server) ServeListener(ln QUICListener) error {
 }
 
 func (s *Server) serveListener(ln QUICListener) error {
+	s.startAdvertising()
 	for {
 		conn, err := ln.Accept(s.graceCtx)
 		// server closed
@@ -290,6 +311,44 @@ func (s *Server) serveListener(ln QUICListener) error {
 	}
 }
 
+func (s *Server) startAdvertising() {
+	s.advertiseMutex.Lock()
+	defer s.advertiseMutex.Unlock()
+
+	if s.Advertiser == nil || s.advertising {
+		return
+	}
+	// If the server is closed after this check, closing the server withdraws the advertisement,
+	// since it has to wait for the advertiseMutex.
+	s.mutex.RLock()
+	closed := s.closed
+	s.mutex.RUnlock()
+	if closed {
+		return
+	}
+	if err := s.Advertiser.Advertise(); err != nil {
+		logger := s.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("Advertising the server failed", "error", err)
+		return
+	}
+	s.advertising = true
+}
+
+// withdrawAdvertisement must be called without holding the mutex.
+func (s *Server) withdrawAdvertisement() error {
+	s.advertiseMutex.Lock()
+	defer s.advertiseMutex.Unlock()
+
+	if !s.advertising {
+		return nil
+	}
+	s.advertising = false
+	return s.Advertiser.Withdraw()
+}
+
 var errServerWithoutTLSConfig = errors.New("use of http3.Server without TLSConfig")
 
 func (s *Server) setupListenerForConn(tlsConf *tls.Config, conn net.PacketConn) (*QUICListener, error) {
@@ -426,6 +485,9 @@ func (s *Server) NewRawServerConn(conn *quic.Conn) (*RawServerConn, error) {
 }
 
 func (s *Server) newRawServerConn(conn *quic.Conn) (*RawServerConn, *quic.SendStream, qlogwriter.Recorder, error) {
+	if err := validateAdditionalSettings(s.AdditionalSettings); err != nil {
+		return nil, nil, nil, err
+	}
 	var qlogger qlogwriter.Recorder
 	if qlogTrace := conn.QlogTrace(); qlogTrace != nil && qlogTrace.SupportsSchemas(qlog.EventSchema) {
 		qlogger = qlogTrace.AddProducer()
@@ -570,16 +632,19 @@ func (s *Server) maxHeaderBytes() int {
 // It is the caller's responsibility to close any connection passed to ServeQUICConn.
 func (s *Server) Close() error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
 	s.closed = true
 	// server is never used
 	if s.closeCtx == nil {
+		s.mutex.Unlock()
 		return nil
 	}
 	s.closeCancel()
+	s.mutex.Unlock()
 
-	var err error
+	err := s.withdrawAdvertisement()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	for _, l := range s.listeners {
 		if l.createdLocally {
 			if cerr := (*l.ln).Close(); cerr != nil && err == nil {
@@ -608,9 +673,15 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return nil
 	}
 	s.graceCancel()
+	s.mutex.Unlock()
 
-	// close all listeners
 	var closeErrs []error
+	if err := s.withdrawAdvertisement(); err != nil {
+		closeErrs = append(closeErrs, err)
+	}
+
+	s.mutex.Lock()
+	// close all listeners
 	for _, l := range s.listeners {
 		if l.createdLocally {
 			if err := (*l.ln).Close(); err != nil {
@@ -53,6 +53,7 @@ func newRawServerConn(
 		logger:         logger,
 	}
 	c.rawConn = *newRawConn(conn, enableDatagrams, c.onStreamsEmpty, nil, qlogger, logger)
+	c.serverContext = context.WithValue(serverContext, SettingsContextKey, Settingser(&c.rawConn))
 	if idleTimeout > 0 {
 		c.idleTimer = time.AfterFunc(idleTimeout, c.onIdleTimer)
 	}
@@ -192,6 +193,12 @@ func (c *RawServerConn) handleRequestStream(str *stateTrackingStream) {
 	ctx, cancel := context.WithCancel(connCtx)
 	req = req.WithContext(ctx)
 	context.AfterFunc(str.Context(), cancel)
+	// If the connection context is canceled while the handler is running, reset the stream.
+	// Otherwise, a handler that doesn't check the request context might block forever.
+	stopResetOnCancel := context.AfterFunc(connCtx, func() {
+		str.CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+		str.CancelWrite(quic.StreamErrorCode(ErrCodeRequestCanceled))
+	})
 
 	r := newResponseWriter(hstr, conn, req.Method == http.MethodHead, c.logger)
 	handler := c.requestHandler
@@ -221,6 +228,10 @@ func (c *RawServerConn) handleRequestStream(str *stateTrackingStream) {
 		}()
 		handler.ServeHTTP(r, req)
 	}()
+	// Once the handler returned, the stream is closed (or handed over to the application, if hijacked).
+	if !stopResetOnCancel() {
+		return
+	}
 
 	if r.wasStreamHijacked() {
 		return
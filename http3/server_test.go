@@ -111,6 +111,16 @@ func testServerSettings(t *testing.T, enableDatagrams bool, other map[uint64]uin
 	require.Equal(t, settingsFrame.Other, other)
 }
 
+func TestServerInvalidAdditionalSettings(t *testing.T) {
+	for _, id := range []uint64{0x2, settingQPACKMaxTableCapacity, settingMaxFieldSectionSize, settingDatagram} {
+		s := Server{AdditionalSettings: map[uint64]uint64{13: 37, id: 1}}
+		s.init()
+		_, serverConn := newConnPair(t)
+		_, err := s.NewRawServerConn(serverConn)
+		require.ErrorContains(t, err, fmt.Sprintf("setting %#x", id))
+	}
+}
+
 func TestServerRequestHandling(t *testing.T) {
 	t.Run("200 with an empty handler", func(t *testing.T) {
 		var eventRecorder events.Recorder
@@ -448,6 +458,7 @@ func TestServerRequestContext(t *testing.T) {
 	require.Equal(t, s, requestContext.Value(ServerContextKey))
 	require.Equal(t, serverConn.LocalAddr(), requestContext.Value(http.LocalAddrContextKey))
 	require.Equal(t, serverConn.RemoteAddr(), requestContext.Value(RemoteAddrContextKey))
+	require.Implements(t, (*Settingser)(nil), requestContext.Value(SettingsContextKey))
 	select {
 	case <-requestContext.Done():
 		t.Fatal("request context was canceled")
@@ -465,6 +476,69 @@ func TestServerRequestContext(t *testing.T) {
 	close(block)
 }
 
+func TestServerRequestContextCancellation(t *testing.T) {
+	clientConn, serverConn := newConnPair(t)
+	str, err := clientConn.OpenStream()
+	require.NoError(t, err)
+	// Send the request, but don't close the stream.
+	// The handler blocks reading the request body.
+	_, err = str.Write(encodeRequest(t, httptest.NewRequest(http.MethodPost, "https://www.example.com", nil)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctxChan := make(chan context.Context, 1)
+	handlerErrChan := make(chan error, 1)
+	s := &Server{
+		ConnContext: func(connCtx context.Context, _ *quic.Conn) context.Context {
+			connCtx, connCancel := context.WithCancel(connCtx)
+			context.AfterFunc(ctx, connCancel)
+			return connCtx
+		},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxChan <- r.Context()
+			_, err := io.Copy(w, r.Body)
+			handlerErrChan <- err
+		}),
+	}
+	hconn, err := s.NewRawServerConn(serverConn)
+	require.NoError(t, err)
+	serverStr, err := serverConn.AcceptStream(context.Background())
+	require.NoError(t, err)
+	handlingDone := make(chan struct{})
+	go func() {
+		defer close(handlingDone)
+		hconn.HandleRequestStream(serverStr)
+	}()
+
+	var requestContext context.Context
+	select {
+	case requestContext = <-ctxChan:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	cancel()
+	select {
+	case <-requestContext.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request context wasn't canceled")
+	}
+	select {
+	case err := <-handlerErrChan:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handler didn't return")
+	}
+	expectStreamReadReset(t, str, quic.StreamErrorCode(ErrCodeRequestCanceled))
+	expectStreamWriteReset(t, str, quic.StreamErrorCode(ErrCodeRequestCanceled))
+	select {
+	case <-handlingDone:
+	case <-time.After(time.Second):
+		t.Fatal("request stream handling didn't return")
+	}
+}
+
 func TestServerHTTPStreamHijacking(t *testing.T) {
 	clientConn, serverConn := newConnPair(t)
 	str, err := clientConn.OpenStream()
@@ -696,6 +770,111 @@ func TestServerConcurrentServeAndClose(t *testing.T) {
 	}
 }
 
+type mockAdvertiser struct {
+	advertised chan struct{}
+	withdrawn  chan struct{}
+}
+
+func (a *mockAdvertiser) Advertise() error { close(a.advertised); return nil }
+func (a *mockAdvertiser) Withdraw() error  { close(a.withdrawn); return nil }
+
+func TestServerAdvertising(t *testing.T) {
+	t.Run("Close", func(t *testing.T) {
+		testServerAdvertising(t, func(s *Server) error { return s.Close() })
+	})
+	t.Run("Shutdown", func(t *testing.T) {
+		testServerAdvertising(t, func(s *Server) error { return s.Shutdown(context.Background()) })
+	})
+}
+
+func testServerAdvertising(t *testing.T, closeFn func(*Server) error) {
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	defer c.Close()
+
+	advertiser := &mockAdvertiser{advertised: make(chan struct{}), withdrawn: make(chan struct{})}
+	s := &Server{TLSConfig: testdata.GetTLSConfig(), Advertiser: advertiser}
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.Serve(c) }()
+
+	select {
+	case <-advertiser.advertised:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case <-advertiser.withdrawn:
+		t.Fatal("advertisement withdrawn too early")
+	default:
+	}
+
+	require.NoError(t, closeFn(s))
+	select {
+	case <-advertiser.withdrawn:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	// closing again doesn't withdraw the advertisement again
+	require.NoError(t, s.Close())
+}
+
+type funcAdvertiser struct {
+	advertise, withdraw func() error
+}
+
+func (a *funcAdvertiser) Advertise() error { return a.advertise() }
+func (a *funcAdvertiser) Withdraw() error  { return a.withdraw() }
+
+func TestServerAdvertisingWithoutHoldingMutex(t *testing.T) {
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	defer c.Close()
+
+	s := &Server{TLSConfig: testdata.GetTLSConfig()}
+	// the mutex must not be held while (potentially slow) calls to the Advertiser are made
+	mutexHeld := func() bool {
+		if !s.mutex.TryLock() {
+			return true
+		}
+		s.mutex.Unlock()
+		return false
+	}
+	advertised := make(chan bool, 1)
+	withdrawn := make(chan bool, 1)
+	s.Advertiser = &funcAdvertiser{
+		advertise: func() error { advertised <- mutexHeld(); return nil },
+		withdraw:  func() error { withdrawn <- mutexHeld(); return nil },
+	}
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.Serve(c) }()
+
+	select {
+	case held := <-advertised:
+		require.False(t, held)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	require.NoError(t, s.Close())
+	select {
+	case held := <-withdrawn:
+		require.False(t, held)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
 func TestServerImmediateGracefulShutdown(t *testing.T) {
 	s := &Server{TLSConfig: testdata.GetTLSConfig()}
 	errChan := make(chan error, 1)
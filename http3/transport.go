@@ -26,10 +26,30 @@ type Settings struct {
 	EnableDatagrams bool
 	// Extended CONNECT, RFC 9220
 	EnableExtendedConnect bool
-	// Other settings, defined by the application
+	// SETTINGS_MAX_FIELD_SECTION_SIZE, -1 if the peer didn't limit the size of the field section
+	MaxFieldSectionSize int64
+	// SETTINGS_QPACK_MAX_TABLE_CAPACITY (RFC 9204), 0 if the peer didn't send this setting
+	QPACKMaxTableCapacity uint64
+	// SETTINGS_QPACK_BLOCKED_STREAMS (RFC 9204), 0 if the peer didn't send this setting
+	QPACKBlockedStreams uint64
+	// Other settings, including all settings that are not recognized by this package
 	Other map[uint64]uint64
 }
 
+// validateAdditionalSettings checks that the application doesn't use any settings
+// that are reserved by RFC 9114, or that are controlled by this package.
+func validateAdditionalSettings(settings map[uint64]uint64) error {
+	for id := range settings {
+		switch id {
+		case 0x0, 0x2, 0x3, 0x4, 0x5:
+			return fmt.Errorf("http3: setting %#x is reserved", id)
+		case settingQPACKMaxTableCapacity, settingMaxFieldSectionSize, settingQPACKBlockedStreams, settingExtendedConnect, settingDatagram:
+			return fmt.Errorf("http3: setting %#x can't be used as an additional setting", id)
+		}
+	}
+	return nil
+}
+
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
 type RoundTripOpt struct {
 	// OnlyCachedConn controls whether the Transport may create a new QUIC connection.
@@ -83,7 +103,8 @@ type Transport struct {
 	EnableDatagrams bool
 
 	// Additional HTTP/3 settings.
-	// It is invalid to specify any settings defined by RFC 9114 (HTTP/3) and RFC 9297 (HTTP Datagrams).
+	// It is invalid to specify any settings defined by RFC 9114 (HTTP/3), RFC 9204 (QPACK),
+	// RFC 9220 (Extended CONNECT) and RFC 9297 (HTTP Datagrams).
 	AdditionalSettings map[uint64]uint64
 
 	// MaxResponseHeaderBytes specifies a limit on how many response bytes are
@@ -125,6 +146,9 @@ var (
 )
 
 func (t *Transport) init() error {
+	if err := validateAdditionalSettings(t.AdditionalSettings); err != nil {
+		return err
+	}
 	if t.newClientConn == nil {
 		t.newClientConn = func(conn *quic.Conn) clientConn {
 			return newClientConn(
@@ -290,6 +314,31 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.RoundTripOpt(req, RoundTripOpt{})
 }
 
+// PeerSettings returns the HTTP/3 settings that the server sent on the cached connection
+// for the given authority (host or host:port), waiting until the SETTINGS frame was received.
+// It returns ErrNoCachedConn if there's no connection to this authority.
+func (t *Transport) PeerSettings(ctx context.Context, authority string) (*Settings, error) {
+	t.mutex.Lock()
+	cl, ok := t.clients[authorityAddr(authority)]
+	t.mutex.Unlock()
+	if !ok {
+		return nil, ErrNoCachedConn
+	}
+	select {
+	case <-cl.dialing:
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+	if cl.dialErr != nil {
+		return nil, cl.dialErr
+	}
+	s, ok := cl.clientConn.(Settingser)
+	if !ok {
+		return nil, errors.New("http3: connection doesn't expose HTTP/3 settings")
+	}
+	return WaitForSettings(ctx, s)
+}
+
 func (t *Transport) getClient(ctx context.Context, hostname string, onlyCached bool) (rtc *roundTripperWithCount, isReused bool, err error) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -205,6 +206,66 @@ func TestTransportDatagrams(t *testing.T) {
 	})
 }
 
+func TestTransportInvalidAdditionalSettings(t *testing.T) {
+	tr := &Transport{
+		AdditionalSettings: map[uint64]uint64{settingExtendedConnect: 1},
+		Dial: func(context.Context, string, *tls.Config, *quic.Config) (*quic.Conn, error) {
+			t.Fatal("dial should not be called")
+			return nil, nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+	_, err := tr.RoundTrip(req)
+	require.EqualError(t, err, "http3: setting 0x8 can't be used as an additional setting")
+}
+
+func TestTransportPeerSettings(t *testing.T) {
+	clientConn, serverConn := newConnPair(t)
+	tr := &Transport{
+		Dial: func(context.Context, string, *tls.Config, *quic.Config) (*quic.Conn, error) {
+			return clientConn, nil
+		},
+	}
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// no connection to this server yet
+	_, err := tr.PeerSettings(ctx, "quic-go.net")
+	require.ErrorIs(t, err, ErrNoCachedConn)
+
+	// the server never sends a response
+	reqCtx, reqCancel := context.WithTimeout(ctx, scaleDuration(10*time.Millisecond))
+	defer reqCancel()
+	req := httptest.NewRequestWithContext(reqCtx, http.MethodGet, "https://quic-go.net/", nil)
+	_, err = tr.RoundTrip(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the server hasn't sent its SETTINGS yet
+	shortCtx, shortCancel := context.WithTimeout(ctx, scaleDuration(10*time.Millisecond))
+	defer shortCancel()
+	_, err = tr.PeerSettings(shortCtx, "quic-go.net:443")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	controlStr, err := serverConn.OpenUniStream()
+	require.NoError(t, err)
+	b := quicvarint.Append(nil, streamTypeControlStream)
+	b = (&settingsFrame{
+		MaxFieldSectionSize: 1000,
+		ExtendedConnect:     true,
+		Other:               map[uint64]uint64{1337: 42},
+	}).Append(b)
+	_, err = controlStr.Write(b)
+	require.NoError(t, err)
+
+	settings, err := tr.PeerSettings(ctx, "quic-go.net")
+	require.NoError(t, err)
+	require.True(t, settings.EnableExtendedConnect)
+	require.False(t, settings.EnableDatagrams)
+	require.EqualValues(t, 1000, settings.MaxFieldSectionSize)
+	require.Equal(t, map[uint64]uint64{1337: 42}, settings.Other)
+}
+
 func TestTransportMultipleQUICVersions(t *testing.T) {
 	qconf := &quic.Config{
 		Versions: []quic.Version{quic.Version2, quic.Version1},
@@ -83,15 +83,97 @@ func BenchmarkStreamChurn(b *testing.B) {
 	}
 }
 
+func BenchmarkSmallRequests(b *testing.B) {
+	b.Run("Write and Close", func(b *testing.B) { benchmarkSmallRequests(b, false) })
+	b.Run("WriteAndClose", func(b *testing.B) { benchmarkSmallRequests(b, true) })
+}
+
+func benchmarkSmallRequests(b *testing.B, useWriteAndClose bool) {
+	const numRequests = 1000
+	request := []byte("GET /index.html")
+
+	ln, err := quic.Listen(newUDPConnLocalhost(b), tlsConfig, &quic.Config{MaxIncomingStreams: 1e10})
+	require.NoError(b, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(b), ln.Addr(), tlsClientConfig, nil)
+	require.NoError(b, err)
+	defer conn.CloseWithError(0, "")
+
+	serverConn, err := ln.Accept(context.Background())
+	require.NoError(b, err)
+	defer serverConn.CloseWithError(0, "")
+
+	received := make(chan struct{}, numRequests)
+	go func() {
+		for {
+			str, err := serverConn.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				if _, err := io.ReadAll(str); err != nil {
+					return
+				}
+				str.Close()
+				received <- struct{}{}
+			}()
+		}
+	}()
+
+	packetsBefore := conn.ConnectionStats().PacketsSent
+	for b.Loop() {
+		for range numRequests {
+			str, err := conn.OpenStreamSync(context.Background())
+			if err != nil {
+				b.Fatalf("error opening stream: %v", err)
+			}
+			if useWriteAndClose {
+				if _, err := str.WriteAndClose(request); err != nil {
+					b.Fatalf("error writing request: %v", err)
+				}
+			} else {
+				if _, err := str.Write(request); err != nil {
+					b.Fatalf("error writing request: %v", err)
+				}
+				if err := str.Close(); err != nil {
+					b.Fatalf("error closing stream: %v", err)
+				}
+			}
+		}
+		for range numRequests {
+			<-received
+		}
+	}
+	packets := conn.ConnectionStats().PacketsSent - packetsBefore
+	b.ReportMetric(float64(packets)/float64(b.N), "packets/op")
+}
+
 func BenchmarkTransfer(b *testing.B) {
-	b.Run(fmt.Sprintf("%d kb", len(PRData)/1024), func(b *testing.B) { benchmarkTransfer(b, PRData) })
-	b.Run(fmt.Sprintf("%d kb", len(PRDataLong)/1024), func(b *testing.B) { benchmarkTransfer(b, PRDataLong) })
+	b.Run(fmt.Sprintf("%d kb", len(PRData)/1024), func(b *testing.B) { benchmarkTransfer(b, PRData, nil) })
+	b.Run(fmt.Sprintf("%d kb", len(PRDataLong)/1024), func(b *testing.B) { benchmarkTransfer(b, PRDataLong, nil) })
+	// measures the overhead of the ReceivedAckHook on the sender
+	b.Run(fmt.Sprintf("%d kb, ACK hook", len(PRDataLong)/1024), func(b *testing.B) {
+		benchmarkTransferWithServerConfig(b, PRDataLong, nil, &quic.Config{ReceivedAckHook: func(*quic.ReceivedAck) {}})
+	})
+	b.Run(fmt.Sprintf("%d kb, ACK hook, copy", len(PRDataLong)/1024), func(b *testing.B) {
+		benchmarkTransferWithServerConfig(b, PRDataLong, nil, &quic.Config{
+			ReceivedAckHook:     func(*quic.ReceivedAck) {},
+			ReceivedAckHookCopy: true,
+		})
+	})
+}
+
+func benchmarkTransfer(b *testing.B, data []byte, clientConf *quic.Config) {
+	benchmarkTransferWithServerConfig(b, data, clientConf, nil)
 }
 
-func benchmarkTransfer(b *testing.B, data []byte) {
+func benchmarkTransferWithServerConfig(b *testing.B, data []byte, clientConf, serverConf *quic.Config) {
 	b.ReportAllocs()
 
-	ln, err := quic.Listen(newUDPConnLocalhost(b), tlsConfig, nil)
+	ln, err := quic.Listen(newUDPConnLocalhost(b), tlsConfig, serverConf)
 	require.NoError(b, err)
 	defer ln.Close()
 
@@ -125,7 +207,7 @@ func benchmarkTransfer(b *testing.B, data []byte) {
 	buf := make([]byte, len(data))
 
 	for b.Loop() {
-		c, err := tr.Dial(context.Background(), ln.Addr(), tlsClientConfig, nil)
+		c, err := tr.Dial(context.Background(), ln.Addr(), tlsClientConfig, clientConf)
 		if err != nil {
 			b.Fatalf("error dialing: %v", err)
 		}
@@ -149,3 +231,61 @@ func benchmarkTransfer(b *testing.B, data []byte) {
 		c.CloseWithError(0, "")
 	}
 }
+
+// BenchmarkThroughput measures the throughput of a single connection.
+// In contrast to BenchmarkTransfer, the handshake is not part of the measurement.
+func BenchmarkThroughput(b *testing.B) {
+	b.Run("serial decryption", func(b *testing.B) { benchmarkThroughput(b, nil) })
+	b.Run("parallel decryption", func(b *testing.B) {
+		benchmarkThroughput(b, &quic.Config{EnableParallelDecryption: true})
+	})
+}
+
+func benchmarkThroughput(b *testing.B, clientConf *quic.Config) {
+	const size = 16 << 20
+	data := make([]byte, 64<<10)
+
+	ln, err := quic.Listen(newUDPConnLocalhost(b), tlsConfig, &quic.Config{MaxIncomingUniStreams: 1e10})
+	require.NoError(b, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(b), ln.Addr(), tlsClientConfig, clientConf)
+	require.NoError(b, err)
+	defer conn.CloseWithError(0, "")
+
+	serverConn, err := ln.Accept(context.Background())
+	require.NoError(b, err)
+	defer serverConn.CloseWithError(0, "")
+
+	go func() {
+		for {
+			str, err := serverConn.OpenUniStreamSync(context.Background())
+			if err != nil {
+				return
+			}
+			for written := 0; written < size; written += len(data) {
+				if _, err := str.Write(data); err != nil {
+					return
+				}
+			}
+			str.Close()
+		}
+	}()
+
+	b.SetBytes(size)
+	for b.Loop() {
+		str, err := conn.AcceptUniStream(context.Background())
+		if err != nil {
+			b.Fatalf("error accepting stream: %v", err)
+		}
+		n, err := io.Copy(io.Discard, str)
+		if err != nil {
+			b.Fatalf("error reading data: %v", err)
+		}
+		if n != size {
+			b.Fatalf("read %d bytes, expected %d", n, size)
+		}
+	}
+}
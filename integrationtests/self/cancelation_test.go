@@ -596,3 +596,112 @@ func TestHeavyStreamCancellation(t *testing.T) {
 		t.Fatal("timeout waiting for server to stop")
 	}
 }
+
+// Both endpoints cancel the stream at the same time: the receiver sends a STOP_SENDING frame,
+// while the sender sends a RESET_STREAM frame.
+// The stream needs to end up in a terminal state, and the flow control credit consumed by the
+// canceled streams needs to be returned to the sender.
+func TestStopSendingResetStreamRace(t *testing.T) {
+	const (
+		numStreams     = 20
+		dataLen        = 5000
+		connFCWindow   = 4 * dataLen
+		maxIncomingUni = 2
+	)
+
+	server, err := quic.Listen(
+		newUDPConnLocalhost(t),
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{
+			InitialConnectionReceiveWindow: connFCWindow,
+			MaxConnectionReceiveWindow:     connFCWindow,
+			MaxIncomingUniStreams:          maxIncomingUni,
+		}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	serverConn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	// In total, this sends more data than the connection-level flow control window.
+	// This only works if the flow control credit of the canceled streams is returned.
+	for i := range numStreams {
+		str, err := conn.OpenUniStreamSync(ctx)
+		require.NoError(t, err)
+		writeErrChan := make(chan error, 1)
+		go func() {
+			_, err := str.Write(PRData[:dataLen])
+			writeErrChan <- err
+		}()
+
+		rstr, err := serverConn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		// make sure that some data was received before canceling the stream
+		_, err = io.ReadFull(rstr, make([]byte, 100))
+		require.NoError(t, err, "stream %d", i)
+
+		errorCode := quic.StreamErrorCode(i)
+		var ready, done sync.WaitGroup
+		ready.Add(2)
+		done.Add(2)
+		barrier := make(chan struct{})
+		go func() {
+			defer done.Done()
+			ready.Done()
+			<-barrier
+			rstr.CancelRead(errorCode)
+		}()
+		go func() {
+			defer done.Done()
+			ready.Done()
+			<-barrier
+			str.CancelWrite(errorCode)
+		}()
+		ready.Wait()
+		close(barrier)
+		done.Wait()
+
+		select {
+		case err := <-writeErrChan:
+			if err != nil {
+				var streamErr *quic.StreamError
+				require.ErrorAs(t, err, &streamErr)
+				require.Equal(t, errorCode, streamErr.ErrorCode)
+			}
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for Write to return")
+		}
+		var streamErr *quic.StreamError
+		_, err = rstr.Read([]byte{0})
+		require.ErrorAs(t, err, &streamErr)
+		require.Equal(t, errorCode, streamErr.ErrorCode)
+		_, err = str.Write([]byte{0})
+		require.ErrorAs(t, err, &streamErr)
+		require.Equal(t, errorCode, streamErr.ErrorCode)
+
+		select {
+		case <-str.Context().Done():
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for the send stream to be closed")
+		}
+	}
+
+	// Make sure that the connection is still usable, and that there's enough flow control credit.
+	str, err := conn.OpenUniStreamSync(ctx)
+	require.NoError(t, err)
+	_, err = str.Write(PRData[:connFCWindow])
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	rstr, err := serverConn.AcceptUniStream(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rstr)
+	require.NoError(t, err)
+	require.Equal(t, PRData[:connFCWindow], data)
+}
@@ -3,7 +3,9 @@ package self_test
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"net"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -228,3 +230,225 @@ func testTransportClose(t *testing.T, conn net.PacketConn, closeFn func(), expec
 		require.ErrorIs(t, err, expectedErr)
 	}
 }
+
+func TestUnreadStreamDataOnClose(t *testing.T) {
+	t.Run("discarding buffered data", func(t *testing.T) {
+		testUnreadStreamDataOnClose(t, false)
+	})
+	t.Run("keeping receive buffers", func(t *testing.T) {
+		testUnreadStreamDataOnClose(t, true)
+	})
+}
+
+func testUnreadStreamDataOnClose(t *testing.T, keepReceiveBuffers bool) {
+	server, err := quic.Listen(
+		newUDPConnLocalhost(t),
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{KeepReceiveBuffersOnClose: keepReceiveBuffers}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	data := GeneratePRData(1000)
+	str, err := conn.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write(data)
+	require.NoError(t, err)
+
+	sconn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	sstr, err := sconn.AcceptStream(ctx)
+	require.NoError(t, err)
+	b := make([]byte, 100)
+	_, err = io.ReadFull(sstr, b)
+	require.NoError(t, err)
+	// wait until all the data has been received
+	_, err = sstr.Peek(make([]byte, 900))
+	require.NoError(t, err)
+	require.Nil(t, sconn.UnreadStreamData())
+
+	conn.CloseWithError(42, "bye")
+	select {
+	case <-sconn.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the connection to be closed")
+	}
+	require.Equal(t,
+		[]quic.UnreadStreamData{{StreamID: str.StreamID(), UnreadBytes: 900, ContiguousOffset: 1000}},
+		sconn.UnreadStreamData(),
+	)
+
+	rest, err := io.ReadAll(sstr)
+	var appErr *quic.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, quic.ApplicationErrorCode(42), appErr.ErrorCode)
+	if keepReceiveBuffers {
+		require.Equal(t, data[100:], rest)
+	} else {
+		require.Empty(t, rest)
+	}
+}
+
+func TestTransportCloseGracefully(t *testing.T) {
+	tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	addTracer(tr)
+	ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	sconn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+
+	// start a transfer
+	str, err := sconn.OpenUniStream()
+	require.NoError(t, err)
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := str.Write(PRDataLong)
+		if err == nil {
+			err = str.Close()
+		}
+		writeErr <- err
+	}()
+	rstr, err := conn.AcceptUniStream(ctx)
+	require.NoError(t, err)
+	_, err = io.ReadFull(rstr, make([]byte, 1000))
+	require.NoError(t, err)
+
+	closed := make(chan error, 1)
+	go func() { closed <- tr.CloseGracefully(ctx) }()
+
+	// the listener is closed
+	_, err = ln.Accept(ctx)
+	require.ErrorIs(t, err, quic.ErrServerClosed)
+
+	// the transfer can be completed...
+	data, err := io.ReadAll(rstr)
+	require.NoError(t, err)
+	require.Equal(t, PRDataLong[1000:], data)
+	require.NoError(t, <-writeErr)
+
+	// ... and the transport is only closed once the connection is closed
+	select {
+	case <-closed:
+		t.Fatal("transport closed before the connection was closed")
+	case <-time.After(scaleDuration(10 * time.Millisecond)):
+	}
+	require.NoError(t, conn.CloseWithError(0, ""))
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// it's not possible to dial new connections
+	_, err = tr.Dial(ctx, conn.RemoteAddr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.ErrorIs(t, err, quic.ErrTransportClosed)
+}
+
+func TestTransportCloseGracefullyTimeout(t *testing.T) {
+	tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	addTracer(tr)
+	ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	sconn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+	defer closeCancel()
+	require.ErrorIs(t, tr.CloseGracefully(closeCtx), context.DeadlineExceeded)
+	// the remaining connection was terminated
+	select {
+	case <-sconn.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestListenerCloseWithDelay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			numConns = 100
+			delay    = time.Second
+		)
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverPacketConn}
+		defer tr.Close()
+		ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientTr := &quic.Transport{Conn: clientPacketConn}
+		defer clientTr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		serverConns := make([]*quic.Conn, 0, numConns)
+		for range numConns {
+			conn, err := clientTr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+			require.NoError(t, err)
+			defer conn.CloseWithError(0, "")
+			sconn, err := ln.Accept(ctx)
+			require.NoError(t, err)
+			serverConns = append(serverConns, sconn)
+		}
+
+		var mx sync.Mutex
+		var closeTimes []time.Duration
+		start := time.Now()
+		for _, sconn := range serverConns {
+			go func() {
+				<-sconn.Context().Done()
+				mx.Lock()
+				closeTimes = append(closeTimes, time.Since(start))
+				mx.Unlock()
+			}()
+		}
+
+		require.NoError(t, ln.CloseWithDelay(delay, "shutting down"))
+		require.Less(t, time.Since(start), delay)
+		synctest.Wait()
+
+		mx.Lock()
+		defer mx.Unlock()
+		require.Len(t, closeTimes, numConns)
+		slices.Sort(closeTimes)
+		require.Less(t, closeTimes[len(closeTimes)-1], delay)
+		for i, t1 := range closeTimes {
+			var count int
+			for _, t2 := range closeTimes[i:] {
+				if t2-t1 >= 100*time.Millisecond {
+					break
+				}
+				count++
+			}
+			require.LessOrEqualf(t, count, 20, "%d connections closed within 100ms after %s", count, t1)
+		}
+
+		for _, sconn := range serverConns {
+			require.ErrorIs(t,
+				context.Cause(sconn.Context()),
+				&quic.ApplicationError{ErrorCode: 0, ErrorMessage: "shutting down"},
+			)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCongestionControlSwitchMidTransfer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientConn, serverConn, closeFn := newSimnetLink(t, 20*time.Millisecond)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientConn, serverConn.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+		require.Equal(t, quic.NewReno, sconn.ConnectionStats().CongestionControl)
+
+		errChan := make(chan error, 1)
+		go func() {
+			str, err := sconn.OpenStream()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := str.Write(PRData[:len(PRData)/2]); err != nil {
+				errChan <- err
+				return
+			}
+			if err := sconn.SetCongestionControl(quic.CUBIC); err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := str.Write(PRData[len(PRData)/2:]); err != nil {
+				errChan <- err
+				return
+			}
+			errChan <- str.Close()
+		}()
+
+		str, err := conn.AcceptStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		require.NoError(t, <-errChan)
+
+		stats := sconn.ConnectionStats()
+		require.Equal(t, quic.CUBIC, stats.CongestionControl)
+		require.Equal(t, "CUBIC", stats.CongestionControl.String())
+		// the client's congestion controller is not affected
+		require.Equal(t, quic.NewReno, conn.ConnectionStats().CongestionControl)
+	})
+}
+
+func TestCongestionControlSwitchThroughput(t *testing.T) {
+	// Switching the congestion controller mid-transfer carries over the congestion controller state.
+	// The transfer therefore takes (almost) as long as without switching.
+	withoutSwitch := measureTransferDuration(t, false)
+	withSwitch := measureTransferDuration(t, true)
+	t.Logf("transfer took %s without switching, %s with switching", withoutSwitch, withSwitch)
+	require.LessOrEqual(t, withSwitch, withoutSwitch*11/10)
+}
+
+func measureTransferDuration(t *testing.T, switchCongestionControl bool) time.Duration {
+	var duration time.Duration
+	synctest.Test(t, func(t *testing.T) {
+		clientConn, serverConn, closeFn := newSimnetLink(t, 50*time.Millisecond)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientConn, serverConn.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+
+		start := time.Now()
+		errChan := make(chan error, 1)
+		go func() {
+			str, err := sconn.OpenStream()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := str.Write(PRData[:len(PRData)/2]); err != nil {
+				errChan <- err
+				return
+			}
+			if switchCongestionControl {
+				if err := sconn.SetCongestionControl(quic.CUBIC); err != nil {
+					errChan <- err
+					return
+				}
+			}
+			if _, err := str.Write(PRData[len(PRData)/2:]); err != nil {
+				errChan <- err
+				return
+			}
+			errChan <- str.Close()
+		}()
+
+		str, err := conn.AcceptStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		require.NoError(t, <-errChan)
+		duration = time.Since(start)
+		if switchCongestionControl {
+			require.Equal(t, quic.CUBIC, sconn.ConnectionStats().CongestionControl)
+		}
+	})
+	return duration
+}
+
+func TestConnectionStateCongestionLimited(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientConn, serverConn, closeFn := newSimnetLink(t, 50*time.Millisecond)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientConn, serverConn.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+		require.False(t, sconn.ConnectionState().CongestionLimited)
+
+		errChan := make(chan error, 1)
+		var wasCongestionLimited bool
+		go func() {
+			str, err := sconn.OpenStream()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			// The link doesn't limit the bandwidth, so a saturating writer
+			// is limited by the congestion window.
+			for data := PRData; len(data) > 0; {
+				n := min(len(data), 1<<10)
+				if _, err := str.Write(data[:n]); err != nil {
+					errChan <- err
+					return
+				}
+				data = data[n:]
+				if sconn.ConnectionState().CongestionLimited {
+					wasCongestionLimited = true
+				}
+			}
+			errChan <- str.Close()
+		}()
+
+		str, err := conn.AcceptStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		require.NoError(t, <-errChan)
+		require.True(t, wasCongestionLimited)
+
+		// once all data has been acknowledged, the connection is application limited
+		time.Sleep(time.Second)
+		require.False(t, sconn.ConnectionState().CongestionLimited)
+		// the client only sent acknowledgements
+		require.False(t, conn.ConnectionState().CongestionLimited)
+	})
+}
@@ -9,10 +9,14 @@ import (
 	"net"
 	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/quic-go/quic-go"
 	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/testutils/events"
+	"github.com/quic-go/quic-go/testutils/simnet"
 
 	"github.com/stretchr/testify/require"
 )
@@ -142,3 +146,392 @@ func TestConnectionMigration(t *testing.T) {
 	require.Less(t, int(packetsPath2.Load()-c2BeforeSwitch), 20)
 	require.Equal(t, tr1.Conn.LocalAddr(), conn.LocalAddr())
 }
+
+func TestPathProbeMaxRetransmissions(t *testing.T) {
+	ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer ln.Close()
+
+	tr1 := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	defer tr1.Close()
+	tr2 := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	defer tr2.Close()
+
+	// the path from tr2 is unresponsive
+	var droppedPath2 atomic.Int64
+	proxy := quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: ln.Addr().(*net.UDPAddr),
+		DropPacket: func(dir quicproxy.Direction, from, _ net.Addr, _ []byte) bool {
+			if dir == quicproxy.DirectionIncoming && from.(*net.UDPAddr).Port == tr2.Conn.LocalAddr().(*net.UDPAddr).Port {
+				droppedPath2.Add(1)
+				return true
+			}
+			return false
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const maxRetransmissions = 2
+	var eventRecorder events.Recorder
+	conn, err := tr1.Dial(
+		ctx,
+		proxy.LocalAddr(),
+		getTLSClientConfig(),
+		getQuicConfig(&quic.Config{
+			MaxPathChallengeRetransmissions: maxRetransmissions,
+			Tracer:                          newTracer(&eventRecorder),
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	sconn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	defer sconn.CloseWithError(0, "")
+
+	path, err := conn.AddPath(tr2)
+	require.NoError(t, err)
+	require.ErrorIs(t, path.Probe(ctx), quic.ErrPathValidationFailed)
+	require.NoError(t, ctx.Err())
+	require.ErrorIs(t, path.Switch(), quic.ErrPathNotValidated)
+	require.NoError(t, path.Close())
+
+	require.Equal(t, int64(1+maxRetransmissions), droppedPath2.Load())
+	evs := eventRecorder.Events(qlog.PathChallengeRetransmitted{})
+	require.Len(t, evs, maxRetransmissions)
+	for i, ev := range evs {
+		ev := ev.(qlog.PathChallengeRetransmitted)
+		require.Equal(t, i+1, ev.Count)
+		require.Equal(t, tr2.Conn.LocalAddr().(*net.UDPAddr).AddrPort(), ev.Local.IPv4)
+	}
+
+	// the connection continues to work on the original path
+	str, err := conn.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	sstr, err := sconn.AcceptStream(ctx)
+	require.NoError(t, err)
+	b := make([]byte, 6)
+	_, err = io.ReadFull(sstr, b)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), b)
+}
+
+type peerMigration struct {
+	oldAddr, newAddr net.Addr
+}
+
+func TestPeerMigrationVerification(t *testing.T) {
+	t.Run("accept", func(t *testing.T) {
+		migrations := make(chan peerMigration, 1)
+		clientConn, serverConn := testPeerMigration(t, &quic.Config{
+			VerifyPeerMigration: func(_ *quic.Conn, oldAddr, newAddr net.Addr) (quic.PeerMigrationAction, error) {
+				migrations <- peerMigration{oldAddr: oldAddr, newAddr: newAddr}
+				return quic.PeerMigrationAccept, nil
+			},
+		})
+		select {
+		case m := <-migrations:
+			require.NotEqual(t, m.oldAddr, m.newAddr)
+			require.Equal(t, m.newAddr, serverConn.RemoteAddr())
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		require.False(t, serverConn.RequiresRevalidation())
+		require.NoError(t, clientConn.Context().Err())
+	})
+
+	t.Run("revalidate", func(t *testing.T) {
+		migrated := make(chan struct{})
+		clientConn, serverConn := testPeerMigration(t, &quic.Config{
+			VerifyPeerMigration: func(*quic.Conn, net.Addr, net.Addr) (quic.PeerMigrationAction, error) {
+				close(migrated)
+				return quic.PeerMigrationRevalidate, nil
+			},
+		})
+		select {
+		case <-migrated:
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		require.Eventually(t, serverConn.RequiresRevalidation, time.Second, 5*time.Millisecond)
+		serverConn.MarkRevalidated()
+		require.False(t, serverConn.RequiresRevalidation())
+		require.NoError(t, clientConn.Context().Err())
+	})
+
+	t.Run("close", func(t *testing.T) {
+		clientConn, _ := testPeerMigration(t, &quic.Config{
+			VerifyPeerMigration: func(*quic.Conn, net.Addr, net.Addr) (quic.PeerMigrationAction, error) {
+				return quic.PeerMigrationAccept, &quic.ApplicationError{ErrorCode: 42, ErrorMessage: "address changed"}
+			},
+		})
+		select {
+		case <-clientConn.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		var appErr *quic.ApplicationError
+		require.ErrorAs(t, context.Cause(clientConn.Context()), &appErr)
+		require.True(t, appErr.Remote)
+		require.Equal(t, quic.ApplicationErrorCode(42), appErr.ErrorCode)
+		require.Equal(t, "address changed", appErr.ErrorMessage)
+	})
+
+	t.Run("migration disabled", func(t *testing.T) {
+		clientConn, serverConn := testPeerMigration(t, &quic.Config{DisablePeerMigration: true})
+		remoteAddr := serverConn.RemoteAddr()
+		// packets sent from the new address are dropped
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+		defer cancel()
+		_, err := serverConn.AcceptUniStream(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, remoteAddr, serverConn.RemoteAddr())
+		require.NoError(t, serverConn.Context().Err())
+		require.NoError(t, clientConn.Context().Err())
+	})
+}
+
+// testPeerMigration establishes a connection, and then changes the client's address
+// (as observed by the server) by simulating a NAT rebinding.
+func testPeerMigration(t *testing.T, serverConf *quic.Config) (clientConn, serverConn *quic.Conn) {
+	t.Helper()
+
+	server, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(serverConf))
+	require.NoError(t, err)
+	defer server.Close()
+
+	proxy := quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: server.Addr().(*net.UDPAddr),
+	}
+	require.NoError(t, proxy.Start())
+	t.Cleanup(func() { proxy.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	clientUDPConn := newUDPConnLocalhost(t)
+	clientConn, err = quic.Dial(ctx, clientUDPConn, proxy.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	t.Cleanup(func() { clientConn.CloseWithError(0, "") })
+	serverConn, err = server.Accept(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, proxy.SwitchConn(clientUDPConn.LocalAddr().(*net.UDPAddr), newUDPConnLocalhost(t)))
+
+	// send some data from the new address, so the server validates the new path
+	str, err := clientConn.OpenUniStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	return clientConn, serverConn
+}
+
+func TestPreferredAddressMigration(t *testing.T) {
+	t.Run("migration", func(t *testing.T) {
+		testPreferredAddressMigration(t, false)
+	})
+	t.Run("preferred address unreachable", func(t *testing.T) {
+		testPreferredAddressMigration(t, true)
+	})
+}
+
+func testPreferredAddressMigration(t *testing.T, unreachable bool) {
+	serverUDPConn := newUDPConnLocalhost(t)
+	tr := &quic.Transport{Conn: serverUDPConn}
+	defer tr.Close()
+
+	// Both proxies forward packets to the server.
+	// The client connects to the first proxy, and the server advertises the second one as its preferred address.
+	var packetsOriginal, packetsPreferred atomic.Int64
+	proxyOriginal := quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: serverUDPConn.LocalAddr().(*net.UDPAddr),
+		DelayPacket: func(quicproxy.Direction, net.Addr, net.Addr, []byte) time.Duration {
+			packetsOriginal.Add(1)
+			return 0
+		},
+	}
+	require.NoError(t, proxyOriginal.Start())
+	defer proxyOriginal.Close()
+	proxyPreferred := quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: serverUDPConn.LocalAddr().(*net.UDPAddr),
+		DropPacket: func(quicproxy.Direction, net.Addr, net.Addr, []byte) bool { return unreachable },
+		DelayPacket: func(quicproxy.Direction, net.Addr, net.Addr, []byte) time.Duration {
+			packetsPreferred.Add(1)
+			return 0
+		},
+	}
+	require.NoError(t, proxyPreferred.Start())
+	defer proxyPreferred.Close()
+
+	ln, err := tr.Listen(
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{PreferredAddress: proxyPreferred.LocalAddr().(*net.UDPAddr)}),
+	)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), proxyOriginal.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	sconn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	defer sconn.CloseWithError(0, "")
+
+	select {
+	case <-conn.HandshakeConfirmed():
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for handshake confirmation")
+	}
+
+	if unreachable {
+		// path validation times out after 3 PTOs
+		time.Sleep(scaleDuration(200 * time.Millisecond))
+		require.Equal(t, proxyOriginal.LocalAddr(), conn.RemoteAddr())
+	} else {
+		require.Eventually(t,
+			func() bool { return conn.RemoteAddr().String() == proxyPreferred.LocalAddr().String() },
+			scaleDuration(time.Second),
+			scaleDuration(5*time.Millisecond),
+		)
+	}
+
+	str, err := conn.OpenUniStream()
+	require.NoError(t, err)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(errChan)
+		sstr, err := sconn.AcceptUniStream(ctx)
+		if err != nil {
+			errChan <- fmt.Errorf("accepting stream: %w", err)
+			return
+		}
+		data, err := io.ReadAll(sstr)
+		if err != nil {
+			errChan <- fmt.Errorf("reading stream data: %w", err)
+			return
+		}
+		if !bytes.Equal(data, PRData) {
+			errChan <- errors.New("unexpected data")
+		}
+	}()
+
+	time.Sleep(scaleDuration(10 * time.Millisecond)) // wait for ACKs on the old path
+	numOriginal := packetsOriginal.Load()
+	numPreferred := packetsPreferred.Load()
+	_, err = str.Write(PRData)
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for data")
+	}
+
+	if unreachable {
+		require.Greater(t, packetsOriginal.Load(), numOriginal)
+		require.Equal(t, numPreferred, packetsPreferred.Load())
+		return
+	}
+	// Packets in both directions are sent via the preferred address:
+	// The server sees the packets arriving from a new address, and migrates the connection as well.
+	require.Equal(t, numOriginal, packetsOriginal.Load())
+	require.Greater(t, packetsPreferred.Load(), numPreferred)
+}
+
+func TestPathStats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// Latency is applied to packets sent to an endpoint.
+		// The RTT of the first path is 10ms, the RTT of the second path is 40ms.
+		n := &simnet.Simnet{Router: &simnet.PerfectRouter{}}
+		serverAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002}
+		serverPacketConn := n.NewEndpoint(serverAddr, simnet.NodeBiDiLinkSettings{Latency: 5 * time.Millisecond})
+		clientPacketConn1 := n.NewEndpoint(
+			&net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001},
+			simnet.NodeBiDiLinkSettings{Latency: 5 * time.Millisecond},
+		)
+		clientPacketConn2 := n.NewEndpoint(
+			&net.UDPAddr{IP: net.ParseIP("1.0.0.3"), Port: 9003},
+			simnet.NodeBiDiLinkSettings{Latency: 35 * time.Millisecond},
+		)
+		require.NoError(t, n.Start())
+		defer n.Close()
+		defer serverPacketConn.Close()
+		defer clientPacketConn1.Close()
+		defer clientPacketConn2.Close()
+
+		server, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer server.Close()
+
+		tr1 := &quic.Transport{Conn: clientPacketConn1}
+		defer tr1.Close()
+		tr2 := &quic.Transport{Conn: clientPacketConn2}
+		defer tr2.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := tr1.Dial(ctx, serverAddr, getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		serverConn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+		<-conn.HandshakeConfirmed()
+
+		path, err := conn.AddPath(tr2)
+		require.NoError(t, err)
+		require.NoError(t, path.Probe(ctx))
+		time.Sleep(time.Second) // wait for all ACKs
+
+		paths := conn.Paths()
+		require.Len(t, paths, 2)
+		require.True(t, paths[0].Active)
+		require.True(t, paths[0].Validated)
+		require.Equal(t, clientPacketConn1.LocalAddr(), paths[0].LocalAddr)
+		require.Equal(t, 10*time.Millisecond, paths[0].MinRTT)
+		require.NotZero(t, conn.ConnectionStats().CongestionWindow)
+		require.Zero(t, conn.ConnectionStats().BytesInFlight)
+
+		require.False(t, paths[1].Active)
+		require.True(t, paths[1].Validated)
+		require.Equal(t, clientPacketConn2.LocalAddr(), paths[1].LocalAddr)
+		require.Equal(t, serverAddr.String(), paths[1].RemoteAddr.String())
+		require.Equal(t, 40*time.Millisecond, paths[1].MinRTT)
+		require.Equal(t, 40*time.Millisecond, paths[1].SmoothedRTT)
+
+		// after switching, the second path is the active path
+		require.NoError(t, path.Switch())
+		str, err := conn.OpenUniStream()
+		require.NoError(t, err)
+		_, err = str.Write(PRData)
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		sstr, err := serverConn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(sstr)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		time.Sleep(time.Second) // wait for all ACKs
+
+		paths = conn.Paths()
+		require.Len(t, paths, 1)
+		require.True(t, paths[0].Active)
+		require.Equal(t, clientPacketConn2.LocalAddr(), paths[0].LocalAddr)
+		require.Equal(t, 40*time.Millisecond, paths[0].MinRTT)
+	})
+}
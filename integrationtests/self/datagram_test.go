@@ -251,3 +251,64 @@ func TestDatagramLoss(t *testing.T) {
 		assert.EqualValues(t, numDatagrams-numDroppedToClient, clientDatagrams, "datagrams received by the client")
 	})
 }
+
+func TestDatagramSendIfIdle(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		server, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(&quic.Config{EnableDatagrams: true}))
+		require.NoError(t, err)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		start := time.Now()
+		clientConn, err := quic.Dial(
+			ctx,
+			clientPacketConn,
+			serverPacketConn.LocalAddr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableDatagrams: true}),
+		)
+		require.NoError(t, err)
+		defer clientConn.CloseWithError(0, "")
+
+		serverConn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		// wait for the connection to become idle
+		time.Sleep(time.Second)
+		synctest.Wait()
+		stats := clientConn.ConnectionStats()
+		require.False(t, stats.LastAckElicitingPacketSentAt.Before(start))
+		require.False(t, stats.LastPacketReceivedAt.Before(start))
+		require.True(t, stats.LastAckElicitingPacketSentAt.Before(time.Now()))
+		require.True(t, stats.LastPacketReceivedAt.Before(time.Now()))
+
+		const idleFor = 500 * time.Millisecond
+		sent, err := clientConn.SendDatagramIfIdle(nil, idleFor)
+		require.NoError(t, err)
+		require.True(t, sent)
+		sendTime := time.Now()
+		data, err := serverConn.ReceiveDatagram(ctx)
+		require.NoError(t, err)
+		require.Empty(t, data)
+		require.True(t, sendTime.Equal(clientConn.ConnectionStats().LastAckElicitingPacketSentAt))
+
+		// the connection was active recently, so the heartbeat is suppressed
+		time.Sleep(idleFor / 2)
+		sent, err = clientConn.SendDatagramIfIdle(nil, idleFor)
+		require.NoError(t, err)
+		require.False(t, sent)
+
+		time.Sleep(idleFor / 2)
+		sent, err = clientConn.SendDatagramIfIdle([]byte("heartbeat"), idleFor)
+		require.NoError(t, err)
+		require.True(t, sent)
+		data, err = serverConn.ReceiveDatagram(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte("heartbeat"), data)
+	})
+}
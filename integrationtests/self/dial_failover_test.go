@@ -0,0 +1,140 @@
+package self_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/testutils/events"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialFailover(t *testing.T) {
+	tlsConf := getTLSConfig()
+	primary, err := quic.ListenEarly(newUDPConnLocalhost(t), tlsConf, getQuicConfig(&quic.Config{Allow0RTT: true}))
+	require.NoError(t, err)
+	defer primary.Close()
+	backup, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer backup.Close()
+
+	clientTLSConf := dialAndReceiveTicket(t, primary, newUDPConnLocalhost(t), nil)
+
+	dialFailover := func(t *testing.T, primaryAddr net.Addr, headStart time.Duration) (*quic.Conn, quic.FailoverInfo, *events.Recorder) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+		t.Cleanup(func() { tr.Close() })
+		var eventRecorder events.Recorder
+		conn, info, err := tr.DialFailover(
+			ctx,
+			primaryAddr,
+			backup.Addr(),
+			headStart,
+			clientTLSConf,
+			getQuicConfig(&quic.Config{Tracer: newTracer(&eventRecorder)}),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.CloseWithError(0, "") })
+		return conn, info, &eventRecorder
+	}
+
+	t.Run("primary wins", func(t *testing.T) {
+		conn, info, _ := dialFailover(t, primary.Addr(), time.Second)
+		require.False(t, info.UsedBackup)
+		require.Equal(t, quic.FailoverPrimaryHandshakeComplete, info.Reason)
+		require.True(t, conn.ConnectionState().Used0RTT)
+		require.Equal(t, primary.Addr().String(), conn.RemoteAddr().String())
+
+		sconn, err := primary.Accept(context.Background())
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+		// the backup address was never dialed
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = backup.Accept(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("primary wins, 0-RTT rejected", func(t *testing.T) {
+		// this server uses the same session ticket keys, but doesn't accept 0-RTT
+		primaryNo0RTT, err := quic.ListenEarly(newUDPConnLocalhost(t), tlsConf, getQuicConfig(nil))
+		require.NoError(t, err)
+		defer primaryNo0RTT.Close()
+
+		conn, info, _ := dialFailover(t, primaryNo0RTT.Addr(), time.Second)
+		require.False(t, info.UsedBackup)
+		require.Equal(t, quic.FailoverPrimary0RTTRejected, info.Reason)
+		require.False(t, conn.ConnectionState().Used0RTT)
+		require.Equal(t, primaryNo0RTT.Addr().String(), conn.RemoteAddr().String())
+	})
+
+	t.Run("primary fails", func(t *testing.T) {
+		// the handshake fails, since the server doesn't support the client's ALPN
+		badTLSConf := getTLSConfig()
+		badTLSConf.NextProtos = []string{"foobar"}
+		primaryFailing, err := quic.ListenEarly(newUDPConnLocalhost(t), badTLSConf, getQuicConfig(nil))
+		require.NoError(t, err)
+		defer primaryFailing.Close()
+
+		start := time.Now()
+		const headStart = time.Second
+		conn, info, _ := dialFailover(t, primaryFailing.Addr(), headStart)
+		// the backup address is dialed as soon as the primary fails
+		require.Less(t, time.Since(start), headStart)
+		require.True(t, info.UsedBackup)
+		require.Equal(t, quic.FailoverPrimaryFailed, info.Reason)
+		var transportErr *quic.TransportError
+		require.ErrorAs(t, info.PrimaryErr, &transportErr)
+		require.Equal(t, backup.Addr().String(), conn.RemoteAddr().String())
+
+		sconn, err := backup.Accept(context.Background())
+		require.NoError(t, err)
+		sconn.CloseWithError(0, "")
+	})
+
+	t.Run("primary unresponsive", func(t *testing.T) {
+		var numDropped atomic.Int64
+		proxy := quicproxy.Proxy{
+			Conn:       newUDPConnLocalhost(t),
+			ServerAddr: primary.Addr().(*net.UDPAddr),
+			DropPacket: func(quicproxy.Direction, net.Addr, net.Addr, []byte) bool {
+				numDropped.Add(1)
+				return true
+			},
+		}
+		require.NoError(t, proxy.Start())
+		defer proxy.Close()
+
+		start := time.Now()
+		const headStart = 50 * time.Millisecond
+		conn, info, eventRecorder := dialFailover(t, proxy.LocalAddr(), headStart)
+		require.GreaterOrEqual(t, time.Since(start), headStart)
+		require.True(t, info.UsedBackup)
+		require.Equal(t, quic.FailoverBackupHandshakeComplete, info.Reason)
+		require.NoError(t, info.PrimaryErr)
+		require.Equal(t, backup.Addr().String(), conn.RemoteAddr().String())
+		require.NotZero(t, numDropped.Load())
+
+		sconn, err := backup.Accept(context.Background())
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+
+		// the connection to the primary address is closed
+		require.Eventually(t, func() bool {
+			return len(eventRecorder.Events(qlog.ConnectionClosed{})) == 1
+		}, time.Second, 5*time.Millisecond)
+		select {
+		case <-conn.Context().Done():
+			t.Fatal("the backup connection should not have been closed")
+		default:
+		}
+	})
+}
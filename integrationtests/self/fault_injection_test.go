@@ -0,0 +1,133 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjectionNetwork(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		var numFaults atomic.Int64
+		faultInjector := func(info quic.FaultInfo) quic.FaultAction {
+			if info.Layer != quic.FaultLayerNetwork {
+				return quic.FaultAction{}
+			}
+			var action quic.FaultAction
+			switch r := rand.IntN(100); {
+			case r < 5:
+				action.Drop = true
+			case r < 10:
+				action.Duplicate = true
+			case r < 15:
+				action.Delay = time.Duration(rand.IntN(20)) * time.Millisecond
+			case r < 20:
+				pos := rand.IntN(len(info.Data))
+				action.CorruptStart, action.CorruptEnd = pos, pos+1
+			default:
+				return action
+			}
+			numFaults.Add(1)
+			return action
+		}
+
+		serverTr := &quic.Transport{Conn: serverPacketConn, FaultInjector: faultInjector}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientTr := &quic.Transport{Conn: clientPacketConn, FaultInjector: faultInjector}
+		defer clientTr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		conn, err := clientTr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		data := GeneratePRData(200 * 1024)
+		go func() {
+			str, err := serverConn.OpenUniStream()
+			if err != nil {
+				return
+			}
+			str.Write(data)
+			str.Close()
+		}()
+
+		str, err := conn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		received, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(data, received), "data mismatch")
+		t.Logf("injected %d faults", numFaults.Load())
+		require.NotZero(t, numFaults.Load())
+	})
+}
+
+func TestFaultInjectionPlaintextCorruption(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		var corrupt atomic.Bool
+		serverTr := &quic.Transport{
+			Conn: serverPacketConn,
+			FaultInjector: func(info quic.FaultInfo) quic.FaultAction {
+				if info.Layer != quic.FaultLayerPlaintext || info.Direction != quic.FaultDirectionOutgoing || !corrupt.Load() {
+					return quic.FaultAction{}
+				}
+				// corrupt the frame type of the first frame
+				return quic.FaultAction{CorruptStart: 0, CorruptEnd: 1}
+			},
+		}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+
+		// The packet is encrypted after corruption, so the client successfully decrypts it,
+		// and then fails to parse the frame.
+		corrupt.Store(true)
+		str, err := serverConn.OpenUniStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+
+		select {
+		case <-conn.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		var transportErr *quic.TransportError
+		require.ErrorAs(t, context.Cause(conn.Context()), &transportErr)
+		require.False(t, transportErr.Remote)
+		require.Equal(t, quic.FrameEncodingError, transportErr.ErrorCode)
+	})
+}
@@ -287,3 +287,78 @@ func TestContextOnClientSide(t *testing.T) {
 	checkContextFromChan(tlsContextChan, false)
 	checkContextFromChan(tracerContextChan, false)
 }
+
+type userDataTrace struct {
+	ctx      context.Context
+	userData chan any
+}
+
+var _ qlogwriter.Trace = &userDataTrace{}
+
+func (t *userDataTrace) AddProducer() qlogwriter.Recorder {
+	return &mockRecorder{onClose: func() { t.userData <- quic.UserDataFromContext(t.ctx) }}
+}
+
+func (t *userDataTrace) SupportsSchemas(string) bool { return true }
+
+func TestConnUserData(t *testing.T) {
+	type tenant struct{ name string }
+
+	tracerUserData := make(chan any, 1)
+	server, err := quic.Listen(
+		newUDPConnLocalhost(t),
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{
+			Tracer: func(ctx context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+				return &userDataTrace{ctx: ctx, userData: tracerUserData}
+			},
+		}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	require.Nil(t, conn.UserData())
+
+	serverConn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	require.Nil(t, serverConn.UserData())
+	serverConn.SetUserData(&tenant{name: "foo"})
+	require.Equal(t, &tenant{name: "foo"}, serverConn.UserData())
+	require.Equal(t, &tenant{name: "foo"}, quic.UserDataFromContext(serverConn.Context()))
+
+	// the user data is accessible from the stream handler
+	handlerUserData := make(chan any, 1)
+	go func() {
+		str, err := serverConn.AcceptStream(ctx)
+		if err != nil {
+			handlerUserData <- err
+			return
+		}
+		handlerUserData <- quic.UserDataFromContext(str.Context())
+	}()
+	str, err := conn.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+
+	select {
+	case v := <-handlerUserData:
+		require.Equal(t, &tenant{name: "foo"}, v)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// the user data is accessible from the tracer
+	serverConn.CloseWithError(0, "")
+	select {
+	case v := <-tracerUserData:
+		require.Equal(t, &tenant{name: "foo"}, v)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
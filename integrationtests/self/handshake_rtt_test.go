@@ -3,13 +3,19 @@ package self_test
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/testutils/simnet"
 
 	"github.com/stretchr/testify/require"
 )
@@ -46,6 +52,168 @@ func testHandshakeRTTRetry(t *testing.T, doRetry bool) {
 	}
 }
 
+func TestHandshakeAddressValidationInfo(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 100 * time.Millisecond
+
+		clientPacketConn, serverPacketConn, close := newSimnetLink(t, rtt)
+		defer close(t)
+
+		serverTr := &quic.Transport{
+			Conn: serverPacketConn,
+			// perform a Retry, unless the client presents a token from a NEW_TOKEN frame
+			VerifySourceAddress: func(net.Addr) bool { return true },
+		}
+		addTracer(serverTr)
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientTr := &quic.Transport{Conn: clientPacketConn}
+		addTracer(clientTr)
+		defer clientTr.Close()
+		clientConf := getQuicConfig(&quic.Config{TokenStore: quic.NewLRUTokenStore(1, 1)})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*rtt)
+		defer cancel()
+		conn, err := clientTr.Dial(ctx, serverPacketConn.LocalAddr(), getTLSClientConfig(), clientConf)
+		require.NoError(t, err)
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+
+		clientInfo := conn.ConnectionState().AddressValidation
+		serverInfo := serverConn.ConnectionState().AddressValidation
+		require.True(t, clientInfo.UsedRetry)
+		// The ClientHello might be split across multiple Initial packets,
+		// leading to multiple Retry packets, only the first of which is processed.
+		require.GreaterOrEqual(t, clientInfo.RetriesReceived, 1)
+		require.True(t, serverInfo.UsedRetry)
+		require.False(t, serverInfo.UsedNewToken)
+		require.Equal(t, rtt, serverInfo.RetryLatency)
+		require.Zero(t, serverInfo.RetriesReceived)
+		require.NotZero(t, serverInfo.RetrySrcConnectionID.Len())
+		require.Equal(t, serverConn.ConnectionState().OriginalDestinationConnectionID, conn.ConnectionState().OriginalDestinationConnectionID)
+		require.Equal(t, serverInfo.RetrySrcConnectionID, clientInfo.RetrySrcConnectionID)
+
+		// wait for the NEW_TOKEN frame to arrive
+		time.Sleep(rtt)
+		conn.CloseWithError(0, "")
+		serverConn.CloseWithError(0, "")
+
+		// the second connection uses the token received in the NEW_TOKEN frame
+		conn, err = clientTr.Dial(ctx, serverPacketConn.LocalAddr(), getTLSClientConfig(), clientConf)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		serverConn, err = ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		require.Equal(t, quic.AddressValidationInfo{}, conn.ConnectionState().AddressValidation)
+		require.Equal(t, quic.AddressValidationInfo{UsedNewToken: true}, serverConn.ConnectionState().AddressValidation)
+	})
+}
+
+func TestHandshakeUsedRetry(t *testing.T) {
+	t.Run("with Retry", func(t *testing.T) {
+		testHandshakeUsedRetry(t, true)
+	})
+	t.Run("without Retry", func(t *testing.T) {
+		testHandshakeUsedRetry(t, false)
+	})
+}
+
+func testHandshakeUsedRetry(t *testing.T, doRetry bool) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, close := newSimnetLink(t, 10*time.Millisecond)
+		defer close(t)
+
+		serverTr := &quic.Transport{
+			Conn:                serverPacketConn,
+			VerifySourceAddress: func(net.Addr) bool { return doRetry },
+		}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		require.Equal(t, doRetry, conn.ConnectionState().AddressValidation.UsedRetry)
+		require.Equal(t, doRetry, serverConn.ConnectionState().AddressValidation.UsedRetry)
+	})
+}
+
+func TestHandshakeOriginalDestinationConnectionID(t *testing.T) {
+	t.Run("with Retry", func(t *testing.T) {
+		testHandshakeOriginalDestinationConnectionID(t, true)
+	})
+	t.Run("without Retry", func(t *testing.T) {
+		testHandshakeOriginalDestinationConnectionID(t, false)
+	})
+}
+
+func testHandshakeOriginalDestinationConnectionID(t *testing.T, doRetry bool) {
+	synctest.Test(t, func(t *testing.T) {
+		var mutex sync.Mutex
+		var firstDestConnID protocol.ConnectionID
+		clientAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001}
+		router := &callbackRouter{
+			Router: &simnet.PerfectRouter{},
+			OnSendPacket: func(p simnet.Packet) {
+				if p.From.String() != clientAddr.String() {
+					return
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				if firstDestConnID.Len() > 0 {
+					return
+				}
+				hdr, _, _, err := wire.ParsePacket(p.Data)
+				if err != nil {
+					panic(fmt.Sprintf("failed to parse packet: %v", err))
+				}
+				firstDestConnID = hdr.DestConnectionID
+			},
+		}
+		clientPacketConn, serverPacketConn, close := newSimnetLinkWithRouter(t, 10*time.Millisecond, router)
+		defer close(t)
+		require.Equal(t, clientAddr.String(), clientPacketConn.LocalAddr().String())
+
+		serverTr := &quic.Transport{
+			Conn:                serverPacketConn,
+			VerifySourceAddress: func(net.Addr) bool { return doRetry },
+		}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		require.NotZero(t, firstDestConnID.Len())
+		require.Equal(t, doRetry, conn.ConnectionState().AddressValidation.UsedRetry)
+		require.Equal(t, firstDestConnID, conn.ConnectionState().OriginalDestinationConnectionID)
+		require.Equal(t, firstDestConnID, serverConn.ConnectionState().OriginalDestinationConnectionID)
+	})
+}
+
 func TestHandshakeRTTHelloRetryRequest(t *testing.T) {
 	tlsConf := getTLSConfig()
 	tlsConf.CurvePreferences = []tls.CurveID{tls.CurveP384}
@@ -199,3 +367,151 @@ func testHandshake05RTT(t *testing.T, use05RTT bool) {
 		}
 	})
 }
+
+func TestHandshakeConfirmationDelayed(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		clientPacketConn, serverPacketConn, close := newSimnetLink(t, rtt)
+		defer close(t)
+
+		serverTr := &quic.Transport{Conn: serverPacketConn}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		// drop all packets sent by the server after the client completed the handshake
+		var drop atomic.Bool
+		clientTr := &quic.Transport{
+			Conn: clientPacketConn,
+			FaultInjector: func(info quic.FaultInfo) quic.FaultAction {
+				return quic.FaultAction{
+					Drop: info.Layer == quic.FaultLayerNetwork && info.Direction == quic.FaultDirectionIncoming && drop.Load(),
+				}
+			},
+		}
+		defer clientTr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		conn, err := clientTr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		drop.Store(true)
+
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+		require.True(t, serverConn.ConnectionState().HandshakeConfirmed)
+
+		state := conn.ConnectionState()
+		require.True(t, state.HandshakeComplete)
+		require.False(t, state.HandshakeConfirmed)
+
+		// the client can already send application data
+		str, err := conn.OpenUniStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+
+		time.Sleep(10 * rtt)
+		select {
+		case <-conn.HandshakeConfirmed():
+			t.Fatal("handshake shouldn't be confirmed")
+		default:
+		}
+		require.False(t, conn.ConnectionState().HandshakeConfirmed)
+
+		// the server retransmits the HANDSHAKE_DONE frame
+		drop.Store(false)
+		select {
+		case <-conn.HandshakeConfirmed():
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for handshake confirmation")
+		}
+		require.True(t, conn.ConnectionState().HandshakeConfirmed)
+
+		serverStr, err := serverConn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(serverStr)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), data)
+	})
+}
+
+// reorderingRouter delays all server datagrams that only contain Handshake packets,
+// such that they arrive after the server's first 1-RTT packet.
+type reorderingRouter struct {
+	simnet.PerfectRouter
+
+	ServerAddr net.Addr
+	Delay      time.Duration
+}
+
+func (r *reorderingRouter) SendPacket(p simnet.Packet) error {
+	if p.From.String() == r.ServerAddr.String() &&
+		containsPacketType(p.Data, protocol.PacketTypeHandshake) &&
+		!containsPacketType(p.Data, protocol.PacketTypeInitial) {
+		time.AfterFunc(r.Delay, func() { r.PerfectRouter.SendPacket(p) })
+		return nil
+	}
+	return r.PerfectRouter.SendPacket(p)
+}
+
+func TestHandshakeReordered1RTTPackets(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 20 * time.Millisecond
+		router := &reorderingRouter{
+			ServerAddr: &net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002},
+			Delay:      rtt / 2,
+		}
+		clientPacketConn, serverPacketConn, close := newSimnetLinkWithRouter(t, rtt, router)
+		defer close(t)
+
+		server, err := quic.ListenEarly(serverPacketConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer server.Close()
+
+		connChan := make(chan *quic.Conn, 1)
+		errChan := make(chan error, 1)
+		go func() {
+			conn, err := server.Accept(context.Background())
+			if err != nil {
+				errChan <- err
+				return
+			}
+			connChan <- conn
+			// send 0.5-RTT data
+			str, err := conn.OpenUniStream()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := str.Write([]byte("foobar")); err != nil {
+				errChan <- err
+				return
+			}
+			errChan <- str.Close()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*rtt)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, serverPacketConn.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		require.NoError(t, <-errChan)
+		serverConn := <-connChan
+		defer serverConn.CloseWithError(0, "")
+
+		str, err := conn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), data)
+
+		// The 1-RTT packet arrived before the Handshake packets, and was processed
+		// once the 1-RTT keys became available.
+		require.NotZero(t, conn.ConnectionStats().Early1RTTPacketsSalvaged)
+	})
+}
@@ -157,6 +157,38 @@ func TestHandshakeCipherSuites(t *testing.T) {
 	}
 }
 
+func TestHandshakeKeyExchange(t *testing.T) {
+	t.Run("hybrid post-quantum", func(t *testing.T) {
+		testHandshakeKeyExchange(t, tls.X25519MLKEM768, true)
+	})
+	t.Run("classical", func(t *testing.T) {
+		testHandshakeKeyExchange(t, tls.X25519, false)
+	})
+}
+
+func testHandshakeKeyExchange(t *testing.T, curveID tls.CurveID, postQuantum bool) {
+	ln, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer ln.Close()
+
+	tlsConf := getTLSClientConfig()
+	tlsConf.CurvePreferences = []tls.CurveID{curveID}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), tlsConf, getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	serverConn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	for _, cs := range []quic.ConnectionState{conn.ConnectionState(), serverConn.ConnectionState()} {
+		require.Equal(t, curveID, cs.TLS.CurveID)
+		require.Equal(t, curveID.String(), cs.KeyExchange)
+		require.Equal(t, postQuantum, cs.PostQuantumKeyExchange)
+	}
+}
+
 func TestTLSGetConfigForClientError(t *testing.T) {
 	tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
 	addTracer(tr)
@@ -341,6 +373,34 @@ func TestServerAcceptQueueOverflow(t *testing.T) {
 	require.Equal(t, quic.ConnectionRefused, transportErr.ErrorCode)
 }
 
+func TestServerOverload(t *testing.T) {
+	var overloaded atomic.Bool
+	overloaded.Store(true)
+	tr := &quic.Transport{
+		Conn:       newUDPConnLocalhost(t),
+		OnOverload: overloaded.Load,
+	}
+	addTracer(tr)
+	defer tr.Close()
+	server, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	var transportErr *quic.TransportError
+	require.ErrorAs(t, err, &transportErr)
+	require.Equal(t, quic.ConnectionRefused, transportErr.ErrorCode)
+
+	overloaded.Store(false)
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	_, err = server.Accept(ctx)
+	require.NoError(t, err)
+}
+
 func TestHandshakeCloseListener(t *testing.T) {
 	t.Run("using Transport.Listen", func(t *testing.T) {
 		testHandshakeCloseListener(t, func(tlsConf *tls.Config) *quic.Listener {
@@ -604,6 +664,47 @@ func testTokensFromNewTokenFrames(t *testing.T, maxTokenAge time.Duration, expec
 	}
 }
 
+func TestNewTokensDisabled(t *testing.T) {
+	addrVerifiedChan := make(chan bool, 2)
+	quicConf := getQuicConfig(&quic.Config{DisableNewTokens: true})
+	quicConf.GetConfigForClient = func(info *quic.ClientInfo) (*quic.Config, error) {
+		addrVerifiedChan <- info.AddrVerified
+		return quicConf, nil
+	}
+	server, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), quicConf)
+	require.NoError(t, err)
+	defer server.Close()
+
+	gets := make(chan string, 2)
+	puts := make(chan string, 2)
+	ts := newTokenStore(gets, puts)
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), server.Addr(), getTLSClientConfig(), getQuicConfig(&quic.Config{TokenStore: ts}))
+		cancel()
+		require.NoError(t, err)
+		sconn, err := server.Accept(context.Background())
+		require.NoError(t, err)
+		select {
+		case addrVerified := <-addrVerifiedChan:
+			require.False(t, addrVerified)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for addr verified")
+		}
+		// wait for the handshake to be confirmed, which is when the NEW_TOKEN frame would have been sent
+		select {
+		case <-conn.HandshakeComplete():
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the handshake to complete")
+		}
+		time.Sleep(scaleDuration(10 * time.Millisecond))
+		require.NoError(t, conn.CloseWithError(0, ""))
+		sconn.CloseWithError(0, "")
+		<-gets
+	}
+	require.Empty(t, puts)
+}
+
 func TestInvalidToken(t *testing.T) {
 	const rtt = 10 * time.Millisecond
 
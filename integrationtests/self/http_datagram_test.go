@@ -53,6 +53,16 @@ func TestHTTPSettings(t *testing.T) {
 			connChan <- w.(http3.Settingser)
 			w.WriteHeader(http.StatusOK)
 		})
+		ctxSettingsChan := make(chan *http3.Settings, 1)
+		mux.HandleFunc("/settings-from-context", func(w http.ResponseWriter, r *http.Request) {
+			settings, err := http3.WaitForSettings(r.Context(), r.Context().Value(http3.SettingsContextKey).(http3.Settingser))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ctxSettingsChan <- settings
+			w.WriteHeader(http.StatusOK)
+		})
 
 		tr := &http3.Transport{
 			TLSClientConfig: getTLSClientConfigWithoutServerName(),
@@ -86,6 +96,27 @@ func TestHTTPSettings(t *testing.T) {
 		require.True(t, settings.EnableDatagrams)
 		require.False(t, settings.EnableExtendedConnect)
 		require.Equal(t, uint64(42), settings.Other[1337])
+
+		// the settings can also be retrieved from the request context
+		req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("https://localhost:%d/settings-from-context", port), nil)
+		require.NoError(t, err)
+		rsp, err := tr.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		select {
+		case settings = <-ctxSettingsChan:
+		case <-time.After(time.Second):
+			t.Fatal("handler didn't receive HTTP/3 settings")
+		}
+		require.Equal(t, uint64(42), settings.Other[1337])
+
+		// the client can retrieve the server's settings from the Transport
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		serverSettings, err := tr.PeerSettings(ctx, fmt.Sprintf("localhost:%d", port))
+		require.NoError(t, err)
+		require.True(t, serverSettings.EnableExtendedConnect)
+		require.Positive(t, serverSettings.MaxFieldSectionSize)
 	})
 }
 
@@ -18,6 +18,15 @@ import (
 )
 
 func TestKeyUpdates(t *testing.T) {
+	t.Run("serial decryption", func(t *testing.T) {
+		testKeyUpdates(t, false)
+	})
+	t.Run("parallel decryption", func(t *testing.T) {
+		testKeyUpdates(t, true)
+	})
+}
+
+func testKeyUpdates(t *testing.T, parallelDecryption bool) {
 	reset := handshake.SetKeyUpdateInterval(1) // update keys as frequently as possible
 	t.Cleanup(reset)
 
@@ -53,7 +62,10 @@ func TestKeyUpdates(t *testing.T) {
 		newUDPConnLocalhost(t),
 		server.Addr(),
 		getTLSClientConfig(),
-		getQuicConfig(&quic.Config{Tracer: newTracer(&eventRecorder)}),
+		getQuicConfig(&quic.Config{
+			Tracer:                   newTracer(&eventRecorder),
+			EnableParallelDecryption: parallelDecryption,
+		}),
 	)
 	require.NoError(t, err)
 	defer conn.CloseWithError(0, "")
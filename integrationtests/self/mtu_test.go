@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -42,6 +46,39 @@ func TestInitialPacketSize(t *testing.T) {
 	<-done
 }
 
+func TestInitialPacketPadding(t *testing.T) {
+	t.Run("padding the Initial packet", func(t *testing.T) {
+		testInitialPacketPadding(t, false)
+	})
+	t.Run("padding the coalesced packet", func(t *testing.T) {
+		testInitialPacketPadding(t, true)
+	})
+}
+
+func testInitialPacketPadding(t *testing.T, coalescedPadding bool) {
+	server := newUDPConnLocalhost(t)
+	client := newUDPConnLocalhost(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		quic.Dial(ctx, client, server.LocalAddr(), getTLSClientConfig(), getQuicConfig(&quic.Config{
+			InitialPacketSize:       protocol.MinInitialPacketSize,
+			CoalescedInitialPadding: coalescedPadding,
+		}))
+	}()
+
+	buf := make([]byte, 2000)
+	n, _, err := server.ReadFrom(buf)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, protocol.MinInitialPacketSize)
+
+	cancel()
+	<-done
+}
+
 func TestPathMTUDiscovery(t *testing.T) {
 	rtt := scaleDuration(5 * time.Millisecond)
 	const mtu = 1400
@@ -195,3 +232,113 @@ func TestPathMTUDiscovery(t *testing.T) {
 	// except for at most one MTU probe packet.
 	require.LessOrEqual(t, numPacketsLargerThanDiscoveredMTU, 1)
 }
+
+// msgSizeLimitedConn rejects packets larger than maxSize with EMSGSIZE,
+// the same way the kernel does when the DF bit is set and the packet exceeds the interface MTU.
+// It doesn't implement quic.OOBCapablePacketConn, so that packets are sent using WriteTo.
+type msgSizeLimitedConn struct {
+	net.PacketConn
+	udpConn *net.UDPConn
+
+	maxSize  atomic.Int64 // 0 means no limit
+	largest  atomic.Int64
+	rejected atomic.Int64
+}
+
+func newMsgSizeLimitedConn(c *net.UDPConn) *msgSizeLimitedConn {
+	return &msgSizeLimitedConn{PacketConn: c, udpConn: c}
+}
+
+// SyscallConn is used to set the DF bit.
+func (c *msgSizeLimitedConn) SyscallConn() (syscall.RawConn, error) { return c.udpConn.SyscallConn() }
+
+func (c *msgSizeLimitedConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if maxSize := c.maxSize.Load(); maxSize > 0 && int64(len(b)) > maxSize {
+		c.rejected.Add(1)
+		return 0, &net.OpError{Op: "write", Net: "udp", Addr: addr, Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+	}
+	for {
+		largest := c.largest.Load()
+		if int64(len(b)) <= largest || c.largest.CompareAndSwap(largest, int64(len(b))) {
+			break
+		}
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func TestPacketTooLargeError(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("EMSGSIZE is only detected on Linux and macOS")
+	}
+
+	ln, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		for {
+			str, err := conn.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				defer str.Close()
+				io.Copy(str, str)
+			}()
+		}
+	}()
+
+	sconn := newMsgSizeLimitedConn(newUDPConnLocalhost(t))
+	tr := &quic.Transport{Conn: sconn}
+	defer tr.Close()
+
+	conn, err := tr.Dial(context.Background(), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	echo := func(t *testing.T) {
+		t.Helper()
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		errChan := make(chan error, 1)
+		go func() {
+			data, err := io.ReadAll(str)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if !bytes.Equal(data, PRDataLong) {
+				errChan <- fmt.Errorf("echoed data doesn't match: %x", data)
+				return
+			}
+			errChan <- nil
+		}()
+		_, err = str.Write(PRDataLong)
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		case <-time.After(20 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	// DPLPMTUD increases the packet size beyond the initial packet size
+	echo(t)
+	largest := sconn.largest.Load()
+	t.Logf("largest packet sent: %d", largest)
+	require.Greater(t, largest, int64(1400))
+	require.Zero(t, conn.ConnectionStats().PacketSizeReductions)
+
+	// simulate a reduction of the interface MTU
+	const maxSize = 1350
+	sconn.maxSize.Store(maxSize)
+	echo(t)
+	require.NotZero(t, sconn.rejected.Load())
+	require.NotZero(t, conn.ConnectionStats().PacketSizeReductions)
+}
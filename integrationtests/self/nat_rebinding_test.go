@@ -6,14 +6,18 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/quic-go/quic-go"
 	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
 	"github.com/quic-go/quic-go/qlog"
 	"github.com/quic-go/quic-go/qlogwriter"
+	"github.com/quic-go/quic-go/testutils/simnet"
 
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +25,7 @@ import (
 func TestNATRebinding(t *testing.T) {
 	tr, tracer := newPacketTracer()
 	tlsConf := getTLSConfig()
-	f, err := os.Create("keylog.txt")
+	f, err := os.Create(filepath.Join(t.TempDir(), "keylog.txt"))
 	require.NoError(t, err)
 	defer f.Close()
 	tlsConf.KeyLogWriter = f
@@ -124,3 +128,112 @@ func TestNATRebinding(t *testing.T) {
 	}
 	require.True(t, foundPathResponse)
 }
+
+// spoofingRouter simulates an off-path attacker that races copies of the client's
+// 1-RTT packets with a spoofed source address to the server.
+type spoofingRouter struct {
+	simnet.PerfectRouter
+
+	ClientAddr, ServerAddr *net.UDPAddr
+	NumAddrs               int
+
+	counter atomic.Int64
+
+	mx sync.Mutex
+	// number of bytes sent from and to the spoofed addresses
+	bytesSpoofed, bytesToSpoofed map[string]int
+}
+
+func (r *spoofingRouter) SendPacket(p simnet.Packet) error {
+	// packets sent to the spoofed addresses are dropped
+	if p.To.String() != r.ClientAddr.String() && p.To.String() != r.ServerAddr.String() {
+		r.mx.Lock()
+		r.bytesToSpoofed[p.To.String()] += len(p.Data)
+		r.mx.Unlock()
+		return nil
+	}
+	if p.From.String() == r.ClientAddr.String() && p.Data[0]&0x80 == 0 {
+		n := r.counter.Add(1)
+		spoofed := simnet.Packet{
+			To:   p.To,
+			From: &net.UDPAddr{IP: r.ClientAddr.IP, Port: 10000 + int(n)%r.NumAddrs},
+			Data: append([]byte(nil), p.Data...),
+		}
+		r.mx.Lock()
+		r.bytesSpoofed[spoofed.From.String()] += len(p.Data)
+		r.mx.Unlock()
+		if err := r.PerfectRouter.SendPacket(spoofed); err != nil {
+			return err
+		}
+	}
+	return r.PerfectRouter.SendPacket(p)
+}
+
+func TestNATRebindingAddressFlappingAttack(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001}
+		serverAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002}
+		router := &spoofingRouter{
+			ClientAddr:     clientAddr,
+			ServerAddr:     serverAddr,
+			NumAddrs:       20,
+			bytesSpoofed:   make(map[string]int),
+			bytesToSpoofed: make(map[string]int),
+		}
+		n := &simnet.Simnet{Router: router}
+		settings := simnet.NodeBiDiLinkSettings{Latency: 5 * time.Millisecond}
+		clientPacketConn := n.NewEndpoint(clientAddr, settings)
+		serverPacketConn := n.NewEndpoint(serverAddr, settings)
+		require.NoError(t, n.Start())
+		defer n.Close()
+		defer clientPacketConn.Close()
+		defer serverPacketConn.Close()
+
+		server, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, serverAddr, getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		go func() {
+			str, err := serverConn.OpenUniStream()
+			if err != nil {
+				return
+			}
+			defer str.Close()
+			str.Write(PRData)
+		}()
+
+		str, err := conn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+
+		// the connection never migrated to any of the spoofed addresses
+		require.Equal(t, clientAddr.String(), serverConn.RemoteAddr().String())
+		require.Greater(t, router.counter.Load(), int64(router.NumAddrs))
+		stats := serverConn.ConnectionStats()
+		t.Logf("path validations dropped: %d, path probes limited by anti-amplification limit: %d",
+			stats.PathValidationsDropped, stats.PathProbesAmplificationLimited,
+		)
+		require.NotZero(t, stats.PathValidationsDropped)
+		require.Zero(t, stats.PathSwitchesDropped)
+
+		// the server never sent more than 3x the amount of data received from a spoofed address
+		router.mx.Lock()
+		defer router.mx.Unlock()
+		require.NotEmpty(t, router.bytesToSpoofed)
+		for addr, n := range router.bytesToSpoofed {
+			require.LessOrEqual(t, n, 3*router.bytesSpoofed[addr], "sent %d bytes to %s", n, addr)
+		}
+	})
+}
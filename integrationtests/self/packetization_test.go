@@ -6,7 +6,9 @@ import (
 	"io"
 	"net"
 	"os"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/quic-go/quic-go"
@@ -131,6 +133,93 @@ func TestACKBundling(t *testing.T) {
 	require.Greater(t, numBundledOutgoing, numMsg*9/10)
 }
 
+func TestAckLatencyHint(t *testing.T) {
+	numAcksDefault := countAcksForTransfer(t, quic.AckLatencyDefault)
+	numAcksTolerant := countAcksForTransfer(t, quic.AckLatencyTolerant)
+	t.Logf("ACK frames received: %d (default), %d (latency-tolerant)", numAcksDefault, numAcksTolerant)
+	require.Less(t, numAcksTolerant, numAcksDefault/2)
+}
+
+// countAcksForTransfer transfers data from the client to the server,
+// and counts the number of packets containing an ACK frame sent by the server.
+func countAcksForTransfer(t *testing.T, hint quic.AckLatencyHint) int {
+	data := GeneratePRData(500 << 10)
+
+	server, err := quic.Listen(
+		newUDPConnLocalhost(t),
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	clientCounter, clientTracer := newPacketTracer()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.Dial(
+		ctx,
+		newUDPConnLocalhost(t),
+		server.Addr(),
+		getTLSClientConfig(),
+		getQuicConfig(&quic.Config{
+			DisablePathMTUDiscovery: true,
+			Tracer:                  func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace { return clientTracer },
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	serverErrChan := make(chan error, 1)
+	go func() {
+		defer close(serverErrChan)
+		conn, err := server.Accept(ctx)
+		if err != nil {
+			serverErrChan <- fmt.Errorf("accept failed: %w", err)
+			return
+		}
+		str, err := conn.AcceptUniStream(ctx)
+		if err != nil {
+			serverErrChan <- fmt.Errorf("accept stream failed: %w", err)
+			return
+		}
+		str.SetAckLatencyHint(hint)
+		b, err := io.ReadAll(str)
+		if err != nil {
+			serverErrChan <- fmt.Errorf("read failed: %w", err)
+			return
+		}
+		if len(b) != len(data) {
+			serverErrChan <- fmt.Errorf("received %d bytes, expected %d", len(b), len(data))
+			return
+		}
+		conn.CloseWithError(0, "")
+	}()
+
+	str, err := conn.OpenUniStream()
+	require.NoError(t, err)
+	_, err = str.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	require.NoError(t, <-serverErrChan)
+
+	select {
+	case <-conn.Context().Done():
+	case <-ctx.Done():
+		t.Fatal("timeout")
+	}
+
+	var numAcks int
+	for _, p := range clientCounter.getRcvdShortHeaderPackets() {
+		for _, f := range p.frames {
+			if _, ok := f.Frame.(*qlog.AckFrame); ok {
+				numAcks++
+				break
+			}
+		}
+	}
+	return numAcks
+}
+
 func TestStreamDataBlocked(t *testing.T) {
 	testConnAndStreamDataBlocked(t, true, false)
 }
@@ -271,3 +360,107 @@ func testConnAndStreamDataBlocked(t *testing.T, limitStream, limitConn bool) {
 		}
 	}
 }
+
+// stallingConn blocks all writes while stalled is set.
+type stallingConn struct {
+	net.PacketConn
+
+	stalled atomic.Bool
+	unstall chan struct{}
+}
+
+func (c *stallingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if c.stalled.Load() {
+		<-c.unstall
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func TestMaxQueuedPackets(t *testing.T) {
+	t.Run("stream data", func(t *testing.T) {
+		testMaxQueuedPackets(t, false)
+	})
+	t.Run("datagrams", func(t *testing.T) {
+		testMaxQueuedPackets(t, true)
+	})
+}
+
+func testMaxQueuedPackets(t *testing.T, useDatagrams bool) {
+	const maxQueuedPackets = 3
+
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(&quic.Config{EnableDatagrams: true}))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientConn := &stallingConn{PacketConn: clientPacketConn, unstall: make(chan struct{})}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(
+			ctx,
+			clientConn,
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableDatagrams: true, MaxQueuedPackets: maxQueuedPackets}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+		str, err := conn.OpenUniStream()
+		require.NoError(t, err)
+		synctest.Wait()
+
+		clientConn.stalled.Store(true)
+		packetsBefore := conn.ConnectionStats().PacketsSent
+
+		var numDatagrams atomic.Int64
+		done := make(chan error, 1)
+		go func() {
+			if useDatagrams {
+				for {
+					if err := conn.SendDatagram(make([]byte, 1000)); err != nil {
+						done <- err
+						return
+					}
+					numDatagrams.Add(1)
+				}
+			}
+			if _, err := str.Write(make([]byte, 1<<20)); err != nil {
+				done <- err
+				return
+			}
+			done <- str.Close()
+		}()
+		synctest.Wait()
+
+		// The first packet is blocked in the write call, all other packets are queued.
+		require.Equal(t, uint64(maxQueuedPackets+1), conn.ConnectionStats().PacketsSent-packetsBefore)
+		select {
+		case err := <-done:
+			t.Fatalf("writer should have been blocked: %v", err)
+		default:
+		}
+		if useDatagrams {
+			t.Logf("sent %d datagrams before blocking", numDatagrams.Load())
+		}
+
+		clientConn.stalled.Store(false)
+		close(clientConn.unstall)
+		if useDatagrams {
+			_, err := serverConn.ReceiveDatagram(ctx)
+			require.NoError(t, err)
+			return
+		}
+		sstr, err := serverConn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(sstr)
+		require.NoError(t, err)
+		require.Len(t, data, 1<<20)
+		require.NoError(t, <-done)
+	})
+}
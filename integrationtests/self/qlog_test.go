@@ -1,7 +1,9 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"sync/atomic"
 	"testing"
 	"testing/synctest"
@@ -136,3 +138,115 @@ func TestQlogHandshakeFailed(t *testing.T) {
 		require.Zero(t, clientTrace.OpenRecorders(), "client recorders should be closed after failed handshake")
 	})
 }
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+	closed chan struct{}
+}
+
+func (b *bufferWriteCloser) Close() error {
+	close(b.closed)
+	return nil
+}
+
+type taggedUserData struct {
+	tenant string
+	user   int
+}
+
+var _ qlogwriter.Tagger = &taggedUserData{}
+
+func (d *taggedUserData) Tags() []qlogwriter.Tag {
+	tags := []qlogwriter.Tag{{Key: "tenant", Value: d.tenant}}
+	if d.user != 0 {
+		tags = append(tags, qlogwriter.Tag{Key: "user", Value: d.user})
+	}
+	return tags
+}
+
+func TestQlogConnectionTags(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		buf := &bufferWriteCloser{closed: make(chan struct{})}
+		ln, err := quic.ListenEarly(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+					fileSeq := qlogwriter.NewConnectionFileSeq(buf, isClient, connID, nil)
+					go fileSeq.Run()
+					return fileSeq
+				},
+			}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		select {
+		case <-serverConn.HandshakeComplete():
+			t.Fatal("handshake already completed")
+		default:
+		}
+		serverConn.SetUserData(&taggedUserData{tenant: "acme"})
+
+		select {
+		case <-serverConn.HandshakeComplete():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		serverConn.SetUserData(&taggedUserData{tenant: "acme", user: 42})
+		require.Equal(t, &taggedUserData{tenant: "acme", user: 42}, serverConn.UserData())
+
+		str, err := serverConn.OpenUniStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		time.Sleep(20 * time.Millisecond) // wait for the data to be acknowledged
+		serverConn.CloseWithError(0, "")
+
+		select {
+		case <-buf.closed:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the qlog to be written")
+		}
+
+		var numUntagged, numTenant, numTenantAndUser int
+		records := bytes.Split(buf.Bytes(), []byte{qlogwriter.RecordSeparator})
+		require.Greater(t, len(records), 2)
+		for _, record := range records[2:] { // skip the empty record and the trace header
+			var ev struct {
+				Tags map[string]any `json:"tags"`
+			}
+			require.NoError(t, json.Unmarshal(record, &ev))
+			switch {
+			case ev.Tags == nil:
+				// events recorded before the first tag was set
+				require.Zero(t, numTenant)
+				require.Zero(t, numTenantAndUser)
+				numUntagged++
+			case len(ev.Tags) == 1:
+				require.Equal(t, map[string]any{"tenant": "acme"}, ev.Tags)
+				require.Zero(t, numTenantAndUser)
+				numTenant++
+			default:
+				require.Equal(t, map[string]any{"tenant": "acme", "user": float64(42)}, ev.Tags)
+				numTenantAndUser++
+			}
+		}
+		t.Logf("untagged: %d, tenant: %d, tenant and user: %d", numUntagged, numTenant, numTenantAndUser)
+		require.NotZero(t, numUntagged)
+		require.NotZero(t, numTenant)
+		require.NotZero(t, numTenantAndUser)
+	})
+}
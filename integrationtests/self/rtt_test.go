@@ -6,6 +6,8 @@ import (
 	"io"
 	"math/rand/v2"
 	"net"
+	"slices"
+	"sync"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -181,3 +183,110 @@ func TestDownloadWithReordering(t *testing.T) {
 		})
 	}
 }
+
+func TestPingRTT(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 50 * time.Millisecond
+		clientConn, serverConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientConn, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+
+		require.Empty(t, conn.PingRTTHistory())
+		for range 3 {
+			conn.SendPing()
+			time.Sleep(time.Second)
+		}
+
+		history := conn.PingRTTHistory()
+		require.Len(t, history, 3)
+		// The peer might delay the acknowledgment by up to max_ack_delay.
+		for _, pingRTT := range history {
+			require.GreaterOrEqual(t, pingRTT, rtt)
+			require.LessOrEqual(t, pingRTT, rtt+protocol.MaxAckDelay)
+		}
+		minRTT, maxRTT, avgRTT := conn.PingRTTStats()
+		require.Equal(t, slices.Min(history), minRTT)
+		require.Equal(t, slices.Max(history), maxRTT)
+		require.GreaterOrEqual(t, avgRTT, minRTT)
+		require.LessOrEqual(t, avgRTT, maxRTT)
+	})
+}
+
+func TestReceivedAckHook(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 20 * time.Millisecond
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		var mx sync.Mutex
+		var acks []*quic.ReceivedAck
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				ReceivedAckHook: func(ack *quic.ReceivedAck) {
+					mx.Lock()
+					acks = append(acks, ack)
+					mx.Unlock()
+				},
+				ReceivedAckHookCopy: true,
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		str, err := conn.OpenUniStream()
+		require.NoError(t, err)
+		_, err = str.Write(PRData)
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+
+		serverStr, err := serverConn.AcceptUniStream(context.Background())
+		require.NoError(t, err)
+		data, err := io.ReadAll(serverStr)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		time.Sleep(rtt) // wait for the final ACKs to arrive
+
+		mx.Lock()
+		defer mx.Unlock()
+		require.NotEmpty(t, acks)
+		encLevels := make(map[protocol.EncryptionLevel]struct{})
+		var numRTTSamples int
+		for _, ack := range acks {
+			encLevels[ack.EncryptionLevel] = struct{}{}
+			require.NotEmpty(t, ack.Ranges)
+			require.GreaterOrEqual(t, ack.Ranges[0].Largest, ack.LargestNewlyAcked)
+			require.False(t, ack.ReceivedAt.IsZero())
+			if ack.RTTSample > 0 {
+				numRTTSamples++
+				require.GreaterOrEqual(t, ack.RTTSample, rtt)
+			}
+		}
+		require.Contains(t, encLevels, protocol.EncryptionInitial)
+		require.Contains(t, encLevels, protocol.Encryption1RTT)
+		require.NotZero(t, numRTTSamples)
+	})
+}
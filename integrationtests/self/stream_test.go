@@ -5,10 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
 
 	"golang.org/x/sync/errgroup"
 
@@ -315,3 +319,341 @@ func TestUnidirectionalStreams(t *testing.T) {
 		client.CloseWithError(0, "")
 	})
 }
+
+func TestStreamFlowControlState(t *testing.T) {
+	ln, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer client.CloseWithError(0, "")
+	serverConn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	request := GeneratePRData(100 << 10)
+	response := GeneratePRData(50 << 10)
+
+	serverStrChan := make(chan *quic.Stream, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		str, err := serverConn.AcceptStream(ctx)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		serverStrChan <- str
+		data, err := io.ReadAll(str)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		if !bytes.Equal(data, request) {
+			errChan <- fmt.Errorf("data mismatch")
+			return
+		}
+		_, err = str.WriteAndClose(response)
+		errChan <- err
+	}()
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	_, err = str.WriteAndClose(request)
+	require.NoError(t, err)
+	data, err := io.ReadAll(str)
+	require.NoError(t, err)
+	require.Equal(t, response, data)
+	require.NoError(t, <-errChan)
+	serverStr := <-serverStrChan
+
+	clientState := str.FlowControlState()
+	serverState := serverStr.FlowControlState()
+	require.Equal(t, uint64(len(request)), clientState.BytesSent)
+	require.Equal(t, uint64(len(request)), serverState.BytesConsumed)
+	require.Equal(t, uint64(len(response)), serverState.BytesSent)
+	require.Equal(t, uint64(len(response)), clientState.BytesConsumed)
+	require.GreaterOrEqual(t, clientState.SendLimit, clientState.BytesSent)
+	require.GreaterOrEqual(t, serverState.SendLimit, serverState.BytesSent)
+	// the limits granted by a peer can't exceed the limits it advertised
+	require.LessOrEqual(t, clientState.SendLimit, serverState.ReceiveLimit)
+	require.LessOrEqual(t, serverState.SendLimit, clientState.ReceiveLimit)
+}
+
+func TestPredictiveStreamLimits(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		require.Zero(t, testPredictiveStreamLimits(t, true))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.NotZero(t, testPredictiveStreamLimits(t, false))
+	})
+}
+
+// testPredictiveStreamLimits opens and closes unidirectional streams at a high rate,
+// and returns the number of STREAMS_BLOCKED frames sent by the client.
+func testPredictiveStreamLimits(t *testing.T, enable bool) (numStreamsBlocked int) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 20 * time.Millisecond
+		const maxStreams = 10
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				MaxIncomingUniStreams:  maxStreams,
+				PredictiveStreamLimits: enable,
+			}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		counter, tracer := newPacketTracer()
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace { return tracer },
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		const numStreams = 500
+		errChan := make(chan error, 1)
+		go func() {
+			for range numStreams {
+				str, err := serverConn.AcceptUniStream(context.Background())
+				if err != nil {
+					errChan <- err
+					return
+				}
+				go func() {
+					if _, err := io.ReadAll(str); err != nil {
+						errChan <- err
+					}
+				}()
+			}
+			errChan <- nil
+		}()
+
+		for i := range numStreams {
+			str, err := conn.OpenUniStreamSync(context.Background())
+			require.NoError(t, err)
+			_, err = str.WriteAndClose([]byte("foobar"))
+			require.NoError(t, err)
+			// First open streams slowly, so the server can estimate the stream close rate.
+			// Then open 15 streams per RTT, which is more than the stream limit allows.
+			if i < 50 {
+				time.Sleep(rtt / 5)
+			} else {
+				time.Sleep(rtt / 15)
+			}
+		}
+		require.NoError(t, <-errChan)
+
+		for _, p := range counter.getSentShortHeaderPackets() {
+			for _, f := range p.frames {
+				if _, ok := f.Frame.(*qlog.StreamsBlockedFrame); ok {
+					numStreamsBlocked++
+				}
+			}
+		}
+		t.Logf("sent %d STREAMS_BLOCKED frames", numStreamsBlocked)
+	})
+	return numStreamsBlocked
+}
+
+func TestMaxUnacceptedStreams(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 20 * time.Millisecond
+		const maxStreams = 10
+		const maxUnaccepted = 3
+		const numStreams = 30
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				MaxIncomingUniStreams: maxStreams,
+				MaxUnacceptedStreams:  maxUnaccepted,
+			}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		counter, tracer := newPacketTracer()
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace { return tracer },
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		errChan := make(chan error, 1)
+		go func() {
+			for i := range numStreams {
+				str, err := conn.OpenUniStreamSync(context.Background())
+				if err != nil {
+					errChan <- err
+					return
+				}
+				if _, err := str.WriteAndClose([]byte(fmt.Sprintf("stream %d", i))); err != nil {
+					errChan <- err
+					return
+				}
+			}
+			errChan <- nil
+		}()
+
+		// The application doesn't accept any streams.
+		// The client can only open the streams allowed by the initial stream limit.
+		time.Sleep(10 * rtt)
+		stats := serverConn.ConnectionStats()
+		require.EqualValues(t, maxStreams, stats.UnacceptedStreams)
+		require.GreaterOrEqual(t, stats.OldestUnacceptedStreamAge, 9*rtt)
+
+		for i := range numStreams {
+			str, err := serverConn.AcceptUniStream(context.Background())
+			require.NoError(t, err)
+			data, err := io.ReadAll(str)
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("stream %d", i), string(data))
+			// Once the streams opened using the initial stream limit are accepted,
+			// the client is only granted new streams when the number of unaccepted streams
+			// drops below the limit.
+			if i >= maxStreams {
+				require.LessOrEqual(t, serverConn.ConnectionStats().UnacceptedStreams, uint64(maxUnaccepted))
+			}
+			time.Sleep(rtt / 2)
+		}
+		require.NoError(t, <-errChan)
+		require.Zero(t, serverConn.ConnectionStats().UnacceptedStreams)
+
+		var numStreamsBlocked int
+		for _, p := range counter.getSentShortHeaderPackets() {
+			for _, f := range p.frames {
+				if _, ok := f.Frame.(*qlog.StreamsBlockedFrame); ok {
+					numStreamsBlocked++
+				}
+			}
+		}
+		require.NotZero(t, numStreamsBlocked)
+	})
+}
+
+func TestStreamEventHook(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		ln, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				InitialStreamReceiveWindow: 2000,
+				MaxStreamReceiveWindow:     2000,
+			}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		var mx sync.Mutex
+		var events []quic.StreamEvent
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				StreamEventHookEnabled: true,
+				StreamEventHook: func(ev quic.StreamEvent) {
+					mx.Lock()
+					events = append(events, ev)
+					mx.Unlock()
+				},
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		data := GeneratePRData(10 * 1000)
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		errChan := make(chan error, 1)
+		go func() {
+			if _, err := str.Write(data); err != nil {
+				errChan <- err
+				return
+			}
+			errChan <- str.Close()
+		}()
+
+		serverStr, err := serverConn.AcceptStream(context.Background())
+		require.NoError(t, err)
+		received, err := io.ReadAll(serverStr)
+		require.NoError(t, err)
+		require.Equal(t, data, received)
+		require.NoError(t, <-errChan)
+		_, err = serverStr.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, serverStr.Close())
+
+		response, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), response)
+		time.Sleep(time.Second) // wait for all ACKs to arrive, so the stream is closed
+
+		mx.Lock()
+		defer mx.Unlock()
+		counts := make(map[quic.StreamEventType]int)
+		var sent, rcvd uint64
+		for _, ev := range events {
+			require.Equal(t, str.StreamID(), ev.StreamID)
+			counts[ev.Type]++
+			switch ev.Type {
+			case quic.StreamEventDataSent:
+				sent = max(sent, ev.Offset+ev.Length)
+			case quic.StreamEventDataReceived:
+				rcvd = max(rcvd, ev.Offset+ev.Length)
+			}
+		}
+		require.Equal(t, 1, counts[quic.StreamEventOpened])
+		require.Equal(t, 1, counts[quic.StreamEventClosed])
+		require.Equal(t, quic.StreamEventOpened, events[0].Type)
+		require.Equal(t, quic.StreamEventClosed, events[len(events)-1].Type)
+		require.EqualValues(t, len(data), sent)
+		require.EqualValues(t, 6, rcvd)
+		// The stream was blocked by the server's flow control limit, and unblocked by MAX_STREAM_DATA frames.
+		// The server doesn't increase the limit once the FIN was received,
+		// so the stream might still be blocked when all data was sent.
+		require.NotZero(t, counts[quic.StreamEventUnblocked])
+		require.GreaterOrEqual(t, counts[quic.StreamEventBlocked], counts[quic.StreamEventUnblocked])
+		require.LessOrEqual(t, counts[quic.StreamEventBlocked], counts[quic.StreamEventUnblocked]+1)
+	})
+}
@@ -159,7 +159,163 @@ func TestIdleTimeout(t *testing.T) {
 	})
 }
 
+func TestSetIdleTimeout(t *testing.T) {
+	t.Run("lowering the idle timeout", func(t *testing.T) {
+		testSetIdleTimeout(t, 5*time.Second, 5*time.Second)
+	})
+	t.Run("raising beyond the negotiated value", func(t *testing.T) {
+		testSetIdleTimeout(t, time.Hour, 20*time.Second)
+	})
+}
+
+func testSetIdleTimeout(t *testing.T, idleTimeout, expected time.Duration) {
+	synctest.Test(t, func(t *testing.T) {
+		var drop atomic.Bool
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLinkWithRouter(t,
+			time.Millisecond,
+			&droppingRouter{Drop: func(p simnet.Packet) bool { return drop.Load() }},
+		)
+		defer closeFn(t)
+
+		server, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true, MaxIdleTimeout: time.Minute}),
+		)
+		require.NoError(t, err)
+		defer server.Close()
+
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			serverPacketConn.LocalAddr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true, MaxIdleTimeout: 20 * time.Second}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := server.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		// wait for the HANDSHAKE_DONE frame and all ACKs to be received
+		time.Sleep(time.Second)
+		drop.Store(true)
+		start := time.Now()
+		conn.SetIdleTimeout(idleTimeout)
+
+		select {
+		case <-conn.Context().Done():
+			took := time.Since(start)
+			require.GreaterOrEqual(t, took, expected-time.Second)
+			require.LessOrEqual(t, took, expected)
+			requireIdleTimeoutError(t, context.Cause(conn.Context()))
+		case <-time.After(time.Minute):
+			t.Fatal("timeout waiting for idle timeout")
+		}
+	})
+}
+
+func TestConnectivityDegraded(t *testing.T) {
+	t.Run("default PTO count", func(t *testing.T) {
+		testConnectivityDegraded(t, 0)
+	})
+	t.Run("custom PTO count", func(t *testing.T) {
+		testConnectivityDegraded(t, 5)
+	})
+}
+
+func testConnectivityDegraded(t *testing.T, ptoCount int) {
+	synctest.Test(t, func(t *testing.T) {
+		const idleTimeout = 20 * time.Second
+
+		var drop atomic.Bool
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLinkWithRouter(t,
+			10*time.Millisecond,
+			&droppingRouter{Drop: func(p simnet.Packet) bool { return drop.Load() }},
+		)
+		defer closeFn(t)
+
+		server, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+		)
+		require.NoError(t, err)
+		defer server.Close()
+
+		type degradation struct {
+			conn *quic.Conn
+			time time.Time
+		}
+		degraded := make(chan degradation, 10)
+		conn, err := quic.Dial(
+			context.Background(),
+			clientPacketConn,
+			serverPacketConn.LocalAddr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery:      true,
+				MaxIdleTimeout:               idleTimeout,
+				OnConnectivityDegraded:       func(c *quic.Conn) { degraded <- degradation{conn: c, time: time.Now()} },
+				ConnectivityDegradedPTOCount: ptoCount,
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := server.Accept(context.Background())
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		// exchange some data, so that the RTT is known
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		sstr, err := serverConn.AcceptStream(context.Background())
+		require.NoError(t, err)
+		_, err = io.ReadFull(sstr, make([]byte, 6))
+		require.NoError(t, err)
+
+		// blackhole the path
+		drop.Store(true)
+		start := time.Now()
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+
+		select {
+		case d := <-degraded:
+			require.Equal(t, conn, d.conn)
+			took := d.time.Sub(start)
+			t.Logf("connectivity degraded after %s (idle timeout: %s)", took, idleTimeout)
+			require.Less(t, took, idleTimeout/4)
+		case <-conn.Context().Done():
+			t.Fatal("connection timed out before connectivity degradation was reported")
+		}
+		require.NoError(t, conn.Context().Err())
+
+		// the callback is only called once while connectivity remains degraded
+		select {
+		case <-degraded:
+			t.Fatal("callback called again")
+		case <-conn.Context().Done():
+			requireIdleTimeoutError(t, context.Cause(conn.Context()))
+		}
+	})
+}
+
 func TestKeepAlive(t *testing.T) {
+	t.Run("per-connection timers", func(t *testing.T) {
+		testKeepAlive(t, false)
+	})
+	t.Run("consolidated timers", func(t *testing.T) {
+		testKeepAlive(t, true)
+	})
+}
+
+func testKeepAlive(t *testing.T, consolidateTimers bool) {
 	synctest.Test(t, func(t *testing.T) {
 		const idleTimeout = 4 * time.Second
 
@@ -170,19 +326,21 @@ func TestKeepAlive(t *testing.T) {
 		)
 		defer closeFn(t)
 
-		server, err := quic.Listen(
-			serverPacketConn,
+		serverTr := &quic.Transport{Conn: serverPacketConn, ConsolidateTimers: consolidateTimers}
+		defer serverTr.Close()
+		server, err := serverTr.Listen(
 			getTLSConfig(),
 			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
 		)
 		require.NoError(t, err)
 		defer server.Close()
 
+		clientTr := &quic.Transport{Conn: clientPacketConn, ConsolidateTimers: consolidateTimers}
+		defer clientTr.Close()
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
-		conn, err := quic.Dial(
+		conn, err := clientTr.Dial(
 			ctx,
-			clientPacketConn,
 			serverPacketConn.LocalAddr(),
 			getTLSClientConfig(),
 			getQuicConfig(&quic.Config{
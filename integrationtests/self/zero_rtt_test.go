@@ -865,6 +865,78 @@ func Test0RTTRejectedWhenDisabled(t *testing.T) {
 	})
 }
 
+func Test0RTTRejectedRetry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 5 * time.Millisecond
+		router := &zeroRTTCountingRouter{Router: &simnet.PerfectRouter{}}
+		clientConn, serverConn, closeFn := newSimnetLinkWithRouter(t, rtt, router)
+		defer closeFn(t)
+
+		tlsConf := getTLSConfig()
+		tr := &quic.Transport{Conn: serverConn}
+		defer tr.Close()
+		ln, err := tr.ListenEarly(tlsConf, getQuicConfig(&quic.Config{Allow0RTT: true}))
+		require.NoError(t, err)
+		clientTLSConf := dialAndReceiveTicket(t, ln, clientConn, nil)
+		require.NoError(t, ln.Close())
+
+		time.Sleep(time.Hour)
+		synctest.Wait()
+
+		ln, err = tr.ListenEarly(tlsConf, getQuicConfig(&quic.Config{Allow0RTT: false}))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		ctr := &quic.Transport{Conn: clientConn}
+		defer ctr.Close()
+		conn, err := ctr.DialEarly(ctx, ln.Addr(), clientTLSConf, getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+
+		// this stream is not retried
+		str1, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str1.Write([]byte("foobar"))
+		require.NoError(t, err)
+		// this stream is retried
+		str2, err := conn.OpenStream()
+		require.NoError(t, err)
+		str2.Enable0RTTRetry()
+		_, err = str2.WriteAndClose(PRData)
+		require.NoError(t, err)
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		require.False(t, sconn.ConnectionState().Used0RTT)
+		// The first stream is implicitly opened by the second stream, and reset.
+		sstr1, err := sconn.AcceptStream(ctx)
+		require.NoError(t, err)
+		require.Equal(t, str1.StreamID(), sstr1.StreamID())
+		_, err = io.ReadAll(sstr1)
+		require.ErrorIs(t, err, &quic.StreamError{StreamID: str1.StreamID(), ErrorCode: 0, Remote: true})
+
+		sstr2, err := sconn.AcceptStream(ctx)
+		require.NoError(t, err)
+		require.Equal(t, str2.StreamID(), sstr2.StreamID())
+		data, err := io.ReadAll(sstr2)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		_, err = sstr2.WriteAndClose([]byte("response"))
+		require.NoError(t, err)
+
+		// the retried stream remains usable
+		data, err = io.ReadAll(str2)
+		require.NoError(t, err)
+		require.Equal(t, []byte("response"), data)
+		require.False(t, conn.ConnectionState().Used0RTT)
+		_, err = str1.Write([]byte("foobar"))
+		require.ErrorIs(t, err, quic.Err0RTTRejected)
+		require.NotZero(t, router.Num0RTTPackets())
+	})
+}
+
 func Test0RTTRejectedOnDatagramsDisabled(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		const rtt = 5 * time.Millisecond
@@ -1152,3 +1224,135 @@ func Test0RTTDatagrams(t *testing.T) {
 		require.Len(t, counter.getRcvd0RTTPacketNumbers(), 1)
 	})
 }
+
+func Test0RTTDatagramsRejected(t *testing.T) {
+	t.Run("datagrams still supported", func(t *testing.T) {
+		test0RTTDatagramsRejected(t, true)
+	})
+	t.Run("datagrams no longer supported", func(t *testing.T) {
+		test0RTTDatagramsRejected(t, false)
+	})
+}
+
+func test0RTTDatagramsRejected(t *testing.T, serverEnableDatagrams bool) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 5 * time.Millisecond
+		router := &zeroRTTCountingRouter{Router: &simnet.PerfectRouter{}}
+		clientConn, serverConn, closeFn := newSimnetLinkWithRouter(t, rtt, router)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverConn}
+		defer tr.Close()
+		ln, err := tr.ListenEarly(getTLSConfig(), getQuicConfig(&quic.Config{Allow0RTT: true, EnableDatagrams: true}))
+		require.NoError(t, err)
+		clientTLSConf := dialAndReceiveTicket(t, ln, clientConn, nil)
+		require.NoError(t, ln.Close())
+
+		// If datagrams are still supported, 0-RTT is rejected because the server disabled it.
+		// Otherwise, 0-RTT is rejected because the server disabled datagram support.
+		ln, err = tr.ListenEarly(
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{Allow0RTT: !serverEnableDatagrams, EnableDatagrams: serverEnableDatagrams}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.DialEarly(ctx,
+			clientConn,
+			ln.Addr(),
+			clientTLSConf,
+			getQuicConfig(&quic.Config{EnableDatagrams: true}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		// the remembered transport parameters allow sending of datagrams
+		require.True(t, conn.ConnectionState().SupportsDatagrams.Remote)
+		require.NoError(t, conn.SendDatagram([]byte("0-RTT datagram")))
+
+		select {
+		case <-conn.HandshakeComplete():
+		case <-time.After(time.Second):
+			t.Fatal("handshake did not complete in time")
+		}
+		require.False(t, conn.ConnectionState().Used0RTT)
+		require.NotZero(t, router.Num0RTTPackets())
+		require.Equal(t, uint64(1), conn.ConnectionStats().Datagrams0RTTDropped)
+		require.ErrorIs(t, conn.SendDatagram([]byte("foobar")), quic.Err0RTTRejected)
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+		require.False(t, sconn.ConnectionState().Used0RTT)
+
+		nextConn, err := conn.NextConnection(ctx)
+		require.NoError(t, err)
+		require.Equal(t, serverEnableDatagrams, nextConn.ConnectionState().SupportsDatagrams.Remote)
+		if !serverEnableDatagrams {
+			require.EqualError(t, nextConn.SendDatagram([]byte("foobar")), "datagram support disabled")
+			return
+		}
+		require.NoError(t, nextConn.SendDatagram([]byte("1-RTT datagram")))
+		rcvdMsg, err := sconn.ReceiveDatagram(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte("1-RTT datagram"), rcvdMsg)
+	})
+}
+
+func Test0RTTSharedSessionTicketKeys(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n := &simnet.Simnet{Router: &simnet.PerfectRouter{}}
+		settings := simnet.NodeBiDiLinkSettings{Latency: 5 * time.Millisecond}
+		clientConn := n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001}, settings)
+		serverConn1 := n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002}, settings)
+		serverConn2 := n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.3"), Port: 9003}, settings)
+		require.NoError(t, n.Start())
+		defer func() {
+			require.NoError(t, clientConn.Close())
+			require.NoError(t, serverConn1.Close())
+			require.NoError(t, serverConn2.Close())
+			require.NoError(t, n.Close())
+		}()
+
+		key1 := quic.SessionTicketKey{ID: 1, Key: [32]byte{1}}
+		key2 := quic.SessionTicketKey{ID: 2, Key: [32]byte{2}}
+
+		// The two servers share the same keys, but don't share any other state.
+		var mutex sync.Mutex
+		var events2 []quic.SessionTicketEvent
+		ring1 := &quic.SessionTicketKeyRing{}
+		ring2 := &quic.SessionTicketKeyRing{
+			OnEvent: func(e quic.SessionTicketEvent) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				events2 = append(events2, e)
+			},
+		}
+		listen := func(conn net.PacketConn, ring *quic.SessionTicketKeyRing) *quic.EarlyListener {
+			require.NoError(t, ring.SetKeys([]quic.SessionTicketKey{key1}))
+			tr := &quic.Transport{Conn: conn}
+			t.Cleanup(func() { tr.Close() })
+			ln, err := tr.ListenEarly(ring.ConfigureTLSConfig(getTLSConfig()), getQuicConfig(&quic.Config{Allow0RTT: true}))
+			require.NoError(t, err)
+			t.Cleanup(func() { ln.Close() })
+			return ln
+		}
+		ln1 := listen(serverConn1, ring1)
+		ln2 := listen(serverConn2, ring2)
+
+		clientTLSConf := dialAndReceiveTicket(t, ln1, clientConn, nil)
+
+		// Rotate the keys on both servers, while keeping the old key for decryption.
+		require.NoError(t, ring1.SetKeys([]quic.SessionTicketKey{key2, key1}))
+		require.NoError(t, ring2.SetKeys([]quic.SessionTicketKey{key2, key1}))
+
+		// The ticket issued by the first server is accepted by the second server.
+		transfer0RTTData(t, ln2, clientConn, clientTLSConf, getQuicConfig(nil), PRData)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		require.Contains(t, events2, quic.SessionTicketEvent{Type: quic.SessionTicketAccepted, KeyID: 1, Stale: true})
+		require.Contains(t, events2, quic.SessionTicketEvent{Type: quic.SessionTicketIssued, KeyID: 2})
+	})
+}
@@ -151,6 +151,35 @@ func TestClientSupportsMoreVersionsThanServer(t *testing.T) {
 	)
 }
 
+func TestNegotiatedVersion2(t *testing.T) {
+	// The server only supports QUIC v2, while the client prefers QUIC v1.
+	server, err := quic.ListenAddr("localhost:0", getTLSConfig(), &quic.Config{Versions: []protocol.Version{quic.Version2}})
+	require.NoError(t, err)
+	defer server.Close()
+
+	var clientEventTracer events.Recorder
+	conn, err := quic.DialAddr(
+		context.Background(),
+		fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+		getTLSClientConfig(),
+		maybeAddQLOGTracer(&quic.Config{
+			Versions: []protocol.Version{quic.Version1, quic.Version2},
+			Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace {
+				return &events.Trace{Recorder: &clientEventTracer}
+			},
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	require.Len(t, clientEventTracer.Events(qlog.VersionNegotiationReceived{}), 1)
+	require.Equal(t, quic.Version2, conn.ConnectionState().Version)
+
+	sconn, err := server.Accept(context.Background())
+	require.NoError(t, err)
+	defer sconn.CloseWithError(0, "")
+	require.Equal(t, quic.Version2, sconn.ConnectionState().Version)
+}
+
 func TestServerDisablesVersionNegotiation(t *testing.T) {
 	// The server doesn't support the highest supported version, which is the first one the client will try,
 	// but it supports a bunch of versions that the client doesn't speak
@@ -102,6 +102,59 @@ const (
 	CUBIC
 )
 
+func (a CongestionControlAlgorithm) String() string {
+	switch a {
+	case NewReno:
+		return "NewReno"
+	case CUBIC:
+		return "CUBIC"
+	default:
+		return "unknown congestion control algorithm"
+	}
+}
+
+func (a CongestionControlAlgorithm) valid() bool {
+	return a == NewReno || a == CUBIC
+}
+
+// OutOfOrderBufferOverflowAction is the action taken when a STREAM frame would exceed
+// the limit configured by Config.MaxStreamOutOfOrderBuffer.
+type OutOfOrderBufferOverflowAction int
+
+const (
+	// OutOfOrderBufferOverflowDrop drops the packet containing the STREAM frame.
+	// The packet is not acknowledged, and the peer will retransmit the data.
+	// None of the other frames in the packet are processed either.
+	OutOfOrderBufferOverflowDrop OutOfOrderBufferOverflowAction = iota
+	// OutOfOrderBufferOverflowReset cancels reading from the stream,
+	// using the error code configured by Config.StreamOutOfOrderBufferErrorCode.
+	OutOfOrderBufferOverflowReset
+)
+
+// UnacceptedStreamsOverflowAction is the action taken when the peer opens more streams
+// than allowed by Config.MaxUnacceptedStreams.
+type UnacceptedStreamsOverflowAction int
+
+const (
+	// UnacceptedStreamsOverflowQueue queues the streams until they are accepted by the application.
+	UnacceptedStreamsOverflowQueue UnacceptedStreamsOverflowAction = iota
+	// UnacceptedStreamsOverflowReset resets the streams, using the error code configured by
+	// Config.UnacceptedStreamsErrorCode. These streams are never returned by AcceptStream or AcceptUniStream.
+	UnacceptedStreamsOverflowReset
+)
+
+// PeerMigrationAction is the action taken when the client migrated the connection to a new address,
+// see Config.VerifyPeerMigration.
+type PeerMigrationAction int
+
+const (
+	// PeerMigrationAccept accepts the migration.
+	PeerMigrationAccept PeerMigrationAction = iota
+	// PeerMigrationRevalidate accepts the migration, but marks the connection as requiring
+	// revalidation of the application state, see Conn.RequiresRevalidation.
+	PeerMigrationRevalidate
+)
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// GetConfigForClient is called for incoming connections.
@@ -121,17 +174,46 @@ type Config struct {
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// OnConnectivityDegraded is called when the path to the peer appears to be blackholed,
+	// i.e. when ConnectivityDegradedPTOCount consecutive probe timeouts (PTOs) fired without
+	// receiving an acknowledgement.
+	// This usually happens long before the idle timeout expires, allowing the application to
+	// react early, for example by migrating the connection to a different path, or by closing it.
+	// It is called again if connectivity degrades again after an acknowledgement was received.
+	// The callback is run on a separate Go routine, and is passed the affected connection.
+	OnConnectivityDegraded func(*Conn)
+	// ConnectivityDegradedPTOCount is the number of consecutive PTOs without receiving an
+	// acknowledgement after which OnConnectivityDegraded is called.
+	// If this value is zero, it defaults to 3.
+	ConnectivityDegradedPTOCount int
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
 	// otherwise the token is associated with the server's IP address.
 	TokenStore TokenStore
+	// DisableNewTokens disables sending of NEW_TOKEN frames after completion of the handshake.
+	// Clients use these tokens to skip address validation on future connection attempts,
+	// see section 8.1.3 of RFC 9000. Tokens are encrypted using the Transport's TokenGeneratorKey.
+	// Only valid for the server.
+	DisableNewTokens bool
 	// InitialStreamReceiveWindow is the initial size of the stream-level flow control window for receiving data.
 	// If the application is consuming data quickly enough, the flow control auto-tuning algorithm
 	// will increase the window up to MaxStreamReceiveWindow.
 	// If this value is zero, it will default to 512 KB.
 	// Values larger than the maximum varint (quicvarint.Max) will be clipped to that value.
 	InitialStreamReceiveWindow uint64
+	// InitialStreamReceiveWindowBidiLocal is the initial size of the stream-level flow control window
+	// for bidirectional streams opened by this endpoint (initial_max_stream_data_bidi_local).
+	// If this value is zero, InitialStreamReceiveWindow is used.
+	InitialStreamReceiveWindowBidiLocal uint64
+	// InitialStreamReceiveWindowBidiRemote is the initial size of the stream-level flow control window
+	// for bidirectional streams opened by the peer (initial_max_stream_data_bidi_remote).
+	// If this value is zero, InitialStreamReceiveWindow is used.
+	InitialStreamReceiveWindowBidiRemote uint64
+	// InitialStreamReceiveWindowUni is the initial size of the stream-level flow control window
+	// for unidirectional streams opened by the peer (initial_max_stream_data_uni).
+	// If this value is zero, InitialStreamReceiveWindow is used.
+	InitialStreamReceiveWindowUni uint64
 	// MaxStreamReceiveWindow is the maximum stream-level flow control window for receiving data.
 	// If this value is zero, it will default to 6 MB.
 	// Values larger than the maximum varint (quicvarint.Max) will be clipped to that value.
@@ -153,6 +235,68 @@ type Config struct {
 	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
 	// in this callback.
 	AllowConnectionWindowIncrease func(conn *Conn, delta uint64) bool
+	// EnableReceiveWindowDonation enables sharing of the connection-level receive window between streams.
+	// This is useful for workloads where one stream at a time is bursting, while the other streams are idle.
+	// Stream-level windows then start at their initial size, and are only increased by auto-tuning
+	// by borrowing from the connection-level window, such that the increases of all streams combined
+	// never exceed the connection-level window. A stream returns the borrowed window when it stops
+	// consuming data quickly, and once it has been read completely or reading was canceled.
+	// In this mode, InitialStreamReceiveWindow should be kept small,
+	// since it is the window that each stream is guaranteed to receive.
+	EnableReceiveWindowDonation bool
+	// MaxSendBufferPerStream is the maximum amount of data on a single stream that has been sent,
+	// but not yet acknowledged by the peer.
+	// When the limit is reached, Write on the stream blocks until the amount of unacknowledged
+	// data has dropped below 25% of the limit, applying backpressure to the application.
+	// If this value is zero, the amount of data is only limited by flow control.
+	MaxSendBufferPerStream uint64
+	// SendBufferMemoryPressureHook returns the current memory pressure, as a factor between 0 and 1.
+	// It is called periodically (at most every 100ms) while the connection is sending data.
+	// If the pressure is above 0.8, the MaxSendBufferPerStream limit is halved for all streams.
+	// If the pressure is above 0.95, the limit is quartered, and the receive windows of new streams
+	// are not increased beyond their initial size.
+	// Data that has already been sent is not discarded, since it might need to be retransmitted:
+	// the send buffers drain as the peer acknowledges the data.
+	// If not set, and MaxSendBufferPerStream is set, the ratio of runtime.MemStats.HeapInuse
+	// and runtime.MemStats.HeapSys is used, updated at most once per second.
+	// If neither is set, memory pressure is not monitored.
+	SendBufferMemoryPressureHook func() float64
+	// MaxStreamsPerPacket limits the number of streams that STREAM frames are packed for in a single packet.
+	// When many streams have small amounts of data to send, this avoids packets containing a large number
+	// of tiny STREAM frames, which can be costly to process for constrained peers.
+	// The packet is then filled from fewer streams, while all streams are still served in a round-robin fashion.
+	// If this value is zero, the number of streams per packet is not limited.
+	MaxStreamsPerPacket int
+	// PrioritizeRetransmissions makes the connection send all lost STREAM data before sending new STREAM data.
+	// By default, retransmissions are scheduled alongside new data, with every stream being served in a
+	// round-robin fashion. For latency-critical applications, this means that lost data can be delayed
+	// by new data written to other streams.
+	PrioritizeRetransmissions bool
+	// MaxStreamOutOfOrderBuffer is the maximum amount of data on a single stream that is buffered
+	// beyond a gap in the received data, and therefore can't be read by the application yet.
+	// If this value is zero, the amount of data is only limited by flow control.
+	MaxStreamOutOfOrderBuffer uint64
+	// StreamOutOfOrderBufferOverflow is the action taken when a STREAM frame would exceed MaxStreamOutOfOrderBuffer.
+	// By default, the packet containing the frame is dropped.
+	StreamOutOfOrderBufferOverflow OutOfOrderBufferOverflowAction
+	// StreamOutOfOrderBufferErrorCode is the error code used to cancel reading from a stream
+	// when StreamOutOfOrderBufferOverflow is set to OutOfOrderBufferOverflowReset.
+	StreamOutOfOrderBufferErrorCode StreamErrorCode
+	// MaxUnacceptedStreams is the maximum number of incoming streams (of each type) that are
+	// waiting to be accepted by the application.
+	// While this many streams are waiting, the peer isn't granted any additional streams,
+	// and will be blocked on the stream limit until the application accepts a stream.
+	// The initial stream limits (MaxIncomingStreams and MaxIncomingUniStreams) are not affected,
+	// so the peer might open more streams before the application accepts the first one.
+	// UnacceptedStreamsOverflow configures how the streams exceeding this limit are handled.
+	// If this value is zero, the number of unaccepted streams is only limited by the stream limits.
+	MaxUnacceptedStreams int64
+	// UnacceptedStreamsOverflow is the action taken on streams exceeding MaxUnacceptedStreams.
+	// By default, these streams are queued until accepted.
+	UnacceptedStreamsOverflow UnacceptedStreamsOverflowAction
+	// UnacceptedStreamsErrorCode is the error code used to reset streams
+	// when UnacceptedStreamsOverflow is set to UnacceptedStreamsOverflowReset.
+	UnacceptedStreamsErrorCode StreamErrorCode
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -163,6 +307,14 @@ type Config struct {
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	// Values larger than 2^60 will be clipped to that value.
 	MaxIncomingUniStreams int64
+	// PredictiveStreamLimits makes quic-go increase the peer's stream limits proactively.
+	// The rate at which the peer's streams are closed is estimated, and the peer is allowed
+	// to open the streams that are expected to be closed within the next RTT right away,
+	// instead of waiting for the MAX_STREAMS frame.
+	// This avoids stalls for applications that open and close streams at a high rate,
+	// at the cost of allowing the peer to temporarily exceed MaxIncomingStreams and
+	// MaxIncomingUniStreams (by up to a factor of 2).
+	PredictiveStreamLimits bool
 	// KeepAlivePeriod defines whether this peer will periodically send a packet to keep the connection alive.
 	// If set to 0, then no keep alive is sent. Otherwise, the keep alive is sent on that period (or at most
 	// every half of MaxIdleTimeout, whichever is smaller).
@@ -173,6 +325,13 @@ type Config struct {
 	// If set too high, the path might not support packets of that size, leading to a timeout of the QUIC handshake.
 	// Values below 1200 are invalid.
 	InitialPacketSize uint16
+	// CoalescedInitialPadding changes how datagrams containing Initial packets are expanded to the
+	// size required by RFC 9000 (Section 14.1).
+	// By default, PADDING frames are added to the Initial packet.
+	// If set, and other packets are coalesced with the Initial packet, the padding is added to the
+	// last packet in the datagram instead.
+	// Either way, these datagrams are at least InitialPacketSize (and never less than 1200) bytes large.
+	CoalescedInitialPadding bool
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
 	// This allows the sending of QUIC packets that fully utilize the available MTU of the path.
 	// Path MTU discovery is only available on systems that allow setting of the Don't Fragment (DF) bit.
@@ -185,9 +344,169 @@ type Config struct {
 	// Enable QUIC Stream Resets with Partial Delivery.
 	// See https://datatracker.ietf.org/doc/html/draft-ietf-quic-reliable-stream-reset-07.
 	EnableStreamResetPartialDelivery bool
+	// EnableParallelDecryption enables concurrent decryption of 1-RTT packets after the handshake has been confirmed.
+	// This can increase the throughput of a single connection at very high bandwidths,
+	// when decrypting packets on a single core becomes the bottleneck.
+	// Frames are still processed serially, in the order the packets were received.
+	EnableParallelDecryption bool
+	// ReceivePacketBudget is the maximum number of received packets that the connection processes
+	// before sending packets and checking its timers.
+	// This prevents a flood of incoming packets from delaying outgoing packets.
+	// If not set, it defaults to 32.
+	ReceivePacketBudget int
+	// SendPacketBudget is the maximum number of packets (or batches of packets, when using GSO)
+	// that the connection sends before processing received packets and checking its timers.
+	// The connection only interrupts sending if received packets are waiting to be processed.
+	// At least one packet is sent before received packets are processed.
+	// This prevents sending under high write pressure from delaying the processing of ACKs.
+	// If not set, it defaults to 8.
+	SendPacketBudget int
+	// MaxQueuedPackets is the maximum number of packets (or batches of packets, when using GSO)
+	// that have been packed, but not yet written to the underlying connection.
+	// Once this limit is reached, no new packets are packed, which applies backpressure
+	// to calls to SendStream.Write and Conn.SendDatagram.
+	// If not set, it defaults to 8.
+	MaxQueuedPackets int
+	// CoalesceAcks avoids sending ACK-only packets where possible.
+	// By default, an ACK is sent as soon as two ack-eliciting packets have been received.
+	// With this option, the ACK is delayed until the next packet carrying data is sent,
+	// but at most by the max_ack_delay (25ms).
+	// ACKs are still sent immediately when packet loss or reordering is detected.
+	// This is useful on asymmetric links, where the uplink is the scarce resource.
+	// Note that delaying ACKs slows down loss recovery and congestion window growth of the peer.
+	CoalesceAcks bool
+	// MinimizeAckDelay acknowledges every ack-eliciting packet immediately,
+	// instead of acknowledging every second packet (or fewer, on high-bandwidth connections).
+	// This minimizes the ack delay reported to the peer, which is useful for latency-critical
+	// applications, for example when estimating one-way delays, at the cost of sending more ACKs.
+	// It takes precedence over CoalesceAcks.
+	MinimizeAckDelay bool
+	// StrictPathValidation requires PATH_RESPONSE frames to be received on the path that
+	// the corresponding PATH_CHALLENGE frame was sent on.
+	// RFC 9000 allows a PATH_RESPONSE received on any path to validate the challenged path.
+	// Enabling this option prevents an attacker who can observe PATH_CHALLENGE frames
+	// from validating a path using a PATH_RESPONSE sent from a different address.
+	// Only valid for the server.
+	StrictPathValidation bool
+	// InitialPacketIPTolerance controls which Initial packets are accepted for a connection
+	// if they are received from a different IP address than the one the connection was started from.
+	// Such packets might have been replayed by an attacker.
+	// If zero, the IP addresses need to be identical (the port may differ).
+	// Otherwise, it is the number of trailing bytes of the IP address that are allowed to differ.
+	// For example, setting it to 1 allows the last octet of an IPv4 address to change,
+	// which can happen when a NAT rebinds during the handshake.
+	// Only valid for the server.
+	InitialPacketIPTolerance int
+	// HandshakeQueueDepth is the maximum number of received packets that the server buffers
+	// before processing them. When the queue is full, the oldest packet is dropped.
+	// If not set, it defaults to 4096.
+	// Only valid for the server.
+	HandshakeQueueDepth int
+	// HandshakeQueueStrategy determines the order in which the buffered packets are processed.
+	// By default, packets are processed in the order they were received.
+	// Only valid for the server.
+	HandshakeQueueStrategy HandshakeQueueStrategy
+	// DisablePeerMigration asks the client not to migrate the connection to a different address,
+	// using the disable_active_migration transport parameter.
+	// If the client's address changes anyway (this includes NAT rebindings), packets received
+	// from the new address are dropped, and the connection stays on the current path (see section 9 of RFC 9000).
+	// Since these packets are not acknowledged, the connection might eventually time out.
+	// Only valid for the server.
+	DisablePeerMigration bool
+	// PreferredAddress is advertised to the client in the preferred_address transport parameter.
+	// After the handshake is confirmed, the client validates the path to the preferred address,
+	// and migrates the connection to it (see section 9.6 of RFC 9000).
+	// If path validation fails, the client continues using the address it connected to.
+	// Packets sent to the preferred address must be delivered to the same Transport, for example by binding
+	// its socket to the unspecified address, or by forwarding them on the network.
+	// Only valid for the server.
+	PreferredAddress *net.UDPAddr
+	// VerifyPeerMigration is called when the client migrated the connection to a new address,
+	// after the new path has been validated.
+	// If it returns an error, the connection is closed. Returning an *ApplicationError
+	// closes the connection with the application error code and message.
+	// To avoid deadlocks, it is not valid to call blocking functions on the connection,
+	// such as CloseWithError, in this callback.
+	// Only valid for the server.
+	VerifyPeerMigration func(conn *Conn, oldAddr, newAddr net.Addr) (PeerMigrationAction, error)
+	// MaxPathChallengeRetransmissions is the maximum number of times a PATH_CHALLENGE is retransmitted
+	// when probing a path (see Path.Probe), including the path to the server's preferred address.
+	// If no PATH_RESPONSE is received after the last retransmission, path validation fails
+	// with ErrPathValidationFailed.
+	// If not set, PATH_CHALLENGEs are retransmitted until the context passed to Path.Probe is canceled.
+	// Only valid for the client.
+	MaxPathChallengeRetransmissions int
+	// MaxIssuedConnectionIDs limits the total number of connection IDs issued to the peer
+	// in NEW_CONNECTION_ID frames over the lifetime of the connection.
+	// Every connection ID retired by the peer is usually replaced by a new one.
+	// Once the limit is reached, retired connection IDs are not replaced anymore,
+	// and the peer keeps using the connection IDs that are still active.
+	// If not set, the number of issued connection IDs is not limited.
+	MaxIssuedConnectionIDs uint64
+	// ConnectionCloseRetransmitInterval is the minimum interval between two retransmissions of the
+	// CONNECTION_CLOSE packet after the connection was closed locally.
+	// The packet is retransmitted in response to packets received while the connection is draining,
+	// with an exponential backoff (on the 1st, 2nd, 4th, 8th, ... packet received).
+	// Setting an interval additionally limits these retransmissions to at most one per interval,
+	// which bounds the number of packets sent in response to a flood of incoming packets.
+	// If zero, only the exponential backoff applies.
+	ConnectionCloseRetransmitInterval time.Duration
+	// StrictMode enables a pedantic validation of the frames received from the peer,
+	// intended for conformance testing:
+	// Frame types that are not minimally encoded close the connection with a PROTOCOL_VIOLATION,
+	// and transport errors caused by a frame carry the type of that frame.
+	// Violations are recorded as qlog.ProtocolViolation events.
+	StrictMode bool
+	// ErrorSpaces are the error spaces registered by the application.
+	// The names of the error spaces that the error codes of RESET_STREAM, STOP_SENDING and
+	// CONNECTION_CLOSE frames belong to are included in qlog.
+	ErrorSpaces *ErrorSpaces
+	// KeepReceiveBuffersOnClose keeps stream data that was received, but not yet read by the application,
+	// readable after the connection is closed.
+	// Reads then return the buffered data first, followed by the error that closed the connection.
+	// By default, reads fail immediately once the connection is closed, and the buffered data is discarded.
+	KeepReceiveBuffersOnClose bool
+	// IgnoreLateStreamResets ignores RESET_STREAM frames received after all stream data (including the FIN)
+	// was received. The application then reads all data, followed by io.EOF, and the reset is reported by Stream.Stats.
+	// Likewise, STOP_SENDING frames received after all stream data was acknowledged are ignored.
+	// RFC 9000 allows both behaviors. By default, a late RESET_STREAM frame interrupts the delivery of stream data.
+	IgnoreLateStreamResets bool
 	// CongestionControl is the congestion control algorithm to use.
 	// If not set, it defaults to NewReno.
 	CongestionControl CongestionControlAlgorithm
+	// CongestionControlSwitch allows switching the congestion controller of long-lived connections,
+	// for example from a slow-start-friendly algorithm to a more steady-state one.
+	// Once the handshake has completed, it is called every 30 seconds with the age of the connection
+	// and the current connection statistics. If it returns true, the connection switches to the
+	// returned algorithm. The new congestion controller continues with the current congestion window.
+	// Unknown algorithms are ignored.
+	// It is called synchronously from the connection's run loop, and must not block.
+	CongestionControlSwitch func(age time.Duration, stats ConnectionStats) (CongestionControlAlgorithm, bool)
+	// EnableRuntimeTrace annotates the connection for Go's execution tracer (see runtime/trace).
+	// A task is created for every connection, and the congestion window is logged on every update.
+	// The slow start, congestion avoidance and recovery phases are marked as user regions.
+	// This only has an effect while an execution trace is being collected, e.g. using trace.Start.
+	EnableRuntimeTrace bool
+	// StreamEventHook is called for stream-level events: when streams are opened and closed,
+	// when STREAM frames are sent and received, and when a stream is blocked by flow control.
+	// It is a lightweight alternative to qlog, for applications that don't need packet-level traces.
+	// The hook is only called if StreamEventHookEnabled is set.
+	// It is called synchronously, from the connection's run loop as well as from the goroutines
+	// calling methods on the connection or on its streams. It must be safe for concurrent use,
+	// it must not block, and it must not call any methods on the connection or its streams.
+	StreamEventHook func(event StreamEvent)
+	// StreamEventHookEnabled enables the StreamEventHook.
+	StreamEventHookEnabled bool
+	// ReceivedAckHook is called for every ACK frame received, after it was processed by the loss detection.
+	// It is a lightweight alternative to qlog, for applications that analyze acknowledgments.
+	// The *ReceivedAck is borrowed: it is reused for the next ACK frame, including the Ranges slice,
+	// and must not be retained after the hook returns. Set ReceivedAckHookCopy to obtain a fresh copy
+	// for every ACK frame, at the cost of allocations.
+	// The hook is called synchronously from the connection's run loop. It must not block,
+	// and it must not call any methods on the connection or its streams.
+	ReceivedAckHook func(ack *ReceivedAck)
+	// ReceivedAckHookCopy makes the ReceivedAckHook receive a copy of the ACK frame, which it may retain.
+	ReceivedAckHookCopy bool
 
 	Tracer func(ctx context.Context, isClient bool, connID ConnectionID) qlogwriter.Trace
 }
@@ -203,6 +522,55 @@ type ClientInfo struct {
 	AddrVerified bool
 }
 
+// UnreadStreamData describes the data on a receive stream that the application hadn't read
+// when the connection was closed.
+type UnreadStreamData struct {
+	StreamID StreamID
+	// UnreadBytes is the number of bytes that were received, but not read by the application.
+	// This includes data received out of order, i.e. data beyond ContiguousOffset.
+	UnreadBytes uint64
+	// ContiguousOffset is the offset up to which stream data was received without any gaps.
+	ContiguousOffset uint64
+}
+
+// AckLatencyHint tells the receiver how quickly data received on a stream needs to be acknowledged.
+// See ReceiveStream.SetAckLatencyHint.
+type AckLatencyHint uint8
+
+const (
+	// AckLatencyDefault acknowledges stream data as quickly as data on any other stream.
+	AckLatencyDefault AckLatencyHint = iota
+	// AckLatencyTolerant is used for streams that don't need to be acknowledged quickly, e.g. bulk transfers.
+	// As long as all packets received since the last ACK only carry data for such streams,
+	// the ACK is delayed by up to max_ack_delay, instead of being sent after every other packet.
+	// This reduces the number of ACK packets sent, e.g. to save power on mobile devices.
+	AckLatencyTolerant
+)
+
+// StreamStats contains statistics about the receive side of a stream.
+type StreamStats struct {
+	// BufferedBytes is the number of bytes that were received, but not yet read by the application.
+	// This includes data received out of order.
+	BufferedBytes uint64
+	// OutOfOrderBytes is the number of bytes buffered beyond a gap in the received data.
+	OutOfOrderBytes uint64
+	// IgnoredReset is the error of a RESET_STREAM frame that was received after all stream data
+	// had been received, and was therefore ignored. See Config.IgnoreLateStreamResets.
+	IgnoredReset *StreamError
+}
+
+// FlowControlState contains the flow control offsets of a stream.
+type FlowControlState struct {
+	// SendLimit is the offset up to which the peer allows us to send data on the stream.
+	SendLimit uint64
+	// BytesSent is the number of bytes sent on the stream.
+	BytesSent uint64
+	// ReceiveLimit is the offset up to which we allow the peer to send data on the stream.
+	ReceiveLimit uint64
+	// BytesConsumed is the number of bytes that were read by the application.
+	BytesConsumed uint64
+}
+
 // ConnectionState records basic details about a QUIC connection.
 type ConnectionState struct {
 	// TLS contains information about the TLS connection state, incl. the tls.ConnectionState.
@@ -219,10 +587,82 @@ type ConnectionState struct {
 		// Local is true if support was enabled via Config.EnableStreamResetPartialDelivery.
 		Remote, Local bool
 	}
+	// HandshakeComplete says if the handshake has completed, see [Conn.HandshakeComplete].
+	HandshakeComplete bool
+	// HandshakeConfirmed says if the handshake has been confirmed, see [Conn.HandshakeConfirmed].
+	HandshakeConfirmed bool
 	// Used0RTT says if 0-RTT resumption was used.
 	Used0RTT bool
 	// Version is the QUIC version of the QUIC connection.
+	// If version negotiation was performed, this is the negotiated version,
+	// which is not necessarily the first version in Config.Versions.
 	Version Version
 	// GSO says if generic segmentation offload is used.
 	GSO bool
+	// AddressValidation contains information about the address validation performed during the handshake.
+	// AddressValidation.UsedRetry says if the server performed a Retry, adding one round trip to the handshake.
+	AddressValidation AddressValidationInfo
+	// OriginalDestinationConnectionID is the Destination Connection ID of the client's first Initial packet.
+	// It is set whether or not a Retry was performed: a Retry doesn't change it,
+	// and it is the same on the client and the server side.
+	// qlog traces are grouped by this connection ID.
+	OriginalDestinationConnectionID ConnectionID
+	// SmoothedRTT is the current smoothed RTT estimate of the active network path.
+	// See https://www.rfc-editor.org/rfc/rfc9002#section-5.3
+	SmoothedRTT time.Duration
+	// RTTVariance is the current estimate of the variation in the RTT samples (rttvar in RFC 9002).
+	RTTVariance time.Duration
+	// LatestRTT is the last RTT sample observed on the active network path.
+	LatestRTT time.Duration
+	// MinRTT is the minimum RTT observed on the active network path.
+	MinRTT time.Duration
+	// CongestionLimited says if the connection is currently limited by the congestion window,
+	// i.e. it would send more data if the congestion controller allowed it.
+	// It is updated whenever packets are acknowledged. This is the opposite of being application limited.
+	CongestionLimited bool
+	// KeyExchange is the name of the key exchange group negotiated during the handshake,
+	// for example "X25519MLKEM768" or "X25519".
+	// It is empty if the key exchange hasn't completed yet.
+	KeyExchange string
+	// PostQuantumKeyExchange says if the negotiated key exchange group is a hybrid
+	// post-quantum group, for example X25519MLKEM768.
+	PostQuantumKeyExchange bool
+	// Streams contains the number of streams opened on this connection, by either endpoint.
+	Streams StreamCounts
+}
+
+// StreamCounts contains the number of streams of a connection.
+// A stream is counted as active from the moment it is opened (or the peer's first frame for it is received),
+// until it has been completed: all data was sent and acknowledged (or the stream was reset),
+// and all data was received and read by the application (or reading was canceled).
+type StreamCounts struct {
+	// ActiveBidi is the number of bidirectional streams that are currently active.
+	ActiveBidi uint64
+	// ActiveUni is the number of unidirectional streams that are currently active.
+	ActiveUni uint64
+	// TotalBidi is the number of bidirectional streams opened since the connection was established.
+	TotalBidi uint64
+	// TotalUni is the number of unidirectional streams opened since the connection was established.
+	TotalUni uint64
+}
+
+// AddressValidationInfo contains information about the address validation performed during the handshake.
+type AddressValidationInfo struct {
+	// UsedRetry says if the client's address was validated using a Retry.
+	// On the client side, this means that a Retry packet was received and processed.
+	UsedRetry bool
+	// UsedNewToken says if the client presented a valid token that it received in a NEW_TOKEN frame
+	// on a previous connection. Only set on the server side.
+	UsedNewToken bool
+	// RetryLatency is the time the Retry added to the handshake,
+	// measured from receiving the client's first Initial packet (and sending the Retry)
+	// until receiving the first Initial packet carrying the Retry token.
+	// Only set on the server side.
+	RetryLatency time.Duration
+	// RetriesReceived is the number of Retry packets received, including invalid and ignored Retry packets.
+	// Only set on the client side.
+	RetriesReceived int
+	// RetrySrcConnectionID is the Source Connection ID chosen by the server in the Retry packet.
+	// Only set if a Retry was performed.
+	RetrySrcConnectionID ConnectionID
 }
@@ -1,6 +1,9 @@
 package ackhandler
 
 import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/wire"
@@ -13,6 +16,11 @@ type SentPacketHandler interface {
 	// ReceivedAck processes an ACK frame.
 	// It does not store a copy of the frame.
 	ReceivedAck(f *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime monotime.Time) (bool /* 1-RTT packet acked */, error)
+	// LastAck returns the largest packet number newly acknowledged by the last ACK frame passed to ReceivedAck,
+	// and the RTT sample it generated.
+	// The packet number is protocol.InvalidPacketNumber if no packets were newly acknowledged,
+	// and the RTT sample is 0 if no RTT sample was generated.
+	LastAck() (largestNewlyAcked protocol.PacketNumber, rttSample time.Duration)
 	ReceivedPacket(protocol.EncryptionLevel, monotime.Time)
 	ReceivedBytes(_ protocol.ByteCount, rcvTime monotime.Time)
 	DropPackets(_ protocol.EncryptionLevel, rcvTime monotime.Time)
@@ -24,6 +32,9 @@ type SentPacketHandler interface {
 	// It is used for pacing packets.
 	TimeUntilSend() monotime.Time
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// ReduceMaxDatagramSize is called when a packet was too large to be sent.
+	// Packets larger than the new size are declared lost, and their frames are queued for retransmission.
+	ReduceMaxDatagramSize(_ protocol.ByteCount, now monotime.Time)
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -34,6 +45,11 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() monotime.Time
 	OnLossDetectionTimeout(now monotime.Time) error
+	// PTOCount is the number of consecutive PTOs that fired without receiving an ACK.
+	PTOCount() uint32
 
 	MigratedPath(now monotime.Time, initialMaxPacketSize protocol.ByteCount)
+	// SetCongestionControl switches to a different congestion control algorithm.
+	// The new congestion controller takes over the state of the current one.
+	SetCongestionControl(_ congestion.CongestionControlAlgorithm, maxDatagramSize protocol.ByteCount, now monotime.Time)
 }
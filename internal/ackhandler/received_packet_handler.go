@@ -7,6 +7,7 @@ import (
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/qlogwriter"
 )
 
 type ReceivedPacketHandler struct {
@@ -17,11 +18,11 @@ type ReceivedPacketHandler struct {
 	lowest1RTTPacket protocol.PacketNumber
 }
 
-func NewReceivedPacketHandler(logger utils.Logger) *ReceivedPacketHandler {
+func NewReceivedPacketHandler(rttStats *utils.RTTStats, qlogger qlogwriter.Recorder, logger utils.Logger) *ReceivedPacketHandler {
 	return &ReceivedPacketHandler{
 		initialPackets:   newReceivedPacketTracker(),
 		handshakePackets: newReceivedPacketTracker(),
-		appDataPackets:   *newAppDataReceivedPacketTracker(logger),
+		appDataPackets:   *newAppDataReceivedPacketTracker(rttStats, qlogger, logger),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -49,15 +50,49 @@ func (h *ReceivedPacketHandler) ReceivedPacket(
 		}
 		return h.appDataPackets.ReceivedPacket(pn, ecn, rcvTime, ackEliciting)
 	case protocol.Encryption1RTT:
-		if h.lowest1RTTPacket == protocol.InvalidPacketNumber || pn < h.lowest1RTTPacket {
-			h.lowest1RTTPacket = pn
-		}
+		h.received1RTTPacket(pn)
 		return h.appDataPackets.ReceivedPacket(pn, ecn, rcvTime, ackEliciting)
 	default:
 		panic(fmt.Sprintf("received packet with unknown encryption level: %s", encLevel))
 	}
 }
 
+// ReceivedLatencyTolerantPacket is called instead of ReceivedPacket for ack-eliciting 1-RTT packets
+// that only carry data that doesn't need to be acknowledged quickly.
+// As long as all ack-eliciting packets received since the last ACK are latency-tolerant,
+// the ACK is delayed by up to max_ack_delay.
+func (h *ReceivedPacketHandler) ReceivedLatencyTolerantPacket(pn protocol.PacketNumber, ecn protocol.ECN, rcvTime monotime.Time) error {
+	h.received1RTTPacket(pn)
+	return h.appDataPackets.ReceivedLatencyTolerantPacket(pn, ecn, rcvTime)
+}
+
+func (h *ReceivedPacketHandler) received1RTTPacket(pn protocol.PacketNumber) {
+	if h.lowest1RTTPacket == protocol.InvalidPacketNumber || pn < h.lowest1RTTPacket {
+		h.lowest1RTTPacket = pn
+	}
+}
+
+// ReceivedBytes is called for every datagram received.
+// The receive rate is used to adapt the ACK frequency.
+func (h *ReceivedPacketHandler) ReceivedBytes(n protocol.ByteCount, rcvTime monotime.Time) {
+	h.appDataPackets.ReceivedBytes(n, rcvTime)
+}
+
+// EnableAckCoalescing delays ACKs for the Application Data packet number space,
+// such that they can be sent along with data, instead of in ACK-only packets.
+// ACKs are still sent after max_ack_delay, as well as immediately when packets are reordered,
+// lost or ECN-CE marked.
+func (h *ReceivedPacketHandler) EnableAckCoalescing() {
+	h.appDataPackets.coalesceAcks = true
+}
+
+// EnableImmediateAcks makes sure that every ack-eliciting packet in the Application Data packet number space
+// is acknowledged immediately, minimizing the ack delay at the cost of sending more ACKs.
+// It takes precedence over ACK coalescing.
+func (h *ReceivedPacketHandler) EnableImmediateAcks() {
+	h.appDataPackets.immediateAcks = true
+}
+
 func (h *ReceivedPacketHandler) IgnorePacketsBelow(pn protocol.PacketNumber) {
 	h.appDataPackets.IgnoreBelow(pn)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
@@ -13,7 +14,7 @@ import (
 )
 
 func TestGenerateACKsForPacketNumberSpaces(t *testing.T) {
-	handler := NewReceivedPacketHandler(utils.DefaultLogger)
+	handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	now := monotime.Now()
 	sendTime := now.Add(-time.Second)
@@ -54,7 +55,7 @@ func TestGenerateACKsForPacketNumberSpaces(t *testing.T) {
 }
 
 func TestReceive0RTTAnd1RTT(t *testing.T) {
-	handler := NewReceivedPacketHandler(utils.DefaultLogger)
+	handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	sendTime := monotime.Now().Add(-time.Second)
 
@@ -72,7 +73,7 @@ func TestReceive0RTTAnd1RTT(t *testing.T) {
 }
 
 func TestDropPackets(t *testing.T) {
-	handler := NewReceivedPacketHandler(utils.DefaultLogger)
+	handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	sendTime := monotime.Now().Add(-time.Second)
 
@@ -98,7 +99,7 @@ func TestDropPackets(t *testing.T) {
 }
 
 func TestAckRangePruning(t *testing.T) {
-	handler := NewReceivedPacketHandler(utils.DefaultLogger)
+	handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	sendTime := monotime.Now()
 	require.NoError(t, handler.ReceivedPacket(1, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true))
@@ -118,7 +119,7 @@ func TestAckRangePruning(t *testing.T) {
 }
 
 func TestPacketDuplicateDetection(t *testing.T) {
-	handler := NewReceivedPacketHandler(utils.DefaultLogger)
+	handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
 	sendTime := monotime.Now()
 
 	// 1-RTT is tested separately at the end
@@ -142,3 +143,28 @@ func TestPacketDuplicateDetection(t *testing.T) {
 	require.NoError(t, handler.ReceivedPacket(4, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true))
 	require.True(t, handler.IsPotentiallyDuplicate(4, protocol.Encryption1RTT))
 }
+
+func TestPacketNumberWindow(t *testing.T) {
+	encLevels := []protocol.EncryptionLevel{
+		protocol.EncryptionInitial,
+		protocol.EncryptionHandshake,
+		protocol.Encryption1RTT,
+	}
+
+	for _, encLevel := range encLevels {
+		t.Run(encLevel.String(), func(t *testing.T) {
+			handler := NewReceivedPacketHandler(utils.NewRTTStats(), nil, utils.DefaultLogger)
+			sendTime := monotime.Now()
+
+			// the first packet can have any packet number
+			require.NoError(t, handler.ReceivedPacket(10, protocol.ECNNon, encLevel, sendTime, true))
+			// packets up to the edge of the window are accepted
+			require.NoError(t, handler.ReceivedPacket(10+protocol.MaxPacketNumberWindow, protocol.ECNNon, encLevel, sendTime, true))
+			// the window moves with the largest received packet number
+			pn := 11 + 2*protocol.MaxPacketNumberWindow
+			err := handler.ReceivedPacket(pn, protocol.ECNNon, encLevel, sendTime, true)
+			require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation})
+			require.False(t, handler.IsPotentiallyDuplicate(pn, encLevel))
+		})
+	}
+}
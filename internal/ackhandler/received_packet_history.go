@@ -109,6 +109,15 @@ func (h *receivedPacketHistory) DeleteBelow(p protocol.PacketNumber) {
 	}
 }
 
+// Largest returns the largest packet number that is still tracked.
+// It returns protocol.InvalidPacketNumber if no packet is tracked.
+func (h *receivedPacketHistory) Largest() protocol.PacketNumber {
+	if len(h.ranges) == 0 {
+		return protocol.InvalidPacketNumber
+	}
+	return h.ranges[len(h.ranges)-1].End
+}
+
 // Backward returns an iterator over the ranges in reverse order
 func (h *receivedPacketHistory) Backward() iter.Seq[interval] {
 	return func(yield func(interval) bool) {
@@ -12,9 +12,11 @@ import (
 
 func TestReceivedPacketHistorySingleRange(t *testing.T) {
 	hist := newReceivedPacketHistory()
+	require.Equal(t, protocol.InvalidPacketNumber, hist.Largest())
 
 	require.True(t, hist.ReceivedPacket(4))
 	require.Equal(t, []interval{{Start: 4, End: 4}}, slices.Collect(hist.Backward()))
+	require.Equal(t, protocol.PacketNumber(4), hist.Largest())
 
 	// add a duplicate packet
 	require.False(t, hist.ReceivedPacket(4))
@@ -6,8 +6,11 @@ import (
 
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
 )
 
 const reorderingThreshold = 1
@@ -28,6 +31,12 @@ func newReceivedPacketTracker() *receivedPacketTracker {
 }
 
 func (h *receivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, ackEliciting bool) error {
+	if largest := h.packetHistory.Largest(); largest != protocol.InvalidPacketNumber && pn > largest+protocol.MaxPacketNumberWindow {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("packet number %d too far ahead of largest received packet number %d", pn, largest),
+		}
+	}
 	if isNew := h.packetHistory.ReceivedPacket(pn); !isNew {
 		return fmt.Errorf("receivedPacketTracker BUG: ReceivedPacket called for old / duplicate packet %d", pn)
 	}
@@ -76,13 +85,29 @@ func (h *receivedPacketTracker) IsPotentiallyDuplicate(pn protocol.PacketNumber)
 }
 
 // number of ack-eliciting packets received before sending an ACK
-const packetsBeforeAck = 2
+const (
+	packetsBeforeAck = 2
+	// used when the RTT is low and the receive rate exceeds protocol.ACKFrequencyThresholdBW
+	packetsBeforeAckHighBW = 4
+	// used when the RTT is low and the receive rate exceeds protocol.ACKFrequencyThresholdHighBW
+	packetsBeforeAckVeryHighBW = 8
+)
+
+// minReceiveRateSampleInterval is the minimum interval over which the receive rate is sampled.
+const minReceiveRateSampleInterval = 10 * time.Millisecond
 
 // The appDataReceivedPacketTracker tracks packets received in the Application Data packet number space.
 // It waits until at least 2 packets were received before queueing an ACK, or until the max_ack_delay was reached.
+// On low-RTT, high-throughput connections, it waits for 4 or 8 packets, similar to the Linux TCP stack.
 type appDataReceivedPacketTracker struct {
 	receivedPacketTracker
 
+	rttStats *utils.RTTStats
+
+	rateSampleStart monotime.Time
+	rateSampleBytes protocol.ByteCount
+	receiveRate     uint64 // in bits/s
+
 	largestObservedRcvdTime monotime.Time
 
 	largestObserved protocol.PacketNumber
@@ -90,23 +115,45 @@ type appDataReceivedPacketTracker struct {
 
 	maxAckDelay time.Duration
 	ackQueued   bool // true if we need send a new ACK
+	// If set, an ACK is not queued after receiving a number of ack-eliciting packets.
+	// Instead, the ACK is sent along with the next packet carrying data,
+	// or once the max_ack_delay of the first unacknowledged packet expires.
+	coalesceAcks bool
+	// If set, an ACK is queued for every ack-eliciting packet.
+	// This takes precedence over coalesceAcks.
+	immediateAcks bool
 
 	ackElicitingPacketsReceivedSinceLastAck int
+	onlyLatencyTolerantSinceLastAck         bool          // all ack-eliciting packets since the last ACK were latency-tolerant
+	firstAckElicitingRcvdTime               monotime.Time // of the first ack-eliciting packet since the last ACK
 	ackAlarm                                monotime.Time
 
-	logger utils.Logger
+	qlogger qlogwriter.Recorder
+	logger  utils.Logger
 }
 
-func newAppDataReceivedPacketTracker(logger utils.Logger) *appDataReceivedPacketTracker {
+func newAppDataReceivedPacketTracker(rttStats *utils.RTTStats, qlogger qlogwriter.Recorder, logger utils.Logger) *appDataReceivedPacketTracker {
 	h := &appDataReceivedPacketTracker{
 		receivedPacketTracker: *newReceivedPacketTracker(),
+		rttStats:              rttStats,
 		maxAckDelay:           protocol.MaxAckDelay,
+		qlogger:               qlogger,
 		logger:                logger,
 	}
 	return h
 }
 
 func (h *appDataReceivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, rcvTime monotime.Time, ackEliciting bool) error {
+	return h.receivedPacket(pn, ecn, rcvTime, ackEliciting, false)
+}
+
+// ReceivedLatencyTolerantPacket is called for ack-eliciting packets that don't need to be acknowledged quickly.
+// Unless other ack-eliciting packets are received, the ACK is only sent after max_ack_delay.
+func (h *appDataReceivedPacketTracker) ReceivedLatencyTolerantPacket(pn protocol.PacketNumber, ecn protocol.ECN, rcvTime monotime.Time) error {
+	return h.receivedPacket(pn, ecn, rcvTime, true, true)
+}
+
+func (h *appDataReceivedPacketTracker) receivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, rcvTime monotime.Time, ackEliciting, latencyTolerant bool) error {
 	if err := h.receivedPacketTracker.ReceivedPacket(pn, ecn, ackEliciting); err != nil {
 		return err
 	}
@@ -117,6 +164,12 @@ func (h *appDataReceivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber,
 	if !ackEliciting {
 		return nil
 	}
+	if h.ackElicitingPacketsReceivedSinceLastAck == 0 {
+		h.firstAckElicitingRcvdTime = rcvTime
+		h.onlyLatencyTolerantSinceLastAck = latencyTolerant
+	} else if !latencyTolerant {
+		h.onlyLatencyTolerantSinceLastAck = false
+	}
 	h.ackElicitingPacketsReceivedSinceLastAck++
 	isMissing := h.isMissing(pn)
 	if !h.ackQueued && h.shouldQueueACK(pn, ecn, isMissing) {
@@ -124,6 +177,12 @@ func (h *appDataReceivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber,
 		h.ackAlarm = 0 // cancel the ack alarm
 	}
 	if !h.ackQueued {
+		// When coalescing ACKs, or when only receiving latency-tolerant packets,
+		// the alarm is not postponed by subsequent packets.
+		// This bounds the delay of every packet by max_ack_delay.
+		if (h.coalesceAcks || h.onlyLatencyTolerantSinceLastAck) && !h.ackAlarm.IsZero() {
+			return nil
+		}
 		// No ACK queued, but we'll need to acknowledge the packet after max_ack_delay.
 		h.ackAlarm = rcvTime.Add(h.maxAckDelay)
 		if h.logger.Debug() {
@@ -133,6 +192,37 @@ func (h *appDataReceivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber,
 	return nil
 }
 
+// ReceivedBytes is called for every datagram received.
+// It is used to estimate the receive rate.
+func (h *appDataReceivedPacketTracker) ReceivedBytes(n protocol.ByteCount, rcvTime monotime.Time) {
+	if h.rateSampleStart.IsZero() {
+		h.rateSampleStart = rcvTime
+	}
+	h.rateSampleBytes += n
+	elapsed := rcvTime.Sub(h.rateSampleStart)
+	if elapsed < max(h.rttStats.SmoothedRTT(), minReceiveRateSampleInterval) {
+		return
+	}
+	h.receiveRate = uint64(h.rateSampleBytes) * 8 * uint64(time.Second) / uint64(elapsed)
+	h.rateSampleStart = rcvTime
+	h.rateSampleBytes = 0
+}
+
+// ackThreshold returns the number of ack-eliciting packets that need to be received before an ACK is queued.
+func (h *appDataReceivedPacketTracker) ackThreshold() int {
+	if !h.rttStats.HasMeasurement() || h.rttStats.SmoothedRTT() >= protocol.ACKFrequencyThresholdRTT {
+		return packetsBeforeAck
+	}
+	switch {
+	case h.receiveRate > protocol.ACKFrequencyThresholdHighBW:
+		return packetsBeforeAckVeryHighBW
+	case h.receiveRate > protocol.ACKFrequencyThresholdBW:
+		return packetsBeforeAckHighBW
+	default:
+		return packetsBeforeAck
+	}
+}
+
 // IgnoreBelow sets a lower limit for acknowledging packets.
 // Packets with packet numbers smaller than p will not be acked.
 func (h *appDataReceivedPacketTracker) IgnoreBelow(pn protocol.PacketNumber) {
@@ -183,10 +273,15 @@ func (h *appDataReceivedPacketTracker) shouldQueueACK(pn protocol.PacketNumber,
 		return true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	if h.immediateAcks {
+		return true
+	}
+
+	// send an ACK every 2 ack-eliciting packets (or every 4 or 8 packets at high throughputs),
+	// unless all of them were latency-tolerant
+	if threshold := h.ackThreshold(); !h.coalesceAcks && !h.onlyLatencyTolerantSinceLastAck && h.ackElicitingPacketsReceivedSinceLastAck >= threshold {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, threshold)
 		}
 		return true
 	}
@@ -219,6 +314,14 @@ func (h *appDataReceivedPacketTracker) GetAckFrame(now monotime.Time, onlyIfQueu
 		return nil
 	}
 	ack.DelayTime = max(0, now.Sub(h.largestObservedRcvdTime))
+	// The latency is only defined if this ACK acknowledges an ack-eliciting packet.
+	if h.qlogger != nil && h.ackElicitingPacketsReceivedSinceLastAck > 0 {
+		h.qlogger.RecordEvent(qlog.AckDelaySent{
+			LargestAcked: ack.LargestAcked(),
+			AckDelay:     ack.DelayTime,
+			Latency:      max(0, now.Sub(h.firstAckElicitingRcvdTime)),
+		})
+	}
 	h.ackQueued = false
 	h.ackAlarm = 0
 	h.ackElicitingPacketsReceivedSinceLastAck = 0
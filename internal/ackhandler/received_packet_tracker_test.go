@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -8,6 +9,9 @@ import (
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
+	"github.com/quic-go/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/require"
 )
@@ -51,7 +55,7 @@ func TestReceivedPacketTrackerGenerateACKs(t *testing.T) {
 }
 
 func TestAppDataReceivedPacketTrackerECN(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	require.NoError(t, tr.ReceivedPacket(0, protocol.ECT0, monotime.Now(), true))
 	pn := protocol.PacketNumber(1)
@@ -70,7 +74,7 @@ func TestAppDataReceivedPacketTrackerECN(t *testing.T) {
 }
 
 func TestAppDataReceivedPacketTrackerAckEverySecondPacket(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 	require.Nil(t, tr.GetAckFrame(monotime.Now(), true))
 
 	for p := protocol.PacketNumber(1); p <= 20; p++ {
@@ -84,8 +88,59 @@ func TestAppDataReceivedPacketTrackerAckEverySecondPacket(t *testing.T) {
 	}
 }
 
+// simulateReceiving receives ack-eliciting packets at the given rate (in bits/s),
+// and returns the number of ACKs that were generated.
+func simulateReceiving(tb testing.TB, tr *appDataReceivedPacketTracker, rate uint64, numPackets int) (numAcks int) {
+	const packetSize = 1400
+	interval := time.Duration(packetSize * 8 * uint64(time.Second) / rate)
+	now := monotime.Now()
+	pn := tr.largestObserved + 1
+	for range numPackets {
+		tr.ReceivedBytes(packetSize, now)
+		require.NoError(tb, tr.ReceivedPacket(pn, protocol.ECNNon, now, true))
+		pn++
+		if tr.GetAckFrame(now, true) != nil {
+			numAcks++
+		}
+		now = now.Add(interval)
+	}
+	return numAcks
+}
+
+func TestAppDataReceivedPacketTrackerAdaptiveAckFrequency(t *testing.T) {
+	t.Run("low RTT", func(t *testing.T) {
+		for _, tc := range []struct {
+			name          string
+			rate          uint64
+			packetsPerAck int
+		}{
+			{name: "10 Mbps", rate: 10e6, packetsPerAck: 2},
+			{name: "500 Mbps", rate: 500e6, packetsPerAck: 4},
+			{name: "5 Gbps", rate: 5e9, packetsPerAck: 8},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				var rttStats utils.RTTStats
+				rttStats.UpdateRTT(time.Millisecond, 0)
+				tr := newAppDataReceivedPacketTracker(&rttStats, nil, utils.DefaultLogger)
+				// warm up the receive rate estimate
+				simulateReceiving(t, tr, tc.rate, 10000)
+				numAcks := simulateReceiving(t, tr, tc.rate, 8000)
+				require.Equal(t, 8000/tc.packetsPerAck, numAcks)
+			})
+		}
+	})
+
+	t.Run("high RTT", func(t *testing.T) {
+		var rttStats utils.RTTStats
+		rttStats.UpdateRTT(50*time.Millisecond, 0)
+		tr := newAppDataReceivedPacketTracker(&rttStats, nil, utils.DefaultLogger)
+		simulateReceiving(t, tr, 5e9, 100000)
+		require.Equal(t, 8000/packetsBeforeAck, simulateReceiving(t, tr, 5e9, 8000))
+	})
+}
+
 func TestAppDataReceivedPacketTrackerAlarmTimeout(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	now := monotime.Now()
 	require.NoError(t, tr.ReceivedPacket(1, protocol.ECNNon, now, false))
@@ -103,7 +158,7 @@ func TestAppDataReceivedPacketTrackerAlarmTimeout(t *testing.T) {
 }
 
 func TestAppDataReceivedPacketTrackerQueuesECNCE(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	require.NoError(t, tr.ReceivedPacket(1, protocol.ECNCE, monotime.Now(), true))
 	ack := tr.GetAckFrame(monotime.Now(), true)
@@ -113,7 +168,7 @@ func TestAppDataReceivedPacketTrackerQueuesECNCE(t *testing.T) {
 }
 
 func TestAppDataReceivedPacketTrackerMissingPackets(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	now := monotime.Now()
 	require.NoError(t, tr.ReceivedPacket(0, protocol.ECNNon, now, true))
@@ -140,8 +195,122 @@ func TestAppDataReceivedPacketTrackerMissingPackets(t *testing.T) {
 	require.NotNil(t, tr.GetAckFrame(now, true))
 }
 
+func TestAppDataReceivedPacketTrackerCoalesceAcks(t *testing.T) {
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
+	tr.coalesceAcks = true
+
+	start := monotime.Now()
+	now := start
+	for pn := protocol.PacketNumber(0); pn < 10; pn++ {
+		require.NoError(t, tr.ReceivedPacket(pn, protocol.ECNNon, now, true))
+		// no ACK-only packet is sent...
+		require.Nil(t, tr.GetAckFrame(now, true))
+		now = now.Add(time.Millisecond)
+	}
+	// ... but the alarm is not postponed by subsequent packets
+	require.Equal(t, start.Add(protocol.MaxAckDelay), tr.GetAlarmTimeout())
+
+	// if there's data to send, the ACK is sent along with the data
+	ack := tr.GetAckFrame(now, false)
+	require.NotNil(t, ack)
+	require.Equal(t, []wire.AckRange{{Smallest: 0, Largest: 9}}, ack.AckRanges)
+	require.Zero(t, tr.GetAlarmTimeout())
+
+	// if there's no data to send, the ACK is sent when the alarm fires
+	require.NoError(t, tr.ReceivedPacket(10, protocol.ECNNon, now, true))
+	require.NoError(t, tr.ReceivedPacket(11, protocol.ECNNon, now.Add(time.Millisecond), true))
+	require.Nil(t, tr.GetAckFrame(now.Add(protocol.MaxAckDelay-time.Nanosecond), true))
+	ack = tr.GetAckFrame(now.Add(protocol.MaxAckDelay), true)
+	require.NotNil(t, ack)
+	require.Equal(t, protocol.PacketNumber(11), ack.LargestAcked())
+
+	// missing packets are still acknowledged immediately
+	require.NoError(t, tr.ReceivedPacket(20, protocol.ECNNon, now, true))
+	require.NotNil(t, tr.GetAckFrame(now, false))
+	require.NoError(t, tr.ReceivedPacket(15, protocol.ECNNon, now, true))
+	require.NotNil(t, tr.GetAckFrame(now, true))
+}
+
+func TestAppDataReceivedPacketTrackerLatencyTolerant(t *testing.T) {
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
+
+	start := monotime.Now()
+	now := start
+	for pn := protocol.PacketNumber(0); pn < 10; pn++ {
+		require.NoError(t, tr.ReceivedLatencyTolerantPacket(pn, protocol.ECNNon, now))
+		require.Nil(t, tr.GetAckFrame(now, true))
+		now = now.Add(time.Millisecond)
+	}
+	// the alarm is not postponed by subsequent packets
+	require.Equal(t, start.Add(protocol.MaxAckDelay), tr.GetAlarmTimeout())
+	require.Nil(t, tr.GetAckFrame(start.Add(protocol.MaxAckDelay-time.Nanosecond), true))
+	ack := tr.GetAckFrame(start.Add(protocol.MaxAckDelay), true)
+	require.NotNil(t, ack)
+	require.Equal(t, []wire.AckRange{{Smallest: 0, Largest: 9}}, ack.AckRanges)
+
+	// a packet that is not latency-tolerant is acknowledged as usual
+	require.NoError(t, tr.ReceivedLatencyTolerantPacket(10, protocol.ECNNon, now))
+	require.Nil(t, tr.GetAckFrame(now, true))
+	require.NoError(t, tr.ReceivedPacket(11, protocol.ECNNon, now, true))
+	ack = tr.GetAckFrame(now, true)
+	require.NotNil(t, ack)
+	require.Equal(t, protocol.PacketNumber(11), ack.LargestAcked())
+
+	// ... and so are subsequent latency-tolerant packets, until the next ACK is sent
+	require.NoError(t, tr.ReceivedPacket(12, protocol.ECNNon, now, true))
+	require.Nil(t, tr.GetAckFrame(now, true))
+	require.NoError(t, tr.ReceivedLatencyTolerantPacket(13, protocol.ECNNon, now))
+	require.NotNil(t, tr.GetAckFrame(now, true))
+
+	// missing packets are still acknowledged immediately
+	require.NoError(t, tr.ReceivedLatencyTolerantPacket(20, protocol.ECNNon, now))
+	require.NotNil(t, tr.GetAckFrame(now, true))
+	require.NoError(t, tr.ReceivedLatencyTolerantPacket(15, protocol.ECNNon, now))
+	require.NotNil(t, tr.GetAckFrame(now, true))
+}
+
+func TestAppDataReceivedPacketTrackerImmediateAcks(t *testing.T) {
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
+	tr.immediateAcks = true
+	// immediate ACKs take precedence over ACK coalescing
+	tr.coalesceAcks = true
+
+	now := monotime.Now()
+	for pn := protocol.PacketNumber(0); pn < 5; pn++ {
+		require.NoError(t, tr.ReceivedPacket(pn, protocol.ECNNon, now, true))
+		ack := tr.GetAckFrame(now, true)
+		require.NotNil(t, ack)
+		require.Equal(t, pn, ack.LargestAcked())
+	}
+	// non-ack-eliciting packets are not acknowledged
+	require.NoError(t, tr.ReceivedPacket(5, protocol.ECNNon, now, false))
+	require.Nil(t, tr.GetAckFrame(now, true))
+}
+
+func TestAppDataReceivedPacketTrackerAckLatency(t *testing.T) {
+	var eventRecorder events.Recorder
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), &eventRecorder, utils.DefaultLogger)
+
+	now := monotime.Now()
+	require.NoError(t, tr.ReceivedPacket(0, protocol.ECNNon, now, true))
+	require.NoError(t, tr.ReceivedPacket(1, protocol.ECNNon, now.Add(5*time.Millisecond), false))
+	require.NoError(t, tr.ReceivedPacket(2, protocol.ECNNon, now.Add(10*time.Millisecond), true))
+	require.NotNil(t, tr.GetAckFrame(now.Add(12*time.Millisecond), true))
+	// the latency is measured from the first ack-eliciting packet since the last ACK
+	require.NoError(t, tr.ReceivedPacket(3, protocol.ECNNon, now.Add(20*time.Millisecond), true))
+	require.NotNil(t, tr.GetAckFrame(now.Add(protocol.MaxAckDelay+20*time.Millisecond), true))
+
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.AckDelaySent{LargestAcked: 2, AckDelay: 2 * time.Millisecond, Latency: 12 * time.Millisecond},
+			qlog.AckDelaySent{LargestAcked: 3, AckDelay: protocol.MaxAckDelay, Latency: protocol.MaxAckDelay},
+		},
+		eventRecorder.Events(qlog.AckDelaySent{}),
+	)
+}
+
 func TestAppDataReceivedPacketTrackerDelayTime(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	now := monotime.Now()
 	require.NoError(t, tr.ReceivedPacket(1, protocol.ECNNon, now, true))
@@ -158,7 +327,7 @@ func TestAppDataReceivedPacketTrackerDelayTime(t *testing.T) {
 }
 
 func TestAppDataReceivedPacketTrackerIgnoreBelow(t *testing.T) {
-	tr := newAppDataReceivedPacketTracker(utils.DefaultLogger)
+	tr := newAppDataReceivedPacketTracker(utils.NewRTTStats(), nil, utils.DefaultLogger)
 
 	tr.IgnoreBelow(4)
 	// check that packets below 7 are considered duplicates
@@ -186,3 +355,17 @@ func TestAppDataReceivedPacketTrackerIgnoreBelow(t *testing.T) {
 		"receivedPacketTracker BUG: ReceivedPacket called for old / duplicate packet 4",
 	)
 }
+
+func BenchmarkAppDataReceivedPacketTrackerAckFrequency(b *testing.B) {
+	for _, rate := range []uint64{10e6, 500e6, 5e9} {
+		b.Run(fmt.Sprintf("%d Mbps", rate/1e6), func(b *testing.B) {
+			var rttStats utils.RTTStats
+			rttStats.UpdateRTT(time.Millisecond, 0)
+			tr := newAppDataReceivedPacketTracker(&rttStats, nil, utils.DefaultLogger)
+			simulateReceiving(b, tr, rate, 10000)
+			b.ResetTimer()
+			numAcks := simulateReceiving(b, tr, rate, b.N)
+			b.ReportMetric(float64(numAcks)/float64(b.N), "acks/packet")
+		})
+	}
+}
@@ -87,11 +87,16 @@ type sentPacketHandler struct {
 
 	ackedPackets []packetWithPacketNumber // to avoid allocations in detectAndRemoveAckedPackets
 
+	// information about the last ACK frame processed, see LastAck
+	lastAckLargestNewlyAcked protocol.PacketNumber
+	lastAckRTTSample         time.Duration
+
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
-	connStats  *utils.ConnectionStats
+	congestion        congestion.SendAlgorithmWithDebugInfos
+	congestionControl congestion.CongestionControlAlgorithm
+	rttStats          *utils.RTTStats
+	connStats         *utils.ConnectionStats
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -111,6 +116,9 @@ type sentPacketHandler struct {
 	qlogger     qlogwriter.Recorder
 	lastMetrics qlog.MetricsUpdated
 	logger      utils.Logger
+
+	congestionHooks          *congestion.Hooks // might be nil
+	lastCongestionWindowHook protocol.ByteCount
 }
 
 var _ SentPacketHandler = &sentPacketHandler{}
@@ -127,6 +135,7 @@ func NewSentPacketHandler(
 	ignorePacketsBelow func(protocol.PacketNumber),
 	pers protocol.Perspective,
 	qlogger qlogwriter.Recorder,
+	congestionHooks *congestion.Hooks,
 	logger utils.Logger,
 	congControl congestion.CongestionControlAlgorithm,
 ) SentPacketHandler {
@@ -139,6 +148,7 @@ func NewSentPacketHandler(
 		initialMaxDatagramSize,
 		!useCubic, // use Reno if not CUBIC
 		qlogger,
+		congestionHooks,
 	)
 
 	h := &sentPacketHandler{
@@ -147,15 +157,20 @@ func NewSentPacketHandler(
 		initialPackets:                 newPacketNumberSpace(initialPN, false),
 		handshakePackets:               newPacketNumberSpace(0, false),
 		appDataPackets:                 newPacketNumberSpace(0, true),
+		lastAckLargestNewlyAcked:       protocol.InvalidPacketNumber,
 		lostPackets:                    *newLostPacketTracker(64),
 		rttStats:                       rttStats,
 		connStats:                      connStats,
 		congestion:                     congestion,
+		congestionControl:              congControl,
 		ignorePacketsBelow:             ignorePacketsBelow,
 		perspective:                    pers,
 		qlogger:                        qlogger,
+		congestionHooks:                congestionHooks,
 		logger:                         logger,
 	}
+	connStats.CongestionControl.Store(uint32(congControl))
+	h.updateConnStats()
 	if enableECN {
 		h.enableECN = true
 		h.ecnTracker = newECNTracker(logger, qlogger)
@@ -163,6 +178,18 @@ func NewSentPacketHandler(
 	return h
 }
 
+// updateConnStats publishes the congestion window and the bytes in flight.
+// It is called whenever either of them might have changed.
+func (h *sentPacketHandler) updateConnStats() {
+	cwnd := h.congestion.GetCongestionWindow()
+	h.connStats.CongestionWindow.Store(uint64(cwnd))
+	h.connStats.BytesInFlight.Store(uint64(h.bytesInFlight))
+	if h.congestionHooks != nil && h.congestionHooks.OnCongestionWindowChange != nil && cwnd != h.lastCongestionWindowHook {
+		h.lastCongestionWindowHook = cwnd
+		h.congestionHooks.OnCongestionWindowChange(cwnd)
+	}
+}
+
 func (h *sentPacketHandler) removeFromBytesInFlight(p *packet) {
 	if p.includedInBytesInFlight {
 		if p.Length > h.bytesInFlight {
@@ -221,6 +248,7 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel, now m
 	h.ptoCount = 0
 	h.numProbesToSend = 0
 	h.ptoMode = SendNone
+	h.updateConnStats()
 	h.setLossDetectionTimer(now)
 }
 
@@ -235,6 +263,7 @@ func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount, t monotime.Time)
 
 func (h *sentPacketHandler) ReceivedPacket(l protocol.EncryptionLevel, t monotime.Time) {
 	h.connStats.PacketsReceived.Add(1)
+	h.connStats.LastPacketReceived.Store(int64(t))
 	if h.perspective == protocol.PerspectiveServer && l == protocol.EncryptionHandshake && !h.peerAddressValidated {
 		h.peerAddressValidated = true
 		h.setLossDetectionTimer(t)
@@ -294,6 +323,7 @@ func (h *sentPacketHandler) SentPacket(
 	}
 	if isAckEliciting {
 		pnSpace.lastAckElicitingPacketTime = t
+		h.connStats.LastAckElicitingPacketSent.Store(int64(t))
 		h.bytesInFlight += size
 		p.includedInBytesInFlight = true
 		if h.numProbesToSend > 0 {
@@ -313,6 +343,7 @@ func (h *sentPacketHandler) SentPacket(
 		}
 		return
 	}
+	h.updateConnStats()
 	if h.qlogger != nil {
 		h.qlogMetricsUpdated()
 	}
@@ -380,6 +411,8 @@ func (h *sentPacketHandler) getPacketNumberSpace(encLevel protocol.EncryptionLev
 
 func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime monotime.Time) (bool /* contained 1-RTT packet */, error) {
 	pnSpace := h.getPacketNumberSpace(encLevel)
+	h.lastAckLargestNewlyAcked = protocol.InvalidPacketNumber
+	h.lastAckRTTSample = 0
 
 	largestAcked := ack.LargestAcked()
 	if largestAcked > pnSpace.largestSent {
@@ -388,6 +421,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 			ErrorMessage: "received ACK for an unsent packet",
 		}
 	}
+	if encLevel == protocol.Encryption1RTT && h.qlogger != nil {
+		h.qlogger.RecordEvent(qlog.AckDelayReceived{LargestAcked: largestAcked, AckDelay: ack.DelayTime})
+	}
 
 	// Servers complete address validation when a protected packet is received.
 	if h.perspective == protocol.PerspectiveClient && !h.peerCompletedAddressValidation &&
@@ -403,6 +439,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if err != nil || len(ackedPackets) == 0 {
 		return false, err
 	}
+	h.lastAckLargestNewlyAcked = ackedPackets[len(ackedPackets)-1].PacketNumber
 	// update the RTT, if:
 	// * the largest acked is newly acknowledged, AND
 	// * at least one new ack-eliciting packet was acknowledged
@@ -414,7 +451,8 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 				ackDelay = min(ack.DelayTime, h.rttStats.MaxAckDelay())
 			}
 			if h.largestAckedTime.IsZero() || !p.SendTime.Before(h.largestAckedTime) {
-				h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackDelay)
+				h.lastAckRTTSample = rcvTime.Sub(p.SendTime)
+				h.rttStats.UpdateRTT(h.lastAckRTTSample, ackDelay)
 				if h.logger.Debug() {
 					h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 				}
@@ -477,6 +515,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	}
 	h.numProbesToSend = 0
 
+	h.updateConnStats()
 	if h.qlogger != nil {
 		h.qlogMetricsUpdated()
 	}
@@ -801,6 +840,8 @@ func (h *sentPacketHandler) detectLostPackets(now monotime.Time, encLevel protoc
 	lostSendTime := now.Add(-lossDelay)
 
 	priorInFlight := h.bytesInFlight
+	// only used for qlog: the packets declared lost in this pass
+	var lostByTime, lostByReordering []protocol.PacketNumber
 	for pn, p := range pnSpace.history.Packets() {
 		if pn > pnSpace.largestAcked {
 			break
@@ -821,6 +862,7 @@ func (h *sentPacketHandler) detectLostPackets(now monotime.Time, encLevel protoc
 						},
 						Trigger: qlog.PacketLossTimeThreshold,
 					})
+					lostByTime = append(lostByTime, pn)
 				}
 			}
 		} else if pnSpace.history.Difference(pnSpace.largestAcked, pn) >= packetThreshold {
@@ -837,6 +879,7 @@ func (h *sentPacketHandler) detectLostPackets(now monotime.Time, encLevel protoc
 						},
 						Trigger: qlog.PacketLossReorderingThreshold,
 					})
+					lostByReordering = append(lostByReordering, pn)
 				}
 			}
 		} else if pnSpace.lossTime.IsZero() {
@@ -865,10 +908,29 @@ func (h *sentPacketHandler) detectLostPackets(now monotime.Time, encLevel protoc
 			}
 		}
 	}
+	if len(lostByTime) > 0 {
+		h.qlogger.RecordEvent(qlog.PacketsLost{
+			EncryptionLevel: encLevel,
+			PacketNumbers:   lostByTime,
+			Trigger:         qlog.PacketLossTimeThreshold,
+		})
+	}
+	if len(lostByReordering) > 0 {
+		h.qlogger.RecordEvent(qlog.PacketsLost{
+			EncryptionLevel: encLevel,
+			PacketNumbers:   lostByReordering,
+			Trigger:         qlog.PacketLossReorderingThreshold,
+		})
+	}
+}
+
+func (h *sentPacketHandler) PTOCount() uint32 {
+	return h.ptoCount
 }
 
 func (h *sentPacketHandler) OnLossDetectionTimeout(now monotime.Time) error {
 	defer h.setLossDetectionTimer(now)
+	defer h.updateConnStats()
 
 	if h.handshakeConfirmed {
 		h.detectLostPathProbes(now)
@@ -1021,6 +1083,9 @@ func (h *sentPacketHandler) SendMode(now monotime.Time) SendMode {
 		return SendAck
 	}
 	if !h.congestion.HasPacingBudget(now) {
+		if h.logger.Debug() {
+			h.logger.Debugf("Pacing limited: budget %d", h.congestion.PacingBudget(now))
+		}
 		return SendPacingLimited
 	}
 	return SendAny
@@ -1034,6 +1099,26 @@ func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
 	h.congestion.SetMaxDatagramSize(s)
 }
 
+func (h *sentPacketHandler) ReduceMaxDatagramSize(s protocol.ByteCount, now monotime.Time) {
+	for pn, p := range h.appDataPackets.history.Packets() {
+		if p.isPathProbePacket || p.Length <= s {
+			continue
+		}
+		// These packets never made it onto the wire.
+		// This is not a congestion signal, so the congestion controller is not notified.
+		h.appDataPackets.history.DeclareLost(pn)
+		h.removeFromBytesInFlight(p)
+		if p.IsAckEliciting() {
+			h.queueFramesForRetransmission(p)
+		}
+	}
+	// The congestion controller doesn't allow decreasing the max datagram size,
+	// so it is replaced by one that takes over its state.
+	h.congestion = h.takeOverCongestionController(h.congestionControl, s, now)
+	h.updateConnStats()
+	h.setLossDetectionTimer(now)
+}
+
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
@@ -1118,6 +1203,7 @@ func (h *sentPacketHandler) ResetForRetry(now monotime.Time) {
 		}
 	}
 	h.ptoCount = 0
+	h.updateConnStats()
 }
 
 func (h *sentPacketHandler) MigratedPath(now monotime.Time, initialMaxDatagramSize protocol.ByteCount) {
@@ -1141,6 +1227,56 @@ func (h *sentPacketHandler) MigratedPath(now monotime.Time, initialMaxDatagramSi
 		initialMaxDatagramSize,
 		true, // use Reno
 		h.qlogger,
+		h.congestionHooks,
 	)
+	h.congestionControl = congestion.NewReno
+	h.connStats.CongestionControl.Store(uint32(congestion.NewReno))
+	h.updateConnStats()
 	h.setLossDetectionTimer(now)
 }
+
+func (h *sentPacketHandler) LastAck() (largestNewlyAcked protocol.PacketNumber, rttSample time.Duration) {
+	return h.lastAckLargestNewlyAcked, h.lastAckRTTSample
+}
+
+func (h *sentPacketHandler) SetCongestionControl(alg congestion.CongestionControlAlgorithm, maxDatagramSize protocol.ByteCount, now monotime.Time) {
+	if alg == h.congestionControl {
+		return
+	}
+	if h.logger.Debug() {
+		h.logger.Debugf("Switching congestion controller. Congestion window: %d", h.congestion.GetCongestionWindow())
+	}
+	h.congestion = h.takeOverCongestionController(alg, maxDatagramSize, now)
+	h.congestionControl = alg
+	h.connStats.CongestionControl.Store(uint32(alg))
+	h.updateConnStats()
+}
+
+// takeOverCongestionController creates a new congestion controller that continues where the current one left off.
+// All ACKs received so far have been delivered to the current congestion controller, so its state is complete.
+// If the state can't be transferred, the new congestion controller starts in congestion avoidance,
+// using the current congestion window.
+func (h *sentPacketHandler) takeOverCongestionController(
+	alg congestion.CongestionControlAlgorithm,
+	maxDatagramSize protocol.ByteCount,
+	now monotime.Time,
+) congestion.SendAlgorithmWithDebugInfos {
+	if old, ok := h.congestion.(congestion.SendAlgorithmWithState); ok {
+		cc := congestion.NewCubicSender(congestion.DefaultClock{}, h.rttStats, h.connStats, maxDatagramSize, alg != congestion.CUBIC, h.qlogger, h.congestionHooks)
+		err := cc.ImportState(old.ExportState(now), now)
+		if err == nil {
+			return cc
+		}
+		h.logger.Debugf("Failed to transfer the congestion controller state: %s", err)
+	}
+	return congestion.NewCubicSenderInCongestionAvoidance(
+		congestion.DefaultClock{},
+		h.rttStats,
+		h.connStats,
+		maxDatagramSize,
+		h.congestion.GetCongestionWindow(),
+		alg != congestion.CUBIC,
+		h.qlogger,
+		h.congestionHooks,
+	)
+}
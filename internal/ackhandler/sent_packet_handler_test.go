@@ -119,6 +119,7 @@ func testSentPacketHandlerSendAndAcknowledge(t *testing.T, encLevel protocol.Enc
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -175,6 +176,7 @@ func TestSentPacketHandlerAcknowledgeSkippedPacket(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -219,6 +221,7 @@ func TestSentPacketHandlerRTTAckEliciting(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		&eventRecorder,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -265,6 +268,9 @@ func TestSentPacketHandlerRTTAckEliciting(t *testing.T) {
 	now = now.Add(200 * time.Millisecond)
 	ackPackets(t, now, pn1, pn2, pn3)
 	require.Equal(t, 200*time.Millisecond, rttStats.LatestRTT())
+	largestNewlyAcked, rttSample := sph.LastAck()
+	require.Equal(t, pn3, largestNewlyAcked)
+	require.Equal(t, 200*time.Millisecond, rttSample)
 	require.Zero(t, getPacketsInFlight())
 	require.Zero(t, getBytesInFlight())
 
@@ -279,6 +285,9 @@ func TestSentPacketHandlerRTTAckEliciting(t *testing.T) {
 	// only non-ack-eliciting packets are newly acknowledged, so the RTT is not updated
 	ackPackets(t, now, pn2, pn3, pn4, pn5)
 	require.Equal(t, 200*time.Millisecond, rttStats.LatestRTT())
+	largestNewlyAcked, rttSample = sph.LastAck()
+	require.Equal(t, pn5, largestNewlyAcked)
+	require.Zero(t, rttSample)
 
 	pn6 := sendPacket(t, now, 1400, true)
 	require.Equal(t, 1, getPacketsInFlight())
@@ -294,6 +303,15 @@ func TestSentPacketHandlerRTTAckEliciting(t *testing.T) {
 	require.Equal(t, 800*time.Millisecond, rttStats.LatestRTT())
 	require.Zero(t, getPacketsInFlight())
 	require.Zero(t, getBytesInFlight())
+	largestNewlyAcked, rttSample = sph.LastAck()
+	require.Equal(t, pn7, largestNewlyAcked)
+	require.Equal(t, 800*time.Millisecond, rttSample)
+
+	// a duplicate ACK doesn't acknowledge any new packets
+	ackPackets(t, now.Add(time.Second), pn6, pn7)
+	largestNewlyAcked, rttSample = sph.LastAck()
+	require.Equal(t, protocol.InvalidPacketNumber, largestNewlyAcked)
+	require.Zero(t, rttSample)
 }
 
 func TestSentPacketHandlerRTTAcrossPacketNumberSpaces(t *testing.T) {
@@ -308,6 +326,7 @@ func TestSentPacketHandlerRTTAcrossPacketNumberSpaces(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -343,6 +362,55 @@ func TestSentPacketHandlerRTTAcrossPacketNumberSpaces(t *testing.T) {
 	require.Equal(t, 3*time.Second, rttStats.LatestRTT())
 }
 
+func TestSentPacketHandlerPacketNumberSpaceIsolation(t *testing.T) {
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&utils.ConnectionStats{},
+		false,
+		false,
+		nil,
+		protocol.PerspectiveClient,
+		nil,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	)
+
+	var initialPackets, handshakePackets packetTracker
+	var initialPNs, handshakePNs []protocol.PacketNumber
+	now := monotime.Now()
+	for range 2 {
+		pn := sph.PopPacketNumber(protocol.EncryptionInitial)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{initialPackets.NewPingFrame(pn)}, protocol.EncryptionInitial, protocol.ECNNon, 1200, false, false)
+		initialPNs = append(initialPNs, pn)
+	}
+	for range 5 {
+		pn := sph.PopPacketNumber(protocol.EncryptionHandshake)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{handshakePackets.NewPingFrame(pn)}, protocol.EncryptionHandshake, protocol.ECNNon, 1200, false, false)
+		handshakePNs = append(handshakePNs, pn)
+	}
+
+	// An ACK in the Initial space can't acknowledge packet numbers only used in the Handshake space.
+	_, err := sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(slices.Clone(handshakePNs)...)}, protocol.EncryptionInitial, monotime.Now())
+	require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation})
+	require.ErrorContains(t, err, "received ACK for an unsent packet")
+	require.Empty(t, initialPackets.Acked)
+	require.Empty(t, handshakePackets.Acked)
+
+	// The packet numbers of the Initial packets are also used in the Handshake space.
+	// Acknowledging them in the Initial space only acknowledges the Initial packets.
+	_, err = sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(slices.Clone(initialPNs)...)}, protocol.EncryptionInitial, monotime.Now())
+	require.NoError(t, err)
+	require.Equal(t, initialPNs, initialPackets.Acked)
+	require.Empty(t, handshakePackets.Acked)
+
+	_, err = sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(slices.Clone(handshakePNs)...)}, protocol.EncryptionHandshake, monotime.Now())
+	require.NoError(t, err)
+	require.Equal(t, handshakePNs, handshakePackets.Acked)
+}
+
 func TestSentPacketHandlerRTTAckDelays(t *testing.T) {
 	t.Run("Initial", func(t *testing.T) {
 		testSentPacketHandlerRTTAckDelays(t, protocol.EncryptionInitial, false)
@@ -370,6 +438,7 @@ func testSentPacketHandlerRTTAckDelays(t *testing.T, encLevel protocol.Encryptio
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -462,6 +531,7 @@ func testSentPacketHandlerAmplificationLimitServer(t *testing.T, addressValidate
 		nil,
 		protocol.PerspectiveServer,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -534,6 +604,7 @@ func testSentPacketHandlerAmplificationLimitClient(t *testing.T, dropHandshake b
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -591,6 +662,7 @@ func TestSentPacketHandlerDelayBasedLossDetection(t *testing.T) {
 		nil,
 		protocol.PerspectiveServer,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -647,6 +719,7 @@ func TestSentPacketHandlerPacketBasedLossDetection(t *testing.T) {
 		nil,
 		protocol.PerspectiveServer,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -679,6 +752,53 @@ func TestSentPacketHandlerPacketBasedLossDetection(t *testing.T) {
 	require.Equal(t, []protocol.PacketNumber{pns[0], pns[1]}, packets.Lost)
 }
 
+func TestSentPacketHandlerBurstLossSummary(t *testing.T) {
+	var eventRecorder events.Recorder
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&utils.ConnectionStats{},
+		true,
+		false,
+		nil,
+		protocol.PerspectiveServer,
+		&eventRecorder,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	)
+
+	var packets packetTracker
+	now := monotime.Now()
+	var pns []protocol.PacketNumber
+	for range 10 {
+		pn := sph.PopPacketNumber(protocol.Encryption1RTT)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.Encryption1RTT, protocol.ECNNon, 1000, false, false)
+		pns = append(pns, pn)
+	}
+
+	// packets 0 to 5 are lost in a burst
+	_, err := sph.ReceivedAck(
+		&wire.AckFrame{AckRanges: ackRanges(pns[6], pns[7], pns[8], pns[9])},
+		protocol.Encryption1RTT,
+		now.Add(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.Equal(t, pns[:6], packets.Lost)
+	require.Len(t, eventRecorder.Events(qlog.PacketLost{}), 6)
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.PacketsLost{
+				EncryptionLevel: protocol.Encryption1RTT,
+				PacketNumbers:   pns[:6],
+				Trigger:         qlog.PacketLossReorderingThreshold,
+			},
+		},
+		eventRecorder.Events(qlog.PacketsLost{}),
+	)
+}
+
 func TestSentPacketHandlerPTO(t *testing.T) {
 	t.Run("Initial", func(t *testing.T) {
 		testSentPacketHandlerPTO(t, protocol.EncryptionInitial, SendPTOInitial)
@@ -710,6 +830,7 @@ func testSentPacketHandlerPTO(t *testing.T, encLevel protocol.EncryptionLevel, p
 		nil,
 		protocol.PerspectiveServer,
 		&eventRecorder,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -788,6 +909,7 @@ func testSentPacketHandlerPTO(t *testing.T, encLevel protocol.EncryptionLevel, p
 	)
 	// PTO timer expiration doesn't declare packets lost
 	require.Empty(t, packets.Lost)
+	require.Equal(t, uint32(1), sph.PTOCount())
 
 	now = timeout
 	require.Equal(t, ptoMode, sph.SendMode(now))
@@ -843,6 +965,7 @@ func testSentPacketHandlerPTO(t *testing.T, encLevel protocol.EncryptionLevel, p
 	eventRecorder.Clear()
 	// PTO timer expiration doesn't declare packets lost
 	require.Empty(t, packets.Lost)
+	require.Equal(t, uint32(2), sph.PTOCount())
 
 	// send packet 7, 8 as probe packets
 	sendTimes = append(sendTimes, now.Add(100*time.Millisecond))
@@ -870,6 +993,7 @@ func testSentPacketHandlerPTO(t *testing.T, encLevel protocol.EncryptionLevel, p
 	require.NoError(t, err)
 	require.Equal(t, []protocol.PacketNumber{pns[7]}, packets.Acked)
 	require.Equal(t, []protocol.PacketNumber{pns[4], pns[6]}, packets.Lost)
+	require.Zero(t, sph.PTOCount())
 	require.Len(t, eventRecorder.Events(qlog.PacketLost{}), 2)
 	require.Equal(t,
 		[]qlogwriter.Event{
@@ -929,6 +1053,7 @@ func TestSentPacketHandlerPacketNumberSpacesPTO(t *testing.T) {
 		nil,
 		protocol.PerspectiveServer,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1023,6 +1148,7 @@ func TestSentPacketHandler0RTT(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1065,17 +1191,21 @@ func TestSentPacketHandler0RTT(t *testing.T) {
 func TestSentPacketHandlerCongestion(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+	// the congestion window is published in the connection stats
+	cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(12345)).AnyTimes()
 	rttStats := utils.NewRTTStats()
+	var connStats utils.ConnectionStats
 	sph := NewSentPacketHandler(
 		0,
 		1200,
 		rttStats,
-		&utils.ConnectionStats{},
+		&connStats,
 		true,
 		false,
 		nil,
 		protocol.PerspectiveServer,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1102,6 +1232,8 @@ func TestSentPacketHandlerCongestion(t *testing.T) {
 		sendTimes = append(sendTimes, now)
 		now = now.Add(100 * time.Millisecond)
 	}
+	require.Equal(t, uint64(bytesInFlight), connStats.BytesInFlight.Load())
+	require.Equal(t, uint64(12345), connStats.CongestionWindow.Load())
 
 	// try to send another packet: not congestion-limited, but pacing-limited
 	now = now.Add(100 * time.Millisecond)
@@ -1134,6 +1266,7 @@ func TestSentPacketHandlerCongestion(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []protocol.PacketNumber{pns[2], pns[3]}, packets.Acked)
 	require.Equal(t, []protocol.PacketNumber{pns[0], pns[1]}, packets.Lost)
+	require.Equal(t, uint64(1000), connStats.BytesInFlight.Load())
 
 	// Now receive a (delayed) ACK for the 1st packet.
 	// Since this packet was already lost, we don't expect any calls to the congestion controller.
@@ -1153,6 +1286,149 @@ func TestSentPacketHandlerCongestion(t *testing.T) {
 	sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.EncryptionInitial, protocol.ECNNon, 1000, false, false)
 }
 
+func TestSentPacketHandlerSetCongestionControl(t *testing.T) {
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&utils.ConnectionStats{},
+		true,
+		false,
+		nil,
+		protocol.PerspectiveServer,
+		nil,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	).(*sentPacketHandler)
+	reno := sph.congestion
+	require.True(t, reno.InSlowStart())
+	require.EqualValues(t, congestion.NewReno, sph.connStats.CongestionControl.Load())
+	cwnd := reno.GetCongestionWindow()
+
+	now := monotime.Now()
+	// switching to the algorithm that's already in use is a no-op
+	sph.SetCongestionControl(congestion.NewReno, 1200, now)
+	require.Same(t, reno, sph.congestion)
+
+	// the new congestion controller continues with the current state,
+	// and doesn't start over
+	sph.SetCongestionControl(congestion.CUBIC, 1200, now)
+	require.NotSame(t, reno, sph.congestion)
+	require.Equal(t, cwnd, sph.congestion.GetCongestionWindow())
+	require.True(t, sph.congestion.InSlowStart())
+	require.Equal(t, congestion.CUBIC, sph.congestionControl)
+	require.EqualValues(t, congestion.CUBIC, sph.connStats.CongestionControl.Load())
+
+	// enter recovery
+	var packets packetTracker
+	var pns []protocol.PacketNumber
+	for range 10 {
+		pn := sph.PopPacketNumber(protocol.Encryption1RTT)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.Encryption1RTT, protocol.ECNNon, 1200, false, false)
+		pns = append(pns, pn)
+	}
+	now = now.Add(100 * time.Millisecond)
+	_, err := sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(pns[4])}, protocol.Encryption1RTT, now)
+	require.NoError(t, err)
+	packetsLost := sph.connStats.PacketsLost.Load()
+	require.NotZero(t, packetsLost)
+	cubic := sph.congestion
+	require.True(t, cubic.InRecovery())
+	cwnd = cubic.GetCongestionWindow()
+	require.Less(t, cwnd, reno.GetCongestionWindow())
+
+	sph.SetCongestionControl(congestion.NewReno, 1200, now)
+	require.NotSame(t, cubic, sph.congestion)
+	require.Equal(t, cwnd, sph.congestion.GetCongestionWindow())
+	require.True(t, sph.congestion.InRecovery())
+	require.False(t, sph.congestion.InSlowStart())
+	// the loss of another packet sent before the congestion event doesn't reduce the congestion window again
+	_, err = sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(pns[4:]...)}, protocol.Encryption1RTT, now)
+	require.NoError(t, err)
+	require.Greater(t, sph.connStats.PacketsLost.Load(), packetsLost)
+	require.Equal(t, cwnd, sph.congestion.GetCongestionWindow())
+}
+
+func TestSentPacketHandlerSetCongestionControlStateMismatch(t *testing.T) {
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&utils.ConnectionStats{},
+		true,
+		false,
+		nil,
+		protocol.PerspectiveServer,
+		nil,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	).(*sentPacketHandler)
+	// an RTO collapses the congestion window to the minimum congestion window
+	sph.congestion.OnRetransmissionTimeout(true)
+	cwnd := sph.congestion.GetCongestionWindow()
+	require.Equal(t, 2*protocol.ByteCount(1200), cwnd)
+
+	// With a larger datagram size, this window is smaller than the minimum congestion window of the new controller.
+	// The new congestion controller therefore starts in congestion avoidance, using the current congestion window.
+	sph.SetCongestionControl(congestion.CUBIC, 1500, monotime.Now())
+	require.Equal(t, cwnd, sph.congestion.GetCongestionWindow())
+	require.False(t, sph.congestion.InSlowStart())
+	require.False(t, sph.congestion.InRecovery())
+	require.Equal(t, congestion.CUBIC, sph.congestionControl)
+}
+
+func TestSentPacketHandlerReduceMaxDatagramSize(t *testing.T) {
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&utils.ConnectionStats{},
+		true,
+		false,
+		nil,
+		protocol.PerspectiveServer,
+		nil,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	).(*sentPacketHandler)
+	sph.SetMaxDatagramSize(1400)
+	cwnd := sph.congestion.GetCongestionWindow()
+
+	now := monotime.Now()
+	var packets packetTracker
+	var pns []protocol.PacketNumber
+	for i := range 6 {
+		size := protocol.ByteCount(1200)
+		if i%2 == 0 {
+			size = 1400
+		}
+		pn := sph.PopPacketNumber(protocol.Encryption1RTT)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.Encryption1RTT, protocol.ECNNon, size, false, false)
+		pns = append(pns, pn)
+	}
+	require.Equal(t, protocol.ByteCount(3*1200+3*1400), sph.getBytesInFlight())
+
+	// the packets larger than the new size are declared lost
+	sph.ReduceMaxDatagramSize(1300, now)
+	require.Equal(t, []protocol.PacketNumber{pns[0], pns[2], pns[4]}, packets.Lost)
+	require.Equal(t, protocol.ByteCount(3*1200), sph.getBytesInFlight())
+	require.EqualValues(t, 3*1200, sph.connStats.BytesInFlight.Load())
+	// this is not a congestion event
+	require.False(t, sph.congestion.InRecovery())
+	require.Equal(t, cwnd, sph.congestion.GetCongestionWindow())
+	require.Equal(t, congestion.NewReno, sph.congestionControl)
+	// the max datagram size can be increased again
+	sph.SetMaxDatagramSize(1350)
+
+	_, err := sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(slices.Clone(pns)...)}, protocol.Encryption1RTT, now.Add(10*time.Millisecond))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []protocol.PacketNumber{pns[1], pns[3], pns[5]}, packets.Acked)
+	require.Zero(t, sph.getBytesInFlight())
+}
+
 func TestSentPacketHandlerRetry(t *testing.T) {
 	t.Run("long RTT measurement", func(t *testing.T) {
 		testSentPacketHandlerRetry(t, time.Second, time.Second)
@@ -1178,6 +1454,7 @@ func testSentPacketHandlerRetry(t *testing.T, rtt, expectedRTT time.Duration) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1231,6 +1508,7 @@ func TestSentPacketHandlerRetryAfterPTO(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1263,6 +1541,7 @@ func TestSentPacketHandlerRetryAfterPTO(t *testing.T) {
 func TestSentPacketHandlerECN(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+	cong.EXPECT().GetCongestionWindow().AnyTimes()
 	cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	cong.EXPECT().MaybeExitSlowStart().AnyTimes()
@@ -1277,6 +1556,7 @@ func TestSentPacketHandlerECN(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1383,6 +1663,7 @@ func TestSentPacketHandlerPathProbe(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1465,6 +1746,7 @@ func TestSentPacketHandlerPathProbeAckAndLoss(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1543,6 +1825,7 @@ func testSentPacketHandlerRandomized(t *testing.T, seed uint64) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1614,6 +1897,7 @@ func TestSentPacketHandlerSpuriousLoss(t *testing.T) {
 		nil,
 		protocol.PerspectiveClient,
 		&eventRecorder,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1751,6 +2035,7 @@ func benchmarkSendAndAcknowledge(b *testing.B, ackEvery, inFlight int) {
 		nil,
 		protocol.PerspectiveClient,
 		nil,
+		nil,
 		utils.DefaultLogger,
 		congestion.NewReno,
 	)
@@ -1792,3 +2077,48 @@ func benchmarkSendAndAcknowledge(b *testing.B, ackEvery, inFlight int) {
 		}
 	}
 }
+
+func TestSentPacketHandlerAckDelayReceived(t *testing.T) {
+	var eventRecorder events.Recorder
+	rttStats := utils.NewRTTStats()
+	rttStats.SetMaxAckDelay(25 * time.Millisecond)
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		rttStats,
+		&utils.ConnectionStats{},
+		true,
+		false,
+		nil,
+		protocol.PerspectiveClient,
+		&eventRecorder,
+		nil,
+		utils.DefaultLogger,
+		congestion.NewReno,
+	)
+
+	var packets packetTracker
+	now := monotime.Now()
+	hsPN := sph.PopPacketNumber(protocol.EncryptionHandshake)
+	sph.SentPacket(now, hsPN, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(hsPN)}, protocol.EncryptionHandshake, protocol.ECNNon, 1000, false, false)
+	pn := sph.PopPacketNumber(protocol.Encryption1RTT)
+	sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.Encryption1RTT, protocol.ECNNon, 1000, false, false)
+
+	// ACKs for Handshake packets are ignored
+	_, err := sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(hsPN), DelayTime: time.Second}, protocol.EncryptionHandshake, now.Add(time.Second))
+	require.NoError(t, err)
+	// the ack delay is reported before it is limited to max_ack_delay
+	_, err = sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(pn), DelayTime: time.Second}, protocol.Encryption1RTT, now.Add(time.Second))
+	require.NoError(t, err)
+	// duplicate ACKs are reported as well
+	_, err = sph.ReceivedAck(&wire.AckFrame{AckRanges: ackRanges(pn), DelayTime: 10 * time.Millisecond}, protocol.Encryption1RTT, now.Add(time.Second))
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.AckDelayReceived{LargestAcked: pn, AckDelay: time.Second},
+			qlog.AckDelayReceived{LargestAcked: pn, AckDelay: 10 * time.Millisecond},
+		},
+		eventRecorder.Events(qlog.AckDelayReceived{}),
+	)
+}
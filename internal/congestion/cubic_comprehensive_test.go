@@ -26,6 +26,7 @@ func TestCubicSenderConfig(t *testing.T) {
 			10*maxDatagramSize,
 			100*maxDatagramSize,
 			nil,
+			nil,
 		)
 
 		// Enter congestion avoidance
@@ -66,6 +67,7 @@ func TestCubicSenderConfig(t *testing.T) {
 			10*maxDatagramSize,
 			100*maxDatagramSize,
 			nil,
+			nil,
 		)
 
 		// Enter congestion avoidance
@@ -59,11 +59,13 @@ type cubicSender struct {
 
 	lastState qlog.CongestionState
 	qlogger   qlogwriter.Recorder
+	hooks     *Hooks
 }
 
 var (
 	_ SendAlgorithm               = &cubicSender{}
 	_ SendAlgorithmWithDebugInfos = &cubicSender{}
+	_ SendAlgorithmWithState      = &cubicSender{}
 )
 
 // NewCubicSender makes a new cubic sender
@@ -74,6 +76,7 @@ func NewCubicSender(
 	initialMaxDatagramSize protocol.ByteCount,
 	reno bool,
 	qlogger qlogwriter.Recorder,
+	hooks *Hooks,
 ) *cubicSender {
 	return newCubicSender(
 		clock,
@@ -84,9 +87,38 @@ func NewCubicSender(
 		initialCongestionWindow*initialMaxDatagramSize,
 		protocol.MaxCongestionWindowPackets*initialMaxDatagramSize,
 		qlogger,
+		hooks,
 	)
 }
 
+// NewCubicSenderInCongestionAvoidance makes a new cubic sender that starts in congestion avoidance,
+// using the given congestion window.
+// It is used when switching the congestion controller of an established connection.
+func NewCubicSenderInCongestionAvoidance(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	connStats *utils.ConnectionStats,
+	maxDatagramSize protocol.ByteCount,
+	congestionWindow protocol.ByteCount,
+	reno bool,
+	qlogger qlogwriter.Recorder,
+	hooks *Hooks,
+) *cubicSender {
+	c := newCubicSender(
+		clock,
+		rttStats,
+		connStats,
+		reno,
+		maxDatagramSize,
+		congestionWindow,
+		protocol.MaxCongestionWindowPackets*maxDatagramSize,
+		qlogger,
+		hooks,
+	)
+	c.slowStartThreshold = congestionWindow
+	return c
+}
+
 func newCubicSender(
 	clock Clock,
 	rttStats *utils.RTTStats,
@@ -96,6 +128,7 @@ func newCubicSender(
 	initialCongestionWindow,
 	initialMaxCongestionWindow protocol.ByteCount,
 	qlogger qlogwriter.Recorder,
+	hooks *Hooks,
 ) *cubicSender {
 	c := &cubicSender{
 		rttStats:                   rttStats,
@@ -111,15 +144,11 @@ func newCubicSender(
 		clock:                      clock,
 		reno:                       reno,
 		qlogger:                    qlogger,
+		hooks:                      hooks,
 		maxDatagramSize:            initialMaxDatagramSize,
 	}
 	c.pacer = newPacer(c.BandwidthEstimate)
-	if c.qlogger != nil {
-		c.lastState = qlog.CongestionStateSlowStart
-		c.qlogger.RecordEvent(qlog.CongestionStateUpdated{
-			State: qlog.CongestionStateSlowStart,
-		})
-	}
+	c.maybeQlogStateChange(qlog.CongestionStateSlowStart)
 	return c
 }
 
@@ -132,6 +161,11 @@ func (c *cubicSender) HasPacingBudget(now monotime.Time) bool {
 	return c.pacer.Budget(now) >= c.maxDatagramSize
 }
 
+// PacingBudget returns the current fill level of the pacer's token bucket.
+func (c *cubicSender) PacingBudget(now monotime.Time) protocol.ByteCount {
+	return c.pacer.Budget(now)
+}
+
 func (c *cubicSender) maxCongestionWindow() protocol.ByteCount {
 	return c.maxDatagramSize * protocol.MaxCongestionWindowPackets
 }
@@ -187,6 +221,7 @@ func (c *cubicSender) OnPacketAcked(
 	eventTime monotime.Time,
 ) {
 	c.largestAckedPacketNumber = max(ackedPacketNumber, c.largestAckedPacketNumber)
+	c.updateCongestionLimited(c.isCwndLimited(priorInFlight))
 	if c.InRecovery() {
 		return
 	}
@@ -309,12 +344,70 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.slowStartThreshold = c.initialMaxCongestionWindow
 }
 
+// ExportState exports the state of the congestion controller.
+func (c *cubicSender) ExportState(now monotime.Time) State {
+	return State{
+		CongestionWindow:   c.congestionWindow,
+		SlowStartThreshold: c.slowStartThreshold,
+		PacingRate:         c.pacer.Rate(),
+		PacingBudget:       c.pacer.Budget(now),
+		InRecovery:         c.InRecovery(),
+		RecoveryStart:      c.largestSentAtLastCutback,
+		LargestSent:        c.largestSentPacketNumber,
+		LargestAcked:       c.largestAckedPacketNumber,
+	}
+}
+
+// ImportState initializes the congestion controller from a state exported by another congestion controller.
+// The pacing rate is not imported, since it is derived from the congestion window and the RTT.
+func (c *cubicSender) ImportState(s State, now monotime.Time) error {
+	if s.CongestionWindow < c.minCongestionWindow() || s.CongestionWindow > c.maxCongestionWindow() {
+		return fmt.Errorf("%w: congestion window %d out of range", ErrStateMismatch, s.CongestionWindow)
+	}
+	if s.SlowStartThreshold < c.minCongestionWindow() {
+		return fmt.Errorf("%w: slow start threshold %d too small", ErrStateMismatch, s.SlowStartThreshold)
+	}
+	inRecovery := s.LargestAcked != protocol.InvalidPacketNumber && s.LargestAcked <= s.RecoveryStart
+	if s.InRecovery != inRecovery {
+		return fmt.Errorf("%w: inconsistent recovery state", ErrStateMismatch)
+	}
+	c.congestionWindow = s.CongestionWindow
+	c.slowStartThreshold = s.SlowStartThreshold
+	c.largestSentAtLastCutback = s.RecoveryStart
+	c.largestSentPacketNumber = s.LargestSent
+	c.largestAckedPacketNumber = s.LargestAcked
+	c.pacer.SetBudget(s.PacingBudget, now)
+	switch {
+	case c.InRecovery():
+		c.maybeQlogStateChange(qlog.CongestionStateRecovery)
+	case c.InSlowStart():
+		c.maybeQlogStateChange(qlog.CongestionStateSlowStart)
+	default:
+		c.maybeQlogStateChange(qlog.CongestionStateCongestionAvoidance)
+	}
+	return nil
+}
+
+// updateCongestionLimited records whether the sender was using (almost) the entire congestion window
+// when the last packet was acknowledged.
+func (c *cubicSender) updateCongestionLimited(limited bool) {
+	if c.connStats.CongestionLimited.Swap(limited) == limited || c.qlogger == nil {
+		return
+	}
+	c.qlogger.RecordEvent(qlog.CongestionLimitedUpdated{Limited: limited})
+}
+
 func (c *cubicSender) maybeQlogStateChange(new qlog.CongestionState) {
-	if c.qlogger == nil || new == c.lastState {
+	if new == c.lastState {
 		return
 	}
-	c.qlogger.RecordEvent(qlog.CongestionStateUpdated{State: new})
 	c.lastState = new
+	if c.qlogger != nil {
+		c.qlogger.RecordEvent(qlog.CongestionStateUpdated{State: new})
+	}
+	if c.hooks != nil && c.hooks.OnCongestionStateChange != nil {
+		c.hooks.OnCongestionStateChange(new)
+	}
 }
 
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
@@ -8,6 +8,9 @@ import (
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
+	"github.com/quic-go/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/require"
 )
@@ -54,6 +57,7 @@ func newTestCubicSender(cubic bool) *testCubicSender {
 			initialCongestionWindowPackets*maxDatagramSize,
 			MaxCongestionWindow,
 			nil,
+			nil,
 		),
 	}
 }
@@ -136,6 +140,33 @@ func TestCubicSenderPacing(t *testing.T) {
 	require.Less(t, delay.Sub(monotime.Time(*sender.clock)), time.Hour)
 }
 
+func TestCubicSenderPacingBudget(t *testing.T) {
+	sender := newTestCubicSender(false)
+	sender.rttStats.UpdateRTT(10*time.Millisecond, 0)
+
+	// after idling, the token bucket is full
+	sender.clock.Advance(time.Hour)
+	maxBudget := sender.sender.pacer.maxBurstSize()
+	require.Equal(t, maxBudget, sender.sender.PacingBudget(sender.clock.Now()))
+
+	// the budget drains as packets are sent
+	budget := maxBudget
+	for sender.sender.HasPacingBudget(sender.clock.Now()) {
+		sender.sender.OnPacketSent(sender.clock.Now(), sender.bytesInFlight, sender.packetNumber, maxDatagramSize, true)
+		sender.packetNumber++
+		sender.bytesInFlight += maxDatagramSize
+		budget -= maxDatagramSize
+		require.Equal(t, budget, sender.sender.PacingBudget(sender.clock.Now()))
+	}
+	require.Less(t, budget, maxDatagramSize)
+
+	// and refills as time advances
+	sender.clock.Advance(time.Millisecond)
+	require.Greater(t, sender.sender.PacingBudget(sender.clock.Now()), budget)
+	sender.clock.Advance(time.Hour)
+	require.Equal(t, maxBudget, sender.sender.PacingBudget(sender.clock.Now()))
+}
+
 func TestCubicSenderApplicationLimitedSlowStart(t *testing.T) {
 	sender := newTestCubicSender(false)
 
@@ -156,6 +187,69 @@ func TestCubicSenderApplicationLimitedSlowStart(t *testing.T) {
 	require.Equal(t, defaultWindowTCP+maxDatagramSize*2*2, bytesToSend)
 }
 
+func TestCubicSenderCongestionLimited(t *testing.T) {
+	t.Run("small window", func(t *testing.T) {
+		testCubicSenderCongestionLimited(t, 10, true)
+	})
+	t.Run("large window", func(t *testing.T) {
+		testCubicSenderCongestionLimited(t, 100, false)
+	})
+}
+
+func testCubicSenderCongestionLimited(t *testing.T, cwndPackets protocol.ByteCount, expectLimited bool) {
+	// the application always has 20 packets worth of data to send
+	const numPackets = 20
+
+	var clock mockClock
+	var rttStats utils.RTTStats
+	var connStats utils.ConnectionStats
+	var eventRecorder events.Recorder
+	sender := newCubicSender(
+		&clock,
+		&rttStats,
+		&connStats,
+		false,
+		maxDatagramSize,
+		cwndPackets*maxDatagramSize,
+		MaxCongestionWindow,
+		&eventRecorder,
+		nil,
+	)
+
+	var bytesInFlight protocol.ByteCount
+	var pn protocol.PacketNumber
+	for ; pn < numPackets && sender.CanSend(bytesInFlight); pn++ {
+		sender.OnPacketSent(clock.Now(), bytesInFlight, pn, maxDatagramSize, true)
+		bytesInFlight += maxDatagramSize
+	}
+	rttStats.UpdateRTT(60*time.Millisecond, 0)
+	sender.OnPacketAcked(0, maxDatagramSize, bytesInFlight, clock.Now())
+	bytesInFlight -= maxDatagramSize
+	require.Equal(t, expectLimited, connStats.CongestionLimited.Load())
+	if !expectLimited {
+		require.Empty(t, eventRecorder.Events(qlog.CongestionLimitedUpdated{}))
+		return
+	}
+	require.Equal(t,
+		[]qlogwriter.Event{qlog.CongestionLimitedUpdated{Limited: true}},
+		eventRecorder.Events(qlog.CongestionLimitedUpdated{}),
+	)
+
+	// the application stops sending, and the outstanding packets are acknowledged
+	for i := protocol.PacketNumber(1); i < pn; i++ {
+		sender.OnPacketAcked(i, maxDatagramSize, bytesInFlight, clock.Now())
+		bytesInFlight -= maxDatagramSize
+	}
+	require.False(t, connStats.CongestionLimited.Load())
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.CongestionLimitedUpdated{Limited: true},
+			qlog.CongestionLimitedUpdated{Limited: false},
+		},
+		eventRecorder.Events(qlog.CongestionLimitedUpdated{}),
+	)
+}
+
 func TestCubicSenderExponentialSlowStart(t *testing.T) {
 	sender := newTestCubicSender(false)
 
@@ -488,6 +582,50 @@ func TestCubicSenderResetAfterConnectionMigration(t *testing.T) {
 	require.False(t, sender.sender.hybridSlowStart.Started())
 }
 
+func TestCubicSenderExportImportState(t *testing.T) {
+	sender := newTestCubicSender(false)
+	sender.rttStats.UpdateRTT(60*time.Millisecond, 0)
+	for range 5 {
+		sender.SendAvailableSendWindow()
+		sender.AckNPackets(2)
+	}
+	sender.SendAvailableSendWindow()
+	// lose a packet to enter recovery
+	sender.LoseNPackets(1)
+	require.True(t, sender.sender.InRecovery())
+
+	state := sender.sender.ExportState(sender.clock.Now())
+	require.Equal(t, sender.sender.GetCongestionWindow(), state.CongestionWindow)
+	require.Equal(t, sender.sender.GetCongestionWindow(), state.SlowStartThreshold)
+	require.InEpsilon(t, float64(sender.sender.BandwidthEstimate()*5/4), float64(state.PacingRate), 0.001)
+	require.True(t, state.InRecovery)
+	require.Equal(t, sender.packetNumber-1, state.RecoveryStart)
+
+	reno := newTestCubicSender(false)
+	reno.sender.reno = true
+	require.NoError(t, reno.sender.ImportState(state, sender.clock.Now()))
+	require.Equal(t, state.CongestionWindow, reno.sender.GetCongestionWindow())
+	require.True(t, reno.sender.InRecovery())
+	require.False(t, reno.sender.InSlowStart())
+	require.Equal(t, state.PacingBudget, reno.sender.PacingBudget(sender.clock.Now()))
+	// another loss of a packet sent before entering recovery doesn't reduce the congestion window
+	reno.sender.OnCongestionEvent(state.RecoveryStart, maxDatagramSize, state.CongestionWindow)
+	require.Equal(t, state.CongestionWindow, reno.sender.GetCongestionWindow())
+
+	t.Run("mismatched state", func(t *testing.T) {
+		for _, s := range []State{
+			{CongestionWindow: maxDatagramSize, SlowStartThreshold: protocol.MaxByteCount},
+			{CongestionWindow: 2 * MaxCongestionWindow, SlowStartThreshold: protocol.MaxByteCount},
+			{CongestionWindow: defaultWindowTCP, SlowStartThreshold: 0},
+			{CongestionWindow: defaultWindowTCP, SlowStartThreshold: protocol.MaxByteCount, InRecovery: true, LargestAcked: protocol.InvalidPacketNumber},
+		} {
+			sender := newTestCubicSender(false)
+			require.ErrorIs(t, sender.sender.ImportState(s, sender.clock.Now()), ErrStateMismatch)
+			require.Equal(t, defaultWindowTCP, sender.sender.GetCongestionWindow())
+		}
+	})
+}
+
 func TestCubicSenderSlowStartsUpToMaximumCongestionWindow(t *testing.T) {
 	var clock mockClock
 	rttStats := utils.RTTStats{}
@@ -501,6 +639,7 @@ func TestCubicSenderSlowStartsUpToMaximumCongestionWindow(t *testing.T) {
 		initialCongestionWindowPackets*maxDatagramSize,
 		initialMaxCongestionWindow,
 		nil,
+		nil,
 	)
 
 	for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
@@ -528,6 +667,7 @@ func TestCubicSenderSlowStartsPacketSizeIncrease(t *testing.T) {
 		initialCongestionWindowPackets*maxDatagramSize,
 		initialMaxCongestionWindow,
 		nil,
+		nil,
 	)
 	const packetSize = initialMaxDatagramSize + 100
 	sender.SetMaxDatagramSize(packetSize)
@@ -552,6 +692,7 @@ func TestCubicSenderLimitCwndIncreaseInCongestionAvoidance(t *testing.T) {
 		initialCongestionWindowPackets*maxDatagramSize,
 		MaxCongestionWindow,
 		nil,
+		nil,
 	)
 	testSender := &testCubicSender{
 		sender:   sender,
@@ -592,3 +733,44 @@ func TestCubicSenderLimitCwndIncreaseInCongestionAvoidance(t *testing.T) {
 	testSender.AckNPackets(2)
 	require.Equal(t, savedCwnd+maxDatagramSize, sender.GetCongestionWindow())
 }
+
+func TestCubicSenderHooks(t *testing.T) {
+	var clock mockClock
+	rttStats := utils.RTTStats{}
+	var states []qlog.CongestionState
+	sender := &testCubicSender{
+		clock:        &clock,
+		rttStats:     &rttStats,
+		packetNumber: 1,
+		sender: newCubicSender(
+			&clock,
+			&rttStats,
+			&utils.ConnectionStats{},
+			true,
+			protocol.InitialPacketSize,
+			initialCongestionWindowPackets*maxDatagramSize,
+			MaxCongestionWindow,
+			nil, // the hooks are called without a qlog recorder
+			&Hooks{OnCongestionStateChange: func(s qlog.CongestionState) { states = append(states, s) }},
+		),
+	}
+	require.Equal(t, []qlog.CongestionState{qlog.CongestionStateSlowStart}, states)
+
+	sender.SendAvailableSendWindow()
+	sender.AckNPackets(2)
+	sender.SendAvailableSendWindow()
+	sender.LoseNPackets(1)
+	require.Equal(t, []qlog.CongestionState{qlog.CongestionStateSlowStart, qlog.CongestionStateRecovery}, states)
+	// recovery ends once a packet sent after the loss is acknowledged
+	sender.AckNPackets(int(sender.bytesInFlight / maxDatagramSize))
+	sender.SendAvailableSendWindow()
+	sender.AckNPackets(1)
+	require.Equal(t,
+		[]qlog.CongestionState{
+			qlog.CongestionStateSlowStart,
+			qlog.CongestionStateRecovery,
+			qlog.CongestionStateCongestionAvoidance,
+		},
+		states,
+	)
+}
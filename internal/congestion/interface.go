@@ -1,8 +1,11 @@
 package congestion
 
 import (
+	"errors"
+
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/qlog"
 )
 
 // CongestionControlAlgorithm is the congestion control algorithm to use.
@@ -15,6 +18,15 @@ const (
 	CUBIC
 )
 
+// Hooks allow observing the congestion controller independently of qlog.
+// Both callbacks are optional.
+type Hooks struct {
+	// OnCongestionStateChange is called when the congestion state changes.
+	OnCongestionStateChange func(qlog.CongestionState)
+	// OnCongestionWindowChange is called when the congestion window changes.
+	OnCongestionWindowChange func(protocol.ByteCount)
+}
+
 // A SendAlgorithm performs congestion control
 type SendAlgorithm interface {
 	TimeUntilSend(bytesInFlight protocol.ByteCount) monotime.Time
@@ -34,4 +46,39 @@ type SendAlgorithmWithDebugInfos interface {
 	InSlowStart() bool
 	InRecovery() bool
 	GetCongestionWindow() protocol.ByteCount
+	// PacingBudget returns the number of bytes the pacer allows to be sent at the given time.
+	PacingBudget(now monotime.Time) protocol.ByteCount
+}
+
+// ErrStateMismatch is returned by ImportState if the state can't be imported.
+var ErrStateMismatch = errors.New("congestion: mismatched congestion controller state")
+
+// State is a snapshot of the state of a congestion controller.
+// It is used to carry over the state when switching the congestion controller of a connection.
+type State struct {
+	CongestionWindow   protocol.ByteCount
+	SlowStartThreshold protocol.ByteCount
+	// PacingRate is the rate at which the pacer releases packets.
+	PacingRate Bandwidth
+	// PacingBudget is the number of bytes the pacer allows to be sent at the time of the export.
+	PacingBudget protocol.ByteCount
+
+	// InRecovery says if the sender is in recovery.
+	// Recovery ends once a packet sent after RecoveryStart is acknowledged.
+	InRecovery bool
+	// RecoveryStart is the largest packet number sent when the last congestion event occurred.
+	// Losses of packets up to this packet number belong to the same congestion event.
+	RecoveryStart protocol.PacketNumber
+	LargestSent   protocol.PacketNumber
+	LargestAcked  protocol.PacketNumber
+}
+
+// A SendAlgorithmWithState is a SendAlgorithm that can export its state,
+// and that can be initialized from the state exported by a (different) SendAlgorithm.
+type SendAlgorithmWithState interface {
+	SendAlgorithm
+	ExportState(now monotime.Time) State
+	// ImportState initializes the congestion controller from the state.
+	// It returns ErrStateMismatch if the state is inconsistent, or can't be represented by this controller.
+	ImportState(s State, now monotime.Time) error
 }
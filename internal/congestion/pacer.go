@@ -105,6 +105,18 @@ func (p *pacer) TimeUntilSend() monotime.Time {
 	return p.lastSentTime.Add(max(protocol.MinPacingDelay, time.Duration(d)*time.Nanosecond))
 }
 
+// Rate returns the pacing rate.
+func (p *pacer) Rate() Bandwidth {
+	return Bandwidth(p.adjustedBandwidth()) * BytesPerSecond
+}
+
+// SetBudget sets the budget available at the given time.
+// It is used when the pacer takes over from the pacer of another congestion controller.
+func (p *pacer) SetBudget(budget protocol.ByteCount, now monotime.Time) {
+	p.budgetAtLastSent = min(budget, p.maxBurstSize())
+	p.lastSentTime = now
+}
+
 func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
 	p.maxDatagramSize = s
 }
@@ -25,6 +25,8 @@ type baseFlowController struct {
 	maxReceiveWindowSize protocol.ByteCount
 
 	allowWindowIncrease func(size protocol.ByteCount) bool
+	// shrinkWindow is called when an auto-tuning epoch ends without the window being consumed quickly.
+	shrinkWindow func()
 
 	epochStartTime   monotime.Time
 	epochStartOffset protocol.ByteCount
@@ -84,6 +86,11 @@ func (c *baseFlowController) getWindowUpdate(now monotime.Time) protocol.ByteCou
 	}
 
 	c.maybeAdjustWindowSize(now)
+	// If the window size was reduced, the offset might not increase:
+	// Credit that was already advertised can't be taken back.
+	if c.bytesRead+c.receiveWindowSize <= c.receiveWindow {
+		return 0
+	}
 	c.receiveWindow = c.bytesRead + c.receiveWindowSize
 	return c.receiveWindow
 }
@@ -108,6 +115,8 @@ func (c *baseFlowController) maybeAdjustWindowSize(now monotime.Time) {
 		if newSize > c.receiveWindowSize && (c.allowWindowIncrease == nil || c.allowWindowIncrease(newSize-c.receiveWindowSize)) {
 			c.receiveWindowSize = newSize
 		}
+	} else if c.shrinkWindow != nil {
+		c.shrinkWindow()
 	}
 	c.startNewAutoTuningEpoch(now)
 }
@@ -12,6 +12,11 @@ import (
 
 type connectionFlowController struct {
 	baseFlowController
+
+	// If window donation is enabled, streams borrow from the connection-level receive window
+	// to increase their receive windows. In total, they can't borrow more than the connection window.
+	windowDonation bool
+	donatedWindow  protocol.ByteCount
 }
 
 var _ ConnectionFlowController = &connectionFlowController{}
@@ -96,6 +101,36 @@ func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCoun
 	c.startNewAutoTuningEpoch(now)
 }
 
+// EnableReceiveWindowDonation enables receive window donation.
+// Streams then only auto-tune their receive windows by borrowing from the connection-level window,
+// and return the borrowed window when they stop consuming data quickly, or when they are done.
+// It must be called before any stream flow controller is created.
+func (c *connectionFlowController) EnableReceiveWindowDonation() {
+	c.windowDonation = true
+}
+
+func (c *connectionFlowController) donatesReceiveWindow() bool {
+	return c.windowDonation
+}
+
+func (c *connectionFlowController) borrowReceiveWindow(n protocol.ByteCount) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.donatedWindow+n > c.receiveWindowSize {
+		return false
+	}
+	c.donatedWindow += n
+	return true
+}
+
+func (c *connectionFlowController) returnReceiveWindow(n protocol.ByteCount) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.donatedWindow -= n
+}
+
 // Reset rests the flow controller. This happens when 0-RTT is rejected.
 // All stream data is invalidated, it's as if we had never opened a stream and never sent any data.
 // At that point, we only have sent stream data, but we didn't have the keys to open 1-RTT keys yet.
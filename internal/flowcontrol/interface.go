@@ -26,12 +26,17 @@ type StreamFlowController interface {
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
 	IsNewlyBlocked() bool
+	// Offsets returns the send window granted by the peer, the number of bytes sent,
+	// the receive window advertised to the peer, and the number of bytes read.
+	// It is safe to call concurrently with the other methods.
+	Offsets() (sendWindow, bytesSent, receiveWindow, bytesRead protocol.ByteCount)
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
 	AddBytesRead(protocol.ByteCount) (hasWindowUpdate bool)
+	EnableReceiveWindowDonation()
 	Reset() error
 	IsNewlyBlocked() (bool, protocol.ByteCount)
 }
@@ -43,4 +48,7 @@ type connectionFlowControllerI interface {
 	EnsureMinimumWindowSize(protocol.ByteCount, monotime.Time)
 	// for receiving
 	IncrementHighestReceived(protocol.ByteCount, monotime.Time) error
+	donatesReceiveWindow() bool
+	borrowReceiveWindow(protocol.ByteCount) (ok bool)
+	returnReceiveWindow(protocol.ByteCount)
 }
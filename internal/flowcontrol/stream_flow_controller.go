@@ -17,6 +17,9 @@ type streamFlowController struct {
 	connection connectionFlowControllerI
 
 	receivedFinalOffset bool
+
+	// only set if receive window donation is enabled
+	initialReceiveWindowSize protocol.ByteCount
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -31,7 +34,7 @@ func NewStreamFlowController(
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) StreamFlowController {
-	return &streamFlowController{
+	c := &streamFlowController{
 		streamID:   streamID,
 		connection: cfc.(connectionFlowControllerI),
 		baseFlowController: baseFlowController{
@@ -43,6 +46,12 @@ func NewStreamFlowController(
 			logger:               logger,
 		},
 	}
+	if c.connection.donatesReceiveWindow() {
+		c.initialReceiveWindowSize = receiveWindow
+		c.allowWindowIncrease = c.connection.borrowReceiveWindow
+		c.shrinkWindow = c.shrinkReceiveWindow
+	}
+	return c
 }
 
 // UpdateHighestReceived updates the highestReceived value, if the offset is higher.
@@ -102,6 +111,10 @@ func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) (hasStreamWind
 	c.mutex.Lock()
 	c.addBytesRead(n)
 	hasStreamWindowUpdate = c.shouldQueueWindowUpdate()
+	// All data was read, the borrowed window won't be needed anymore.
+	if c.receivedFinalOffset && c.bytesRead == c.highestReceived {
+		c.returnBorrowedWindow()
+	}
 	c.mutex.Unlock()
 	hasConnWindowUpdate = c.connection.AddBytesRead(n)
 	return
@@ -111,6 +124,7 @@ func (c *streamFlowController) Abandon() {
 	c.mutex.Lock()
 	unread := c.highestReceived - c.bytesRead
 	c.bytesRead = c.highestReceived
+	c.returnBorrowedWindow()
 	c.mutex.Unlock()
 	if unread > 0 {
 		c.connection.AddBytesRead(unread)
@@ -118,10 +132,19 @@ func (c *streamFlowController) Abandon() {
 }
 
 func (c *streamFlowController) AddBytesSent(n protocol.ByteCount) {
+	// The mutex only protects the receive side, but Offsets might be called from any goroutine.
+	c.mutex.Lock()
 	c.baseFlowController.AddBytesSent(n)
+	c.mutex.Unlock()
 	c.connection.AddBytesSent(n)
 }
 
+func (c *streamFlowController) UpdateSendWindow(offset protocol.ByteCount) (updated bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.baseFlowController.UpdateSendWindow(offset)
+}
+
 func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return min(c.baseFlowController.SendWindowSize(), c.connection.SendWindowSize())
 }
@@ -131,6 +154,12 @@ func (c *streamFlowController) IsNewlyBlocked() bool {
 	return blocked
 }
 
+func (c *streamFlowController) Offsets() (sendWindow, bytesSent, receiveWindow, bytesRead protocol.ByteCount) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.sendWindow, c.bytesSent, c.receiveWindow, c.bytesRead
+}
+
 func (c *streamFlowController) shouldQueueWindowUpdate() bool {
 	return !c.receivedFinalOffset && c.hasWindowUpdate()
 }
@@ -148,7 +177,30 @@ func (c *streamFlowController) GetWindowUpdate(now monotime.Time) protocol.ByteC
 	offset := c.getWindowUpdate(now)
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
 		c.logger.Debugf("Increasing receive flow control window for stream %d to %d", c.streamID, c.receiveWindowSize)
-		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize)*protocol.ConnectionFlowControlMultiplier), now)
+		// With window donation, the increase was borrowed from the connection-level window.
+		if c.initialReceiveWindowSize == 0 {
+			c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize)*protocol.ConnectionFlowControlMultiplier), now)
+		}
 	}
 	return offset
 }
+
+// shrinkReceiveWindow returns (part of) the window borrowed from the connection-level window.
+// The window can't shrink below the credit that was already advertised to the peer.
+// needs to be called with locked mutex
+func (c *streamFlowController) shrinkReceiveWindow() {
+	newSize := max(c.receiveWindowSize/2, c.initialReceiveWindowSize, c.receiveWindow-c.bytesRead)
+	if newSize < c.receiveWindowSize {
+		c.connection.returnReceiveWindow(c.receiveWindowSize - newSize)
+		c.receiveWindowSize = newSize
+	}
+}
+
+// needs to be called with locked mutex
+func (c *streamFlowController) returnBorrowedWindow() {
+	if c.initialReceiveWindowSize == 0 || c.receiveWindowSize <= c.initialReceiveWindowSize {
+		return
+	}
+	c.connection.returnReceiveWindow(c.receiveWindowSize - c.initialReceiveWindowSize)
+	c.receiveWindowSize = c.initialReceiveWindowSize
+}
@@ -174,6 +174,10 @@ func TestStreamSendWindow(t *testing.T) {
 	fc.AddBytesSent(200)
 	require.Zero(t, fc.SendWindowSize())
 	require.False(t, fc.IsNewlyBlocked()) // we're blocked, but not on stream flow control
+
+	sendWindow, bytesSent, _, _ := fc.Offsets()
+	require.Equal(t, protocol.ByteCount(1000), sendWindow)
+	require.Equal(t, protocol.ByteCount(300), bytesSent)
 }
 
 func TestStreamWindowUpdate(t *testing.T) {
@@ -293,3 +297,202 @@ func TestStreamWindowAutoTuning(t *testing.T) {
 	// the connection window is also increased, but it bumps into its maximum value
 	require.Equal(t, protocol.ByteCount(203+350), connFC.GetWindowUpdate(now))
 }
+
+// burstStream simulates a peer sending size bytes on a stream as fast as flow control allows.
+// All data is read immediately. It returns the number of round trips it took to transfer the data.
+func burstStream(
+	t *testing.T,
+	connFC *connectionFlowController,
+	fc StreamFlowController,
+	size protocol.ByteCount,
+	now *monotime.Time,
+	rtt time.Duration,
+	onRoundTrip func(),
+) (rounds int) {
+	t.Helper()
+
+	var highest protocol.ByteCount
+	for highest < size {
+		_, _, receiveWindow, _ := fc.Offsets()
+		n := min(receiveWindow-highest, connFC.receiveWindow-connFC.highestReceived, size-highest)
+		require.NotZero(t, n)
+		highest += n
+		require.NoError(t, fc.UpdateHighestReceived(highest, highest == size, *now))
+		*now = now.Add(rtt)
+		fc.AddBytesRead(n)
+		fc.GetWindowUpdate(*now)
+		connFC.GetWindowUpdate(*now)
+		rounds++
+		onRoundTrip()
+	}
+	return rounds
+}
+
+func TestStreamWindowDonation(t *testing.T) {
+	const (
+		rtt             = time.Second
+		initialWindow   = 100
+		maxStreamWindow = 1600
+		burstSize       = 16000
+	)
+	rttStats := utils.NewRTTStats()
+	rttStats.UpdateRTT(rtt, 0)
+
+	connFC := NewConnectionFlowController(
+		4000,
+		4000,
+		func(protocol.ByteCount) bool { return true },
+		rttStats,
+		utils.DefaultLogger,
+	)
+	connFC.EnableReceiveWindowDonation()
+
+	fcs := make([]StreamFlowController, 4)
+	for i := range fcs {
+		fcs[i] = NewStreamFlowController(
+			protocol.StreamID(4*i),
+			connFC,
+			initialWindow,
+			maxStreamWindow,
+			protocol.MaxByteCount,
+			rttStats,
+			utils.DefaultLogger,
+		)
+	}
+
+	// The memory exposure is the sum of the credit advertised on all streams, that wasn't consumed yet.
+	var maxOutstanding, maxDonated protocol.ByteCount
+	onRoundTrip := func() {
+		var outstanding protocol.ByteCount
+		for _, fc := range fcs {
+			// once the final offset is received, the peer can't send any more data
+			if fc.(*streamFlowController).receivedFinalOffset {
+				continue
+			}
+			_, _, receiveWindow, bytesRead := fc.Offsets()
+			outstanding += receiveWindow - bytesRead
+		}
+		maxOutstanding = max(maxOutstanding, outstanding)
+		maxDonated = max(maxDonated, connFC.donatedWindow)
+		require.LessOrEqual(t, connFC.donatedWindow, connFC.receiveWindowSize)
+	}
+
+	now := monotime.Now()
+	for _, fc := range fcs {
+		rounds := burstStream(t, connFC, fc, burstSize, &now, rtt, onRoundTrip)
+		// The window is doubled every round trip: 100 + 200 + 400 + 800 bytes.
+		// After that, every round trip transfers a full stream window.
+		require.Equal(t, 4+(burstSize-1500+maxStreamWindow-1)/maxStreamWindow, rounds)
+		// the borrowed window was returned once the stream was read completely
+		require.Zero(t, connFC.donatedWindow)
+	}
+	require.Equal(t, protocol.ByteCount(maxStreamWindow-initialWindow), maxDonated)
+	// only a single stream ever used more than its initial window
+	require.LessOrEqual(t, maxOutstanding, protocol.ByteCount(maxStreamWindow+(len(fcs)-1)*initialWindow))
+}
+
+func TestStreamWindowDonationLimitedByConnectionWindow(t *testing.T) {
+	rttStats := utils.NewRTTStats()
+	rttStats.UpdateRTT(time.Second, 0)
+
+	connFC := NewConnectionFlowController(
+		300,
+		300,
+		func(protocol.ByteCount) bool { return true },
+		rttStats,
+		utils.DefaultLogger,
+	)
+	connFC.EnableReceiveWindowDonation()
+	newFC := func(id protocol.StreamID) StreamFlowController {
+		return NewStreamFlowController(id, connFC, 100, 1000, protocol.MaxByteCount, rttStats, utils.DefaultLogger)
+	}
+	fc1 := newFC(0)
+	fc2 := newFC(4)
+
+	now := monotime.Now()
+	// stream 1 doubles its window twice, borrowing 100 and 200 bytes
+	require.NoError(t, fc1.UpdateHighestReceived(100, false, now))
+	now = now.Add(time.Second)
+	fc1.AddBytesRead(100)
+	require.Equal(t, protocol.ByteCount(100+200), fc1.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(100+300), connFC.GetWindowUpdate(now))
+	require.NoError(t, fc1.UpdateHighestReceived(300, false, now))
+	now = now.Add(time.Second)
+	fc1.AddBytesRead(200)
+	require.Equal(t, protocol.ByteCount(300+400), fc1.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(300+300), connFC.GetWindowUpdate(now))
+	// this exhausts the connection window
+	require.Equal(t, protocol.ByteCount(300), connFC.donatedWindow)
+
+	// stream 2 can't borrow anything
+	require.NoError(t, fc2.UpdateHighestReceived(100, false, now))
+	now = now.Add(time.Second)
+	fc2.AddBytesRead(100)
+	require.Equal(t, protocol.ByteCount(100+100), fc2.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(300), connFC.donatedWindow)
+
+	// once reading from stream 1 is canceled, stream 2 can borrow
+	fc1.Abandon()
+	require.Zero(t, connFC.donatedWindow)
+	require.NoError(t, fc2.UpdateHighestReceived(200, false, now))
+	now = now.Add(time.Second)
+	fc2.AddBytesRead(100)
+	require.Equal(t, protocol.ByteCount(200+200), fc2.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(100), connFC.donatedWindow)
+}
+
+func TestStreamWindowDonationShrinking(t *testing.T) {
+	rttStats := utils.NewRTTStats()
+	rttStats.UpdateRTT(time.Second, 0)
+
+	connFC := NewConnectionFlowController(
+		protocol.MaxByteCount,
+		protocol.MaxByteCount,
+		func(protocol.ByteCount) bool { return true },
+		rttStats,
+		utils.DefaultLogger,
+	)
+	connFC.EnableReceiveWindowDonation()
+	fc := NewStreamFlowController(0, connFC, 100, 1000, protocol.MaxByteCount, rttStats, utils.DefaultLogger)
+
+	now := monotime.Now()
+	var offset protocol.ByteCount
+	// data consumption is fast, the window is increased to 800 bytes
+	for _, n := range []protocol.ByteCount{100, 200, 400} {
+		offset += n
+		require.NoError(t, fc.UpdateHighestReceived(offset, false, now))
+		now = now.Add(time.Second)
+		fc.AddBytesRead(n)
+		require.Equal(t, offset+2*n, fc.GetWindowUpdate(now))
+	}
+	require.Equal(t, protocol.ByteCount(700), connFC.donatedWindow)
+
+	// data consumption slows down, the window size is halved, as far as the advertised credit allows
+	offset += 600
+	require.NoError(t, fc.UpdateHighestReceived(offset, false, now))
+	now = now.Add(10 * time.Second)
+	fc.AddBytesRead(600)
+	require.Equal(t, offset+400, fc.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(300), connFC.donatedWindow)
+
+	offset += 300
+	require.NoError(t, fc.UpdateHighestReceived(offset, false, now))
+	now = now.Add(10 * time.Second)
+	fc.AddBytesRead(300)
+	require.Equal(t, offset+200, fc.GetWindowUpdate(now))
+	require.Equal(t, protocol.ByteCount(100), connFC.donatedWindow)
+
+	// the window never shrinks below the initial window
+	offset += 200
+	require.NoError(t, fc.UpdateHighestReceived(offset, false, now))
+	now = now.Add(10 * time.Second)
+	fc.AddBytesRead(200)
+	require.Equal(t, offset+100, fc.GetWindowUpdate(now))
+	require.Zero(t, connFC.donatedWindow)
+	offset += 100
+	require.NoError(t, fc.UpdateHighestReceived(offset, false, now))
+	now = now.Add(10 * time.Second)
+	fc.AddBytesRead(100)
+	require.Equal(t, offset+100, fc.GetWindowUpdate(now))
+	require.Zero(t, connFC.donatedWindow)
+}
@@ -5,8 +5,11 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/tls"
+	"encoding/binary"
 	"fmt"
 
+	"github.com/quic-go/quic-go/internal/protocol"
+
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
@@ -102,3 +105,15 @@ func (f *xorNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]by
 
 	return result, err
 }
+
+// openConcurrently is like Open, but it is safe for concurrent use,
+// since it doesn't modify the nonce mask.
+func (f *xorNonceAEAD) openConcurrently(out []byte, pn protocol.PacketNumber, ciphertext, additionalData []byte) ([]byte, error) {
+	nonce := f.nonceMask
+	var pnBytes [8]byte
+	binary.BigEndian.PutUint64(pnBytes[:], uint64(pn))
+	for i, b := range pnBytes {
+		nonce[4+i] ^= b
+	}
+	return f.aead.Open(out, nonce[:], ciphertext, additionalData)
+}
@@ -59,6 +59,9 @@ type cryptoSetup struct {
 
 	used0RTT atomic.Bool
 
+	// readLevel is the highest encryption level for which read keys were installed (ignoring 0-RTT)
+	readLevel tls.QUICEncryptionLevel
+
 	aead          *updatableAEAD
 	has1RTTSealer bool
 	has1RTTOpener bool
@@ -222,6 +225,15 @@ func (h *cryptoSetup) Close() error {
 // HandleMessage handles a TLS handshake message.
 // It is called by the crypto streams when a new message is available.
 func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) error {
+	// RFC 9001, section 4.1.3: Data received at a previously installed encryption level
+	// must not extend past the end of previously received data.
+	// The crypto stream only passes new data to the crypto setup.
+	if encLevel.ToTLSEncryptionLevel() < h.readLevel {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("received crypto data at a previously installed encryption level: %s", encLevel),
+		}
+	}
 	if err := h.handleMessage(data, encLevel); err != nil {
 		return wrapError(err)
 	}
@@ -249,6 +261,9 @@ func (h *cryptoSetup) handleEvent(ev tls.QUICEvent) (err error) {
 		return nil
 	case tls.QUICSetReadSecret:
 		h.setReadKey(ev.Level, ev.Suite, ev.Data)
+		if ev.Level != tls.QUICEncryptionLevelEarly {
+			h.readLevel = max(h.readLevel, ev.Level)
+		}
 		return nil
 	case tls.QUICSetWriteSecret:
 		h.setWriteKey(ev.Level, ev.Suite, ev.Data)
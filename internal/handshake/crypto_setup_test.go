@@ -345,8 +345,64 @@ func TestNewSessionTicketAtWrongEncryptionLevel(t *testing.T) {
 	// inject an invalid session ticket
 	b := append([]byte{uint8(typeNewSessionTicket), 0, 0, 6}, []byte("foobar")...)
 	err := client.HandleMessage(b, protocol.EncryptionHandshake)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "tls: handshake data received at wrong level")
+	require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation})
+}
+
+func TestHandshakeDataAtInitialEncryptionLevel(t *testing.T) {
+	clientConf, serverConf := getTLSConfigs()
+	client := NewCryptoSetupClient(
+		protocol.ConnectionID{},
+		&wire.TransportParameters{ActiveConnectionIDLimit: 2},
+		clientConf,
+		false,
+		utils.NewRTTStats(),
+		nil,
+		utils.DefaultLogger.WithPrefix("client"),
+		protocol.Version1,
+	)
+	var token protocol.StatelessResetToken
+	server := NewCryptoSetupServer(
+		protocol.ConnectionID{},
+		&net.UDPAddr{IP: net.IPv6loopback, Port: 1234},
+		&net.UDPAddr{IP: net.IPv6loopback, Port: 4321},
+		&wire.TransportParameters{ActiveConnectionIDLimit: 2, StatelessResetToken: &token},
+		serverConf,
+		false,
+		utils.NewRTTStats(),
+		nil,
+		utils.DefaultLogger.WithPrefix("server"),
+		protocol.Version1,
+	)
+	require.NoError(t, client.StartHandshake(context.Background()))
+	require.NoError(t, server.StartHandshake(context.Background()))
+
+	collectData := func(cs CryptoSetup) (initialData, handshakeData []byte) {
+		for {
+			ev := cs.NextEvent()
+			switch ev.Kind {
+			case EventNoEvent:
+				return initialData, handshakeData
+			case EventWriteInitialData:
+				initialData = append(initialData, ev.Data...)
+			case EventWriteHandshakeData:
+				handshakeData = append(handshakeData, ev.Data...)
+			}
+		}
+	}
+
+	clientHello, _ := collectData(client)
+	require.NotEmpty(t, clientHello)
+	require.NoError(t, server.HandleMessage(clientHello, protocol.EncryptionInitial))
+	serverHello, serverHandshakeData := collectData(server)
+	require.NotEmpty(t, serverHello)
+	require.NotEmpty(t, serverHandshakeData)
+	// the ServerHello causes the client to install the Handshake keys
+	require.NoError(t, client.HandleMessage(serverHello, protocol.EncryptionInitial))
+
+	// the server's Handshake messages (EncryptedExtensions, Certificate, etc.) are sent in the Initial space
+	err := client.HandleMessage(serverHandshakeData, protocol.EncryptionInitial)
+	require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation})
+	require.ErrorContains(t, err, "received crypto data at a previously installed encryption level")
 }
 
 func TestHandlingNewSessionTicketFails(t *testing.T) {
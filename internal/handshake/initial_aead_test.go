@@ -2,9 +2,13 @@ package handshake
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/aes"
 	"crypto/rand"
 	"testing"
 
+	"golang.org/x/crypto/hkdf"
+
 	"github.com/quic-go/quic-go/internal/protocol"
 
 	"github.com/stretchr/testify/require"
@@ -84,6 +88,62 @@ func TestComputeServerKeyAndIV(t *testing.T) {
 	}
 }
 
+// TestInitialKeyDerivation checks every intermediate value of the Initial key derivation
+// against the test vectors from RFC 9001, Appendix A.1.
+// The encryption of the full packets is checked by TestClientInitial and TestServersInitial.
+func TestInitialKeyDerivation(t *testing.T) {
+	connID := protocol.ParseConnectionID(splitHexString(t, "0x8394c8f03e515708"))
+
+	require.Equal(t, splitHexString(t, "0x38762cf7f55934b34d179ae6a4c80cadccbb7f0a"), getSalt(protocol.Version1))
+	initialSecret := hkdf.Extract(crypto.SHA256.New, connID.Bytes(), getSalt(protocol.Version1))
+	require.Equal(t, splitHexString(t, "7db5df06e7a69e432496adedb0085192 3595221596ae2ae9fb8115c1e9ed0a44"), initialSecret)
+
+	clientSecret, serverSecret := computeSecrets(connID, protocol.Version1)
+	require.Equal(t, splitHexString(t, "c00cf151ca5be075ed0ebfb5c80323c4 2d6b7db67881289af4008f1f6c357aea"), clientSecret)
+	require.Equal(t, splitHexString(t, "3c199828fd139efd216c155ad844cc81 fb82fa8d7446fa7d78be803acdda951b"), serverSecret)
+
+	for _, tc := range []struct {
+		name                    string
+		secret                  []byte
+		expectedKey, expectedIV []byte
+		expectedHP              []byte
+		sample, expectedHPMask  []byte
+	}{
+		{
+			name:           "client",
+			secret:         clientSecret,
+			expectedKey:    splitHexString(t, "1f369613dd76d5467730efcbe3b1a22d"),
+			expectedIV:     splitHexString(t, "fa044b2f42a3fd3b46fb255c"),
+			expectedHP:     splitHexString(t, "9f50449e04a0e810283a1e9933adedd2"),
+			sample:         splitHexString(t, "d1b1c98dd7689fb8ec11d242b123dc9b"),
+			expectedHPMask: splitHexString(t, "437b9aec36"),
+		},
+		{
+			name:           "server",
+			secret:         serverSecret,
+			expectedKey:    splitHexString(t, "cf3a5331653c364c88f0f379b6067e37"),
+			expectedIV:     splitHexString(t, "0ac1493ca1905853b0bba03e"),
+			expectedHP:     splitHexString(t, "c206b8d9b9f0f37644430b490eeaa314"),
+			sample:         splitHexString(t, "2cd0991cd25b0aac406a5816b6394100"),
+			expectedHPMask: splitHexString(t, "2ec0d8356a"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key, iv := computeInitialKeyAndIV(tc.secret, protocol.Version1)
+			require.Equal(t, tc.expectedKey, key)
+			require.Equal(t, tc.expectedIV, iv)
+			hp := hkdfExpandLabel(crypto.SHA256, tc.secret, []byte{}, hkdfHeaderProtectionLabel(protocol.Version1), 16)
+			require.Equal(t, tc.expectedHP, hp)
+
+			block, err := aes.NewCipher(hp)
+			require.NoError(t, err)
+			mask := make([]byte, aes.BlockSize)
+			block.Encrypt(mask, tc.sample)
+			require.Equal(t, tc.expectedHPMask, mask[:5])
+		})
+	}
+}
+
 func TestClientInitial(t *testing.T) {
 	connID := protocol.ParseConnectionID(splitHexString(t, "0x8394c8f03e515708"))
 
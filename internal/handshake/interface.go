@@ -41,6 +41,27 @@ type ShortHeaderOpener interface {
 	Open(dst, src []byte, rcvTime monotime.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte) ([]byte, error)
 }
 
+// OpenRequest is a request to open a short header packet, used by BatchShortHeaderOpener.
+type OpenRequest struct {
+	Dst, Src       []byte
+	AssociatedData []byte
+	RcvTime        monotime.Time
+	PacketNumber   protocol.PacketNumber
+	KeyPhase       protocol.KeyPhaseBit
+
+	// Decrypted and Err are set by OpenBatch.
+	Decrypted []byte
+	Err       error
+
+	concurrent bool // set if the packet was decrypted concurrently
+}
+
+// BatchShortHeaderOpener opens batches of short header packets.
+type BatchShortHeaderOpener interface {
+	ShortHeaderOpener
+	OpenBatch([]OpenRequest)
+}
+
 // LongHeaderSealer seals a long header packet
 type LongHeaderSealer interface {
 	Seal(dst, src []byte, packetNumber protocol.PacketNumber, associatedData []byte) []byte
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"encoding/binary"
 	"fmt"
+	"sync"
 	"sync/atomic"
 
 	"github.com/quic-go/quic-go/internal/monotime"
@@ -44,19 +45,19 @@ type updatableAEAD struct {
 
 	// Time when the keys should be dropped. Keys are dropped on the next call to Open().
 	prevRcvAEADExpiry monotime.Time
-	prevRcvAEAD       cipher.AEAD
+	prevRcvAEAD       *xorNonceAEAD
 
 	firstRcvdWithCurrentKey protocol.PacketNumber
 	firstSentWithCurrentKey protocol.PacketNumber
 	highestRcvdPN           protocol.PacketNumber // highest packet number received (which could be successfully unprotected)
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
-	rcvAEAD                 cipher.AEAD
+	rcvAEAD                 *xorNonceAEAD
 	sendAEAD                cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
 	aeadOverhead int
 
-	nextRcvAEAD           cipher.AEAD
+	nextRcvAEAD           *xorNonceAEAD
 	nextSendAEAD          cipher.AEAD
 	nextRcvTrafficSecret  []byte
 	nextSendTrafficSecret []byte
@@ -181,19 +182,26 @@ func (a *updatableAEAD) DecodePacketNumber(wirePN protocol.PacketNumber, wirePNL
 
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime monotime.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
 	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
+	if err := a.processOpenResult(pn, err); err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+func (a *updatableAEAD) processOpenResult(pn protocol.PacketNumber, err error) error {
 	if err == ErrDecryptionFailed {
 		a.invalidPacketCount++
 		if a.invalidPacketCount >= a.invalidPacketLimit {
-			return nil, &qerr.TransportError{ErrorCode: qerr.AEADLimitReached}
+			return &qerr.TransportError{ErrorCode: qerr.AEADLimitReached}
 		}
 	}
 	if err == nil {
 		a.highestRcvdPN = max(a.highestRcvdPN, pn)
 	}
-	return dec, err
+	return err
 }
 
-func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+func (a *updatableAEAD) maybeDropPreviousKeys(rcvTime monotime.Time) {
 	if a.prevRcvAEAD != nil && !a.prevRcvAEADExpiry.IsZero() && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
 		a.logger.Debugf("Dropping key phase %d", a.keyPhase-1)
@@ -209,6 +217,10 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol
 			})
 		}
 	}
+}
+
+func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	a.maybeDropPreviousKeys(rcvTime)
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	if kp != a.keyPhase.Bit() {
 		if a.keyPhase > 0 && a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < a.firstRcvdWithCurrentKey {
@@ -260,6 +272,11 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol
 	if err != nil {
 		return dec, ErrDecryptionFailed
 	}
+	a.receivedWithCurrentKey(rcvTime, pn)
+	return dec, err
+}
+
+func (a *updatableAEAD) receivedWithCurrentKey(rcvTime monotime.Time, pn protocol.PacketNumber) {
 	a.numRcvdWithCurrentKey++
 	if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
 		// We initiated the key updated, and now we received the first packet protected with the new key phase.
@@ -270,7 +287,76 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol
 		}
 		a.firstRcvdWithCurrentKey = pn
 	}
-	return dec, err
+}
+
+// OpenBatch opens a batch of packets.
+// The result is the same as if Open was called for every packet, in the order of the batch.
+// Packets protected with the current key phase are decrypted concurrently,
+// all other packets (i.e. packets that might trigger a key update) are opened serially.
+func (a *updatableAEAD) OpenBatch(batch []OpenRequest) {
+	if len(batch) == 0 {
+		return
+	}
+	// Don't modify any state while the packets are decrypted concurrently.
+	keyPhase := a.keyPhase
+	var wg sync.WaitGroup
+	for i := range batch {
+		r := &batch[i]
+		if r.KeyPhase != keyPhase.Bit() {
+			continue
+		}
+		r.concurrent = true
+		wg.Add(1)
+		runConcurrently(func() {
+			defer wg.Done()
+			r.Decrypted, r.Err = a.rcvAEAD.openConcurrently(r.Dst, r.PacketNumber, r.Src, r.AssociatedData)
+		})
+	}
+	wg.Wait()
+
+	for i := range batch {
+		r := &batch[i]
+		if !r.concurrent {
+			r.Decrypted, r.Err = a.Open(r.Dst, r.Src, r.RcvTime, r.PacketNumber, r.KeyPhase, r.AssociatedData)
+			continue
+		}
+		err := a.processOpenResult(r.PacketNumber, a.commitConcurrentOpen(r.RcvTime, r.PacketNumber, keyPhase, r.Err))
+		if err != nil {
+			r.Decrypted = nil
+		}
+		r.Err = err
+	}
+}
+
+// commitConcurrentOpen updates the state after a packet was decrypted concurrently, using the keys of key phase keyPhase.
+// The key phase might have changed in the meantime, if a packet opened serially triggered a key update.
+// It returns the error that open would have returned.
+func (a *updatableAEAD) commitConcurrentOpen(rcvTime monotime.Time, pn protocol.PacketNumber, keyPhase protocol.KeyPhase, openErr error) error {
+	a.maybeDropPreviousKeys(rcvTime)
+	switch a.keyPhase {
+	case keyPhase:
+		if openErr != nil {
+			return ErrDecryptionFailed
+		}
+		a.receivedWithCurrentKey(rcvTime, pn)
+		return nil
+	case keyPhase + 1:
+		// The peer updated its keys.
+		// open would have used the previous keys for packets sent before the key update.
+		if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < a.firstRcvdWithCurrentKey {
+			if a.prevRcvAEAD == nil {
+				return ErrKeysDropped
+			}
+			if openErr != nil {
+				return ErrDecryptionFailed
+			}
+			return nil
+		}
+		// open would have tried (and failed) to open the packet using the keys of the next key phase.
+		return ErrDecryptionFailed
+	default:
+		return ErrDecryptionFailed
+	}
 }
 
 func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
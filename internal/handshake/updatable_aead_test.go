@@ -26,7 +26,7 @@ const (
 
 func randomCipherSuite() cipherSuite { return cipherSuites[mrand.IntN(len(cipherSuites))] }
 
-func setupEndpoints(t *testing.T, serverRTTStats *utils.RTTStats) (client, server *updatableAEAD, serverEventRecorder *events.Recorder) {
+func setupEndpoints(t testing.TB, serverRTTStats *utils.RTTStats) (client, server *updatableAEAD, serverEventRecorder *events.Recorder) {
 	cs := randomCipherSuite()
 	var eventRecorder events.Recorder
 
@@ -221,6 +221,139 @@ func TestAEADLimitReached(t *testing.T) {
 	require.Equal(t, qerr.AEADLimitReached, transportErr.ErrorCode)
 }
 
+func TestOpenBatch(t *testing.T) {
+	client, server, _ := setupEndpoints(t, utils.NewRTTStats())
+
+	now := monotime.Now()
+	batch := make([]OpenRequest, 0, 10)
+	for i := range 10 {
+		pn := protocol.PacketNumber(0x1337 + i)
+		encrypted := server.Seal(nil, []byte(msg), pn, []byte(ad))
+		if i == 5 {
+			encrypted[0] ^= 0xff // corrupt the packet
+		}
+		batch = append(batch, OpenRequest{
+			Src:            encrypted,
+			AssociatedData: []byte(ad),
+			RcvTime:        now,
+			PacketNumber:   pn,
+			KeyPhase:       protocol.KeyPhaseZero,
+		})
+	}
+	client.OpenBatch(batch)
+	for i, r := range batch {
+		if i == 5 {
+			require.Equal(t, ErrDecryptionFailed, r.Err)
+			require.Nil(t, r.Decrypted)
+			continue
+		}
+		require.NoError(t, r.Err)
+		require.Equal(t, msg, string(r.Decrypted))
+	}
+	require.Equal(t, uint64(9), client.numRcvdWithCurrentKey)
+	require.Equal(t, uint64(1), client.invalidPacketCount)
+	require.Equal(t, protocol.PacketNumber(0x1337+9), client.highestRcvdPN)
+}
+
+func TestOpenBatchKeyUpdate(t *testing.T) {
+	client, server, eventRecorder := setupEndpoints(t, utils.NewRTTStats())
+
+	now := monotime.Now()
+	newRequest := func(pn protocol.PacketNumber, kp protocol.KeyPhaseBit) OpenRequest {
+		return OpenRequest{
+			Src:            client.Seal(nil, []byte(msg), pn, []byte(ad)),
+			AssociatedData: []byte(ad),
+			RcvTime:        now,
+			PacketNumber:   pn,
+			KeyPhase:       kp,
+		}
+	}
+	_, err := server.Open(nil, client.Seal(nil, []byte(msg), 0x40, []byte(ad)), now, 0x40, protocol.KeyPhaseZero, []byte(ad))
+	require.NoError(t, err)
+	_ = server.Seal(nil, []byte(msg), 0x1, []byte(ad))
+
+	batch := []OpenRequest{
+		newRequest(0x41, protocol.KeyPhaseZero),
+		newRequest(0x43, protocol.KeyPhaseZero),
+	}
+	reordered := newRequest(0x42, protocol.KeyPhaseZero)
+	// a packet with the old key phase, but a packet number higher than the first packet with the new key phase
+	invalid := newRequest(0x46, protocol.KeyPhaseZero)
+	client.rollKeys()
+	batch = append(batch,
+		newRequest(0x44, protocol.KeyPhaseOne), // triggers the key update
+		newRequest(0x45, protocol.KeyPhaseOne),
+		reordered,
+		invalid,
+	)
+
+	server.OpenBatch(batch)
+	for _, r := range batch[:5] {
+		require.NoError(t, r.Err)
+		require.Equal(t, msg, string(r.Decrypted))
+	}
+	require.Equal(t, ErrDecryptionFailed, batch[5].Err)
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	require.Equal(t, protocol.PacketNumber(0x44), server.firstRcvdWithCurrentKey)
+	require.Equal(t, protocol.PacketNumber(0x45), server.highestRcvdPN)
+	require.Equal(t,
+		bothSides(qlog.KeyUpdated{Trigger: qlog.KeyUpdateRemote, KeyPhase: 1}),
+		eventRecorder.Events(),
+	)
+}
+
+func BenchmarkOpenBatch(b *testing.B) {
+	const batchSize = 32
+	data := make([]byte, 1400)
+	rand.Read(data)
+
+	b.Run("serial", func(b *testing.B) {
+		benchmarkOpenBatch(b, batchSize, data, false)
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		benchmarkOpenBatch(b, batchSize, data, true)
+	})
+}
+
+func benchmarkOpenBatch(b *testing.B, batchSize int, data []byte, concurrent bool) {
+	client, server, _ := setupEndpoints(b, utils.NewRTTStats())
+	now := monotime.Now()
+	encrypted := make([][]byte, batchSize)
+	for i := range batchSize {
+		encrypted[i] = server.Seal(nil, data, protocol.PacketNumber(i), []byte(ad))
+	}
+	batch := make([]OpenRequest, batchSize)
+	buf := make([]byte, batchSize*len(data))
+
+	b.SetBytes(int64(batchSize * len(data)))
+	b.ResetTimer()
+	for b.Loop() {
+		for i := range batch {
+			batch[i] = OpenRequest{
+				Dst:            buf[i*len(data) : i*len(data)],
+				Src:            encrypted[i],
+				AssociatedData: []byte(ad),
+				RcvTime:        now,
+				PacketNumber:   protocol.PacketNumber(i),
+				KeyPhase:       protocol.KeyPhaseZero,
+			}
+		}
+		if concurrent {
+			client.OpenBatch(batch)
+		} else {
+			for i := range batch {
+				r := &batch[i]
+				r.Decrypted, r.Err = client.Open(r.Dst, r.Src, r.RcvTime, r.PacketNumber, r.KeyPhase, r.AssociatedData)
+			}
+		}
+		for _, r := range batch {
+			if r.Err != nil {
+				b.Fatal(r.Err)
+			}
+		}
+	}
+}
+
 func TestKeyUpdates(t *testing.T) {
 	client, server, _ := setupEndpoints(t, utils.NewRTTStats())
 
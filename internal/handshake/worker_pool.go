@@ -0,0 +1,39 @@
+package handshake
+
+import (
+	"runtime"
+	"sync"
+)
+
+// maxWorkers is the maximum number of goroutines used to decrypt packets concurrently.
+const maxWorkers = 8
+
+// workers is a pool of goroutines that is shared by all connections.
+// The goroutines are started when packets are decrypted concurrently for the first time.
+var workers struct {
+	once  sync.Once
+	queue chan func()
+}
+
+func startWorkers() {
+	n := min(runtime.GOMAXPROCS(0), maxWorkers)
+	workers.queue = make(chan func(), 4*n)
+	for range n {
+		go func() {
+			for f := range workers.queue {
+				f()
+			}
+		}()
+	}
+}
+
+// runConcurrently runs f on one of the workers.
+// If all workers are busy, f is run on the calling goroutine.
+func runConcurrently(f func()) {
+	workers.once.Do(startWorkers)
+	select {
+	case workers.queue <- f:
+	default:
+		f()
+	}
+}
@@ -11,8 +11,10 @@ package mockackhandler
 
 import (
 	reflect "reflect"
+	time "time"
 
 	ackhandler "github.com/quic-go/quic-go/internal/ackhandler"
+	congestion "github.com/quic-go/quic-go/internal/congestion"
 	monotime "github.com/quic-go/quic-go/internal/monotime"
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	wire "github.com/quic-go/quic-go/internal/wire"
@@ -155,6 +157,45 @@ func (c *MockSentPacketHandlerGetLossDetectionTimeoutCall) DoAndReturn(f func()
 	return c
 }
 
+// LastAck mocks base method.
+func (m *MockSentPacketHandler) LastAck() (protocol.PacketNumber, time.Duration) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastAck")
+	ret0, _ := ret[0].(protocol.PacketNumber)
+	ret1, _ := ret[1].(time.Duration)
+	return ret0, ret1
+}
+
+// LastAck indicates an expected call of LastAck.
+func (mr *MockSentPacketHandlerMockRecorder) LastAck() *MockSentPacketHandlerLastAckCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastAck", reflect.TypeOf((*MockSentPacketHandler)(nil).LastAck))
+	return &MockSentPacketHandlerLastAckCall{Call: call}
+}
+
+// MockSentPacketHandlerLastAckCall wrap *gomock.Call
+type MockSentPacketHandlerLastAckCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSentPacketHandlerLastAckCall) Return(largestNewlyAcked protocol.PacketNumber, rttSample time.Duration) *MockSentPacketHandlerLastAckCall {
+	c.Call = c.Call.Return(largestNewlyAcked, rttSample)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSentPacketHandlerLastAckCall) Do(f func() (protocol.PacketNumber, time.Duration)) *MockSentPacketHandlerLastAckCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSentPacketHandlerLastAckCall) DoAndReturn(f func() (protocol.PacketNumber, time.Duration)) *MockSentPacketHandlerLastAckCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // MigratedPath mocks base method.
 func (m *MockSentPacketHandler) MigratedPath(now monotime.Time, initialMaxPacketSize protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -229,6 +270,44 @@ func (c *MockSentPacketHandlerOnLossDetectionTimeoutCall) DoAndReturn(f func(mon
 	return c
 }
 
+// PTOCount mocks base method.
+func (m *MockSentPacketHandler) PTOCount() uint32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PTOCount")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// PTOCount indicates an expected call of PTOCount.
+func (mr *MockSentPacketHandlerMockRecorder) PTOCount() *MockSentPacketHandlerPTOCountCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PTOCount", reflect.TypeOf((*MockSentPacketHandler)(nil).PTOCount))
+	return &MockSentPacketHandlerPTOCountCall{Call: call}
+}
+
+// MockSentPacketHandlerPTOCountCall wrap *gomock.Call
+type MockSentPacketHandlerPTOCountCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSentPacketHandlerPTOCountCall) Return(arg0 uint32) *MockSentPacketHandlerPTOCountCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSentPacketHandlerPTOCountCall) Do(f func() uint32) *MockSentPacketHandlerPTOCountCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSentPacketHandlerPTOCountCall) DoAndReturn(f func() uint32) *MockSentPacketHandlerPTOCountCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // PeekPacketNumber mocks base method.
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -455,6 +534,42 @@ func (c *MockSentPacketHandlerReceivedPacketCall) DoAndReturn(f func(protocol.En
 	return c
 }
 
+// ReduceMaxDatagramSize mocks base method.
+func (m *MockSentPacketHandler) ReduceMaxDatagramSize(arg0 protocol.ByteCount, now monotime.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReduceMaxDatagramSize", arg0, now)
+}
+
+// ReduceMaxDatagramSize indicates an expected call of ReduceMaxDatagramSize.
+func (mr *MockSentPacketHandlerMockRecorder) ReduceMaxDatagramSize(arg0, now any) *MockSentPacketHandlerReduceMaxDatagramSizeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReduceMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).ReduceMaxDatagramSize), arg0, now)
+	return &MockSentPacketHandlerReduceMaxDatagramSizeCall{Call: call}
+}
+
+// MockSentPacketHandlerReduceMaxDatagramSizeCall wrap *gomock.Call
+type MockSentPacketHandlerReduceMaxDatagramSizeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSentPacketHandlerReduceMaxDatagramSizeCall) Return() *MockSentPacketHandlerReduceMaxDatagramSizeCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSentPacketHandlerReduceMaxDatagramSizeCall) Do(f func(protocol.ByteCount, monotime.Time)) *MockSentPacketHandlerReduceMaxDatagramSizeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSentPacketHandlerReduceMaxDatagramSizeCall) DoAndReturn(f func(protocol.ByteCount, monotime.Time)) *MockSentPacketHandlerReduceMaxDatagramSizeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ResetForRetry mocks base method.
 func (m *MockSentPacketHandler) ResetForRetry(rcvTime monotime.Time) {
 	m.ctrl.T.Helper()
@@ -565,6 +680,42 @@ func (c *MockSentPacketHandlerSentPacketCall) DoAndReturn(f func(monotime.Time,
 	return c
 }
 
+// SetCongestionControl mocks base method.
+func (m *MockSentPacketHandler) SetCongestionControl(arg0 congestion.CongestionControlAlgorithm, maxDatagramSize protocol.ByteCount, now monotime.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCongestionControl", arg0, maxDatagramSize, now)
+}
+
+// SetCongestionControl indicates an expected call of SetCongestionControl.
+func (mr *MockSentPacketHandlerMockRecorder) SetCongestionControl(arg0, maxDatagramSize, now any) *MockSentPacketHandlerSetCongestionControlCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockSentPacketHandler)(nil).SetCongestionControl), arg0, maxDatagramSize, now)
+	return &MockSentPacketHandlerSetCongestionControlCall{Call: call}
+}
+
+// MockSentPacketHandlerSetCongestionControlCall wrap *gomock.Call
+type MockSentPacketHandlerSetCongestionControlCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSentPacketHandlerSetCongestionControlCall) Return() *MockSentPacketHandlerSetCongestionControlCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSentPacketHandlerSetCongestionControlCall) Do(f func(congestion.CongestionControlAlgorithm, protocol.ByteCount, monotime.Time)) *MockSentPacketHandlerSetCongestionControlCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSentPacketHandlerSetCongestionControlCall) DoAndReturn(f func(congestion.CongestionControlAlgorithm, protocol.ByteCount, monotime.Time)) *MockSentPacketHandlerSetCongestionControlCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // SetMaxDatagramSize mocks base method.
 func (m *MockSentPacketHandler) SetMaxDatagramSize(count protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -411,6 +411,44 @@ func (c *MockSendAlgorithmWithDebugInfosOnRetransmissionTimeoutCall) DoAndReturn
 	return c
 }
 
+// PacingBudget mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) PacingBudget(now monotime.Time) protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingBudget", now)
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// PacingBudget indicates an expected call of PacingBudget.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) PacingBudget(now any) *MockSendAlgorithmWithDebugInfosPacingBudgetCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingBudget", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).PacingBudget), now)
+	return &MockSendAlgorithmWithDebugInfosPacingBudgetCall{Call: call}
+}
+
+// MockSendAlgorithmWithDebugInfosPacingBudgetCall wrap *gomock.Call
+type MockSendAlgorithmWithDebugInfosPacingBudgetCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSendAlgorithmWithDebugInfosPacingBudgetCall) Return(arg0 protocol.ByteCount) *MockSendAlgorithmWithDebugInfosPacingBudgetCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSendAlgorithmWithDebugInfosPacingBudgetCall) Do(f func(monotime.Time) protocol.ByteCount) *MockSendAlgorithmWithDebugInfosPacingBudgetCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSendAlgorithmWithDebugInfosPacingBudgetCall) DoAndReturn(f func(monotime.Time) protocol.ByteCount) *MockSendAlgorithmWithDebugInfosPacingBudgetCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // SetMaxDatagramSize mocks base method.
 func (m *MockSendAlgorithmWithDebugInfos) SetMaxDatagramSize(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
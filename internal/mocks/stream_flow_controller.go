@@ -228,6 +228,47 @@ func (c *MockStreamFlowControllerIsNewlyBlockedCall) DoAndReturn(f func() bool)
 	return c
 }
 
+// Offsets mocks base method.
+func (m *MockStreamFlowController) Offsets() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Offsets")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	ret2, _ := ret[2].(protocol.ByteCount)
+	ret3, _ := ret[3].(protocol.ByteCount)
+	return ret0, ret1, ret2, ret3
+}
+
+// Offsets indicates an expected call of Offsets.
+func (mr *MockStreamFlowControllerMockRecorder) Offsets() *MockStreamFlowControllerOffsetsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Offsets", reflect.TypeOf((*MockStreamFlowController)(nil).Offsets))
+	return &MockStreamFlowControllerOffsetsCall{Call: call}
+}
+
+// MockStreamFlowControllerOffsetsCall wrap *gomock.Call
+type MockStreamFlowControllerOffsetsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStreamFlowControllerOffsetsCall) Return(sendWindow, bytesSent, receiveWindow, bytesRead protocol.ByteCount) *MockStreamFlowControllerOffsetsCall {
+	c.Call = c.Call.Return(sendWindow, bytesSent, receiveWindow, bytesRead)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStreamFlowControllerOffsetsCall) Do(f func() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount, protocol.ByteCount)) *MockStreamFlowControllerOffsetsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStreamFlowControllerOffsetsCall) DoAndReturn(f func() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount, protocol.ByteCount)) *MockStreamFlowControllerOffsetsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // SendWindowSize mocks base method.
 func (m *MockStreamFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -17,6 +17,10 @@ const MaxCongestionWindowPackets = 10000
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the connection.
 const MaxUndecryptablePackets = 32
 
+// MaxUndecryptable1RTTBytes limits the total size of the 1-RTT packets that are queued
+// because they arrived before the 1-RTT keys were available.
+const MaxUndecryptable1RTTBytes = 16 * MaxPacketBufferSize
+
 // ConnectionFlowControlMultiplier determines how much larger the connection flow control windows needs to be relative to any stream's flow control window
 // This is the value that Chromium is using
 const ConnectionFlowControlMultiplier = 1.5
@@ -42,8 +46,20 @@ const DefaultMaxIncomingStreams = 100
 // DefaultMaxIncomingUniStreams is the maximum number of unidirectional streams that a peer may open
 const DefaultMaxIncomingUniStreams = 100
 
-// MaxServerUnprocessedPackets is the max number of packets stored in the server that are not yet processed.
-const MaxServerUnprocessedPackets = 1024
+// DefaultReceivePacketBudget is the default number of received packets processed
+// in one iteration of the connection's run loop.
+const DefaultReceivePacketBudget = 32
+
+// DefaultSendPacketBudget is the default number of packets (or GSO batches) sent
+// in one iteration of the connection's run loop.
+const DefaultSendPacketBudget = 8
+
+// DefaultMaxQueuedPackets is the default number of packets (or GSO batches) that are queued
+// for sending, but haven't been written to the underlying connection yet.
+const DefaultMaxQueuedPackets = 8
+
+// DefaultHandshakeQueueDepth is the default number of packets stored in the server that are not yet processed.
+const DefaultHandshakeQueueDepth = 4096
 
 // MaxConnUnprocessedPackets is the max number of packets stored in each connection that are not yet processed.
 const MaxConnUnprocessedPackets = 256
@@ -93,6 +109,10 @@ const MinCoalescedPacketSize = 128
 // This limits the size of the ClientHello and Certificates that can be received.
 const MaxCryptoStreamOffset = 16 * (1 << 10)
 
+// MaxCryptoStreamFrameSorterGaps is the maximum number of gaps between received CRYPTO frames.
+// Since the amount of data on the crypto streams is small, this is much lower than MaxStreamFrameSorterGaps.
+const MaxCryptoStreamFrameSorterGaps = 64
+
 // MinRemoteIdleTimeout is the minimum value that we accept for the remote idle timeout
 const MinRemoteIdleTimeout = 5 * time.Second
 
@@ -102,6 +122,10 @@ const DefaultIdleTimeout = 30 * time.Second
 // DefaultHandshakeIdleTimeout is the default idle timeout used before handshake completion.
 const DefaultHandshakeIdleTimeout = 5 * time.Second
 
+// DefaultConnectivityDegradedPTOCount is the default number of consecutive PTOs without receiving an ACK
+// after which the connectivity is considered degraded, see Config.OnConnectivityDegraded.
+const DefaultConnectivityDegradedPTOCount = 3
+
 // MinStreamFrameSize is the minimum size that has to be left in a packet, so that we add another STREAM frame.
 // This avoids splitting up STREAM frames into small pieces, which has 2 advantages:
 // 1. it reduces the framing overhead
@@ -120,6 +144,12 @@ const MaxPostHandshakeCryptoFrameSize = 1000
 // in a single byte varint.
 const MaxNumAckRanges = 64
 
+// MaxPacketNumberWindow is the maximum distance a received packet number may be ahead of
+// the largest packet number received so far in the same packet number space.
+// Packet numbers are increased by (at most) one for every packet sent, with the exception of
+// packet numbers skipped to prevent optimistic ACK attacks.
+const MaxPacketNumberWindow PacketNumber = 1 << 20
+
 // MinPacingDelay is the minimum duration that is used for packet pacing
 // If the packet packing frequency is higher, multiple packets might be sent at once.
 // Example: For a packet pacing delay of 200μs, we would send 5 packets at once, wait for 1ms, and so forth.
@@ -132,6 +162,11 @@ const DefaultConnectionIDLength = 4
 // MaxActiveConnectionIDs is the number of connection IDs that we're storing.
 const MaxActiveConnectionIDs = 4
 
+// MaxUnacknowledgedRetiredConnectionIDs is the maximum number of RETIRE_CONNECTION_ID frames
+// that can be outstanding (i.e. sent, but not yet acknowledged) at the same time.
+// A peer that causes us to retire more connection IDs is likely trying to exhaust our resources.
+const MaxUnacknowledgedRetiredConnectionIDs = 2 * MaxActiveConnectionIDs
+
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
@@ -153,6 +188,15 @@ const MaxAckDelay = 25 * time.Millisecond
 // This is the value that should be advertised to the peer.
 const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 
+// ACKFrequencyThresholdRTT is the smoothed RTT below which we reduce the ACK frequency at high throughputs.
+const ACKFrequencyThresholdRTT = 10 * time.Millisecond
+
+// ACKFrequencyThresholdBW is the receive rate (in bits/s) above which we only acknowledge every 4th ack-eliciting packet.
+const ACKFrequencyThresholdBW = 100 * 1000 * 1000
+
+// ACKFrequencyThresholdHighBW is the receive rate (in bits/s) above which we only acknowledge every 8th ack-eliciting packet.
+const ACKFrequencyThresholdHighBW = 1000 * 1000 * 1000
+
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
 
@@ -167,3 +211,10 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxConnUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the connection, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// MaxWriteBatchDelay is the maximum time that sending is deferred by a write batch (see Conn.Batch).
+// This prevents a batch that the application forgot to end from stalling the connection.
+const MaxWriteBatchDelay = 10 * time.Millisecond
+
+// CongestionControlSwitchInterval is the interval at which Config.CongestionControlSwitch is called.
+const CongestionControlSwitchInterval = 30 * time.Second
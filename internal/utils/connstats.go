@@ -11,4 +11,24 @@ type ConnectionStats struct {
 	PacketsReceived atomic.Uint64
 	BytesLost       atomic.Uint64
 	PacketsLost     atomic.Uint64
+
+	PathValidationsDropped         atomic.Uint64
+	PathProbesAmplificationLimited atomic.Uint64
+	PathSwitchesDropped            atomic.Uint64
+
+	MaxCryptoFrameGaps atomic.Uint64
+
+	Datagrams0RTTDropped atomic.Uint64
+
+	Early1RTTPacketsSalvaged atomic.Uint64
+
+	PacketSizeReductions atomic.Uint64
+
+	CongestionControl atomic.Uint32 // the congestion.CongestionControlAlgorithm currently in use
+	CongestionLimited atomic.Bool   // updated by the congestion controller for every acknowledged packet
+	CongestionWindow  atomic.Uint64 // updated by the sent packet handler
+	BytesInFlight     atomic.Uint64 // updated by the sent packet handler
+
+	LastAckElicitingPacketSent atomic.Int64 // a monotime.Time
+	LastPacketReceived         atomic.Int64 // a monotime.Time
 }
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Every power of two is split into latencySubBuckets buckets,
+// bounding the relative error of the percentiles to 1/latencySubBuckets.
+const (
+	latencySubBucketBits = 2
+	latencySubBuckets    = 1 << latencySubBucketBits
+	// 40 powers of two cover latencies of up to 2^40 µs (about 12 days).
+	// Larger latencies are recorded in the last bucket.
+	numLatencyBuckets = 40 * latencySubBuckets
+)
+
+// A LatencyHistogram records latencies with microsecond resolution in logarithmically sized buckets.
+// The zero value is an empty histogram.
+type LatencyHistogram struct {
+	counts [numLatencyBuckets]uint32
+	total  uint64
+}
+
+// Add records a latency sample.
+func (h *LatencyHistogram) Add(d time.Duration) {
+	h.counts[latencyBucketIndex(max(d.Microseconds(), 0))]++
+	h.total++
+}
+
+// Percentile returns the latency below which p percent of the samples fall.
+// The result is rounded up to the upper bound of the bucket the sample was recorded in.
+// It returns 0 if no samples were recorded.
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(min(max(p, 0), 100) / 100 * float64(h.total)))
+	rank = max(rank, 1)
+	var count uint64
+	for i, c := range h.counts {
+		count += uint64(c)
+		if count >= rank {
+			return time.Duration(latencyBucketUpperBound(i)) * time.Microsecond
+		}
+	}
+	return time.Duration(latencyBucketUpperBound(numLatencyBuckets-1)) * time.Microsecond
+}
+
+func latencyBucketIndex(us int64) int {
+	v := uint64(us)
+	if v < latencySubBuckets {
+		return int(v)
+	}
+	msb := bits.Len64(v) - 1
+	shift := msb - latencySubBucketBits
+	sub := int(v>>shift) & (latencySubBuckets - 1)
+	return min((shift+1)*latencySubBuckets+sub, numLatencyBuckets-1)
+}
+
+func latencyBucketUpperBound(i int) int64 {
+	if i < latencySubBuckets {
+		return int64(i)
+	}
+	shift := i/latencySubBuckets - 1
+	sub := i % latencySubBuckets
+	lower := int64(latencySubBuckets+sub) << shift
+	return lower + (1 << shift) - 1
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	for us := int64(0); us < 1<<16; us++ {
+		i := latencyBucketIndex(us)
+		require.LessOrEqual(t, us, latencyBucketUpperBound(i))
+		if i > 0 {
+			require.Greater(t, us, latencyBucketUpperBound(i-1))
+		}
+	}
+	// the relative error is bounded
+	for _, us := range []int64{5, 123, 4567, 1_000_000, 987_654_321} {
+		upper := latencyBucketUpperBound(latencyBucketIndex(us))
+		require.LessOrEqual(t, float64(upper-us)/float64(us), 1.0/latencySubBuckets)
+	}
+	// very large latencies end up in the last bucket
+	require.Equal(t, numLatencyBuckets-1, latencyBucketIndex(1<<50))
+}
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	var h LatencyHistogram
+	require.Zero(t, h.Percentile(99))
+
+	for i := range 100 {
+		h.Add(time.Duration(i+1) * time.Millisecond)
+	}
+	require.Equal(t, time.Millisecond, h.Percentile(0).Truncate(time.Millisecond))
+	p50 := h.Percentile(50)
+	require.GreaterOrEqual(t, p50, 50*time.Millisecond)
+	require.LessOrEqual(t, p50, 50*time.Millisecond*5/4)
+	p95 := h.Percentile(95)
+	require.GreaterOrEqual(t, p95, 95*time.Millisecond)
+	require.LessOrEqual(t, p95, 95*time.Millisecond*5/4)
+	p99 := h.Percentile(99)
+	require.GreaterOrEqual(t, p99, 99*time.Millisecond)
+	require.LessOrEqual(t, p99, 99*time.Millisecond*5/4)
+	require.LessOrEqual(t, p95, p99)
+	require.Equal(t, p99, h.Percentile(99.5))
+	require.GreaterOrEqual(t, h.Percentile(100), 100*time.Millisecond)
+
+	// negative durations are recorded as 0
+	var h2 LatencyHistogram
+	h2.Add(-time.Second)
+	require.Zero(t, h2.Percentile(100))
+}
@@ -18,6 +18,7 @@ type FrameParser struct {
 	supportsDatagrams     bool
 	supportsResetStreamAt bool
 	supportsAckFrequency  bool
+	strict                bool
 
 	// To avoid allocating when parsing, keep a single ACK frame struct.
 	// It is used over and over again.
@@ -48,6 +49,15 @@ func (p *FrameParser) ParseType(b []byte, encLevel protocol.EncryptionLevel) (Fr
 			}
 		}
 		b = b[l:]
+		// RFC 9000, Section 12.4: frame types MUST be encoded using the shortest possible encoding.
+		// Endpoints MAY treat the receipt of a longer encoding as a PROTOCOL_VIOLATION.
+		if p.strict && l != quicvarint.Len(typ) {
+			return 0, parsed, &qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				FrameType:    typ,
+				ErrorMessage: "frame type not minimally encoded",
+			}
+		}
 		if typ == 0x0 { // skip PADDING frames
 			continue
 		}
@@ -63,9 +73,11 @@ func (p *FrameParser) ParseType(b []byte, encLevel protocol.EncryptionLevel) (Fr
 				ErrorMessage: errUnknownFrameType.Error(),
 			}
 		}
+		// RFC 9000, Section 12.4: receiving a frame in a packet type that doesn't permit it
+		// is a connection error of type PROTOCOL_VIOLATION.
 		if !ft.isAllowedAtEncLevel(encLevel) {
 			return 0, parsed, &qerr.TransportError{
-				ErrorCode:    qerr.FrameEncodingError,
+				ErrorCode:    qerr.ProtocolViolation,
 				FrameType:    typ,
 				ErrorMessage: fmt.Sprintf("%d not allowed at encryption level %s", ft, encLevel),
 			}
@@ -178,6 +190,12 @@ func (p *FrameParser) ParseLessCommonFrame(frameType FrameType, data []byte, v p
 	return frame, l, err
 }
 
+// SetStrict enables strict validation of received frames:
+// Frame types that are not minimally encoded result in a PROTOCOL_VIOLATION.
+func (p *FrameParser) SetStrict(strict bool) {
+	p.strict = strict
+}
+
 // SetAckDelayExponent sets the acknowledgment delay exponent (sent in the transport parameters).
 // This value is used to scale the ACK Delay field in the ACK frame.
 func (p *FrameParser) SetAckDelayExponent(exp uint8) {
@@ -480,7 +480,8 @@ func TestFrameAllowedAtEncLevel(t *testing.T) {
 					require.Error(t, err)
 					var transportErr *qerr.TransportError
 					require.ErrorAs(t, err, &transportErr)
-					require.Equal(t, qerr.FrameEncodingError, transportErr.ErrorCode)
+					require.Equal(t, qerr.ProtocolViolation, transportErr.ErrorCode)
+					require.Equal(t, uint64(tc.frameType), transportErr.FrameType)
 				}
 			})
 		}
@@ -569,6 +570,63 @@ func TestFrameParserInvalidFrameType(t *testing.T) {
 	require.Equal(t, qerr.FrameEncodingError, transportErr.ErrorCode)
 }
 
+func TestFrameParserStrictFrameTypeEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		frameType FrameType
+		length    int
+	}{
+		{name: "PADDING", frameType: 0x0, length: 2},
+		{name: "PING", frameType: FrameTypePing, length: 2},
+		{name: "ACK", frameType: FrameTypeAck, length: 4},
+		{name: "STREAM", frameType: 0x8, length: 2},
+		{name: "NEW_CONNECTION_ID", frameType: FrameTypeNewConnectionID, length: 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := quicvarint.AppendWithLen(nil, uint64(tc.frameType), tc.length)
+
+			// by default, non-minimal encodings are accepted
+			parser := NewFrameParser(false, false, false)
+			frameType, l, err := parser.ParseType(b, protocol.Encryption1RTT)
+			if tc.frameType == 0x0 {
+				require.ErrorIs(t, err, io.EOF)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.frameType, frameType)
+			}
+			require.Equal(t, tc.length, l)
+
+			parser.SetStrict(true)
+			_, _, err = parser.ParseType(b, protocol.Encryption1RTT)
+			require.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.ProtocolViolation, FrameType: uint64(tc.frameType)})
+			require.ErrorContains(t, err, "frame type not minimally encoded")
+		})
+	}
+}
+
+func TestFrameParserErrorCodeRoundTrip(t *testing.T) {
+	for _, code := range []uint64{0, 0x3f, 0x40, 0x3fffffff, 0x40000000, quicvarint.Max} {
+		for _, f := range []Frame{
+			&ResetStreamFrame{StreamID: 4, ErrorCode: qerr.StreamErrorCode(code), FinalSize: 42},
+			&StopSendingFrame{StreamID: 4, ErrorCode: qerr.StreamErrorCode(code)},
+			&ConnectionCloseFrame{ErrorCode: code, ReasonPhrase: "foobar"},
+			&ConnectionCloseFrame{IsApplicationError: true, ErrorCode: code, ReasonPhrase: "foobar"},
+		} {
+			t.Run(fmt.Sprintf("%T, error code %d", f, code), func(t *testing.T) {
+				parser := NewFrameParser(true, true, true)
+				b, err := f.Append(nil, protocol.Version1)
+				require.NoError(t, err)
+				require.Len(t, b, int(f.Length(protocol.Version1)))
+				frameType, l, err := parser.ParseType(b, protocol.Encryption1RTT)
+				require.NoError(t, err)
+				frame, _, err := parser.ParseLessCommonFrame(frameType, b[l:], protocol.Version1)
+				require.NoError(t, err)
+				require.Equal(t, f, frame)
+			})
+		}
+	}
+}
+
 func TestFrameParsingErrorsOnInvalidFrames(t *testing.T) {
 	parser := NewFrameParser(true, true, true)
 	f := &MaxStreamDataFrame{
@@ -828,3 +886,131 @@ func benchmarkFrames(b *testing.B, frames ...Frame) {
 		parseFrames(b, parser, buf, frames...)
 	}
 }
+
+func TestFrameParserTruncatedFrames(t *testing.T) {
+	frames := []Frame{
+		&AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: quicvarint.Max}}, DelayTime: time.Second, ECT0: quicvarint.Max},
+		&StreamFrame{StreamID: quicvarint.Max, Offset: 0x1337, Data: []byte("foobar"), DataLenPresent: true},
+		&DatagramFrame{Data: []byte("foobar"), DataLenPresent: true},
+		&MaxDataFrame{MaximumData: quicvarint.Max},
+		&MaxStreamDataFrame{StreamID: quicvarint.Max, MaximumStreamData: quicvarint.Max},
+		&ResetStreamFrame{StreamID: quicvarint.Max, FinalSize: quicvarint.Max, ErrorCode: quicvarint.Max},
+		&ResetStreamFrame{StreamID: quicvarint.Max, ReliableSize: 0x42, FinalSize: quicvarint.Max},
+		&StopSendingFrame{StreamID: quicvarint.Max, ErrorCode: quicvarint.Max},
+		&CryptoFrame{Offset: quicvarint.Max - 6, Data: []byte("foobar")},
+		&NewTokenFrame{Token: []byte("foobar")},
+		&MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: protocol.MaxStreamCount},
+		&DataBlockedFrame{MaximumData: quicvarint.Max},
+		&StreamDataBlockedFrame{StreamID: quicvarint.Max, MaximumStreamData: quicvarint.Max},
+		&StreamsBlockedFrame{Type: protocol.StreamTypeBidi, StreamLimit: protocol.MaxStreamCount},
+		&NewConnectionIDFrame{SequenceNumber: quicvarint.Max, RetirePriorTo: 1, ConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4})},
+		&RetireConnectionIDFrame{SequenceNumber: quicvarint.Max},
+		&PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		&PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		&ConnectionCloseFrame{ErrorCode: quicvarint.Max, FrameType: quicvarint.Max, ReasonPhrase: "foobar"},
+		&ConnectionCloseFrame{IsApplicationError: true, ErrorCode: quicvarint.Max, ReasonPhrase: "foobar"},
+		&AckFrequencyFrame{SequenceNumber: quicvarint.Max, AckElicitingThreshold: quicvarint.Max, ReorderingThreshold: quicvarint.Max},
+	}
+
+	for _, f := range frames {
+		t.Run(fmt.Sprintf("%T", f), func(t *testing.T) {
+			b, err := f.Append(nil, protocol.Version1)
+			require.NoError(t, err)
+			parser := NewFrameParser(true, true, true)
+			frameType, l, err := parser.ParseType(b, protocol.Encryption1RTT)
+			require.NoError(t, err)
+			_, n, err := parseFrameOfType(parser, frameType, b[l:], protocol.Encryption1RTT)
+			require.NoError(t, err)
+			require.Equal(t, len(b)-l, n)
+
+			for i := l; i < len(b); i++ {
+				_, _, err := parseFrameOfType(parser, frameType, b[l:i], protocol.Encryption1RTT)
+				require.Error(t, err, "truncated to %d bytes", i)
+			}
+		})
+	}
+}
+
+func TestFrameParserVarintLimits(t *testing.T) {
+	parser := NewFrameParser(true, true, true)
+
+	// the maximum varint value is accepted...
+	b, err := (&MaxDataFrame{MaximumData: quicvarint.Max}).Append(nil, protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{byte(FrameTypeMaxData), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, b)
+	frame, l, err := parser.ParseLessCommonFrame(FrameTypeMaxData, b[1:], protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, 8, l)
+	require.Equal(t, &MaxDataFrame{MaximumData: quicvarint.Max}, frame)
+
+	// ... but one beyond the maximum can't be encoded
+	require.PanicsWithError(t, "value doesn't fit into 62 bits: 4611686018427387904", func() {
+		(&MaxDataFrame{MaximumData: quicvarint.Max + 1}).Append(nil, protocol.Version1)
+	})
+	// The two most significant bits encode the length of the varint.
+	// 0x4000000000000000 therefore is decoded as a 2-byte varint (with value 0),
+	// leaving 6 trailing bytes that don't belong to the frame.
+	b = []byte{byte(FrameTypeMaxData), 0x40, 0, 0, 0, 0, 0, 0, 0}
+	frame, l, err = parser.ParseLessCommonFrame(FrameTypeMaxData, b[1:], protocol.Version1)
+	require.NoError(t, err)
+	require.Equal(t, 2, l)
+	require.Equal(t, &MaxDataFrame{MaximumData: 0}, frame)
+}
+
+// FuzzFrameParser feeds arbitrary data to the frame parser.
+// Parsing must never panic, no matter how the input is malformed (e.g. truncated varints).
+func FuzzFrameParser(f *testing.F) {
+	for _, frame := range []Frame{
+		&AckFrame{AckRanges: []AckRange{{Smallest: 5, Largest: 10}, {Smallest: 1, Largest: 2}}, ECT0: 1, ECNCE: 2},
+		&StreamFrame{StreamID: 4, Offset: 0x1337, Data: []byte("foobar"), DataLenPresent: true},
+		&DatagramFrame{Data: []byte("foobar"), DataLenPresent: true},
+		&MaxStreamDataFrame{StreamID: quicvarint.Max, MaximumStreamData: quicvarint.Max},
+		&NewConnectionIDFrame{SequenceNumber: 2, RetirePriorTo: 1, ConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4})},
+		&ConnectionCloseFrame{ErrorCode: 0x42, FrameType: 0x1337, ReasonPhrase: "foobar"},
+		&AckFrequencyFrame{SequenceNumber: 1, AckElicitingThreshold: 2, RequestMaxAckDelay: time.Second, ReorderingThreshold: 3},
+	} {
+		b, err := frame.Append(nil, protocol.Version1)
+		require.NoError(f, err)
+		f.Add(b)
+	}
+	// MAX_DATA frame with an 8-byte varint, truncated after 4 bytes
+	f.Add([]byte{byte(FrameTypeMaxData), 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, encLevel := range []protocol.EncryptionLevel{
+			protocol.EncryptionInitial,
+			protocol.EncryptionHandshake,
+			protocol.Encryption0RTT,
+			protocol.Encryption1RTT,
+		} {
+			parser := NewFrameParser(true, true, true)
+			b := data
+			for len(b) > 0 {
+				frameType, l, err := parser.ParseType(b, encLevel)
+				if err != nil {
+					break
+				}
+				b = b[l:]
+				_, l, err = parseFrameOfType(parser, frameType, b, encLevel)
+				if err != nil {
+					break
+				}
+				require.LessOrEqual(t, l, len(b))
+				b = b[l:]
+			}
+		}
+	})
+}
+
+func parseFrameOfType(parser *FrameParser, frameType FrameType, data []byte, encLevel protocol.EncryptionLevel) (Frame, int, error) {
+	switch {
+	case frameType.IsStreamFrameType():
+		return parser.ParseStreamFrame(frameType, data, protocol.Version1)
+	case frameType.IsAckFrameType():
+		return parser.ParseAckFrame(frameType, data, encLevel, protocol.Version1)
+	case frameType.IsDatagramFrameType():
+		return parser.ParseDatagramFrame(frameType, data, protocol.Version1)
+	default:
+		return parser.ParseLessCommonFrame(frameType, data, protocol.Version1)
+	}
+}
@@ -573,3 +573,36 @@ func benchmarkArbitraryHeaderParsing(b *testing.B, destLen, srcLen int) {
 		}
 	}
 }
+
+// FuzzParsePacket feeds arbitrary data to the packet header parser.
+// Parsing must never panic, no matter how the input is malformed (e.g. truncated varints).
+func FuzzParsePacket(f *testing.F) {
+	b, err := (&ExtendedHeader{
+		Header: Header{
+			Type:             protocol.PacketTypeInitial,
+			DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 6, 7, 8}),
+			Token:            []byte("token"),
+			Length:           2 + 6,
+			Version:          protocol.Version1,
+		},
+		PacketNumber:    0x1337,
+		PacketNumberLen: 2,
+	}).Append(nil, protocol.Version1)
+	require.NoError(f, err)
+	f.Add(append(b, []byte("foobar")...))
+	// Initial packet with the token length encoded as an 8-byte varint, truncated after 2 bytes
+	f.Add([]byte{0xc0, 0, 0, 0, 1, 4, 1, 2, 3, 4, 4, 5, 6, 7, 8, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for len(data) > 0 {
+			hdr, packet, rest, err := ParsePacket(data)
+			if err != nil {
+				return
+			}
+			require.LessOrEqual(t, len(packet)+len(rest), len(data))
+			_, _ = hdr.ParseExtended(packet)
+			data = rest
+		}
+	})
+}
@@ -934,3 +934,32 @@ func benchmarkTransportParameters(b *testing.B, withPreferredAddress bool) {
 		}
 	}
 }
+
+// FuzzTransportParameters feeds arbitrary data to the transport parameter parser.
+// Parsing must never panic, no matter how the input is malformed (e.g. truncated varints).
+func FuzzTransportParameters(f *testing.F) {
+	params := &TransportParameters{
+		InitialMaxStreamDataBidiLocal:   0x1234,
+		InitialMaxData:                  quicvarint.Max,
+		MaxIdleTimeout:                  time.Minute,
+		MaxAckDelay:                     25 * time.Millisecond,
+		ActiveConnectionIDLimit:         4,
+		InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{5, 6, 7, 8}),
+	}
+	f.Add(params.Marshal(protocol.PerspectiveServer), true)
+	f.Add(params.Marshal(protocol.PerspectiveClient), false)
+	// initial_max_data with an 8-byte varint, truncated after 4 bytes
+	f.Add([]byte{byte(initialMaxDataParameterID), 8, 0xff, 0xff, 0xff, 0xff}, true)
+
+	f.Fuzz(func(t *testing.T, data []byte, sentByServer bool) {
+		sentBy := protocol.PerspectiveClient
+		if sentByServer {
+			sentBy = protocol.PerspectiveServer
+		}
+		var p TransportParameters
+		_ = p.Unmarshal(data, sentBy)
+		var sessionTicketParams TransportParameters
+		_ = sessionTicketParams.UnmarshalFromSessionTicket(data)
+	})
+}
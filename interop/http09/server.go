@@ -32,7 +32,7 @@ func (w *responseWriter) WriteHeader(int) {}
 
 // Server is a HTTP/0.9 server listening for QUIC connections.
 type Server struct {
-	Handler *http.ServeMux
+	Handler http.Handler
 }
 
 // ServeListener serves HTTP/0.9 on all connections accepted from a QUIC listener.
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -9,9 +10,7 @@ import (
 	"os"
 
 	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/http3"
-	"github.com/quic-go/quic-go/internal/qtls"
-	"github.com/quic-go/quic-go/interop/http09"
+	"github.com/quic-go/quic-go/interop/testcase"
 	"github.com/quic-go/quic-go/interop/utils"
 )
 
@@ -33,12 +32,12 @@ func main() {
 		defer keyLog.Close()
 	}
 
-	testcase := os.Getenv("TESTCASE")
-
-	quicConf := &quic.Config{
-		Allow0RTT: testcase == "zerortt",
-		Tracer:    utils.NewQLOGConnectionTracer,
+	tc := testcase.TestCase(os.Getenv("TESTCASE"))
+	if !testcase.IsSupported(testcase.RoleServer, tc) {
+		fmt.Printf("unsupported test case: %s\n", tc)
+		os.Exit(127)
 	}
+
 	cert, err := tls.LoadX509KeyPair("/certs/cert.pem", "/certs/priv.key")
 	if err != nil {
 		fmt.Println(err)
@@ -47,59 +46,16 @@ func main() {
 	tlsConf := &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		KeyLogWriter: keyLog,
-		NextProtos:   []string{http09.NextProto},
-	}
-
-	switch testcase {
-	case "versionnegotiation", "handshake", "retry", "transfer", "resumption", "multiconnect", "zerortt":
-		err = runHTTP09Server(tlsConf, quicConf, testcase == "retry")
-	case "chacha20":
-		reset := qtls.SetCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256)
-		defer reset()
-		err = runHTTP09Server(tlsConf, quicConf, false)
-	case "http3":
-		tlsConf.NextProtos = []string{http3.NextProtoH3}
-		err = runHTTP3Server(tlsConf, quicConf)
-	default:
-		fmt.Printf("unsupported test case: %s\n", testcase)
-		os.Exit(127)
 	}
+	quicConf := &quic.Config{Tracer: utils.NewQLOGConnectionTracer}
 
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 443})
 	if err != nil {
-		fmt.Printf("Error running server: %s\n", err.Error())
+		fmt.Println(err)
 		os.Exit(1)
 	}
-}
-
-func runHTTP09Server(tlsConf *tls.Config, quicConf *quic.Config, forceRetry bool) error {
-	http.DefaultServeMux.Handle("/", http.FileServer(http.Dir("/www")))
-	server := http09.Server{}
-
-	udpAddr, err := net.ResolveUDPAddr("udp", ":443")
-	if err != nil {
-		return err
-	}
-	conn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		return err
-	}
-	tr := &quic.Transport{
-		Conn:                conn,
-		VerifySourceAddress: func(net.Addr) bool { return forceRetry },
-	}
-	ln, err := tr.ListenEarly(tlsConf, quicConf)
-	if err != nil {
-		return err
-	}
-	return server.ServeListener(ln)
-}
-
-func runHTTP3Server(tlsConf *tls.Config, quicConf *quic.Config) error {
-	server := http3.Server{
-		Addr:       ":443",
-		TLSConfig:  tlsConf,
-		QUICConfig: quicConf,
+	if err := testcase.Serve(context.Background(), tc, conn, tlsConf, quicConf, http.FileServer(http.Dir("/www"))); err != nil {
+		fmt.Printf("Error running server: %s\n", err.Error())
+		os.Exit(1)
 	}
-	http.DefaultServeMux.Handle("/", http.FileServer(http.Dir("/www")))
-	return server.ListenAndServe()
 }
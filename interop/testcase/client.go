@@ -0,0 +1,561 @@
+package testcase
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/interop/http09"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
+)
+
+// Options are the options for running a test case as a client.
+type Options struct {
+	// TLSConfig is the TLS configuration used to dial the server.
+	// The NextProtos are set depending on the test case.
+	// If nil, a default configuration is used, which doesn't verify the server's certificate.
+	TLSConfig *tls.Config
+	// QUICConfig is the QUIC configuration used to dial the server.
+	// The Tracer is overwritten to collect the qlog traces.
+	QUICConfig *quic.Config
+	// Paths are the paths of the files to download.
+	// Test cases that use multiple connections (e.g. resumption) require at least 2 paths.
+	Paths []string
+	// Output is called for every file downloaded.
+	// If nil, the contents of downloaded files are discarded.
+	Output func(path string, r io.Reader) error
+	// Timeout is the timeout for running the test case.
+	// If zero, it defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+const defaultTimeout = 30 * time.Second
+
+// versionNegotiationVersion is a reserved version (RFC 9000, section 15),
+// used to force the server to send a Version Negotiation packet.
+const versionNegotiationVersion protocol.Version = 0x1a2a3a4a
+
+// RunClient runs a test case against the server at addr.
+func RunClient(ctx context.Context, tc TestCase, addr string, opts *Options) *Result {
+	switch tc {
+	case Handshake:
+		return RunHandshake(ctx, addr, opts)
+	case Transfer:
+		return RunTransfer(ctx, addr, opts)
+	case Retry:
+		return RunRetry(ctx, addr, opts)
+	case Resumption:
+		return RunResumption(ctx, addr, opts)
+	case ZeroRTT:
+		return RunZeroRTT(ctx, addr, opts)
+	case MultiConnect:
+		return RunMultiConnect(ctx, addr, opts)
+	case ChaCha20:
+		return RunChaCha20(ctx, addr, opts)
+	case KeyUpdate:
+		return RunKeyUpdate(ctx, addr, opts)
+	case ECN:
+		return RunECN(ctx, addr, opts)
+	case Amplification:
+		return RunAmplification(ctx, addr, opts)
+	case VersionNegotiation:
+		return RunVersionNegotiation(ctx, addr, opts)
+	case HTTP3:
+		return RunHTTP3(ctx, addr, opts)
+	default:
+		return &Result{TestCase: tc, Err: ErrUnsupported}
+	}
+}
+
+// RunHandshake runs the handshake test case.
+// It downloads the files on a single connection, and checks that no Retry was performed.
+func RunHandshake(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, Handshake, addr, opts, func(ctx context.Context, c *client) error {
+		if err := c.downloadOnOneConn(ctx, c.opts.Paths, false); err != nil {
+			return err
+		}
+		if c.receivedPacketOfType(qlog.PacketTypeRetry) {
+			return errors.New("server unexpectedly sent a Retry")
+		}
+		return nil
+	})
+}
+
+// RunTransfer runs the transfer test case.
+// It downloads the files on a single connection.
+func RunTransfer(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, Transfer, addr, opts, func(ctx context.Context, c *client) error {
+		return c.downloadOnOneConn(ctx, c.opts.Paths, false)
+	})
+}
+
+// RunRetry runs the retry test case.
+// It downloads the files, and checks that the server sent a Retry.
+func RunRetry(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, Retry, addr, opts, func(ctx context.Context, c *client) error {
+		if err := c.downloadOnOneConn(ctx, c.opts.Paths, false); err != nil {
+			return err
+		}
+		if !c.receivedPacketOfType(qlog.PacketTypeRetry) {
+			return errors.New("server didn't send a Retry")
+		}
+		return nil
+	})
+}
+
+// RunResumption runs the resumption test case.
+// It downloads the first file on the first connection, and the remaining files
+// on a second connection, and checks that the second connection used TLS session resumption.
+func RunResumption(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, Resumption, addr, opts, func(ctx context.Context, c *client) error {
+		state, err := c.resume(ctx, false)
+		if err != nil {
+			return err
+		}
+		if state.Used0RTT {
+			return errors.New("unexpectedly used 0-RTT")
+		}
+		return nil
+	})
+}
+
+// RunZeroRTT runs the zerortt test case.
+// It downloads the first file on the first connection, and the remaining files
+// using 0-RTT on a second connection, and checks that the server accepted 0-RTT.
+func RunZeroRTT(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, ZeroRTT, addr, opts, func(ctx context.Context, c *client) error {
+		state, err := c.resume(ctx, true)
+		if err != nil {
+			return err
+		}
+		if !state.Used0RTT {
+			return errors.New("server didn't accept 0-RTT")
+		}
+		return nil
+	})
+}
+
+// RunMultiConnect runs the multiconnect test case.
+// It downloads every file on a separate connection.
+func RunMultiConnect(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, MultiConnect, addr, opts, func(ctx context.Context, c *client) error {
+		for _, p := range c.opts.Paths {
+			if err := c.downloadOnOneConn(ctx, []string{p}, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RunChaCha20 runs the chacha20 test case.
+// It only offers the ChaCha20-Poly1305 cipher suite, and downloads the files on a single connection.
+func RunChaCha20(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, ChaCha20, addr, opts, func(ctx context.Context, c *client) error {
+		defer useChaCha20()()
+
+		conn, err := c.dial(ctx, false)
+		if err != nil {
+			return err
+		}
+		defer conn.CloseWithError(0, "")
+		if cs := conn.ConnectionState().TLS.CipherSuite; cs != tls.TLS_CHACHA20_POLY1305_SHA256 {
+			return fmt.Errorf("negotiated unexpected cipher suite: %s", tls.CipherSuiteName(cs))
+		}
+		return c.download(ctx, conn, c.opts.Paths)
+	})
+}
+
+// RunKeyUpdate runs the keyupdate test case.
+// It initiates a key update early in the connection, downloads the files,
+// and checks that packets protected with the updated keys were exchanged in both directions.
+func RunKeyUpdate(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, KeyUpdate, addr, opts, func(ctx context.Context, c *client) error {
+		orig := handshake.FirstKeyUpdateInterval
+		handshake.FirstKeyUpdateInterval = 100
+		defer func() { handshake.FirstKeyUpdateInterval = orig }()
+
+		if err := c.downloadOnOneConn(ctx, c.opts.Paths, false); err != nil {
+			return err
+		}
+		var sentWithNewKey, receivedWithNewKey bool
+		for _, ev := range c.qlogs.Events() {
+			switch e := ev.(type) {
+			case qlog.PacketSent:
+				if e.Header.PacketType == qlog.PacketType1RTT && e.Header.KeyPhaseBit == qlog.KeyPhaseOne {
+					sentWithNewKey = true
+				}
+			case qlog.PacketReceived:
+				if e.Header.PacketType == qlog.PacketType1RTT && e.Header.KeyPhaseBit == qlog.KeyPhaseOne {
+					receivedWithNewKey = true
+				}
+			}
+		}
+		if !sentWithNewKey {
+			return errors.New("didn't send any packets protected with the updated keys")
+		}
+		if !receivedWithNewKey {
+			return errors.New("didn't receive any packets protected with the updated keys")
+		}
+		return nil
+	})
+}
+
+// RunECN runs the ecn test case.
+// It downloads the files on a single connection, and checks that ECN-marked packets were
+// exchanged in both directions, and that the server reported ECN counts in its ACK frames.
+func RunECN(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, ECN, addr, opts, func(ctx context.Context, c *client) error {
+		if err := c.downloadOnOneConn(ctx, c.opts.Paths, false); err != nil {
+			return err
+		}
+		var sentECT, receivedECT, receivedECNCounts bool
+		for _, ev := range c.qlogs.Events() {
+			switch e := ev.(type) {
+			case qlog.PacketSent:
+				if isECT(e.ECN) {
+					sentECT = true
+				}
+			case qlog.PacketReceived:
+				if isECT(e.ECN) {
+					receivedECT = true
+				}
+				for _, f := range e.Frames {
+					if ack, ok := f.Frame.(*qlog.AckFrame); ok && ack.ECT0+ack.ECT1+ack.ECNCE > 0 {
+						receivedECNCounts = true
+					}
+				}
+			}
+		}
+		if !sentECT {
+			return errors.New("didn't send any ECN-marked packets")
+		}
+		if !receivedECT {
+			return errors.New("didn't receive any ECN-marked packets")
+		}
+		if !receivedECNCounts {
+			return errors.New("server didn't report any ECN counts")
+		}
+		return nil
+	})
+}
+
+func isECT(ecn qlog.ECN) bool {
+	return ecn == qlog.ECT0 || ecn == qlog.ECT1 || ecn == qlog.ECNCE
+}
+
+// RunAmplification runs the amplificationlimit test case.
+// It downloads the files, and checks that the server didn't send more than 3 times
+// the amount of data it received before the client's address was validated.
+func RunAmplification(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, Amplification, addr, opts, func(ctx context.Context, c *client) error {
+		if err := c.downloadOnOneConn(ctx, c.opts.Paths, false); err != nil {
+			return err
+		}
+		sent, rcvd := c.conn.sent.Load(), c.conn.rcvd.Load()
+		if rcvd > 3*sent {
+			return fmt.Errorf("server exceeded the amplification limit: received %d bytes, sent %d bytes before address validation", rcvd, sent)
+		}
+		return nil
+	})
+}
+
+// RunVersionNegotiation runs the versionnegotiation test case.
+// It only offers a reserved QUIC version, and checks that the server responds with a Version Negotiation packet.
+func RunVersionNegotiation(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, VersionNegotiation, addr, opts, func(ctx context.Context, c *client) error {
+		orig := protocol.SupportedVersions
+		protocol.SupportedVersions = []protocol.Version{versionNegotiationVersion}
+		defer func() { protocol.SupportedVersions = orig }()
+
+		conn, err := c.dial(ctx, false)
+		if err == nil {
+			conn.CloseWithError(0, "")
+			return errors.New("expected version negotiation to fail")
+		}
+		var vnErr *quic.VersionNegotiationError
+		if !errors.As(err, &vnErr) {
+			return fmt.Errorf("expected a version negotiation error, got: %w", err)
+		}
+		return nil
+	})
+}
+
+// RunHTTP3 runs the http3 test case.
+// It downloads the files using HTTP/3, on a single connection.
+func RunHTTP3(ctx context.Context, addr string, opts *Options) *Result {
+	return run(ctx, HTTP3, addr, opts, func(ctx context.Context, c *client) error {
+		rt := &http3.Transport{
+			TLSClientConfig: c.tlsConf,
+			QUICConfig:      c.quicConf,
+			Dial: func(ctx context.Context, _ string, tlsConf *tls.Config, quicConf *quic.Config) (*quic.Conn, error) {
+				return c.tr.DialEarly(ctx, c.raddr, tlsConf, quicConf)
+			},
+		}
+		defer rt.Close()
+
+		var g errgroup.Group
+		for _, p := range c.opts.Paths {
+			g.Go(func() error {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+addr+p, nil)
+				if err != nil {
+					return err
+				}
+				rsp, err := rt.RoundTrip(req)
+				if err != nil {
+					return err
+				}
+				defer rsp.Body.Close()
+				if rsp.StatusCode != http.StatusOK {
+					return fmt.Errorf("requesting %s failed: status %d", p, rsp.StatusCode)
+				}
+				return c.output(p, rsp.Body)
+			})
+		}
+		return g.Wait()
+	})
+}
+
+func run(ctx context.Context, tc TestCase, addr string, opts *Options, fn func(context.Context, *client) error) *Result {
+	runMutex.Lock()
+	defer runMutex.Unlock()
+
+	res := &Result{TestCase: tc}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	if opts == nil {
+		opts = &Options{}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c, err := newClient(tc, addr, opts)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Err = fn(ctx, c)
+	c.Close()
+	res.Qlogs = c.qlogs.Qlogs(ctx)
+	return res
+}
+
+type client struct {
+	opts     *Options
+	raddr    net.Addr
+	tlsConf  *tls.Config
+	quicConf *quic.Config
+
+	conn  *countingConn
+	tr    *quic.Transport
+	qlogs *qlogCollector
+}
+
+func newClient(tc TestCase, addr string, opts *Options) (*client, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		opts:  opts,
+		raddr: raddr,
+		conn:  &countingConn{PacketConn: udpConn},
+		qlogs: &qlogCollector{},
+	}
+	// Bytes are only counted until the client sends its first Handshake packet,
+	// since this validates the client's address.
+	c.qlogs.OnEvent = func(ev qlogwriter.Event) {
+		if e, ok := ev.(qlog.PacketSent); ok && e.Header.PacketType == qlog.PacketTypeHandshake {
+			c.conn.stopCounting.Store(true)
+		}
+	}
+	if tc == ECN {
+		// Reading and setting the ECN bits requires access to the underlying *net.UDPConn.
+		c.tr = &quic.Transport{Conn: udpConn}
+	} else {
+		c.tr = &quic.Transport{Conn: c.conn}
+	}
+
+	if opts.TLSConfig != nil {
+		c.tlsConf = opts.TLSConfig.Clone()
+	} else {
+		c.tlsConf = &tls.Config{InsecureSkipVerify: true}
+	}
+	if c.tlsConf.ServerName == "" {
+		c.tlsConf.ServerName = host
+	}
+	if tc == HTTP3 {
+		c.tlsConf.NextProtos = []string{http3.NextProtoH3}
+	} else {
+		c.tlsConf.NextProtos = []string{http09.NextProto}
+	}
+	if opts.QUICConfig != nil {
+		c.quicConf = opts.QUICConfig.Clone()
+	} else {
+		c.quicConf = &quic.Config{}
+	}
+	c.quicConf.Tracer = c.qlogs.Tracer
+	return c, nil
+}
+
+func (c *client) dial(ctx context.Context, use0RTT bool) (*quic.Conn, error) {
+	if use0RTT {
+		return c.tr.DialEarly(ctx, c.raddr, c.tlsConf, c.quicConf)
+	}
+	return c.tr.Dial(ctx, c.raddr, c.tlsConf, c.quicConf)
+}
+
+func (c *client) downloadOnOneConn(ctx context.Context, paths []string, use0RTT bool) error {
+	conn, err := c.dial(ctx, use0RTT)
+	if err != nil {
+		return err
+	}
+	defer conn.CloseWithError(0, "")
+	return c.download(ctx, conn, paths)
+}
+
+// download downloads the files in parallel, using HTTP/0.9.
+func (c *client) download(ctx context.Context, conn *quic.Conn, paths []string) error {
+	var g errgroup.Group
+	for _, p := range paths {
+		g.Go(func() error {
+			str, err := conn.OpenStreamSync(ctx)
+			if err != nil {
+				return err
+			}
+			if _, err := str.Write([]byte("GET " + p + "\r\n")); err != nil {
+				return err
+			}
+			if err := str.Close(); err != nil {
+				return err
+			}
+			return c.output(p, str)
+		})
+	}
+	return g.Wait()
+}
+
+func (c *client) output(path string, r io.Reader) error {
+	if c.opts.Output == nil {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	return c.opts.Output(path, r)
+}
+
+// resume downloads the first file on the first connection, waits for a session ticket,
+// and then downloads the remaining files on a second connection.
+// It returns the connection state of the second connection.
+func (c *client) resume(ctx context.Context, use0RTT bool) (quic.ConnectionState, error) {
+	if len(c.opts.Paths) < 2 {
+		return quic.ConnectionState{}, errors.New("expected at least 2 paths")
+	}
+	var put <-chan struct{}
+	c.tlsConf.ClientSessionCache, put = newSessionCache(tls.NewLRUClientSessionCache(1))
+
+	if err := c.downloadOnOneConn(ctx, c.opts.Paths[:1], false); err != nil {
+		return quic.ConnectionState{}, err
+	}
+	select {
+	case <-put:
+	case <-ctx.Done():
+		return quic.ConnectionState{}, errors.New("didn't receive a session ticket")
+	}
+
+	conn, err := c.dial(ctx, use0RTT)
+	if err != nil {
+		return quic.ConnectionState{}, err
+	}
+	defer conn.CloseWithError(0, "")
+	if err := c.download(ctx, conn, c.opts.Paths[1:]); err != nil {
+		return quic.ConnectionState{}, err
+	}
+	state := conn.ConnectionState()
+	if !state.TLS.DidResume {
+		return state, errors.New("didn't resume the TLS session")
+	}
+	return state, nil
+}
+
+func (c *client) receivedPacketOfType(t qlog.PacketType) bool {
+	for _, ev := range c.qlogs.Events() {
+		if e, ok := ev.(qlog.PacketReceived); ok && e.Header.PacketType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) Close() error {
+	err := c.tr.Close()
+	c.conn.PacketConn.Close()
+	return err
+}
+
+type sessionCache struct {
+	tls.ClientSessionCache
+	put chan<- struct{}
+}
+
+func newSessionCache(c tls.ClientSessionCache) (tls.ClientSessionCache, <-chan struct{}) {
+	put := make(chan struct{}, 100)
+	return &sessionCache{ClientSessionCache: c, put: put}, put
+}
+
+func (c *sessionCache) Put(key string, cs *tls.ClientSessionState) {
+	c.ClientSessionCache.Put(key, cs)
+	select {
+	case c.put <- struct{}{}:
+	default:
+	}
+}
+
+// countingConn counts the bytes sent and received, until stopCounting is set.
+type countingConn struct {
+	net.PacketConn
+
+	stopCounting atomic.Bool
+	sent, rcvd   atomic.Int64
+}
+
+func (c *countingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if n > 0 && !c.stopCounting.Load() {
+		c.rcvd.Add(int64(n))
+	}
+	return n, addr, err
+}
+
+func (c *countingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if n > 0 && !c.stopCounting.Load() {
+		c.sent.Add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,122 @@
+package testcase
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	h3qlog "github.com/quic-go/quic-go/http3/qlog"
+	"github.com/quic-go/quic-go/qlog"
+	"github.com/quic-go/quic-go/qlogwriter"
+)
+
+// A Qlog is the qlog trace of a single connection, in the JSON-SEQ format.
+type Qlog struct {
+	// ConnectionID is the original destination connection ID.
+	ConnectionID quic.ConnectionID
+	IsClient     bool
+	Data         []byte
+}
+
+type qlogBuffer struct {
+	bytes.Buffer
+	closed chan struct{}
+}
+
+func (b *qlogBuffer) Close() error {
+	close(b.closed)
+	return nil
+}
+
+type qlogTrace struct {
+	*qlogwriter.FileSeq
+
+	connID   quic.ConnectionID
+	isClient bool
+	buf      *qlogBuffer
+	onEvent  func(qlogwriter.Event)
+}
+
+func (t *qlogTrace) AddProducer() qlogwriter.Recorder {
+	r := t.FileSeq.AddProducer()
+	if r == nil {
+		return nil
+	}
+	return &qlogRecorder{Recorder: r, onEvent: t.onEvent}
+}
+
+type qlogRecorder struct {
+	qlogwriter.Recorder
+	onEvent func(qlogwriter.Event)
+}
+
+func (r *qlogRecorder) RecordEvent(ev qlogwriter.Event) {
+	r.onEvent(ev)
+	r.Recorder.RecordEvent(ev)
+}
+
+// The qlogCollector collects the qlog traces, as well as the events of all connections of a test case.
+type qlogCollector struct {
+	// OnEvent, if set, is called synchronously for every event recorded.
+	OnEvent func(qlogwriter.Event)
+
+	mutex  sync.Mutex
+	traces []*qlogTrace
+	events []qlogwriter.Event
+}
+
+func (c *qlogCollector) Tracer(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+	buf := &qlogBuffer{closed: make(chan struct{})}
+	t := &qlogTrace{
+		FileSeq:  qlogwriter.NewConnectionFileSeq(buf, isClient, connID, []string{qlog.EventSchema, h3qlog.EventSchema}),
+		connID:   connID,
+		isClient: isClient,
+		buf:      buf,
+		onEvent:  c.recordEvent,
+	}
+	go t.Run()
+	c.mutex.Lock()
+	c.traces = append(c.traces, t)
+	c.mutex.Unlock()
+	return t
+}
+
+func (c *qlogCollector) recordEvent(ev qlogwriter.Event) {
+	if c.OnEvent != nil {
+		c.OnEvent(ev)
+	}
+	c.mutex.Lock()
+	c.events = append(c.events, ev)
+	c.mutex.Unlock()
+}
+
+// Events returns all events recorded so far, for all connections.
+func (c *qlogCollector) Events() []qlogwriter.Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]qlogwriter.Event(nil), c.events...)
+}
+
+// Qlogs waits for the qlog traces to be completed, and returns them.
+// Traces of connections that aren't closed when ctx is canceled are not returned.
+func (c *qlogCollector) Qlogs(ctx context.Context) []Qlog {
+	c.mutex.Lock()
+	traces := c.traces
+	c.mutex.Unlock()
+
+	qlogs := make([]Qlog, 0, len(traces))
+	for _, t := range traces {
+		select {
+		case <-t.buf.closed:
+		case <-ctx.Done():
+			continue
+		}
+		qlogs = append(qlogs, Qlog{
+			ConnectionID: t.connID,
+			IsClient:     t.isClient,
+			Data:         t.buf.Bytes(),
+		})
+	}
+	return qlogs
+}
@@ -0,0 +1,80 @@
+package testcase
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/interop/http09"
+)
+
+// Serve runs the server side of a test case on conn, until ctx is canceled.
+// Files are served using the handler, via HTTP/3 for the http3 test case, and via HTTP/0.9 otherwise.
+// It returns ErrUnsupported if the test case is not supported by the server.
+// The NextProtos of the TLS configuration are set depending on the test case.
+// When ctx is canceled, Serve returns nil.
+func Serve(ctx context.Context, tc TestCase, conn net.PacketConn, tlsConf *tls.Config, quicConf *quic.Config, handler http.Handler) error {
+	if !IsSupported(RoleServer, tc) {
+		return ErrUnsupported
+	}
+	tlsConf = tlsConf.Clone()
+	if quicConf != nil {
+		quicConf = quicConf.Clone()
+	} else {
+		quicConf = &quic.Config{}
+	}
+	quicConf.Allow0RTT = tc == ZeroRTT
+
+	if tc == ChaCha20 {
+		defer useChaCha20()()
+	}
+
+	var err error
+	if tc == HTTP3 {
+		tlsConf.NextProtos = []string{http3.NextProtoH3}
+		err = serveHTTP3(ctx, conn, tlsConf, quicConf, handler)
+	} else {
+		tlsConf.NextProtos = []string{http09.NextProto}
+		err = serveHTTP09(ctx, conn, tlsConf, quicConf, handler, tc == Retry)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func serveHTTP09(ctx context.Context, conn net.PacketConn, tlsConf *tls.Config, quicConf *quic.Config, handler http.Handler, forceRetry bool) error {
+	tr := &quic.Transport{
+		Conn:                conn,
+		VerifySourceAddress: func(net.Addr) bool { return forceRetry },
+	}
+	defer tr.Close()
+	ln, err := tr.ListenEarly(tlsConf, quicConf)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	server := http09.Server{Handler: handler}
+	return server.ServeListener(ln)
+}
+
+func serveHTTP3(ctx context.Context, conn net.PacketConn, tlsConf *tls.Config, quicConf *quic.Config, handler http.Handler) error {
+	server := &http3.Server{
+		TLSConfig:  tlsConf,
+		QUICConfig: quicConf,
+		Handler:    handler,
+	}
+	stop := context.AfterFunc(ctx, func() { server.Close() })
+	defer stop()
+
+	if err := server.Serve(conn); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
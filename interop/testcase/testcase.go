@@ -0,0 +1,132 @@
+// Package testcase implements the test cases of the QUIC interop runner
+// (https://github.com/quic-interop/quic-interop-runner) as a library.
+//
+// This allows running the interop test cases against any deployment,
+// without the Docker-based network simulator.
+// The client side of a test case performs real assertions about the connection,
+// and returns a structured [Result], including the qlog traces of all connections.
+//
+// Some test cases need to modify global state (e.g. the enabled cipher suites).
+// Test cases are therefore serialized, it is not possible to run multiple test cases concurrently.
+package testcase
+
+import (
+	"crypto/tls"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/qtls"
+)
+
+// A TestCase is a QUIC interop runner test case.
+// The values are the names used by the interop runner.
+type TestCase string
+
+const (
+	// Handshake tests a simple handshake.
+	Handshake TestCase = "handshake"
+	// Transfer tests the transfer of multiple files on a single connection.
+	Transfer TestCase = "transfer"
+	// Retry tests that the server sends a Retry, and that the client handles it.
+	Retry TestCase = "retry"
+	// Resumption tests TLS session resumption.
+	Resumption TestCase = "resumption"
+	// ZeroRTT tests 0-RTT data.
+	ZeroRTT TestCase = "zerortt"
+	// MultiConnect tests multiple consecutive handshakes.
+	MultiConnect TestCase = "multiconnect"
+	// ChaCha20 tests a handshake using the ChaCha20-Poly1305 cipher suite.
+	ChaCha20 TestCase = "chacha20"
+	// KeyUpdate tests a key update initiated by the client.
+	KeyUpdate TestCase = "keyupdate"
+	// ECN tests that packets are sent with ECN markings, and that the peer reports the ECN counts.
+	ECN TestCase = "ecn"
+	// Amplification tests that the server respects the 3x amplification limit.
+	Amplification TestCase = "amplificationlimit"
+	// VersionNegotiation tests that the server sends a Version Negotiation packet.
+	VersionNegotiation TestCase = "versionnegotiation"
+	// HTTP3 tests the transfer of multiple files using HTTP/3.
+	HTTP3 TestCase = "http3"
+)
+
+// A Role is the role of the endpoint in a test case.
+type Role int
+
+const (
+	// RoleClient is the client.
+	RoleClient Role = iota
+	// RoleServer is the server.
+	RoleServer
+)
+
+var supported = map[Role][]TestCase{
+	RoleClient: {
+		Handshake, Transfer, Retry, Resumption, ZeroRTT, MultiConnect,
+		ChaCha20, KeyUpdate, ECN, Amplification, VersionNegotiation, HTTP3,
+	},
+	RoleServer: {
+		Handshake, Transfer, Retry, Resumption, ZeroRTT, MultiConnect,
+		ChaCha20, KeyUpdate, ECN, Amplification, VersionNegotiation, HTTP3,
+	},
+}
+
+// Supported returns the test cases supported in the given role.
+func Supported(role Role) []TestCase {
+	return slices.Clone(supported[role])
+}
+
+// IsSupported says if a test case is supported in the given role.
+// The interop runner expects an endpoint to exit with status code 127 for unsupported test cases.
+func IsSupported(role Role, tc TestCase) bool {
+	return slices.Contains(supported[role], tc)
+}
+
+// ErrUnsupported is returned for unsupported test cases.
+var ErrUnsupported = errors.New("unsupported test case")
+
+// Result is the result of running a test case.
+type Result struct {
+	TestCase TestCase
+	// Err is nil if the test case passed.
+	Err      error
+	Duration time.Duration
+	// Qlogs contains the qlog traces of all connections established during the test case.
+	Qlogs []Qlog
+}
+
+// Passed says if the test case passed.
+func (r *Result) Passed() bool { return r.Err == nil }
+
+// runMutex serializes test case runs, since some test cases modify global state.
+var runMutex sync.Mutex
+
+var (
+	chaCha20Mutex sync.Mutex
+	chaCha20Users int
+	chaCha20Reset func()
+)
+
+// useChaCha20 restricts the TLS 1.3 cipher suites to ChaCha20-Poly1305,
+// until the returned function is called.
+// It is reference counted, such that a client and a server in the same process can use it concurrently.
+func useChaCha20() (reset func()) {
+	chaCha20Mutex.Lock()
+	defer chaCha20Mutex.Unlock()
+	if chaCha20Users == 0 {
+		chaCha20Reset = qtls.SetCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256)
+	}
+	chaCha20Users++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			chaCha20Mutex.Lock()
+			defer chaCha20Mutex.Unlock()
+			chaCha20Users--
+			if chaCha20Users == 0 {
+				chaCha20Reset()
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package testcase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, tc TestCase, files map[string][]byte) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	mux := http.NewServeMux()
+	for path, data := range files {
+		mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) { w.Write(data) })
+	}
+	// The versionnegotiation test case modifies the supported versions.
+	// Explicitly configure the server's versions, since the server might start after the client modified them.
+	serverConf := &quic.Config{Versions: []quic.Version{quic.Version1}}
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() { errChan <- Serve(ctx, tc, conn, testdata.GetTLSConfig(), serverConf, mux) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+	return conn.LocalAddr().String()
+}
+
+func TestSupported(t *testing.T) {
+	require.True(t, IsSupported(RoleClient, KeyUpdate))
+	require.True(t, IsSupported(RoleServer, KeyUpdate))
+	require.True(t, IsSupported(RoleServer, ECN))
+	require.False(t, IsSupported(RoleClient, "foobar"))
+	require.False(t, IsSupported(RoleServer, "foobar"))
+	require.Contains(t, Supported(RoleServer), Amplification)
+
+	require.ErrorIs(t, RunClient(context.Background(), "foobar", "localhost:443", nil).Err, ErrUnsupported)
+	require.ErrorIs(t, Serve(context.Background(), "foobar", nil, nil, nil, nil), ErrUnsupported)
+}
+
+func TestTestCases(t *testing.T) {
+	files := make(map[string][]byte)
+	for path, size := range map[string]int{"/small": 1000, "/medium": 100_000, "/large": 2_000_000} {
+		files[path] = make([]byte, size)
+		rand.Read(files[path])
+	}
+
+	for _, tc := range Supported(RoleClient) {
+		t.Run(string(tc), func(t *testing.T) {
+			addr := startServer(t, tc, files)
+
+			var mx sync.Mutex
+			downloaded := make(map[string][]byte)
+			res := RunClient(context.Background(), tc, addr, &Options{
+				TLSConfig: &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost"},
+				Paths:     []string{"/small", "/medium", "/large"},
+				Output: func(path string, r io.Reader) error {
+					data, err := io.ReadAll(r)
+					if err != nil {
+						return err
+					}
+					mx.Lock()
+					downloaded[path] = data
+					mx.Unlock()
+					return nil
+				},
+				Timeout: 10 * time.Second,
+			})
+			require.NoError(t, res.Err)
+			require.True(t, res.Passed())
+			require.Equal(t, tc, res.TestCase)
+			require.NotZero(t, res.Duration)
+
+			if tc == VersionNegotiation {
+				require.Empty(t, downloaded)
+				require.Len(t, res.Qlogs, 1)
+				require.Contains(t, string(res.Qlogs[0].Data), "version_negotiation")
+				return
+			}
+			require.Equal(t, files, downloaded)
+			require.NotEmpty(t, res.Qlogs)
+			for _, q := range res.Qlogs {
+				require.True(t, q.IsClient)
+				require.True(t, bytes.HasPrefix(q.Data, []byte{0x1e}), "expected a JSON-SEQ record separator")
+			}
+			switch tc {
+			case Resumption, ZeroRTT, MultiConnect:
+				require.Greater(t, len(res.Qlogs), 1)
+			}
+		})
+	}
+}
+
+func TestTestCaseFailure(t *testing.T) {
+	// the server doesn't send a Retry, the client expects one
+	addr := startServer(t, Handshake, map[string][]byte{"/file": []byte("foobar")})
+	res := RunRetry(context.Background(), addr, &Options{
+		TLSConfig: &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost"},
+		Paths:     []string{"/file"},
+		Timeout:   10 * time.Second,
+	})
+	require.False(t, res.Passed())
+	require.EqualError(t, res.Err, "server didn't send a Retry")
+	require.Len(t, res.Qlogs, 1)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/quic-go/quic-go"
 	h3qlog "github.com/quic-go/quic-go/http3/qlog"
 	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/interop/testcase"
 	"github.com/quic-go/quic-go/qlog"
 	"github.com/quic-go/quic-go/qlogwriter"
 )
@@ -31,16 +32,11 @@ func GetSSLKeyLog() (io.WriteCloser, error) {
 
 // NewQLOGConnectionTracer create a qlog file in QLOGDIR
 func NewQLOGConnectionTracer(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
-	qlogDir := os.Getenv("QLOGDIR")
+	qlogDir := getQlogDir()
 	if len(qlogDir) == 0 {
 		return nil
 	}
-	if _, err := os.Stat(qlogDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(qlogDir, 0o666); err != nil {
-			log.Fatalf("failed to create qlog dir %s: %v", qlogDir, err)
-		}
-	}
-	path := fmt.Sprintf("%s/%s.sqlog", strings.TrimRight(qlogDir, "/"), connID)
+	path := fmt.Sprintf("%s/%s.sqlog", qlogDir, connID)
 	f, err := os.Create(path)
 	if err != nil {
 		log.Printf("Failed to create qlog file %s: %s", path, err.Error())
@@ -56,3 +52,32 @@ func NewQLOGConnectionTracer(_ context.Context, isClient bool, connID quic.Conne
 	go fileSeq.Run()
 	return fileSeq
 }
+
+// WriteQlogs writes qlogs collected by a test case to QLOGDIR
+func WriteQlogs(qlogs []testcase.Qlog) error {
+	qlogDir := getQlogDir()
+	if len(qlogDir) == 0 {
+		return nil
+	}
+	for _, q := range qlogs {
+		path := fmt.Sprintf("%s/%s.sqlog", qlogDir, q.ConnectionID)
+		if err := os.WriteFile(path, q.Data, 0o644); err != nil {
+			return err
+		}
+		log.Printf("Created qlog file: %s\n", path)
+	}
+	return nil
+}
+
+func getQlogDir() string {
+	qlogDir := os.Getenv("QLOGDIR")
+	if len(qlogDir) == 0 {
+		return ""
+	}
+	if _, err := os.Stat(qlogDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(qlogDir, 0o666); err != nil {
+			log.Fatalf("failed to create qlog dir %s: %v", qlogDir, err)
+		}
+	}
+	return strings.TrimRight(qlogDir, "/")
+}
@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// memoryPressureCheckInterval is the minimum interval between two calls to Config.SendBufferMemoryPressureHook.
+const memoryPressureCheckInterval = 100 * time.Millisecond
+
+type memoryPressureLevel uint32
+
+const (
+	memoryPressureNone memoryPressureLevel = iota
+	// memoryPressureHigh halves the per-stream send buffer limit.
+	memoryPressureHigh
+	// memoryPressureCritical quarters the per-stream send buffer limit,
+	// and prevents the receive windows of new streams from growing beyond their initial size.
+	memoryPressureCritical
+)
+
+func memoryPressureLevelFromFactor(f float64) memoryPressureLevel {
+	switch {
+	case f > 0.95:
+		return memoryPressureCritical
+	case f > 0.8:
+		return memoryPressureHigh
+	default:
+		return memoryPressureNone
+	}
+}
+
+// sendBufferLimit returns the per-stream send buffer limit, given the configured limit.
+func (l memoryPressureLevel) sendBufferLimit(limit protocol.ByteCount) protocol.ByteCount {
+	switch l {
+	case memoryPressureCritical:
+		return max(limit/4, 1)
+	case memoryPressureHigh:
+		return max(limit/2, 1)
+	default:
+		return limit
+	}
+}
+
+// heapMemoryPressure is the default memory pressure hook.
+// It returns the fraction of the heap that is in use.
+// Since runtime.ReadMemStats stops the world, the value is shared between all connections,
+// and updated at most once per second.
+var heapMemoryPressure = func() func() float64 {
+	var mutex sync.Mutex
+	var lastUpdate time.Time
+	var pressure float64
+	return func() float64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if now := time.Now(); now.Sub(lastUpdate) >= time.Second {
+			lastUpdate = now
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			if stats.HeapSys > 0 {
+				pressure = float64(stats.HeapInuse) / float64(stats.HeapSys)
+			}
+		}
+		return pressure
+	}
+}()
@@ -42,6 +42,44 @@ func (m *MockStreamFrameGetter) EXPECT() *MockStreamFrameGetterMockRecorder {
 	return m.recorder
 }
 
+// hasRetransmission mocks base method.
+func (m *MockStreamFrameGetter) hasRetransmission() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "hasRetransmission")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// hasRetransmission indicates an expected call of hasRetransmission.
+func (mr *MockStreamFrameGetterMockRecorder) hasRetransmission() *MockStreamFrameGetterhasRetransmissionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasRetransmission", reflect.TypeOf((*MockStreamFrameGetter)(nil).hasRetransmission))
+	return &MockStreamFrameGetterhasRetransmissionCall{Call: call}
+}
+
+// MockStreamFrameGetterhasRetransmissionCall wrap *gomock.Call
+type MockStreamFrameGetterhasRetransmissionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStreamFrameGetterhasRetransmissionCall) Return(arg0 bool) *MockStreamFrameGetterhasRetransmissionCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStreamFrameGetterhasRetransmissionCall) Do(f func() bool) *MockStreamFrameGetterhasRetransmissionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStreamFrameGetterhasRetransmissionCall) DoAndReturn(f func() bool) *MockStreamFrameGetterhasRetransmissionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // popStreamFrame mocks base method.
 func (m *MockStreamFrameGetter) popStreamFrame(arg0 protocol.ByteCount, arg1 protocol.Version) (ackhandler.StreamFrame, *wire.StreamDataBlockedFrame, bool) {
 	m.ctrl.T.Helper()
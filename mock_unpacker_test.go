@@ -122,3 +122,39 @@ func (c *MockUnpackerUnpackShortHeaderCall) DoAndReturn(f func(monotime.Time, []
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
+
+// UnpackShortHeaderBatch mocks base method.
+func (m *MockUnpacker) UnpackShortHeaderBatch(arg0 []receivedPacket) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UnpackShortHeaderBatch", arg0)
+}
+
+// UnpackShortHeaderBatch indicates an expected call of UnpackShortHeaderBatch.
+func (mr *MockUnpackerMockRecorder) UnpackShortHeaderBatch(arg0 any) *MockUnpackerUnpackShortHeaderBatchCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpackShortHeaderBatch", reflect.TypeOf((*MockUnpacker)(nil).UnpackShortHeaderBatch), arg0)
+	return &MockUnpackerUnpackShortHeaderBatchCall{Call: call}
+}
+
+// MockUnpackerUnpackShortHeaderBatchCall wrap *gomock.Call
+type MockUnpackerUnpackShortHeaderBatchCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUnpackerUnpackShortHeaderBatchCall) Return() *MockUnpackerUnpackShortHeaderBatchCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUnpackerUnpackShortHeaderBatchCall) Do(f func([]receivedPacket)) *MockUnpackerUnpackShortHeaderBatchCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUnpackerUnpackShortHeaderBatchCall) DoAndReturn(f func([]receivedPacket)) *MockUnpackerUnpackShortHeaderBatchCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
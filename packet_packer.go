@@ -24,7 +24,7 @@ type packer interface {
 	PackPTOProbePacket(_ protocol.EncryptionLevel, _ protocol.ByteCount, addPingIfEmpty bool, now monotime.Time, v protocol.Version) (*coalescedPacket, error)
 	PackConnectionClose(*qerr.TransportError, protocol.ByteCount, protocol.Version) (*coalescedPacket, error)
 	PackApplicationClose(*qerr.ApplicationError, protocol.ByteCount, protocol.Version) (*coalescedPacket, error)
-	PackPathProbePacket(protocol.ConnectionID, []ackhandler.Frame, protocol.Version) (shortHeaderPacket, *packetBuffer, error)
+	PackPathProbePacket(protocol.ConnectionID, []ackhandler.Frame, protocol.ByteCount, protocol.Version) (shortHeaderPacket, *packetBuffer, error)
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount, v protocol.Version) (shortHeaderPacket, *packetBuffer, error)
 
 	SetToken([]byte)
@@ -135,6 +135,12 @@ type packetPacker struct {
 	rand                rand.Rand
 
 	numNonAckElicitingAcks int
+
+	// If set, the padding required for datagrams containing Initial packets is added to the last
+	// packet coalesced into the datagram, instead of to the Initial packet.
+	coalescedInitialPadding bool
+
+	faultInjector func(FaultInfo) FaultAction // only set when testing
 }
 
 var _ packer = &packetPacker{}
@@ -151,23 +157,27 @@ func newPacketPacker(
 	acks ackFrameSource,
 	datagramQueue *datagramQueue,
 	perspective protocol.Perspective,
+	coalescedInitialPadding bool,
+	faultInjector func(FaultInfo) FaultAction,
 ) *packetPacker {
 	var b [16]byte
 	_, _ = crand.Read(b[:])
 
 	return &packetPacker{
-		cryptoSetup:         cryptoSetup,
-		getDestConnID:       getDestConnID,
-		srcConnID:           srcConnID,
-		initialStream:       initialStream,
-		handshakeStream:     handshakeStream,
-		retransmissionQueue: retransmissionQueue,
-		datagramQueue:       datagramQueue,
-		perspective:         perspective,
-		framer:              framer,
-		acks:                acks,
-		rand:                *rand.New(rand.NewPCG(binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:]))),
-		pnManager:           packetNumberManager,
+		cryptoSetup:             cryptoSetup,
+		getDestConnID:           getDestConnID,
+		srcConnID:               srcConnID,
+		initialStream:           initialStream,
+		handshakeStream:         handshakeStream,
+		retransmissionQueue:     retransmissionQueue,
+		datagramQueue:           datagramQueue,
+		perspective:             perspective,
+		coalescedInitialPadding: coalescedInitialPadding,
+		framer:                  framer,
+		acks:                    acks,
+		rand:                    *rand.New(rand.NewPCG(binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:]))),
+		pnManager:               packetNumberManager,
+		faultInjector:           faultInjector,
 	}
 }
 
@@ -425,29 +435,44 @@ func (p *packetPacker) PackCoalescedPacket(onlyAck bool, maxSize protocol.ByteCo
 		buffer:         buffer,
 		longHdrPackets: make([]*longHeaderPacket, 0, 3),
 	}
+	// The datagram is expanded to the required size by padding either the Initial packet,
+	// or the last packet coalesced into the datagram.
+	var initialPadding, handshakePadding, appDataPadding protocol.ByteCount
 	if initialPayload.length > 0 {
 		padding := p.initialPaddingLen(initialPayload.frames, size, maxSize)
-		cont, err := p.appendLongHeaderPacket(buffer, initialHdr, initialPayload, padding, protocol.EncryptionInitial, initialSealer, v)
+		switch {
+		case !p.coalescedInitialPadding:
+			initialPadding = padding
+		case zeroRTTPayload.length > 0 || oneRTTPayload.length > 0:
+			appDataPadding = padding
+		case handshakePayload.length > 0:
+			handshakePadding = padding
+		default:
+			initialPadding = padding
+		}
+	}
+	if initialPayload.length > 0 {
+		cont, err := p.appendLongHeaderPacket(buffer, initialHdr, initialPayload, initialPadding, protocol.EncryptionInitial, initialSealer, v)
 		if err != nil {
 			return nil, err
 		}
 		packet.longHdrPackets = append(packet.longHdrPackets, cont)
 	}
 	if handshakePayload.length > 0 {
-		cont, err := p.appendLongHeaderPacket(buffer, handshakeHdr, handshakePayload, 0, protocol.EncryptionHandshake, handshakeSealer, v)
+		cont, err := p.appendLongHeaderPacket(buffer, handshakeHdr, handshakePayload, handshakePadding, protocol.EncryptionHandshake, handshakeSealer, v)
 		if err != nil {
 			return nil, err
 		}
 		packet.longHdrPackets = append(packet.longHdrPackets, cont)
 	}
 	if zeroRTTPayload.length > 0 {
-		longHdrPacket, err := p.appendLongHeaderPacket(buffer, zeroRTTHdr, zeroRTTPayload, 0, protocol.Encryption0RTT, zeroRTTSealer, v)
+		longHdrPacket, err := p.appendLongHeaderPacket(buffer, zeroRTTHdr, zeroRTTPayload, appDataPadding, protocol.Encryption0RTT, zeroRTTSealer, v)
 		if err != nil {
 			return nil, err
 		}
 		packet.longHdrPackets = append(packet.longHdrPackets, longHdrPacket)
 	} else if oneRTTPayload.length > 0 {
-		shp, err := p.appendShortHeaderPacket(buffer, connID, oneRTTPacketNumber, oneRTTPacketNumberLen, kp, oneRTTPayload, 0, maxSize, oneRTTSealer, false, v)
+		shp, err := p.appendShortHeaderPacket(buffer, connID, oneRTTPacketNumber, oneRTTPacketNumberLen, kp, oneRTTPayload, appDataPadding, maxSize, oneRTTSealer, false, v)
 		if err != nil {
 			return nil, err
 		}
@@ -668,6 +693,7 @@ func (p *packetPacker) composeNextPacket(
 	}
 
 	if pl.ack != nil && !hasData && !hasRetransmission {
+		omitDatagramLength(&pl, v)
 		return pl
 	}
 
@@ -706,9 +732,29 @@ func (p *packetPacker) composeNextPacket(
 			}
 		}
 	}
+	omitDatagramLength(&pl, v)
 	return pl
 }
 
+// omitDatagramLength removes the length field from the DATAGRAM frame, if the payload contains one.
+// This is only possible if the DATAGRAM frame is the last frame in the packet.
+// STREAM frames are always packed after the control frames, so this is only the case
+// if the payload doesn't contain any STREAM frames.
+// appendPacketPayload then takes care of appending the DATAGRAM frame last.
+func omitDatagramLength(pl *payload, v protocol.Version) {
+	if len(pl.streamFrames) > 0 {
+		return
+	}
+	for _, f := range pl.frames {
+		if df, ok := f.Frame.(*wire.DatagramFrame); ok && df.DataLenPresent {
+			pl.length -= df.Length(v)
+			df.DataLenPresent = false
+			pl.length += df.Length(v)
+			return
+		}
+	}
+}
+
 func (p *packetPacker) PackPTOProbePacket(
 	encLevel protocol.EncryptionLevel,
 	maxPacketSize protocol.ByteCount,
@@ -811,7 +857,8 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 	return packet, buffer, err
 }
 
-func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, frames []ackhandler.Frame, v protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
+// PackPathProbePacket packs a path probe packet, padded to size bytes.
+func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, frames []ackhandler.Frame, size protocol.ByteCount, v protocol.Version) (shortHeaderPacket, *packetBuffer, error) {
 	pn, pnLen := p.pnManager.PeekPacketNumber(protocol.Encryption1RTT)
 	buf := getPacketBuffer()
 	s, err := p.cryptoSetup.Get1RTTSealer()
@@ -826,8 +873,8 @@ func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, frames
 		frames: frames,
 		length: l,
 	}
-	padding := protocol.MinInitialPacketSize - p.shortHeaderPacketLength(connID, pnLen, payload) - protocol.ByteCount(s.Overhead())
-	packet, err := p.appendShortHeaderPacket(buf, connID, pn, pnLen, s.KeyPhase(), payload, padding, protocol.MinInitialPacketSize, s, false, v)
+	padding := max(0, size-p.shortHeaderPacketLength(connID, pnLen, payload)-protocol.ByteCount(s.Overhead()))
+	packet, err := p.appendShortHeaderPacket(buf, connID, pn, pnLen, s.KeyPhase(), payload, padding, size, s, false, v)
 	if err != nil {
 		return shortHeaderPacket{}, nil, err
 	}
@@ -966,6 +1013,14 @@ func (p *packetPacker) appendPacketPayload(raw []byte, pl payload, paddingLen pr
 	// This makes sure that the receiver doesn't rely on the order in which frames are packed.
 	if len(pl.frames) > 1 {
 		p.rand.Shuffle(len(pl.frames), func(i, j int) { pl.frames[i], pl.frames[j] = pl.frames[j], pl.frames[i] })
+		// a DATAGRAM frame without a length field extends to the end of the packet
+		for i, f := range pl.frames {
+			if df, ok := f.Frame.(*wire.DatagramFrame); ok && !df.DataLenPresent {
+				last := len(pl.frames) - 1
+				pl.frames[i], pl.frames[last] = pl.frames[last], pl.frames[i]
+				break
+			}
+		}
 	}
 	for _, f := range pl.frames {
 		var err error
@@ -989,6 +1044,9 @@ func (p *packetPacker) appendPacketPayload(raw []byte, pl payload, paddingLen pr
 }
 
 func (p *packetPacker) encryptPacket(raw []byte, sealer sealer, pn protocol.PacketNumber, payloadOffset, pnLen protocol.ByteCount) []byte {
+	if p.faultInjector != nil {
+		applyPlaintextFault(p.faultInjector, FaultDirectionOutgoing, raw[payloadOffset:])
+	}
 	_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], pn, raw[:payloadOffset])
 	raw = raw[:len(raw)+sealer.Overhead()]
 	// apply header protection
@@ -67,6 +67,8 @@ func newTestPacketPacker(t *testing.T, mockCtrl *gomock.Controller, pers protoco
 			ackFramer,
 			datagramQueue,
 			pers,
+			false,
+			nil,
 		),
 	}
 }
@@ -322,6 +324,60 @@ func TestPack0RTTPacket(t *testing.T) {
 	require.NotNil(t, p.longHdrPackets[0].frames[0].Handler)
 }
 
+func TestPackInitialPadding(t *testing.T) {
+	t.Run("padding the Initial packet", func(t *testing.T) {
+		testPackInitialPadding(t, false)
+	})
+	t.Run("padding the coalesced packet", func(t *testing.T) {
+		testPackInitialPadding(t, true)
+	})
+}
+
+func testPackInitialPadding(t *testing.T, coalescedPadding bool) {
+	const maxPacketSize protocol.ByteCount = 1234
+
+	mockCtrl := gomock.NewController(t)
+	tp := newTestPacketPacker(t, mockCtrl, protocol.PerspectiveClient)
+	tp.packer.coalescedInitialPadding = coalescedPadding
+	tp.pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+	tp.pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+	tp.pnManager.EXPECT().PeekPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+	tp.pnManager.EXPECT().PopPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42))
+	tp.sealingManager.EXPECT().GetInitialSealer().Return(newMockShortHeaderSealer(mockCtrl), nil)
+	tp.sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+	tp.sealingManager.EXPECT().Get0RTTSealer().Return(newMockShortHeaderSealer(mockCtrl), nil)
+	tp.sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+	tp.ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, gomock.Any(), false)
+	tp.framer.EXPECT().HasData().Return(true)
+	expectAppendFrames(tp.framer, nil, []ackhandler.StreamFrame{{Frame: &wire.StreamFrame{Data: []byte("foobar")}}})
+	clientHello := getClientHello(t, "quic-go.net")
+	tp.initialStream.Write(clientHello)
+
+	p, err := tp.packer.PackCoalescedPacket(false, maxPacketSize, monotime.Now(), protocol.Version1)
+	require.NoError(t, err)
+	// the datagram is padded to the maximum packet size either way
+	require.Equal(t, maxPacketSize, p.buffer.Len())
+	require.Len(t, p.longHdrPackets, 2)
+	require.Equal(t, protocol.EncryptionInitial, p.longHdrPackets[0].EncryptionLevel())
+	require.Equal(t, protocol.Encryption0RTT, p.longHdrPackets[1].EncryptionLevel())
+	require.Len(t, p.longHdrPackets[1].streamFrames, 1)
+
+	hdrs, more := parsePacket(t, p.buffer.Data)
+	require.Len(t, hdrs, 2)
+	require.Empty(t, more)
+	require.Equal(t, protocol.PacketTypeInitial, hdrs[0].Type)
+	require.Equal(t, protocol.PacketType0RTT, hdrs[1].Type)
+	initialLen := hdrs[0].Length
+	zeroRTTLen := hdrs[1].Length
+	if coalescedPadding {
+		require.Less(t, initialLen, protocol.ByteCount(len(clientHello)+100))
+		require.Greater(t, zeroRTTLen, maxPacketSize-initialLen-100)
+	} else {
+		require.Greater(t, initialLen, maxPacketSize-100)
+		require.Less(t, zeroRTTLen, protocol.ByteCount(100))
+	}
+}
+
 // ACK frames can't be sent in 0-RTT packets
 func TestPack0RTTPacketNoACK(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
@@ -665,9 +721,89 @@ func TestPackDatagramFrames(t *testing.T) {
 	require.Len(t, p.Frames, 1)
 	require.IsType(t, &wire.DatagramFrame{}, p.Frames[0].Frame)
 	require.Equal(t, []byte("foobar"), p.Frames[0].Frame.(*wire.DatagramFrame).Data)
+	// the DATAGRAM frame is the last frame in the packet, so the length field is omitted
+	require.False(t, p.Frames[0].Frame.(*wire.DatagramFrame).DataLenPresent)
 	require.NotEmpty(t, buffer.Data)
 }
 
+func TestPackDatagramFrameWithoutLength(t *testing.T) {
+	t.Run("with control frames", func(t *testing.T) {
+		testPackDatagramFrameWithoutLength(t, false)
+	})
+	t.Run("with STREAM frames", func(t *testing.T) {
+		testPackDatagramFrameWithoutLength(t, true)
+	})
+}
+
+func testPackDatagramFrameWithoutLength(t *testing.T, withStreamFrame bool) {
+	mockCtrl := gomock.NewController(t)
+	tp := newTestPacketPacker(t, mockCtrl, protocol.PerspectiveServer)
+
+	tp.ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, gomock.Any(), false)
+	tp.pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+	tp.pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+	sealer := newMockShortHeaderSealer(mockCtrl)
+	tp.sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+	tp.datagramQueue.Add(&wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")})
+	tp.framer.EXPECT().HasData().Return(true)
+	controlFrames := []ackhandler.Frame{
+		{Frame: &wire.MaxDataFrame{MaximumData: 0x1337}},
+		{Frame: &wire.MaxStreamsFrame{MaxStreamNum: 42}},
+		{Frame: &wire.PingFrame{}},
+	}
+	var streamFrames []ackhandler.StreamFrame
+	if withStreamFrame {
+		streamFrames = append(streamFrames, ackhandler.StreamFrame{Frame: &wire.StreamFrame{StreamID: 5, Data: []byte("lorem")}})
+	}
+	expectAppendFrames(tp.framer, controlFrames, streamFrames)
+
+	buffer := getPacketBuffer()
+	p, err := tp.packer.AppendPacket(buffer, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
+	require.NoError(t, err)
+	require.Len(t, p.Frames, 4)
+	var datagram *wire.DatagramFrame
+	for _, f := range p.Frames {
+		if df, ok := f.Frame.(*wire.DatagramFrame); ok {
+			datagram = df
+		}
+	}
+	require.NotNil(t, datagram)
+	// the length field can only be omitted if the DATAGRAM frame is the last frame in the packet
+	require.Equal(t, withStreamFrame, datagram.DataLenPresent)
+
+	// parse the packet
+	data := buffer.Data[:len(buffer.Data)-sealer.Overhead()]
+	l, _, _, _, err := wire.ParseShortHeader(data, 4)
+	require.NoError(t, err)
+	data = data[l:]
+	parser := wire.NewFrameParser(true, false, false)
+	var frames []wire.Frame
+	for len(data) > 0 {
+		typ, l, err := parser.ParseType(data, protocol.Encryption1RTT)
+		require.NoError(t, err)
+		data = data[l:]
+		var frame wire.Frame
+		switch {
+		case typ.IsStreamFrameType():
+			frame, l, err = parser.ParseStreamFrame(typ, data, protocol.Version1)
+		case typ.IsDatagramFrameType():
+			frame, l, err = parser.ParseDatagramFrame(typ, data, protocol.Version1)
+		default:
+			frame, l, err = parser.ParseLessCommonFrame(typ, data, protocol.Version1)
+		}
+		require.NoError(t, err)
+		data = data[l:]
+		frames = append(frames, frame)
+	}
+	require.Len(t, frames, len(p.Frames)+len(p.StreamFrames))
+	require.Contains(t, frames, &wire.DatagramFrame{DataLenPresent: withStreamFrame, Data: []byte("foobar")})
+	if withStreamFrame {
+		require.IsType(t, &wire.StreamFrame{}, frames[len(frames)-1])
+	} else {
+		require.IsType(t, &wire.DatagramFrame{}, frames[len(frames)-1])
+	}
+}
+
 func TestPackLargeDatagramFrame(t *testing.T) {
 	// If a packet contains an ACK, and doesn't have enough space for the DATAGRAM frame,
 	// it should be skipped. It will be packed in the next packet.
@@ -1057,6 +1193,7 @@ func TestPackPathProbePacket(t *testing.T) {
 			{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}},
 			{Frame: &wire.PathResponseFrame{Data: [8]byte{8, 7, 6, 5, 4, 3, 2, 1}}},
 		},
+		protocol.MinInitialPacketSize,
 		protocol.Version1,
 	)
 	require.NoError(t, err)
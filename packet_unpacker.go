@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/monotime"
@@ -32,11 +33,26 @@ type unpackedPacket struct {
 	data            []byte
 }
 
+// unpackedShortHeaderPacket is a 1-RTT packet that was unpacked as part of a batch.
+type unpackedShortHeaderPacket struct {
+	pn    protocol.PacketNumber
+	pnLen protocol.PacketNumberLen
+	kp    protocol.KeyPhaseBit
+	data  []byte
+	err   error
+
+	opened bool // false if parsing the header failed
+}
+
 // The packetUnpacker unpacks QUIC packets.
 type packetUnpacker struct {
 	cs handshake.CryptoSetup
 
 	shortHdrConnIDLen int
+
+	// used by UnpackShortHeaderBatch, reused to avoid allocations
+	unpacked     []unpackedShortHeaderPacket
+	openRequests []handshake.OpenRequest
 }
 
 var _ unpacker = &packetUnpacker{}
@@ -124,6 +140,87 @@ func (u *packetUnpacker) UnpackShortHeader(rcvTime monotime.Time, data []byte) (
 	return pn, pnLen, kp, decrypted, nil
 }
 
+// UnpackShortHeaderBatch unpacks all 1-RTT packets contained in a batch of received packets.
+// Header protection is removed serially, the payloads are then decrypted concurrently.
+// For every 1-RTT packet, the result is stored in the unpacked field of the received packet.
+// The results are only valid until the next call to UnpackShortHeaderBatch.
+//
+// Unlike UnpackShortHeader, all packet numbers are decoded relative to the highest packet number
+// received before this batch, since it is not yet known which packets of the batch can be decrypted.
+// This is correct for any packet sent by a compliant peer: the peer chooses the packet number length
+// based on the largest acknowledged packet number, and all ACKs were sent before the batch was received.
+// Decoding relative to a packet number of the same batch instead would allow an attacker to
+// inject a packet with a forged packet number, causing the decryption of the following packets to fail.
+// If the 1-RTT keys don't support concurrent decryption, no packets are unpacked.
+func (u *packetUnpacker) UnpackShortHeaderBatch(packets []receivedPacket) {
+	o, err := u.cs.Get1RTTOpener()
+	if err != nil {
+		return
+	}
+	opener, ok := o.(handshake.BatchShortHeaderOpener)
+	if !ok {
+		return
+	}
+
+	// Make sure that the slice is not reallocated, as the received packets point into it.
+	u.unpacked = slices.Grow(u.unpacked[:0], len(packets))
+	u.openRequests = u.openRequests[:0]
+	for i := range packets {
+		p := &packets[i]
+		if wire.IsLongHeaderPacket(p.data[0]) {
+			continue
+		}
+		u.unpacked = append(u.unpacked, unpackedShortHeaderPacket{})
+		up := &u.unpacked[len(u.unpacked)-1]
+		p.unpacked = up
+
+		l, pn, pnLen, kp, parseErr := u.unpackShortHeader(opener, p.data)
+		if parseErr != nil && parseErr != wire.ErrInvalidReservedBits {
+			up.err = &headerParseError{parseErr}
+			continue
+		}
+		// See the function documentation for why this doesn't use the packet numbers of this batch.
+		up.pn = opener.DecodePacketNumber(pn, pnLen)
+		up.pnLen = pnLen
+		up.kp = kp
+		up.err = parseErr
+		up.opened = true
+		u.openRequests = append(u.openRequests, handshake.OpenRequest{
+			Dst:            p.data[l:l],
+			Src:            p.data[l:],
+			AssociatedData: p.data[:l],
+			RcvTime:        p.rcvTime,
+			PacketNumber:   up.pn,
+			KeyPhase:       kp,
+		})
+	}
+
+	opener.OpenBatch(u.openRequests)
+
+	var j int
+	for i := range u.unpacked {
+		up := &u.unpacked[i]
+		if !up.opened {
+			continue
+		}
+		r := &u.openRequests[j]
+		j++
+		switch {
+		case r.Err != nil:
+			up.err = r.Err
+		case up.err != nil: // invalid reserved bits
+		case len(r.Decrypted) == 0:
+			up.err = &qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "empty packet",
+			}
+		default:
+			up.data = r.Decrypted
+		}
+	}
+	clear(u.openRequests)
+}
+
 func (u *packetUnpacker) unpackLongHeaderPacket(opener handshake.LongHeaderOpener, hdr *wire.Header, data []byte) (*wire.ExtendedHeader, []byte, error) {
 	extHdr, parseErr := u.unpackLongHeader(opener, hdr, data)
 	// If the reserved bits are set incorrectly, we still need to continue unpacking.
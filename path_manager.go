@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"bytes"
 	"crypto/rand"
 	"net"
 	"slices"
@@ -28,13 +29,47 @@ const maxPaths = 3
 // sending them with spoofed source addresses.
 const pathTimeout = 5 * time.Second
 
+// Maximum number of path validations started for a single IP address within
+// pathValidationRateInterval. This limits the number of PATH_CHALLENGEs an attacker can
+// elicit by sending packets from many different ports.
+const (
+	maxPathValidationsPerAddr  = 5
+	pathValidationRateInterval = 10 * time.Second
+)
+
+// Maximum number of IP addresses to track for rate limiting path validations.
+// If this limit is reached, no new path validations are started until old entries expire.
+const maxPathValidationAddrs = 32
+
+// Maximum number of path switches within pathSwitchInterval.
+// This prevents an attacker from making the connection flap between two (or more) paths.
+const (
+	maxPathSwitches    = 3
+	pathSwitchInterval = time.Second
+)
+
+// Maximum size of a path probe packet without any padding: a short header packet with the
+// longest possible connection ID and packet number, containing a PATH_CHALLENGE and a
+// PATH_RESPONSE frame (9 bytes each), and a 16 byte AEAD tag.
+const maxUnpaddedPathProbePacketSize = 1 + protocol.MaxConnIDLen + 4 + 2*9 + 16
+
 type path struct {
 	id             pathID
 	addr           net.Addr
 	lastPacketTime monotime.Time
 	pathChallenge  [8]byte
+	challengeSent  bool
 	validated      bool
 	rcvdNonProbing bool
+
+	// used to enforce the anti-amplification limit on unvalidated paths
+	bytesReceived protocol.ByteCount
+	bytesSent     protocol.ByteCount
+}
+
+type pathValidationAttempts struct {
+	firstAttempt monotime.Time
+	count        int
 }
 
 type pathManager struct {
@@ -45,36 +80,58 @@ type pathManager struct {
 	getConnID    func(pathID) (_ protocol.ConnectionID, ok bool)
 	retireConnID func(pathID)
 
-	logger utils.Logger
+	// If set, a PATH_RESPONSE only validates the path it was received on.
+	strictPathValidation bool
+
+	// number of path validations started, by IP address
+	validationAttempts map[string]*pathValidationAttempts
+	// times of the most recent path switches, ordered from oldest to newest
+	switchTimes []monotime.Time
+
+	connStats *utils.ConnectionStats
+	logger    utils.Logger
 }
 
 func newPathManager(
 	getConnID func(pathID) (_ protocol.ConnectionID, ok bool),
 	retireConnID func(pathID),
+	strictPathValidation bool,
+	connStats *utils.ConnectionStats,
 	logger utils.Logger,
 ) *pathManager {
 	return &pathManager{
-		paths:        make([]*path, 0, maxPaths+1),
-		getConnID:    getConnID,
-		retireConnID: retireConnID,
-		logger:       logger,
+		paths:                make([]*path, 0, maxPaths+1),
+		getConnID:            getConnID,
+		retireConnID:         retireConnID,
+		strictPathValidation: strictPathValidation,
+		validationAttempts:   make(map[string]*pathValidationAttempts),
+		connStats:            connStats,
+		logger:               logger,
 	}
 }
 
-// Returns a path challenge frame if one should be sent.
-// May return nil.
+// HandlePacket handles a packet of size bytes received from remoteAddr.
+// It returns the frames that should be sent in a path probe packet, and the size that
+// packet should be padded to. May return nil.
+// Path probe packets are padded to protocol.MinInitialPacketSize, unless the anti-amplification
+// limit of an unvalidated path doesn't allow this (see section 8.2.1 of RFC 9000).
+// This is the only data sent on unvalidated paths: all other packets are sent on the
+// active path until the connection switches to a validated path.
 func (pm *pathManager) HandlePacket(
 	remoteAddr net.Addr,
 	t monotime.Time,
+	size protocol.ByteCount,
 	pathChallenge *wire.PathChallengeFrame, // may be nil if the packet didn't contain a PATH_CHALLENGE
 	isNonProbing bool,
-) (_ protocol.ConnectionID, _ []ackhandler.Frame, shouldSwitch bool) {
+) (_ protocol.ConnectionID, _ []ackhandler.Frame, probeSize protocol.ByteCount, shouldSwitch bool) {
 	var p *path
 	for i, path := range pm.paths {
 		if addrsEqual(path.addr, remoteAddr) {
 			p = path
 			p.lastPacketTime = t
-			// already sent a PATH_CHALLENGE for this path
+			if !p.validated {
+				p.bytesReceived += size
+			}
 			if isNonProbing {
 				path.rcvdNonProbing = true
 			}
@@ -82,27 +139,42 @@ func (pm *pathManager) HandlePacket(
 				pm.logger.Debugf("received packet for path %s that was already probed, validated: %t", remoteAddr, path.validated)
 			}
 			shouldSwitch = path.validated && path.rcvdNonProbing
+			if shouldSwitch && len(pm.switchTimes) >= maxPathSwitches && t.Sub(pm.switchTimes[0]) < pathSwitchInterval {
+				pm.logger.Debugf("not switching to path %s, since there were already %d path switches in the last %s", remoteAddr, len(pm.switchTimes), t.Sub(pm.switchTimes[0]))
+				pm.connStats.PathSwitchesDropped.Add(1)
+				shouldSwitch = false
+			}
 			if i != len(pm.paths)-1 {
 				// move the path to the end of the list
 				pm.paths = slices.Delete(pm.paths, i, i+1)
 				pm.paths = append(pm.paths, p)
 			}
-			if pathChallenge == nil {
-				return protocol.ConnectionID{}, nil, shouldSwitch
+			// already sent a PATH_CHALLENGE for this path
+			if pathChallenge == nil && p.challengeSent {
+				return protocol.ConnectionID{}, nil, 0, shouldSwitch
 			}
+			break
 		}
 	}
 
-	if len(pm.paths) >= maxPaths {
-		if pm.paths[0].lastPacketTime.Add(pathTimeout).After(t) {
-			if pm.logger.Debug() {
-				pm.logger.Debugf("received packet for previously unseen path %s, but already have %d paths", remoteAddr, len(pm.paths))
+	if p == nil {
+		if len(pm.paths) >= maxPaths {
+			if pm.paths[0].lastPacketTime.Add(pathTimeout).After(t) {
+				if pm.logger.Debug() {
+					pm.logger.Debugf("received packet for previously unseen path %s, but already have %d paths", remoteAddr, len(pm.paths))
+				}
+				pm.connStats.PathValidationsDropped.Add(1)
+				return protocol.ConnectionID{}, nil, 0, shouldSwitch
 			}
-			return protocol.ConnectionID{}, nil, shouldSwitch
+			// evict the oldest path, if the last packet was received more than pathTimeout ago
+			pm.retireConnID(pm.paths[0].id)
+			pm.paths = pm.paths[1:]
+		}
+		if !pm.allowPathValidation(remoteAddr, t) {
+			pm.logger.Debugf("received packet for previously unseen path %s, but too many paths were probed for this address", remoteAddr)
+			pm.connStats.PathValidationsDropped.Add(1)
+			return protocol.ConnectionID{}, nil, 0, shouldSwitch
 		}
-		// evict the oldest path, if the last packet was received more than pathTimeout ago
-		pm.retireConnID(pm.paths[0].id)
-		pm.paths = pm.paths[1:]
 	}
 
 	var pathID pathID
@@ -116,10 +188,9 @@ func (pm *pathManager) HandlePacket(
 	connID, ok := pm.getConnID(pathID)
 	if !ok {
 		pm.logger.Debugf("skipping validation of new path %s since no connection ID is available", remoteAddr)
-		return protocol.ConnectionID{}, nil, shouldSwitch
+		return protocol.ConnectionID{}, nil, 0, shouldSwitch
 	}
 
-	frames := make([]ackhandler.Frame, 0, 2)
 	if p == nil {
 		var pathChallengeData [8]byte
 		rand.Read(pathChallengeData[:])
@@ -129,9 +200,30 @@ func (pm *pathManager) HandlePacket(
 			lastPacketTime: t,
 			rcvdNonProbing: isNonProbing,
 			pathChallenge:  pathChallengeData,
+			bytesReceived:  size,
 		}
 		pm.nextPathID++
 		pm.paths = append(pm.paths, p)
+		pm.recordPathValidation(remoteAddr, t)
+	}
+
+	probeSize = protocol.MinInitialPacketSize
+	if !p.validated {
+		// RFC 9000, section 8.2.1: the anti-amplification limit applies to unvalidated paths
+		if 3*p.bytesReceived < p.bytesSent+maxUnpaddedPathProbePacketSize {
+			if pm.logger.Debug() {
+				pm.logger.Debugf("not sending path probe to %s, since the anti-amplification limit was reached (received: %d, sent: %d)", remoteAddr, p.bytesReceived, p.bytesSent)
+			}
+			pm.connStats.PathProbesAmplificationLimited.Add(1)
+			return protocol.ConnectionID{}, nil, 0, shouldSwitch
+		}
+		probeSize = min(probeSize, 3*p.bytesReceived-p.bytesSent)
+		p.bytesSent += probeSize
+	}
+
+	frames := make([]ackhandler.Frame, 0, 2)
+	if !p.challengeSent {
+		p.challengeSent = true
 		frames = append(frames, ackhandler.Frame{
 			Frame:   &wire.PathChallengeFrame{Data: p.pathChallenge},
 			Handler: (*pathManagerAckHandler)(pm),
@@ -144,22 +236,76 @@ func (pm *pathManager) HandlePacket(
 			Handler: (*pathManagerAckHandler)(pm),
 		})
 	}
-	return connID, frames, shouldSwitch
+	return connID, frames, probeSize, shouldSwitch
+}
+
+func pathValidationKey(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+	return addr.String()
+}
+
+// allowPathValidation says if a new path validation can be started for remoteAddr.
+func (pm *pathManager) allowPathValidation(remoteAddr net.Addr, t monotime.Time) bool {
+	if a, ok := pm.validationAttempts[pathValidationKey(remoteAddr)]; ok {
+		if t.Sub(a.firstAttempt) >= pathValidationRateInterval {
+			return true
+		}
+		return a.count < maxPathValidationsPerAddr
+	}
+	if len(pm.validationAttempts) < maxPathValidationAddrs {
+		return true
+	}
+	for key, a := range pm.validationAttempts {
+		if t.Sub(a.firstAttempt) >= pathValidationRateInterval {
+			delete(pm.validationAttempts, key)
+		}
+	}
+	return len(pm.validationAttempts) < maxPathValidationAddrs
 }
 
-func (pm *pathManager) HandlePathResponseFrame(f *wire.PathResponseFrame) {
+func (pm *pathManager) recordPathValidation(remoteAddr net.Addr, t monotime.Time) {
+	key := pathValidationKey(remoteAddr)
+	a, ok := pm.validationAttempts[key]
+	if !ok || t.Sub(a.firstAttempt) >= pathValidationRateInterval {
+		pm.validationAttempts[key] = &pathValidationAttempts{firstAttempt: t, count: 1}
+		return
+	}
+	a.count++
+}
+
+// HandlePathResponseFrame handles a PATH_RESPONSE frame received on the path to remoteAddr.
+// If the PATH_RESPONSE was received on a different path than the one the corresponding
+// PATH_CHALLENGE was sent on, it returns the address of the challenged path.
+// RFC 9000 allows PATH_RESPONSE frames to validate a path when received on any path.
+// When strict path validation is enabled, such a PATH_RESPONSE doesn't validate the path.
+func (pm *pathManager) HandlePathResponseFrame(f *wire.PathResponseFrame, remoteAddr net.Addr) (mismatchedPath net.Addr) {
 	for _, p := range pm.paths {
-		if f.Data == p.pathChallenge {
-			// path validated
-			p.validated = true
-			pm.logger.Debugf("path %s validated", p.addr)
-			break
+		if f.Data != p.pathChallenge {
+			continue
 		}
+		if !addrsEqual(p.addr, remoteAddr) {
+			mismatchedPath = p.addr
+			if pm.strictPathValidation {
+				pm.logger.Debugf("ignoring PATH_RESPONSE for path %s received from %s", p.addr, remoteAddr)
+				break
+			}
+		}
+		// path validated
+		p.validated = true
+		pm.logger.Debugf("path %s validated", p.addr)
+		break
 	}
+	return mismatchedPath
 }
 
 // SwitchToPath is called when the connection switches to a new path
-func (pm *pathManager) SwitchToPath(addr net.Addr) {
+func (pm *pathManager) SwitchToPath(addr net.Addr, t monotime.Time) {
+	if len(pm.switchTimes) >= maxPathSwitches {
+		pm.switchTimes = pm.switchTimes[1:]
+	}
+	pm.switchTimes = append(pm.switchTimes, t)
 	// retire all other paths
 	for _, path := range pm.paths {
 		if addrsEqual(path.addr, addr) {
@@ -193,6 +339,25 @@ func (pm *pathManagerAckHandler) OnLost(f wire.Frame) {
 	}
 }
 
+// ipsWithinTolerance says if the IP addresses of addr1 and addr2 only differ in their last tolerance bytes.
+// Ports are ignored. Addresses that are not UDP addresses are compared using addrsEqual.
+func ipsWithinTolerance(addr1, addr2 net.Addr, tolerance int) bool {
+	a1, ok1 := addr1.(*net.UDPAddr)
+	a2, ok2 := addr2.(*net.UDPAddr)
+	if !ok1 || !ok2 {
+		return addrsEqual(addr1, addr2)
+	}
+	ip1, ip2 := a1.IP.To4(), a2.IP.To4()
+	if ip1 == nil || ip2 == nil {
+		ip1, ip2 = a1.IP.To16(), a2.IP.To16()
+		if ip1 == nil || ip2 == nil {
+			return false
+		}
+	}
+	n := max(len(ip1)-tolerance, 0)
+	return bytes.Equal(ip1[:n], ip2[:n])
+}
+
 func addrsEqual(addr1, addr2 net.Addr) bool {
 	if addr1 == nil || addr2 == nil {
 		return false
@@ -4,13 +4,17 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"maps"
+	"net"
 	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -19,6 +23,9 @@ var (
 	ErrPathClosed = errors.New("path closed")
 	// ErrPathNotValidated is returned when trying to use a path before path probing has completed.
 	ErrPathNotValidated = errors.New("path not yet validated")
+	// ErrPathValidationFailed is returned when the peer didn't respond to any PATH_CHALLENGE
+	// within the configured number of retransmissions (see Config.MaxPathChallengeRetransmissions).
+	ErrPathValidationFailed = errors.New("path validation failed")
 )
 
 var errPathDoesNotExist = errors.New("path does not exist")
@@ -27,7 +34,8 @@ var errPathDoesNotExist = errors.New("path does not exist")
 type Path struct {
 	id          pathID
 	pathManager *pathManagerOutgoing
-	tr          *Transport
+	tr          *Transport // nil if the path uses the connection's current socket
+	remoteAddr  net.Addr   // nil if the path uses the connection's current remote address
 	initialRTT  time.Duration
 
 	enablePath func()
@@ -40,6 +48,8 @@ func (p *Path) Probe(ctx context.Context) error {
 
 	p.pathManager.enqueueProbe(p)
 	nextProbeDur := p.initialRTT
+	maxRetransmissions := p.pathManager.maxChallengeRetransmissions
+	var numRetransmissions int
 	var timer *time.Timer
 	var timerChan <-chan time.Time
 	for {
@@ -50,6 +60,10 @@ func (p *Path) Probe(ctx context.Context) error {
 			p.validated.Store(true)
 			return nil
 		case <-timerChan:
+			if maxRetransmissions > 0 && numRetransmissions >= maxRetransmissions {
+				return ErrPathValidationFailed
+			}
+			numRetransmissions++
 			nextProbeDur *= 2 // exponential backoff
 			p.pathManager.enqueueProbe(p)
 		case <-path.ProbeSent():
@@ -103,13 +117,23 @@ func (p *Path) Close() error {
 	return nil
 }
 
+type pathChallenge struct {
+	data     [8]byte
+	sentTime monotime.Time
+}
+
 type pathOutgoing struct {
-	pathChallenges [][8]byte // length is implicitly limited by exponential backoff
-	tr             *Transport
-	isValidated    bool
-	probeSent      chan struct{} // receives when a PATH_CHALLENGE is sent
-	validated      chan struct{} // closed when the path the corresponding PATH_RESPONSE is received
-	enablePath     func()
+	pathChallenges []pathChallenge // length is implicitly limited by exponential backoff
+	// number of PATH_CHALLENGEs sent since the path was last (re-)probed
+	numChallengesSent int
+	tr                *Transport
+	remoteAddr        net.Addr
+	isValidated       bool
+	// rttStats is updated with the RTT samples obtained from PATH_CHALLENGE / PATH_RESPONSE exchanges
+	rttStats   *utils.RTTStats
+	probeSent  chan struct{} // receives when a PATH_CHALLENGE is sent
+	validated  chan struct{} // closed when the path the corresponding PATH_RESPONSE is received
+	enablePath func()
 }
 
 func (p *pathOutgoing) ProbeSent() <-chan struct{} { return p.probeSent }
@@ -119,6 +143,10 @@ type pathManagerOutgoing struct {
 	getConnID       func(pathID) (_ protocol.ConnectionID, ok bool)
 	retireConnID    func(pathID)
 	scheduleSending func()
+	// called when a PATH_CHALLENGE is retransmitted, may be nil
+	onChallengeRetransmitted func(_ *Transport, remoteAddr net.Addr, count int)
+
+	maxChallengeRetransmissions int
 
 	mx             sync.Mutex
 	activePath     pathID
@@ -135,14 +163,18 @@ func newPathManagerOutgoing(
 	getConnID func(pathID) (_ protocol.ConnectionID, ok bool),
 	retireConnID func(pathID),
 	scheduleSending func(),
+	maxChallengeRetransmissions int,
+	onChallengeRetransmitted func(_ *Transport, remoteAddr net.Addr, count int),
 ) *pathManagerOutgoing {
 	return &pathManagerOutgoing{
-		activePath:      0, // at initialization time, we're guaranteed to be using the handshake path
-		nextPathID:      1,
-		getConnID:       getConnID,
-		retireConnID:    retireConnID,
-		scheduleSending: scheduleSending,
-		paths:           make(map[pathID]*pathOutgoing, 4),
+		activePath:                  0, // at initialization time, we're guaranteed to be using the handshake path
+		nextPathID:                  1,
+		getConnID:                   getConnID,
+		retireConnID:                retireConnID,
+		scheduleSending:             scheduleSending,
+		maxChallengeRetransmissions: max(maxChallengeRetransmissions, 0),
+		onChallengeRetransmitted:    onChallengeRetransmitted,
+		paths:                       make(map[pathID]*pathOutgoing, 4),
 	}
 }
 
@@ -153,11 +185,14 @@ func (pm *pathManagerOutgoing) addPath(p *Path, enablePath func()) *pathOutgoing
 	// path might already exist, and just being re-probed
 	if existingPath, ok := pm.paths[p.id]; ok {
 		existingPath.validated = make(chan struct{})
+		existingPath.numChallengesSent = 0
 		return existingPath
 	}
 
 	path := &pathOutgoing{
 		tr:         p.tr,
+		remoteAddr: p.remoteAddr,
+		rttStats:   utils.NewRTTStats(),
 		probeSent:  make(chan struct{}, 1),
 		validated:  make(chan struct{}),
 		enablePath: enablePath,
@@ -168,7 +203,10 @@ func (pm *pathManagerOutgoing) addPath(p *Path, enablePath func()) *pathOutgoing
 
 func (pm *pathManagerOutgoing) enqueueProbe(p *Path) {
 	pm.mx.Lock()
-	pm.pathsToProbe = append(pm.pathsToProbe, p.id)
+	// If the path is already queued, the probes are coalesced into a single PATH_CHALLENGE.
+	if !slices.Contains(pm.pathsToProbe, p.id) {
+		pm.pathsToProbe = append(pm.pathsToProbe, p.id)
+	}
 	pm.mx.Unlock()
 	pm.scheduleSending()
 }
@@ -216,6 +254,16 @@ func (pm *pathManagerOutgoing) switchToPath(id pathID) error {
 }
 
 func (pm *pathManagerOutgoing) NewPath(t *Transport, initialRTT time.Duration, enablePath func()) *Path {
+	return pm.newPath(t, nil, initialRTT, enablePath)
+}
+
+// NewPreferredAddressPath creates a path to the server's preferred address.
+// It uses the connection's current socket.
+func (pm *pathManagerOutgoing) NewPreferredAddressPath(remoteAddr net.Addr, initialRTT time.Duration) *Path {
+	return pm.newPath(nil, remoteAddr, initialRTT, func() {})
+}
+
+func (pm *pathManagerOutgoing) newPath(t *Transport, remoteAddr net.Addr, initialRTT time.Duration, enablePath func()) *Path {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
 
@@ -225,13 +273,17 @@ func (pm *pathManagerOutgoing) NewPath(t *Transport, initialRTT time.Duration, e
 		pathManager: pm,
 		id:          id,
 		tr:          t,
+		remoteAddr:  remoteAddr,
 		enablePath:  enablePath,
 		initialRTT:  initialRTT,
 		abandon:     make(chan struct{}),
 	}
 }
 
-func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ackhandler.Frame, _ *Transport, hasPath bool) {
+// NextPathToProbe returns the next path that should be probed.
+// A nil Transport means that the probe is sent using the connection's current socket,
+// and a nil remote address means that it is sent to the connection's current remote address.
+func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ackhandler.Frame, _ *Transport, remoteAddr net.Addr, hasPath bool) {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
 
@@ -248,17 +300,21 @@ func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ack
 		pm.pathsToProbe = pm.pathsToProbe[1:]
 	}
 	if id == invalidPathID {
-		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, false
+		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, nil, false
 	}
 
 	connID, ok := pm.getConnID(id)
 	if !ok {
-		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, false
+		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, nil, false
 	}
 
 	var b [8]byte
 	_, _ = rand.Read(b[:])
-	p.pathChallenges = append(p.pathChallenges, b)
+	p.pathChallenges = append(p.pathChallenges, pathChallenge{data: b, sentTime: monotime.Now()})
+	p.numChallengesSent++
+	if p.numChallengesSent > 1 && pm.onChallengeRetransmitted != nil {
+		pm.onChallengeRetransmitted(p.tr, p.remoteAddr, p.numChallengesSent-1)
+	}
 
 	pm.pathsToProbe = pm.pathsToProbe[1:]
 	p.enablePath()
@@ -270,7 +326,7 @@ func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ack
 		Frame:   &wire.PathChallengeFrame{Data: b},
 		Handler: (*pathManagerOutgoingAckHandler)(pm),
 	}
-	return connID, frame, p.tr, true
+	return connID, frame, p.tr, p.remoteAddr, true
 }
 
 func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame) {
@@ -278,7 +334,9 @@ func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame
 	defer pm.mx.Unlock()
 
 	for _, p := range pm.paths {
-		if slices.Contains(p.pathChallenges, f.Data) {
+		if i := slices.IndexFunc(p.pathChallenges, func(c pathChallenge) bool { return c.data == f.Data }); i != -1 {
+			p.rttStats.UpdateRTT(monotime.Since(p.pathChallenges[i].sentTime), 0)
+			p.pathChallenges = slices.Delete(p.pathChallenges, i, i+1)
 			// path validated
 			if !p.isValidated {
 				// make sure that duplicate PATH_RESPONSE frames are ignored
@@ -291,16 +349,46 @@ func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame
 	}
 }
 
-func (pm *pathManagerOutgoing) ShouldSwitchPath() (*Transport, bool) {
+// ShouldSwitchPath says if the connection should switch to a different path.
+// See NextPathToProbe for the meaning of a nil Transport and a nil remote address.
+func (pm *pathManagerOutgoing) ShouldSwitchPath() (_ *Transport, remoteAddr net.Addr, _ bool) {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
 
 	if pm.pathToSwitchTo == nil {
-		return nil, false
+		return nil, nil, false
 	}
 	p := pm.pathToSwitchTo
 	pm.pathToSwitchTo = nil
-	return p.tr, true
+	return p.tr, p.remoteAddr, true
+}
+
+// AppendPathStats appends the statistics of all paths except for the active path.
+// localAddr and remoteAddr are the addresses currently used by the connection.
+func (pm *pathManagerOutgoing) AppendPathStats(stats []PathStats, localAddr, remoteAddr net.Addr) []PathStats {
+	pm.mx.Lock()
+	defer pm.mx.Unlock()
+
+	for _, id := range slices.Sorted(maps.Keys(pm.paths)) {
+		if id == pm.activePath {
+			continue
+		}
+		p := pm.paths[id]
+		s := PathStats{LocalAddr: localAddr, RemoteAddr: remoteAddr, Validated: p.isValidated}
+		if p.tr != nil {
+			s.LocalAddr = p.tr.Conn.LocalAddr()
+		}
+		if p.remoteAddr != nil {
+			s.RemoteAddr = p.remoteAddr
+		}
+		if p.rttStats.HasMeasurement() {
+			s.MinRTT = p.rttStats.MinRTT()
+			s.LatestRTT = p.rttStats.LatestRTT()
+			s.SmoothedRTT = p.rttStats.SmoothedRTT()
+		}
+		stats = append(stats, s)
+	}
+	return stats
 }
 
 type pathManagerOutgoingAckHandler pathManagerOutgoing
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"net"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -25,9 +26,11 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 			},
 			func(id pathID) { t.Fatal("didn't expect any connection ID to be retired") },
 			func() {},
+			0,
+			nil,
 		)
 
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		tr1 := &Transport{}
@@ -42,15 +45,16 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		synctest.Wait()
 
 		require.False(t, enabled)
-		connID, f, tr, ok := pm.NextPathToProbe()
+		connID, f, tr, remoteAddr, ok := pm.NextPathToProbe()
 		require.True(t, ok)
 		require.Equal(t, tr1, tr)
+		require.Nil(t, remoteAddr)
 		require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), connID)
 		require.IsType(t, &wire.PathChallengeFrame{}, f.Frame)
 		pc := f.Frame.(*wire.PathChallengeFrame)
 		require.True(t, enabled)
 
-		_, _, _, ok = pm.NextPathToProbe()
+		_, _, _, _, ok = pm.NextPathToProbe()
 		require.False(t, ok)
 
 		select {
@@ -68,7 +72,7 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		}
 
 		require.ErrorIs(t, p.Switch(), ErrPathNotValidated)
-		_, ok = pm.ShouldSwitchPath()
+		_, _, ok = pm.ShouldSwitchPath()
 		require.False(t, ok)
 
 		// ... neither does receiving a random PATH_RESPONSE...
@@ -97,14 +101,15 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc.Data})
 
 		// now switch to the other path
-		_, ok = pm.ShouldSwitchPath()
+		_, _, ok = pm.ShouldSwitchPath()
 		require.False(t, ok)
 		require.NoError(t, p.Switch())
 		// the active path can't be closed
 		require.EqualError(t, p.Close(), "cannot close active path")
-		switchToTransport, ok := pm.ShouldSwitchPath()
+		switchToTransport, switchToAddr, ok := pm.ShouldSwitchPath()
 		require.True(t, ok)
 		require.Equal(t, tr1, switchToTransport)
+		require.Nil(t, switchToAddr)
 	})
 }
 
@@ -120,9 +125,11 @@ func TestPathManagerOutgoingRetransmissions(t *testing.T) {
 			func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
 			func(id pathID) { retiredConnIDs = append(retiredConnIDs, connIDs[id]) },
 			func() { scheduledSending <- struct{}{} },
+			0,
+			nil,
 		)
 
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		tr1 := &Transport{}
@@ -139,7 +146,7 @@ func TestPathManagerOutgoingRetransmissions(t *testing.T) {
 				case <-done:
 					return
 				}
-				_, f, _, ok := pm.NextPathToProbe()
+				_, f, _, _, ok := pm.NextPathToProbe()
 				if !ok {
 					// should never happen
 					pathChallengeChan <- [8]byte{}
@@ -225,6 +232,115 @@ func TestPathManagerOutgoingRetransmissions(t *testing.T) {
 	})
 }
 
+func TestPathManagerOutgoingMaxRetransmissions(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const maxRetransmissions = 3
+		scheduledSending := make(chan struct{}, 20)
+		var retransmissions []int
+		pm := newPathManagerOutgoing(
+			func(id pathID) (protocol.ConnectionID, bool) {
+				return protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), true
+			},
+			func(id pathID) {},
+			func() { scheduledSending <- struct{}{} },
+			maxRetransmissions,
+			func(_ *Transport, _ net.Addr, count int) { retransmissions = append(retransmissions, count) },
+		)
+
+		const initialRTT = 10 * time.Millisecond
+		p := pm.NewPath(&Transport{}, initialRTT, func() {})
+
+		errChan := make(chan error, 1)
+		start := time.Now()
+		go func() { errChan <- p.Probe(context.Background()) }()
+
+		// the peer never responds
+		var numSent int
+	loop:
+		for {
+			select {
+			case <-scheduledSending:
+				_, _, _, _, ok := pm.NextPathToProbe()
+				require.True(t, ok)
+				numSent++
+			case err := <-errChan:
+				require.ErrorIs(t, err, ErrPathValidationFailed)
+				break loop
+			}
+		}
+		require.Equal(t, 1+maxRetransmissions, numSent)
+		require.Equal(t, []int{1, 2, 3}, retransmissions)
+		// the last PATH_CHALLENGE is given the same (backed-off) timeout as the previous ones
+		require.Equal(t, initialRTT*(1+2+4+8), time.Since(start))
+		require.ErrorIs(t, p.Switch(), ErrPathNotValidated)
+
+		// probing the path again resets the retransmission count
+		retransmissions = retransmissions[:0]
+		go func() { errChan <- p.Probe(context.Background()) }()
+		synctest.Wait()
+		<-scheduledSending
+		_, f, _, _, ok := pm.NextPathToProbe()
+		require.True(t, ok)
+		time.Sleep(initialRTT)
+		<-scheduledSending
+		_, _, _, _, ok = pm.NextPathToProbe()
+		require.True(t, ok)
+		require.Equal(t, []int{1}, retransmissions)
+		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: f.Frame.(*wire.PathChallengeFrame).Data})
+		synctest.Wait()
+		require.NoError(t, <-errChan)
+	})
+}
+
+func TestPathManagerOutgoingCoalesceProbes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		scheduledSending := make(chan struct{}, 20)
+		pm := newPathManagerOutgoing(
+			func(id pathID) (protocol.ConnectionID, bool) {
+				return protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), true
+			},
+			func(id pathID) {},
+			func() { scheduledSending <- struct{}{} },
+			0,
+			nil,
+		)
+
+		const initialRTT = 10 * time.Millisecond
+		p := pm.NewPath(&Transport{}, initialRTT, func() {})
+
+		// the peer never responds
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() { errChan <- p.Probe(ctx) }()
+		synctest.Wait()
+		// the path is already queued for probing
+		pm.enqueueProbe(p)
+
+		start := time.Now()
+		var sendTimes []time.Duration
+		var pathChallenges [][8]byte
+		for time.Since(start) < initialRTT*(1+2+4) {
+			<-scheduledSending
+			for {
+				_, f, _, _, ok := pm.NextPathToProbe()
+				if !ok {
+					break
+				}
+				sendTimes = append(sendTimes, time.Since(start))
+				pathChallenges = append(pathChallenges, f.Frame.(*wire.PathChallengeFrame).Data)
+			}
+		}
+		// exactly one PATH_CHALLENGE is sent per probe timeout
+		require.Equal(t, []time.Duration{0, initialRTT, initialRTT * (1 + 2), initialRTT * (1 + 2 + 4)}, sendTimes)
+		for i := 1; i < len(pathChallenges); i++ {
+			require.NotEqual(t, pathChallenges[i-1], pathChallenges[i])
+		}
+
+		cancel()
+		require.ErrorIs(t, <-errChan, context.Canceled)
+	})
+}
+
 func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		connIDs := []protocol.ConnectionID{
@@ -239,6 +355,8 @@ func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 			},
 			func(id pathID) { retiredPaths = append(retiredPaths, id) },
 			func() {},
+			0,
+			nil,
 		)
 
 		// path abandoned before the PATH_CHALLENGE is sent out
@@ -253,7 +371,7 @@ func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 		// closing the path multiple times is ok
 		require.NoError(t, p1.Close())
 		require.NoError(t, p1.Close())
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		synctest.Wait()
@@ -272,16 +390,74 @@ func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 		// wait for the path to be queued for probing
 		synctest.Wait()
 
-		connID, f, _, ok := pm.NextPathToProbe()
+		connID, f, _, _, ok := pm.NextPathToProbe()
 		require.True(t, ok)
 		require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), connID)
 
 		require.NoError(t, p2.Close())
 		require.Equal(t, []pathID{p2.id}, retiredPaths)
 		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: f.Frame.(*wire.PathChallengeFrame).Data})
-		_, _, _, ok = pm.NextPathToProbe()
+		_, _, _, _, ok = pm.NextPathToProbe()
 		require.False(t, ok)
 		// it's not possible to switch to an abandoned path
 		require.ErrorIs(t, p2.Switch(), ErrPathClosed)
 	})
 }
+
+func TestPathManagerOutgoingPreferredAddress(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pm := newPathManagerOutgoing(
+			func(id pathID) (protocol.ConnectionID, bool) {
+				return protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), true
+			},
+			func(id pathID) { t.Fatal("didn't expect any connection ID to be retired") },
+			func() {},
+			0,
+			nil,
+		)
+
+		addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443}
+		p := pm.NewPreferredAddressPath(addr, time.Second)
+		errChan := make(chan error, 1)
+		go func() { errChan <- p.Probe(context.Background()) }()
+
+		// wait for the path to be queued for probing
+		synctest.Wait()
+
+		// the probe is sent on the current socket, to the preferred address
+		_, f, tr, remoteAddr, ok := pm.NextPathToProbe()
+		require.True(t, ok)
+		require.Nil(t, tr)
+		require.Equal(t, addr, remoteAddr)
+
+		localAddr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		currentRemoteAddr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443}
+		require.Equal(t,
+			[]PathStats{{LocalAddr: localAddr, RemoteAddr: addr}},
+			pm.AppendPathStats(nil, localAddr, currentRemoteAddr),
+		)
+
+		// the RTT is measured from sending the PATH_CHALLENGE to receiving the PATH_RESPONSE
+		time.Sleep(50 * time.Millisecond)
+		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: f.Frame.(*wire.PathChallengeFrame).Data})
+		synctest.Wait()
+		require.NoError(t, <-errChan)
+		require.Equal(t,
+			[]PathStats{{
+				LocalAddr:   localAddr,
+				RemoteAddr:  addr,
+				Validated:   true,
+				MinRTT:      50 * time.Millisecond,
+				LatestRTT:   50 * time.Millisecond,
+				SmoothedRTT: 50 * time.Millisecond,
+			}},
+			pm.AppendPathStats(nil, localAddr, currentRemoteAddr),
+		)
+
+		require.NoError(t, p.Switch())
+		tr, remoteAddr, ok = pm.ShouldSwitchPath()
+		require.True(t, ok)
+		require.Nil(t, tr)
+		require.Equal(t, addr, remoteAddr)
+	})
+}
@@ -28,12 +28,15 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	pm := newPathManager(
 		func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
 		func(id pathID) { retiredConnIDs = append(retiredConnIDs, connIDs[id]) },
+		false,
+		&utils.ConnectionStats{},
 		utils.DefaultLogger,
 	)
 	now := monotime.Now()
-	connID, frames, shouldSwitch := pm.HandlePacket(
+	connID, frames, _, shouldSwitch := pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
 		false,
 	)
@@ -48,9 +51,10 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// receiving another packet for the same path doesn't trigger another PATH_CHALLENGE
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		nil,
 		false,
 	)
@@ -60,7 +64,7 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 
 	// receiving a packet for a different path triggers another PATH_CHALLENGE
 	addr2 := &net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000}
-	connID, frames, shouldSwitch = pm.HandlePacket(addr2, now, nil, false)
+	connID, frames, _, shouldSwitch = pm.HandlePacket(addr2, now, 1200, nil, false)
 	require.Equal(t, connIDs[1], connID)
 	require.Len(t, frames, 1)
 	require.IsType(t, &wire.PathChallengeFrame{}, frames[0].Frame)
@@ -72,9 +76,10 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	for _, f := range frames {
 		f.Handler.OnAcked(f.Frame)
 	}
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		nil,
 		false,
 	)
@@ -83,17 +88,18 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// receiving a PATH_RESPONSE for the second path confirms the path
-	pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc2.Data})
-	connID, frames, shouldSwitch = pm.HandlePacket(addr2, now, nil, false)
+	pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc2.Data}, addr2)
+	connID, frames, _, shouldSwitch = pm.HandlePacket(addr2, now, 1200, nil, false)
 	require.Zero(t, connID)
 	require.Empty(t, frames)
 	require.False(t, shouldSwitch) // no non-probing packet received yet
 	require.Empty(t, retiredConnIDs)
 
 	// confirming the path doesn't remove other paths
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		nil,
 		false,
 	)
@@ -102,9 +108,10 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// now receive a non-probing packet for the new path
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000},
 		now,
+		1200,
 		nil,
 		true,
 	)
@@ -113,10 +120,10 @@ func TestPathManagerIntentionalMigration(t *testing.T) {
 	require.True(t, shouldSwitch)
 
 	// now switch to the new path
-	pm.SwitchToPath(&net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000})
+	pm.SwitchToPath(&net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000}, now)
 
 	// switching to the path removes other paths
-	connID, frames, shouldSwitch = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, nil, false)
+	connID, frames, _, shouldSwitch = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, 1200, nil, false)
 	require.Equal(t, connIDs[2], connID)
 	require.NotEmpty(t, frames)
 	require.NotEqual(t, frames[0].Frame.(*wire.PathChallengeFrame).Data, pc1.Data)
@@ -131,13 +138,16 @@ func TestPathManagerMultipleProbes(t *testing.T) {
 	pm := newPathManager(
 		func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
 		func(id pathID) {},
+		false,
+		&utils.ConnectionStats{},
 		utils.DefaultLogger,
 	)
 	now := monotime.Now()
 	// first receive a packet without a PATH_CHALLENGE
-	connID, frames, shouldSwitch := pm.HandlePacket(
+	connID, frames, _, shouldSwitch := pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		nil,
 		false,
 	)
@@ -147,9 +157,10 @@ func TestPathManagerMultipleProbes(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// now receive a packet on the same path with a PATH_CHALLENGE
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
 		false,
 	)
@@ -159,9 +170,10 @@ func TestPathManagerMultipleProbes(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// now receive another packet on the same path with a PATH_RESPONSE
-	connID, frames, shouldSwitch = pm.HandlePacket(
+	connID, frames, _, shouldSwitch = pm.HandlePacket(
 		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
 		now,
+		1200,
 		&wire.PathChallengeFrame{Data: [8]byte{8, 7, 6, 5, 4, 3, 2, 1}},
 		false,
 	)
@@ -185,11 +197,13 @@ func TestPathManagerNATRebinding(t *testing.T) {
 	pm := newPathManager(
 		func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
 		func(id pathID) { retiredConnIDs = append(retiredConnIDs, connIDs[id]) },
+		false,
+		&utils.ConnectionStats{},
 		utils.DefaultLogger,
 	)
 
 	now := monotime.Now()
-	connID, frames, shouldSwitch := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, nil, true)
+	connID, frames, _, shouldSwitch := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, 1200, nil, true)
 	require.Equal(t, connIDs[0], connID)
 	require.Len(t, frames, 1)
 	require.IsType(t, &wire.PathChallengeFrame{}, frames[0].Frame)
@@ -198,14 +212,57 @@ func TestPathManagerNATRebinding(t *testing.T) {
 	require.False(t, shouldSwitch)
 
 	// receiving a PATH_RESPONSE for the second path confirms the path
-	pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc1.Data})
+	pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc1.Data}, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000})
 	// we now switch to the new path, as soon as the next packet on that path is received
-	connID, frames, shouldSwitch = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, nil, false)
+	connID, frames, _, shouldSwitch = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now, 1200, nil, false)
 	require.Zero(t, connID)
 	require.Empty(t, frames)
 	require.True(t, shouldSwitch)
 }
 
+func TestPathManagerPathResponseOnWrongPath(t *testing.T) {
+	t.Run("strict path validation", func(t *testing.T) {
+		testPathManagerPathResponseOnWrongPath(t, true)
+	})
+	t.Run("RFC 9000 path validation", func(t *testing.T) {
+		testPathManagerPathResponseOnWrongPath(t, false)
+	})
+}
+
+func testPathManagerPathResponseOnWrongPath(t *testing.T, strict bool) {
+	connIDs := []protocol.ConnectionID{
+		protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+	}
+	pm := newPathManager(
+		func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
+		func(id pathID) {},
+		strict,
+		&utils.ConnectionStats{},
+		utils.DefaultLogger,
+	)
+
+	now := monotime.Now()
+	addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}
+	_, frames, _, _ := pm.HandlePacket(addr, now, 1200, nil, true)
+	require.Len(t, frames, 1)
+	require.IsType(t, &wire.PathChallengeFrame{}, frames[0].Frame)
+	pc := frames[0].Frame.(*wire.PathChallengeFrame)
+
+	// a spoofed PATH_RESPONSE, received on a different path
+	spoofedAddr := &net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000}
+	require.Equal(t, addr, pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc.Data}, spoofedAddr))
+	_, _, _, shouldSwitch := pm.HandlePacket(addr, now, 1200, nil, false)
+	require.Equal(t, !strict, shouldSwitch)
+	if !strict {
+		return
+	}
+
+	// the PATH_RESPONSE received on the challenged path validates the path
+	require.Nil(t, pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc.Data}, addr))
+	_, _, _, shouldSwitch = pm.HandlePacket(addr, now, 1200, nil, false)
+	require.True(t, shouldSwitch)
+}
+
 func TestPathManagerLimits(t *testing.T) {
 	var connIDs []protocol.ConnectionID
 	for range 2*maxPaths + 2 {
@@ -217,6 +274,8 @@ func TestPathManagerLimits(t *testing.T) {
 	pm := newPathManager(
 		func(id pathID) (protocol.ConnectionID, bool) { return connIDs[id], true },
 		func(id pathID) { retiredConnIDs = append(retiredConnIDs, connIDs[id]) },
+		false,
+		&utils.ConnectionStats{},
 		utils.DefaultLogger,
 	)
 
@@ -225,7 +284,7 @@ func TestPathManagerLimits(t *testing.T) {
 	var firstPathConnID protocol.ConnectionID
 	require.Greater(t, pathTimeout, maxPaths*time.Second)
 	for i := range maxPaths {
-		connID, frames, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + i}, now, nil, true)
+		connID, frames, _, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + i}, now, 1200, nil, true)
 		require.NotEmpty(t, frames)
 		require.Equal(t, connIDs[i], connID)
 		if i == 0 {
@@ -235,14 +294,14 @@ func TestPathManagerLimits(t *testing.T) {
 	}
 	// the maximum number of paths is already being probed
 	now = firstPathTime.Add(pathTimeout).Add(-time.Nanosecond)
-	connID, frames, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 2000}, now, nil, true)
+	connID, frames, _, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(2, 0, 0, 0), Port: 2000}, now, 1200, nil, true)
 	require.Zero(t, connID)
 	require.Empty(t, frames)
 
 	// receiving another packet after the pathTimeout of the first path evicts the first path
 	now = firstPathTime.Add(pathTimeout)
 	connIDIndex := maxPaths
-	connID, frames, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + maxPaths}, now, nil, true)
+	connID, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + maxPaths}, now, 1200, nil, true)
 	require.NotEmpty(t, frames)
 	require.Equal(t, connIDs[connIDIndex], connID)
 	require.Equal(t, []protocol.ConnectionID{firstPathConnID}, retiredConnIDs)
@@ -250,9 +309,9 @@ func TestPathManagerLimits(t *testing.T) {
 
 	// switching to a new path frees is up all paths
 	var f1 []ackhandler.Frame
-	pm.SwitchToPath(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000})
+	pm.SwitchToPath(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}, now)
 	for i := range maxPaths {
-		connID, frames, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 3000 + i}, now, nil, true)
+		connID, frames, _, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(3, 0, 0, byte(i)), Port: 3000}, now, 1200, nil, true)
 		if i == 0 {
 			f1 = frames
 		}
@@ -261,7 +320,7 @@ func TestPathManagerLimits(t *testing.T) {
 		connIDIndex++
 	}
 	// again, the maximum number of paths is already being probed
-	connID, frames, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 2000}, now, nil, true)
+	connID, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(2, 0, 0, 0), Port: 2000}, now, 1200, nil, true)
 	require.Zero(t, connID)
 	require.Empty(t, frames)
 
@@ -269,14 +328,152 @@ func TestPathManagerLimits(t *testing.T) {
 	f1[0].Handler.OnLost(f1[0].Frame)
 
 	// we can open exactly one more path
-	connID, frames, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4000}, now, nil, true)
+	connID, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(4, 0, 0, 0), Port: 4000}, now, 1200, nil, true)
 	require.NotEmpty(t, frames)
 	require.Equal(t, connIDs[connIDIndex], connID)
-	connID, frames, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4001}, now, nil, true)
+	connID, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(4, 0, 0, 1), Port: 4001}, now, 1200, nil, true)
 	require.Zero(t, connID)
 	require.Empty(t, frames)
 }
 
+func TestPathManagerRateLimitPerAddress(t *testing.T) {
+	var connStats utils.ConnectionStats
+	var retiredConnIDs int
+	pm := newPathManager(
+		func(id pathID) (protocol.ConnectionID, bool) {
+			return protocol.ParseConnectionID([]byte{byte(id)}), true
+		},
+		func(id pathID) { retiredConnIDs++ },
+		false,
+		&connStats,
+		utils.DefaultLogger,
+	)
+
+	start := monotime.Now()
+	// an attacker sending packets from many different ports
+	var port int
+	for i := range maxPathValidationsPerAddr {
+		_, frames, _, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + port}, start, 1200, nil, true)
+		port++
+		require.NotEmpty(t, frames, "path validation %d", i)
+		// losing the PATH_CHALLENGE removes the path, so we don't run into the maxPaths limit
+		frames[0].Handler.OnLost(frames[0].Frame)
+	}
+	require.Equal(t, maxPathValidationsPerAddr, retiredConnIDs)
+	now := start.Add(pathValidationRateInterval - time.Nanosecond)
+	connID, frames, _, _ := pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + port}, now, 1200, nil, true)
+	require.Zero(t, connID)
+	require.Empty(t, frames)
+	require.Equal(t, uint64(1), connStats.PathValidationsDropped.Load())
+
+	// other IP addresses are not affected
+	_, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000}, now, 1200, nil, true)
+	require.NotEmpty(t, frames)
+
+	// after the rate limit interval, path validation is possible again
+	_, frames, _, _ = pm.HandlePacket(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000 + port}, start.Add(pathValidationRateInterval), 1200, nil, true)
+	require.NotEmpty(t, frames)
+	require.Equal(t, uint64(1), connStats.PathValidationsDropped.Load())
+}
+
+func TestPathManagerAmplificationLimit(t *testing.T) {
+	var connStats utils.ConnectionStats
+	pm := newPathManager(
+		func(id pathID) (protocol.ConnectionID, bool) {
+			return protocol.ParseConnectionID([]byte{byte(id)}), true
+		},
+		func(id pathID) {},
+		false,
+		&connStats,
+		utils.DefaultLogger,
+	)
+
+	now := monotime.Now()
+	addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000}
+	// a tiny packet doesn't allow sending a path probe packet
+	connID, frames, _, _ := pm.HandlePacket(addr, now, 10, nil, true)
+	require.Zero(t, connID)
+	require.Empty(t, frames)
+	require.Equal(t, uint64(1), connStats.PathProbesAmplificationLimited.Load())
+	// once enough data was received, an unpadded PATH_CHALLENGE is sent
+	connID, frames, probeSize, _ := pm.HandlePacket(addr, now, maxUnpaddedPathProbePacketSize/3, nil, true)
+	require.NotZero(t, connID)
+	require.Len(t, frames, 1)
+	require.IsType(t, &wire.PathChallengeFrame{}, frames[0].Frame)
+	require.Equal(t, 3*(10+maxUnpaddedPathProbePacketSize/3), int(probeSize))
+	pc := frames[0].Frame.(*wire.PathChallengeFrame)
+
+	// PATH_RESPONSEs are subject to the same limit
+	connID, frames, _, _ = pm.HandlePacket(addr, now, 10, &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, false)
+	require.Zero(t, connID)
+	require.Empty(t, frames)
+	require.Equal(t, uint64(2), connStats.PathProbesAmplificationLimited.Load())
+	// the probe packet is padded as far as the anti-amplification limit allows
+	connID, frames, probeSize, _ = pm.HandlePacket(addr, now, 1000, &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, false)
+	require.NotZero(t, connID)
+	require.Len(t, frames, 1)
+	require.Equal(t, protocol.MinInitialPacketSize, int(probeSize))
+
+	// once the path is validated, the limit doesn't apply anymore
+	pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc.Data}, addr)
+	connID, frames, probeSize, _ = pm.HandlePacket(addr, now, 10, &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, false)
+	require.NotZero(t, connID)
+	require.Len(t, frames, 1)
+	require.IsType(t, &wire.PathResponseFrame{}, frames[0].Frame)
+	require.Equal(t, protocol.MinInitialPacketSize, int(probeSize))
+	require.Equal(t, uint64(2), connStats.PathProbesAmplificationLimited.Load())
+}
+
+func TestPathManagerPathSwitchFlapping(t *testing.T) {
+	var connStats utils.ConnectionStats
+	pm := newPathManager(
+		func(id pathID) (protocol.ConnectionID, bool) {
+			return protocol.ParseConnectionID([]byte{byte(id)}), true
+		},
+		func(id pathID) {},
+		false,
+		&connStats,
+		utils.DefaultLogger,
+	)
+
+	validatePath := func(addr net.Addr, now monotime.Time) {
+		t.Helper()
+		_, frames, _, shouldSwitch := pm.HandlePacket(addr, now, 1200, nil, true)
+		require.False(t, shouldSwitch)
+		require.NotEmpty(t, frames)
+		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data}, addr)
+	}
+
+	start := monotime.Now()
+	now := start
+	addrs := []net.Addr{
+		&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1000},
+		&net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1000},
+	}
+	// the peer switches back and forth between two paths
+	for i := range maxPathSwitches {
+		addr := addrs[i%2]
+		validatePath(addr, now)
+		_, _, _, shouldSwitch := pm.HandlePacket(addr, now, 1200, nil, true)
+		require.True(t, shouldSwitch)
+		pm.SwitchToPath(addr, now)
+		now = now.Add(pathSwitchInterval / (2 * maxPathSwitches))
+	}
+	require.Zero(t, connStats.PathSwitchesDropped.Load())
+
+	// the next path switch would happen too quickly
+	addr := addrs[maxPathSwitches%2]
+	validatePath(addr, now)
+	_, _, _, shouldSwitch := pm.HandlePacket(addr, now, 1200, nil, true)
+	require.False(t, shouldSwitch)
+	require.Equal(t, uint64(1), connStats.PathSwitchesDropped.Load())
+
+	// once the interval has passed, the path switch is allowed
+	_, _, _, shouldSwitch = pm.HandlePacket(addr, start.Add(pathSwitchInterval), 1200, nil, true)
+	require.True(t, shouldSwitch)
+	require.Equal(t, uint64(1), connStats.PathSwitchesDropped.Load())
+}
+
 type mockAddr struct {
 	str string
 }
@@ -284,6 +481,83 @@ type mockAddr struct {
 func (a *mockAddr) Network() string { return "mock" }
 func (a *mockAddr) String() string  { return a.str }
 
+func TestIPsWithinTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		addr1     net.Addr
+		addr2     net.Addr
+		tolerance int
+		expected  bool
+	}{
+		{
+			name:     "same IPv4 address, different ports",
+			addr1:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321},
+			expected: true,
+		},
+		{
+			name:     "different last octet, strict",
+			addr1:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 5), Port: 1234},
+			expected: false,
+		},
+		{
+			name:      "different last octet, tolerant",
+			addr1:     &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:     &net.UDPAddr{IP: net.IPv4(1, 2, 3, 5), Port: 1234},
+			tolerance: 1,
+			expected:  true,
+		},
+		{
+			name:      "different third octet, tolerant",
+			addr1:     &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:     &net.UDPAddr{IP: net.IPv4(1, 2, 4, 4), Port: 1234},
+			tolerance: 1,
+			expected:  false,
+		},
+		{
+			name:     "IPv4 and IPv4-mapped IPv6 address",
+			addr1:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To4(), Port: 1234},
+			addr2:    &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To16(), Port: 1234},
+			expected: true,
+		},
+		{
+			name:      "IPv4 and IPv6 address",
+			addr1:     &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:     &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234},
+			tolerance: 4,
+			expected:  false,
+		},
+		{
+			name:      "different last byte of IPv6 address, tolerant",
+			addr1:     &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234},
+			addr2:     &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 1234},
+			tolerance: 1,
+			expected:  true,
+		},
+		{
+			name:      "tolerance larger than the address",
+			addr1:     &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+			addr2:     &net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 1234},
+			tolerance: 10,
+			expected:  true,
+		},
+		{
+			name:      "non-UDP addresses",
+			addr1:     &mockAddr{str: "192.0.2.1:1234"},
+			addr2:     &mockAddr{str: "192.0.2.2:1234"},
+			tolerance: 1,
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ipsWithinTolerance(tt.addr1, tt.addr2, tt.tolerance))
+		})
+	}
+}
+
 func TestAddrsEqual(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,113 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/monotime"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// maxPingRTTHistory is the number of PING round-trip times that are kept.
+const maxPingRTTHistory = 10
+
+// The pingTracker measures the round-trip time of PING frames sent by the application.
+// PING frames don't carry any payload. Instead, every PING frame is assigned a sequence number,
+// which is stored in the frame handler, and resolved when the packet containing the frame is acknowledged.
+type pingTracker struct {
+	mutex sync.Mutex
+
+	nextSeq     uint64
+	outstanding map[uint64]monotime.Time // sequence number -> send time
+
+	history    [maxPingRTTHistory]time.Duration // ring buffer
+	historyPos int
+	historyLen int
+}
+
+func newPingTracker() *pingTracker {
+	return &pingTracker{outstanding: make(map[uint64]monotime.Time)}
+}
+
+// NewPing registers a new PING frame, and returns the frame to send.
+func (t *pingTracker) NewPing(now monotime.Time) ackhandler.Frame {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	seq := t.nextSeq
+	t.nextSeq++
+	t.outstanding[seq] = now
+	return ackhandler.Frame{
+		Frame:   &wire.PingFrame{},
+		Handler: &pingTrackerAckHandler{pingTracker: t, seq: seq},
+	}
+}
+
+func (t *pingTracker) onAcked(seq uint64, now monotime.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	sendTime, ok := t.outstanding[seq]
+	if !ok {
+		return
+	}
+	delete(t.outstanding, seq)
+	t.history[t.historyPos] = now.Sub(sendTime)
+	t.historyPos = (t.historyPos + 1) % maxPingRTTHistory
+	t.historyLen = min(t.historyLen+1, maxPingRTTHistory)
+}
+
+func (t *pingTracker) onLost(seq uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.outstanding, seq)
+}
+
+// History returns the most recent round-trip times, oldest first.
+func (t *pingTracker) History() []time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.historyLen == 0 {
+		return nil
+	}
+	history := make([]time.Duration, 0, t.historyLen)
+	start := t.historyPos - t.historyLen
+	if start < 0 {
+		start += maxPingRTTHistory
+	}
+	for i := range t.historyLen {
+		history = append(history, t.history[(start+i)%maxPingRTTHistory])
+	}
+	return history
+}
+
+// Stats returns the minimum, maximum and average of the most recent round-trip times.
+func (t *pingTracker) Stats() (minRTT, maxRTT, avgRTT time.Duration) {
+	history := t.History()
+	if len(history) == 0 {
+		return 0, 0, 0
+	}
+	minRTT = history[0]
+	var sum time.Duration
+	for _, rtt := range history {
+		minRTT = min(minRTT, rtt)
+		maxRTT = max(maxRTT, rtt)
+		sum += rtt
+	}
+	return minRTT, maxRTT, sum / time.Duration(len(history))
+}
+
+type pingTrackerAckHandler struct {
+	*pingTracker
+	seq uint64
+}
+
+var _ ackhandler.FrameHandler = &pingTrackerAckHandler{}
+
+func (h *pingTrackerAckHandler) OnAcked(wire.Frame) { h.onAcked(h.seq, monotime.Now()) }
+
+// PING frames are not retransmitted. A lost PING frame doesn't produce an RTT measurement.
+func (h *pingTrackerAckHandler) OnLost(wire.Frame) { h.onLost(h.seq) }
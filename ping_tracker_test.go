@@ -0,0 +1,58 @@
+package quic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingTracker(t *testing.T) {
+	tracker := newPingTracker()
+	minRTT, maxRTT, avgRTT := tracker.Stats()
+	require.Zero(t, minRTT)
+	require.Zero(t, maxRTT)
+	require.Zero(t, avgRTT)
+	require.Empty(t, tracker.History())
+
+	now := monotime.Now()
+	ping1 := tracker.NewPing(now)
+	require.Equal(t, &wire.PingFrame{}, ping1.Frame)
+	ping2 := tracker.NewPing(now.Add(time.Millisecond))
+	ping3 := tracker.NewPing(now.Add(2 * time.Millisecond))
+
+	// PINGs are matched by their sequence number, not by the order they're acknowledged in
+	tracker.onAcked(ping2.Handler.(*pingTrackerAckHandler).seq, now.Add(31*time.Millisecond))
+	tracker.onAcked(ping1.Handler.(*pingTrackerAckHandler).seq, now.Add(10*time.Millisecond))
+	// lost PINGs don't produce a measurement
+	ping3.Handler.OnLost(ping3.Frame)
+	// duplicate acknowledgments are ignored
+	tracker.onAcked(ping1.Handler.(*pingTrackerAckHandler).seq, now.Add(100*time.Millisecond))
+
+	require.Equal(t, []time.Duration{30 * time.Millisecond, 10 * time.Millisecond}, tracker.History())
+	minRTT, maxRTT, avgRTT = tracker.Stats()
+	require.Equal(t, 10*time.Millisecond, minRTT)
+	require.Equal(t, 30*time.Millisecond, maxRTT)
+	require.Equal(t, 20*time.Millisecond, avgRTT)
+	require.Empty(t, tracker.outstanding)
+}
+
+func TestPingTrackerHistoryLimit(t *testing.T) {
+	tracker := newPingTracker()
+	now := monotime.Now()
+	for i := range 2*maxPingRTTHistory + 3 {
+		ping := tracker.NewPing(now)
+		tracker.onAcked(ping.Handler.(*pingTrackerAckHandler).seq, now.Add(time.Duration(i+1)*time.Millisecond))
+	}
+	history := tracker.History()
+	require.Len(t, history, maxPingRTTHistory)
+	for i, rtt := range history {
+		require.Equal(t, time.Duration(maxPingRTTHistory+4+i)*time.Millisecond, rtt)
+	}
+	minRTT, maxRTT, _ := tracker.Stats()
+	require.Equal(t, time.Duration(maxPingRTTHistory+4)*time.Millisecond, minRTT)
+	require.Equal(t, time.Duration(2*maxPingRTTHistory+3)*time.Millisecond, maxRTT)
+}
@@ -133,6 +133,9 @@ type ConnectionClosed struct {
 
 	ConnectionError  *TransportErrorCode
 	ApplicationError *ApplicationErrorCode
+	// ApplicationErrorSpace is the name of the error space that the application error code belongs to,
+	// see quic.ErrorSpaces.
+	ApplicationErrorSpace string
 
 	Reason string
 
@@ -195,7 +198,11 @@ func (e ConnectionClosed) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	}
 	if e.ApplicationError != nil {
 		h.WriteToken(jsontext.String("application_error"))
-		h.WriteToken(jsontext.String("unknown"))
+		if e.ApplicationErrorSpace != "" {
+			h.WriteToken(jsontext.String(e.ApplicationErrorSpace))
+		} else {
+			h.WriteToken(jsontext.String("unknown"))
+		}
 		h.WriteToken(jsontext.String("error_code"))
 		h.WriteToken(jsontext.Uint(uint64(*e.ApplicationError)))
 	}
@@ -519,6 +526,34 @@ func (e PacketLost) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	return h.err
 }
 
+// PacketsLost summarizes a single loss detection pass.
+// It is recorded in addition to the PacketLost events for the individual packets,
+// once for every trigger that caused packets to be declared lost.
+type PacketsLost struct {
+	EncryptionLevel protocol.EncryptionLevel
+	PacketNumbers   []PacketNumber
+	Trigger         PacketLossReason
+}
+
+func (e PacketsLost) Name() string { return "recovery:packets_lost" }
+
+func (e PacketsLost) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("packet_number_space"))
+	h.WriteToken(jsontext.String(encLevelToPacketNumberSpace(e.EncryptionLevel)))
+	h.WriteToken(jsontext.String("packet_numbers"))
+	h.WriteToken(jsontext.BeginArray)
+	for _, pn := range e.PacketNumbers {
+		h.WriteToken(jsontext.Uint(uint64(pn)))
+	}
+	h.WriteToken(jsontext.EndArray)
+	h.WriteToken(jsontext.String("trigger"))
+	h.WriteToken(jsontext.String(string(e.Trigger)))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
 type SpuriousLoss struct {
 	EncryptionLevel  protocol.EncryptionLevel
 	PacketNumber     protocol.PacketNumber
@@ -543,6 +578,53 @@ func (e SpuriousLoss) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	return h.err
 }
 
+// AckDelayReceived is recorded for every ACK frame received in the Application Data packet number space.
+type AckDelayReceived struct {
+	LargestAcked protocol.PacketNumber
+	// AckDelay is the ack delay reported by the peer,
+	// before it is limited to the peer's max_ack_delay for the RTT calculation.
+	AckDelay time.Duration
+}
+
+func (e AckDelayReceived) Name() string { return "recovery:ack_delay_received" }
+
+func (e AckDelayReceived) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("largest_acknowledged"))
+	h.WriteToken(jsontext.Uint(uint64(e.LargestAcked)))
+	h.WriteToken(jsontext.String("ack_delay"))
+	h.WriteToken(jsontext.Float(milliseconds(e.AckDelay)))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
+// AckDelaySent is recorded when an ACK frame acknowledging new ack-eliciting packets
+// is sent in the Application Data packet number space.
+type AckDelaySent struct {
+	LargestAcked protocol.PacketNumber
+	// AckDelay is the ack delay reported to the peer,
+	// i.e. the time since the largest acknowledged packet was received.
+	AckDelay time.Duration
+	// Latency is the time since the first ack-eliciting packet acknowledged by this ACK frame was received.
+	Latency time.Duration
+}
+
+func (e AckDelaySent) Name() string { return "recovery:ack_delay_sent" }
+
+func (e AckDelaySent) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("largest_acknowledged"))
+	h.WriteToken(jsontext.Uint(uint64(e.LargestAcked)))
+	h.WriteToken(jsontext.String("ack_delay"))
+	h.WriteToken(jsontext.Float(milliseconds(e.AckDelay)))
+	h.WriteToken(jsontext.String("latency"))
+	h.WriteToken(jsontext.Float(milliseconds(e.Latency)))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
 type KeyUpdated struct {
 	Trigger  KeyUpdateTrigger
 	KeyType  KeyType
@@ -791,6 +873,23 @@ func (e CongestionStateUpdated) Encode(enc *jsontext.Encoder, _ time.Time) error
 	return h.err
 }
 
+// CongestionLimitedUpdated is recorded when the sender starts or stops being limited by the congestion window.
+// It complements the application_limited congestion state.
+type CongestionLimitedUpdated struct {
+	Limited bool
+}
+
+func (e CongestionLimitedUpdated) Name() string { return "recovery:congestion_limited_updated" }
+
+func (e CongestionLimitedUpdated) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("limited"))
+	h.WriteToken(jsontext.Bool(e.Limited))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
 type ECNStateUpdated struct {
 	State   ECNState
 	Trigger string
@@ -826,6 +925,135 @@ func (e ALPNInformation) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	return h.err
 }
 
+// HandshakeSummary is recorded when the handshake completes.
+// It contains information about the address validation performed during the handshake.
+type HandshakeSummary struct {
+	UsedRetry    bool
+	UsedNewToken bool
+	// RetryLatency is the time the Retry added to the handshake (server only).
+	RetryLatency time.Duration
+	// RetriesReceived is the number of Retry packets received (client only).
+	RetriesReceived          int
+	OriginalDestConnectionID ConnectionID
+	RetrySrcConnectionID     ConnectionID
+}
+
+func (e HandshakeSummary) Name() string { return "transport:handshake_summary" }
+
+func (e HandshakeSummary) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("used_retry"))
+	h.WriteToken(jsontext.Bool(e.UsedRetry))
+	h.WriteToken(jsontext.String("used_new_token"))
+	h.WriteToken(jsontext.Bool(e.UsedNewToken))
+	if e.RetryLatency > 0 {
+		h.WriteToken(jsontext.String("retry_latency"))
+		h.WriteToken(jsontext.Float(milliseconds(e.RetryLatency)))
+	}
+	if e.RetriesReceived > 0 {
+		h.WriteToken(jsontext.String("retries_received"))
+		h.WriteToken(jsontext.Int(int64(e.RetriesReceived)))
+	}
+	if e.UsedRetry {
+		h.WriteToken(jsontext.String("original_destination_connection_id"))
+		h.WriteToken(jsontext.String(e.OriginalDestConnectionID.String()))
+		h.WriteToken(jsontext.String("retry_source_connection_id"))
+		h.WriteToken(jsontext.String(e.RetrySrcConnectionID.String()))
+	}
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
+// PathResponseMismatch is recorded when a PATH_RESPONSE frame is received on a different path
+// than the one the corresponding PATH_CHALLENGE frame was sent on.
+type PathResponseMismatch struct {
+	ChallengedPath PathEndpointInfo
+	ReceivedPath   PathEndpointInfo
+	// Validated says if the challenged path was validated nevertheless.
+	Validated bool
+}
+
+func (e PathResponseMismatch) Name() string { return "transport:path_response_mismatch" }
+
+func (e PathResponseMismatch) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("challenged_path"))
+	if err := e.ChallengedPath.encode(enc); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("received_path"))
+	if err := e.ReceivedPath.encode(enc); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("validated"))
+	h.WriteToken(jsontext.Bool(e.Validated))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
+// PathChallengeRetransmitted is recorded when a PATH_CHALLENGE frame is retransmitted
+// because no PATH_RESPONSE was received for the previous PATH_CHALLENGE frames sent on the path.
+type PathChallengeRetransmitted struct {
+	Local  PathEndpointInfo
+	Remote PathEndpointInfo
+	// Count is the number of retransmissions since probing of the path started.
+	Count int
+}
+
+func (e PathChallengeRetransmitted) Name() string { return "transport:path_challenge_retransmitted" }
+
+func (e PathChallengeRetransmitted) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("local"))
+	if err := e.Local.encode(enc); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("remote"))
+	if err := e.Remote.encode(enc); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("count"))
+	h.WriteToken(jsontext.Int(int64(e.Count)))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
+// ProtocolViolation is recorded when a frame received from the peer violates the protocol.
+// It is only recorded if strict mode is enabled, see Config.StrictMode.
+type ProtocolViolation struct {
+	ErrorCode TransportErrorCode
+	// FrameType is the type of the frame that caused the violation.
+	// It is 0 if the violation can't be attributed to a single frame.
+	FrameType uint64
+	Reason    string
+}
+
+func (e ProtocolViolation) Name() string { return "transport:protocol_violation" }
+
+func (e ProtocolViolation) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("error_code"))
+	if errName := transportError(e.ErrorCode).String(); len(errName) > 0 {
+		h.WriteToken(jsontext.String(errName))
+	} else {
+		h.WriteToken(jsontext.Uint(uint64(e.ErrorCode)))
+	}
+	h.WriteToken(jsontext.String("raw_error_code"))
+	h.WriteToken(jsontext.Uint(uint64(e.ErrorCode)))
+	if e.FrameType != 0 {
+		h.WriteToken(jsontext.String("frame_type"))
+		h.WriteToken(jsontext.Uint(e.FrameType))
+	}
+	h.WriteToken(jsontext.String("reason"))
+	h.WriteToken(jsontext.String(e.Reason))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
 // DebugEvent is a generic event that can be used to log arbitrary messages.
 type DebugEvent struct {
 	EventName string
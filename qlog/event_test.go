@@ -155,6 +155,21 @@ func TestApplicationErrors(t *testing.T) {
 	require.Equal(t, "foobar", ev["reason"])
 }
 
+func TestApplicationErrorsWithErrorSpace(t *testing.T) {
+	code := qerr.ApplicationErrorCode(0x5007)
+	name, ev := testEventEncoding(t, &ConnectionClosed{
+		Initiator:             InitiatorLocal,
+		ApplicationError:      &code,
+		ApplicationErrorSpace: "auth",
+		Reason:                "foobar",
+	})
+
+	require.Equal(t, "transport:connection_closed", name)
+	require.Len(t, ev, 4)
+	require.Equal(t, "auth", ev["application_error"])
+	require.Equal(t, float64(0x5007), ev["error_code"])
+}
+
 func TestTransportErrors(t *testing.T) {
 	tests := []struct {
 		code qerr.TransportErrorCode
@@ -676,6 +691,47 @@ func TestPacketLost(t *testing.T) {
 	require.Equal(t, "reordering_threshold", ev["trigger"])
 }
 
+func TestPacketsLost(t *testing.T) {
+	name, ev := testEventEncoding(t, &PacketsLost{
+		EncryptionLevel: protocol.Encryption1RTT,
+		PacketNumbers:   []protocol.PacketNumber{4, 5, 7},
+		Trigger:         PacketLossTimeThreshold,
+	})
+
+	require.Equal(t, "recovery:packets_lost", name)
+	require.Equal(t, "application_data", ev["packet_number_space"])
+	require.Equal(t, []any{float64(4), float64(5), float64(7)}, ev["packet_numbers"])
+	require.Equal(t, "time_threshold", ev["trigger"])
+}
+
+func TestHandshakeSummary(t *testing.T) {
+	name, ev := testEventEncoding(t, &HandshakeSummary{
+		UsedRetry:                true,
+		RetryLatency:             1337 * time.Millisecond,
+		OriginalDestConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		RetrySrcConnectionID:     protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad}),
+	})
+
+	require.Equal(t, "transport:handshake_summary", name)
+	require.Equal(t, true, ev["used_retry"])
+	require.Equal(t, false, ev["used_new_token"])
+	require.InDelta(t, 1337, ev["retry_latency"], float64(1))
+	require.NotContains(t, ev, "retries_received")
+	require.Equal(t, "deadbeef", ev["original_destination_connection_id"])
+	require.Equal(t, "decafbad", ev["retry_source_connection_id"])
+}
+
+func TestHandshakeSummaryWithoutRetry(t *testing.T) {
+	name, ev := testEventEncoding(t, &HandshakeSummary{UsedNewToken: true})
+
+	require.Equal(t, "transport:handshake_summary", name)
+	require.Equal(t, false, ev["used_retry"])
+	require.Equal(t, true, ev["used_new_token"])
+	require.NotContains(t, ev, "retry_latency")
+	require.NotContains(t, ev, "original_destination_connection_id")
+	require.NotContains(t, ev, "retry_source_connection_id")
+}
+
 func TestSpuriousLoss(t *testing.T) {
 	name, ev := testEventEncoding(t, &SpuriousLoss{
 		EncryptionLevel:  protocol.Encryption1RTT,
@@ -693,6 +749,32 @@ func TestSpuriousLoss(t *testing.T) {
 	require.InDelta(t, 1337, ev["reordering_time"], float64(1))
 }
 
+func TestAckDelayReceived(t *testing.T) {
+	name, ev := testEventEncoding(t, &AckDelayReceived{
+		LargestAcked: 42,
+		AckDelay:     1337 * time.Millisecond,
+	})
+
+	require.Equal(t, "recovery:ack_delay_received", name)
+	require.Len(t, ev, 2)
+	require.Equal(t, float64(42), ev["largest_acknowledged"])
+	require.InDelta(t, 1337, ev["ack_delay"], float64(1))
+}
+
+func TestAckDelaySent(t *testing.T) {
+	name, ev := testEventEncoding(t, &AckDelaySent{
+		LargestAcked: 42,
+		AckDelay:     5 * time.Millisecond,
+		Latency:      20 * time.Millisecond,
+	})
+
+	require.Equal(t, "recovery:ack_delay_sent", name)
+	require.Len(t, ev, 3)
+	require.Equal(t, float64(42), ev["largest_acknowledged"])
+	require.InDelta(t, 5, ev["ack_delay"], float64(1))
+	require.InDelta(t, 20, ev["latency"], float64(1))
+}
+
 func TestMTUUpdated(t *testing.T) {
 	name, ev := testEventEncoding(t, &MTUUpdated{
 		Value: 1337,
@@ -713,6 +795,13 @@ func TestCongestionStateUpdated(t *testing.T) {
 	require.Equal(t, "congestion_avoidance", ev["new"])
 }
 
+func TestCongestionLimitedUpdated(t *testing.T) {
+	name, ev := testEventEncoding(t, &CongestionLimitedUpdated{Limited: true})
+
+	require.Equal(t, "recovery:congestion_limited_updated", name)
+	require.Equal(t, true, ev["limited"])
+}
+
 func TestPTOCountUpdated(t *testing.T) {
 	name, ev := testEventEncoding(t, &PTOCountUpdated{PTOCount: 42})
 
@@ -878,6 +967,62 @@ func TestALPNInformation(t *testing.T) {
 	require.Equal(t, "h3", ev["chosen_alpn"])
 }
 
+func TestPathResponseMismatch(t *testing.T) {
+	name, ev := testEventEncoding(t, &PathResponseMismatch{
+		ChallengedPath: PathEndpointInfo{IPv4: netip.AddrPortFrom(netip.AddrFrom4([4]byte{1, 2, 3, 4}), 1234)},
+		ReceivedPath:   PathEndpointInfo{IPv4: netip.AddrPortFrom(netip.AddrFrom4([4]byte{5, 6, 7, 8}), 5678)},
+	})
+
+	require.Equal(t, "transport:path_response_mismatch", name)
+	require.Len(t, ev, 3)
+	require.Equal(t, "1.2.3.4", ev["challenged_path"].(map[string]any)["ip_v4"])
+	require.Equal(t, float64(1234), ev["challenged_path"].(map[string]any)["port_v4"])
+	require.Equal(t, "5.6.7.8", ev["received_path"].(map[string]any)["ip_v4"])
+	require.Equal(t, float64(5678), ev["received_path"].(map[string]any)["port_v4"])
+	require.Equal(t, false, ev["validated"])
+}
+
+func TestPathChallengeRetransmitted(t *testing.T) {
+	name, ev := testEventEncoding(t, &PathChallengeRetransmitted{
+		Local:  PathEndpointInfo{IPv4: netip.AddrPortFrom(netip.AddrFrom4([4]byte{1, 2, 3, 4}), 1234)},
+		Remote: PathEndpointInfo{IPv6: netip.AddrPortFrom(netip.MustParseAddr("2001:db8::1"), 443)},
+		Count:  3,
+	})
+
+	require.Equal(t, "transport:path_challenge_retransmitted", name)
+	require.Len(t, ev, 3)
+	require.Equal(t, "1.2.3.4", ev["local"].(map[string]any)["ip_v4"])
+	require.Equal(t, float64(1234), ev["local"].(map[string]any)["port_v4"])
+	require.Equal(t, "2001:db8::1", ev["remote"].(map[string]any)["ip_v6"])
+	require.Equal(t, float64(443), ev["remote"].(map[string]any)["port_v6"])
+	require.Equal(t, float64(3), ev["count"])
+}
+
+func TestProtocolViolation(t *testing.T) {
+	t.Run("known error code", func(t *testing.T) {
+		name, ev := testEventEncoding(t, &ProtocolViolation{
+			ErrorCode: qerr.ProtocolViolation,
+			FrameType: 0x18,
+			Reason:    "frame type not minimally encoded",
+		})
+		require.Equal(t, "transport:protocol_violation", name)
+		require.Len(t, ev, 4)
+		require.Equal(t, "protocol_violation", ev["error_code"])
+		require.Equal(t, float64(qerr.ProtocolViolation), ev["raw_error_code"])
+		require.Equal(t, float64(0x18), ev["frame_type"])
+		require.Equal(t, "frame type not minimally encoded", ev["reason"])
+	})
+
+	t.Run("unknown error code, no frame type", func(t *testing.T) {
+		name, ev := testEventEncoding(t, &ProtocolViolation{ErrorCode: 0x1337})
+		require.Equal(t, "transport:protocol_violation", name)
+		require.Len(t, ev, 3)
+		require.Equal(t, float64(0x1337), ev["error_code"])
+		require.Equal(t, float64(0x1337), ev["raw_error_code"])
+		require.Equal(t, "", ev["reason"])
+	})
+}
+
 func TestDebugEvent(t *testing.T) {
 	t.Run("default name", func(t *testing.T) {
 		name, ev := testEventEncoding(t, &DebugEvent{Message: "hello world"})
@@ -9,6 +9,9 @@ import (
 
 type Frame struct {
 	Frame any
+	// ErrorCodeSpace is the name of the error space that the error code of a
+	// RESET_STREAM, STOP_SENDING or CONNECTION_CLOSE frame belongs to, see quic.ErrorSpaces.
+	ErrorCodeSpace string
 }
 
 type frames []Frame
@@ -94,9 +97,9 @@ func (f Frame) Encode(enc *jsontext.Encoder) error {
 	case *AckFrame:
 		return encodeAckFrame(enc, frame)
 	case *ResetStreamFrame:
-		return encodeResetStreamFrame(enc, frame)
+		return encodeResetStreamFrame(enc, frame, f.ErrorCodeSpace)
 	case *StopSendingFrame:
-		return encodeStopSendingFrame(enc, frame)
+		return encodeStopSendingFrame(enc, frame, f.ErrorCodeSpace)
 	case *CryptoFrame:
 		return encodeCryptoFrame(enc, frame)
 	case *NewTokenFrame:
@@ -124,7 +127,7 @@ func (f Frame) Encode(enc *jsontext.Encoder) error {
 	case *PathResponseFrame:
 		return encodePathResponseFrame(enc, frame)
 	case *ConnectionCloseFrame:
-		return encodeConnectionCloseFrame(enc, frame)
+		return encodeConnectionCloseFrame(enc, frame, f.ErrorCodeSpace)
 	case *HandshakeDoneFrame:
 		return encodeHandshakeDoneFrame(enc, frame)
 	case *DatagramFrame:
@@ -200,7 +203,7 @@ func encodeAckFrame(enc *jsontext.Encoder, f *AckFrame) error {
 	return h.err
 }
 
-func encodeResetStreamFrame(enc *jsontext.Encoder, f *ResetStreamFrame) error {
+func encodeResetStreamFrame(enc *jsontext.Encoder, f *ResetStreamFrame, errorCodeSpace string) error {
 	h := encoderHelper{enc: enc}
 	h.WriteToken(jsontext.BeginObject)
 	h.WriteToken(jsontext.String("frame_type"))
@@ -213,6 +216,10 @@ func encodeResetStreamFrame(enc *jsontext.Encoder, f *ResetStreamFrame) error {
 	h.WriteToken(jsontext.Int(int64(f.StreamID)))
 	h.WriteToken(jsontext.String("error_code"))
 	h.WriteToken(jsontext.Int(int64(f.ErrorCode)))
+	if errorCodeSpace != "" {
+		h.WriteToken(jsontext.String("error_code_space"))
+		h.WriteToken(jsontext.String(errorCodeSpace))
+	}
 	h.WriteToken(jsontext.String("final_size"))
 	h.WriteToken(jsontext.Int(int64(f.FinalSize)))
 	if f.ReliableSize > 0 {
@@ -223,7 +230,7 @@ func encodeResetStreamFrame(enc *jsontext.Encoder, f *ResetStreamFrame) error {
 	return h.err
 }
 
-func encodeStopSendingFrame(enc *jsontext.Encoder, f *StopSendingFrame) error {
+func encodeStopSendingFrame(enc *jsontext.Encoder, f *StopSendingFrame, errorCodeSpace string) error {
 	h := encoderHelper{enc: enc}
 	h.WriteToken(jsontext.BeginObject)
 	h.WriteToken(jsontext.String("frame_type"))
@@ -232,6 +239,10 @@ func encodeStopSendingFrame(enc *jsontext.Encoder, f *StopSendingFrame) error {
 	h.WriteToken(jsontext.Int(int64(f.StreamID)))
 	h.WriteToken(jsontext.String("error_code"))
 	h.WriteToken(jsontext.Int(int64(f.ErrorCode)))
+	if errorCodeSpace != "" {
+		h.WriteToken(jsontext.String("error_code_space"))
+		h.WriteToken(jsontext.String(errorCodeSpace))
+	}
 	h.WriteToken(jsontext.EndObject)
 	return h.err
 }
@@ -407,7 +418,7 @@ func encodePathResponseFrame(enc *jsontext.Encoder, f *PathResponseFrame) error
 	return h.err
 }
 
-func encodeConnectionCloseFrame(enc *jsontext.Encoder, f *ConnectionCloseFrame) error {
+func encodeConnectionCloseFrame(enc *jsontext.Encoder, f *ConnectionCloseFrame, errorCodeSpace string) error {
 	h := encoderHelper{enc: enc}
 	h.WriteToken(jsontext.BeginObject)
 	h.WriteToken(jsontext.String("frame_type"))
@@ -428,6 +439,10 @@ func encodeConnectionCloseFrame(enc *jsontext.Encoder, f *ConnectionCloseFrame)
 	}
 	h.WriteToken(jsontext.String("raw_error_code"))
 	h.WriteToken(jsontext.Uint(f.ErrorCode))
+	if errorCodeSpace != "" {
+		h.WriteToken(jsontext.String("error_code_space"))
+		h.WriteToken(jsontext.String(errorCodeSpace))
+	}
 	h.WriteToken(jsontext.String("reason"))
 	h.WriteToken(jsontext.String(f.ReasonPhrase))
 	h.WriteToken(jsontext.EndObject)
@@ -14,9 +14,13 @@ import (
 )
 
 func check(t *testing.T, f any, expected map[string]any) {
+	checkFrame(t, Frame{Frame: f}, expected)
+}
+
+func checkFrame(t *testing.T, f Frame, expected map[string]any) {
 	var buf bytes.Buffer
 	enc := jsontext.NewEncoder(&buf)
-	require.NoError(t, (Frame{Frame: f}).Encode(enc))
+	require.NoError(t, f.Encode(enc))
 	data := buf.Bytes()
 	require.True(t, json.Valid(data))
 	checkEncoding(t, data, expected)
@@ -131,6 +135,42 @@ func TestResetStreamAtFrame(t *testing.T) {
 	)
 }
 
+func TestFramesWithErrorCodeSpace(t *testing.T) {
+	checkFrame(t,
+		Frame{Frame: &ResetStreamFrame{StreamID: 987, FinalSize: 1234, ErrorCode: 0x3001}, ErrorCodeSpace: "storage"},
+		map[string]any{
+			"frame_type":       "reset_stream",
+			"stream_id":        987,
+			"error_code":       0x3001,
+			"error_code_space": "storage",
+			"final_size":       1234,
+		},
+	)
+	checkFrame(t,
+		Frame{Frame: &StopSendingFrame{StreamID: 987, ErrorCode: 0x3002}, ErrorCodeSpace: "storage"},
+		map[string]any{
+			"frame_type":       "stop_sending",
+			"stream_id":        987,
+			"error_code":       0x3002,
+			"error_code_space": "storage",
+		},
+	)
+	checkFrame(t,
+		Frame{
+			Frame:          &ConnectionCloseFrame{IsApplicationError: true, ErrorCode: 0x4001, ReasonPhrase: "lorem ipsum"},
+			ErrorCodeSpace: "storage",
+		},
+		map[string]any{
+			"frame_type":       "connection_close",
+			"error_space":      "application",
+			"error_code":       0x4001,
+			"raw_error_code":   0x4001,
+			"error_code_space": "storage",
+			"reason":           "lorem ipsum",
+		},
+	)
+}
+
 func TestAckFrequencyFrame(t *testing.T) {
 	check(t,
 		&AckFrequencyFrame{
@@ -0,0 +1,74 @@
+package qlogwriter
+
+import (
+	"fmt"
+
+	"github.com/quic-go/quic-go/qlogwriter/jsontext"
+)
+
+// A Tag is a key-value pair attached to an event.
+type Tag struct {
+	Key   string
+	Value any
+}
+
+// A Tagger provides tags, for example the data attached to a connection using quic.Conn.SetUserData.
+type Tagger interface {
+	Tags() []Tag
+}
+
+// A TaggedTrace is a Trace that attaches tags to the events it records.
+type TaggedTrace interface {
+	Trace
+	// SetTags sets the tags attached to all events recorded after the call,
+	// replacing the tags set previously.
+	SetTags([]Tag)
+}
+
+func encodeTags(enc *jsontext.Encoder, tags []Tag) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	for _, tag := range tags {
+		h.WriteToken(jsontext.String(tag.Key))
+		h.WriteToken(tagValueToken(tag.Value))
+	}
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
+func tagValueToken(v any) jsontext.Token {
+	switch v := v.(type) {
+	case nil:
+		return jsontext.Null
+	case string:
+		return jsontext.String(v)
+	case bool:
+		return jsontext.Bool(v)
+	case int:
+		return jsontext.Int(int64(v))
+	case int8:
+		return jsontext.Int(int64(v))
+	case int16:
+		return jsontext.Int(int64(v))
+	case int32:
+		return jsontext.Int(int64(v))
+	case int64:
+		return jsontext.Int(v)
+	case uint:
+		return jsontext.Uint(uint64(v))
+	case uint8:
+		return jsontext.Uint(uint64(v))
+	case uint16:
+		return jsontext.Uint(uint64(v))
+	case uint32:
+		return jsontext.Uint(uint64(v))
+	case uint64:
+		return jsontext.Uint(v)
+	case float32:
+		return jsontext.Float(float64(v))
+	case float64:
+		return jsontext.Float(v)
+	default:
+		return jsontext.String(fmt.Sprint(v))
+	}
+}
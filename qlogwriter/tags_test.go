@@ -0,0 +1,60 @@
+package qlogwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSeqTags(t *testing.T) {
+	buf := &bytes.Buffer{}
+	fileSeq := NewFileSeq(nopWriteCloser(buf))
+	writer := fileSeq.AddProducer()
+	go fileSeq.Run()
+
+	writer.RecordEvent(testEvent{message: "untagged"})
+	tags := []Tag{
+		{Key: "tenant", Value: "acme"},
+		{Key: "user", Value: 42},
+		{Key: "premium", Value: true},
+		{Key: "addr", Value: netip.MustParseAddr("1.2.3.4")},
+		{Key: "nothing", Value: nil},
+	}
+	fileSeq.SetTags(tags)
+	tags[0].Value = "modified" // SetTags copies the tags
+	writer.RecordEvent(testEvent{message: "tagged"})
+	fileSeq.SetTags(nil)
+	writer.RecordEvent(testEvent{message: "untagged again"})
+	require.NoError(t, writer.Close())
+
+	records := strings.Split(buf.String(), string(RecordSeparator))
+	require.Len(t, records, 5) // empty string, trace header and three events
+
+	var untagged map[string]any
+	require.NoError(t, json.Unmarshal([]byte(records[2]), &untagged))
+	require.NotContains(t, untagged, "tags")
+
+	var tagged map[string]any
+	require.NoError(t, json.Unmarshal([]byte(records[3]), &tagged))
+	require.Equal(t, "transport:test_event", tagged["name"])
+	require.Equal(t, map[string]any{"message": "tagged"}, tagged["data"])
+	require.Equal(t,
+		map[string]any{
+			"tenant":  "acme",
+			"user":    float64(42),
+			"premium": true,
+			"addr":    "1.2.3.4",
+			"nothing": nil,
+		},
+		tagged["tags"],
+	)
+
+	var untaggedAgain map[string]any
+	require.NoError(t, json.Unmarshal([]byte(records[4]), &untaggedAgain))
+	require.Equal(t, map[string]any{"message": "untagged again"}, untaggedAgain["data"])
+	require.NotContains(t, untaggedAgain, "tags")
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/qlogwriter/jsontext"
@@ -53,6 +54,7 @@ var recordSeparator = []byte{RecordSeparator}
 type event struct {
 	Time  time.Time
 	Event Event
+	Tags  []Tag
 }
 
 const eventChanSize = 50
@@ -75,10 +77,12 @@ type FileSeq struct {
 	producers int
 	closed    bool
 
+	tags atomic.Pointer[[]Tag]
+
 	eventSchemas []string
 }
 
-var _ Trace = &FileSeq{}
+var _ TaggedTrace = &FileSeq{}
 
 // NewFileSeq creates a new JSON-SEQ qlog trace to log transport events.
 func NewFileSeq(w io.WriteCloser) *FileSeq {
@@ -127,6 +131,17 @@ func (t *FileSeq) SupportsSchemas(schema string) bool {
 	return slices.Contains(t.eventSchemas, schema)
 }
 
+// SetTags sets the tags that are encoded as the "tags" field of all events recorded after the call.
+// It is safe to call SetTags concurrently with recording events.
+func (t *FileSeq) SetTags(tags []Tag) {
+	if len(tags) == 0 {
+		t.tags.Store(nil)
+		return
+	}
+	tags = slices.Clone(tags)
+	t.tags.Store(&tags)
+}
+
 func (t *FileSeq) AddProducer() Recorder {
 	t.mx.Lock()
 	defer t.mx.Unlock()
@@ -148,7 +163,11 @@ func (t *FileSeq) record(eventTime time.Time, details Event) {
 	}
 	t.mx.Unlock()
 
-	t.events <- event{Time: eventTime, Event: details}
+	var tags []Tag
+	if p := t.tags.Load(); p != nil {
+		tags = *p
+	}
+	t.events <- event{Time: eventTime, Event: details, Tags: tags}
 }
 
 func (t *FileSeq) Run() {
@@ -193,6 +212,13 @@ func (t *FileSeq) encodeEvent(e event) {
 		t.encodeErr = err
 		return
 	}
+	if len(e.Tags) > 0 {
+		h.WriteToken(jsontext.String("tags"))
+		if err := encodeTags(t.enc, e.Tags); err != nil {
+			t.encodeErr = err
+			return
+		}
+	}
 	h.WriteToken(jsontext.EndObject)
 	if h.err != nil {
 		t.encodeErr = h.err
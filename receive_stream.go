@@ -1,9 +1,11 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
@@ -41,10 +43,21 @@ type ReceiveStream struct {
 	cancelledLocally    bool
 	cancelErr           *StreamError
 	closeForShutdownErr error
+	// If set, data that was received before closeForShutdown was called can still be read.
+	readAfterShutdown bool
+	unreadOnShutdown  UnreadStreamData
+	// If set, a RESET_STREAM frame received after all data was received is ignored.
+	ignoreLateResets bool
+	ignoredReset     *StreamError
 
 	readPos      protocol.ByteCount
 	reliableSize protocol.ByteCount
 
+	// set by the application, read when deciding whether to delay an ACK
+	ackLatencyTolerant atomic.Bool
+
+	outOfOrderLimit outOfOrderBufferLimit
+
 	readChan chan struct{}
 	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
 	deadline monotime.Time
@@ -57,6 +70,17 @@ var (
 	_ receiveStreamFrameHandler = &ReceiveStream{}
 )
 
+// errStreamDataDropped is returned when a STREAM frame is dropped because it would exceed
+// the out-of-order buffer limit. The packet containing the frame must not be acknowledged.
+var errStreamDataDropped = errors.New("dropped STREAM frame exceeding the out-of-order buffer limit")
+
+// outOfOrderBufferLimit limits the amount of data buffered beyond a gap in the received data.
+type outOfOrderBufferLimit struct {
+	maxBytes  protocol.ByteCount // if 0, the amount of data is only limited by flow control
+	action    OutOfOrderBufferOverflowAction
+	errorCode qerr.StreamErrorCode
+}
+
 func newReceiveStream(
 	streamID protocol.StreamID,
 	sender streamSender,
@@ -82,6 +106,19 @@ func (s *ReceiveStream) StreamID() protocol.StreamID {
 // Read can be made to time out using [ReceiveStream.SetReadDeadline].
 // If the stream was canceled, the error is a [StreamError].
 func (s *ReceiveStream) Read(p []byte) (int, error) {
+	return s.read(p, true)
+}
+
+// ReadAvailable reads the stream data that is available right now, without blocking.
+// If no data is available, it returns 0 and a nil error.
+// Once all data has been read, it returns [io.EOF].
+// If the stream was canceled, the error is a [StreamError].
+// The read deadline doesn't apply to ReadAvailable.
+func (s *ReceiveStream) ReadAvailable(p []byte) (int, error) {
+	return s.read(p, false)
+}
+
+func (s *ReceiveStream) read(p []byte, block bool) (int, error) {
 	// Concurrent use of Read is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
 	// Make sure that we only execute one call at any given time to avoid hard to debug failures.
@@ -89,7 +126,7 @@ func (s *ReceiveStream) Read(p []byte) (int, error) {
 	defer func() { <-s.readOnce }()
 
 	s.mutex.Lock()
-	queuedStreamWindowUpdate, queuedConnWindowUpdate, n, err := s.readImpl(p)
+	queuedStreamWindowUpdate, queuedConnWindowUpdate, n, err := s.readImpl(p, block)
 	completed := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
@@ -126,7 +163,7 @@ func (s *ReceiveStream) isNewlyCompleted() bool {
 	return false
 }
 
-func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnWindowUpdate bool, _ int, _ error) {
+func (s *ReceiveStream) readImpl(p []byte, block bool) (hasStreamWindowUpdate bool, hasConnWindowUpdate bool, _ int, _ error) {
 	if s.currentFrameIsLast && s.currentFrame == nil {
 		s.errorRead = true
 		return false, false, 0, io.EOF
@@ -135,7 +172,7 @@ func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnW
 		s.errorRead = true
 		return false, false, 0, s.cancelErr
 	}
-	if s.closeForShutdownErr != nil {
+	if s.closeForShutdownErr != nil && !s.readAfterShutdown {
 		return false, false, 0, s.closeForShutdownErr
 	}
 
@@ -151,7 +188,7 @@ func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnW
 
 		for {
 			// Stop waiting on errors
-			if s.closeForShutdownErr != nil {
+			if s.closeForShutdownErr != nil && !s.readAfterShutdown {
 				return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, s.closeForShutdownErr
 			}
 			if s.cancelledLocally || s.isRemoteCancellationEffective() {
@@ -160,13 +197,20 @@ func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnW
 			}
 
 			deadline := s.deadline
-			if !deadline.IsZero() && !monotime.Now().Before(deadline) {
-				return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, errDeadline
+			if block && !deadline.IsZero() && !monotime.Now().Before(deadline) {
+				return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, ErrReadDeadlineExceeded
 			}
 
 			if s.currentFrame != nil || s.currentFrameIsLast {
 				break
 			}
+			// all buffered data was read
+			if s.closeForShutdownErr != nil {
+				return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, s.closeForShutdownErr
+			}
+			if !block {
+				return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, nil
+			}
 
 			s.mutex.Unlock()
 			if deadline.IsZero() {
@@ -196,8 +240,9 @@ func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnW
 		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
 
 		// when a RESET_STREAM was received, the flow controller was already
-		// informed about the final offset for this stream
-		if !s.isRemoteCancellationEffective() {
+		// informed about the final offset for this stream.
+		// After the connection was closed, there's no need to send window updates.
+		if !s.isRemoteCancellationEffective() && s.closeForShutdownErr == nil {
 			hasStream, hasConn := s.flowController.AddBytesRead(protocol.ByteCount(m))
 			if hasStream {
 				s.queuedMaxStreamData = true
@@ -268,13 +313,13 @@ func (s *ReceiveStream) peekImpl(b []byte) (int, error) {
 		if s.cancelledLocally || s.isRemoteCancellationEffective() {
 			return 0, s.cancelErr
 		}
-		if s.closeForShutdownErr != nil {
+		if s.closeForShutdownErr != nil && !s.readAfterShutdown {
 			return 0, s.closeForShutdownErr
 		}
 
 		deadline := s.deadline
 		if !deadline.IsZero() && !monotime.Now().Before(deadline) {
-			return 0, errDeadline
+			return 0, ErrReadDeadlineExceeded
 		}
 
 		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
@@ -330,6 +375,10 @@ func (s *ReceiveStream) peekImpl(b []byte) (int, error) {
 		if s.currentFrameIsLast || s.readPos >= s.finalOffset {
 			return 0, io.EOF
 		}
+		// not enough buffered data available
+		if s.closeForShutdownErr != nil {
+			return 0, s.closeForShutdownErr
+		}
 
 		s.mutex.Unlock()
 		if deadline.IsZero() {
@@ -402,10 +451,13 @@ func (s *ReceiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) (queuedNe
 
 func (s *ReceiveStream) handleStreamFrame(frame *wire.StreamFrame, now monotime.Time) error {
 	s.mutex.Lock()
-	err := s.handleStreamFrameImpl(frame, now)
+	queuedNewControlFrame, err := s.handleStreamFrameImpl(frame, now)
 	completed := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
+	if queuedNewControlFrame {
+		s.sender.onHasStreamControlFrame(s.streamID, s)
+	}
 	if completed {
 		s.flowController.Abandon()
 		s.sender.onStreamCompleted(s.streamID)
@@ -413,22 +465,58 @@ func (s *ReceiveStream) handleStreamFrame(frame *wire.StreamFrame, now monotime.
 	return err
 }
 
-func (s *ReceiveStream) handleStreamFrameImpl(frame *wire.StreamFrame, now monotime.Time) error {
+func (s *ReceiveStream) handleStreamFrameImpl(frame *wire.StreamFrame, now monotime.Time) (queuedNewControlFrame bool, _ error) {
 	maxOffset := frame.Offset + frame.DataLen()
 	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.Fin, now); err != nil {
-		return err
+		return false, err
 	}
 	if frame.Fin {
 		s.finalOffset = maxOffset
 	}
 	if s.cancelledLocally {
-		return nil
+		return false, nil
+	}
+	if s.exceedsOutOfOrderLimit(frame) {
+		if s.outOfOrderLimit.action == OutOfOrderBufferOverflowReset {
+			return s.cancelReadImpl(s.outOfOrderLimit.errorCode), nil
+		}
+		return false, errStreamDataDropped
 	}
 	if err := s.frameQueue.Push(frame.Data, frame.Offset, frame.PutBack); err != nil {
-		return err
+		return false, err
 	}
 	s.signalRead()
-	return nil
+	return false, nil
+}
+
+func (s *ReceiveStream) exceedsOutOfOrderLimit(frame *wire.StreamFrame) bool {
+	_, exceeds := s.checkOutOfOrderLimitImpl(frame, 0)
+	return exceeds
+}
+
+// checkOutOfOrderLimit checks if the STREAM frame would be dropped because it exceeds the out-of-order buffer limit,
+// assuming that pending bytes of out-of-order data are buffered in addition to the data already buffered.
+// It returns the number of bytes the frame adds to the out-of-order data.
+// It is used to check all STREAM frames of a packet before handling any frame of that packet.
+func (s *ReceiveStream) checkOutOfOrderLimit(frame *wire.StreamFrame, pending protocol.ByteCount) (outOfOrder protocol.ByteCount, drop bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.cancelledLocally || s.outOfOrderLimit.action != OutOfOrderBufferOverflowDrop {
+		return 0, false
+	}
+	return s.checkOutOfOrderLimitImpl(frame, pending)
+}
+
+func (s *ReceiveStream) checkOutOfOrderLimitImpl(frame *wire.StreamFrame, pending protocol.ByteCount) (outOfOrder protocol.ByteCount, exceeds bool) {
+	if s.outOfOrderLimit.maxBytes == 0 {
+		return 0, false
+	}
+	// Data at the contiguous offset fills (the beginning of) the gap, and is therefore never out of order.
+	if frame.Offset <= s.frameQueue.ContiguousOffset() {
+		return 0, false
+	}
+	return frame.DataLen(), s.frameQueue.OutOfOrderBytes()+pending+frame.DataLen() > s.outOfOrderLimit.maxBytes
 }
 
 func (s *ReceiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame, now monotime.Time) error {
@@ -450,6 +538,14 @@ func (s *ReceiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame,
 	if err := s.flowController.UpdateHighestReceived(frame.FinalSize, true, now); err != nil {
 		return err
 	}
+	// If all data was received (or even read), the application can read the entire stream.
+	// RFC 9000 allows us to deliver the data instead of signaling the reset.
+	if s.ignoreLateResets && !s.cancelledRemotely && !s.cancelledLocally && s.allDataReceived() {
+		if s.ignoredReset == nil {
+			s.ignoredReset = &StreamError{StreamID: s.streamID, ErrorCode: frame.ErrorCode, Remote: true}
+		}
+		return nil
+	}
 	s.finalOffset = frame.FinalSize
 
 	// senders are allowed to reduce the reliable size, but frames might have been reordered
@@ -475,6 +571,11 @@ func (s *ReceiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame,
 	return nil
 }
 
+// allDataReceived says if the FIN and all data up to the final offset was received.
+func (s *ReceiveStream) allDataReceived() bool {
+	return s.finalOffset != protocol.MaxByteCount && s.frameQueue.ContiguousOffset() >= s.finalOffset
+}
+
 func (s *ReceiveStream) getControlFrame(now monotime.Time) (_ ackhandler.Frame, ok, hasMore bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -509,16 +610,60 @@ func (s *ReceiveStream) SetReadDeadline(t time.Time) error {
 	return nil
 }
 
+// SetAckLatencyHint sets how quickly data received on this stream needs to be acknowledged.
+// ACKs are sent for the connection as a whole, so a packet carrying data for any other stream
+// is acknowledged as quickly as usual.
+func (s *ReceiveStream) SetAckLatencyHint(hint AckLatencyHint) {
+	s.ackLatencyTolerant.Store(hint == AckLatencyTolerant)
+}
+
+func (s *ReceiveStream) isAckLatencyTolerant() bool { return s.ackLatencyTolerant.Load() }
+
+// Stats returns statistics about the data buffered on the stream.
+func (s *ReceiveStream) Stats() StreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	buffered := s.frameQueue.BufferedBytes()
+	if s.currentFrame != nil {
+		buffered += protocol.ByteCount(len(s.currentFrame) - s.readPosInFrame)
+	}
+	return StreamStats{
+		BufferedBytes:   uint64(buffered),
+		OutOfOrderBytes: uint64(s.frameQueue.OutOfOrderBytes()),
+		IgnoredReset:    s.ignoredReset,
+	}
+}
+
 // CloseForShutdown closes a stream abruptly.
-// It makes Read unblock (and return the error) immediately.
+// It makes Read unblock (and return the error) immediately,
+// unless reading buffered data after shutdown is enabled.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RESET.
 func (s *ReceiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
+	if s.closeForShutdownErr == nil {
+		unread := s.frameQueue.BufferedBytes()
+		if s.currentFrame != nil {
+			unread += protocol.ByteCount(len(s.currentFrame) - s.readPosInFrame)
+		}
+		s.unreadOnShutdown = UnreadStreamData{
+			StreamID:         s.streamID,
+			UnreadBytes:      uint64(unread),
+			ContiguousOffset: uint64(s.frameQueue.ContiguousOffset()),
+		}
+	}
 	s.closeForShutdownErr = err
 	s.mutex.Unlock()
 	s.signalRead()
 }
 
+// unreadData returns the unread data at the time the stream was closed for shutdown.
+func (s *ReceiveStream) unreadData() UnreadStreamData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.unreadOnShutdown
+}
+
 // signalRead performs a non-blocking send on the readChan
 func (s *ReceiveStream) signalRead() {
 	select {
@@ -122,6 +122,51 @@ func TestReceiveStreamReadData(t *testing.T) {
 	require.Equal(t, []byte{'f', 'o', 'o', 'b', 'a', 'z'}, b)
 }
 
+func TestReceiveStreamReadAvailable(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newReceiveStream(42, mockSender, mockFC)
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+
+	// no data available
+	b := make([]byte, 6)
+	n, err := str.ReadAvailable(b)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// less data available than requested
+	now := monotime.Now()
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")}, now))
+	n, err = str.ReadAvailable(b)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), b[:n])
+
+	// data after a gap is not available
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("baz"), Fin: true}, now))
+	n, err = str.ReadAvailable(b)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// the read deadline doesn't apply
+	require.NoError(t, str.SetReadDeadline(time.Now().Add(-time.Second)))
+	n, err = str.ReadAvailable(b)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// once the gap is filled, all data up to the end of the stream is read
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")}, now))
+	mockSender.EXPECT().onStreamCompleted(protocol.StreamID(42))
+	n, err = str.ReadAvailable(b)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, []byte("barbaz"), b[:n])
+
+	n, err = str.ReadAvailable(b)
+	require.ErrorIs(t, err, io.EOF)
+	require.Zero(t, n)
+}
+
 func TestReceiveStreamPeekData(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
@@ -335,7 +380,7 @@ func testReceiveStreamDeadlineInThePast(t *testing.T, consumesBytes bool, op fun
 	n, err := op(str, b)
 	require.Error(t, err)
 	require.Zero(t, n)
-	require.ErrorIs(t, err, errDeadline)
+	require.ErrorIs(t, err, ErrReadDeadlineExceeded)
 
 	// data is read when the deadline is in the future
 	require.NoError(t, str.SetReadDeadline(time.Now().Add(time.Second)))
@@ -648,6 +693,121 @@ func TestReceiveStreamCloseForShutdown(t *testing.T) {
 	})
 }
 
+func TestReceiveStreamReadAfterShutdown(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	str := newReceiveStream(42, nil, mockFC)
+	str.readAfterShutdown = true
+
+	now := monotime.Now()
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false, now).Times(2)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")}, now))
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("baz")}, now))
+	mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+	b := make([]byte, 3)
+	n, err := (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(b)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), b[:n])
+
+	str.closeForShutdown(assert.AnError)
+	require.Equal(t, UnreadStreamData{StreamID: 42, UnreadBytes: 6, ContiguousOffset: 6}, str.unreadData())
+
+	// the buffered data can still be read, without sending any flow control updates
+	b = make([]byte, 10)
+	n, err = (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(b)
+	require.ErrorIs(t, err, assert.AnError)
+	require.Equal(t, []byte("bar"), b[:n])
+	// the data after the gap can't be read
+	n, err = (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(b)
+	require.ErrorIs(t, err, assert.AnError)
+	require.Zero(t, n)
+	n, err = (&peekerWithTimeout{Peeker: str, Timeout: time.Second}).Peek(b[:1])
+	require.ErrorIs(t, err, assert.AnError)
+	require.Zero(t, n)
+	// the unread data is recorded when the stream is closed
+	require.Equal(t, uint64(6), str.unreadData().UnreadBytes)
+}
+
+func TestReceiveStreamStats(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	str := newReceiveStream(42, nil, mockFC)
+
+	now := monotime.Now()
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false, now).Times(2)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")}, now))
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("baz")}, now))
+	require.Equal(t, StreamStats{BufferedBytes: 9, OutOfOrderBytes: 3}, str.Stats())
+
+	mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+	n, err := (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, StreamStats{BufferedBytes: 5, OutOfOrderBytes: 3}, str.Stats())
+
+	// fill the gap
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false, now)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("lore")}, now))
+	require.Equal(t, StreamStats{BufferedBytes: 9}, str.Stats())
+}
+
+func TestReceiveStreamOutOfOrderLimitDrop(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	str := newReceiveStream(42, nil, mockFC)
+	str.outOfOrderLimit = outOfOrderBufferLimit{maxBytes: 10, action: OutOfOrderBufferOverflowDrop}
+
+	now := monotime.Now()
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false, now).AnyTimes()
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("foobar")}, now))
+	require.ErrorIs(t,
+		str.handleStreamFrame(&wire.StreamFrame{Offset: 20, Data: []byte("raboof")}, now),
+		errStreamDataDropped,
+	)
+	require.Equal(t, StreamStats{BufferedBytes: 6, OutOfOrderBytes: 6}, str.Stats())
+	// the limit can be checked before handling the frame,
+	// taking into account other out-of-order data of the same packet
+	outOfOrder, drop := str.checkOutOfOrderLimit(&wire.StreamFrame{Offset: 20, Data: []byte("baz")}, 0)
+	require.Equal(t, protocol.ByteCount(3), outOfOrder)
+	require.False(t, drop)
+	_, drop = str.checkOutOfOrderLimit(&wire.StreamFrame{Offset: 20, Data: []byte("baz")}, 2)
+	require.True(t, drop)
+	outOfOrder, drop = str.checkOutOfOrderLimit(&wire.StreamFrame{Data: []byte("0123456789")}, 10)
+	require.Zero(t, outOfOrder)
+	require.False(t, drop)
+	// this frame still fits
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 20, Data: []byte("baz")}, now))
+	require.Equal(t, StreamStats{BufferedBytes: 9, OutOfOrderBytes: 9}, str.Stats())
+
+	// data at the contiguous offset is always accepted
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("0123456789")}, now))
+	require.Equal(t, StreamStats{BufferedBytes: 19, OutOfOrderBytes: 3}, str.Stats())
+	// now that the gap is closed, the retransmission is accepted
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 20, Data: []byte("raboof")}, now))
+	require.Equal(t, StreamStats{BufferedBytes: 22, OutOfOrderBytes: 6}, str.Stats())
+}
+
+func TestReceiveStreamOutOfOrderLimitReset(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newReceiveStream(42, mockSender, mockFC)
+	str.outOfOrderLimit = outOfOrderBufferLimit{maxBytes: 10, action: OutOfOrderBufferOverflowReset, errorCode: 1337}
+
+	now := monotime.Now()
+	mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false, now).Times(2)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("foobar")}, now))
+	mockSender.EXPECT().onHasStreamControlFrame(protocol.StreamID(42), str)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 20, Data: []byte("raboof")}, now))
+	require.True(t, mockCtrl.Satisfied())
+
+	f, ok, _ := str.getControlFrame(now)
+	require.True(t, ok)
+	require.Equal(t, &wire.StopSendingFrame{StreamID: 42, ErrorCode: 1337}, f.Frame)
+	_, err := (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(make([]byte, 1))
+	require.ErrorIs(t, err, &StreamError{StreamID: 42, ErrorCode: 1337, Remote: false})
+}
+
 func TestReceiveStreamCancellation(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
@@ -868,10 +1028,20 @@ func TestReceiveStreamReset(t *testing.T) {
 }
 
 func TestReceiveStreamResetAfterFINRead(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		testReceiveStreamResetAfterFINRead(t, false)
+	})
+	t.Run("ignoring late resets", func(t *testing.T) {
+		testReceiveStreamResetAfterFINRead(t, true)
+	})
+}
+
+func testReceiveStreamResetAfterFINRead(t *testing.T, ignoreLateResets bool) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	mockSender := NewMockStreamSender(mockCtrl)
 	str := newReceiveStream(42, mockSender, mockFC)
+	str.ignoreLateResets = ignoreLateResets
 	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true, gomock.Any())
 	mockSender.EXPECT().onStreamCompleted(protocol.StreamID(42))
 	require.NoError(t, str.handleStreamFrame(
@@ -888,15 +1058,72 @@ func TestReceiveStreamResetAfterFINRead(t *testing.T) {
 	// Now receive a RESET_STREAM frame.
 	// We don't expect any more calls to onStreamCompleted.
 	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true, gomock.Any())
-	mockFC.EXPECT().Abandon()
+	if !ignoreLateResets {
+		mockFC.EXPECT().Abandon()
+	}
 	require.NoError(t, str.handleResetStreamFrame(
 		&wire.ResetStreamFrame{StreamID: 42, ErrorCode: 1234, FinalSize: 6},
 		monotime.Now(),
 	))
-	// now read the error
 	n, err = str.Read([]byte{0})
-	require.Error(t, err)
+	require.ErrorIs(t, err, io.EOF)
 	require.Zero(t, n)
+	if !ignoreLateResets {
+		require.Nil(t, str.Stats().IgnoredReset)
+	} else {
+		require.Equal(t, &StreamError{StreamID: 42, ErrorCode: 1234, Remote: true}, str.Stats().IgnoredReset)
+	}
+}
+
+func TestReceiveStreamResetAfterAllDataReceived(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		testReceiveStreamResetAfterAllDataReceived(t, false)
+	})
+	t.Run("ignoring late resets", func(t *testing.T) {
+		testReceiveStreamResetAfterAllDataReceived(t, true)
+	})
+}
+
+func testReceiveStreamResetAfterAllDataReceived(t *testing.T, ignoreLateResets bool) {
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newReceiveStream(42, mockSender, mockFC)
+	str.ignoreLateResets = ignoreLateResets
+
+	// receive the data out of order, the FIN arrives first
+	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true, gomock.Any())
+	require.NoError(t, str.handleStreamFrame(
+		&wire.StreamFrame{StreamID: 42, Offset: 3, Data: []byte("bar"), Fin: true},
+		monotime.Now(),
+	))
+	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), false, gomock.Any())
+	require.NoError(t, str.handleStreamFrame(
+		&wire.StreamFrame{StreamID: 42, Data: []byte("foo")},
+		monotime.Now(),
+	))
+
+	// the RESET_STREAM frame arrives before the application read the data
+	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true, gomock.Any())
+	if !ignoreLateResets {
+		mockFC.EXPECT().Abandon()
+	}
+	require.NoError(t, str.handleResetStreamFrame(
+		&wire.ResetStreamFrame{StreamID: 42, ErrorCode: 1234, FinalSize: 6},
+		monotime.Now(),
+	))
+
+	mockSender.EXPECT().onStreamCompleted(protocol.StreamID(42))
+	if !ignoreLateResets {
+		_, err := str.Read(make([]byte, 6))
+		require.Equal(t, &StreamError{StreamID: 42, ErrorCode: 1234, Remote: true}, err)
+		return
+	}
+	mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3)).Times(2)
+	data, err := io.ReadAll(str)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), data)
+	require.Equal(t, &StreamError{StreamID: 42, ErrorCode: 1234, Remote: true}, str.Stats().IgnoredReset)
 }
 
 // Calling Read concurrently doesn't make any sense (and is forbidden),
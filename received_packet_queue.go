@@ -0,0 +1,191 @@
+package quic
+
+import (
+	"container/heap"
+	"net"
+	"net/netip"
+	"sync"
+
+	list "github.com/quic-go/quic-go/internal/utils/linkedlist"
+)
+
+// HandshakeQueueStrategy determines the order in which the server processes
+// the packets buffered in its receive queue, see Config.HandshakeQueueStrategy.
+type HandshakeQueueStrategy int
+
+const (
+	// HandshakeQueueFIFO processes packets in the order they were received.
+	HandshakeQueueFIFO HandshakeQueueStrategy = iota
+	// HandshakeQueueSourceIPDiverse prefers packets from source IPs that have fewer packets queued.
+	// Packets from the same source IP are processed in the order they were received.
+	// This prevents a single source IP from monopolizing the server when it's under load.
+	HandshakeQueueSourceIPDiverse
+)
+
+type receivedPacketQueueEntry struct {
+	packet receivedPacket
+	ip     netip.Addr
+	// rank is the number of packets from the same source IP that were queued when this packet was added
+	rank  int
+	seq   uint64
+	index int // index in the heap
+	elem  *list.Element[*receivedPacketQueueEntry]
+}
+
+type receivedPacketQueueHeap []*receivedPacketQueueEntry
+
+var _ heap.Interface = &receivedPacketQueueHeap{}
+
+func (h receivedPacketQueueHeap) Len() int { return len(h) }
+
+func (h receivedPacketQueueHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h receivedPacketQueueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *receivedPacketQueueHeap) Push(x any) {
+	e := x.(*receivedPacketQueueEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *receivedPacketQueueHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// The receivedPacketQueue buffers the packets received by the server until the run loop processes them.
+// If the queue is full, the oldest packet is dropped to make room for the new one.
+//
+// For the FIFO strategy, packets are buffered in a channel.
+// Only the source IP diverse strategy needs to reorder packets, and uses a heap protected by a mutex.
+type receivedPacketQueue struct {
+	// only set for the FIFO strategy
+	fifo chan receivedPacket
+
+	// only used for the source IP diverse strategy
+	mutex     sync.Mutex
+	maxLen    int
+	nextSeq   uint64
+	heap      receivedPacketQueueHeap
+	arrivals  list.List[*receivedPacketQueueEntry] // in the order the packets were received
+	queued    map[netip.Addr]int                   // number of queued packets per source IP
+	available chan struct{}
+}
+
+func newReceivedPacketQueue(maxLen int, strategy HandshakeQueueStrategy) *receivedPacketQueue {
+	if strategy != HandshakeQueueSourceIPDiverse {
+		return &receivedPacketQueue{fifo: make(chan receivedPacket, maxLen)}
+	}
+	return &receivedPacketQueue{
+		maxLen:    maxLen,
+		queued:    make(map[netip.Addr]int),
+		available: make(chan struct{}, 1),
+	}
+}
+
+// Add adds a packet to the queue.
+// If the queue is full, the oldest packet is removed from the queue and returned.
+// It must not be called concurrently.
+func (q *receivedPacketQueue) Add(p receivedPacket) (dropped receivedPacket, didDrop bool) {
+	if q.fifo != nil {
+		return q.addFIFO(p)
+	}
+
+	q.mutex.Lock()
+	if q.heap.Len() >= q.maxLen {
+		dropped = q.remove(q.arrivals.Front().Value)
+		didDrop = true
+	}
+	e := &receivedPacketQueueEntry{packet: p, seq: q.nextSeq, ip: sourceIP(p.remoteAddr)}
+	q.nextSeq++
+	e.rank = q.queued[e.ip]
+	q.queued[e.ip]++
+	e.elem = q.arrivals.PushBack(e)
+	heap.Push(&q.heap, e)
+	q.mutex.Unlock()
+
+	select {
+	case q.available <- struct{}{}:
+	default:
+	}
+	return dropped, didDrop
+}
+
+func (q *receivedPacketQueue) addFIFO(p receivedPacket) (dropped receivedPacket, didDrop bool) {
+	select {
+	case q.fifo <- p:
+		return receivedPacket{}, false
+	default:
+	}
+	// The queue is full. The run loop might have consumed a packet in the meantime,
+	// in which case there's no need to drop a packet.
+	select {
+	case dropped = <-q.fifo:
+		didDrop = true
+	default:
+	}
+	// Add is never called concurrently, so there's room for the packet now.
+	q.fifo <- p
+	return dropped, didDrop
+}
+
+// Packets returns the channel that packets are delivered on for the FIFO strategy.
+// It returns nil for the source IP diverse strategy, in which case packets are retrieved using Pop.
+func (q *receivedPacketQueue) Packets() <-chan receivedPacket { return q.fifo }
+
+// Available returns a channel that is written to when packets are added to the queue.
+// It returns nil for the FIFO strategy.
+func (q *receivedPacketQueue) Available() <-chan struct{} { return q.available }
+
+// Pop returns the next packet that should be processed.
+func (q *receivedPacketQueue) Pop() (receivedPacket, bool) {
+	if q.fifo != nil {
+		select {
+		case p := <-q.fifo:
+			return p, true
+		default:
+			return receivedPacket{}, false
+		}
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.heap.Len() == 0 {
+		return receivedPacket{}, false
+	}
+	return q.remove(q.heap[0]), true
+}
+
+func (q *receivedPacketQueue) remove(e *receivedPacketQueueEntry) receivedPacket {
+	heap.Remove(&q.heap, e.index)
+	q.arrivals.Remove(e.elem)
+	if q.queued[e.ip] <= 1 {
+		delete(q.queued, e.ip)
+	} else {
+		q.queued[e.ip]--
+	}
+	return e.packet
+}
+
+func sourceIP(addr net.Addr) netip.Addr {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return netip.Addr{}
+	}
+	ip, _ := netip.AddrFromSlice(udpAddr.IP)
+	return ip.Unmap()
+}
@@ -0,0 +1,120 @@
+package quic
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func receivedPacketQueueTestPacket(ip net.IP, port int) receivedPacket {
+	return receivedPacket{
+		remoteAddr: &net.UDPAddr{IP: ip, Port: port},
+		buffer:     getPacketBuffer(),
+	}
+}
+
+func TestReceivedPacketQueueFIFO(t *testing.T) {
+	q := newReceivedPacketQueue(10, HandshakeQueueFIFO)
+	_, ok := q.Pop()
+	require.False(t, ok)
+
+	for i := range 3 {
+		_, dropped := q.Add(receivedPacketQueueTestPacket(net.IPv4(192, 168, 0, 1), 1000+i))
+		require.False(t, dropped)
+	}
+	_, dropped := q.Add(receivedPacketQueueTestPacket(net.IPv4(192, 168, 0, 2), 2000))
+	require.False(t, dropped)
+	require.Len(t, q.Packets(), 4)
+	require.Nil(t, q.Available())
+
+	for _, port := range []int{1000, 1001, 1002, 2000} {
+		p, ok := q.Pop()
+		require.True(t, ok)
+		require.Equal(t, port, p.remoteAddr.(*net.UDPAddr).Port)
+	}
+	_, ok = q.Pop()
+	require.False(t, ok)
+}
+
+func TestReceivedPacketQueueDropOldest(t *testing.T) {
+	for _, strategy := range []HandshakeQueueStrategy{HandshakeQueueFIFO, HandshakeQueueSourceIPDiverse} {
+		q := newReceivedPacketQueue(3, strategy)
+		// the oldest packet is from the IP with the most packets, and would be processed first
+		for i := range 3 {
+			_, dropped := q.Add(receivedPacketQueueTestPacket(net.IPv4(10, 0, 0, byte(i)), 1000+i))
+			require.False(t, dropped)
+		}
+		p, dropped := q.Add(receivedPacketQueueTestPacket(net.IPv4(10, 0, 0, 3), 1003))
+		require.True(t, dropped)
+		require.Equal(t, 1000, p.remoteAddr.(*net.UDPAddr).Port)
+		p, dropped = q.Add(receivedPacketQueueTestPacket(net.IPv4(10, 0, 0, 4), 1004))
+		require.True(t, dropped)
+		require.Equal(t, 1001, p.remoteAddr.(*net.UDPAddr).Port)
+
+		var ports []int
+		for {
+			p, ok := q.Pop()
+			if !ok {
+				break
+			}
+			ports = append(ports, p.remoteAddr.(*net.UDPAddr).Port)
+		}
+		require.Equal(t, []int{1002, 1003, 1004}, ports)
+	}
+}
+
+func TestReceivedPacketQueueSourceIPDiverse(t *testing.T) {
+	attacker := net.IPv4(203, 0, 113, 1)
+	legitimate := []net.IP{
+		net.IPv4(192, 0, 2, 1),
+		net.IPv4(192, 0, 2, 2),
+		net.IPv4(192, 0, 2, 3),
+		net.ParseIP("2001:db8::1"),
+	}
+
+	// The attacker floods the server with Initial packets before the legitimate clients connect.
+	fill := func(q *receivedPacketQueue) {
+		for i := range 50 {
+			q.Add(receivedPacketQueueTestPacket(attacker, 1000+i))
+		}
+		for i, ip := range legitimate {
+			q.Add(receivedPacketQueueTestPacket(ip, 2000+i))
+		}
+	}
+	// uniqueSourceIPs processes n packets, and returns the number of unique source IPs
+	uniqueSourceIPs := func(q *receivedPacketQueue, n int) int {
+		ips := make(map[string]struct{})
+		for range n {
+			p, ok := q.Pop()
+			require.True(t, ok)
+			ips[p.remoteAddr.(*net.UDPAddr).IP.String()] = struct{}{}
+		}
+		return len(ips)
+	}
+
+	n := len(legitimate) + 1
+	fifo := newReceivedPacketQueue(100, HandshakeQueueFIFO)
+	fill(fifo)
+	require.Equal(t, 1, uniqueSourceIPs(fifo, n))
+
+	diverse := newReceivedPacketQueue(100, HandshakeQueueSourceIPDiverse)
+	fill(diverse)
+	require.Equal(t, n, uniqueSourceIPs(diverse, n))
+
+	// packets from the same source IP are processed in the order they were received
+	p, ok := diverse.Pop()
+	require.True(t, ok)
+	require.Equal(t, 1001, p.remoteAddr.(*net.UDPAddr).Port)
+	// IPv4-mapped IPv6 addresses are treated like the IPv4 address
+	diverse.Add(receivedPacketQueueTestPacket(net.IPv4(192, 0, 2, 1).To4(), 3000))
+	diverse.Add(receivedPacketQueueTestPacket(net.IPv4(192, 0, 2, 1), 3001))
+	diverse.Add(receivedPacketQueueTestPacket(net.IPv4(192, 0, 2, 2), 3002))
+	var ports []int
+	for range 3 {
+		p, ok := diverse.Pop()
+		require.True(t, ok)
+		ports = append(ports, p.remoteAddr.(*net.UDPAddr).Port)
+	}
+	require.Equal(t, []int{3000, 3002, 3001}, ports)
+}
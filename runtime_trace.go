@@ -0,0 +1,74 @@
+package quic
+
+import (
+	"context"
+	"runtime/trace"
+	"strconv"
+
+	"github.com/quic-go/quic-go/internal/congestion"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+const runtimeTraceTaskType = "quic connection"
+
+// The runtimeTracer annotates the connection with runtime/trace tasks, regions and logs.
+// It observes the congestion controller using congestion.Hooks, and is independent of qlog.
+// All methods must be called on the run loop goroutine,
+// since runtime/trace regions must begin and end on the same goroutine.
+type runtimeTracer struct {
+	ctx    context.Context
+	task   *trace.Task
+	region *trace.Region
+	state  qlog.CongestionState
+}
+
+// Hooks returns the hooks that need to be passed to the congestion controller.
+// It returns nil if t is nil.
+func (t *runtimeTracer) Hooks() *congestion.Hooks {
+	if t == nil {
+		return nil
+	}
+	return &congestion.Hooks{
+		OnCongestionStateChange:  t.onCongestionStateChange,
+		OnCongestionWindowChange: t.onCongestionWindowChange,
+	}
+}
+
+// Start creates the task for the connection, and starts the region for the current congestion state.
+func (t *runtimeTracer) Start(ctx context.Context) {
+	t.ctx, t.task = trace.NewTask(ctx, runtimeTraceTaskType)
+	if t.state != "" {
+		t.region = trace.StartRegion(t.ctx, t.state.String())
+	}
+}
+
+// End ends the current region and the task.
+func (t *runtimeTracer) End() {
+	if t.region != nil {
+		t.region.End()
+		t.region = nil
+	}
+	if t.task != nil {
+		t.task.End()
+		t.task = nil
+	}
+}
+
+func (t *runtimeTracer) onCongestionStateChange(state qlog.CongestionState) {
+	t.state = state
+	if t.task == nil {
+		return
+	}
+	if t.region != nil {
+		t.region.End()
+	}
+	t.region = trace.StartRegion(t.ctx, t.state.String())
+}
+
+func (t *runtimeTracer) onCongestionWindowChange(cwnd protocol.ByteCount) {
+	if t.task == nil {
+		return
+	}
+	trace.Log(t.ctx, "cwnd", strconv.FormatUint(uint64(cwnd), 10))
+}
@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"runtime/trace"
+	"testing"
+
+	"github.com/quic-go/quic-go/qlog"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeTracer(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("execution tracer already running")
+	}
+	var buf bytes.Buffer
+	require.NoError(t, trace.Start(&buf))
+
+	tracer := &runtimeTracer{}
+	hooks := tracer.Hooks()
+	// hooks called before the task is started only update the congestion state
+	hooks.OnCongestionStateChange(qlog.CongestionStateSlowStart)
+	hooks.OnCongestionWindowChange(1000)
+	require.Nil(t, tracer.region)
+
+	tracer.Start(context.Background())
+	require.NotNil(t, tracer.task)
+	require.NotNil(t, tracer.region)
+	hooks.OnCongestionWindowChange(12345)
+	hooks.OnCongestionStateChange(qlog.CongestionStateCongestionAvoidance)
+	hooks.OnCongestionStateChange(qlog.CongestionStateRecovery)
+	require.Equal(t, qlog.CongestionStateRecovery, tracer.state)
+	tracer.End()
+	require.Nil(t, tracer.task)
+	require.Nil(t, tracer.region)
+	// hooks called after the task was ended don't start a new region
+	hooks.OnCongestionStateChange(qlog.CongestionStateSlowStart)
+	require.Nil(t, tracer.region)
+	trace.Stop()
+
+	for _, s := range []string{
+		runtimeTraceTaskType,
+		"cwnd",
+		"12345",
+		string(qlog.CongestionStateSlowStart),
+		string(qlog.CongestionStateCongestionAvoidance),
+		string(qlog.CongestionStateRecovery),
+	} {
+		require.Contains(t, buf.String(), s)
+	}
+	require.NotContains(t, buf.String(), "1000")
+}
+
+func TestRuntimeTracerNil(t *testing.T) {
+	var tracer *runtimeTracer
+	require.Nil(t, tracer.Hooks())
+}
@@ -116,6 +116,12 @@ func (c *sconn) capabilities() connCapabilities {
 	return capabilities
 }
 
+// maxPayloadSize returns the largest UDP payload that the kernel allows sending to the remote address.
+// It is only available on some platforms.
+func (c *sconn) maxPayloadSize() (protocol.ByteCount, bool) {
+	return getMaxPayloadSize(c.rawConn, c.RemoteAddr())
+}
+
 func (c *sconn) ChangeRemoteAddr(addr net.Addr, info packetInfo) {
 	c.remoteAddrInfo.Store(&remoteAddrInfo{
 		addr: addr,
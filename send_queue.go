@@ -26,20 +26,24 @@ type sendQueue struct {
 	closeCalled chan struct{} // runStopped when Close() is called
 	runStopped  chan struct{} // runStopped when the run loop returns
 	available   chan struct{}
+	capacity    int
 	conn        sendConn
+
+	// called with the size of a packet that the kernel rejected as too large
+	onMsgSizeErr func(protocol.ByteCount)
 }
 
 var _ sender = &sendQueue{}
 
-const sendQueueCapacity = 8
-
-func newSendQueue(conn sendConn) sender {
+func newSendQueue(conn sendConn, capacity int, onMsgSizeErr func(protocol.ByteCount)) sender {
 	return &sendQueue{
-		conn:        conn,
-		runStopped:  make(chan struct{}),
-		closeCalled: make(chan struct{}),
-		available:   make(chan struct{}, 1),
-		queue:       make(chan queueEntry, sendQueueCapacity),
+		conn:         conn,
+		onMsgSizeErr: onMsgSizeErr,
+		runStopped:   make(chan struct{}),
+		closeCalled:  make(chan struct{}),
+		available:    make(chan struct{}, 1),
+		capacity:     capacity,
+		queue:        make(chan queueEntry, capacity),
 	}
 }
 
@@ -50,7 +54,7 @@ func (h *sendQueue) Send(p *packetBuffer, gsoSize uint16, ecn protocol.ECN) {
 	select {
 	case h.queue <- queueEntry{buf: p, gsoSize: gsoSize, ecn: ecn}:
 		// clear available channel if we've reached capacity
-		if len(h.queue) == sendQueueCapacity {
+		if len(h.queue) == h.capacity {
 			select {
 			case <-h.available:
 			default:
@@ -67,7 +71,7 @@ func (h *sendQueue) SendProbe(p *packetBuffer, addr net.Addr, info packetInfo) {
 }
 
 func (h *sendQueue) WouldBlock() bool {
-	return len(h.queue) == sendQueueCapacity
+	return len(h.queue) == h.capacity
 }
 
 func (h *sendQueue) Available() <-chan struct{} {
@@ -95,6 +99,13 @@ func (h *sendQueue) Run() error {
 				if !isSendMsgSizeErr(err) {
 					return err
 				}
+				if h.onMsgSizeErr != nil {
+					size := protocol.ByteCount(len(e.buf.Data))
+					if e.gsoSize > 0 {
+						size = protocol.ByteCount(e.gsoSize)
+					}
+					h.onMsgSizeErr(size)
+				}
 			}
 			e.buf.Release()
 			select {
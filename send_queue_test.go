@@ -3,6 +3,8 @@ package quic
 import (
 	"net"
 	"net/netip"
+	"os"
+	"syscall"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -25,7 +27,7 @@ func TestSendQueueSendOnePacket(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		c := NewMockSendConn(mockCtrl)
-		q := newSendQueue(c)
+		q := newSendQueue(c, protocol.DefaultMaxQueuedPackets, nil)
 
 		written := make(chan struct{})
 		c.EXPECT().Write([]byte("foobar"), uint16(10), protocol.ECT1).Do(
@@ -62,7 +64,7 @@ func TestSendQueueBlocking(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		c := NewMockSendConn(mockCtrl)
-		q := newSendQueue(c)
+		q := newSendQueue(c, protocol.DefaultMaxQueuedPackets, nil)
 
 		blockWrite := make(chan struct{})
 		written := make(chan struct{}, 1)
@@ -84,7 +86,7 @@ func TestSendQueueBlocking(t *testing.T) {
 		}()
 
 		// +1, since one packet will be queued in the Write call
-		for i := range sendQueueCapacity + 1 {
+		for i := range protocol.DefaultMaxQueuedPackets + 1 {
 			require.False(t, q.WouldBlock())
 			q.Send(getPacketWithContents([]byte("foobar")), 10, protocol.ECT1)
 			// make sure that the first packet is actually enqueued in the Write call
@@ -133,7 +135,7 @@ func TestSendQueueBlocking(t *testing.T) {
 		default:
 		}
 
-		for range sendQueueCapacity {
+		for range protocol.DefaultMaxQueuedPackets {
 			blockWrite <- struct{}{}
 		}
 		synctest.Wait()
@@ -155,7 +157,7 @@ func TestSendQueueWriteError(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		c := NewMockSendConn(mockCtrl)
-		q := newSendQueue(c)
+		q := newSendQueue(c, protocol.DefaultMaxQueuedPackets, nil)
 
 		c.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError)
 		q.Send(getPacketWithContents([]byte("foobar")), 6, protocol.ECNNon)
@@ -176,7 +178,7 @@ func TestSendQueueWriteError(t *testing.T) {
 		sent := make(chan struct{})
 		go func() {
 			defer close(sent)
-			for range 2 * sendQueueCapacity {
+			for range 2 * protocol.DefaultMaxQueuedPackets {
 				q.Send(getPacketWithContents([]byte("raboof")), 6, protocol.ECNNon)
 			}
 		}()
@@ -191,10 +193,43 @@ func TestSendQueueWriteError(t *testing.T) {
 	})
 }
 
+func TestSendQueueMsgSizeError(t *testing.T) {
+	msgSizeErr := &net.OpError{Op: "sendmsg", Err: os.NewSyscallError("sendmsg", syscall.EMSGSIZE)}
+	if !isSendMsgSizeErr(msgSizeErr) {
+		t.Skip("EMSGSIZE is not detected on this platform")
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		c := NewMockSendConn(mockCtrl)
+		var sizes []protocol.ByteCount
+		q := newSendQueue(c, protocol.DefaultMaxQueuedPackets, func(size protocol.ByteCount) { sizes = append(sizes, size) })
+
+		gomock.InOrder(
+			c.EXPECT().Write([]byte("foobarfoo"), uint16(0), protocol.ECNNon).Return(msgSizeErr),
+			c.EXPECT().Write([]byte("foobarfoobar"), uint16(6), protocol.ECNNon).Return(msgSizeErr),
+			c.EXPECT().Write([]byte("raboof"), uint16(0), protocol.ECNNon),
+		)
+		q.Send(getPacketWithContents([]byte("foobarfoo")), 0, protocol.ECNNon)
+		q.Send(getPacketWithContents([]byte("foobarfoobar")), 6, protocol.ECNNon)
+		q.Send(getPacketWithContents([]byte("raboof")), 0, protocol.ECNNon)
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- q.Run() }()
+
+		synctest.Wait()
+		// for GSO batches, the size of a single packet is reported
+		require.Equal(t, []protocol.ByteCount{9, 6}, sizes)
+
+		q.Close()
+		require.NoError(t, <-errChan)
+	})
+}
+
 func TestSendQueueSendProbe(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	c := NewMockSendConn(mockCtrl)
-	q := newSendQueue(c)
+	q := newSendQueue(c, protocol.DefaultMaxQueuedPackets, nil)
 
 	addr := &net.UDPAddr{IP: net.IPv4(42, 42, 42, 42), Port: 42}
 	localAddr := netip.MustParseAddr("43.43.43.43")
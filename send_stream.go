@@ -1,8 +1,10 @@
 package quic
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -39,7 +42,8 @@ type SendStream struct {
 	queuedResetStreamFrame *wire.ResetStreamFrame
 
 	supportsResetStreamAt bool
-	finishedWriting       bool // set once Close() is called
+	finishedWriting       bool // set once Close() is called, or once WriteAndClose wrote all data
+	finOnWrite            bool // set while WriteAndClose is writing, the FIN is sent with the last bytes
 	finSent               bool // set when a STREAM_FRAME with FIN bit has been sent
 	// Set when the application knows about the cancellation.
 	// This can happen because the application called CancelWrite,
@@ -50,11 +54,49 @@ type SendStream struct {
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
 	nextFrame      *wire.StreamFrame
 
+	// maxSendBuffer limits the amount of data that has been sent but not yet acknowledged.
+	// Once the limit is reached, no new data is sent (and Write blocks),
+	// until the amount of unacknowledged data has dropped below 25% of the limit.
+	// 0 means no limit.
+	maxSendBuffer     protocol.ByteCount
+	bytesUnacked      protocol.ByteCount
+	sendBufferBlocked bool
+
+	// ackedOffset is the offset up to which all stream data was acknowledged.
+	// ackedRanges are the acknowledged ranges beyond ackedOffset, sorted by their start offset.
+	ackedOffset protocol.ByteCount
+	ackedRanges []byteInterval
+
+	// writeTimes are the times of the Write calls, sorted by the offset their data ends at.
+	// They are used to measure the time from a Write call to the acknowledgement of the data,
+	// and are removed once the data was acknowledged.
+	writeTimes            []streamWriteTime
+	writeLatency          time.Duration // exponentially weighted moving average
+	writeLatencyHistogram *utils.LatencyHistogram
+
 	writeChan chan struct{}
 	writeOnce chan struct{}
 	deadline  monotime.Time
 
 	flowController flowcontrol.StreamFlowController
+
+	// zeroRTTRetryAllowed is set for streams opened by the client before the handshake completed.
+	// If zeroRTTRetry is set, all data sent on the stream is kept in zeroRTTData,
+	// such that it can be retransmitted in 1-RTT packets if the server rejects 0-RTT.
+	zeroRTTRetryAllowed bool
+	zeroRTTRetry        bool
+	zeroRTTData         []byte
+	// replayData is the data sent in 0-RTT packets that needs to be sent again after 0-RTT was rejected.
+	// It is sent before nextFrame and dataForWriting.
+	replayData []byte
+
+	// If set, a STOP_SENDING frame received after all data was acknowledged is ignored.
+	ignoreLateStopSending bool
+}
+
+type streamWriteTime struct {
+	end  protocol.ByteCount
+	time monotime.Time
 }
 
 var (
@@ -97,14 +139,36 @@ func (s *SendStream) Write(p []byte) (int, error) {
 	s.writeOnce <- struct{}{}
 	defer func() { <-s.writeOnce }()
 
-	isNewlyCompleted, n, err := s.write(p)
+	isNewlyCompleted, n, err := s.write(p, false)
 	if isNewlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return n, err
 }
 
-func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error) {
+// WriteAndClose writes data to the stream and closes it.
+// It is equivalent to calling Write followed by Close, but guarantees that the FIN bit
+// is sent on the STREAM frame carrying the last bytes of p, instead of in a separate STREAM frame.
+// If Write fails, the stream is not closed.
+func (s *SendStream) WriteAndClose(p []byte) (int, error) {
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	if len(p) == 0 {
+		return 0, s.Close()
+	}
+	isNewlyCompleted, n, err := s.write(p, true)
+	if isNewlyCompleted {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	if err != nil {
+		return n, err
+	}
+	s.ctxCancel(nil)
+	return n, nil
+}
+
+func (s *SendStream) write(p []byte, closeStream bool) (bool /* is newly completed */, int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -119,13 +183,22 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 		return false, 0, fmt.Errorf("write on closed stream %d", s.streamID)
 	}
 	if !s.deadline.IsZero() && !monotime.Now().Before(s.deadline) {
-		return false, 0, errDeadline
+		return false, 0, ErrWriteDeadlineExceeded
 	}
 	if len(p) == 0 {
 		return false, 0, nil
 	}
 
+	end := s.writeOffset + protocol.ByteCount(len(s.replayData)+len(p))
+	if s.nextFrame != nil {
+		end += s.nextFrame.DataLen()
+	}
+	s.writeTimes = append(s.writeTimes, streamWriteTime{end: end, time: monotime.Now()})
 	s.dataForWriting = p
+	// The FIN bit is set on the frame that empties dataForWriting and nextFrame,
+	// i.e. on the frame carrying the last bytes of p.
+	// The stream is only marked as closed once all data was written.
+	s.finOnWrite = closeStream
 
 	var (
 		deadlineTimer  *time.Timer
@@ -143,7 +216,7 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
 			if s.nextFrame == nil {
 				f := wire.GetStreamFrame()
-				f.Offset = s.writeOffset
+				f.Offset = s.writeOffset + protocol.ByteCount(len(s.replayData))
 				f.StreamID = s.streamID
 				f.DataLenPresent = true
 				f.Data = f.Data[:len(s.dataForWriting)]
@@ -159,11 +232,19 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 			copied = true
 		} else {
 			bytesWritten = len(p) - len(s.dataForWriting)
+			// all data was consumed (and the FIN might have been sent), even if the deadline expired in the meantime
+			if s.dataForWriting == nil {
+				break
+			}
 			deadline = s.deadline
 			if !deadline.IsZero() {
 				if !monotime.Now().Before(deadline) {
+					// the data that wasn't written will be written by a later call to Write
+					s.writeTimes[len(s.writeTimes)-1].end -= protocol.ByteCount(len(s.dataForWriting))
 					s.dataForWriting = nil
-					return false, bytesWritten, errDeadline
+					// don't send a FIN for a partial write
+					s.finOnWrite = false
+					return false, bytesWritten, ErrWriteDeadlineExceeded
 				}
 				if deadlineTimer == nil {
 					deadlineTimer = time.NewTimer(monotime.Until(deadline))
@@ -172,7 +253,7 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 					deadlineTimer.Reset(monotime.Until(deadline))
 				}
 			}
-			if s.dataForWriting == nil || s.shutdownErr != nil || s.resetErr != nil {
+			if s.shutdownErr != nil || s.resetErr != nil {
 				break
 			}
 		}
@@ -198,8 +279,13 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 	}
 
 	if bytesWritten == len(p) {
+		if s.finOnWrite {
+			s.finOnWrite = false
+			s.finishedWriting = true
+		}
 		return false, bytesWritten, nil
 	}
+	s.finOnWrite = false
 	if s.shutdownErr != nil {
 		return false, bytesWritten, s.shutdownErr
 	}
@@ -237,6 +323,12 @@ func (s *SendStream) popStreamFrame(maxBytes protocol.ByteCount, v protocol.Vers
 	}, blocked, hasMoreData
 }
 
+func (s *SendStream) hasRetransmission() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.retransmissionQueue) > 0
+}
+
 func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount, v protocol.Version) (_ *wire.StreamFrame, _ *wire.StreamDataBlockedFrame, hasMoreData bool) {
 	if s.shutdownErr != nil {
 		return nil, nil, false
@@ -260,8 +352,8 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		}
 	}
 
-	if len(s.dataForWriting) == 0 && s.nextFrame == nil {
-		if s.finishedWriting && !s.finSent {
+	if len(s.dataForWriting) == 0 && s.nextFrame == nil && len(s.replayData) == 0 {
+		if (s.finishedWriting || s.finOnWrite) && !s.finSent {
 			s.finSent = true
 			return &wire.StreamFrame{
 				StreamID:       s.streamID,
@@ -273,7 +365,16 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		return nil, nil, false
 	}
 
+	// Wait for the peer to acknowledge data before sending more.
+	// The stream is added back to the framer once enough data has been acknowledged.
+	if s.sendBufferBlocked {
+		return nil, nil, false
+	}
+
 	maxDataLen := s.flowController.SendWindowSize()
+	if s.maxSendBuffer > 0 {
+		maxDataLen = min(maxDataLen, s.maxSendBuffer-s.bytesUnacked)
+	}
 	if maxDataLen == 0 {
 		return nil, nil, true
 	}
@@ -290,6 +391,16 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	if f.DataLen() > 0 {
 		s.writeOffset += f.DataLen()
 		s.flowController.AddBytesSent(f.DataLen())
+		if s.zeroRTTRetry {
+			s.zeroRTTData = append(s.zeroRTTData, f.Data...)
+		}
+		if s.maxSendBuffer > 0 {
+			s.bytesUnacked += f.DataLen()
+			if s.bytesUnacked >= s.maxSendBuffer {
+				s.sendBufferBlocked = true
+				hasMoreData = false
+			}
+		}
 	}
 	if s.resetErr != nil && s.writeOffset >= reliableOffset {
 		hasMoreData = false
@@ -300,7 +411,7 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	if f.DataLen() == maxDataLen && s.flowController.IsNewlyBlocked() {
 		blocked = &wire.StreamDataBlockedFrame{StreamID: s.streamID, MaximumStreamData: s.writeOffset}
 	}
-	f.Fin = s.finishedWriting && s.dataForWriting == nil && s.nextFrame == nil && !s.finSent
+	f.Fin = (s.finishedWriting || s.finOnWrite) && s.dataForWriting == nil && s.nextFrame == nil && len(s.replayData) == 0 && !s.finSent
 	if f.Fin {
 		s.finSent = true
 	}
@@ -310,6 +421,25 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 // popNewStreamFrame returns a new STREAM frame to send for this stream
 // hasMoreData says if there's more data to send, *not* taking into account the reliable size
 func (s *SendStream) popNewStreamFrame(maxBytes, maxDataLen protocol.ByteCount, v protocol.Version) (_ *wire.StreamFrame, hasMoreData bool) {
+	if len(s.replayData) > 0 {
+		f := wire.GetStreamFrame()
+		f.Fin = false
+		f.StreamID = s.streamID
+		f.Offset = s.writeOffset
+		f.DataLenPresent = true
+		l := min(maxDataLen, f.MaxDataLen(maxBytes, v), protocol.ByteCount(len(s.replayData)))
+		if l == 0 {
+			f.PutBack()
+			return nil, true
+		}
+		f.Data = f.Data[:l]
+		copy(f.Data, s.replayData)
+		s.replayData = s.replayData[l:]
+		if len(s.replayData) == 0 {
+			s.replayData = nil
+		}
+		return f, s.replayData != nil || s.nextFrame != nil || s.dataForWriting != nil
+	}
 	if s.nextFrame != nil {
 		maxDataLen := min(maxDataLen, s.nextFrame.MaxDataLen(maxBytes, v))
 		if maxDataLen == 0 {
@@ -389,6 +519,9 @@ func (s *SendStream) isNewlyCompleted() bool {
 	if s.nextFrame != nil && s.nextFrame.DataLen() > 0 {
 		return false
 	}
+	if len(s.replayData) > 0 {
+		return false
+	}
 	// We need to keep the stream around until all frames have been sent and acknowledged.
 	if s.numOutstandingFrames > 0 || len(s.retransmissionQueue) > 0 || s.queuedResetStreamFrame != nil {
 		return false
@@ -448,12 +581,81 @@ func (s *SendStream) SetReliableBoundary() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.reliableSize = s.writeOffset
+	s.reliableSize = s.writeOffset + protocol.ByteCount(len(s.replayData))
 	if s.nextFrame != nil {
 		s.reliableSize += s.nextFrame.DataLen()
 	}
 }
 
+// Enable0RTTRetry marks the data written to this stream as safe to retry if the server rejects 0-RTT.
+// The stream then keeps a copy of all data sent in 0-RTT packets until the handshake completes.
+// If 0-RTT is rejected, this data is retransmitted in 1-RTT packets, and the stream (including its stream ID)
+// remains usable, instead of failing with [Err0RTTRejected].
+// It only has an effect on streams opened by the client before the handshake completed,
+// and must be called before the first call to Write.
+func (s *SendStream) Enable0RTTRetry() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.zeroRTTRetryAllowed {
+		s.zeroRTTRetry = true
+	}
+}
+
+// disable0RTTRetry is called when the handshake completes.
+// From this moment on, there's no need to keep the data sent in 0-RTT packets.
+func (s *SendStream) disable0RTTRetry() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.zeroRTTRetryAllowed = false
+	s.zeroRTTRetry = false
+	s.zeroRTTData = nil
+}
+
+func (s *SendStream) retriesOn0RTTRejection() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.zeroRTTRetry
+}
+
+// restartAfter0RTTRejection resets the stream's send state, such that all data sent in 0-RTT packets
+// is sent again in 1-RTT packets, subject to the new flow controller.
+// It returns false if the stream can't be restarted, because it was canceled or closed.
+func (s *SendStream) restartAfter0RTTRejection(fc flowcontrol.StreamFlowController) bool {
+	s.mutex.Lock()
+	if !s.zeroRTTRetry || s.shutdownErr != nil || s.resetErr != nil || s.completed {
+		s.mutex.Unlock()
+		return false
+	}
+	s.replayData = s.zeroRTTData
+	s.zeroRTTData = nil
+	s.zeroRTTRetry = false
+	s.zeroRTTRetryAllowed = false
+	// All packets sent in 0-RTT were dropped, so we won't receive acknowledgements for any of the frames.
+	for _, f := range s.retransmissionQueue {
+		f.PutBack()
+	}
+	clear(s.retransmissionQueue)
+	s.retransmissionQueue = nil
+	s.numOutstandingFrames = 0
+	s.writeOffset = 0
+	s.ackedOffset = 0
+	s.ackedRanges = nil
+	s.bytesUnacked = 0
+	s.sendBufferBlocked = false
+	s.finSent = false
+	s.flowController = fc
+	hasData := len(s.replayData) > 0 || s.nextFrame != nil || s.dataForWriting != nil || s.finishedWriting
+	s.mutex.Unlock()
+
+	if hasData {
+		s.sender.onHasStreamData(s.streamID, s)
+	}
+	return true
+}
+
 // returnFramesToPool returns all queued frames to the sync.Pool
 func (s *SendStream) returnFramesToPool() {
 	for _, f := range s.retransmissionQueue {
@@ -571,6 +773,12 @@ func (s *SendStream) handleStopSendingFrame(f *wire.StopSendingFrame) {
 		s.mutex.Unlock()
 		return
 	}
+	// If all data (including the FIN) was acknowledged, the peer already received the entire stream.
+	// There's no need to send a RESET_STREAM frame, or to surface the error to the application.
+	if s.ignoreLateStopSending && s.finSent && s.resetErr == nil && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0 {
+		s.mutex.Unlock()
+		return
+	}
 	// if the peer stopped reading from the stream, there's no need to transmit any data reliably
 	s.reliableSize = 0
 	s.numOutstandingFrames = 0
@@ -613,6 +821,101 @@ func (s *SendStream) reliableOffset() protocol.ByteCount {
 	return s.reliableSize
 }
 
+// Flushed returns the offset up to which stream data has been sent.
+// Data written to the stream, but not yet sent, is not included.
+// Sent data was not necessarily received by the peer, see Acked.
+func (s *SendStream) Flushed() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return uint64(s.writeOffset)
+}
+
+// Acked returns the offset up to which stream data was acknowledged by the peer.
+// After a Write timed out, this allows the application to determine how much data
+// was durably received by the peer, once the acknowledgements arrive.
+func (s *SendStream) Acked() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return uint64(s.ackedOffset)
+}
+
+// WriteLatency returns the exponentially weighted moving average of the time
+// from the Write call until the acknowledgement of the data written.
+// It returns 0 if no data was acknowledged yet.
+func (s *SendStream) WriteLatency() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.writeLatency
+}
+
+// WriteLatencyPercentile returns the p-th percentile (0 <= p <= 100) of the time
+// from the Write call until the acknowledgement of the data written, e.g. 99 for the p99 latency.
+// Latencies are recorded in a histogram, the result may be overestimated by up to 25%.
+// It returns 0 if no data was acknowledged yet.
+func (s *SendStream) WriteLatencyPercentile(p float64) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.writeLatencyHistogram == nil {
+		return 0
+	}
+	return s.writeLatencyHistogram.Percentile(p)
+}
+
+func (s *SendStream) onDataAcked(offset, length protocol.ByteCount) {
+	end := offset + length
+	if end <= s.ackedOffset {
+		return
+	}
+	s.updateWriteLatency(offset)
+	defer s.pruneWriteTimes()
+	if offset > s.ackedOffset {
+		i, _ := slices.BinarySearchFunc(s.ackedRanges, offset, func(r byteInterval, offset protocol.ByteCount) int {
+			return cmp.Compare(r.Start, offset)
+		})
+		s.ackedRanges = slices.Insert(s.ackedRanges, i, byteInterval{Start: offset, End: end})
+		return
+	}
+	s.ackedOffset = end
+	var i int
+	for ; i < len(s.ackedRanges) && s.ackedRanges[i].Start <= s.ackedOffset; i++ {
+		s.ackedOffset = max(s.ackedOffset, s.ackedRanges[i].End)
+	}
+	s.ackedRanges = slices.Delete(s.ackedRanges, 0, i)
+}
+
+// updateWriteLatency takes a latency sample for newly acknowledged data starting at offset,
+// using the time of the Write call that wrote the first byte.
+func (s *SendStream) updateWriteLatency(offset protocol.ByteCount) {
+	i, _ := slices.BinarySearchFunc(s.writeTimes, offset, func(w streamWriteTime, offset protocol.ByteCount) int {
+		if w.end <= offset {
+			return -1
+		}
+		return 1
+	})
+	if i == len(s.writeTimes) {
+		return
+	}
+	sample := monotime.Since(s.writeTimes[i].time)
+	if s.writeLatencyHistogram == nil {
+		s.writeLatencyHistogram = &utils.LatencyHistogram{}
+		s.writeLatency = sample
+	} else {
+		s.writeLatency = (7*s.writeLatency + sample) / 8
+	}
+	s.writeLatencyHistogram.Add(sample)
+}
+
+// pruneWriteTimes removes the times of Write calls whose data was completely acknowledged.
+func (s *SendStream) pruneWriteTimes() {
+	var i int
+	for i < len(s.writeTimes) && s.writeTimes[i].end <= s.ackedOffset {
+		i++
+	}
+	if i > 0 {
+		s.writeTimes = slices.Delete(s.writeTimes, 0, i)
+	}
+}
+
 // The Context is canceled as soon as the write-side of the stream is closed.
 // This happens when Close() or CancelWrite() is called, or when the peer
 // cancels the read-side of their stream.
@@ -648,6 +951,25 @@ func (s *SendStream) closeForShutdown(err error) {
 	s.signalWrite()
 }
 
+// setMaxSendBuffer changes the send buffer limit, see Config.MaxSendBufferPerStream.
+// It must only be called for streams that were created with a non-zero limit.
+func (s *SendStream) setMaxSendBuffer(limit protocol.ByteCount) {
+	s.mutex.Lock()
+	s.maxSendBuffer = limit
+	var unblocked bool
+	if s.bytesUnacked >= limit {
+		s.sendBufferBlocked = true
+	} else if s.sendBufferBlocked && s.bytesUnacked < limit/4 {
+		s.sendBufferBlocked = false
+		unblocked = true
+	}
+	s.mutex.Unlock()
+
+	if unblocked {
+		s.sender.onHasStreamData(s.streamID, s)
+	}
+}
+
 // signalWrite performs a non-blocking send on the writeChan
 func (s *SendStream) signalWrite() {
 	select {
@@ -662,6 +984,8 @@ var _ ackhandler.FrameHandler = &sendStreamAckHandler{}
 
 func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
+	offset := sf.Offset
+	dataLen := sf.DataLen()
 	sf.PutBack()
 
 	s.mutex.Lock()
@@ -673,9 +997,21 @@ func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	(*SendStream)(s).onDataAcked(offset, dataLen)
+	var unblocked bool
+	if s.maxSendBuffer > 0 {
+		s.bytesUnacked -= min(s.bytesUnacked, dataLen)
+		if s.sendBufferBlocked && s.bytesUnacked < s.maxSendBuffer/4 {
+			s.sendBufferBlocked = false
+			unblocked = true
+		}
+	}
 	completed := (*SendStream)(s).isNewlyCompleted()
 	s.mutex.Unlock()
 
+	if unblocked {
+		s.sender.onHasStreamData(s.streamID, (*SendStream)(s))
+	}
 	if completed {
 		s.sender.onStreamCompleted(s.streamID)
 	}
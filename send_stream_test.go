@@ -412,6 +412,119 @@ func TestSendStreamDeadlineExtension(t *testing.T) {
 	})
 }
 
+func TestSendStreamDeadlinePartialWrite(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), 42, mockSender, mockFC, false)
+
+		deadline := time.Second
+		require.NoError(t, str.SetWriteDeadline(time.Now().Add(deadline)))
+		mockSender.EXPECT().onHasStreamData(gomock.Any(), str).AnyTimes()
+		type writeResult struct {
+			n   int
+			err error
+		}
+		writeChan := make(chan writeResult, 1)
+		go func() {
+			n, err := str.Write(make([]byte, 5000))
+			writeChan <- writeResult{n: n, err: err}
+		}()
+		synctest.Wait()
+
+		// the stream is blocked by flow control after sending 1500 bytes
+		mockFC.EXPECT().IsNewlyBlocked().AnyTimes()
+		mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(1000))
+		mockFC.EXPECT().AddBytesSent(protocol.ByteCount(1000))
+		f1, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.NotNil(t, f1.Frame)
+		mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(500))
+		mockFC.EXPECT().AddBytesSent(protocol.ByteCount(500))
+		f2, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.NotNil(t, f2.Frame)
+
+		select {
+		case res := <-writeChan:
+			require.ErrorIs(t, res.err, ErrWriteDeadlineExceeded)
+			require.Equal(t, 1500, res.n)
+		case <-time.After(deadline + time.Nanosecond):
+			t.Fatal("timeout")
+		}
+		require.Equal(t, uint64(1500), str.Flushed())
+		require.Zero(t, str.Acked())
+
+		// ACKs might arrive out of order
+		f2.Handler.OnAcked(f2.Frame)
+		require.Zero(t, str.Acked())
+		f1.Handler.OnAcked(f1.Frame)
+		require.Equal(t, uint64(1500), str.Acked())
+		require.Equal(t, uint64(1500), str.Flushed())
+	})
+}
+
+func TestSendStreamAckedOffset(t *testing.T) {
+	str := newSendStream(context.Background(), 42, nil, nil, false)
+	str.onDataAcked(10, 10)
+	str.onDataAcked(40, 10)
+	str.onDataAcked(25, 10)
+	require.Zero(t, str.Acked())
+	str.onDataAcked(0, 5)
+	require.Equal(t, uint64(5), str.Acked())
+	// retransmission of data that was already acknowledged
+	str.onDataAcked(0, 15)
+	require.Equal(t, uint64(20), str.Acked())
+	str.onDataAcked(20, 5)
+	require.Equal(t, uint64(35), str.Acked())
+	str.onDataAcked(30, 20)
+	require.Equal(t, uint64(50), str.Acked())
+	require.Empty(t, str.ackedRanges)
+}
+
+func TestSendStreamWriteLatency(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 1337
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+		mockSender.EXPECT().onHasStreamData(streamID, str).AnyTimes()
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+
+		require.Zero(t, str.WriteLatency())
+		require.Zero(t, str.WriteLatencyPercentile(99))
+
+		_, err := str.Write([]byte("foo"))
+		require.NoError(t, err)
+		f1, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.NotNil(t, f1.Frame)
+		time.Sleep(10 * time.Millisecond)
+		_, err = str.Write([]byte("bar"))
+		require.NoError(t, err)
+		f2, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.NotNil(t, f2.Frame)
+		time.Sleep(30 * time.Millisecond)
+
+		// the frames are acknowledged out of order
+		f2.Handler.OnAcked(f2.Frame)
+		require.Equal(t, 30*time.Millisecond, str.WriteLatency())
+		require.Len(t, str.writeTimes, 2)
+		time.Sleep(20 * time.Millisecond)
+		f1.Handler.OnAcked(f1.Frame)
+		require.Equal(t, (7*30*time.Millisecond+60*time.Millisecond)/8, str.WriteLatency())
+		// all data was acknowledged
+		require.Empty(t, str.writeTimes)
+
+		p99 := str.WriteLatencyPercentile(99)
+		require.GreaterOrEqual(t, p99, 60*time.Millisecond)
+		require.LessOrEqual(t, p99, 75*time.Millisecond)
+		p50 := str.WriteLatencyPercentile(50)
+		require.GreaterOrEqual(t, p50, 30*time.Millisecond)
+		require.Less(t, p50, 60*time.Millisecond)
+	})
+}
+
 func TestSendStreamClose(t *testing.T) {
 	const streamID protocol.StreamID = 1234
 	mockCtrl := gomock.NewController(t)
@@ -484,6 +597,210 @@ func TestSendStreamImmediateClose(t *testing.T) {
 	)
 }
 
+func TestSendStreamWriteAndClose(t *testing.T) {
+	const streamID protocol.StreamID = 1234
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	n, err := str.WriteAndClose([]byte("foobar"))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	select {
+	case <-str.Context().Done():
+	default:
+		t.Fatal("stream context should have been canceled")
+	}
+
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+	frame, _, hasMore := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.False(t, hasMore)
+	require.EqualExportedValues(t,
+		&wire.StreamFrame{StreamID: streamID, Fin: true, Data: []byte("foobar"), DataLenPresent: true},
+		frame.Frame,
+	)
+	frame, _, hasMore = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.Nil(t, frame.Frame)
+	require.False(t, hasMore)
+
+	_, err = str.Write([]byte("foobar"))
+	require.ErrorContains(t, err, "write on closed stream 1234")
+	require.NoError(t, str.Close())
+}
+
+func TestSendStream0RTTRetry(t *testing.T) {
+	const streamID protocol.StreamID = 1234
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+	// retries are only possible for streams opened by the client before the handshake completed
+	str.Enable0RTTRetry()
+	require.False(t, str.retriesOn0RTTRejection())
+	str.zeroRTTRetryAllowed = true
+	str.Enable0RTTRetry()
+	require.True(t, str.retriesOn0RTTRejection())
+
+	mockSender.EXPECT().onHasStreamData(streamID, str).Times(2)
+	_, err := str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+	frame, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.Equal(t, []byte("foobar"), frame.Frame.Data)
+	// this data is not sent before 0-RTT is rejected
+	_, err = str.Write([]byte("baz"))
+	require.NoError(t, err)
+
+	// 0-RTT is rejected, all data is sent again
+	mockFC2 := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	require.True(t, str.restartAfter0RTTRejection(mockFC2))
+	require.False(t, str.retriesOn0RTTRejection())
+	mockFC2.EXPECT().SendWindowSize().Return(protocol.ByteCount(4))
+	mockFC2.EXPECT().AddBytesSent(protocol.ByteCount(4))
+	mockFC2.EXPECT().IsNewlyBlocked()
+	frame, _, hasMore := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.True(t, hasMore)
+	require.EqualExportedValues(t,
+		&wire.StreamFrame{StreamID: streamID, Data: []byte("foob"), DataLenPresent: true},
+		frame.Frame,
+	)
+	mockFC2.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC2.EXPECT().AddBytesSent(protocol.ByteCount(2))
+	frame, _, hasMore = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.True(t, hasMore)
+	require.EqualExportedValues(t,
+		&wire.StreamFrame{StreamID: streamID, Offset: 4, Data: []byte("ar"), DataLenPresent: true},
+		frame.Frame,
+	)
+	mockFC2.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC2.EXPECT().AddBytesSent(protocol.ByteCount(3))
+	frame, _, _ = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.EqualExportedValues(t,
+		&wire.StreamFrame{StreamID: streamID, Offset: 6, Data: []byte("baz"), DataLenPresent: true},
+		frame.Frame,
+	)
+	// the stream can only be restarted once
+	require.False(t, str.restartAfter0RTTRejection(mockFC2))
+}
+
+func TestSendStream0RTTRetryDisabled(t *testing.T) {
+	const streamID protocol.StreamID = 1234
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+	str.zeroRTTRetryAllowed = true
+	str.Enable0RTTRetry()
+
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	_, err := str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+	str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.Equal(t, []byte("foobar"), str.zeroRTTData)
+
+	// the handshake completed, and 0-RTT was accepted
+	str.disable0RTTRetry()
+	require.False(t, str.retriesOn0RTTRejection())
+	require.Nil(t, str.zeroRTTData)
+	str.Enable0RTTRetry()
+	require.False(t, str.retriesOn0RTTRejection())
+}
+
+func TestSendStreamWriteAndCloseDeadline(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 1234
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		require.NoError(t, str.SetWriteDeadline(time.Now().Add(time.Second)))
+		_, err := str.WriteAndClose(make([]byte, 5000))
+		require.ErrorIs(t, err, ErrWriteDeadlineExceeded)
+
+		// the stream wasn't closed, since the data wasn't written
+		frame, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.Nil(t, frame.Frame)
+		require.NoError(t, str.SetWriteDeadline(time.Time{}))
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		require.NoError(t, str.Close())
+		frame, _, _ = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.EqualExportedValues(t,
+			&wire.StreamFrame{StreamID: streamID, Fin: true, DataLenPresent: true},
+			frame.Frame,
+		)
+	})
+}
+
+func TestSendStreamWriteAndCloseBlocked(t *testing.T) {
+	t.Run("write succeeds", func(t *testing.T) {
+		testSendStreamWriteAndCloseBlocked(t, false)
+	})
+	t.Run("connection closed", func(t *testing.T) {
+		testSendStreamWriteAndCloseBlocked(t, true)
+	})
+}
+
+func testSendStreamWriteAndCloseBlocked(t *testing.T, shutdown bool) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 1234
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+		isClosed := func() bool {
+			str.mutex.Lock()
+			defer str.mutex.Unlock()
+			return str.finishedWriting
+		}
+
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := str.WriteAndClose(make([]byte, 5000))
+			errChan <- err
+		}()
+		synctest.Wait()
+		// the stream is only closed once all data was written
+		require.False(t, isClosed())
+
+		if shutdown {
+			testErr := errors.New("shutdown")
+			str.closeForShutdown(testErr)
+			synctest.Wait()
+			require.ErrorIs(t, <-errChan, testErr)
+			require.False(t, isClosed())
+			return
+		}
+
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		var frames []*wire.StreamFrame
+		for {
+			frame, _, _ := str.popStreamFrame(1000, protocol.Version1)
+			require.NotNil(t, frame.Frame)
+			frames = append(frames, frame.Frame)
+			if frame.Frame.Fin {
+				break
+			}
+		}
+		synctest.Wait()
+		require.NoError(t, <-errChan)
+		require.True(t, isClosed())
+		// the FIN is sent on the frame carrying the last bytes
+		last := frames[len(frames)-1]
+		require.Equal(t, protocol.ByteCount(5000), last.Offset+last.DataLen())
+	})
+}
+
 func TestSendStreamFlowControlBlocked(t *testing.T) {
 	const streamID protocol.StreamID = 42
 	mockCtrl := gomock.NewController(t)
@@ -517,6 +834,122 @@ func TestSendStreamFlowControlBlocked(t *testing.T) {
 	require.False(t, hasMore)
 }
 
+func TestSendStreamSendBufferLimit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 42
+		const maxSendBuffer = 10000
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+		str.maxSendBuffer = maxSendBuffer
+
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		mockFC.EXPECT().IsNewlyBlocked().AnyTimes()
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := str.Write(make([]byte, 10*maxSendBuffer))
+			errChan <- err
+		}()
+		synctest.Wait()
+
+		// the peer doesn't acknowledge any data
+		popFrames := func() (frames []ackhandler.StreamFrame, sent protocol.ByteCount) {
+			for {
+				f, _, hasMore := str.popStreamFrame(1000, protocol.Version1)
+				if f.Frame != nil {
+					frames = append(frames, f)
+					sent += f.Frame.DataLen()
+				}
+				if !hasMore {
+					return frames, sent
+				}
+			}
+		}
+		frames, sent := popFrames()
+		require.Equal(t, protocol.ByteCount(maxSendBuffer), sent)
+		// nothing more is sent until data is acknowledged
+		f, _, hasMore := str.popStreamFrame(1000, protocol.Version1)
+		require.Nil(t, f.Frame)
+		require.False(t, hasMore)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			t.Fatalf("write should be blocked: %v", err)
+		default:
+		}
+
+		// acknowledge data, until just above 25% of the limit is outstanding
+		var acked protocol.ByteCount
+		for sent-acked-frames[0].Frame.DataLen() >= maxSendBuffer/4 {
+			acked += frames[0].Frame.DataLen()
+			frames[0].Handler.OnAcked(frames[0].Frame)
+			frames = frames[1:]
+		}
+		f, _, hasMore = str.popStreamFrame(1000, protocol.Version1)
+		require.Nil(t, f.Frame)
+		require.False(t, hasMore)
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		acked += frames[0].Frame.DataLen()
+		frames[0].Handler.OnAcked(frames[0].Frame)
+		require.True(t, mockCtrl.Satisfied())
+
+		// the writer is unblocked, and more data can be sent
+		_, sent2 := popFrames()
+		require.Equal(t, protocol.ByteCount(maxSendBuffer)-(sent-acked), sent2)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			t.Fatalf("write should be blocked: %v", err)
+		default:
+		}
+
+		str.closeForShutdown(assert.AnError)
+		synctest.Wait()
+		require.ErrorIs(t, <-errChan, assert.AnError)
+	})
+}
+
+func TestSendStreamSendBufferLimitChange(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 42
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+		str.maxSendBuffer = 10000
+
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		mockFC.EXPECT().IsNewlyBlocked().AnyTimes()
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		go str.Write(make([]byte, 20000))
+		synctest.Wait()
+
+		f, _, hasMore := str.popStreamFrame(1000, protocol.Version1)
+		require.NotNil(t, f.Frame)
+		require.True(t, hasMore)
+		sent := f.Frame.DataLen()
+
+		// reducing the limit below the amount of unacknowledged data blocks the stream
+		str.setMaxSendBuffer(sent / 2)
+		f2, _, hasMore := str.popStreamFrame(1000, protocol.Version1)
+		require.Nil(t, f2.Frame)
+		require.False(t, hasMore)
+
+		// increasing the limit unblocks the stream
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		str.setMaxSendBuffer(10000)
+		require.True(t, mockCtrl.Satisfied())
+		f2, _, _ = str.popStreamFrame(1000, protocol.Version1)
+		require.NotNil(t, f2.Frame)
+
+		str.closeForShutdown(assert.AnError)
+	})
+}
+
 func TestSendStreamCloseForShutdown(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		const streamID protocol.StreamID = 1337
@@ -871,6 +1304,63 @@ func testSendStreamStopSendingAfterWrite(t *testing.T, completeBy string) {
 	require.False(t, ok)
 }
 
+func TestSendStreamStopSendingAfterFINAcked(t *testing.T) {
+	t.Run("FIN acknowledged", func(t *testing.T) {
+		testSendStreamStopSendingAfterFIN(t, true, false)
+	})
+	t.Run("FIN acknowledged, ignoring late STOP_SENDING", func(t *testing.T) {
+		testSendStreamStopSendingAfterFIN(t, true, true)
+	})
+	t.Run("FIN not acknowledged, ignoring late STOP_SENDING", func(t *testing.T) {
+		testSendStreamStopSendingAfterFIN(t, false, true)
+	})
+}
+
+func testSendStreamStopSendingAfterFIN(t *testing.T, finAcked, ignoreLateStopSending bool) {
+	const streamID protocol.StreamID = 1000
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+	str.ignoreLateStopSending = ignoreLateStopSending
+
+	mockSender.EXPECT().onHasStreamData(streamID, str).Times(2)
+	_, err := (&writerWithTimeout{Writer: str, Timeout: time.Second}).Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC.EXPECT().AddBytesSent(gomock.Any())
+	frame, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.NotNil(t, frame.Frame)
+	require.True(t, frame.Frame.Fin)
+
+	if finAcked {
+		mockSender.EXPECT().onStreamCompleted(streamID)
+		frame.Handler.OnAcked(frame.Frame)
+		require.True(t, mockCtrl.Satisfied())
+	}
+
+	if !finAcked || !ignoreLateStopSending {
+		// the peer might not have received the FIN yet, or late STOP_SENDING frames are not ignored
+		mockSender.EXPECT().onHasStreamControlFrame(streamID, str)
+		str.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1337})
+		cf, ok, _ := str.getControlFrame(monotime.Now())
+		require.True(t, ok)
+		require.Equal(t, &wire.ResetStreamFrame{StreamID: streamID, FinalSize: 6, ErrorCode: 1337}, cf.Frame)
+		return
+	}
+
+	// the STOP_SENDING frame is ignored, no RESET_STREAM frame is sent
+	str.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1337})
+	_, ok, _ := str.getControlFrame(monotime.Now())
+	require.False(t, ok)
+	require.NoError(t, str.Close())
+	_, err = (&writerWithTimeout{Writer: str, Timeout: time.Second}).Write([]byte("foobar"))
+	require.ErrorContains(t, err, "write on closed stream")
+	require.NotErrorAs(t, err, new(*StreamError))
+	require.ErrorIs(t, context.Cause(str.Context()), context.Canceled)
+}
+
 func TestSendStreamStopSendingDuringWrite(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		const streamID protocol.StreamID = 1000
@@ -1030,14 +1520,17 @@ func TestSendStreamRetransmissions(t *testing.T) {
 	require.True(t, mockCtrl.Satisfied())
 
 	// lose the frame
+	require.False(t, str.hasRetransmission())
 	mockSender.EXPECT().onHasStreamData(streamID, str)
 	f1.Handler.OnLost(f1.Frame)
 	require.True(t, mockCtrl.Satisfied())
+	require.True(t, str.hasRetransmission())
 
 	// when popping a new frame, we first get the retransmission...
 	f2, _, hasMoreData := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
 	require.EqualExportedValues(t, &wire.StreamFrame{StreamID: streamID, Data: []byte("foo"), DataLenPresent: true}, f2.Frame)
 	require.True(t, hasMoreData)
+	require.False(t, str.hasRetransmission())
 	require.True(t, mockCtrl.Satisfied())
 
 	// ... then we get the new data
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net"
 	"sync"
 	"time"
@@ -62,7 +63,7 @@ type baseServer struct {
 	statelessResetter *statelessResetter
 	onClose           func()
 
-	receivedPackets chan receivedPacket
+	receivedPackets *receivedPacketQueue
 
 	nextZeroRTTCleanup monotime.Time
 	zeroRTTQueues      map[protocol.ConnectionID]*zeroRTTQueue // only initialized if acceptEarlyConns == true
@@ -86,7 +87,10 @@ type baseServer struct {
 		*tls.Config,
 		*handshake.TokenGenerator,
 		bool, /* client address validated by an address validation token */
+		AddressValidationInfo,
 		time.Duration,
+		func(FaultInfo) FaultAction,
+		*timerWheel,
 		qlogwriter.Trace,
 		utils.Logger,
 		protocol.Version,
@@ -112,6 +116,8 @@ type baseServer struct {
 	handshakingCount        sync.WaitGroup
 
 	verifySourceAddress func(net.Addr) bool
+	connRateLimiter     ConnectionRateLimiter
+	onOverload          func() bool
 
 	connQueue chan *Conn
 
@@ -139,6 +145,15 @@ func (l *Listener) Close() error {
 	return l.baseServer.Close()
 }
 
+// CloseWithDelay closes the listener, and then closes all connections accepted by it,
+// sending a CONNECTION_CLOSE frame with application error code 0 and the given reason phrase.
+// Instead of closing all connections at once, the CONNECTION_CLOSE frames are spread evenly over the duration t,
+// avoiding a thundering herd of clients reconnecting at the same time.
+// It blocks until all connections have been closed.
+func (l *Listener) CloseWithDelay(t time.Duration, msg string) error {
+	return l.baseServer.closeWithDelay(t, msg)
+}
+
 // Addr returns the local network address that the server is listening on.
 func (l *Listener) Addr() net.Addr {
 	return l.baseServer.Addr()
@@ -171,6 +186,13 @@ func (l *EarlyListener) Close() error {
 	return l.baseServer.Close()
 }
 
+// CloseWithDelay closes the listener, and then closes all connections accepted by it,
+// spreading the CONNECTION_CLOSE frames evenly over the duration t.
+// See [Listener.CloseWithDelay] for details.
+func (l *EarlyListener) CloseWithDelay(t time.Duration, msg string) error {
+	return l.baseServer.closeWithDelay(t, msg)
+}
+
 // Addr returns the local network addr that the server is listening on.
 func (l *EarlyListener) Addr() net.Addr {
 	return l.baseServer.Addr()
@@ -249,6 +271,8 @@ func newServer(
 	tokenGeneratorKey TokenGeneratorKey,
 	maxTokenAge time.Duration,
 	verifySourceAddress func(net.Addr) bool,
+	connRateLimiter ConnectionRateLimiter,
+	onOverload func() bool,
 	disableVersionNegotiation bool,
 	acceptEarly bool,
 ) *baseServer {
@@ -261,13 +285,15 @@ func newServer(
 		tokenGenerator:            handshake.NewTokenGenerator(tokenGeneratorKey),
 		maxTokenAge:               maxTokenAge,
 		verifySourceAddress:       verifySourceAddress,
+		connRateLimiter:           connRateLimiter,
+		onOverload:                onOverload,
 		connIDGenerator:           connIDGenerator,
 		statelessResetter:         statelessResetter,
 		connQueue:                 make(chan *Conn, protocol.MaxAcceptQueueSize),
 		errorChan:                 make(chan struct{}),
 		stopAccepting:             make(chan struct{}),
 		running:                   make(chan struct{}),
-		receivedPackets:           make(chan receivedPacket, protocol.MaxServerUnprocessedPackets),
+		receivedPackets:           newReceivedPacketQueue(config.HandshakeQueueDepth, config.HandshakeQueueStrategy),
 		versionNegotiationQueue:   make(chan receivedPacket, 4),
 		invalidTokenQueue:         make(chan rejectedPacket, 4),
 		connectionRefusedQueue:    make(chan rejectedPacket, 4),
@@ -296,14 +322,19 @@ func (s *baseServer) run() {
 			return
 		default:
 		}
-		select {
-		case <-s.errorChan:
-			return
-		case p := <-s.receivedPackets:
-			if bufferStillInUse := s.handlePacketImpl(p); !bufferStillInUse {
-				p.buffer.Release()
+		p, ok := s.receivedPackets.Pop()
+		if !ok {
+			select {
+			case <-s.errorChan:
+				return
+			case p = <-s.receivedPackets.Packets():
+			case <-s.receivedPackets.Available():
+				continue
 			}
 		}
+		if bufferStillInUse := s.handlePacketImpl(p); !bufferStillInUse {
+			p.buffer.Release()
+		}
 	}
 }
 
@@ -386,6 +417,43 @@ func (s *baseServer) close(e error, transportClose bool) {
 	}
 }
 
+// closeWithDelay closes the server, and then closes all server connections of the Transport,
+// spreading the CONNECTION_CLOSE frames evenly over the duration d. It blocks until all connections are closed.
+func (s *baseServer) closeWithDelay(d time.Duration, msg string) error {
+	err := s.Close()
+
+	var conns []*Conn
+	for _, conn := range (*Transport)(s.tr).openConns() {
+		if conn.perspective == protocol.PerspectiveServer {
+			conns = append(conns, conn)
+		}
+	}
+	if len(conns) == 0 {
+		return err
+	}
+
+	// Each connection is assigned a slot of equal length, and closed at a random point within that slot.
+	slot := d / time.Duration(len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		delay := time.Duration(i) * slot
+		if slot > 0 {
+			delay += rand.N(slot)
+		}
+		wg.Go(func() {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				conn.CloseWithError(0, msg)
+			case <-conn.Context().Done():
+			}
+		})
+	}
+	wg.Wait()
+	return err
+}
+
 // Addr returns the server's network address
 func (s *baseServer) Addr() net.Addr {
 	return s.conn.LocalAddr()
@@ -393,18 +461,22 @@ func (s *baseServer) Addr() net.Addr {
 
 func (s *baseServer) handlePacket(p receivedPacket) {
 	select {
-	case s.receivedPackets <- p:
 	case <-s.errorChan:
 		return
 	default:
-		s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", p.remoteAddr, p.Size())
-		if s.qlogger != nil {
-			s.qlogger.RecordEvent(qlog.PacketDropped{
-				Raw:     qlog.RawInfo{Length: int(p.Size())},
-				Trigger: qlog.PacketDropDOSPrevention,
-			})
-		}
 	}
+	dropped, ok := s.receivedPackets.Add(p)
+	if !ok {
+		return
+	}
+	s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", dropped.remoteAddr, dropped.Size())
+	if s.qlogger != nil {
+		s.qlogger.RecordEvent(qlog.PacketDropped{
+			Raw:     qlog.RawInfo{Length: int(dropped.Size())},
+			Trigger: qlog.PacketDropDOSPrevention,
+		})
+	}
+	dropped.buffer.Release()
 }
 
 func (s *baseServer) handlePacketImpl(p receivedPacket) bool /* is the buffer still in use? */ {
@@ -754,8 +826,36 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 
 	// restore RTT from token
 	var rtt time.Duration
-	if token != nil && !token.IsRetryToken {
-		rtt = token.RTT
+	var addrValidation AddressValidationInfo
+	if token != nil {
+		if token.IsRetryToken {
+			addrValidation.UsedRetry = true
+			addrValidation.RetryLatency = time.Since(token.SentTime)
+			addrValidation.RetrySrcConnectionID = *retrySrcConnID
+		} else {
+			rtt = token.RTT
+			addrValidation.UsedNewToken = true
+		}
+	}
+
+	if s.onOverload != nil && s.onOverload() {
+		s.logger.Debugf("Rejecting new connection from %s due to overload", p.remoteAddr)
+		s.refuseNewConn(p, hdr)
+		return nil
+	}
+
+	var handshakeStarted bool
+	if s.connRateLimiter != nil {
+		if !s.connRateLimiter.Allow(p.remoteAddr) {
+			s.logger.Debugf("Rejecting new connection from %s due to the connection rate limiter", p.remoteAddr)
+			s.refuseNewConn(p, hdr)
+			return nil
+		}
+		defer func() {
+			if !handshakeStarted {
+				s.connRateLimiter.Done(p.remoteAddr)
+			}
+		}()
 	}
 
 	config := s.config
@@ -800,6 +900,7 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 	} else {
 		cancel = cancel1
 	}
+	ctx = contextWithConnUserData(ctx)
 	var qlogTrace qlogwriter.Trace
 	if config.Tracer != nil {
 		// Use the same connection ID that is passed to the client's GetLogWriter callback.
@@ -830,7 +931,10 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 		s.tlsConf,
 		s.tokenGenerator,
 		clientAddrVerified,
+		addrValidation,
 		rtt,
+		(*Transport)(s.tr).FaultInjector,
+		(*Transport)(s.tr).timerWheel,
 		qlogTrace,
 		s.logger,
 		hdr.Version,
@@ -853,7 +957,13 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 		delete(s.zeroRTTQueues, hdr.DestConnectionID)
 	}
 
-	s.handshakingCount.Go(func() { s.handleNewConn(conn) })
+	handshakeStarted = true
+	s.handshakingCount.Go(func() {
+		s.handleNewConn(conn)
+		if s.connRateLimiter != nil {
+			s.connRateLimiter.Done(p.remoteAddr)
+		}
+	})
 	go conn.run()
 	return nil
 }
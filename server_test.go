@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net"
 	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,6 +33,8 @@ type serverOpts struct {
 	tokenGeneratorKey         TokenGeneratorKey
 	maxTokenAge               time.Duration
 	useRetry                  bool
+	connRateLimiter           ConnectionRateLimiter
+	onOverload                func() bool
 	disableVersionNegotiation bool
 	acceptEarly               bool
 	newConn                   func(
@@ -50,7 +53,10 @@ type serverOpts struct {
 		*tls.Config,
 		*handshake.TokenGenerator,
 		bool, /* client address validated by an address validation token */
+		AddressValidationInfo,
 		time.Duration,
+		func(FaultInfo) FaultAction,
+		*timerWheel,
 		qlogwriter.Trace,
 		utils.Logger,
 		protocol.Version,
@@ -59,7 +65,7 @@ type serverOpts struct {
 
 func newTestServer(t *testing.T, serverOpts *serverOpts) *testServer {
 	t.Helper()
-	c, err := wrapConn(newUDPConnLocalhost(t))
+	c, err := wrapConn(newUDPConnLocalhost(t), 0, 0)
 	require.NoError(t, err)
 	verifySourceAddress := func(net.Addr) bool { return serverOpts.useRetry }
 	config := populateConfig(serverOpts.config)
@@ -78,6 +84,8 @@ func newTestServer(t *testing.T, serverOpts *serverOpts) *testServer {
 		serverOpts.tokenGeneratorKey,
 		serverOpts.maxTokenAge,
 		verifySourceAddress,
+		serverOpts.connRateLimiter,
+		serverOpts.onOverload,
 		serverOpts.disableVersionNegotiation,
 		serverOpts.acceptEarly,
 	)
@@ -643,6 +651,7 @@ type connConstructorArgs struct {
 	clientDestConnID protocol.ConnectionID
 	destConnID       protocol.ConnectionID
 	srcConnID        protocol.ConnectionID
+	addrValidation   AddressValidationInfo
 }
 
 type connConstructorRecorder struct {
@@ -676,12 +685,16 @@ func (r *connConstructorRecorder) NewConn(
 	_ *tls.Config,
 	_ *handshake.TokenGenerator,
 	_ bool,
+	addrValidation AddressValidationInfo,
 	_ time.Duration,
+	_ func(FaultInfo) FaultAction,
+	_ *timerWheel,
 	_ qlogwriter.Trace,
 	_ utils.Logger,
 	_ protocol.Version,
 ) *wrappedConn {
 	r.ch <- connConstructorArgs{
+		addrValidation:   addrValidation,
 		ctx:              ctx,
 		connRunner:       connRunner,
 		config:           config,
@@ -771,9 +784,13 @@ func testServerCreateConnection(t *testing.T, useRetry bool) {
 		assert.Equal(t, protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}), args.destConnID)
 		assert.Equal(t, protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde}), args.origDestConnID)
 		assert.Equal(t, protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad}), *args.retrySrcConnID)
+		assert.True(t, args.addrValidation.UsedRetry)
+		assert.False(t, args.addrValidation.UsedNewToken)
+		assert.Equal(t, *args.retrySrcConnID, args.addrValidation.RetrySrcConnectionID)
 	} else {
 		assert.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}), args.origDestConnID)
 		assert.Zero(t, args.retrySrcConnID)
+		assert.Zero(t, args.addrValidation)
 	}
 
 	for range 3 {
@@ -884,12 +901,46 @@ func TestServerGetConfigForClientReject(t *testing.T) {
 	checkConnectionClose(t, conn, &eventRecorder, destConnID, srcConnID, qerr.ConnectionRefused)
 }
 
+func TestServerOverload(t *testing.T) {
+	var overloaded atomic.Bool
+	overloaded.Store(true)
+	var eventRecorder events.Recorder
+	recorder := newConnConstructorRecorder(&connTestHooks{})
+	server := newTestServer(t, &serverOpts{
+		eventRecorder: &eventRecorder,
+		newConn:       recorder.NewConn,
+		onOverload:    overloaded.Load,
+	})
+
+	conn := newUDPConnLocalhost(t)
+	srcConnID := randConnID(6)
+	destConnID := randConnID(8)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), srcConnID, destConnID))
+	checkConnectionClose(t, conn, &eventRecorder, destConnID, srcConnID, qerr.ConnectionRefused)
+	select {
+	case <-recorder.Args():
+		t.Fatal("didn't expect a connection to be created")
+	default:
+	}
+
+	// once the server is no longer overloaded, new connections are accepted
+	overloaded.Store(false)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), randConnID(8)))
+	select {
+	case <-recorder.Args():
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
 func TestServerReceiveQueue(t *testing.T) {
 	var eventRecorder events.Recorder
 	acceptConn := make(chan struct{})
 	defer close(acceptConn)
-	newConnChan := make(chan struct{}, protocol.MaxServerUnprocessedPackets+2)
+	const queueDepth = 16
+	newConnChan := make(chan struct{}, queueDepth+2)
 	server := newTestServer(t, &serverOpts{
+		config:        &Config{HandshakeQueueDepth: queueDepth},
 		eventRecorder: &eventRecorder,
 		newConn: func(
 			_ context.Context,
@@ -907,7 +958,10 @@ func TestServerReceiveQueue(t *testing.T) {
 			_ *tls.Config,
 			_ *handshake.TokenGenerator,
 			_ bool,
+			_ AddressValidationInfo,
 			_ time.Duration,
+			_ func(FaultInfo) FaultAction,
+			_ *timerWheel,
 			_ qlogwriter.Trace,
 			_ utils.Logger,
 			_ protocol.Version,
@@ -919,8 +973,15 @@ func TestServerReceiveQueue(t *testing.T) {
 	})
 
 	conn := newUDPConnLocalhost(t)
-	for i := range protocol.MaxServerUnprocessedPackets + 1 {
-		server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), randConnID(8)))
+	var oldest receivedPacket
+	for i := range queueDepth + 1 {
+		p := getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), randConnID(8))
+		if i == 1 {
+			// the first packet is processed immediately, the second one is the oldest packet in the queue
+			p.data = append(p.data, make([]byte, 10)...) // make it distinguishable by its size
+			oldest = p
+		}
+		server.handlePacket(p)
 		// newConn blocks on the acceptConn channel, so this blocks the server's run loop
 		if i == 0 {
 			select {
@@ -931,8 +992,8 @@ func TestServerReceiveQueue(t *testing.T) {
 		}
 	}
 
-	p := getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), randConnID(8))
-	server.handlePacket(p)
+	// the queue is full, the oldest packet is dropped
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), randConnID(8)))
 
 	require.Eventually(t,
 		func() bool { return len(eventRecorder.Events(qlog.PacketDropped{})) > 0 },
@@ -942,7 +1003,7 @@ func TestServerReceiveQueue(t *testing.T) {
 	require.Equal(t,
 		[]qlogwriter.Event{
 			qlog.PacketDropped{
-				Raw:     qlog.RawInfo{Length: int(p.Size())},
+				Raw:     qlog.RawInfo{Length: int(oldest.Size())},
 				Trigger: qlog.PacketDropDOSPrevention,
 			},
 		},
@@ -1385,3 +1446,58 @@ func TestServer0RTTQueueing(t *testing.T) {
 		require.Contains(t, eventRecorder.Events(qlog.PacketDropped{}), event)
 	}
 }
+
+func TestServerConnectionRateLimiting(t *testing.T) {
+	const maxHandshakes = 3
+	var hooks []*connTestHooks
+	for range 2 * maxHandshakes {
+		hooks = append(hooks, &connTestHooks{
+			// handshakes never complete
+			handshakeComplete: func() <-chan struct{} { return make(chan struct{}) },
+		})
+	}
+	recorder := newConnConstructorRecorder(hooks...)
+	var eventRecorder events.Recorder
+	server := newTestServer(t, &serverOpts{
+		eventRecorder:   &eventRecorder,
+		newConn:         recorder.NewConn,
+		connRateLimiter: &SubnetConnectionRateLimiter{MaxHandshakes: maxHandshakes},
+	})
+
+	// a flood of connection attempts from the same /24 subnet
+	for i := range 10 * maxHandshakes {
+		server.handlePacket(getValidInitialPacket(t,
+			&net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(i+1)), Port: 1234},
+			randConnID(6),
+			randConnID(8),
+		))
+	}
+	for range maxHandshakes {
+		select {
+		case <-recorder.Args():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	select {
+	case <-recorder.Args():
+		t.Fatal("should have throttled connection attempts")
+	case <-time.After(scaleDuration(10 * time.Millisecond)):
+	}
+	// throttled connection attempts are refused
+	require.NotEmpty(t, eventRecorder.Events(qlog.PacketSent{}))
+
+	// other subnets are unaffected
+	for i := range maxHandshakes {
+		server.handlePacket(getValidInitialPacket(t,
+			&net.UDPAddr{IP: net.IPv4(10, 0, 1, byte(i+1)), Port: 1234},
+			randConnID(6),
+			randConnID(8),
+		))
+		select {
+		case <-recorder.Args():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
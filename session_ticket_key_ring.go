@@ -0,0 +1,147 @@
+package quic
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// A SessionTicketKey is a key used to encrypt and decrypt TLS session tickets.
+type SessionTicketKey struct {
+	// ID identifies the key. It is sent unencrypted as part of the session ticket,
+	// and allows selecting the key for decryption without trial decryption.
+	ID uint32
+	// Key is the key material, see tls.Config.SetSessionTicketKeys.
+	Key [32]byte
+}
+
+// SessionTicketEventType is the type of a SessionTicketEvent.
+type SessionTicketEventType uint8
+
+const (
+	// SessionTicketIssued is emitted when a new session ticket is issued to a client.
+	SessionTicketIssued SessionTicketEventType = iota + 1
+	// SessionTicketAccepted is emitted when a session ticket presented by a client was decrypted.
+	SessionTicketAccepted
+)
+
+// A SessionTicketEvent is emitted when a session ticket is issued or accepted.
+type SessionTicketEvent struct {
+	Type SessionTicketEventType
+	// KeyID is the ID of the key used to encrypt the session ticket.
+	KeyID uint32
+	// Stale is set if the key was rotated out, i.e. if it is not the key currently used to encrypt new tickets.
+	Stale bool
+}
+
+type sessionTicketKeyRingEntry struct {
+	id   uint32
+	conf *tls.Config // only used for EncryptTicket and DecryptTicket
+}
+
+// A SessionTicketKeyRing encrypts and decrypts TLS session tickets using a set of keys
+// that can be rotated at runtime, for example when the keys are distributed by a central service.
+// Servers that share the same keys can resume each other's sessions, and accept 0-RTT data.
+//
+// Unlike keys set using tls.Config.SetSessionTicketKeys, rotated keys take effect for all copies
+// of the tls.Config, including copies created by this package and by http3.ConfigureTLSConfig.
+type SessionTicketKeyRing struct {
+	// OnEvent is called when a session ticket is issued or accepted.
+	// It can be used to audit the use of stale keys.
+	// It must not be modified after the key ring was used to configure a tls.Config.
+	OnEvent func(SessionTicketEvent)
+
+	mutex sync.RWMutex
+	keys  []sessionTicketKeyRingEntry // the first key is used to encrypt new tickets
+}
+
+// SetKeys sets the keys of the key ring.
+// The first key is used to encrypt new session tickets.
+// All keys are used to decrypt session tickets.
+// It is safe to call SetKeys concurrently with ongoing handshakes.
+func (r *SessionTicketKeyRing) SetKeys(keys []SessionTicketKey) error {
+	if len(keys) == 0 {
+		return errors.New("quic: at least one session ticket key is required")
+	}
+	entries := make([]sessionTicketKeyRingEntry, 0, len(keys))
+	ids := make(map[uint32]struct{}, len(keys))
+	for _, k := range keys {
+		if _, ok := ids[k.ID]; ok {
+			return fmt.Errorf("quic: duplicate session ticket key ID %d", k.ID)
+		}
+		ids[k.ID] = struct{}{}
+		conf := &tls.Config{}
+		conf.SetSessionTicketKeys([][32]byte{k.Key})
+		entries = append(entries, sessionTicketKeyRingEntry{id: k.ID, conf: conf})
+	}
+
+	r.mutex.Lock()
+	r.keys = entries
+	r.mutex.Unlock()
+	return nil
+}
+
+// ConfigureTLSConfig returns a copy of tlsConf that uses the key ring to encrypt and decrypt session tickets.
+// It overwrites the WrapSession and UnwrapSession callbacks of the tls.Config.
+func (r *SessionTicketKeyRing) ConfigureTLSConfig(tlsConf *tls.Config) *tls.Config {
+	conf := tlsConf.Clone()
+	conf.WrapSession = r.wrapSession
+	conf.UnwrapSession = r.unwrapSession
+	return conf
+}
+
+func (r *SessionTicketKeyRing) wrapSession(cs tls.ConnectionState, ss *tls.SessionState) ([]byte, error) {
+	r.mutex.RLock()
+	if len(r.keys) == 0 {
+		r.mutex.RUnlock()
+		return nil, errors.New("quic: no session ticket keys set")
+	}
+	key := r.keys[0]
+	r.mutex.RUnlock()
+
+	ticket, err := key.conf.EncryptTicket(cs, ss)
+	if err != nil {
+		return nil, err
+	}
+	if r.OnEvent != nil {
+		r.OnEvent(SessionTicketEvent{Type: SessionTicketIssued, KeyID: key.id})
+	}
+	b := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(ticket)), key.id)
+	return append(b, ticket...), nil
+}
+
+func (r *SessionTicketKeyRing) unwrapSession(identity []byte, cs tls.ConnectionState) (*tls.SessionState, error) {
+	if len(identity) < 4 {
+		return nil, nil
+	}
+	id := binary.BigEndian.Uint32(identity)
+
+	r.mutex.RLock()
+	var (
+		key   sessionTicketKeyRingEntry
+		found bool
+		stale bool
+	)
+	for i, k := range r.keys {
+		if k.id == id {
+			key, found, stale = k, true, i > 0
+			break
+		}
+	}
+	r.mutex.RUnlock()
+	// If we don't know the key, the ticket is ignored, and a full handshake is performed.
+	if !found {
+		return nil, nil
+	}
+
+	ss, err := key.conf.DecryptTicket(identity[4:], cs)
+	if err != nil || ss == nil {
+		return ss, err
+	}
+	if r.OnEvent != nil {
+		r.OnEvent(SessionTicketEvent{Type: SessionTicketAccepted, KeyID: id, Stale: stale})
+	}
+	return ss, nil
+}
@@ -0,0 +1,113 @@
+package quic
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func handshakeWithSessionTicket(t *testing.T, serverConf, clientConf *tls.Config) tls.ConnectionState {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		conn := tls.Server(serverConn, serverConf)
+		if err := conn.Handshake(); err != nil {
+			errChan <- err
+			return
+		}
+		// the session ticket is sent before this byte
+		_, err := conn.Write([]byte{42})
+		errChan <- err
+	}()
+
+	conn := tls.Client(clientConn, clientConf)
+	require.NoError(t, conn.Handshake())
+	b := make([]byte, 1)
+	_, err := conn.Read(b)
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+	return conn.ConnectionState()
+}
+
+func TestSessionTicketKeyRing(t *testing.T) {
+	var events []SessionTicketEvent
+	ring := &SessionTicketKeyRing{OnEvent: func(e SessionTicketEvent) { events = append(events, e) }}
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 1, Key: [32]byte{1}}}))
+	serverConf := ring.ConfigureTLSConfig(testdata.GetTLSConfig())
+	require.Nil(t, testdata.GetTLSConfig().WrapSession)
+
+	clientConf := &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	require.False(t, handshakeWithSessionTicket(t, serverConf, clientConf).DidResume)
+	require.Equal(t, []SessionTicketEvent{{Type: SessionTicketIssued, KeyID: 1}}, events)
+
+	// rotate the keys, the ticket encrypted with the old key can still be decrypted
+	events = events[:0]
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 2, Key: [32]byte{2}}, {ID: 1, Key: [32]byte{1}}}))
+	require.True(t, handshakeWithSessionTicket(t, serverConf.Clone(), clientConf).DidResume)
+	require.Equal(t,
+		[]SessionTicketEvent{
+			{Type: SessionTicketAccepted, KeyID: 1, Stale: true},
+			{Type: SessionTicketIssued, KeyID: 2},
+		},
+		events,
+	)
+
+	// remove the old key, the new ticket can still be decrypted
+	events = events[:0]
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 2, Key: [32]byte{2}}}))
+	require.True(t, handshakeWithSessionTicket(t, serverConf, clientConf).DidResume)
+	require.Equal(t, SessionTicketEvent{Type: SessionTicketAccepted, KeyID: 2}, events[0])
+
+	// replace the key, the client's ticket can't be decrypted any more
+	events = events[:0]
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 3, Key: [32]byte{3}}}))
+	require.False(t, handshakeWithSessionTicket(t, serverConf, clientConf).DidResume)
+	require.Equal(t, []SessionTicketEvent{{Type: SessionTicketIssued, KeyID: 3}}, events)
+}
+
+func TestSessionTicketKeyRingWrongKey(t *testing.T) {
+	// a key with the same ID, but different key material
+	ring := &SessionTicketKeyRing{}
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 1, Key: [32]byte{1}}}))
+	serverConf := ring.ConfigureTLSConfig(testdata.GetTLSConfig())
+	clientConf := &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	require.False(t, handshakeWithSessionTicket(t, serverConf, clientConf).DidResume)
+
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 1, Key: [32]byte{2}}}))
+	require.False(t, handshakeWithSessionTicket(t, serverConf, clientConf).DidResume)
+}
+
+func TestSessionTicketKeyRingSetKeys(t *testing.T) {
+	ring := &SessionTicketKeyRing{}
+	require.ErrorContains(t, ring.SetKeys(nil), "at least one session ticket key is required")
+	require.ErrorContains(t,
+		ring.SetKeys([]SessionTicketKey{{ID: 1}, {ID: 2}, {ID: 1}}),
+		"duplicate session ticket key ID 1",
+	)
+
+	// tickets that are too short or use an unknown key are ignored
+	require.NoError(t, ring.SetKeys([]SessionTicketKey{{ID: 1}}))
+	ss, err := ring.unwrapSession([]byte{0, 0}, tls.ConnectionState{})
+	require.NoError(t, err)
+	require.Nil(t, ss)
+	ss, err = ring.unwrapSession([]byte{0, 0, 0, 2, 1, 2, 3}, tls.ConnectionState{})
+	require.NoError(t, err)
+	require.Nil(t, ss)
+}
@@ -1,7 +1,9 @@
 package quic
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"testing"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -38,5 +40,11 @@ func TestStatelessResetter(t *testing.T) {
 		rand.Read(b)
 		connID2 := protocol.ParseConnectionID(b)
 		require.NotEqual(t, token, m.GetStatelessResetToken(connID2))
+
+		// the token is derived from the key, such that it can be recomputed after a restart
+		h := hmac.New(sha256.New, key[:])
+		h.Write(connID.Bytes())
+		require.Equal(t, h.Sum(nil)[:16], token[:])
+		require.Equal(t, token, newStatelessResetter(&key).GetStatelessResetToken(connID))
 	})
 }
@@ -14,14 +14,26 @@ import (
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
-type deadlineError struct{}
+// ErrReadDeadlineExceeded and ErrWriteDeadlineExceeded keep the error message
+// of the single deadline error used before.
+type deadlineError struct {
+	op string // makes the two errors distinct values, pointers to zero-size values might be equal
+}
 
 func (deadlineError) Error() string   { return "deadline exceeded" }
 func (deadlineError) Temporary() bool { return true }
 func (deadlineError) Timeout() bool   { return true }
 func (deadlineError) Unwrap() error   { return os.ErrDeadlineExceeded }
 
-var errDeadline net.Error = &deadlineError{}
+var (
+	// ErrReadDeadlineExceeded is returned by Read and Peek when the read deadline is exceeded.
+	// It wraps os.ErrDeadlineExceeded.
+	ErrReadDeadlineExceeded net.Error = &deadlineError{op: "read"}
+	// ErrWriteDeadlineExceeded is returned by Write when the write deadline is exceeded.
+	// Write also returns the number of bytes that were accepted before the deadline.
+	// It wraps os.ErrDeadlineExceeded.
+	ErrWriteDeadlineExceeded net.Error = &deadlineError{op: "write"}
+)
 
 // The streamSender is notified by the stream about various events.
 type streamSender interface {
@@ -117,6 +129,15 @@ func (s *Stream) Read(p []byte) (int, error) {
 	return s.receiveStr.Read(p)
 }
 
+// ReadAvailable reads the stream data that is available right now, without blocking.
+// If no data is available, it returns 0 and a nil error.
+// Once all data has been read, it returns [io.EOF].
+// If the stream was canceled, the error is a [StreamError].
+// The read deadline doesn't apply to ReadAvailable.
+func (s *Stream) ReadAvailable(p []byte) (int, error) {
+	return s.receiveStr.ReadAvailable(p)
+}
+
 // Peek fills b with stream data, without consuming the stream data.
 // It blocks until len(b) bytes are available, or an error occurs.
 // It respects the stream deadline set by SetReadDeadline.
@@ -133,6 +154,12 @@ func (s *Stream) Write(p []byte) (int, error) {
 	return s.sendStr.Write(p)
 }
 
+// WriteAndClose writes data to the stream and closes the send-direction of the stream.
+// See [SendStream.WriteAndClose] for more details.
+func (s *Stream) WriteAndClose(p []byte) (int, error) {
+	return s.sendStr.WriteAndClose(p)
+}
+
 // SetReliableBoundary marks the data written to this stream so far as reliable.
 // It is valid to call this function multiple times, thereby increasing the reliable size.
 // It only has an effect if the peer enabled support for the RESET_STREAM_AT extension,
@@ -141,6 +168,30 @@ func (s *Stream) SetReliableBoundary() {
 	s.sendStr.SetReliableBoundary()
 }
 
+// Flushed returns the offset up to which stream data has been sent.
+// See [SendStream.Flushed] for more details.
+func (s *Stream) Flushed() uint64 {
+	return s.sendStr.Flushed()
+}
+
+// Acked returns the offset up to which stream data was acknowledged by the peer.
+// See [SendStream.Acked] for more details.
+func (s *Stream) Acked() uint64 {
+	return s.sendStr.Acked()
+}
+
+// WriteLatency returns the average time from a Write call until the acknowledgement of the data.
+// See [SendStream.WriteLatency] for more details.
+func (s *Stream) WriteLatency() time.Duration {
+	return s.sendStr.WriteLatency()
+}
+
+// WriteLatencyPercentile returns a percentile of the time from a Write call until the acknowledgement of the data.
+// See [SendStream.WriteLatencyPercentile] for more details.
+func (s *Stream) WriteLatencyPercentile(p float64) time.Duration {
+	return s.sendStr.WriteLatencyPercentile(p)
+}
+
 // CancelWrite aborts sending on this stream.
 // See [SendStream.CancelWrite] for more details.
 func (s *Stream) CancelWrite(errorCode StreamErrorCode) {
@@ -153,6 +204,34 @@ func (s *Stream) CancelRead(errorCode StreamErrorCode) {
 	s.receiveStr.CancelRead(errorCode)
 }
 
+// SetAckLatencyHint sets how quickly data received on this stream needs to be acknowledged.
+// ACKs are sent for the connection as a whole, so a packet carrying data for any other stream
+// is acknowledged as quickly as usual.
+func (s *Stream) SetAckLatencyHint(hint AckLatencyHint) {
+	s.receiveStr.SetAckLatencyHint(hint)
+}
+
+// Stats returns statistics about the receive side of the stream.
+// See [ReceiveStream.Stats] for more details.
+func (s *Stream) Stats() StreamStats {
+	return s.receiveStr.Stats()
+}
+
+// FlowControlState returns the current flow control offsets of the stream.
+func (s *Stream) FlowControlState() FlowControlState {
+	// send and receive side share the same flow controller
+	s.sendStr.mutex.Lock()
+	fc := s.sendStr.flowController
+	s.sendStr.mutex.Unlock()
+	sendWindow, bytesSent, receiveWindow, bytesRead := fc.Offsets()
+	return FlowControlState{
+		SendLimit:     uint64(sendWindow),
+		BytesSent:     uint64(bytesSent),
+		ReceiveLimit:  uint64(receiveWindow),
+		BytesConsumed: uint64(bytesRead),
+	}
+}
+
 // The Context is canceled as soon as the write-side of the stream is closed.
 // See [SendStream.Context] for more details.
 func (s *Stream) Context() context.Context {
@@ -169,6 +248,12 @@ func (s *Stream) handleResetStreamFrame(frame *wire.ResetStreamFrame, rcvTime mo
 	return s.receiveStr.handleResetStreamFrame(frame, rcvTime)
 }
 
+func (s *Stream) isAckLatencyTolerant() bool { return s.receiveStr.isAckLatencyTolerant() }
+
+func (s *Stream) checkOutOfOrderLimit(f *wire.StreamFrame, pending protocol.ByteCount) (protocol.ByteCount, bool) {
+	return s.receiveStr.checkOutOfOrderLimit(f, pending)
+}
+
 func (s *Stream) handleStreamFrame(frame *wire.StreamFrame, rcvTime monotime.Time) error {
 	return s.receiveStr.handleStreamFrame(frame, rcvTime)
 }
@@ -185,6 +270,10 @@ func (s *Stream) enableResetStreamAt() {
 	s.sendStr.enableResetStreamAt()
 }
 
+func (s *Stream) hasRetransmission() bool {
+	return s.sendStr.hasRetransmission()
+}
+
 func (s *Stream) popStreamFrame(maxBytes protocol.ByteCount, v protocol.Version) (_ ackhandler.StreamFrame, _ *wire.StreamDataBlockedFrame, hasMore bool) {
 	return s.sendStr.popStreamFrame(maxBytes, v)
 }
@@ -225,6 +314,35 @@ func (s *Stream) closeForShutdown(err error) {
 	s.receiveStr.closeForShutdown(err)
 }
 
+// Enable0RTTRetry marks the data written to this stream as safe to retry if the server rejects 0-RTT.
+// See [SendStream.Enable0RTTRetry] for more details.
+func (s *Stream) Enable0RTTRetry() {
+	s.sendStr.Enable0RTTRetry()
+}
+
+// retriesOn0RTTRejection says if the stream is restarted (instead of being closed) if the server rejects 0-RTT.
+func (s *Stream) retriesOn0RTTRejection() bool {
+	return s.sendStr.retriesOn0RTTRejection()
+}
+
+// disable0RTTRetry is called when the handshake completes.
+func (s *Stream) disable0RTTRetry() {
+	s.sendStr.disable0RTTRetry()
+}
+
+// restartAfter0RTTRejection restarts the stream after the server rejected 0-RTT.
+// Both stream halves use the new flow controller.
+// It returns false if the stream can't be restarted, see [SendStream.restartAfter0RTTRejection].
+func (s *Stream) restartAfter0RTTRejection(fc flowcontrol.StreamFlowController) bool {
+	if !s.sendStr.restartAfter0RTTRejection(fc) {
+		return false
+	}
+	s.receiveStr.mutex.Lock()
+	s.receiveStr.flowController = fc
+	s.receiveStr.mutex.Unlock()
+	return true
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
 func (s *Stream) checkIfCompleted() {
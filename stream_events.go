@@ -0,0 +1,135 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// StreamEventType is the type of a StreamEvent.
+type StreamEventType uint8
+
+const (
+	// StreamEventOpened is emitted when a stream is opened, either by the application or by the peer.
+	StreamEventOpened StreamEventType = iota
+	// StreamEventClosed is emitted when a stream is completed, i.e. when all state for the stream was released.
+	StreamEventClosed
+	// StreamEventDataSent is emitted when a packet containing a STREAM frame is sent.
+	// This includes retransmissions.
+	StreamEventDataSent
+	// StreamEventDataReceived is emitted when a STREAM frame is received.
+	StreamEventDataReceived
+	// StreamEventBlocked is emitted when sending on a stream is blocked by the peer's flow control limit.
+	// The Offset is the flow control limit.
+	StreamEventBlocked
+	// StreamEventUnblocked is emitted when the peer increased the flow control limit of a blocked stream.
+	// The Offset is the new flow control limit.
+	StreamEventUnblocked
+)
+
+func (t StreamEventType) String() string {
+	switch t {
+	case StreamEventOpened:
+		return "opened"
+	case StreamEventClosed:
+		return "closed"
+	case StreamEventDataSent:
+		return "data sent"
+	case StreamEventDataReceived:
+		return "data received"
+	case StreamEventBlocked:
+		return "blocked"
+	case StreamEventUnblocked:
+		return "unblocked"
+	default:
+		return "unknown stream event"
+	}
+}
+
+// A StreamEvent is passed to Config.StreamEventHook.
+type StreamEvent struct {
+	Type     StreamEventType
+	StreamID StreamID
+	// Offset and Length describe the stream data of StreamEventDataSent and StreamEventDataReceived.
+	Offset    uint64
+	Length    uint64
+	Timestamp time.Time
+}
+
+// The streamEventEmitter calls the Config.StreamEventHook.
+// It is only created if the hook is enabled.
+type streamEventEmitter struct {
+	hook func(StreamEvent)
+
+	mutex sync.Mutex
+	// streams that are blocked on flow control, and their flow control limit
+	blocked map[protocol.StreamID]protocol.ByteCount
+}
+
+func newStreamEventEmitter(hook func(StreamEvent)) *streamEventEmitter {
+	return &streamEventEmitter{
+		hook:    hook,
+		blocked: make(map[protocol.StreamID]protocol.ByteCount),
+	}
+}
+
+func (e *streamEventEmitter) emit(typ StreamEventType, id protocol.StreamID, offset, length protocol.ByteCount) {
+	e.hook(StreamEvent{
+		Type:      typ,
+		StreamID:  id,
+		Offset:    uint64(offset),
+		Length:    uint64(length),
+		Timestamp: time.Now(),
+	})
+}
+
+func (e *streamEventEmitter) Opened(id protocol.StreamID) { e.emit(StreamEventOpened, id, 0, 0) }
+
+func (e *streamEventEmitter) Closed(id protocol.StreamID) {
+	e.mutex.Lock()
+	delete(e.blocked, id)
+	e.mutex.Unlock()
+	e.emit(StreamEventClosed, id, 0, 0)
+}
+
+func (e *streamEventEmitter) ReceivedStreamFrame(f *wire.StreamFrame) {
+	e.emit(StreamEventDataReceived, f.StreamID, f.Offset, f.DataLen())
+}
+
+func (e *streamEventEmitter) ReceivedMaxStreamDataFrame(f *wire.MaxStreamDataFrame) {
+	e.mutex.Lock()
+	limit, ok := e.blocked[f.StreamID]
+	if !ok || f.MaximumStreamData <= limit {
+		e.mutex.Unlock()
+		return
+	}
+	delete(e.blocked, f.StreamID)
+	e.mutex.Unlock()
+	e.emit(StreamEventUnblocked, f.StreamID, f.MaximumStreamData, 0)
+}
+
+// SentPacket emits the events for the STREAM and STREAM_DATA_BLOCKED frames in a sent packet.
+func (e *streamEventEmitter) SentPacket(streamFrames []ackhandler.StreamFrame, frames []ackhandler.Frame) {
+	for _, f := range streamFrames {
+		e.emit(StreamEventDataSent, f.Frame.StreamID, f.Frame.Offset, f.Frame.DataLen())
+	}
+	for _, f := range frames {
+		blocked, ok := f.Frame.(*wire.StreamDataBlockedFrame)
+		if !ok {
+			continue
+		}
+		e.mutex.Lock()
+		_, isBlocked := e.blocked[blocked.StreamID]
+		if !isBlocked {
+			e.blocked[blocked.StreamID] = blocked.MaximumStreamData
+		}
+		e.mutex.Unlock()
+		if isBlocked { // retransmission
+			continue
+		}
+		e.emit(StreamEventBlocked, blocked.StreamID, blocked.MaximumStreamData, 0)
+	}
+}
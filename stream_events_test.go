@@ -0,0 +1,54 @@
+package quic
+
+import (
+	"testing"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamEventEmitter(t *testing.T) {
+	var events []StreamEvent
+	e := newStreamEventEmitter(func(ev StreamEvent) { events = append(events, ev) })
+
+	e.Opened(4)
+	e.ReceivedStreamFrame(&wire.StreamFrame{StreamID: 4, Offset: 100, Data: make([]byte, 42)})
+	e.SentPacket(
+		[]ackhandler.StreamFrame{{Frame: &wire.StreamFrame{StreamID: 4, Offset: 200, Data: make([]byte, 10)}}},
+		[]ackhandler.Frame{
+			{Frame: &wire.PingFrame{}},
+			{Frame: &wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 210}},
+		},
+	)
+	// retransmissions of the STREAM_DATA_BLOCKED frame don't emit another event
+	e.SentPacket(nil, []ackhandler.Frame{{Frame: &wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 210}}})
+	// MAX_STREAM_DATA frames that don't increase the limit don't unblock the stream
+	e.ReceivedMaxStreamDataFrame(&wire.MaxStreamDataFrame{StreamID: 4, MaximumStreamData: 210})
+	e.ReceivedMaxStreamDataFrame(&wire.MaxStreamDataFrame{StreamID: 4, MaximumStreamData: 1000})
+	// MAX_STREAM_DATA frames for streams that are not blocked are ignored
+	e.ReceivedMaxStreamDataFrame(&wire.MaxStreamDataFrame{StreamID: 8, MaximumStreamData: 1000})
+	e.Closed(4)
+
+	type event struct {
+		Type     StreamEventType
+		StreamID protocol.StreamID
+		Offset   uint64
+		Length   uint64
+	}
+	var got []event
+	for _, ev := range events {
+		require.False(t, ev.Timestamp.IsZero())
+		got = append(got, event{Type: ev.Type, StreamID: ev.StreamID, Offset: ev.Offset, Length: ev.Length})
+	}
+	require.Equal(t, []event{
+		{Type: StreamEventOpened, StreamID: 4},
+		{Type: StreamEventDataReceived, StreamID: 4, Offset: 100, Length: 42},
+		{Type: StreamEventDataSent, StreamID: 4, Offset: 200, Length: 10},
+		{Type: StreamEventBlocked, StreamID: 4, Offset: 210},
+		{Type: StreamEventUnblocked, StreamID: 4, Offset: 1000},
+		{Type: StreamEventClosed, StreamID: 4},
+	}, got)
+}
@@ -27,12 +27,18 @@ func TestStreamDeadlines(t *testing.T) {
 	str.SetDeadline(time.Now().Add(-time.Second))
 	n, err := (&writerWithTimeout{Writer: str, Timeout: time.Second}).Write([]byte("foobar"))
 	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.ErrorIs(t, err, ErrWriteDeadlineExceeded)
+	require.NotErrorIs(t, err, ErrReadDeadlineExceeded)
+	require.EqualError(t, err, "deadline exceeded")
 	require.Zero(t, n)
 
 	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false, gomock.Any()).AnyTimes()
 	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")}, monotime.Now()))
 	n, err = (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(make([]byte, 6))
 	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.ErrorIs(t, err, ErrReadDeadlineExceeded)
+	require.NotErrorIs(t, err, ErrWriteDeadlineExceeded)
+	require.EqualError(t, err, "deadline exceeded")
 	require.Zero(t, n)
 }
 
@@ -1,14 +1,19 @@
 package quic
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -19,6 +24,18 @@ type StreamLimitReachedError struct{}
 
 func (e StreamLimitReachedError) Error() string { return "too many open streams" }
 
+// ErrStreamIDExhausted is returned from Conn.OpenStream(Sync) and Conn.OpenUniStream(Sync)
+// when all 2^60 stream IDs of the respective stream type have been used on this connection.
+// Unlike StreamLimitReachedError, this condition is permanent: the peer can't grant any more streams.
+var ErrStreamIDExhausted = errors.New("stream IDs exhausted")
+
+// unacceptedStreamsLimit limits the number of incoming streams waiting to be accepted by the application.
+type unacceptedStreamsLimit struct {
+	maxStreams uint64 // if 0, the number of streams is only limited by the stream limit
+	action     UnacceptedStreamsOverflowAction
+	errorCode  qerr.StreamErrorCode
+}
+
 type streamsMap struct {
 	ctx         context.Context // not used for cancellations, but carries the values associated with the connection
 	perspective protocol.Perspective
@@ -37,6 +54,24 @@ type streamsMap struct {
 	incomingUniStreams    *incomingStreamsMap[*ReceiveStream]
 	reset                 bool
 	supportsResetStreamAt bool
+	readAfterClose        bool
+	ignoreLateResets      bool
+	maxSendBuffer         atomic.Int64 // protocol.ByteCount, can be reduced under memory pressure
+	outOfOrderLimit       outOfOrderBufferLimit
+	unacceptedLimit       unacceptedStreamsLimit
+	// If set, the limits for incoming streams are increased proactively.
+	streamLimitRTTStats *utils.RTTStats
+	// If set, called for every new stream.
+	onStreamOpened func(protocol.StreamID)
+
+	bidiCounts, uniCounts streamCounter
+
+	// zeroRTTRetryDone is set once the client's handshake completed.
+	// Streams opened after that can't have been sent in 0-RTT packets.
+	zeroRTTRetryDone atomic.Bool
+	// streams that are retried in 1-RTT packets after 0-RTT was rejected
+	retryBidiStreams map[protocol.StreamID]*Stream
+	retryUniStreams  map[protocol.StreamID]*SendStream
 }
 
 func newStreamsMap(
@@ -47,6 +82,12 @@ func newStreamsMap(
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
+	readAfterClose bool,
+	ignoreLateResets bool,
+	maxSendBuffer protocol.ByteCount,
+	outOfOrderLimit outOfOrderBufferLimit,
+	unacceptedLimit unacceptedStreamsLimit,
+	streamLimitRTTStats *utils.RTTStats,
 ) *streamsMap {
 	m := &streamsMap{
 		ctx:                    ctx,
@@ -56,7 +97,13 @@ func newStreamsMap(
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
 		sender:                 sender,
+		readAfterClose:         readAfterClose,
+		ignoreLateResets:       ignoreLateResets,
+		outOfOrderLimit:        outOfOrderLimit,
+		unacceptedLimit:        unacceptedLimit,
+		streamLimitRTTStats:    streamLimitRTTStats,
 	}
+	m.maxSendBuffer.Store(int64(maxSendBuffer))
 	m.initMaps()
 	return m
 }
@@ -65,7 +112,15 @@ func (m *streamsMap) initMaps() {
 	m.outgoingBidiStreams = newOutgoingStreamsMap(
 		protocol.StreamTypeBidi,
 		func(id protocol.StreamID) *Stream {
-			return newStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str := newStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str.receiveStr.readAfterShutdown = m.readAfterClose
+			str.receiveStr.ignoreLateResets = m.ignoreLateResets
+			str.receiveStr.outOfOrderLimit = m.outOfOrderLimit
+			str.sendStr.maxSendBuffer = protocol.ByteCount(m.maxSendBuffer.Load())
+			str.sendStr.ignoreLateStopSending = m.ignoreLateResets
+			str.sendStr.zeroRTTRetryAllowed = m.zeroRTTRetryAllowed()
+			m.streamOpened(id)
+			return str
 		},
 		m.queueControlFrame,
 		m.perspective,
@@ -73,16 +128,36 @@ func (m *streamsMap) initMaps() {
 	m.incomingBidiStreams = newIncomingStreamsMap(
 		protocol.StreamTypeBidi,
 		func(id protocol.StreamID) *Stream {
-			return newStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str := newStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str.receiveStr.readAfterShutdown = m.readAfterClose
+			str.receiveStr.ignoreLateResets = m.ignoreLateResets
+			str.receiveStr.outOfOrderLimit = m.outOfOrderLimit
+			str.sendStr.maxSendBuffer = protocol.ByteCount(m.maxSendBuffer.Load())
+			str.sendStr.ignoreLateStopSending = m.ignoreLateResets
+			m.streamOpened(id)
+			return str
 		},
 		m.maxIncomingBidiStreams,
 		m.queueControlFrame,
 		m.perspective,
 	)
+	m.incomingBidiStreams.rttStats = m.streamLimitRTTStats
+	m.incomingBidiStreams.maxUnaccepted = m.unacceptedLimit.maxStreams
+	if m.unacceptedLimit.action == UnacceptedStreamsOverflowReset {
+		m.incomingBidiStreams.rejectStream = func(str *Stream) {
+			str.CancelRead(m.unacceptedLimit.errorCode)
+			str.CancelWrite(m.unacceptedLimit.errorCode)
+		}
+	}
 	m.outgoingUniStreams = newOutgoingStreamsMap(
 		protocol.StreamTypeUni,
 		func(id protocol.StreamID) *SendStream {
-			return newSendStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str := newSendStream(m.ctx, id, m.sender, m.newFlowController(id), m.supportsResetStreamAt)
+			str.maxSendBuffer = protocol.ByteCount(m.maxSendBuffer.Load())
+			str.ignoreLateStopSending = m.ignoreLateResets
+			str.zeroRTTRetryAllowed = m.zeroRTTRetryAllowed()
+			m.streamOpened(id)
+			return str
 		},
 		m.queueControlFrame,
 		m.perspective,
@@ -90,12 +165,46 @@ func (m *streamsMap) initMaps() {
 	m.incomingUniStreams = newIncomingStreamsMap(
 		protocol.StreamTypeUni,
 		func(id protocol.StreamID) *ReceiveStream {
-			return newReceiveStream(id, m.sender, m.newFlowController(id))
+			str := newReceiveStream(id, m.sender, m.newFlowController(id))
+			str.readAfterShutdown = m.readAfterClose
+			str.ignoreLateResets = m.ignoreLateResets
+			str.outOfOrderLimit = m.outOfOrderLimit
+			m.streamOpened(id)
+			return str
 		},
 		m.maxIncomingUniStreams,
 		m.queueControlFrame,
 		m.perspective,
 	)
+	m.incomingUniStreams.rttStats = m.streamLimitRTTStats
+	m.incomingUniStreams.maxUnaccepted = m.unacceptedLimit.maxStreams
+	if m.unacceptedLimit.action == UnacceptedStreamsOverflowReset {
+		m.incomingUniStreams.rejectStream = func(str *ReceiveStream) {
+			str.CancelRead(m.unacceptedLimit.errorCode)
+		}
+	}
+}
+
+// A streamCounter counts the streams of one stream type.
+type streamCounter struct {
+	active atomic.Uint64
+	total  atomic.Uint64
+}
+
+func (m *streamsMap) counter(t protocol.StreamType) *streamCounter {
+	if t == protocol.StreamTypeBidi {
+		return &m.bidiCounts
+	}
+	return &m.uniCounts
+}
+
+func (m *streamsMap) streamOpened(id protocol.StreamID) {
+	c := m.counter(id.Type())
+	c.active.Add(1)
+	c.total.Add(1)
+	if m.onStreamOpened != nil {
+		m.onStreamOpened(id)
+	}
 }
 
 func (m *streamsMap) OpenStream() (*Stream, error) {
@@ -165,6 +274,14 @@ func (m *streamsMap) AcceptUniStream(ctx context.Context) (*ReceiveStream, error
 }
 
 func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
+	if err := m.deleteStream(id); err != nil {
+		return err
+	}
+	m.counter(id.Type()).active.Add(^uint64(0))
+	return nil
+}
+
+func (m *streamsMap) deleteStream(id protocol.StreamID) error {
 	switch id.Type() {
 	case protocol.StreamTypeUni:
 		if id.InitiatedBy() == m.perspective {
@@ -253,6 +370,8 @@ func (m *streamsMap) HandleStopSendingFrame(f *wire.StopSendingFrame) error {
 type receiveStreamFrameHandler interface {
 	handleResetStreamFrame(*wire.ResetStreamFrame, monotime.Time) error
 	handleStreamFrame(*wire.StreamFrame, monotime.Time) error
+	isAckLatencyTolerant() bool
+	checkOutOfOrderLimit(*wire.StreamFrame, protocol.ByteCount) (protocol.ByteCount, bool)
 }
 
 func (m *streamsMap) getReceiveStream(id protocol.StreamID) (receiveStreamFrameHandler, error) {
@@ -317,6 +436,33 @@ func (m *streamsMap) HandleStreamFrame(f *wire.StreamFrame, rcvTime monotime.Tim
 	return str.handleStreamFrame(f, rcvTime)
 }
 
+// DropsOutOfOrderData says if packets containing STREAM frames exceeding the out-of-order buffer limit are dropped.
+func (m *streamsMap) DropsOutOfOrderData() bool {
+	return m.outOfOrderLimit.maxBytes > 0 && m.outOfOrderLimit.action == OutOfOrderBufferOverflowDrop
+}
+
+// CheckOutOfOrderLimit says if the STREAM frame would be dropped because it exceeds the out-of-order buffer limit,
+// assuming that pending bytes of out-of-order data for the same stream precede it in the same packet.
+// It returns the number of bytes the frame adds to the out-of-order data.
+// Errors are ignored, they are returned when the frame is handled.
+func (m *streamsMap) CheckOutOfOrderLimit(f *wire.StreamFrame, pending protocol.ByteCount) (outOfOrder protocol.ByteCount, drop bool) {
+	str, err := m.getReceiveStream(f.StreamID)
+	if err != nil || str == nil {
+		return 0, false
+	}
+	return str.checkOutOfOrderLimit(f, pending)
+}
+
+// IsAckLatencyTolerant says if the application marked the receive stream as tolerant to ACK latency,
+// see ReceiveStream.SetAckLatencyHint.
+func (m *streamsMap) IsAckLatencyTolerant(id protocol.StreamID) bool {
+	str, err := m.getReceiveStream(id)
+	if err != nil || str == nil {
+		return false
+	}
+	return str.isAckLatencyTolerant()
+}
+
 func (m *streamsMap) HandleTransportParameters(p *wire.TransportParameters) {
 	m.supportsResetStreamAt = p.EnableResetStreamAt
 	m.outgoingBidiStreams.EnableResetStreamAt()
@@ -327,11 +473,141 @@ func (m *streamsMap) HandleTransportParameters(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum.StreamID(protocol.StreamTypeUni, m.perspective))
 }
 
+// HandleHandshakeComplete is called by the client when the handshake completes,
+// after the server's transport parameters were applied.
+// At this point, we know if the server accepted 0-RTT.
+func (m *streamsMap) HandleHandshakeComplete() {
+	m.restoreStreamsAfter0RTTRejection()
+	m.zeroRTTRetryDone.Store(true)
+	m.outgoingBidiStreams.forEach(func(str *Stream) { str.disable0RTTRetry() })
+	m.outgoingUniStreams.forEach(func(str *SendStream) { str.disable0RTTRetry() })
+}
+
+func (m *streamsMap) zeroRTTRetryAllowed() bool {
+	return m.perspective == protocol.PerspectiveClient && !m.zeroRTTRetryDone.Load()
+}
+
+// restoreStreamsAfter0RTTRejection adds the streams that are retried after 0-RTT was rejected
+// to the streams maps. The data sent on these streams is retransmitted in 1-RTT packets.
+func (m *streamsMap) restoreStreamsAfter0RTTRejection() {
+	if m.retryBidiStreams == nil && m.retryUniStreams == nil {
+		return
+	}
+	skippedBidi := m.outgoingBidiStreams.restoreStreams(m.retryBidiStreams, func(str *Stream) bool {
+		if !str.restartAfter0RTTRejection(m.newFlowController(str.StreamID())) {
+			return false
+		}
+		if m.supportsResetStreamAt {
+			str.enableResetStreamAt()
+		}
+		return true
+	})
+	skippedUni := m.outgoingUniStreams.restoreStreams(m.retryUniStreams, func(str *SendStream) bool {
+		if !str.restartAfter0RTTRejection(m.newFlowController(str.StreamID())) {
+			return false
+		}
+		if m.supportsResetStreamAt {
+			str.enableResetStreamAt()
+		}
+		return true
+	})
+	m.retryBidiStreams = nil
+	m.retryUniStreams = nil
+	// The server never saw the streams that were not retried,
+	// but they are implicitly opened by the streams that were retried.
+	for _, str := range skippedBidi {
+		str.CancelWrite(0)
+		str.CancelRead(0)
+	}
+	for _, str := range skippedUni {
+		str.CancelWrite(0)
+	}
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
 	m.incomingBidiStreams.CloseWithError(err)
 	m.incomingUniStreams.CloseWithError(err)
+	for _, str := range m.retryBidiStreams {
+		str.closeForShutdown(err)
+	}
+	for _, str := range m.retryUniStreams {
+		str.closeForShutdown(err)
+	}
+}
+
+// UnreadData returns the data that the application hadn't read on the open receive streams
+// at the time CloseWithError was called.
+func (m *streamsMap) UnreadData() []UnreadStreamData {
+	var unread []UnreadStreamData
+	m.outgoingBidiStreams.forEach(func(str *Stream) { unread = append(unread, str.receiveStr.unreadData()) })
+	m.incomingBidiStreams.forEach(func(str *Stream) { unread = append(unread, str.receiveStr.unreadData()) })
+	m.incomingUniStreams.forEach(func(str *ReceiveStream) { unread = append(unread, str.unreadData()) })
+	slices.SortFunc(unread, func(a, b UnreadStreamData) int { return cmp.Compare(a.StreamID, b.StreamID) })
+	return unread
+}
+
+// SetMaxSendBuffer changes the send buffer limit for all open and all new streams.
+// It must only be called if a limit was configured.
+func (m *streamsMap) SetMaxSendBuffer(limit protocol.ByteCount) {
+	m.maxSendBuffer.Store(int64(limit))
+
+	m.mutex.Lock()
+	outgoingBidiStreams := m.outgoingBidiStreams
+	outgoingUniStreams := m.outgoingUniStreams
+	incomingBidiStreams := m.incomingBidiStreams
+	m.mutex.Unlock()
+
+	outgoingBidiStreams.forEach(func(str *Stream) { str.sendStr.setMaxSendBuffer(limit) })
+	outgoingUniStreams.forEach(func(str *SendStream) { str.setMaxSendBuffer(limit) })
+	incomingBidiStreams.forEach(func(str *Stream) { str.sendStr.setMaxSendBuffer(limit) })
+}
+
+// OutOfOrderBytes returns the number of bytes buffered beyond a gap in the received data,
+// summed over all open receive streams.
+func (m *streamsMap) OutOfOrderBytes() uint64 {
+	m.mutex.Lock()
+	outgoingBidiStreams := m.outgoingBidiStreams
+	incomingBidiStreams := m.incomingBidiStreams
+	incomingUniStreams := m.incomingUniStreams
+	m.mutex.Unlock()
+
+	var n uint64
+	outgoingBidiStreams.forEach(func(str *Stream) { n += str.receiveStr.Stats().OutOfOrderBytes })
+	incomingBidiStreams.forEach(func(str *Stream) { n += str.receiveStr.Stats().OutOfOrderBytes })
+	incomingUniStreams.forEach(func(str *ReceiveStream) { n += str.Stats().OutOfOrderBytes })
+	return n
+}
+
+// UnacceptedStreams returns the number of incoming streams that haven't been accepted yet,
+// and the time when the oldest of these streams was opened.
+func (m *streamsMap) UnacceptedStreams() (num uint64, oldest monotime.Time) {
+	m.mutex.Lock()
+	incomingBidiStreams := m.incomingBidiStreams
+	incomingUniStreams := m.incomingUniStreams
+	m.mutex.Unlock()
+
+	numBidi, oldestBidi := incomingBidiStreams.Unaccepted()
+	numUni, oldestUni := incomingUniStreams.Unaccepted()
+	switch {
+	case numBidi == 0:
+		return numUni, oldestUni
+	case numUni == 0:
+		return numBidi, oldestBidi
+	default:
+		return numBidi + numUni, min(oldestBidi, oldestUni)
+	}
+}
+
+// StreamCounts returns the number of active streams, and the number of streams opened so far.
+func (m *streamsMap) StreamCounts() StreamCounts {
+	return StreamCounts{
+		ActiveBidi: m.bidiCounts.active.Load(),
+		ActiveUni:  m.uniCounts.active.Load(),
+		TotalBidi:  m.bidiCounts.total.Load(),
+		TotalUni:   m.uniCounts.total.Load(),
+	}
 }
 
 // ResetFor0RTT resets is used when 0-RTT is rejected. In that case, the streams maps are
@@ -339,11 +615,23 @@ func (m *streamsMap) CloseWithError(err error) {
 // 2. reset to their initial state, such that we can immediately process new incoming stream data.
 // Afterwards, calls to Open{Uni}Stream{Sync} / Accept{Uni}Stream will continue to return the error,
 // until UseResetMaps() has been called.
+// Streams for which 0-RTT retries were enabled are not closed, see SendStream.Enable0RTTRetry.
 func (m *streamsMap) ResetFor0RTT() {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.reset = true
+	// Streams that are retried are not closed, they are added to the new maps
+	// once the handshake completes and the new transport parameters are applied.
+	retryBidiStreams := m.outgoingBidiStreams.removeStreams(func(str *Stream) bool { return str.retriesOn0RTTRejection() })
+	retryUniStreams := m.outgoingUniStreams.removeStreams(func(str *SendStream) bool { return str.retriesOn0RTTRejection() })
 	m.CloseWithError(Err0RTTRejected)
+	// The streams that are not retried are discarded, without being deleted.
+	m.outgoingBidiStreams.forEach(func(*Stream) { m.bidiCounts.active.Add(^uint64(0)) })
+	m.outgoingUniStreams.forEach(func(*SendStream) { m.uniCounts.active.Add(^uint64(0)) })
+	m.incomingBidiStreams.forEach(func(*Stream) { m.bidiCounts.active.Add(^uint64(0)) })
+	m.incomingUniStreams.forEach(func(*ReceiveStream) { m.uniCounts.active.Add(^uint64(0)) })
+	m.retryBidiStreams = retryBidiStreams
+	m.retryUniStreams = retryUniStreams
 	m.initMaps()
 }
 
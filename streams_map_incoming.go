@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -19,6 +22,10 @@ type incomingStream interface {
 type incomingStreamEntry[T incomingStream] struct {
 	stream       T
 	shouldDelete bool
+	// rejected is set if the stream was reset since it exceeded the limit of unaccepted streams.
+	// Rejected streams are never returned by AcceptStream.
+	rejected bool
+	openedAt monotime.Time
 }
 
 type incomingStreamsMap[T incomingStream] struct {
@@ -36,6 +43,21 @@ type incomingStreamsMap[T incomingStream] struct {
 	newStream        func(protocol.StreamID) T
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
 
+	// If set, no additional streams are allowed while this many streams are waiting to be accepted.
+	maxUnaccepted uint64
+	// If set, streams exceeding maxUnaccepted are passed to this function, and never accepted.
+	// This can happen if the initial stream limit is higher than maxUnaccepted.
+	rejectStream func(T)
+
+	// If set, the stream limit is increased proactively,
+	// based on the rate at which the peer's streams are closed.
+	rttStats *utils.RTTStats
+	// smoothed interval between two stream closes
+	closeInterval time.Duration
+	lastCloseTime monotime.Time
+	// the number of streams that are expected to be closed within the next RTT, see predictedStreamCloses
+	predictedCloses uint64
+
 	closeErr error
 }
 
@@ -108,10 +130,37 @@ func (m *incomingStreamsMap[T]) AcceptStream(ctx context.Context) (T, error) {
 			return *new(T), err
 		}
 	}
+	if m.maxUnaccepted > 0 {
+		if err := m.skipRejectedStreams(); err != nil {
+			m.mutex.Unlock()
+			return *new(T), err
+		}
+		// accepting the stream might allow us to grant the peer additional streams
+		m.maybeQueueMaxStreams()
+	}
 	m.mutex.Unlock()
 	return entry.stream, nil
 }
 
+// skipRejectedStreams advances nextStreamToAccept past rejected streams.
+// Rejected streams always follow a stream that is not rejected,
+// so it's sufficient to call this function after a stream was accepted.
+func (m *incomingStreamsMap[T]) skipRejectedStreams() error {
+	for {
+		id := m.nextStreamToAccept
+		entry, ok := m.streams[id]
+		if !ok || !entry.rejected {
+			return nil
+		}
+		m.nextStreamToAccept += 4
+		if entry.shouldDelete {
+			if err := m.deleteStream(id); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (m *incomingStreamsMap[T]) GetOrOpenStream(id protocol.StreamID) (T, error) {
 	m.mutex.RLock()
 	if id > m.maxStream {
@@ -139,8 +188,15 @@ func (m *incomingStreamsMap[T]) GetOrOpenStream(id protocol.StreamID) (T, error)
 	// no need to check the two error conditions from above again
 	// * maxStream can only increase, so if the id was valid before, it definitely is valid now
 	// * highestStream is only modified by this function
+	var rejected []T
+	now := monotime.Now()
 	for newNum := m.nextStreamToOpen; newNum <= id; newNum += 4 {
-		m.streams[newNum] = incomingStreamEntry[T]{stream: m.newStream(newNum)}
+		entry := incomingStreamEntry[T]{stream: m.newStream(newNum), openedAt: now}
+		if m.rejectStream != nil && m.maxUnaccepted > 0 && uint64(newNum-m.nextStreamToAccept)/4 >= m.maxUnaccepted {
+			entry.rejected = true
+			rejected = append(rejected, entry.stream)
+		}
+		m.streams[newNum] = entry
 		select {
 		case m.newStreamChan <- struct{}{}:
 		default:
@@ -149,6 +205,11 @@ func (m *incomingStreamsMap[T]) GetOrOpenStream(id protocol.StreamID) (T, error)
 	m.nextStreamToOpen = id + 4
 	entry := m.streams[id]
 	m.mutex.Unlock()
+	// Rejecting the stream might complete it, which deletes it from the map.
+	// It therefore needs to happen after releasing the mutex.
+	for _, str := range rejected {
+		m.rejectStream(str)
+	}
 	return entry.stream, nil
 }
 
@@ -183,11 +244,23 @@ func (m *incomingStreamsMap[T]) deleteStream(id protocol.StreamID) error {
 	}
 
 	delete(m.streams, id)
-	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
-		maxStream := m.nextStreamToOpen + 4*protocol.StreamID(m.maxNumStreams-uint64(len(m.streams))-1)
+	m.predictedCloses = m.predictedStreamCloses()
+	m.maybeQueueMaxStreams()
+	return nil
+}
+
+// maybeQueueMaxStreams queues a MAX_STREAMS frame, giving the peer the option to open new streams.
+func (m *incomingStreamsMap[T]) maybeQueueMaxStreams() {
+	maxNumStreams := m.maxNumStreams + m.predictedCloses
+	if maxNumStreams > uint64(len(m.streams)) {
+		maxStream := m.nextStreamToOpen + 4*protocol.StreamID(maxNumStreams-uint64(len(m.streams))-1)
+		// Don't allow the peer to open more streams than we're willing to queue for the application.
+		// The peer will be blocked on the stream limit until the application accepts streams.
+		if m.maxUnaccepted > 0 {
+			maxStream = min(maxStream, m.nextStreamToAccept+4*protocol.StreamID(m.maxUnaccepted-1))
+		}
 		// never send a value larger than the maximum value for a stream number
-		if maxStream <= protocol.MaxStreamID {
+		if maxStream <= protocol.MaxStreamID && maxStream > m.maxStream {
 			m.maxStream = maxStream
 			m.queueMaxStreamID(&wire.MaxStreamsFrame{
 				Type:         m.streamType,
@@ -195,7 +268,63 @@ func (m *incomingStreamsMap[T]) deleteStream(id protocol.StreamID) error {
 			})
 		}
 	}
-	return nil
+}
+
+// predictedStreamCloses updates the estimate of the stream close rate,
+// and returns the number of streams that are expected to be closed within the next RTT.
+// The peer can open these streams in addition to the streams allowed by maxNumStreams,
+// since it would otherwise be blocked until the MAX_STREAMS frame for these streams arrives.
+// To account for variations of the close rate, the prediction uses twice the RTT.
+// The result is capped at maxNumStreams.
+func (m *incomingStreamsMap[T]) predictedStreamCloses() uint64 {
+	if m.rttStats == nil {
+		return 0
+	}
+	now := monotime.Now()
+	if m.lastCloseTime.IsZero() {
+		m.lastCloseTime = now
+		return 0
+	}
+	interval := max(now.Sub(m.lastCloseTime), time.Microsecond)
+	m.lastCloseTime = now
+	if m.closeInterval == 0 {
+		m.closeInterval = interval
+	} else {
+		m.closeInterval = (7*m.closeInterval + interval) / 8
+	}
+	return min(uint64(2*m.rttStats.SmoothedRTT()/m.closeInterval), m.maxNumStreams)
+}
+
+// Unaccepted returns the number of streams opened by the peer that haven't been accepted yet,
+// and the time when the oldest of these streams was opened.
+// Rejected streams are not counted.
+func (m *incomingStreamsMap[T]) Unaccepted() (num uint64, oldest monotime.Time) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for id := m.nextStreamToAccept; id < m.nextStreamToOpen; id += 4 {
+		entry, ok := m.streams[id]
+		if !ok || entry.rejected {
+			continue
+		}
+		if num == 0 {
+			oldest = entry.openedAt
+		}
+		num++
+	}
+	return num, oldest
+}
+
+// forEach calls f for all streams that haven't been deleted yet.
+func (m *incomingStreamsMap[T]) forEach(f func(T)) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, entry := range m.streams {
+		if !entry.shouldDelete {
+			f(entry.stream)
+		}
+	}
 }
 
 func (m *incomingStreamsMap[T]) CloseWithError(err error) {
@@ -7,8 +7,10 @@ import (
 	"testing/synctest"
 	"time"
 
+	"github.com/quic-go/quic-go/internal/monotime"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	"github.com/stretchr/testify/assert"
@@ -208,9 +210,188 @@ func testStreamsMapIncomingDeletingStreams(t *testing.T, perspective protocol.Pe
 	require.Equal(t, &wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: 7}, frameQueue[0])
 }
 
+func TestStreamsMapIncomingPredictiveStreamLimit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const maxNumStreams = 5
+		var rttStats utils.RTTStats
+		rttStats.UpdateRTT(100*time.Millisecond, 0)
+		var frameQueue []wire.Frame
+		m := newIncomingStreamsMap(
+			protocol.StreamTypeUni,
+			func(id protocol.StreamID) *mockStream { return &mockStream{id: id} },
+			maxNumStreams,
+			func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+			protocol.PerspectiveServer,
+		)
+		m.rttStats = &rttStats
+
+		// The peer always opens as many streams as allowed,
+		// and we close one stream every 10ms, i.e. 10 streams per RTT.
+		maxStreamNum := protocol.StreamNum(maxNumStreams)
+		for i := range 20 {
+			_, err := m.GetOrOpenStream(maxStreamNum.StreamID(protocol.StreamTypeUni, protocol.PerspectiveClient))
+			require.NoError(t, err)
+			str, err := m.AcceptStream(context.Background())
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(t, m.DeleteStream(str.id))
+			require.Len(t, frameQueue, 1)
+			maxStreamNum = frameQueue[0].(*wire.MaxStreamsFrame).MaxStreamNum
+			frameQueue = frameQueue[:0]
+			// no prediction is possible for the first stream
+			if i == 0 {
+				require.Equal(t, protocol.StreamNum(maxNumStreams+1), maxStreamNum)
+			}
+		}
+		// the peer is allowed to have up to twice the limit open
+		require.Equal(t, protocol.StreamNum(2*maxNumStreams+20), maxStreamNum)
+
+		// once streams are closed less frequently than once per RTT,
+		// the peer isn't granted any additional streams
+		for range 30 {
+			_, err := m.GetOrOpenStream(maxStreamNum.StreamID(protocol.StreamTypeUni, protocol.PerspectiveClient))
+			require.NoError(t, err)
+			str, err := m.AcceptStream(context.Background())
+			require.NoError(t, err)
+			time.Sleep(time.Second)
+			require.NoError(t, m.DeleteStream(str.id))
+			if len(frameQueue) > 0 {
+				maxStreamNum = frameQueue[len(frameQueue)-1].(*wire.MaxStreamsFrame).MaxStreamNum
+				frameQueue = frameQueue[:0]
+			}
+		}
+		_, err := m.GetOrOpenStream(maxStreamNum.StreamID(protocol.StreamTypeUni, protocol.PerspectiveClient))
+		require.NoError(t, err)
+		require.Len(t, m.streams, maxNumStreams)
+	})
+}
+
 // There's a maximum number that can be encoded in a MAX_STREAMS frame.
 // Since the stream limit is configurable by the user, we can't rely on this number
 // being high enough that it will never be reached in practice.
+func TestStreamsMapIncomingMaxUnaccepted(t *testing.T) {
+	const maxNumStreams = 10
+	const maxUnaccepted = 3
+	var frameQueue []wire.Frame
+	m := newIncomingStreamsMap(
+		protocol.StreamTypeUni,
+		func(id protocol.StreamID) *mockStream { return &mockStream{id: id} },
+		maxNumStreams,
+		func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		protocol.PerspectiveServer,
+	)
+	m.maxUnaccepted = maxUnaccepted
+	first := protocol.FirstIncomingUniStreamServer
+
+	// the peer opens all streams allowed by the initial stream limit
+	_, err := m.GetOrOpenStream(first + 4*(maxNumStreams-1))
+	require.NoError(t, err)
+	num, _ := m.Unaccepted()
+	require.EqualValues(t, maxNumStreams, num)
+
+	// As long as maxUnaccepted streams are waiting to be accepted,
+	// no additional streams are granted, even if the accepted streams are completed.
+	for range maxNumStreams - maxUnaccepted {
+		str, err := m.AcceptStream(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, m.DeleteStream(str.id))
+		require.Empty(t, frameQueue)
+	}
+	str, err := m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.DeleteStream(str.id))
+	require.Equal(t, []wire.Frame{&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: maxNumStreams + 1}}, frameQueue)
+	frameQueue = frameQueue[:0]
+
+	// accepting a stream grants a new stream, even if the stream is not completed yet
+	_, err = m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []wire.Frame{&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: maxNumStreams + 2}}, frameQueue)
+	num, _ = m.Unaccepted()
+	require.EqualValues(t, 1, num)
+}
+
+func TestStreamsMapIncomingRejectUnaccepted(t *testing.T) {
+	var rejected []protocol.StreamID
+	m := newIncomingStreamsMap(
+		protocol.StreamTypeUni,
+		func(id protocol.StreamID) *mockStream { return &mockStream{id: id} },
+		10,
+		func(wire.Frame) {},
+		protocol.PerspectiveServer,
+	)
+	m.maxUnaccepted = 2
+	m.rejectStream = func(str *mockStream) { rejected = append(rejected, str.id) }
+	first := protocol.FirstIncomingUniStreamServer
+
+	_, err := m.GetOrOpenStream(first + 4*4)
+	require.NoError(t, err)
+	require.Equal(t, []protocol.StreamID{first + 8, first + 12, first + 16}, rejected)
+	num, _ := m.Unaccepted()
+	require.EqualValues(t, 2, num)
+
+	// rejected streams are not returned by AcceptStream
+	str, err := m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first, str.id)
+	// one of the rejected streams is completed before the application accepts the next stream
+	require.NoError(t, m.DeleteStream(first+12))
+	str, err = m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first+4, str.id)
+	ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+	defer cancel()
+	_, err = m.AcceptStream(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the remaining rejected streams are deleted once they complete
+	require.NoError(t, m.DeleteStream(first+8))
+	require.NoError(t, m.DeleteStream(first+16))
+	require.Len(t, m.streams, 2)
+
+	// new streams are not rejected
+	str, err = m.GetOrOpenStream(first + 20)
+	require.NoError(t, err)
+	require.Equal(t, first+20, str.id)
+	require.Len(t, rejected, 3)
+	str, err = m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first+20, str.id)
+}
+
+func TestStreamsMapIncomingUnacceptedAge(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		m := newIncomingStreamsMap(
+			protocol.StreamTypeUni,
+			func(id protocol.StreamID) *mockStream { return &mockStream{id: id} },
+			10,
+			func(wire.Frame) {},
+			protocol.PerspectiveServer,
+		)
+		first := protocol.FirstIncomingUniStreamServer
+
+		num, _ := m.Unaccepted()
+		require.Zero(t, num)
+
+		start := monotime.Now()
+		_, err := m.GetOrOpenStream(first)
+		require.NoError(t, err)
+		time.Sleep(time.Second)
+		_, err = m.GetOrOpenStream(first + 4)
+		require.NoError(t, err)
+
+		num, oldest := m.Unaccepted()
+		require.EqualValues(t, 2, num)
+		require.Equal(t, start, oldest)
+
+		_, err = m.AcceptStream(context.Background())
+		require.NoError(t, err)
+		num, oldest = m.Unaccepted()
+		require.EqualValues(t, 1, num)
+		require.Equal(t, start.Add(time.Second), oldest)
+	})
+}
+
 func TestStreamsMapIncomingDeletingStreamsWithHighLimits(t *testing.T) {
 	t.Run("client", func(t *testing.T) {
 		testStreamsMapIncomingDeletingStreamsWithHighLimits(t, protocol.PerspectiveClient, protocol.FirstIncomingUniStreamClient)
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"fmt"
+	"maps"
 	"slices"
 	"sync"
 
@@ -69,6 +70,9 @@ func (m *outgoingStreamsMap[T]) OpenStream() (T, error) {
 	if m.closeErr != nil {
 		return *new(T), m.closeErr
 	}
+	if m.exhausted() {
+		return *new(T), ErrStreamIDExhausted
+	}
 
 	// if there are OpenStreamSync calls waiting, return an error here
 	if len(m.openQueue) > 0 || m.nextStream > m.maxStream {
@@ -88,6 +92,9 @@ func (m *outgoingStreamsMap[T]) OpenStreamSync(ctx context.Context) (T, error) {
 	if err := ctx.Err(); err != nil {
 		return *new(T), err
 	}
+	if m.exhausted() {
+		return *new(T), ErrStreamIDExhausted
+	}
 	if len(m.openQueue) == 0 && m.nextStream <= m.maxStream {
 		return m.openStream(), nil
 	}
@@ -115,6 +122,12 @@ func (m *outgoingStreamsMap[T]) OpenStreamSync(ctx context.Context) (T, error) {
 		if m.closeErr != nil {
 			return *new(T), m.closeErr
 		}
+		if m.exhausted() {
+			m.openQueue = slices.DeleteFunc(m.openQueue, func(c chan struct{}) bool {
+				return c == waitChan
+			})
+			return *new(T), ErrStreamIDExhausted
+		}
 		if m.nextStream > m.maxStream {
 			// no stream available. Continue waiting
 			continue
@@ -130,9 +143,23 @@ func (m *outgoingStreamsMap[T]) openStream() T {
 	s := m.newStream(m.nextStream)
 	m.streams[m.nextStream] = s
 	m.nextStream += 4
+	if m.exhausted() {
+		// unblock all OpenStreamSync calls, they will return ErrStreamIDExhausted
+		for _, c := range m.openQueue {
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}
 	return s
 }
 
+// exhausted says if all stream IDs of this stream type have been used.
+func (m *outgoingStreamsMap[T]) exhausted() bool {
+	return m.nextStream.StreamNum() > protocol.MaxStreamCount
+}
+
 // maybeSendBlockedFrame queues a STREAMS_BLOCKED frame for the current stream offset,
 // if we haven't sent one for this offset yet
 func (m *outgoingStreamsMap[T]) maybeSendBlockedFrame() {
@@ -205,6 +232,51 @@ func (m *outgoingStreamsMap[T]) UpdateSendWindow(limit protocol.ByteCount) {
 	m.mutex.Unlock()
 }
 
+// removeStreams removes all streams for which f returns true, and returns them.
+func (m *outgoingStreamsMap[T]) removeStreams(f func(T) bool) map[protocol.StreamID]T {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var removed map[protocol.StreamID]T
+	for id, str := range m.streams {
+		if !f(str) {
+			continue
+		}
+		if removed == nil {
+			removed = make(map[protocol.StreamID]T)
+		}
+		removed[id] = str
+		delete(m.streams, id)
+	}
+	return removed
+}
+
+// restoreStreams adds streams that were opened before 0-RTT was rejected back to the map,
+// keeping their stream IDs. It must be called after the peer's stream limit was set.
+// Streams are only restored if restart returns true, all other streams are closed.
+// Since opening a stream implicitly opens all streams with lower stream IDs,
+// it opens new streams for the stream IDs that are skipped, and returns them.
+func (m *outgoingStreamsMap[T]) restoreStreams(strs map[protocol.StreamID]T, restart func(T) bool) (skipped []T) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, id := range slices.Sorted(maps.Keys(strs)) {
+		str := strs[id]
+		if id < m.nextStream || id > m.maxStream || !restart(str) {
+			str.closeForShutdown(Err0RTTRejected)
+			continue
+		}
+		for ; m.nextStream < id; m.nextStream += 4 {
+			s := m.newStream(m.nextStream)
+			m.streams[m.nextStream] = s
+			skipped = append(skipped, s)
+		}
+		m.streams[id] = str
+		m.nextStream = id + 4
+	}
+	return skipped
+}
+
 func (m *outgoingStreamsMap[T]) EnableResetStreamAt() {
 	m.mutex.Lock()
 	for _, str := range m.streams {
@@ -229,6 +301,16 @@ func (m *outgoingStreamsMap[T]) maybeUnblockOpenSync() {
 	}
 }
 
+// forEach calls f for all streams that haven't been deleted yet.
+func (m *outgoingStreamsMap[T]) forEach(f func(T)) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, str := range m.streams {
+		f(str)
+	}
+}
+
 func (m *outgoingStreamsMap[T]) CloseWithError(err error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
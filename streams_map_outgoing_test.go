@@ -155,6 +155,69 @@ func testStreamsMapOutgoingLimits(t *testing.T, perspective protocol.Perspective
 	})
 }
 
+func TestStreamsMapOutgoingStreamIDExhaustion(t *testing.T) {
+	t.Run("client", func(t *testing.T) {
+		testStreamsMapOutgoingStreamIDExhaustion(t, protocol.PerspectiveClient)
+	})
+	t.Run("server", func(t *testing.T) {
+		testStreamsMapOutgoingStreamIDExhaustion(t, protocol.PerspectiveServer)
+	})
+}
+
+func testStreamsMapOutgoingStreamIDExhaustion(t *testing.T, perspective protocol.Perspective) {
+	synctest.Test(t, func(t *testing.T) {
+		m := newOutgoingStreamsMap(
+			protocol.StreamTypeBidi,
+			func(id protocol.StreamID) *mockStream { return &mockStream{id: id} },
+			func(f wire.Frame) {},
+			perspective,
+		)
+		lastStream := protocol.MaxStreamCount.StreamID(protocol.StreamTypeBidi, perspective)
+		// Opening 2^60 streams one by one would take forever.
+		// Pretend that all streams but the last three have already been opened.
+		m.nextStream = lastStream - 8
+		m.SetMaxStream(lastStream - 4)
+
+		str, err := m.OpenStream()
+		require.NoError(t, err)
+		require.Equal(t, lastStream-8, str.id)
+
+		// OpenStreamSync calls block until the peer allows us to open more streams
+		errChan := make(chan error, 3)
+		streamChan := make(chan *mockStream, 3)
+		for range 3 {
+			go func() {
+				str, err := m.OpenStreamSync(context.Background())
+				if err != nil {
+					errChan <- err
+					return
+				}
+				streamChan <- str
+			}()
+			synctest.Wait()
+		}
+		_, err = m.OpenStream()
+		require.ErrorIs(t, err, &StreamLimitReachedError{})
+
+		// The peer allows us to open all remaining stream IDs.
+		// Only two of the three OpenStreamSync calls can open a stream.
+		m.SetMaxStream(protocol.MaxStreamID)
+		synctest.Wait()
+		require.Len(t, streamChan, 2)
+		require.Equal(t, lastStream-4, (<-streamChan).id)
+		require.Equal(t, lastStream, (<-streamChan).id)
+		require.Len(t, errChan, 1)
+		require.ErrorIs(t, <-errChan, ErrStreamIDExhausted)
+
+		// all stream IDs have been used
+		_, err = m.OpenStream()
+		require.ErrorIs(t, err, ErrStreamIDExhausted)
+		require.NotErrorIs(t, err, &StreamLimitReachedError{})
+		_, err = m.OpenStreamSync(context.Background())
+		require.ErrorIs(t, err, ErrStreamIDExhausted)
+	})
+}
+
 func TestStreamsMapOutgoingConcurrentOpenStreamSync(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		m := newOutgoingStreamsMap(
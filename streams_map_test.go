@@ -58,6 +58,12 @@ func testStreamsMapCreatingStreams(t *testing.T,
 		1,
 		1,
 		perspective,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	m.HandleTransportParameters(&wire.TransportParameters{
 		MaxBidiStreamNum: protocol.MaxStreamCount,
@@ -135,6 +141,12 @@ func testStreamsMapDeletingStreams(t *testing.T,
 		100,
 		100,
 		perspective,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	m.HandleTransportParameters(&wire.TransportParameters{
 		MaxBidiStreamNum: 10,
@@ -197,6 +209,77 @@ func testStreamsMapDeletingStreams(t *testing.T,
 	frameQueue = frameQueue[:0]
 }
 
+func TestStreamsMapStreamCounts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	newMap := func() *streamsMap {
+		m := newStreamsMap(
+			context.Background(),
+			NewMockStreamSender(mockCtrl),
+			func(wire.Frame) {},
+			func(protocol.StreamID) flowcontrol.StreamFlowController {
+				fc := mocks.NewMockStreamFlowController(mockCtrl)
+				fc.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				return fc
+			},
+			100,
+			100,
+			protocol.PerspectiveClient,
+			false,
+			false,
+			0,
+			outOfOrderBufferLimit{},
+			unacceptedStreamsLimit{},
+			nil,
+		)
+		m.HandleTransportParameters(&wire.TransportParameters{
+			MaxBidiStreamNum: 10,
+			MaxUniStreamNum:  10,
+		})
+		return m
+	}
+
+	m := newMap()
+	require.Zero(t, m.StreamCounts())
+
+	str1, err := m.OpenStream()
+	require.NoError(t, err)
+	_, err = m.OpenStream()
+	require.NoError(t, err)
+	ustr, err := m.OpenUniStream()
+	require.NoError(t, err)
+	// the peer opens streams 1 and 5 (implicitly opening stream 1), as well as a unidirectional stream
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: protocol.FirstIncomingBidiStreamClient + 4}, monotime.Now()))
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: protocol.FirstIncomingUniStreamClient}, monotime.Now()))
+	require.Equal(t, StreamCounts{ActiveBidi: 4, ActiveUni: 2, TotalBidi: 4, TotalUni: 2}, m.StreamCounts())
+
+	require.NoError(t, m.DeleteStream(str1.StreamID()))
+	require.NoError(t, m.DeleteStream(ustr.StreamID()))
+	// incoming streams are counted as closed, even if they haven't been accepted yet
+	require.NoError(t, m.DeleteStream(protocol.FirstIncomingBidiStreamClient))
+	require.Equal(t, StreamCounts{ActiveBidi: 2, ActiveUni: 1, TotalBidi: 4, TotalUni: 2}, m.StreamCounts())
+	// deleting a stream twice doesn't change the counts
+	require.Error(t, m.DeleteStream(str1.StreamID()))
+	require.Equal(t, StreamCounts{ActiveBidi: 2, ActiveUni: 1, TotalBidi: 4, TotalUni: 2}, m.StreamCounts())
+
+	_, err = m.OpenStream()
+	require.NoError(t, err)
+	require.Equal(t, StreamCounts{ActiveBidi: 3, ActiveUni: 1, TotalBidi: 5, TotalUni: 2}, m.StreamCounts())
+
+	t.Run("0-RTT rejection", func(t *testing.T) {
+		m := newMap()
+		_, err := m.OpenStream()
+		require.NoError(t, err)
+		str, err := m.OpenUniStream()
+		require.NoError(t, err)
+		str.Enable0RTTRetry()
+		require.Equal(t, StreamCounts{ActiveBidi: 1, ActiveUni: 1, TotalBidi: 1, TotalUni: 1}, m.StreamCounts())
+
+		// the bidirectional stream is discarded, the unidirectional stream is retried
+		m.ResetFor0RTT()
+		require.Equal(t, StreamCounts{ActiveBidi: 0, ActiveUni: 1, TotalBidi: 1, TotalUni: 1}, m.StreamCounts())
+	})
+}
+
 func TestStreamsMapStreamLimits(t *testing.T) {
 	t.Run("client", func(t *testing.T) {
 		testStreamsMapStreamLimits(t, protocol.PerspectiveClient)
@@ -222,6 +305,12 @@ func testStreamsMapStreamLimits(t *testing.T, perspective protocol.Perspective)
 		100,
 		100,
 		perspective,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 
 	// increase via transport parameters
@@ -318,6 +407,12 @@ func testStreamsMapHandleReceiveStreamFrames(t *testing.T, pers protocol.Perspec
 		100,
 		100,
 		pers,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	m.HandleMaxStreamsFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: protocol.MaxStreamCount})
 	m.HandleMaxStreamsFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: protocol.MaxStreamCount})
@@ -431,6 +526,12 @@ func testStreamsMapHandleSendStreamFrames(t *testing.T, pers protocol.Perspectiv
 		100,
 		100,
 		pers,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	m.HandleMaxStreamsFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: protocol.MaxStreamCount})
 	m.HandleMaxStreamsFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: protocol.MaxStreamCount})
@@ -513,6 +614,12 @@ func TestStreamsMapClosing(t *testing.T) {
 		1,
 		1,
 		protocol.PerspectiveClient,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	m.CloseWithError(assert.AnError)
 	_, err := m.OpenStream()
@@ -543,6 +650,12 @@ func TestStreamsMap0RTT(t *testing.T) {
 		1,
 		1,
 		protocol.PerspectiveClient,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 	// restored transport parameters
 	m.HandleTransportParameters(&wire.TransportParameters{
@@ -580,6 +693,12 @@ func TestStreamsMap0RTTRejection(t *testing.T) {
 		1,
 		1,
 		protocol.PerspectiveClient,
+		false,
+		false,
+		0,
+		outOfOrderBufferLimit{},
+		unacceptedStreamsLimit{},
+		nil,
 	)
 
 	m.ResetFor0RTT()
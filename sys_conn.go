@@ -52,26 +52,18 @@ type OOBCapablePacketConn interface {
 
 var _ OOBCapablePacketConn = &net.UDPConn{}
 
-func wrapConn(pc net.PacketConn) (rawConn, error) {
-	if err := setReceiveBuffer(pc); err != nil {
-		if !strings.Contains(err.Error(), "use of closed network connection") {
-			setBufferWarningOnce.Do(func() {
-				if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
-					return
-				}
-				log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
-			})
-		}
+// wrapConn wraps a net.PacketConn and increases its socket buffer sizes.
+// If receiveBufferSize or sendBufferSize is zero, the respective buffer is increased to the default size.
+func wrapConn(pc net.PacketConn, receiveBufferSize, sendBufferSize int) (rawConn, error) {
+	if receiveBufferSize > 0 {
+		logConfiguredBufferSizeError(setReceiveBuffer(pc, receiveBufferSize))
+	} else {
+		logBufferSizeError(setReceiveBuffer(pc, protocol.DesiredReceiveBufferSize))
 	}
-	if err := setSendBuffer(pc); err != nil {
-		if !strings.Contains(err.Error(), "use of closed network connection") {
-			setBufferWarningOnce.Do(func() {
-				if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
-					return
-				}
-				log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
-			})
-		}
+	if sendBufferSize > 0 {
+		logConfiguredBufferSizeError(setSendBuffer(pc, sendBufferSize))
+	} else {
+		logBufferSizeError(setSendBuffer(pc, protocol.DesiredSendBufferSize))
 	}
 
 	conn, ok := pc.(interface {
@@ -101,6 +93,31 @@ func wrapConn(pc net.PacketConn) (rawConn, error) {
 	return newConn(c, supportsDF)
 }
 
+// logBufferSizeError logs the error returned when setting the socket buffer sizes.
+// The warning is only logged once.
+func logBufferSizeError(err error) {
+	if err == nil || strings.Contains(err.Error(), "use of closed network connection") {
+		return
+	}
+	setBufferWarningOnce.Do(func() {
+		if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
+			return
+		}
+		log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
+	})
+}
+
+// logConfiguredBufferSizeError logs the error returned when setting the socket buffer sizes
+// configured on the Transport.
+// Since the application explicitly requested these sizes, the warning can't be disabled.
+// It is logged once per Transport, since the buffer sizes are only set when the Transport is initialized.
+func logConfiguredBufferSizeError(err error) {
+	if err == nil || strings.Contains(err.Error(), "use of closed network connection") {
+		return
+	}
+	log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
+}
+
 // The basicConn is the most trivial implementation of a rawConn.
 // It reads a single packet from the underlying net.PacketConn.
 // It is used when
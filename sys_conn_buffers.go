@@ -6,12 +6,11 @@ import (
 	"net"
 	"syscall"
 
-	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
-//go:generate sh -c "echo '// Code generated by go generate. DO NOT EDIT.\n// Source: sys_conn_buffers.go\n' > sys_conn_buffers_write.go && sed -e 's/SetReadBuffer/SetWriteBuffer/g' -e 's/setReceiveBuffer/setSendBuffer/g' -e 's/inspectReadBuffer/inspectWriteBuffer/g' -e 's/protocol\\.DesiredReceiveBufferSize/protocol\\.DesiredSendBufferSize/g' -e 's/forceSetReceiveBuffer/forceSetSendBuffer/g' -e 's/receive buffer/send buffer/g' sys_conn_buffers.go | sed '/^\\/\\/go:generate/d' >> sys_conn_buffers_write.go"
-func setReceiveBuffer(c net.PacketConn) error {
+//go:generate sh -c "echo '// Code generated by go generate. DO NOT EDIT.\n// Source: sys_conn_buffers.go\n' > sys_conn_buffers_write.go && sed -e 's/SetReadBuffer/SetWriteBuffer/g' -e 's/setReceiveBuffer/setSendBuffer/g' -e 's/inspectReadBuffer/inspectWriteBuffer/g' -e 's/rmem_max/wmem_max/g' -e 's/forceSetReceiveBuffer/forceSetSendBuffer/g' -e 's/receive buffer/send buffer/g' sys_conn_buffers.go | sed '/^\\/\\/go:generate/d' >> sys_conn_buffers_write.go"
+func setReceiveBuffer(c net.PacketConn, desiredSize int) error {
 	conn, ok := c.(interface{ SetReadBuffer(int) error })
 	if !ok {
 		return errors.New("connection doesn't allow setting of receive buffer size. Not a *net.UDPConn?")
@@ -32,23 +31,23 @@ func setReceiveBuffer(c net.PacketConn) error {
 	// net.PacketConn interface and the SetReadBuffer method.
 	// We have no way of checking if increasing the buffer size actually worked.
 	if syscallConn == nil {
-		return conn.SetReadBuffer(protocol.DesiredReceiveBufferSize)
+		return conn.SetReadBuffer(desiredSize)
 	}
 
 	size, err := inspectReadBuffer(syscallConn)
 	if err != nil {
 		return fmt.Errorf("failed to determine receive buffer size: %w", err)
 	}
-	if size >= protocol.DesiredReceiveBufferSize {
-		utils.DefaultLogger.Debugf("Conn has receive buffer of %d kiB (wanted: at least %d kiB)", size/1024, protocol.DesiredReceiveBufferSize/1024)
+	if size >= desiredSize {
+		utils.DefaultLogger.Debugf("Conn has receive buffer of %d kiB (wanted: at least %d kiB)", size/1024, desiredSize/1024)
 		return nil
 	}
 	// Ignore the error. We check if we succeeded by querying the buffer size afterward.
-	_ = conn.SetReadBuffer(protocol.DesiredReceiveBufferSize)
+	_ = conn.SetReadBuffer(desiredSize)
 	newSize, err := inspectReadBuffer(syscallConn)
-	if newSize < protocol.DesiredReceiveBufferSize {
+	if newSize < desiredSize {
 		// Try again with RCVBUFFORCE on Linux
-		_ = forceSetReceiveBuffer(syscallConn, protocol.DesiredReceiveBufferSize)
+		_ = forceSetReceiveBuffer(syscallConn, desiredSize)
 		newSize, err = inspectReadBuffer(syscallConn)
 		if err != nil {
 			return fmt.Errorf("failed to determine receive buffer size: %w", err)
@@ -58,10 +57,10 @@ func setReceiveBuffer(c net.PacketConn) error {
 		return fmt.Errorf("failed to determine receive buffer size: %w", err)
 	}
 	if newSize == size {
-		return fmt.Errorf("failed to increase receive buffer size (wanted: %d kiB, got %d kiB)", protocol.DesiredReceiveBufferSize/1024, newSize/1024)
+		return fmt.Errorf("failed to increase receive buffer size (wanted: %d kiB, got %d kiB)%s", desiredSize/1024, newSize/1024, bufferSizeHint("rmem_max", desiredSize))
 	}
-	if newSize < protocol.DesiredReceiveBufferSize {
-		return fmt.Errorf("failed to sufficiently increase receive buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)", size/1024, protocol.DesiredReceiveBufferSize/1024, newSize/1024)
+	if newSize < desiredSize {
+		return fmt.Errorf("failed to sufficiently increase receive buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)%s", size/1024, desiredSize/1024, newSize/1024, bufferSizeHint("rmem_max", desiredSize))
 	}
 	utils.DefaultLogger.Debugf("Increased receive buffer size to %d kiB", newSize/1024)
 	return nil
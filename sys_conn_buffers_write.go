@@ -9,11 +9,10 @@ import (
 	"net"
 	"syscall"
 
-	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
-func setSendBuffer(c net.PacketConn) error {
+func setSendBuffer(c net.PacketConn, desiredSize int) error {
 	conn, ok := c.(interface{ SetWriteBuffer(int) error })
 	if !ok {
 		return errors.New("connection doesn't allow setting of send buffer size. Not a *net.UDPConn?")
@@ -34,23 +33,23 @@ func setSendBuffer(c net.PacketConn) error {
 	// net.PacketConn interface and the SetWriteBuffer method.
 	// We have no way of checking if increasing the buffer size actually worked.
 	if syscallConn == nil {
-		return conn.SetWriteBuffer(protocol.DesiredSendBufferSize)
+		return conn.SetWriteBuffer(desiredSize)
 	}
 
 	size, err := inspectWriteBuffer(syscallConn)
 	if err != nil {
 		return fmt.Errorf("failed to determine send buffer size: %w", err)
 	}
-	if size >= protocol.DesiredSendBufferSize {
-		utils.DefaultLogger.Debugf("Conn has send buffer of %d kiB (wanted: at least %d kiB)", size/1024, protocol.DesiredSendBufferSize/1024)
+	if size >= desiredSize {
+		utils.DefaultLogger.Debugf("Conn has send buffer of %d kiB (wanted: at least %d kiB)", size/1024, desiredSize/1024)
 		return nil
 	}
 	// Ignore the error. We check if we succeeded by querying the buffer size afterward.
-	_ = conn.SetWriteBuffer(protocol.DesiredSendBufferSize)
+	_ = conn.SetWriteBuffer(desiredSize)
 	newSize, err := inspectWriteBuffer(syscallConn)
-	if newSize < protocol.DesiredSendBufferSize {
+	if newSize < desiredSize {
 		// Try again with RCVBUFFORCE on Linux
-		_ = forceSetSendBuffer(syscallConn, protocol.DesiredSendBufferSize)
+		_ = forceSetSendBuffer(syscallConn, desiredSize)
 		newSize, err = inspectWriteBuffer(syscallConn)
 		if err != nil {
 			return fmt.Errorf("failed to determine send buffer size: %w", err)
@@ -60,10 +59,10 @@ func setSendBuffer(c net.PacketConn) error {
 		return fmt.Errorf("failed to determine send buffer size: %w", err)
 	}
 	if newSize == size {
-		return fmt.Errorf("failed to increase send buffer size (wanted: %d kiB, got %d kiB)", protocol.DesiredSendBufferSize/1024, newSize/1024)
+		return fmt.Errorf("failed to increase send buffer size (wanted: %d kiB, got %d kiB)%s", desiredSize/1024, newSize/1024, bufferSizeHint("wmem_max", desiredSize))
 	}
-	if newSize < protocol.DesiredSendBufferSize {
-		return fmt.Errorf("failed to sufficiently increase send buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)", size/1024, protocol.DesiredSendBufferSize/1024, newSize/1024)
+	if newSize < desiredSize {
+		return fmt.Errorf("failed to sufficiently increase send buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)%s", size/1024, desiredSize/1024, newSize/1024, bufferSizeHint("wmem_max", desiredSize))
 	}
 	utils.DefaultLogger.Debugf("Increased send buffer size to %d kiB", newSize/1024)
 	return nil
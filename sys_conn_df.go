@@ -3,7 +3,10 @@
 package quic
 
 import (
+	"net"
 	"syscall"
+
+	"github.com/quic-go/quic-go/internal/protocol"
 )
 
 func setDF(syscall.RawConn) (bool, error) {
@@ -20,3 +23,8 @@ func isRecvMsgSizeErr(err error) bool {
 	// to be implemented for more specific platforms
 	return false
 }
+
+func getMaxPayloadSize(any, net.Addr) (protocol.ByteCount, bool) {
+	// to be implemented for more specific platforms
+	return 0, false
+}
@@ -5,11 +5,14 @@ package quic
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"syscall"
 
 	"golang.org/x/sys/unix"
+
+	"github.com/quic-go/quic-go/internal/protocol"
 )
 
 // for macOS versions, see https://en.wikipedia.org/wiki/Darwin_(operating_system)#Darwin_20_onwards
@@ -71,6 +74,11 @@ func isSendMsgSizeErr(err error) bool {
 	return errors.Is(err, unix.EMSGSIZE)
 }
 
+func getMaxPayloadSize(any, net.Addr) (protocol.ByteCount, bool) {
+	// to be implemented for more specific platforms
+	return 0, false
+}
+
 func isRecvMsgSizeErr(error) bool { return false }
 
 func getMacOSVersion() (int, error) {
@@ -4,10 +4,12 @@ package quic
 
 import (
 	"errors"
+	"net"
 	"syscall"
 
 	"golang.org/x/sys/unix"
 
+	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
@@ -39,4 +41,36 @@ func isSendMsgSizeErr(err error) bool {
 	return errors.Is(err, unix.EMSGSIZE)
 }
 
+// getMaxPayloadSize returns the largest UDP payload that can be sent to the remote address,
+// derived from the path MTU known to the kernel (IP_MTU / IPV6_MTU).
+// The kernel only reports the path MTU for connected sockets.
+func getMaxPayloadSize(c any, remote net.Addr) (protocol.ByteCount, bool) {
+	conn, ok := c.(interface {
+		SyscallConn() (syscall.RawConn, error)
+	})
+	if !ok {
+		return 0, false
+	}
+	udpAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return 0, false
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	level, opt, overhead := unix.IPPROTO_IPV6, unix.IPV6_MTU, 40+8
+	if udpAddr.IP.To4() != nil {
+		level, opt, overhead = unix.IPPROTO_IP, unix.IP_MTU, 20+8
+	}
+	var mtu int
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		mtu, sockErr = unix.GetsockoptInt(int(fd), level, opt)
+	}); err != nil || sockErr != nil || mtu <= overhead {
+		return 0, false
+	}
+	return protocol.ByteCount(mtu - overhead), true
+}
+
 func isRecvMsgSizeErr(error) bool { return false }
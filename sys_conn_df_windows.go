@@ -4,10 +4,12 @@ package quic
 
 import (
 	"errors"
+	"net"
 	"syscall"
 
 	"golang.org/x/sys/windows"
 
+	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
@@ -46,6 +48,11 @@ func isSendMsgSizeErr(err error) bool {
 	return errors.Is(err, windows.WSAEMSGSIZE)
 }
 
+func getMaxPayloadSize(any, net.Addr) (protocol.ByteCount, bool) {
+	// to be implemented for more specific platforms
+	return 0, false
+}
+
 func isRecvMsgSizeErr(err error) bool {
 	// https://docs.microsoft.com/en-us/windows/win32/winsock/windows-sockets-error-codes-2
 	return errors.Is(err, windows.WSAEMSGSIZE)
@@ -5,9 +5,11 @@ package quic
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"syscall"
 	"unsafe"
 
@@ -49,6 +51,23 @@ func forceSetSendBuffer(c syscall.RawConn, bytes int) error {
 	return serr
 }
 
+// bufferSizeHint returns a hint how to raise the kernel's limit for socket buffer sizes,
+// if size exceeds the limit configured in /proc/sys/net/core/<name>.
+func bufferSizeHint(name string, size int) string {
+	b, err := os.ReadFile("/proc/sys/net/core/" + name)
+	if err != nil {
+		return ""
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || size <= limit {
+		return ""
+	}
+	return fmt.Sprintf(
+		". The kernel limits the buffer size to %d kiB (net.core.%s), run 'sysctl -w net.core.%s=%d' to raise the limit",
+		limit/1024, name, name, size,
+	)
+}
+
 func parseIPv4PktInfo(body []byte) (ip netip.Addr, ifIndex uint32, ok bool) {
 	// struct in_pktinfo {
 	// 	unsigned int   ipi_ifindex;  /* Interface index */
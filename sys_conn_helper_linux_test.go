@@ -3,9 +3,13 @@
 package quic
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"golang.org/x/sys/unix"
@@ -72,6 +76,51 @@ func TestForcingSendBufferSize(t *testing.T) {
 	require.Equal(t, 2*large, size)
 }
 
+func TestConfiguredBufferSizes(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("Must be root to force change the buffer sizes")
+	}
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tr := &Transport{
+		Conn:                    c,
+		SocketReceiveBufferSize: 16 << 20,
+		SocketSendBufferSize:    12 << 20,
+	}
+	defer tr.Close()
+	ln, err := tr.Listen(&tls.Config{}, nil)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	syscallConn, err := c.(*net.UDPConn).SyscallConn()
+	require.NoError(t, err)
+	size, err := inspectReadBuffer(syscallConn)
+	require.NoError(t, err)
+	// the kernel doubles this value (to allow space for bookkeeping overhead)
+	require.Equal(t, 2*16<<20, size)
+	size, err = inspectWriteBuffer(syscallConn)
+	require.NoError(t, err)
+	require.Equal(t, 2*12<<20, size)
+}
+
+func TestBufferSizeHint(t *testing.T) {
+	b, err := os.ReadFile("/proc/sys/net/core/rmem_max")
+	if err != nil {
+		t.Skip("can't read net.core.rmem_max")
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	require.NoError(t, err)
+
+	require.Empty(t, bufferSizeHint("rmem_max", limit))
+	require.Contains(t,
+		bufferSizeHint("rmem_max", limit+1),
+		fmt.Sprintf("run 'sysctl -w net.core.rmem_max=%d' to raise the limit", limit+1),
+	)
+	// unknown sysctl
+	require.Empty(t, bufferSizeHint("foobar", limit+1))
+}
+
 func TestGSOError(t *testing.T) {
 	require.True(t, isGSOError(errGSO))
 	require.False(t, isGSOError(nil))
@@ -0,0 +1,171 @@
+package quictest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A NewPairFunc creates a pair of connected connections.
+// clientConf and serverConf might be nil.
+// It is expected to register cleanup functions using t.Cleanup.
+type NewPairFunc func(t *testing.T, clientConf, serverConf *quic.Config) (client, server *quic.Conn)
+
+// RunConformanceTests runs the conformance test suite against the connections created by newPair.
+// If wrap is not nil, every test is run by calling wrap, e.g. to run it in a [testing/synctest] bubble.
+func RunConformanceTests(t *testing.T, wrap func(*testing.T, func(*testing.T)), newPair NewPairFunc) {
+	run := func(t *testing.T, testFn func(*testing.T)) {
+		if wrap == nil {
+			testFn(t)
+			return
+		}
+		wrap(t, testFn)
+	}
+	t.Run("stream data", func(t *testing.T) {
+		run(t, func(t *testing.T) { testStreamData(t, newPair) })
+	})
+	t.Run("flow control", func(t *testing.T) {
+		run(t, func(t *testing.T) { testFlowControlBlocking(t, newPair) })
+	})
+	t.Run("CancelWrite", func(t *testing.T) {
+		run(t, func(t *testing.T) { testCancelWrite(t, newPair) })
+	})
+	t.Run("CancelRead", func(t *testing.T) {
+		run(t, func(t *testing.T) { testCancelRead(t, newPair) })
+	})
+	t.Run("read deadline", func(t *testing.T) {
+		run(t, func(t *testing.T) { testReadDeadline(t, newPair) })
+	})
+	t.Run("datagrams", func(t *testing.T) {
+		run(t, func(t *testing.T) { testDatagrams(t, newPair) })
+	})
+}
+
+func testStreamData(t *testing.T, newPair NewPairFunc) {
+	client, server := newPair(t, nil, nil)
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sstr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(sstr)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), data)
+}
+
+func testFlowControlBlocking(t *testing.T, newPair NewPairFunc) {
+	const window = 1 << 10
+	client, server := newPair(t, nil, &quic.Config{
+		InitialStreamReceiveWindow: window,
+		MaxStreamReceiveWindow:     window,
+	})
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	// the write blocks once the flow control window is exhausted
+	str.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	n, err := str.Write(make([]byte, 10*window))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.Less(t, n, 10*window)
+
+	// reading the data on the server side opens the flow control window
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sstr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	str.SetWriteDeadline(time.Now().Add(time.Second))
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := str.Write(make([]byte, 10*window-n))
+		errChan <- err
+	}()
+	_, err = io.ReadFull(sstr, make([]byte, 10*window))
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+}
+
+func testCancelWrite(t *testing.T, newPair NewPairFunc) {
+	client, server := newPair(t, nil, nil)
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	str.CancelWrite(1337)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sstr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	_, err = io.ReadAll(sstr)
+	require.ErrorIs(t, err, &quic.StreamError{StreamID: str.StreamID(), ErrorCode: 1337, Remote: true})
+}
+
+func testCancelRead(t *testing.T, newPair NewPairFunc) {
+	client, server := newPair(t, nil, nil)
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sstr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	sstr.CancelRead(42)
+
+	str.SetWriteDeadline(time.Now().Add(time.Second))
+	for {
+		if _, err = str.Write([]byte("foobar")); err != nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.ErrorIs(t, err, &quic.StreamError{StreamID: str.StreamID(), ErrorCode: 42, Remote: true})
+}
+
+func testReadDeadline(t *testing.T, newPair NewPairFunc) {
+	client, _ := newPair(t, nil, nil)
+
+	str, err := client.OpenStream()
+	require.NoError(t, err)
+	str.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = str.Read(make([]byte, 10))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	var nerr net.Error
+	require.True(t, errors.As(err, &nerr))
+	require.True(t, nerr.Timeout())
+}
+
+func testDatagrams(t *testing.T, newPair NewPairFunc) {
+	client, server := newPair(t, &quic.Config{EnableDatagrams: true}, &quic.Config{EnableDatagrams: false})
+
+	require.True(t, client.ConnectionState().SupportsDatagrams.Local)
+	require.False(t, client.ConnectionState().SupportsDatagrams.Remote)
+	require.False(t, server.ConnectionState().SupportsDatagrams.Local)
+	require.True(t, server.ConnectionState().SupportsDatagrams.Remote)
+	require.Error(t, client.SendDatagram([]byte("foobar")))
+
+	client, server = newPair(t, &quic.Config{EnableDatagrams: true}, &quic.Config{EnableDatagrams: true})
+	require.NoError(t, client.SendDatagram([]byte("foobar")))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := server.ReceiveDatagram(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), data)
+}
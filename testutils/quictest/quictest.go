@@ -0,0 +1,165 @@
+// Package quictest provides utilities for testing applications built on top of quic-go.
+//
+// Instead of mocking the [quic.Conn] and [quic.Stream] types, it connects two real QUIC
+// connections over an in-memory network. This ensures that all stream semantics
+// (flow control, stream cancellation, deadlines, datagram support, etc.) behave exactly
+// like they do on a real network.
+// [quic.Conn] and [quic.Stream] are concrete types, so a fake implementation couldn't be used
+// in their place. The connections still perform a full TLS handshake, but never touch a real network.
+//
+// [RunConformanceTests] checks the stream and datagram semantics of a pair of connections.
+// It is run against both the in-memory connection pair and connections over UDP sockets.
+//
+// When used inside a [testing/synctest] bubble, all timers are driven by the virtual clock.
+package quictest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/testutils/simnet"
+)
+
+// ALPN is the application protocol negotiated on connections created by [NewConnectionPair].
+const ALPN = "quictest"
+
+// use a very long validity period to cover the synthetic clock used in synctest
+var (
+	notBefore = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter  = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Options configure the connections created by [NewConnectionPair].
+type Options struct {
+	// ClientConfig is the QUIC config used by the client.
+	// If nil, the default config is used.
+	ClientConfig *quic.Config
+	// ServerConfig is the QUIC config used by the server.
+	// If nil, the default config is used.
+	ServerConfig *quic.Config
+	// RTT is the round-trip time of the simulated network.
+	// If zero, packets are delivered without delay.
+	RTT time.Duration
+}
+
+// A ConnectionPair is a pair of connected QUIC connections.
+type ConnectionPair struct {
+	Client *quic.Conn
+	Server *quic.Conn
+
+	net                *simnet.Simnet
+	clientConn         *simnet.SimConn
+	serverConn         *simnet.SimConn
+	clientTr, serverTr *quic.Transport
+	listener           *quic.Listener
+}
+
+// NewConnectionPair creates a client and a server connection, connected over an in-memory network.
+// The handshake is completed when NewConnectionPair returns.
+// The caller must call [ConnectionPair.Close] when done.
+func NewConnectionPair(ctx context.Context, opts *Options) (*ConnectionPair, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	serverTLSConf, clientTLSConf, err := generateTLSConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &simnet.Simnet{Router: &simnet.PerfectRouter{}}
+	settings := simnet.NodeBiDiLinkSettings{Latency: opts.RTT / 2}
+	p := &ConnectionPair{
+		net:        n,
+		clientConn: n.NewEndpoint(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}, settings),
+		serverConn: n.NewEndpoint(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 4321}, settings),
+	}
+	if err := n.Start(); err != nil {
+		return nil, err
+	}
+	p.clientTr = &quic.Transport{Conn: p.clientConn}
+	p.serverTr = &quic.Transport{Conn: p.serverConn}
+
+	p.listener, err = p.serverTr.Listen(serverTLSConf, opts.ServerConfig)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.Client, err = p.clientTr.Dial(ctx, p.serverConn.LocalAddr(), clientTLSConf, opts.ClientConfig)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.Server, err = p.listener.Accept(ctx)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close closes both connections and tears down the in-memory network.
+func (p *ConnectionPair) Close() error {
+	var errs []error
+	if p.Client != nil {
+		errs = append(errs, p.Client.CloseWithError(0, ""))
+	}
+	if p.Server != nil {
+		errs = append(errs, p.Server.CloseWithError(0, ""))
+	}
+	if p.listener != nil {
+		errs = append(errs, p.listener.Close())
+	}
+	errs = append(errs,
+		p.clientTr.Close(),
+		p.serverTr.Close(),
+		p.clientConn.Close(),
+		p.serverConn.Close(),
+		p.net.Close(),
+	)
+	return errors.Join(errs...)
+}
+
+func generateTLSConfigs() (server, client *tls.Config, _ error) {
+	certTempl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{"localhost"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTempl, certTempl, pub, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(cert)
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certBytes}, PrivateKey: priv}},
+		NextProtos:   []string{ALPN},
+	}
+	client = &tls.Config{
+		RootCAs:    certPool,
+		ServerName: "localhost",
+		NextProtos: []string{ALPN},
+	}
+	return server, client, nil
+}
@@ -0,0 +1,75 @@
+package quictest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The conformance tests run against both the in-memory connection pair and connections using real UDP sockets,
+// making sure that the behavior of the two is identical.
+func TestConformance(t *testing.T) {
+	t.Run("in-memory", func(t *testing.T) {
+		RunConformanceTests(t, synctest.Test, newInMemoryPair)
+	})
+
+	t.Run("UDP", func(t *testing.T) {
+		RunConformanceTests(t, nil, newUDPPair)
+	})
+}
+
+func newInMemoryPair(t *testing.T, clientConf, serverConf *quic.Config) (client, server *quic.Conn) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	p, err := NewConnectionPair(ctx, &Options{
+		ClientConfig: clientConf,
+		ServerConfig: serverConf,
+		RTT:          10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close() })
+	return p.Client, p.Server
+}
+
+func newUDPPair(t *testing.T, clientConf, serverConf *quic.Config) (client, server *quic.Conn) {
+	t.Helper()
+
+	serverTLSConf, clientTLSConf, err := generateTLSConfigs()
+	require.NoError(t, err)
+	serverUDPConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	clientUDPConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	serverTr := &quic.Transport{Conn: serverUDPConn}
+	clientTr := &quic.Transport{Conn: clientUDPConn}
+	t.Cleanup(func() {
+		clientTr.Close()
+		serverTr.Close()
+		clientUDPConn.Close()
+		serverUDPConn.Close()
+	})
+
+	ln, err := serverTr.Listen(serverTLSConf, serverConf)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	client, err = clientTr.Dial(ctx, serverUDPConn.LocalAddr(), clientTLSConf, clientConf)
+	require.NoError(t, err)
+	server, err = ln.Accept(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.CloseWithError(0, "")
+		server.CloseWithError(0, "")
+	})
+	return client, server
+}
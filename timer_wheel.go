@@ -0,0 +1,304 @@
+package quic
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+)
+
+const (
+	// timerWheelTick is the granularity of the timer wheel.
+	timerWheelTick      = time.Millisecond
+	timerWheelLevelBits = 6
+	timerWheelSlots     = 1 << timerWheelLevelBits
+	timerWheelSlotMask  = timerWheelSlots - 1
+	timerWheelLevels    = 4
+	// Timers further in the future are placed in the last slot of the wheel,
+	// and rescheduled when that slot is reached (about every 4.6 hours).
+	timerWheelMaxTicks = 1<<(timerWheelLevels*timerWheelLevelBits) - 1
+)
+
+// timerWheelMinDelay is the minimum delay of a deadline handled by the timer wheel.
+// Deadlines in the near future need a higher precision (e.g. pacing),
+// and are handled by the connection's own timer.
+const timerWheelMinDelay = 10 * time.Millisecond
+
+// A timerWheel is a hierarchical timing wheel.
+// It is shared by all connections of a Transport, and uses a single runtime timer,
+// instead of one runtime timer per connection.
+// Timers fire up to one timerWheelTick late, but never early.
+type timerWheel struct {
+	mutex sync.Mutex
+
+	start monotime.Time // the time of tick 0
+	// All ticks before nextTick have been processed.
+	nextTick  uint64
+	slots     [timerWheelLevels][timerWheelSlots]*wheelTimer
+	occupied  [timerWheelLevels]uint64 // one bit per non-empty slot
+	numTimers int
+
+	timer      *time.Timer
+	timerArmed bool
+	timerTick  uint64 // the tick the runtime timer is set for
+	closed     bool
+}
+
+func newTimerWheel() *timerWheel {
+	return &timerWheel{start: monotime.Now()}
+}
+
+// A wheelTimer is a timer that is scheduled on a timerWheel.
+type wheelTimer struct {
+	C <-chan struct{}
+
+	c     chan struct{}
+	wheel *timerWheel
+	// armedTick is the expiry of the timer plus one, or 0 if the timer is not scheduled.
+	// It is written while holding the wheel's mutex, and allows Arm to skip locking it.
+	armedTick atomic.Uint64
+
+	// all fields below are protected by the wheel's mutex
+	scheduled   bool
+	expiry      uint64
+	level, slot int
+	prev, next  *wheelTimer
+}
+
+func (w *timerWheel) NewTimer() *wheelTimer {
+	c := make(chan struct{}, 1)
+	return &wheelTimer{C: c, c: c, wheel: w}
+}
+
+// Reset schedules the timer to fire at (or shortly after) the deadline.
+// If the timer is already scheduled, it is rescheduled.
+func (t *wheelTimer) Reset(deadline monotime.Time) {
+	w := t.wheel
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+	expiry := w.deadlineToTick(deadline)
+	if t.scheduled {
+		if t.expiry == expiry {
+			return
+		}
+		w.remove(t)
+	}
+	t.drain()
+	if w.numTimers == 0 {
+		// The wheel might not have been advanced for a while.
+		w.nextTick = max(w.nextTick, w.currentTick())
+	}
+	if expiry < w.nextTick {
+		t.fire()
+		return
+	}
+	t.expiry = expiry
+	w.insert(t)
+	w.maybeArm()
+}
+
+// Arm makes sure that the timer fires at (or shortly after) the deadline, or earlier.
+// If the timer is already scheduled to fire no later than the deadline, it is left untouched,
+// without locking the wheel's mutex. The timer then fires early, and needs to be re-armed.
+// This avoids contention on the mutex when the deadline moves further into the future,
+// as happens when the idle timeout is extended with every packet received.
+// Arm must not be called concurrently with Reset and Stop.
+func (t *wheelTimer) Arm(deadline monotime.Time) {
+	if armed := t.armedTick.Load(); armed != 0 && armed-1 <= t.wheel.deadlineToTick(deadline) {
+		return
+	}
+	t.Reset(deadline)
+}
+
+// Stop stops the timer.
+func (t *wheelTimer) Stop() {
+	w := t.wheel
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if t.scheduled {
+		w.remove(t)
+	}
+	t.drain()
+}
+
+func (t *wheelTimer) fire() {
+	select {
+	case t.c <- struct{}{}:
+	default:
+	}
+}
+
+func (t *wheelTimer) drain() {
+	select {
+	case <-t.c:
+	default:
+	}
+}
+
+// Close stops the wheel. Timers are not fired after the wheel was closed.
+func (w *timerWheel) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+func (w *timerWheel) deadlineToTick(deadline monotime.Time) uint64 {
+	d := deadline.Sub(w.start)
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + timerWheelTick - 1) / timerWheelTick)
+}
+
+func (w *timerWheel) currentTick() uint64 {
+	return uint64(max(0, monotime.Since(w.start)) / timerWheelTick)
+}
+
+// insert inserts a timer. Its expiry must not be before nextTick.
+func (w *timerWheel) insert(t *wheelTimer) {
+	delta := min(t.expiry-w.nextTick, timerWheelMaxTicks)
+	pos := w.nextTick + delta
+	var level int
+	for delta >= 1<<((level+1)*timerWheelLevelBits) {
+		level++
+	}
+	slot := int(pos>>(level*timerWheelLevelBits)) & timerWheelSlotMask
+
+	t.level = level
+	t.slot = slot
+	t.prev = nil
+	t.next = w.slots[level][slot]
+	if t.next != nil {
+		t.next.prev = t
+	}
+	w.slots[level][slot] = t
+	w.occupied[level] |= 1 << slot
+	t.scheduled = true
+	t.armedTick.Store(t.expiry + 1)
+	w.numTimers++
+}
+
+func (w *timerWheel) remove(t *wheelTimer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		w.slots[t.level][t.slot] = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	if w.slots[t.level][t.slot] == nil {
+		w.occupied[t.level] &^= 1 << t.slot
+	}
+	t.prev = nil
+	t.next = nil
+	t.scheduled = false
+	t.armedTick.Store(0)
+	w.numTimers--
+}
+
+// detach removes all timers from a slot, and returns the first one.
+func (w *timerWheel) detach(level, slot int) *wheelTimer {
+	head := w.slots[level][slot]
+	w.slots[level][slot] = nil
+	w.occupied[level] &^= 1 << slot
+	for t := head; t != nil; t = t.next {
+		t.scheduled = false
+		t.armedTick.Store(0)
+		w.numTimers--
+	}
+	return head
+}
+
+// nextTickWithWork returns the first tick (not before from) that either has
+// expiring timers, or requires timers to be moved down from a higher level.
+func (w *timerWheel) nextTickWithWork(from uint64) uint64 {
+	idx := from & timerWheelSlotMask
+	if rest := w.occupied[0] >> idx; rest != 0 {
+		return from + uint64(bits.TrailingZeros64(rest))
+	}
+	if idx == 0 {
+		return from
+	}
+	return (from | timerWheelSlotMask) + 1
+}
+
+// advance processes all ticks up to and including now, firing all expired timers.
+func (w *timerWheel) advance(now uint64) {
+	for w.nextTick <= now {
+		tick := w.nextTick
+		if tick&timerWheelSlotMask == 0 {
+			w.cascade(tick)
+		}
+		for t := w.detach(0, int(tick&timerWheelSlotMask)); t != nil; {
+			next := t.next
+			t.prev = nil
+			t.next = nil
+			t.fire()
+			t = next
+		}
+		if w.numTimers == 0 {
+			w.nextTick = now + 1
+			return
+		}
+		w.nextTick = min(w.nextTickWithWork(tick+1), now+1)
+	}
+}
+
+// cascade moves the timers of the slots that start at this tick to the lower levels.
+// Higher levels are handled first, since their timers might end up in a lower-level slot
+// that is handled in the same step.
+func (w *timerWheel) cascade(tick uint64) {
+	for level := timerWheelLevels - 1; level > 0; level-- {
+		shift := level * timerWheelLevelBits
+		if tick&(1<<shift-1) != 0 {
+			continue
+		}
+		for t := w.detach(level, int(tick>>shift)&timerWheelSlotMask); t != nil; {
+			next := t.next
+			w.insert(t)
+			t = next
+		}
+	}
+}
+
+func (w *timerWheel) maybeArm() {
+	if w.numTimers == 0 {
+		return
+	}
+	next := w.nextTickWithWork(w.nextTick)
+	if w.timerArmed && w.timerTick <= next {
+		return
+	}
+	w.timerArmed = true
+	w.timerTick = next
+	d := monotime.Until(w.start.Add(time.Duration(next) * timerWheelTick))
+	if w.timer == nil {
+		w.timer = time.AfterFunc(d, w.run)
+		return
+	}
+	w.timer.Reset(d)
+}
+
+func (w *timerWheel) run() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.timerArmed = false
+	if w.closed {
+		return
+	}
+	w.advance(w.currentTick())
+	w.maybeArm()
+}
@@ -0,0 +1,162 @@
+package quic
+
+import (
+	"cmp"
+	mrand "math/rand/v2"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerWheelFiring(t *testing.T) {
+	for _, d := range []time.Duration{
+		0,
+		10 * time.Millisecond,
+		63 * time.Millisecond,
+		time.Second + 500*time.Microsecond,
+		10 * time.Minute,
+		12 * time.Hour, // beyond the range of the wheel
+	} {
+		t.Run(d.String(), func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				w := newTimerWheel()
+				defer w.Close()
+				// make sure the wheel doesn't start at a tick boundary
+				time.Sleep(1234 * time.Microsecond)
+
+				timer := w.NewTimer()
+				start := monotime.Now()
+				timer.Reset(start.Add(d))
+				<-timer.C
+				require.GreaterOrEqual(t, monotime.Since(start), d)
+				require.LessOrEqual(t, monotime.Since(start), d+timerWheelTick)
+			})
+		})
+	}
+}
+
+func TestTimerWheelManyTimers(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := newTimerWheel()
+		defer w.Close()
+
+		const num = 1000
+		start := monotime.Now()
+		deadlines := make([]monotime.Time, num)
+		timers := make([]*wheelTimer, num)
+		for i := range num {
+			deadlines[i] = start.Add(time.Duration(mrand.Int64N(int64(10 * time.Second))))
+			timers[i] = w.NewTimer()
+			timers[i].Reset(deadlines[i])
+		}
+
+		order := make([]int, num)
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortFunc(order, func(a, b int) int { return cmp.Compare(deadlines[a], deadlines[b]) })
+		for _, i := range order {
+			<-timers[i].C
+			require.False(t, monotime.Now().Before(deadlines[i]))
+			require.LessOrEqual(t, monotime.Now().Sub(deadlines[i]), timerWheelTick)
+		}
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		require.Zero(t, w.numTimers)
+	})
+}
+
+func TestTimerWheelReset(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := newTimerWheel()
+		defer w.Close()
+
+		timer := w.NewTimer()
+		start := monotime.Now()
+		timer.Reset(start.Add(time.Second))
+		// move the deadline further into the future
+		timer.Reset(start.Add(2 * time.Second))
+		time.Sleep(1500 * time.Millisecond)
+		select {
+		case <-timer.C:
+			t.Fatal("timer fired too early")
+		default:
+		}
+		// move the deadline closer
+		timer.Reset(start.Add(1600 * time.Millisecond))
+		<-timer.C
+		require.Equal(t, 1600*time.Millisecond, monotime.Since(start))
+
+		// deadlines in the past fire immediately
+		timer.Reset(start)
+		synctest.Wait()
+		select {
+		case <-timer.C:
+		default:
+			t.Fatal("timer didn't fire")
+		}
+	})
+}
+
+func TestTimerWheelArm(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := newTimerWheel()
+		defer w.Close()
+
+		timer := w.NewTimer()
+		start := monotime.Now()
+		timer.Arm(start.Add(time.Second))
+		// Moving the deadline further into the future doesn't reschedule the timer.
+		timer.Arm(start.Add(2 * time.Second))
+		<-timer.C
+		require.Equal(t, time.Second, monotime.Since(start))
+
+		// Once the timer fired, it is scheduled again.
+		timer.Arm(start.Add(2 * time.Second))
+		// Moving the deadline closer reschedules the timer.
+		timer.Arm(start.Add(1500 * time.Millisecond))
+		<-timer.C
+		require.Equal(t, 1500*time.Millisecond, monotime.Since(start))
+
+		// a stopped timer is scheduled again
+		timer.Arm(start.Add(2 * time.Second))
+		timer.Stop()
+		timer.Arm(start.Add(3 * time.Second))
+		time.Sleep(time.Second)
+		select {
+		case <-timer.C:
+			t.Fatal("timer fired too early")
+		default:
+		}
+		<-timer.C
+		require.Equal(t, 3*time.Second, monotime.Since(start))
+	})
+}
+
+func TestTimerWheelStop(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		w := newTimerWheel()
+		defer w.Close()
+
+		timer1 := w.NewTimer()
+		timer2 := w.NewTimer()
+		start := monotime.Now()
+		timer1.Reset(start.Add(time.Second))
+		timer2.Reset(start.Add(time.Second))
+		timer1.Stop()
+
+		<-timer2.C
+		require.Equal(t, time.Second, monotime.Since(start))
+		time.Sleep(time.Second)
+		select {
+		case <-timer1.C:
+			t.Fatal("stopped timer fired")
+		default:
+		}
+	})
+}
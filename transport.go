@@ -82,7 +82,18 @@ type Transport struct {
 	// have the same length.
 	ConnectionIDGenerator ConnectionIDGenerator
 
+	// EnforceConnectionIDLength makes sure that all connection IDs issued by this Transport have the same length.
+	// This is useful if packets are steered to this Transport based on their connection ID (e.g. using XDP / eBPF),
+	// see the connid package for the rules used to parse the connection ID.
+	// If a ConnectionIDGenerator is used, its ConnectionIDLen must match the ConnectionIDLength (if set),
+	// and it must generate connection IDs of that length, otherwise the Transport fails to start.
+	// Connection IDs of a different length generated later are discarded and generated anew.
+	EnforceConnectionIDLength bool
+
 	// The StatelessResetKey is used to generate stateless reset tokens.
+	// The token for a connection ID is the HMAC-SHA256 of the connection ID using this key, truncated to 16 bytes.
+	// This applies to the token sent in the stateless_reset_token transport parameter,
+	// as well as to the tokens sent in NEW_CONNECTION_ID frames.
 	// If no key is configured, sending of stateless resets is disabled.
 	// It is highly recommended to configure a stateless reset key, as stateless resets
 	// allow the peer to quickly recover from crashes and reboots of this node.
@@ -118,6 +129,20 @@ type Transport struct {
 	// implementation of this callback (negating its return value).
 	VerifySourceAddress func(net.Addr) bool
 
+	// ConnectionRateLimiter limits the number of concurrent handshakes.
+	// Connection attempts that are not allowed are refused with a CONNECTION_REFUSED error.
+	// SubnetConnectionRateLimiter limits the number of handshakes per source subnet.
+	// If nil, the number of handshakes is not limited.
+	ConnectionRateLimiter ConnectionRateLimiter
+
+	// OnOverload is called for every new connection attempt, before the handshake is started.
+	// If it returns true, the server is considered overloaded, and the connection attempt is refused
+	// with a CONNECTION_REFUSED error, instead of being dropped silently.
+	// This allows a server to shed load, e.g. under high CPU utilization.
+	// The callback is called from the server's packet handling loop, and therefore needs to return quickly.
+	// It has no effect for clients.
+	OnOverload func() bool
+
 	// ConnContext is called when the server accepts a new connection. To reject a connection return
 	// a non-nil error.
 	// The context is closed when the connection is closed, or when the handshake fails for any reason.
@@ -130,10 +155,41 @@ type Transport struct {
 	// It is not used for dialed connections.
 	ConnContext func(context.Context, *ClientInfo) (context.Context, error)
 
+	// ConsolidateTimers makes all connections of this Transport share a single timer
+	// for deadlines that are not in the near future, such as the idle timeout and keep-alives.
+	// This reduces the scheduling overhead when handling a large number of mostly idle connections,
+	// at the cost of firing these deadlines up to one millisecond late.
+	// Deadlines in the near future, e.g. for pacing, always use a per-connection timer.
+	ConsolidateTimers bool
+
+	// SocketReceiveBufferSize is the size of the kernel's receive buffer (SO_RCVBUF) for the UDP socket.
+	// A small receive buffer causes packets to be dropped at high packet rates, before quic-go can read them.
+	// The buffer is increased to (at least) this size when the Transport is first used.
+	// On Linux, the size is limited by net.core.rmem_max, unless the process has the CAP_NET_ADMIN capability.
+	// If this value is zero, quic-go tries to increase the buffer size to 7 MB.
+	// It has no effect if the Conn is not a *net.UDPConn.
+	SocketReceiveBufferSize int
+
+	// SocketSendBufferSize is the size of the kernel's send buffer (SO_SNDBUF) for the UDP socket.
+	// The buffer is increased to (at least) this size when the Transport is first used.
+	// On Linux, the size is limited by net.core.wmem_max, unless the process has the CAP_NET_ADMIN capability.
+	// If this value is zero, quic-go tries to increase the buffer size to 7 MB.
+	// It has no effect if the Conn is not a *net.UDPConn.
+	SocketSendBufferSize int
+
 	// A Tracer traces events that don't belong to a single QUIC connection.
 	// Recorder.Close is called when the transport is closed.
 	Tracer qlogwriter.Recorder
 
+	// FaultInjector is called for every packet sent and received on this Transport.
+	// It allows dropping, duplicating, delaying and corrupting packets, see [FaultAction] for details.
+	// It is called twice for every packet: once for the UDP datagram as sent on / received from the wire,
+	// and once for the payload of every QUIC packet before encryption / after decryption.
+	// It is called concurrently from multiple Go routines.
+	//
+	// This is intended for testing only. It must not be set in production.
+	FaultInjector func(FaultInfo) FaultAction
+
 	mutex       sync.Mutex
 	handlers    map[protocol.ConnectionID]packetHandler
 	resetTokens map[protocol.StatelessResetToken]packetHandler
@@ -147,6 +203,8 @@ type Transport struct {
 	// If no ConnectionIDGenerator is set, this is set to a default.
 	connIDGenerator   ConnectionIDGenerator
 	statelessResetter *statelessResetter
+	// Only set if ConsolidateTimers is set.
+	timerWheel *timerWheel
 
 	server *baseServer
 
@@ -226,6 +284,8 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 		*t.TokenGeneratorKey,
 		maxTokenAge,
 		t.VerifySourceAddress,
+		t.ConnectionRateLimiter,
+		t.OnOverload,
 		t.DisableVersionNegotiationPackets,
 		allow0RTT,
 	)
@@ -289,6 +349,7 @@ func (t *Transport) doDial(
 		return nil, t.closeErr
 	}
 
+	ctx = contextWithConnUserData(ctx)
 	var qlogTrace qlogwriter.Trace
 	if config.Tracer != nil {
 		qlogTrace = config.Tracer(ctx, true, destConnID)
@@ -310,6 +371,8 @@ func (t *Transport) doDial(
 		initialPacketNumber,
 		use0RTT,
 		hasNegotiatedVersion,
+		t.FaultInjector,
+		t.timerWheel,
 		qlogTrace,
 		logger,
 		version,
@@ -379,13 +442,37 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			conn = c
 		} else {
 			var err error
-			conn, err = wrapConn(t.Conn)
+			conn, err = wrapConn(t.Conn, t.SocketReceiveBufferSize, t.SocketSendBufferSize)
 			if err != nil {
 				t.initErr = err
 				return
 			}
 		}
 
+		if t.FaultInjector != nil {
+			conn = &faultInjectingConn{rawConn: conn, injector: t.FaultInjector}
+		}
+
+		if t.ConnectionIDGenerator != nil {
+			t.connIDGenerator = t.ConnectionIDGenerator
+			t.connIDLen = t.ConnectionIDGenerator.ConnectionIDLen()
+			if t.EnforceConnectionIDLength {
+				gen, err := newFixedLengthConnIDGenerator(t.ConnectionIDGenerator, t.ConnectionIDLength)
+				if err != nil {
+					t.initErr = err
+					return
+				}
+				t.connIDGenerator = gen
+			}
+		} else {
+			connIDLen := t.ConnectionIDLength
+			if t.ConnectionIDLength == 0 && !allowZeroLengthConnIDs {
+				connIDLen = protocol.DefaultConnectionIDLength
+			}
+			t.connIDLen = connIDLen
+			t.connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: t.connIDLen}
+		}
+
 		t.logger = utils.DefaultLogger // TODO: make this configurable
 		t.conn = conn
 		t.handlers = make(map[protocol.ConnectionID]packetHandler)
@@ -403,18 +490,10 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			t.TokenGeneratorKey = &key
 		}
 
-		if t.ConnectionIDGenerator != nil {
-			t.connIDGenerator = t.ConnectionIDGenerator
-			t.connIDLen = t.ConnectionIDGenerator.ConnectionIDLen()
-		} else {
-			connIDLen := t.ConnectionIDLength
-			if t.ConnectionIDLength == 0 && !allowZeroLengthConnIDs {
-				connIDLen = protocol.DefaultConnectionIDLength
-			}
-			t.connIDLen = connIDLen
-			t.connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: t.connIDLen}
-		}
 		t.statelessResetter = newStatelessResetter(t.StatelessResetKey)
+		if t.ConsolidateTimers {
+			t.timerWheel = newTimerWheel()
+		}
 
 		go func() {
 			defer close(t.listening)
@@ -476,6 +555,57 @@ func (t *Transport) Close() error {
 	return nil
 }
 
+// CloseGracefully closes the Transport, without abruptly terminating existing connections.
+// It stops accepting new connections by closing the server (if one was started),
+// and then waits until all existing connections have been closed, by either the application or the peer.
+// Connections that completed the handshake before the server was closed can still be accepted.
+// Once all connections are closed, the Transport is closed, see Close.
+//
+// If ctx is canceled before all connections are closed, the Transport is closed anyway,
+// abruptly terminating the remaining connections, and the context's error is returned.
+//
+// Note that QUIC doesn't allow the server to move established connections to a different socket:
+// the server's preferred address is only communicated during the handshake.
+func (t *Transport) CloseGracefully(ctx context.Context) error {
+	t.mutex.Lock()
+	server := t.server
+	t.mutex.Unlock()
+	if server != nil {
+		server.Close()
+	}
+
+	for _, conn := range t.openConns() {
+		select {
+		case <-conn.Context().Done():
+		case <-ctx.Done():
+			t.Close()
+			return ctx.Err()
+		}
+	}
+	return t.Close()
+}
+
+// openConns returns the connections that haven't been closed yet.
+func (t *Transport) openConns() []*Conn {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var conns []*Conn
+	seen := make(map[*Conn]struct{}, len(t.handlers))
+	for _, handler := range t.handlers {
+		wc, ok := handler.(*wrappedConn)
+		if !ok { // connections that are already closed are represented by a different packetHandler
+			continue
+		}
+		if _, ok := seen[wc.Conn]; ok { // a connection is registered for multiple connection IDs
+			continue
+		}
+		seen[wc.Conn] = struct{}{}
+		conns = append(conns, wc.Conn)
+	}
+	return conns
+}
+
 func (t *Transport) closeServer() {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -516,6 +646,9 @@ func (t *Transport) close(e error) {
 	t.mutex.Unlock() // closing connections requires releasing transport mutex
 	wg.Wait()
 
+	if t.timerWheel != nil {
+		t.timerWheel.Close()
+	}
 	if t.Tracer != nil {
 		t.Tracer.Close()
 	}
@@ -549,6 +682,10 @@ func (t *Transport) listen(conn rawConn) {
 			t.close(err)
 			return
 		}
+		if t.FaultInjector != nil {
+			handlePacketWithFault(t.FaultInjector, p, t.handlePacket)
+			continue
+		}
 		t.handlePacket(p)
 	}
 }
@@ -807,8 +944,9 @@ func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
 // ReplaceWithClosed is called when a connection is closed.
 // Depending on which side closed the connection, we need to:
 // * remote close: absorb delayed packets
-// * local close: retransmit the CONNECTION_CLOSE packet, in case it was lost
-func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, connClosePacket []byte, expiry time.Duration) {
+// * local close: retransmit the CONNECTION_CLOSE packet, in case it was lost,
+// at most once per retransmitInterval (if non-zero)
+func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, connClosePacket []byte, retransmitInterval, expiry time.Duration) {
 	var handler packetHandler
 	if connClosePacket != nil {
 		handler = newClosedLocalConn(
@@ -820,6 +958,7 @@ func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, connCl
 					// Just drop the packet, sending CONNECTION_CLOSE copies is best effort anyway.
 				}
 			},
+			retransmitInterval,
 			h.logger,
 		)
 	} else {
@@ -528,6 +528,8 @@ func testTransportDial(t *testing.T, early bool) {
 			_ protocol.PacketNumber,
 			_ bool,
 			_ bool,
+			_ func(FaultInfo) FaultAction,
+			_ *timerWheel,
 			_ qlogwriter.Trace,
 			_ utils.Logger,
 			_ protocol.Version,
@@ -604,6 +606,8 @@ func TestTransportDialingVersionNegotiation(t *testing.T) {
 		pn protocol.PacketNumber,
 		_ bool,
 		hasNegotiatedVersion bool,
+		_ func(FaultInfo) FaultAction,
+		_ *timerWheel,
 		_ qlogwriter.Trace,
 		_ utils.Logger,
 		v protocol.Version,
@@ -674,7 +678,7 @@ func testTransportReplaceWithClosed(t *testing.T, local bool) {
 		connID := protocol.ParseConnectionID([]byte{4, 3, 2, 1})
 		m := (*packetHandlerMap)(tr)
 		require.True(t, m.Add(connID, handler))
-		m.ReplaceWithClosed([]protocol.ConnectionID{connID}, closePacket, expiry)
+		m.ReplaceWithClosed([]protocol.ConnectionID{connID}, closePacket, 0, expiry)
 
 		p := make([]byte, 100)
 		p[0] = 0x40 // QUIC bit
@@ -737,3 +741,34 @@ func testTransportReplaceWithClosed(t *testing.T, local bool) {
 		require.Equal(t, int(math.Ceil(math.Log2(float64(numSent)))), received)
 	})
 }
+
+func TestTransportEnforceConnectionIDLength(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		tr := &Transport{
+			Conn:                      newUDPConnLocalhost(t),
+			ConnectionIDGenerator:     &connIDLengthsGenerator{lengths: []int{8, 6, 8}, connLen: 8},
+			ConnectionIDLength:        8,
+			EnforceConnectionIDLength: true,
+		}
+		defer tr.Close()
+		ln, err := tr.Listen(&tls.Config{}, nil)
+		require.NoError(t, err)
+		defer ln.Close()
+		connID, err := tr.connIDGenerator.GenerateConnectionID()
+		require.NoError(t, err)
+		require.Equal(t, 8, connID.Len())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tr := &Transport{
+			Conn:                      newUDPConnLocalhost(t),
+			ConnectionIDGenerator:     &connIDLengthsGenerator{lengths: []int{6}, connLen: 8},
+			EnforceConnectionIDLength: true,
+		}
+		defer tr.Close()
+		_, err := tr.Listen(&tls.Config{}, nil)
+		require.EqualError(t, err, "quic: ConnectionIDGenerator generated a connection ID of length 6, expected 8")
+		_, err = tr.Dial(context.Background(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}, &tls.Config{}, nil)
+		require.EqualError(t, err, "quic: ConnectionIDGenerator generated a connection ID of length 6, expected 8")
+	})
+}
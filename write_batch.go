@@ -0,0 +1,71 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+)
+
+// A writeBatch tracks the (possibly nested) write batches opened on a connection.
+// While a batch is open, sending of new data is deferred, but only up to maxDelay
+// after the outermost batch was opened.
+type writeBatch struct {
+	mutex    sync.Mutex
+	maxDelay time.Duration
+	depth    int
+	deadline monotime.Time // zero if no batch is open, or if the batch expired
+}
+
+// Begin opens a batch.
+func (b *writeBatch) Begin(now monotime.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.depth == 0 {
+		b.deadline = now.Add(b.maxDelay)
+	}
+	b.depth++
+}
+
+// End closes a batch. It returns true if this was the outermost batch.
+// Calling End without a corresponding call to Begin is a no-op.
+func (b *writeBatch) End() (done bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.depth == 0 {
+		return false
+	}
+	b.depth--
+	if b.depth > 0 {
+		return false
+	}
+	b.deadline = 0
+	return true
+}
+
+// Deferring says if sending is currently deferred.
+// Once the deadline is reached, sending resumes, even if the batch wasn't ended.
+func (b *writeBatch) Deferring(now monotime.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.deadline.IsZero() {
+		return false
+	}
+	if !now.Before(b.deadline) {
+		b.deadline = 0
+		return false
+	}
+	return true
+}
+
+// Deadline returns the time when the open batch expires.
+// It returns the zero value if there's no open batch.
+func (b *writeBatch) Deadline() monotime.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.deadline
+}
@@ -0,0 +1,35 @@
+package quic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/monotime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBatch(t *testing.T) {
+	b := writeBatch{maxDelay: 10 * time.Millisecond}
+	now := monotime.Now()
+	require.False(t, b.Deferring(now))
+	require.Zero(t, b.Deadline())
+	require.False(t, b.End()) // no batch open
+
+	b.Begin(now)
+	b.Begin(now.Add(5 * time.Millisecond)) // nested batches don't extend the deadline
+	require.Equal(t, now.Add(10*time.Millisecond), b.Deadline())
+	require.True(t, b.Deferring(now.Add(9*time.Millisecond)))
+	require.False(t, b.End())
+	require.True(t, b.Deferring(now.Add(9*time.Millisecond)))
+	require.True(t, b.End())
+	require.False(t, b.Deferring(now.Add(9*time.Millisecond)))
+	require.Zero(t, b.Deadline())
+
+	// the batch expires
+	b.Begin(now)
+	require.False(t, b.Deferring(now.Add(10*time.Millisecond)))
+	require.Zero(t, b.Deadline())
+	require.False(t, b.Deferring(now.Add(time.Millisecond)))
+	require.True(t, b.End())
+}